@@ -14,6 +14,7 @@ import (
 
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/oci"
 	"github.com/lxc/lxd/shared/simplestreams"
 )
 
@@ -239,6 +240,43 @@ func ConnectSimpleStreams(url string, args *ConnectionArgs) (ImageServer, error)
 	return &server, nil
 }
 
+// ConnectOCI lets you connect to a remote OCI (Docker) registry over HTTP or HTTPs.
+//
+// Registry credentials can be provided as part of the URL.
+// Unless the remote server is trusted by the system CA, the remote certificate must be provided (TLSServerCert).
+func ConnectOCI(url string, args *ConnectionArgs) (ImageServer, error) {
+	logger.Debugf("Connecting to a remote OCI registry")
+
+	// Cleanup URL
+	url = strings.TrimSuffix(url, "/")
+
+	// Use empty args if not specified
+	if args == nil {
+		args = &ConnectionArgs{}
+	}
+
+	// Initialize the client struct
+	server := ProtocolOCI{
+		httpHost:        url,
+		httpUserAgent:   args.UserAgent,
+		httpCertificate: args.TLSServerCert,
+		cache:           map[string]oci.Image{},
+		cacheNames:      map[string][]string{},
+	}
+
+	// Setup the HTTP client
+	httpClient, err := tlsHTTPClient(args.HTTPClient, args.TLSClientCert, args.TLSClientKey, args.TLSCA, args.TLSServerCert, args.InsecureSkipVerify, args.Proxy)
+	if err != nil {
+		return nil, err
+	}
+	server.http = httpClient
+
+	// Get OCI registry client
+	server.ociClient = oci.NewClient(url, *httpClient, args.UserAgent)
+
+	return &server, nil
+}
+
 // Internal function called by ConnectLXD and ConnectPublicLXD
 func httpsLXD(url string, args *ConnectionArgs) (InstanceServer, error) {
 	// Use empty args if not specified
@@ -2,6 +2,7 @@ package lxd
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/lxc/lxd/shared/api"
 )
@@ -35,6 +36,10 @@ func (r *ProtocolLXD) UpdateCluster(cluster api.ClusterPut, ETag string) (Operat
 		}
 	}
 
+	if cluster.ClusterToken != "" && !r.HasExtension("clustering_join_token") {
+		return nil, fmt.Errorf("The server is missing the required \"clustering_join_token\" API extension")
+	}
+
 	op, _, err := r.queryOperation("PUT", "/cluster", cluster, "")
 	if err != nil {
 		return nil, err
@@ -108,6 +113,20 @@ func (r *ProtocolLXD) GetClusterMember(name string) (*api.ClusterMember, string,
 	return &member, etag, nil
 }
 
+// CreateClusterMember generates a join token to add a cluster member.
+func (r *ProtocolLXD) CreateClusterMember(member api.ClusterMembersPost) (Operation, error) {
+	if !r.HasExtension("clustering_join_token") {
+		return nil, fmt.Errorf("The server is missing the required \"clustering_join_token\" API extension")
+	}
+
+	op, _, err := r.queryOperation("POST", "/cluster/members", member, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
+
 // UpdateClusterMember updates information about the given member
 func (r *ProtocolLXD) UpdateClusterMember(name string, member api.ClusterMemberPut, ETag string) error {
 	if !r.HasExtension("clustering_edit_roles") {
@@ -118,6 +137,11 @@ func (r *ProtocolLXD) UpdateClusterMember(name string, member api.ClusterMemberP
 			return fmt.Errorf("The server is missing the required \"clustering_failure_domains\" API extension")
 		}
 	}
+	if len(member.Groups) > 0 {
+		if !r.HasExtension("clustering_groups") {
+			return fmt.Errorf("The server is missing the required \"clustering_groups\" API extension")
+		}
+	}
 
 	// Send the request
 	_, _, err := r.query("PUT", fmt.Sprintf("/cluster/members/%s", name), member, ETag)
@@ -141,3 +165,154 @@ func (r *ProtocolLXD) RenameClusterMember(name string, member api.ClusterMemberP
 
 	return nil
 }
+
+// UpdateClusterMemberState evacuates or restores a cluster member
+func (r *ProtocolLXD) UpdateClusterMemberState(name string, state api.ClusterMemberStatePost) (Operation, error) {
+	if !r.HasExtension("clustering_evacuation") {
+		return nil, fmt.Errorf("The server is missing the required \"clustering_evacuation\" API extension")
+	}
+
+	op, _, err := r.queryOperation("POST", fmt.Sprintf("/cluster/members/%s/state", name), state, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
+
+// GetClusterGroupNames returns the names of the cluster groups
+func (r *ProtocolLXD) GetClusterGroupNames() ([]string, error) {
+	if !r.HasExtension("clustering_groups") {
+		return nil, fmt.Errorf("The server is missing the required \"clustering_groups\" API extension")
+	}
+
+	urls := []string{}
+	_, err := r.queryStruct("GET", "/cluster/groups", nil, "", &urls)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse it
+	names := []string{}
+	for _, url := range urls {
+		fields := strings.Split(url, "/cluster/groups/")
+		names = append(names, fields[len(fields)-1])
+	}
+
+	return names, nil
+}
+
+// GetClusterGroups returns the cluster groups
+func (r *ProtocolLXD) GetClusterGroups() ([]api.ClusterGroup, error) {
+	if !r.HasExtension("clustering_groups") {
+		return nil, fmt.Errorf("The server is missing the required \"clustering_groups\" API extension")
+	}
+
+	groups := []api.ClusterGroup{}
+	_, err := r.queryStruct("GET", "/cluster/groups?recursion=1", nil, "", &groups)
+	if err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+// GetClusterGroup returns information about the given cluster group
+func (r *ProtocolLXD) GetClusterGroup(name string) (*api.ClusterGroup, string, error) {
+	if !r.HasExtension("clustering_groups") {
+		return nil, "", fmt.Errorf("The server is missing the required \"clustering_groups\" API extension")
+	}
+
+	group := api.ClusterGroup{}
+	etag, err := r.queryStruct("GET", fmt.Sprintf("/cluster/groups/%s", name), nil, "", &group)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &group, etag, nil
+}
+
+// CreateClusterGroup creates a new cluster group
+func (r *ProtocolLXD) CreateClusterGroup(group api.ClusterGroupsPost) error {
+	if !r.HasExtension("clustering_groups") {
+		return fmt.Errorf("The server is missing the required \"clustering_groups\" API extension")
+	}
+
+	_, _, err := r.query("POST", "/cluster/groups", group, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateClusterGroup updates the description and members of the given cluster group
+func (r *ProtocolLXD) UpdateClusterGroup(name string, group api.ClusterGroupPut, ETag string) error {
+	if !r.HasExtension("clustering_groups") {
+		return fmt.Errorf("The server is missing the required \"clustering_groups\" API extension")
+	}
+
+	_, _, err := r.query("PUT", fmt.Sprintf("/cluster/groups/%s", name), group, ETag)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RenameClusterGroup changes the name of an existing cluster group
+func (r *ProtocolLXD) RenameClusterGroup(name string, group api.ClusterGroupPost) error {
+	if !r.HasExtension("clustering_groups") {
+		return fmt.Errorf("The server is missing the required \"clustering_groups\" API extension")
+	}
+
+	_, _, err := r.query("POST", fmt.Sprintf("/cluster/groups/%s", name), group, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteClusterGroup deletes the given cluster group
+func (r *ProtocolLXD) DeleteClusterGroup(name string) error {
+	if !r.HasExtension("clustering_groups") {
+		return fmt.Errorf("The server is missing the required \"clustering_groups\" API extension")
+	}
+
+	_, _, err := r.query("DELETE", fmt.Sprintf("/cluster/groups/%s", name), nil, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetClusterUpgrade returns the progress of the upgrade of the cluster members
+func (r *ProtocolLXD) GetClusterUpgrade() (*api.ClusterUpgrade, error) {
+	if !r.HasExtension("clustering_upgrade") {
+		return nil, fmt.Errorf("The server is missing the required \"clustering_upgrade\" API extension")
+	}
+
+	upgrade := api.ClusterUpgrade{}
+	_, err := r.queryStruct("GET", "/cluster/upgrade", nil, "", &upgrade)
+	if err != nil {
+		return nil, err
+	}
+
+	return &upgrade, nil
+}
+
+// DrainClusterMemberForUpgrade evacuates the given cluster member so that it can be upgraded next
+func (r *ProtocolLXD) DrainClusterMemberForUpgrade(name string) (Operation, error) {
+	if !r.HasExtension("clustering_upgrade") {
+		return nil, fmt.Errorf("The server is missing the required \"clustering_upgrade\" API extension")
+	}
+
+	op, _, err := r.queryOperation("POST", "/cluster/upgrade", api.ClusterUpgradePost{Member: name}, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
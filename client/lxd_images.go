@@ -805,6 +805,12 @@ func (r *ProtocolLXD) CopyImage(source ImageServer, image api.Image, args *Image
 		req.Source.ImageType = args.Type
 	}
 
+	// OCI registries can't look images up by fingerprint, pass the reference pinning the image instead
+	if info.Protocol == "oci" && image.Properties["oci.reference"] != "" {
+		req.Source.Fingerprint = ""
+		req.Source.Alias = image.Properties["oci.reference"]
+	}
+
 	// Generate secret token if needed
 	if !image.Public {
 		secret, err := source.GetImageSecret(image.Fingerprint)
@@ -0,0 +1,112 @@
+package lxd
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+// GetNetworkLoadBalancerAddresses returns a list of network load balancer listen addresses.
+func (r *ProtocolLXD) GetNetworkLoadBalancerAddresses(networkName string) ([]string, error) {
+	if !r.HasExtension("network_load_balancer") {
+		return nil, fmt.Errorf("The server is missing the required \"network_load_balancer\" API extension")
+	}
+
+	urls := []string{}
+
+	// Fetch the raw value.
+	_, err := r.queryStruct("GET", fmt.Sprintf("/networks/%s/load-balancers", url.PathEscape(networkName)), nil, "", &urls)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse it.
+	listenAddresses := []string{}
+	for _, url := range urls {
+		fields := strings.Split(url, "/load-balancers/")
+		listenAddresses = append(listenAddresses, fields[len(fields)-1])
+	}
+
+	return listenAddresses, nil
+}
+
+// GetNetworkLoadBalancers returns a list of Network load balancer structs.
+func (r *ProtocolLXD) GetNetworkLoadBalancers(networkName string) ([]api.NetworkLoadBalancer, error) {
+	if !r.HasExtension("network_load_balancer") {
+		return nil, fmt.Errorf("The server is missing the required \"network_load_balancer\" API extension")
+	}
+
+	loadBalancers := []api.NetworkLoadBalancer{}
+
+	// Fetch the raw value.
+	_, err := r.queryStruct("GET", fmt.Sprintf("/networks/%s/load-balancers?recursion=1", url.PathEscape(networkName)), nil, "", &loadBalancers)
+	if err != nil {
+		return nil, err
+	}
+
+	return loadBalancers, nil
+}
+
+// GetNetworkLoadBalancer returns a Network load balancer entry for the provided network and listen address.
+func (r *ProtocolLXD) GetNetworkLoadBalancer(networkName string, listenAddress string) (*api.NetworkLoadBalancer, string, error) {
+	if !r.HasExtension("network_load_balancer") {
+		return nil, "", fmt.Errorf("The server is missing the required \"network_load_balancer\" API extension")
+	}
+
+	loadBalancer := api.NetworkLoadBalancer{}
+
+	// Fetch the raw value.
+	etag, err := r.queryStruct("GET", fmt.Sprintf("/networks/%s/load-balancers/%s", url.PathEscape(networkName), url.PathEscape(listenAddress)), nil, "", &loadBalancer)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &loadBalancer, etag, nil
+}
+
+// CreateNetworkLoadBalancer defines a new network load balancer using the provided struct.
+func (r *ProtocolLXD) CreateNetworkLoadBalancer(networkName string, loadBalancer api.NetworkLoadBalancersPost) error {
+	if !r.HasExtension("network_load_balancer") {
+		return fmt.Errorf("The server is missing the required \"network_load_balancer\" API extension")
+	}
+
+	// Send the request.
+	_, _, err := r.query("POST", fmt.Sprintf("/networks/%s/load-balancers", url.PathEscape(networkName)), loadBalancer, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateNetworkLoadBalancer updates the network load balancer to match the provided struct.
+func (r *ProtocolLXD) UpdateNetworkLoadBalancer(networkName string, listenAddress string, loadBalancer api.NetworkLoadBalancerPut, ETag string) error {
+	if !r.HasExtension("network_load_balancer") {
+		return fmt.Errorf("The server is missing the required \"network_load_balancer\" API extension")
+	}
+
+	// Send the request.
+	_, _, err := r.query("PUT", fmt.Sprintf("/networks/%s/load-balancers/%s", url.PathEscape(networkName), url.PathEscape(listenAddress)), loadBalancer, ETag)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteNetworkLoadBalancer deletes an existing network load balancer.
+func (r *ProtocolLXD) DeleteNetworkLoadBalancer(networkName string, listenAddress string) error {
+	if !r.HasExtension("network_load_balancer") {
+		return fmt.Errorf("The server is missing the required \"network_load_balancer\" API extension")
+	}
+
+	// Send the request.
+	_, _, err := r.query("DELETE", fmt.Sprintf("/networks/%s/load-balancers/%s", url.PathEscape(networkName), url.PathEscape(listenAddress)), nil, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
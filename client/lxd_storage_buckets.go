@@ -0,0 +1,215 @@
+package lxd
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+// GetStoragePoolBucketNames returns a list of storage bucket names.
+func (r *ProtocolLXD) GetStoragePoolBucketNames(pool string) ([]string, error) {
+	if !r.HasExtension("storage_buckets") {
+		return nil, fmt.Errorf("The server is missing the required \"storage_buckets\" API extension")
+	}
+
+	urls := []string{}
+
+	// Fetch the raw value.
+	_, err := r.queryStruct("GET", fmt.Sprintf("/storage-pools/%s/buckets", url.PathEscape(pool)), nil, "", &urls)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse it.
+	names := []string{}
+	for _, url := range urls {
+		fields := strings.Split(url, "/buckets/")
+		names = append(names, fields[len(fields)-1])
+	}
+
+	return names, nil
+}
+
+// GetStoragePoolBuckets returns a list of storage bucket structs.
+func (r *ProtocolLXD) GetStoragePoolBuckets(pool string) ([]api.StorageBucket, error) {
+	if !r.HasExtension("storage_buckets") {
+		return nil, fmt.Errorf("The server is missing the required \"storage_buckets\" API extension")
+	}
+
+	buckets := []api.StorageBucket{}
+
+	// Fetch the raw value.
+	_, err := r.queryStruct("GET", fmt.Sprintf("/storage-pools/%s/buckets?recursion=1", url.PathEscape(pool)), nil, "", &buckets)
+	if err != nil {
+		return nil, err
+	}
+
+	return buckets, nil
+}
+
+// GetStoragePoolBucket returns a storage bucket entry for the provided pool and bucket name.
+func (r *ProtocolLXD) GetStoragePoolBucket(pool string, bucketName string) (*api.StorageBucket, string, error) {
+	if !r.HasExtension("storage_buckets") {
+		return nil, "", fmt.Errorf("The server is missing the required \"storage_buckets\" API extension")
+	}
+
+	bucket := api.StorageBucket{}
+
+	// Fetch the raw value.
+	etag, err := r.queryStruct("GET", fmt.Sprintf("/storage-pools/%s/buckets/%s", url.PathEscape(pool), url.PathEscape(bucketName)), nil, "", &bucket)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &bucket, etag, nil
+}
+
+// CreateStoragePoolBucket defines a new storage bucket using the provided struct.
+func (r *ProtocolLXD) CreateStoragePoolBucket(pool string, bucket api.StorageBucketsPost) error {
+	if !r.HasExtension("storage_buckets") {
+		return fmt.Errorf("The server is missing the required \"storage_buckets\" API extension")
+	}
+
+	// Send the request.
+	_, _, err := r.query("POST", fmt.Sprintf("/storage-pools/%s/buckets", url.PathEscape(pool)), bucket, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateStoragePoolBucket updates the storage bucket to match the provided struct.
+func (r *ProtocolLXD) UpdateStoragePoolBucket(pool string, bucketName string, bucket api.StorageBucketPut, ETag string) error {
+	if !r.HasExtension("storage_buckets") {
+		return fmt.Errorf("The server is missing the required \"storage_buckets\" API extension")
+	}
+
+	// Send the request.
+	_, _, err := r.query("PUT", fmt.Sprintf("/storage-pools/%s/buckets/%s", url.PathEscape(pool), url.PathEscape(bucketName)), bucket, ETag)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteStoragePoolBucket deletes an existing storage bucket.
+func (r *ProtocolLXD) DeleteStoragePoolBucket(pool string, bucketName string) error {
+	if !r.HasExtension("storage_buckets") {
+		return fmt.Errorf("The server is missing the required \"storage_buckets\" API extension")
+	}
+
+	// Send the request.
+	_, _, err := r.query("DELETE", fmt.Sprintf("/storage-pools/%s/buckets/%s", url.PathEscape(pool), url.PathEscape(bucketName)), nil, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetStoragePoolBucketKeyNames returns a list of storage bucket key names.
+func (r *ProtocolLXD) GetStoragePoolBucketKeyNames(pool string, bucketName string) ([]string, error) {
+	if !r.HasExtension("storage_buckets") {
+		return nil, fmt.Errorf("The server is missing the required \"storage_buckets\" API extension")
+	}
+
+	urls := []string{}
+
+	// Fetch the raw value.
+	_, err := r.queryStruct("GET", fmt.Sprintf("/storage-pools/%s/buckets/%s/keys", url.PathEscape(pool), url.PathEscape(bucketName)), nil, "", &urls)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse it.
+	names := []string{}
+	for _, url := range urls {
+		fields := strings.Split(url, "/keys/")
+		names = append(names, fields[len(fields)-1])
+	}
+
+	return names, nil
+}
+
+// GetStoragePoolBucketKeys returns a list of storage bucket key structs.
+func (r *ProtocolLXD) GetStoragePoolBucketKeys(pool string, bucketName string) ([]api.StorageBucketKey, error) {
+	if !r.HasExtension("storage_buckets") {
+		return nil, fmt.Errorf("The server is missing the required \"storage_buckets\" API extension")
+	}
+
+	keys := []api.StorageBucketKey{}
+
+	// Fetch the raw value.
+	_, err := r.queryStruct("GET", fmt.Sprintf("/storage-pools/%s/buckets/%s/keys?recursion=1", url.PathEscape(pool), url.PathEscape(bucketName)), nil, "", &keys)
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// GetStoragePoolBucketKey returns a storage bucket key entry for the provided pool, bucket and key name.
+func (r *ProtocolLXD) GetStoragePoolBucketKey(pool string, bucketName string, keyName string) (*api.StorageBucketKey, string, error) {
+	if !r.HasExtension("storage_buckets") {
+		return nil, "", fmt.Errorf("The server is missing the required \"storage_buckets\" API extension")
+	}
+
+	key := api.StorageBucketKey{}
+
+	// Fetch the raw value.
+	etag, err := r.queryStruct("GET", fmt.Sprintf("/storage-pools/%s/buckets/%s/keys/%s", url.PathEscape(pool), url.PathEscape(bucketName), url.PathEscape(keyName)), nil, "", &key)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &key, etag, nil
+}
+
+// CreateStoragePoolBucketKey defines a new storage bucket key using the provided struct.
+func (r *ProtocolLXD) CreateStoragePoolBucketKey(pool string, bucketName string, key api.StorageBucketKeysPost) error {
+	if !r.HasExtension("storage_buckets") {
+		return fmt.Errorf("The server is missing the required \"storage_buckets\" API extension")
+	}
+
+	// Send the request.
+	_, _, err := r.query("POST", fmt.Sprintf("/storage-pools/%s/buckets/%s/keys", url.PathEscape(pool), url.PathEscape(bucketName)), key, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UpdateStoragePoolBucketKey updates the storage bucket key to match the provided struct.
+func (r *ProtocolLXD) UpdateStoragePoolBucketKey(pool string, bucketName string, keyName string, key api.StorageBucketKeyPut, ETag string) error {
+	if !r.HasExtension("storage_buckets") {
+		return fmt.Errorf("The server is missing the required \"storage_buckets\" API extension")
+	}
+
+	// Send the request.
+	_, _, err := r.query("PUT", fmt.Sprintf("/storage-pools/%s/buckets/%s/keys/%s", url.PathEscape(pool), url.PathEscape(bucketName), url.PathEscape(keyName)), key, ETag)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteStoragePoolBucketKey deletes an existing storage bucket key.
+func (r *ProtocolLXD) DeleteStoragePoolBucketKey(pool string, bucketName string, keyName string) error {
+	if !r.HasExtension("storage_buckets") {
+		return fmt.Errorf("The server is missing the required \"storage_buckets\" API extension")
+	}
+
+	// Send the request.
+	_, _, err := r.query("DELETE", fmt.Sprintf("/storage-pools/%s/buckets/%s/keys/%s", url.PathEscape(pool), url.PathEscape(bucketName), url.PathEscape(keyName)), nil, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -117,6 +117,21 @@ func (r *ProtocolLXD) DeleteStoragePool(name string) error {
 	return nil
 }
 
+// MigrateStoragePool moves all volumes of a storage pool to another storage pool
+func (r *ProtocolLXD) MigrateStoragePool(name string, pool api.StoragePoolMigratePost) (Operation, error) {
+	if !r.HasExtension("storage_pool_migrate") {
+		return nil, fmt.Errorf("The server is missing the required \"storage_pool_migrate\" API extension")
+	}
+
+	// Send the request
+	op, _, err := r.queryOperation("POST", fmt.Sprintf("/storage-pools/%s/migrate", url.PathEscape(name)), pool, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
+
 // GetStoragePoolResources gets the resources available to a given storage pool
 func (r *ProtocolLXD) GetStoragePoolResources(name string) (*api.ResourcesStoragePool, error) {
 	if !r.HasExtension("resources") {
@@ -0,0 +1,49 @@
+package lxd
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/lxc/lxd/shared/oci"
+)
+
+// ProtocolOCI implements an OCI registry API client
+type ProtocolOCI struct {
+	ociClient *oci.Client
+
+	http            *http.Client
+	httpHost        string
+	httpUserAgent   string
+	httpCertificate string
+
+	// Images resolved through their tag (indexed by fingerprint) and the fingerprints of each reference.
+	cache      map[string]oci.Image
+	cacheNames map[string][]string
+	cacheLock  sync.Mutex
+}
+
+// Disconnect is a no-op for OCI registries
+func (r *ProtocolOCI) Disconnect() {
+	return
+}
+
+// GetConnectionInfo returns the basic connection information used to interact with the server
+func (r *ProtocolOCI) GetConnectionInfo() (*ConnectionInfo, error) {
+	info := ConnectionInfo{}
+	info.Addresses = []string{r.httpHost}
+	info.Certificate = r.httpCertificate
+	info.Protocol = "oci"
+	info.URL = r.httpHost
+
+	return &info, nil
+}
+
+// GetHTTPClient returns the http client used for the connection. This can be used to set custom http options.
+func (r *ProtocolOCI) GetHTTPClient() (*http.Client, error) {
+	if r.http == nil {
+		return nil, fmt.Errorf("HTTP client isn't set, bad connection")
+	}
+
+	return r.http, nil
+}
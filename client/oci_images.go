@@ -0,0 +1,297 @@
+package lxd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/ioprogress"
+	"github.com/lxc/lxd/shared/oci"
+	"github.com/lxc/lxd/shared/osarch"
+	"github.com/lxc/lxd/shared/units"
+)
+
+// Image handling functions
+
+// GetImages isn't supported by OCI registries
+func (r *ProtocolOCI) GetImages() ([]api.Image, error) {
+	return nil, fmt.Errorf("Listing images is not supported by the OCI protocol")
+}
+
+// GetImageFingerprints isn't supported by OCI registries
+func (r *ProtocolOCI) GetImageFingerprints() ([]string, error) {
+	return nil, fmt.Errorf("Listing images is not supported by the OCI protocol")
+}
+
+// GetImage returns an Image struct for the provided fingerprint.
+// The image must have been looked up through its reference first.
+func (r *ProtocolOCI) GetImage(fingerprint string) (*api.Image, string, error) {
+	image, err := r.getCachedImage(fingerprint)
+	if err != nil {
+		return nil, "", err
+	}
+
+	manifest, err := r.ociClient.GetManifest(*image)
+	if err != nil {
+		return nil, "", err
+	}
+
+	config, err := r.ociClient.GetConfig(image.Repository, manifest)
+	if err != nil {
+		return nil, "", err
+	}
+
+	info := api.Image{}
+	info.Architecture = image.Architecture
+	info.Fingerprint = ociFingerprint(*image)
+	info.Filename = fmt.Sprintf("%s.tar.gz", info.Fingerprint)
+	info.Properties = oci.Properties(*image, config)
+	info.Public = true
+	info.Type = "container"
+
+	if config.Created != nil {
+		info.CreatedAt = *config.Created
+	}
+
+	for _, layer := range manifest.Layers {
+		info.Size += layer.Size
+	}
+
+	return &info, "", nil
+}
+
+// GetImageFile downloads the layers of an image and flattens them into a unified image, returning an
+// ImageFileResponse struct
+func (r *ProtocolOCI) GetImageFile(fingerprint string, req ImageFileRequest) (*ImageFileResponse, error) {
+	// Sanity checks
+	if req.MetaFile == nil {
+		return nil, fmt.Errorf("No file requested")
+	}
+
+	image, err := r.getCachedImage(fingerprint)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest, err := r.ociClient.GetManifest(*image)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := r.ociClient.GetConfig(image.Repository, manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	// Download the layers
+	layersDir, err := ioutil.TempDir("", "lxc_image_")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(layersDir)
+
+	layers := []string{}
+	for i, layer := range manifest.Layers {
+		layerPath := filepath.Join(layersDir, fmt.Sprintf("%d", i))
+		layerFile, err := os.Create(layerPath)
+		if err != nil {
+			return nil, err
+		}
+
+		var progress func(percent int64, speed int64)
+		if req.ProgressHandler != nil {
+			layerName := fmt.Sprintf("layer %d/%d", i+1, len(manifest.Layers))
+			progress = func(percent int64, speed int64) {
+				req.ProgressHandler(ioprogress.ProgressData{Text: fmt.Sprintf("%s: %d%% (%s/s)", layerName, percent, units.GetByteSizeString(speed, 2))})
+			}
+		}
+
+		_, err = r.ociClient.GetBlob(image.Repository, layer, layerFile, req.Canceler, progress)
+		layerFile.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		layers = append(layers, layerPath)
+	}
+
+	// Flatten the layers into a unified image
+	if req.ProgressHandler != nil {
+		req.ProgressHandler(ioprogress.ProgressData{Text: "Flattening layers"})
+	}
+
+	err = oci.Flatten(req.MetaFile, oci.Metadata(*image, config), layers)
+	if err != nil {
+		return nil, err
+	}
+
+	size, err := req.MetaFile.Seek(0, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := ImageFileResponse{}
+	resp.MetaName = fmt.Sprintf("%s.tar.gz", ociFingerprint(*image))
+	resp.MetaSize = size
+
+	return &resp, nil
+}
+
+// GetImageSecret isn't relevant for the OCI protocol
+func (r *ProtocolOCI) GetImageSecret(fingerprint string) (string, error) {
+	return "", fmt.Errorf("Private images aren't supported by the OCI protocol")
+}
+
+// GetPrivateImage isn't relevant for the OCI protocol
+func (r *ProtocolOCI) GetPrivateImage(fingerprint string, secret string) (*api.Image, string, error) {
+	return nil, "", fmt.Errorf("Private images aren't supported by the OCI protocol")
+}
+
+// GetPrivateImageFile isn't relevant for the OCI protocol
+func (r *ProtocolOCI) GetPrivateImageFile(fingerprint string, secret string, req ImageFileRequest) (*ImageFileResponse, error) {
+	return nil, fmt.Errorf("Private images aren't supported by the OCI protocol")
+}
+
+// GetImageAliases isn't supported by OCI registries
+func (r *ProtocolOCI) GetImageAliases() ([]api.ImageAliasesEntry, error) {
+	return nil, fmt.Errorf("Listing aliases is not supported by the OCI protocol")
+}
+
+// GetImageAliasNames isn't supported by OCI registries
+func (r *ProtocolOCI) GetImageAliasNames() ([]string, error) {
+	return nil, fmt.Errorf("Listing aliases is not supported by the OCI protocol")
+}
+
+// GetImageAlias resolves an image reference ("repository[:tag]" or "repository@digest") into an ImageAliasesEntry
+// struct, preferring the image matching the local architecture
+func (r *ProtocolOCI) GetImageAlias(name string) (*api.ImageAliasesEntry, string, error) {
+	aliases, err := r.GetImageAliasArchitectures("", name)
+	if err != nil {
+		return nil, "", err
+	}
+
+	localArch, err := osarch.ArchitectureGetLocal()
+	if err == nil {
+		alias, ok := aliases[localArch]
+		if ok {
+			return alias, "", nil
+		}
+	}
+
+	if len(aliases) > 1 {
+		return nil, "", fmt.Errorf("No image matching the local architecture for %q", name)
+	}
+
+	for _, alias := range aliases {
+		return alias, "", nil
+	}
+
+	return nil, "", fmt.Errorf("Alias '%s' doesn't exist", name)
+}
+
+// GetImageAliasType resolves an image reference into an ImageAliasesEntry struct
+func (r *ProtocolOCI) GetImageAliasType(imageType string, name string) (*api.ImageAliasesEntry, string, error) {
+	if imageType != "" && imageType != "container" {
+		return nil, "", fmt.Errorf("Only container images are supported by the OCI protocol")
+	}
+
+	return r.GetImageAlias(name)
+}
+
+// GetImageAliasArchitectures returns a map of architectures / targets
+func (r *ProtocolOCI) GetImageAliasArchitectures(imageType string, name string) (map[string]*api.ImageAliasesEntry, error) {
+	if imageType != "" && imageType != "container" {
+		return nil, fmt.Errorf("Only container images are supported by the OCI protocol")
+	}
+
+	images, err := r.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+
+	aliases := map[string]*api.ImageAliasesEntry{}
+	for _, image := range images {
+		alias := api.ImageAliasesEntry{}
+		alias.Name = name
+		alias.Target = ociFingerprint(image)
+		alias.Type = "container"
+
+		aliases[image.Architecture] = &alias
+	}
+
+	return aliases, nil
+}
+
+// ExportImage exports (copies) an image to a remote server
+func (r *ProtocolOCI) ExportImage(fingerprint string, image api.ImageExportPost) (Operation, error) {
+	return nil, fmt.Errorf("Exporting images is not supported by the OCI protocol")
+}
+
+// resolve resolves an image reference, caching the resulting images for later lookups by fingerprint.
+func (r *ProtocolOCI) resolve(name string) ([]oci.Image, error) {
+	r.cacheLock.Lock()
+	fingerprints, ok := r.cacheNames[name]
+	if ok {
+		images := []oci.Image{}
+		for _, fingerprint := range fingerprints {
+			images = append(images, r.cache[fingerprint])
+		}
+
+		r.cacheLock.Unlock()
+		return images, nil
+	}
+	r.cacheLock.Unlock()
+
+	images, err := r.ociClient.Resolve(name)
+	if err != nil {
+		return nil, err
+	}
+
+	r.cacheLock.Lock()
+	defer r.cacheLock.Unlock()
+
+	fingerprints = []string{}
+	for _, image := range images {
+		fingerprint := ociFingerprint(image)
+		r.cache[fingerprint] = image
+		fingerprints = append(fingerprints, fingerprint)
+	}
+
+	r.cacheNames[name] = fingerprints
+
+	return images, nil
+}
+
+// getCachedImage returns the previously resolved image matching the fingerprint (or a unique prefix of it).
+func (r *ProtocolOCI) getCachedImage(fingerprint string) (*oci.Image, error) {
+	r.cacheLock.Lock()
+	defer r.cacheLock.Unlock()
+
+	var match *oci.Image
+	for cachedFingerprint, image := range r.cache {
+		if !strings.HasPrefix(cachedFingerprint, fingerprint) {
+			continue
+		}
+
+		if match != nil {
+			return nil, fmt.Errorf("More than one match for fingerprint '%s'", fingerprint)
+		}
+
+		img := image
+		match = &img
+	}
+
+	if match == nil {
+		return nil, fmt.Errorf("Image '%s' not found, OCI images must be looked up by reference first", fingerprint)
+	}
+
+	return match, nil
+}
+
+// ociFingerprint returns the LXD fingerprint of an image, which is the hash of its manifest.
+func ociFingerprint(image oci.Image) string {
+	return strings.TrimPrefix(image.Digest, "sha256:")
+}
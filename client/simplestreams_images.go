@@ -12,6 +12,7 @@ import (
 
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/simplestreams"
 )
 
 // Image handling functions
@@ -86,7 +87,7 @@ func (r *ProtocolSimpleStreams) GetImageFile(fingerprint string, req ImageFileRe
 		size, err := shared.DownloadFileHash(r.http, r.httpUserAgent, req.ProgressHandler, req.Canceler, filename, url, hash, sha256.New(), target)
 		if err != nil {
 			// Handle cancelation
-			if err.Error() == "net/http: request canceled" {
+			if req.Canceler != nil && req.Canceler.Canceled() {
 				return -1, err
 			}
 
@@ -133,44 +134,22 @@ func (r *ProtocolSimpleStreams) GetImageFile(fingerprint string, req ImageFileRe
 					continue
 				}
 
-				// Create temporary file for the delta
-				deltaFile, err := ioutil.TempFile("", "lxc_image_")
+				size, err := simpleStreamsApplyDelta(download, srcPath, file, rootfs.Sha256, req.RootfsFile)
 				if err != nil {
-					return nil, err
-				}
-				defer deltaFile.Close()
-				defer os.Remove(deltaFile.Name())
-
-				// Download the delta
-				_, err = download(file.Path, "rootfs delta", file.Sha256, deltaFile)
-				if err != nil {
-					return nil, err
-				}
-
-				// Create temporary file for the delta
-				patchedFile, err := ioutil.TempFile("", "lxc_image_")
-				if err != nil {
-					return nil, err
-				}
-				defer patchedFile.Close()
-				defer os.Remove(patchedFile.Name())
-
-				// Apply it
-				_, err = shared.RunCommand("xdelta3", "-f", "-d", "-s", srcPath, deltaFile.Name(), patchedFile.Name())
-				if err != nil {
-					return nil, err
-				}
+					// Handle cancelation
+					if req.Canceler != nil && req.Canceler.Canceled() {
+						return nil, err
+					}
 
-				// Copy to the target
-				size, err := io.Copy(req.RootfsFile, patchedFile)
-				if err != nil {
-					return nil, err
+					// Try the next delta or fall back to the whole file
+					continue
 				}
 
 				parts := strings.Split(rootfs.Path, "/")
 				resp.RootfsName = parts[len(parts)-1]
 				resp.RootfsSize = size
 				downloaded = true
+				break
 			}
 		}
 
@@ -190,6 +169,63 @@ func (r *ProtocolSimpleStreams) GetImageFile(fingerprint string, req ImageFileRe
 	return &resp, nil
 }
 
+// simpleStreamsApplyDelta downloads a rootfs delta, applies it to srcPath and copies the result to target if it matches the
+// expected hash. The target is left untouched on failure.
+func simpleStreamsApplyDelta(download func(path string, filename string, hash string, target io.WriteSeeker) (int64, error), srcPath string, delta simplestreams.DownloadableFile, hash string, target io.WriteSeeker) (int64, error) {
+	// Create temporary file for the delta
+	deltaFile, err := ioutil.TempFile("", "lxc_image_")
+	if err != nil {
+		return -1, err
+	}
+	defer deltaFile.Close()
+	defer os.Remove(deltaFile.Name())
+
+	// Download the delta
+	_, err = download(delta.Path, "rootfs delta", delta.Sha256, deltaFile)
+	if err != nil {
+		return -1, err
+	}
+
+	// Create temporary file for the patched rootfs
+	patchedFile, err := ioutil.TempFile("", "lxc_image_")
+	if err != nil {
+		return -1, err
+	}
+	defer patchedFile.Close()
+	defer os.Remove(patchedFile.Name())
+
+	// Apply it
+	_, err = shared.RunCommand("xdelta3", "-f", "-d", "-s", srcPath, deltaFile.Name(), patchedFile.Name())
+	if err != nil {
+		return -1, err
+	}
+
+	// Verify the rebuilt rootfs
+	sha256 := sha256.New()
+	_, err = io.Copy(sha256, patchedFile)
+	if err != nil {
+		return -1, err
+	}
+
+	result := fmt.Sprintf("%x", sha256.Sum(nil))
+	if result != hash {
+		return -1, fmt.Errorf("Hash mismatch for rebuilt rootfs: %s != %s", result, hash)
+	}
+
+	// Copy to the target
+	_, err = patchedFile.Seek(0, 0)
+	if err != nil {
+		return -1, err
+	}
+
+	_, err = target.Seek(0, 0)
+	if err != nil {
+		return -1, err
+	}
+
+	return io.Copy(target, patchedFile)
+}
+
 // GetImageSecret isn't relevant for the simplestreams protocol
 func (r *ProtocolSimpleStreams) GetImageSecret(fingerprint string) (string, error) {
 	return "", fmt.Errorf("Private images aren't supported by the simplestreams protocol")
@@ -0,0 +1,259 @@
+package lxd
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/simplestreams"
+)
+
+// fakeXdelta3 puts an xdelta3 command in PATH which writes the delta file itself as the patched file, so that
+// the content of the rebuilt rootfs is chosen by the test. It returns a function restoring PATH.
+func fakeXdelta3(t *testing.T, dir string) func() {
+	script := "#!/bin/sh\n# xdelta3 -f -d -s <source> <delta> <target>\ncp \"$5\" \"$6\"\n"
+	err := ioutil.WriteFile(filepath.Join(dir, "xdelta3"), []byte(script), 0755)
+	require.NoError(t, err)
+
+	path := os.Getenv("PATH")
+	os.Setenv("PATH", fmt.Sprintf("%s:%s", dir, path))
+
+	return func() { os.Setenv("PATH", path) }
+}
+
+func sha256Hex(content string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(content)))
+}
+
+// simpleStreamsTestServer serves a simplestreams image "bbbb" along with a rootfs delta from image "aaaa".
+type simpleStreamsTestServer struct {
+	server   *httptest.Server
+	files    map[string]string
+	requests map[string]int
+	lock     sync.Mutex
+}
+
+func newSimpleStreamsTestServer(t *testing.T, rootfs string, delta string) *simpleStreamsTestServer {
+	s := &simpleStreamsTestServer{
+		files: map[string]string{
+			"images/aaaa/lxd.tar.xz":      "meta-old",
+			"images/aaaa/rootfs.squashfs": "rootfs-old",
+			"images/bbbb/lxd.tar.xz":      "meta-new",
+			"images/bbbb/rootfs.squashfs": rootfs,
+			"images/bbbb/aaaa.vcdiff":     delta,
+		},
+		requests: map[string]int{},
+	}
+
+	imageFiles := map[string]map[string]simplestreams.DownloadableFile{}
+	for _, fingerprint := range []string{"aaaa", "bbbb"} {
+		imageFiles[fingerprint] = map[string]simplestreams.DownloadableFile{}
+		for fileType, name := range map[string]string{"meta": "lxd.tar.xz", "root": "rootfs.squashfs"} {
+			path := fmt.Sprintf("images/%s/%s", fingerprint, name)
+			imageFiles[fingerprint][fileType] = simplestreams.DownloadableFile{
+				Path:   path,
+				Sha256: sha256Hex(s.files[path]),
+				Size:   int64(len(s.files[path])),
+			}
+		}
+	}
+
+	imageFiles["bbbb"]["root.delta-aaaa"] = simplestreams.DownloadableFile{
+		Path:   "images/bbbb/aaaa.vcdiff",
+		Sha256: sha256Hex(delta),
+		Size:   int64(len(delta)),
+	}
+
+	images := []api.Image{}
+	for i, fingerprint := range []string{"aaaa", "bbbb"} {
+		createdAt := time.Date(2021, time.March, i+1, 12, 0, 0, 0, time.UTC)
+		images = append(images, api.Image{
+			Architecture: "x86_64",
+			Type:         "container",
+			Fingerprint:  fingerprint,
+			CreatedAt:    createdAt,
+			ImagePut: api.ImagePut{
+				Properties: map[string]string{
+					"os":           "ubuntu",
+					"release":      "focal",
+					"architecture": "amd64",
+					"type":         "squashfs",
+					"serial":       createdAt.Format("20060102_1504"),
+				},
+			},
+		})
+	}
+
+	products, err := json.Marshal(simplestreams.ProductsFromLXD(images, imageFiles))
+	require.NoError(t, err)
+	s.files["streams/v1/images.json"] = string(products)
+
+	index, err := json.Marshal(simplestreams.Stream{
+		Format: "index:1.0",
+		Index: map[string]simplestreams.StreamIndex{
+			"images": {
+				DataType: "image-downloads",
+				Path:     "streams/v1/images.json",
+				Products: []string{"ubuntu:focal:amd64:container"},
+			},
+		},
+	})
+	require.NoError(t, err)
+	s.files["streams/v1/index.json"] = string(index)
+
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimLeft(r.URL.Path, "/")
+
+		s.lock.Lock()
+		s.requests[path]++
+		s.lock.Unlock()
+
+		content, ok := s.files[path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Write([]byte(content))
+	}))
+
+	return s
+}
+
+func (s *simpleStreamsTestServer) connect() *ProtocolSimpleStreams {
+	return &ProtocolSimpleStreams{
+		ssClient: simplestreams.NewClient(s.server.URL, *s.server.Client(), ""),
+		http:     s.server.Client(),
+		// Files are first tried over plain http on the same host.
+		httpHost: strings.Replace(s.server.URL, "http://", "https://", 1),
+	}
+}
+
+func (s *simpleStreamsTestServer) requested(path string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.requests[path]
+}
+
+// getImageFile downloads image "bbbb" using the old rootfs stored in dir as the delta source.
+func getImageFile(t *testing.T, r *ProtocolSimpleStreams, dir string) (*ImageFileResponse, string) {
+	srcPath := filepath.Join(dir, "aaaa.rootfs")
+	err := ioutil.WriteFile(srcPath, []byte("rootfs-old"), 0644)
+	require.NoError(t, err)
+
+	metaFile, err := ioutil.TempFile(dir, "meta")
+	require.NoError(t, err)
+	defer metaFile.Close()
+
+	rootfsFile, err := ioutil.TempFile(dir, "rootfs")
+	require.NoError(t, err)
+	defer rootfsFile.Close()
+
+	resp, err := r.GetImageFile("bbbb", ImageFileRequest{
+		MetaFile:   metaFile,
+		RootfsFile: rootfsFile,
+		DeltaSourceRetriever: func(fingerprint string, file string) string {
+			if fingerprint == "aaaa" && file == "rootfs" {
+				return srcPath
+			}
+
+			return ""
+		},
+	})
+	require.NoError(t, err)
+
+	content, err := ioutil.ReadFile(rootfsFile.Name())
+	require.NoError(t, err)
+
+	return resp, string(content[:resp.RootfsSize])
+}
+
+// A rootfs rebuilt from a delta is used when it matches the rootfs hash.
+func TestProtocolSimpleStreams_GetImageFile_Delta(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lxd-client-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	defer fakeXdelta3(t, dir)()
+
+	s := newSimpleStreamsTestServer(t, "rootfs-new", "rootfs-new")
+	defer s.server.Close()
+
+	resp, rootfs := getImageFile(t, s.connect(), dir)
+	assert.Equal(t, "rootfs-new", rootfs)
+	assert.Equal(t, "rootfs.squashfs", resp.RootfsName)
+	assert.Equal(t, 1, s.requested("images/bbbb/aaaa.vcdiff"))
+	assert.Equal(t, 0, s.requested("images/bbbb/rootfs.squashfs"))
+}
+
+// A rootfs rebuilt from a delta which doesn't match the rootfs hash is discarded and the rootfs downloaded in full.
+func TestProtocolSimpleStreams_GetImageFile_DeltaMismatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lxd-client-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	defer fakeXdelta3(t, dir)()
+
+	s := newSimpleStreamsTestServer(t, "rootfs-new", "rootfs-corrupted")
+	defer s.server.Close()
+
+	resp, rootfs := getImageFile(t, s.connect(), dir)
+	assert.Equal(t, "rootfs-new", rootfs)
+	assert.Equal(t, int64(len("rootfs-new")), resp.RootfsSize)
+	assert.Equal(t, 1, s.requested("images/bbbb/aaaa.vcdiff"))
+	assert.Equal(t, 1, s.requested("images/bbbb/rootfs.squashfs"))
+}
+
+func TestSimpleStreamsApplyDelta_HashMismatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lxd-client-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	defer fakeXdelta3(t, dir)()
+
+	target, err := ioutil.TempFile(dir, "rootfs")
+	require.NoError(t, err)
+	defer target.Close()
+
+	_, err = target.WriteString("untouched")
+	require.NoError(t, err)
+
+	download := func(path string, filename string, hash string, target io.WriteSeeker) (int64, error) {
+		n, err := target.Write([]byte("rebuilt"))
+		return int64(n), err
+	}
+
+	delta := simplestreams.DownloadableFile{Path: "delta.vcdiff"}
+
+	// The target is left untouched if the rebuilt rootfs doesn't match.
+	_, err = simpleStreamsApplyDelta(download, filepath.Join(dir, "source"), delta, sha256Hex("expected"), target)
+	assert.EqualError(t, err, fmt.Sprintf("Hash mismatch for rebuilt rootfs: %s != %s", sha256Hex("rebuilt"), sha256Hex("expected")))
+
+	content, err := ioutil.ReadFile(target.Name())
+	require.NoError(t, err)
+	assert.Equal(t, "untouched", string(content))
+
+	// And gets the rebuilt rootfs otherwise.
+	size, err := simpleStreamsApplyDelta(download, filepath.Join(dir, "source"), delta, sha256Hex("rebuilt"), target)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len("rebuilt")), size)
+
+	content, err = ioutil.ReadFile(target.Name())
+	require.NoError(t, err)
+	assert.Equal(t, "rebuilt", string(content[:size]))
+}
@@ -55,6 +55,34 @@ func (c *cmdCluster) Command() *cobra.Command {
 	clusterEditCmd := cmdClusterEdit{global: c.global, cluster: c}
 	cmd.AddCommand(clusterEditCmd.Command())
 
+	// Evacuate
+	clusterEvacuateCmd := cmdClusterEvacuateAction{global: c.global, cluster: c}
+	cmd.AddCommand(clusterEvacuateCmd.Command("evacuate"))
+
+	// Restore
+	clusterRestoreCmd := cmdClusterEvacuateAction{global: c.global, cluster: c}
+	cmd.AddCommand(clusterRestoreCmd.Command("restore"))
+
+	// Add token
+	clusterAddCmd := cmdClusterAdd{global: c.global, cluster: c}
+	cmd.AddCommand(clusterAddCmd.Command())
+
+	// List tokens
+	clusterListTokensCmd := cmdClusterListTokens{global: c.global, cluster: c}
+	cmd.AddCommand(clusterListTokensCmd.Command())
+
+	// Revoke tokens
+	clusterRevokeTokenCmd := cmdClusterRevokeToken{global: c.global, cluster: c}
+	cmd.AddCommand(clusterRevokeTokenCmd.Command())
+
+	// Groups
+	clusterGroupCmd := cmdClusterGroup{global: c.global, cluster: c}
+	cmd.AddCommand(clusterGroupCmd.Command())
+
+	// Upgrade
+	clusterUpgradeCmd := cmdClusterUpgrade{global: c.global, cluster: c}
+	cmd.AddCommand(clusterUpgradeCmd.Command())
+
 	return cmd
 }
 
@@ -519,3 +547,297 @@ func (c *cmdClusterEdit) Run(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// Evacuate and restore
+type cmdClusterEvacuateAction struct {
+	global  *cmdGlobal
+	cluster *cmdCluster
+	action  string
+
+	flagForce bool
+}
+
+func (c *cmdClusterEvacuateAction) Command(action string) *cobra.Command {
+	c.action = action
+
+	cmd := &cobra.Command{}
+	cmd.Use = fmt.Sprintf("%s [<remote>:]<member>", action)
+
+	if action == "evacuate" {
+		cmd.Short = i18n.G("Evacuate cluster member")
+		cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+			`Evacuate cluster member
+
+Instances are migrated to other cluster members or stopped, depending on
+their cluster.evacuate configuration.`))
+	} else {
+		cmd.Short = i18n.G("Restore cluster member")
+		cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+			`Restore cluster member
+
+Evacuated instances are moved back to the cluster member and restarted.`))
+	}
+
+	cmd.Flags().BoolVar(&c.flagForce, "force", false, i18n.G("Don't require user confirmation"))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdClusterEvacuateAction) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing cluster member name"))
+	}
+
+	if !c.flagForce {
+		confirmed := cli.AskBool(fmt.Sprintf(i18n.G("Are you sure you want to %s cluster member %q? (yes/no) [default=no]: "), c.action, resource.name), "no")
+		if !confirmed {
+			return nil
+		}
+	}
+
+	state := api.ClusterMemberStatePost{
+		Action: c.action,
+	}
+
+	op, err := resource.server.UpdateClusterMemberState(resource.name, state)
+	if err != nil {
+		return errors.Wrapf(err, i18n.G("Failed to update cluster member state"))
+	}
+
+	progress := utils.ProgressRenderer{
+		Quiet: c.global.flagQuiet,
+	}
+	_, err = op.AddHandler(progress.UpdateOp)
+	if err != nil {
+		progress.Done("")
+		return err
+	}
+
+	// Wait for operation to finish
+	err = utils.CancelableWait(op, &progress)
+	if err != nil {
+		progress.Done("")
+		return err
+	}
+
+	progress.Done("")
+
+	return nil
+}
+
+// Add
+type cmdClusterAdd struct {
+	global  *cmdGlobal
+	cluster *cmdCluster
+}
+
+func (c *cmdClusterAdd) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("add [[<remote>:]<name>]")
+	cmd.Short = i18n.G("Request a join token for adding a cluster member")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Request a join token for adding a cluster member
+
+The token is single-use and lets the new member join the cluster without knowing the trust password.`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdClusterAdd) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing cluster member name"))
+	}
+
+	// Request the join token.
+	member := api.ClusterMembersPost{
+		ServerName: resource.name,
+	}
+
+	op, err := resource.server.CreateClusterMember(member)
+	if err != nil {
+		return err
+	}
+
+	opAPI := op.Get()
+	joinToken, err := opAPI.ToClusterJoinToken()
+	if err != nil {
+		return errors.Wrapf(err, "Failed converting token operation to join token")
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Member %s join token:")+"\n", resource.name)
+	}
+
+	fmt.Println(joinToken.String())
+
+	return nil
+}
+
+// List Tokens
+type cmdClusterListTokens struct {
+	global  *cmdGlobal
+	cluster *cmdCluster
+
+	flagFormat string
+}
+
+func (c *cmdClusterListTokens) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("list-tokens [<remote>:]")
+	cmd.Short = i18n.G("List all active cluster member join tokens")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`List all active cluster member join tokens`))
+	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdClusterListTokens) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 0, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	remote := ""
+	if len(args) == 1 {
+		remote = args[0]
+	}
+
+	resources, err := c.global.ParseServers(remote)
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	// Get the join tokens, which are the running token operations.
+	ops, err := resource.server.GetOperations()
+	if err != nil {
+		return err
+	}
+
+	data := [][]string{}
+	joinTokens := []api.ClusterMemberJoinToken{}
+	for _, op := range ops {
+		if op.StatusCode != api.Running {
+			continue
+		}
+
+		joinToken, err := op.ToClusterJoinToken()
+		if err != nil {
+			continue // Operation is not a valid cluster member join token operation.
+		}
+
+		// The token itself is only shown when created.
+		data = append(data, []string{joinToken.ServerName, tokenExpiry(joinToken.ExpiresAt)})
+		joinTokens = append(joinTokens, *joinToken)
+	}
+	sort.Sort(byName(data))
+
+	header := []string{
+		i18n.G("NAME"),
+		i18n.G("EXPIRES AT"),
+	}
+
+	return utils.RenderTable(c.flagFormat, header, data, joinTokens)
+}
+
+// Revoke Tokens
+type cmdClusterRevokeToken struct {
+	global  *cmdGlobal
+	cluster *cmdCluster
+}
+
+func (c *cmdClusterRevokeToken) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("revoke-token [<remote>:]<name>")
+	cmd.Short = i18n.G("Revoke cluster member join token")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Revoke cluster member join token`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdClusterRevokeToken) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	// Find the join token of the member.
+	ops, err := resource.server.GetOperations()
+	if err != nil {
+		return err
+	}
+
+	for _, op := range ops {
+		if op.StatusCode != api.Running {
+			continue
+		}
+
+		joinToken, err := op.ToClusterJoinToken()
+		if err != nil || joinToken.ServerName != resource.name {
+			continue
+		}
+
+		err = resource.server.DeleteOperation(op.ID)
+		if err != nil {
+			return err
+		}
+
+		if !c.global.flagQuiet {
+			fmt.Printf(i18n.G("Cluster join token for %s:%s deleted")+"\n", resource.remote, resource.name)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf(i18n.G("No cluster join token for member %s on remote: %s"), resource.name, resource.remote)
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/lxc/lxd/lxc/utils"
+	cli "github.com/lxc/lxd/shared/cmd"
+	"github.com/lxc/lxd/shared/i18n"
+)
+
+type cmdClusterUpgrade struct {
+	global  *cmdGlobal
+	cluster *cmdCluster
+}
+
+func (c *cmdClusterUpgrade) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("upgrade")
+	cmd.Short = i18n.G("Coordinate rolling upgrades of the cluster members")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Coordinate rolling upgrades of the cluster members
+
+Cluster members are drained and upgraded one at a time. Members already
+upgraded are read-only until all members are upgraded.`))
+
+	// Drain
+	clusterUpgradeDrainCmd := cmdClusterUpgradeDrain{global: c.global, cluster: c.cluster}
+	cmd.AddCommand(clusterUpgradeDrainCmd.Command())
+
+	// Status
+	clusterUpgradeStatusCmd := cmdClusterUpgradeStatus{global: c.global, cluster: c.cluster}
+	cmd.AddCommand(clusterUpgradeStatusCmd.Command())
+
+	return cmd
+}
+
+// Drain
+type cmdClusterUpgradeDrain struct {
+	global  *cmdGlobal
+	cluster *cmdCluster
+
+	flagForce bool
+}
+
+func (c *cmdClusterUpgradeDrain) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("drain [<remote>:]<member>")
+	cmd.Short = i18n.G("Evacuate a cluster member before upgrading it")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Evacuate a cluster member before upgrading it
+
+This fails if another cluster member is still being upgraded.`))
+
+	cmd.Flags().BoolVar(&c.flagForce, "force", false, i18n.G("Don't require user confirmation"))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdClusterUpgradeDrain) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing cluster member name"))
+	}
+
+	if !c.flagForce {
+		confirmed := cli.AskBool(fmt.Sprintf(i18n.G("Are you sure you want to drain cluster member %q? (yes/no) [default=no]: "), resource.name), "no")
+		if !confirmed {
+			return nil
+		}
+	}
+
+	op, err := resource.server.DrainClusterMemberForUpgrade(resource.name)
+	if err != nil {
+		return errors.Wrapf(err, i18n.G("Failed to drain cluster member"))
+	}
+
+	progress := utils.ProgressRenderer{
+		Quiet: c.global.flagQuiet,
+	}
+	_, err = op.AddHandler(progress.UpdateOp)
+	if err != nil {
+		progress.Done("")
+		return err
+	}
+
+	// Wait for operation to finish
+	err = utils.CancelableWait(op, &progress)
+	if err != nil {
+		progress.Done("")
+		return err
+	}
+
+	progress.Done("")
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Cluster member %s can now be upgraded")+"\n", resource.name)
+	}
+
+	return nil
+}
+
+// Status
+type cmdClusterUpgradeStatus struct {
+	global  *cmdGlobal
+	cluster *cmdCluster
+
+	flagFormat string
+}
+
+func (c *cmdClusterUpgradeStatus) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("status [<remote>:]")
+	cmd.Short = i18n.G("Show the version of each cluster member")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Show the version of each cluster member`))
+	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdClusterUpgradeStatus) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 0, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	remote := ""
+	if len(args) == 1 {
+		remote = args[0]
+	}
+
+	resources, err := c.global.ParseServers(remote)
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	upgrade, err := resource.server.GetClusterUpgrade()
+	if err != nil {
+		return err
+	}
+
+	// Render the table
+	data := [][]string{}
+	for _, member := range upgrade.Members {
+		upgraded := i18n.G("NO")
+		if member.Upgraded {
+			upgraded = i18n.G("YES")
+		}
+
+		line := []string{member.ServerName, fmt.Sprintf("%d", member.Schema), fmt.Sprintf("%d", member.APIExtensions), member.Status, upgraded}
+		data = append(data, line)
+	}
+	sort.Sort(byName(data))
+
+	header := []string{
+		i18n.G("NAME"),
+		i18n.G("SCHEMA"),
+		i18n.G("API EXTENSIONS"),
+		i18n.G("STATUS"),
+		i18n.G("UPGRADED"),
+	}
+
+	return utils.RenderTable(c.flagFormat, header, data, upgrade.Members)
+}
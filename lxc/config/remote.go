@@ -64,7 +64,7 @@ func (c *Config) GetInstanceServer(name string) (lxd.InstanceServer, error) {
 	}
 
 	// Sanity checks
-	if remote.Public || remote.Protocol == "simplestreams" {
+	if remote.Public || shared.StringInSlice(remote.Protocol, []string{"simplestreams", "oci"}) {
 		return nil, fmt.Errorf("The remote isn't a private LXD server")
 	}
 
@@ -160,6 +160,16 @@ func (c *Config) GetImageServer(name string) (lxd.ImageServer, error) {
 		return d, nil
 	}
 
+	// HTTP or HTTPs (OCI registry)
+	if remote.Protocol == "oci" {
+		d, err := lxd.ConnectOCI(remote.Addr, args)
+		if err != nil {
+			return nil, err
+		}
+
+		return d, nil
+	}
+
 	// HTTPs (public LXD)
 	if remote.Public {
 		d, err := lxd.ConnectPublicLXD(remote.Addr, args)
@@ -260,7 +270,7 @@ func (c *Config) getConnectionArgs(name string) (*lxd.ConnectionArgs, error) {
 	}
 
 	// Stop here if no client certificate involved
-	if remote.Protocol == "simplestreams" || remote.AuthType == "candid" {
+	if shared.StringInSlice(remote.Protocol, []string{"simplestreams", "oci"}) || remote.AuthType == "candid" {
 		return &args, nil
 	}
 
@@ -6,7 +6,9 @@ import (
 	"encoding/pem"
 	"fmt"
 	"sort"
+	"strings"
 
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 
 	"github.com/lxc/lxd/lxc/utils"
@@ -36,10 +38,18 @@ func (c *cmdConfigTrust) Command() *cobra.Command {
 	configTrustListCmd := cmdConfigTrustList{global: c.global, config: c.config, configTrust: c}
 	cmd.AddCommand(configTrustListCmd.Command())
 
+	// List tokens
+	configTrustListTokensCmd := cmdConfigTrustListTokens{global: c.global, config: c.config, configTrust: c}
+	cmd.AddCommand(configTrustListTokensCmd.Command())
+
 	// Remove
 	configTrustRemoveCmd := cmdConfigTrustRemove{global: c.global, config: c.config, configTrust: c}
 	cmd.AddCommand(configTrustRemoveCmd.Command())
 
+	// Revoke token
+	configTrustRevokeTokenCmd := cmdConfigTrustRevokeToken{global: c.global, config: c.config, configTrust: c}
+	cmd.AddCommand(configTrustRevokeTokenCmd.Command())
+
 	return cmd
 }
 
@@ -48,14 +58,33 @@ type cmdConfigTrustAdd struct {
 	global      *cmdGlobal
 	config      *cmdConfig
 	configTrust *cmdConfigTrust
+
+	flagType       string
+	flagRestricted bool
+	flagProjects   string
+	flagName       string
 }
 
 func (c *cmdConfigTrustAdd) Command() *cobra.Command {
 	cmd := &cobra.Command{}
-	cmd.Use = i18n.G("add [<remote>:] <cert>")
+	cmd.Use = i18n.G("add [<remote>:] [<cert>]")
 	cmd.Short = i18n.G("Add new trusted clients")
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
-		`Add new trusted clients`))
+		`Add new trusted clients
+
+The following certificate types are supported:
+- client (default)
+- metrics
+
+Restricted client certificates only have access to the projects listed with --projects.
+
+If no certificate is provided, a single-use token is issued instead. The new client then
+adds itself with "lxc remote add <name> <token>".
+`))
+	cmd.Flags().StringVar(&c.flagType, "type", "client", i18n.G("Type of certificate")+"``")
+	cmd.Flags().BoolVar(&c.flagRestricted, "restricted", false, i18n.G("Restrict the certificate to one or more projects"))
+	cmd.Flags().StringVar(&c.flagProjects, "projects", "", i18n.G("List of projects to restrict the certificate to")+"``")
+	cmd.Flags().StringVar(&c.flagName, "name", "", i18n.G("Alternative certificate name")+"``")
 
 	cmd.RunE = c.Run
 
@@ -64,7 +93,7 @@ func (c *cmdConfigTrustAdd) Command() *cobra.Command {
 
 func (c *cmdConfigTrustAdd) Run(cmd *cobra.Command, args []string) error {
 	// Sanity checks
-	exit, err := c.global.CheckArgs(cmd, args, 1, 2)
+	exit, err := c.global.CheckArgs(cmd, args, 0, 2)
 	if exit {
 		return err
 	}
@@ -82,20 +111,72 @@ func (c *cmdConfigTrustAdd) Run(cmd *cobra.Command, args []string) error {
 
 	resource := resources[0]
 
-	// Add trust relationship
-	fname := args[len(args)-1]
-	x509Cert, err := shared.ReadCert(shared.HostPath(fname))
+	// A token is issued when no certificate is provided.
+	useToken := len(args) == 0 || (len(args) == 1 && strings.HasSuffix(args[0], ":"))
+
+	cert := api.CertificatesPost{}
+	if useToken {
+		cert.Token = true
+		cert.Name = c.flagName
+		if cert.Name == "" {
+			cert.Name = cli.AskString(i18n.G("Please provide client name: "), "", nil)
+		}
+	} else {
+		// Add trust relationship
+		fname := args[len(args)-1]
+		x509Cert, err := shared.ReadCert(shared.HostPath(fname))
+		if err != nil {
+			return err
+		}
+
+		cert.Certificate = base64.StdEncoding.EncodeToString(x509Cert.Raw)
+		cert.Name, _ = shared.SplitExt(fname)
+		if c.flagName != "" {
+			cert.Name = c.flagName
+		}
+	}
+
+	if !shared.StringInSlice(c.flagType, []string{api.CertificateTypeClient, api.CertificateTypeMetrics}) {
+		return fmt.Errorf(i18n.G("Unknown certificate type %q"), c.flagType)
+	}
+
+	cert.Type = c.flagType
+
+	if c.flagProjects != "" && !c.flagRestricted {
+		return fmt.Errorf(i18n.G("Projects can only be set on restricted certificates"))
+	}
+
+	cert.Restricted = c.flagRestricted
+	if c.flagProjects != "" {
+		cert.Projects = strings.Split(c.flagProjects, ",")
+	}
+
+	if !useToken {
+		return resource.server.CreateCertificate(cert)
+	}
+
+	if cert.Type != api.CertificateTypeClient {
+		return fmt.Errorf(i18n.G("Tokens can only be used to add client certificates"))
+	}
+
+	op, err := resource.server.CreateCertificateToken(cert)
 	if err != nil {
 		return err
 	}
-	name, _ := shared.SplitExt(fname)
 
-	cert := api.CertificatesPost{}
-	cert.Certificate = base64.StdEncoding.EncodeToString(x509Cert.Raw)
-	cert.Name = name
-	cert.Type = "client"
+	opAPI := op.Get()
+	addToken, err := opAPI.ToCertificateAddToken()
+	if err != nil {
+		return errors.Wrapf(err, "Failed converting token operation to certificate add token")
+	}
 
-	return resource.server.CreateCertificate(cert)
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Client %s certificate add token:")+"\n", cert.Name)
+	}
+
+	fmt.Println(addToken.String())
+
+	return nil
 }
 
 // List
@@ -150,6 +231,7 @@ func (c *cmdConfigTrustList) Run(cmd *cobra.Command, args []string) error {
 	data := [][]string{}
 	for _, cert := range trust {
 		fp := cert.Fingerprint[0:12]
+		certType := cert.Type
 
 		certBlock, _ := pem.Decode([]byte(cert.Certificate))
 		if certBlock == nil {
@@ -164,12 +246,13 @@ func (c *cmdConfigTrustList) Run(cmd *cobra.Command, args []string) error {
 		const layout = "Jan 2, 2006 at 3:04pm (MST)"
 		issue := cert.NotBefore.Format(layout)
 		expiry := cert.NotAfter.Format(layout)
-		data = append(data, []string{fp, cert.Subject.CommonName, issue, expiry})
+		data = append(data, []string{fp, certType, cert.Subject.CommonName, issue, expiry})
 	}
 	sort.Sort(stringList(data))
 
 	header := []string{
 		i18n.G("FINGERPRINT"),
+		i18n.G("TYPE"),
 		i18n.G("COMMON NAME"),
 		i18n.G("ISSUE DATE"),
 		i18n.G("EXPIRY DATE"),
@@ -221,3 +304,148 @@ func (c *cmdConfigTrustRemove) Run(cmd *cobra.Command, args []string) error {
 	// Remove trust relationship
 	return resource.server.DeleteCertificate(args[len(args)-1])
 }
+
+// List tokens
+type cmdConfigTrustListTokens struct {
+	global      *cmdGlobal
+	config      *cmdConfig
+	configTrust *cmdConfigTrust
+
+	flagFormat string
+}
+
+func (c *cmdConfigTrustListTokens) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("list-tokens [<remote>:]")
+	cmd.Short = i18n.G("List all active certificate add tokens")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`List all active certificate add tokens`))
+	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdConfigTrustListTokens) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 0, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	remote := ""
+	if len(args) > 0 {
+		remote = args[0]
+	}
+
+	resources, err := c.global.ParseServers(remote)
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	// Get the certificate add tokens, which are the running token operations.
+	ops, err := resource.server.GetOperations()
+	if err != nil {
+		return err
+	}
+
+	data := [][]string{}
+	addTokens := []api.CertificateAddToken{}
+	for _, op := range ops {
+		if op.StatusCode != api.Running {
+			continue
+		}
+
+		addToken, err := op.ToCertificateAddToken()
+		if err != nil {
+			continue // Operation is not a valid certificate add token operation.
+		}
+
+		// The token itself is only shown when created.
+		data = append(data, []string{addToken.ClientName, tokenExpiry(addToken.ExpiresAt)})
+		addTokens = append(addTokens, *addToken)
+	}
+	sort.Sort(stringList(data))
+
+	header := []string{
+		i18n.G("NAME"),
+		i18n.G("EXPIRES AT"),
+	}
+
+	return utils.RenderTable(c.flagFormat, header, data, addTokens)
+}
+
+// Revoke token
+type cmdConfigTrustRevokeToken struct {
+	global      *cmdGlobal
+	config      *cmdConfig
+	configTrust *cmdConfigTrust
+}
+
+func (c *cmdConfigTrustRevokeToken) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("revoke-token [<remote>:] <name>")
+	cmd.Short = i18n.G("Revoke certificate add token")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Revoke certificate add token`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdConfigTrustRevokeToken) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 2)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	remote := ""
+	if len(args) > 0 {
+		remote = args[0]
+	}
+
+	resources, err := c.global.ParseServers(remote)
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+	name := args[len(args)-1]
+
+	// Find the certificate add token of the client.
+	ops, err := resource.server.GetOperations()
+	if err != nil {
+		return err
+	}
+
+	for _, op := range ops {
+		if op.StatusCode != api.Running {
+			continue
+		}
+
+		addToken, err := op.ToCertificateAddToken()
+		if err != nil || addToken.ClientName != name {
+			continue
+		}
+
+		err = resource.server.DeleteOperation(op.ID)
+		if err != nil {
+			return err
+		}
+
+		if !c.global.flagQuiet {
+			fmt.Printf(i18n.G("Certificate add token for %s deleted")+"\n", name)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf(i18n.G("No certificate add token for client %s on remote: %s"), name, resource.remote)
+}
@@ -188,8 +188,8 @@ func (c *cmdImageCopy) Run(cmd *cobra.Command, args []string) error {
 	// Copy the image
 	var imgInfo *api.Image
 	var fp string
-	if conf.Remotes[remoteName].Protocol == "simplestreams" && !c.flagCopyAliases && len(c.flagAliases) == 0 {
-		// All simplestreams images are always public, so unless we
+	if shared.StringInSlice(conf.Remotes[remoteName].Protocol, []string{"simplestreams", "oci"}) && !c.flagCopyAliases && len(c.flagAliases) == 0 {
+		// All simplestreams and OCI images are always public, so unless we
 		// need the aliases list too or the real fingerprint, we can skip the otherwise very expensive
 		// alias resolution and image info retrieval step.
 		imgInfo = &api.Image{}
@@ -12,6 +12,7 @@ import (
 	"github.com/lxc/lxd/client"
 	"github.com/lxc/lxd/lxc/config"
 	"github.com/lxc/lxd/lxc/utils"
+	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 	cli "github.com/lxc/lxd/shared/cmd"
 	"github.com/lxc/lxd/shared/i18n"
@@ -253,8 +254,8 @@ func (c *cmdInit) create(conf *config.Config, args []string) (lxd.InstanceServer
 			image = "default"
 		}
 
-		// Optimisation for simplestreams
-		if conf.Remotes[iremote].Protocol == "simplestreams" {
+		// Optimisation for simplestreams and OCI registries
+		if shared.StringInSlice(conf.Remotes[iremote].Protocol, []string{"simplestreams", "oci"}) {
 			imgInfo = &api.Image{}
 			imgInfo.Fingerprint = image
 			imgInfo.Public = true
@@ -152,10 +152,6 @@ func (c *cmdMove) Run(cmd *cobra.Command, args []string) error {
 		// If the target option was specified, we're moving an instance from a
 		// cluster member to another, let's use the dedicated API.
 		if sourceRemote == destRemote {
-			if c.flagStateless {
-				return fmt.Errorf(i18n.G("The --stateless flag can't be used with --target"))
-			}
-
 			if c.flagInstanceOnly {
 				return fmt.Errorf(i18n.G("The --instance-only flag can't be used with --target"))
 			}
@@ -164,7 +160,7 @@ func (c *cmdMove) Run(cmd *cobra.Command, args []string) error {
 				return fmt.Errorf(i18n.G("The --mode flag can't be used with --target"))
 			}
 
-			return moveClusterInstance(conf, sourceResource, destResource, c.flagTarget)
+			return moveClusterInstance(conf, sourceResource, destResource, c.flagTarget, !c.flagStateless)
 		}
 
 		dest, err := conf.GetInstanceServer(destRemote)
@@ -207,7 +203,7 @@ func (c *cmdMove) Run(cmd *cobra.Command, args []string) error {
 }
 
 // Move an instance using special POST /instances/<name>?target=<member> API.
-func moveClusterInstance(conf *config.Config, sourceResource, destResource, target string) error {
+func moveClusterInstance(conf *config.Config, sourceResource, destResource, target string, stateful bool) error {
 	// Parse the source.
 	sourceRemote, sourceName, err := conf.ParseRemote(sourceResource)
 	if err != nil {
@@ -243,7 +239,7 @@ func moveClusterInstance(conf *config.Config, sourceResource, destResource, targ
 
 	// The migrate API will do the right thing when passed a target.
 	source = source.UseTarget(target)
-	req := api.InstancePost{Name: destName, Migration: true}
+	req := api.InstancePost{Name: destName, Migration: true, Live: stateful}
 	op, err := source.MigrateInstance(sourceName, req)
 	if err != nil {
 		return errors.Wrap(err, i18n.G("Migration API failure"))
@@ -33,6 +33,10 @@ func (c *cmdNetwork) Command() *cobra.Command {
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`Manage and attach instances to networks`))
 
+	// ACL
+	networkACLCmd := cmdNetworkACL{global: c.global}
+	cmd.AddCommand(networkACLCmd.Command())
+
 	// Attach
 	networkAttachCmd := cmdNetworkAttach{global: c.global, network: c}
 	cmd.AddCommand(networkAttachCmd.Command())
@@ -61,6 +65,10 @@ func (c *cmdNetwork) Command() *cobra.Command {
 	networkEditCmd := cmdNetworkEdit{global: c.global, network: c}
 	cmd.AddCommand(networkEditCmd.Command())
 
+	// Forward
+	networkForwardCmd := cmdNetworkForward{global: c.global}
+	cmd.AddCommand(networkForwardCmd.Command())
+
 	// Get
 	networkGetCmd := cmdNetworkGet{global: c.global, network: c}
 	cmd.AddCommand(networkGetCmd.Command())
@@ -77,6 +85,10 @@ func (c *cmdNetwork) Command() *cobra.Command {
 	networkListLeasesCmd := cmdNetworkListLeases{global: c.global, network: c}
 	cmd.AddCommand(networkListLeasesCmd.Command())
 
+	// Load balancer
+	networkLoadBalancerCmd := cmdNetworkLoadBalancer{global: c.global}
+	cmd.AddCommand(networkLoadBalancerCmd.Command())
+
 	// Rename
 	networkRenameCmd := cmdNetworkRename{global: c.global, network: c}
 	cmd.AddCommand(networkRenameCmd.Command())
@@ -33,6 +33,10 @@ func (c *cmdStorage) Command() *cobra.Command {
 	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
 		`Manage storage pools and volumes`))
 
+	// Bucket
+	storageBucketCmd := cmdStorageBucket{global: c.global, storage: c}
+	cmd.AddCommand(storageBucketCmd.Command())
+
 	// Create
 	storageCreateCmd := cmdStorageCreate{global: c.global, storage: c}
 	cmd.AddCommand(storageCreateCmd.Command())
@@ -57,6 +61,10 @@ func (c *cmdStorage) Command() *cobra.Command {
 	storageListCmd := cmdStorageList{global: c.global, storage: c}
 	cmd.AddCommand(storageListCmd.Command())
 
+	// Migrate
+	storageMigrateCmd := cmdStorageMigrate{global: c.global, storage: c}
+	cmd.AddCommand(storageMigrateCmd.Command())
+
 	// Set
 	storageSetCmd := cmdStorageSet{global: c.global, storage: c}
 	cmd.AddCommand(storageSetCmd.Command())
@@ -569,6 +577,82 @@ func (c *cmdStorageList) Run(cmd *cobra.Command, args []string) error {
 	return utils.RenderTable(c.flagFormat, header, data, pools)
 }
 
+// Migrate
+type cmdStorageMigrate struct {
+	global  *cmdGlobal
+	storage *cmdStorage
+}
+
+func (c *cmdStorageMigrate) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("migrate [<remote>:]<pool> <target pool>")
+	cmd.Short = i18n.G("Move all volumes of a storage pool to another storage pool")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Move all volumes of a storage pool to another storage pool
+
+Instance, image and custom volumes are moved to the target pool and the disk
+devices of instances and profiles are updated to use it. Instances using the
+pool must be stopped. An interrupted migration is resumed by running the
+command again.`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdStorageMigrate) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing pool name"))
+	}
+
+	// Migrate the pool
+	op, err := resource.server.MigrateStoragePool(resource.name, api.StoragePoolMigratePost{Pool: args[1]})
+	if err != nil {
+		return err
+	}
+
+	// Register progress handler
+	progress := utils.ProgressRenderer{
+		Format: i18n.G("Migrating storage pool: %s"),
+		Quiet:  c.global.flagQuiet,
+	}
+
+	_, err = op.AddHandler(progress.UpdateOp)
+	if err != nil {
+		progress.Done("")
+		return err
+	}
+
+	// Wait for operation to finish
+	err = utils.CancelableWait(op, &progress)
+	if err != nil {
+		progress.Done("")
+		return err
+	}
+
+	progress.Done("")
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Storage pool %s migrated to %s")+"\n", resource.name, args[1])
+	}
+
+	return nil
+}
+
 // Set
 type cmdStorageSet struct {
 	global  *cmdGlobal
@@ -0,0 +1,974 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/lxc/utils"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	cli "github.com/lxc/lxd/shared/cmd"
+	"github.com/lxc/lxd/shared/i18n"
+	"github.com/lxc/lxd/shared/termios"
+)
+
+type cmdStorageBucket struct {
+	global  *cmdGlobal
+	storage *cmdStorage
+
+	flagTarget string
+}
+
+func (c *cmdStorageBucket) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("bucket")
+	cmd.Short = i18n.G("Manage storage buckets")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Manage storage buckets`))
+
+	// List
+	storageBucketListCmd := cmdStorageBucketList{global: c.global, storageBucket: c}
+	cmd.AddCommand(storageBucketListCmd.Command())
+
+	// Show
+	storageBucketShowCmd := cmdStorageBucketShow{global: c.global, storageBucket: c}
+	cmd.AddCommand(storageBucketShowCmd.Command())
+
+	// Create
+	storageBucketCreateCmd := cmdStorageBucketCreate{global: c.global, storageBucket: c}
+	cmd.AddCommand(storageBucketCreateCmd.Command())
+
+	// Get
+	storageBucketGetCmd := cmdStorageBucketGet{global: c.global, storageBucket: c}
+	cmd.AddCommand(storageBucketGetCmd.Command())
+
+	// Set
+	storageBucketSetCmd := cmdStorageBucketSet{global: c.global, storageBucket: c}
+	cmd.AddCommand(storageBucketSetCmd.Command())
+
+	// Unset
+	storageBucketUnsetCmd := cmdStorageBucketUnset{global: c.global, storageBucket: c, storageBucketSet: &storageBucketSetCmd}
+	cmd.AddCommand(storageBucketUnsetCmd.Command())
+
+	// Edit
+	storageBucketEditCmd := cmdStorageBucketEdit{global: c.global, storageBucket: c}
+	cmd.AddCommand(storageBucketEditCmd.Command())
+
+	// Delete
+	storageBucketDeleteCmd := cmdStorageBucketDelete{global: c.global, storageBucket: c}
+	cmd.AddCommand(storageBucketDeleteCmd.Command())
+
+	// Key
+	storageBucketKeyCmd := cmdStorageBucketKey{global: c.global, storageBucket: c}
+	cmd.AddCommand(storageBucketKeyCmd.Command())
+
+	return cmd
+}
+
+// parseResource parses the pool argument and returns the server to use for the bucket, honoring --target.
+func (c *cmdStorageBucket) parseResource(arg string) (lxd.InstanceServer, string, error) {
+	resources, err := c.global.ParseServers(arg)
+	if err != nil {
+		return nil, "", err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return nil, "", fmt.Errorf(i18n.G("Missing pool name"))
+	}
+
+	client := resource.server
+	if c.flagTarget != "" {
+		client = client.UseTarget(c.flagTarget)
+	}
+
+	return client, resource.name, nil
+}
+
+// List
+type cmdStorageBucketList struct {
+	global        *cmdGlobal
+	storageBucket *cmdStorageBucket
+
+	flagFormat string
+}
+
+func (c *cmdStorageBucketList) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("list [<remote>:]<pool>")
+	cmd.Aliases = []string{"ls"}
+	cmd.Short = i18n.G("List storage buckets")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`List storage buckets`))
+
+	cmd.RunE = c.Run
+	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
+	cmd.Flags().StringVar(&c.storageBucket.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+
+	return cmd
+}
+
+func (c *cmdStorageBucketList) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	client, poolName, err := c.storageBucket.parseResource(args[0])
+	if err != nil {
+		return err
+	}
+
+	buckets, err := client.GetStoragePoolBuckets(poolName)
+	if err != nil {
+		return err
+	}
+
+	data := [][]string{}
+	for _, bucket := range buckets {
+		details := []string{
+			bucket.Name,
+			bucket.Description,
+			bucket.Config["size"],
+			bucket.S3URL,
+		}
+
+		data = append(data, details)
+	}
+	sort.Sort(byName(data))
+
+	header := []string{
+		i18n.G("NAME"),
+		i18n.G("DESCRIPTION"),
+		i18n.G("SIZE"),
+		i18n.G("S3 URL"),
+	}
+
+	return utils.RenderTable(c.flagFormat, header, data, buckets)
+}
+
+// Show
+type cmdStorageBucketShow struct {
+	global        *cmdGlobal
+	storageBucket *cmdStorageBucket
+}
+
+func (c *cmdStorageBucketShow) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("show [<remote>:]<pool> <bucket>")
+	cmd.Short = i18n.G("Show storage bucket configurations")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Show storage bucket configurations`))
+	cmd.Flags().StringVar(&c.storageBucket.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdStorageBucketShow) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	client, poolName, err := c.storageBucket.parseResource(args[0])
+	if err != nil {
+		return err
+	}
+
+	// Show storage bucket config
+	bucket, _, err := client.GetStoragePoolBucket(poolName, args[1])
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(&bucket)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s", data)
+
+	return nil
+}
+
+// Create
+type cmdStorageBucketCreate struct {
+	global        *cmdGlobal
+	storageBucket *cmdStorageBucket
+}
+
+func (c *cmdStorageBucketCreate) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("create [<remote>:]<pool> <bucket> [key=value...]")
+	cmd.Short = i18n.G("Create new storage buckets")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Create new storage buckets`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc storage bucket create default b1 size=10GiB
+    Create bucket b1 on pool default with a 10GiB quota
+
+lxc storage bucket create default b1 < config.yaml
+    Create bucket b1 with configuration from config.yaml`))
+
+	cmd.Flags().StringVar(&c.storageBucket.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdStorageBucketCreate) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, -1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	client, poolName, err := c.storageBucket.parseResource(args[0])
+	if err != nil {
+		return err
+	}
+
+	// If stdin isn't a terminal, read yaml from it.
+	var bucketPut api.StorageBucketPut
+	if !termios.IsTerminal(getStdinFd()) {
+		contents, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+
+		err = yaml.UnmarshalStrict(contents, &bucketPut)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Create the storage bucket.
+	bucket := api.StorageBucketsPost{
+		Name:             args[1],
+		StorageBucketPut: bucketPut,
+	}
+
+	if bucket.Config == nil {
+		bucket.Config = map[string]string{}
+	}
+
+	for i := 2; i < len(args); i++ {
+		entry := strings.SplitN(args[i], "=", 2)
+		if len(entry) < 2 {
+			return fmt.Errorf(i18n.G("Bad key=value pair: %s"), entry)
+		}
+
+		bucket.Config[entry[0]] = entry[1]
+	}
+
+	err = client.CreateStoragePoolBucket(poolName, bucket)
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Storage bucket %s created")+"\n", args[1])
+	}
+
+	return nil
+}
+
+// Get
+type cmdStorageBucketGet struct {
+	global        *cmdGlobal
+	storageBucket *cmdStorageBucket
+}
+
+func (c *cmdStorageBucketGet) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("get [<remote>:]<pool> <bucket> <key>")
+	cmd.Short = i18n.G("Get values for storage bucket configuration keys")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Get values for storage bucket configuration keys`))
+
+	cmd.Flags().StringVar(&c.storageBucket.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdStorageBucketGet) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 3, 3)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	client, poolName, err := c.storageBucket.parseResource(args[0])
+	if err != nil {
+		return err
+	}
+
+	bucket, _, err := client.GetStoragePoolBucket(poolName, args[1])
+	if err != nil {
+		return err
+	}
+
+	for k, v := range bucket.Config {
+		if k == args[2] {
+			fmt.Printf("%s\n", v)
+		}
+	}
+
+	return nil
+}
+
+// Set
+type cmdStorageBucketSet struct {
+	global        *cmdGlobal
+	storageBucket *cmdStorageBucket
+}
+
+func (c *cmdStorageBucketSet) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("set [<remote>:]<pool> <bucket> <key>=<value>...")
+	cmd.Short = i18n.G("Set storage bucket configuration keys")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Set storage bucket configuration keys`))
+
+	cmd.Flags().StringVar(&c.storageBucket.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdStorageBucketSet) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 3, -1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	client, poolName, err := c.storageBucket.parseResource(args[0])
+	if err != nil {
+		return err
+	}
+
+	// Get the storage bucket.
+	bucket, etag, err := client.GetStoragePoolBucket(poolName, args[1])
+	if err != nil {
+		return err
+	}
+
+	// Set the keys.
+	keys, err := getConfig(args[2:]...)
+	if err != nil {
+		return err
+	}
+
+	if bucket.Config == nil {
+		bucket.Config = map[string]string{}
+	}
+
+	for k, v := range keys {
+		bucket.Config[k] = v
+	}
+
+	return client.UpdateStoragePoolBucket(poolName, bucket.Name, bucket.Writable(), etag)
+}
+
+// Unset
+type cmdStorageBucketUnset struct {
+	global           *cmdGlobal
+	storageBucket    *cmdStorageBucket
+	storageBucketSet *cmdStorageBucketSet
+}
+
+func (c *cmdStorageBucketUnset) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("unset [<remote>:]<pool> <bucket> <key>")
+	cmd.Short = i18n.G("Unset storage bucket configuration keys")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Unset storage bucket configuration keys`))
+
+	cmd.Flags().StringVar(&c.storageBucket.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdStorageBucketUnset) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 3, 3)
+	if exit {
+		return err
+	}
+
+	args = append(args, "")
+	return c.storageBucketSet.Run(cmd, args)
+}
+
+// Edit
+type cmdStorageBucketEdit struct {
+	global        *cmdGlobal
+	storageBucket *cmdStorageBucket
+}
+
+func (c *cmdStorageBucketEdit) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("edit [<remote>:]<pool> <bucket>")
+	cmd.Short = i18n.G("Edit storage bucket configurations as YAML")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Edit storage bucket configurations as YAML`))
+
+	cmd.Flags().StringVar(&c.storageBucket.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdStorageBucketEdit) helpTemplate() string {
+	return i18n.G(
+		`### This is a YAML representation of the storage bucket.
+### Any line starting with a '# will be ignored.
+###
+### A storage bucket consists of a set of configuration items.
+###
+### An example would look like:
+### name: bucket1
+### description: My bucket
+### config:
+###   size: 10GiB
+###
+### Note that the name, S3 URL and location cannot be changed.`)
+}
+
+func (c *cmdStorageBucketEdit) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	client, poolName, err := c.storageBucket.parseResource(args[0])
+	if err != nil {
+		return err
+	}
+
+	// If stdin isn't a terminal, read text from it
+	if !termios.IsTerminal(getStdinFd()) {
+		contents, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+
+		newdata := api.StorageBucketPut{}
+		err = yaml.UnmarshalStrict(contents, &newdata)
+		if err != nil {
+			return err
+		}
+
+		return client.UpdateStoragePoolBucket(poolName, args[1], newdata, "")
+	}
+
+	// Get the current config.
+	bucket, etag, err := client.GetStoragePoolBucket(poolName, args[1])
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(&bucket)
+	if err != nil {
+		return err
+	}
+
+	// Spawn the editor
+	content, err := shared.TextEditor("", []byte(c.helpTemplate()+"\n\n"+string(data)))
+	if err != nil {
+		return err
+	}
+
+	for {
+		// Parse the text received from the editor.
+		newdata := api.StorageBucket{} // We show the full info, but only send the writable fields.
+		err = yaml.UnmarshalStrict(content, &newdata)
+		if err == nil {
+			err = client.UpdateStoragePoolBucket(poolName, bucket.Name, newdata.Writable(), etag)
+		}
+
+		// Respawn the editor
+		if err != nil {
+			fmt.Fprintf(os.Stderr, i18n.G("Config parsing error: %s")+"\n", err)
+			fmt.Println(i18n.G("Press enter to open the editor again"))
+
+			_, err := os.Stdin.Read(make([]byte, 1))
+			if err != nil {
+				return err
+			}
+
+			content, err = shared.TextEditor("", content)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		break
+	}
+
+	return nil
+}
+
+// Delete
+type cmdStorageBucketDelete struct {
+	global        *cmdGlobal
+	storageBucket *cmdStorageBucket
+}
+
+func (c *cmdStorageBucketDelete) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("delete [<remote>:]<pool> <bucket>")
+	cmd.Aliases = []string{"rm"}
+	cmd.Short = i18n.G("Delete storage buckets")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Delete storage buckets`))
+
+	cmd.Flags().StringVar(&c.storageBucket.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdStorageBucketDelete) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	client, poolName, err := c.storageBucket.parseResource(args[0])
+	if err != nil {
+		return err
+	}
+
+	// Delete the storage bucket.
+	err = client.DeleteStoragePoolBucket(poolName, args[1])
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Storage bucket %s deleted")+"\n", args[1])
+	}
+
+	return nil
+}
+
+// Key
+type cmdStorageBucketKey struct {
+	global        *cmdGlobal
+	storageBucket *cmdStorageBucket
+}
+
+func (c *cmdStorageBucketKey) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("key")
+	cmd.Short = i18n.G("Manage storage bucket keys")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Manage storage bucket keys`))
+
+	// List
+	storageBucketKeyListCmd := cmdStorageBucketKeyList{global: c.global, storageBucket: c.storageBucket}
+	cmd.AddCommand(storageBucketKeyListCmd.Command())
+
+	// Show
+	storageBucketKeyShowCmd := cmdStorageBucketKeyShow{global: c.global, storageBucket: c.storageBucket}
+	cmd.AddCommand(storageBucketKeyShowCmd.Command())
+
+	// Create
+	storageBucketKeyCreateCmd := cmdStorageBucketKeyCreate{global: c.global, storageBucket: c.storageBucket}
+	cmd.AddCommand(storageBucketKeyCreateCmd.Command())
+
+	// Edit
+	storageBucketKeyEditCmd := cmdStorageBucketKeyEdit{global: c.global, storageBucket: c.storageBucket}
+	cmd.AddCommand(storageBucketKeyEditCmd.Command())
+
+	// Delete
+	storageBucketKeyDeleteCmd := cmdStorageBucketKeyDelete{global: c.global, storageBucket: c.storageBucket}
+	cmd.AddCommand(storageBucketKeyDeleteCmd.Command())
+
+	return cmd
+}
+
+// Key list
+type cmdStorageBucketKeyList struct {
+	global        *cmdGlobal
+	storageBucket *cmdStorageBucket
+
+	flagFormat string
+}
+
+func (c *cmdStorageBucketKeyList) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("list [<remote>:]<pool> <bucket>")
+	cmd.Aliases = []string{"ls"}
+	cmd.Short = i18n.G("List storage bucket keys")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`List storage bucket keys`))
+
+	cmd.RunE = c.Run
+	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
+	cmd.Flags().StringVar(&c.storageBucket.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+
+	return cmd
+}
+
+func (c *cmdStorageBucketKeyList) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 2, 2)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	client, poolName, err := c.storageBucket.parseResource(args[0])
+	if err != nil {
+		return err
+	}
+
+	keys, err := client.GetStoragePoolBucketKeys(poolName, args[1])
+	if err != nil {
+		return err
+	}
+
+	data := [][]string{}
+	for _, key := range keys {
+		details := []string{
+			key.Name,
+			key.Description,
+			key.Role,
+		}
+
+		data = append(data, details)
+	}
+	sort.Sort(byName(data))
+
+	header := []string{
+		i18n.G("NAME"),
+		i18n.G("DESCRIPTION"),
+		i18n.G("ROLE"),
+	}
+
+	return utils.RenderTable(c.flagFormat, header, data, keys)
+}
+
+// Key show
+type cmdStorageBucketKeyShow struct {
+	global        *cmdGlobal
+	storageBucket *cmdStorageBucket
+}
+
+func (c *cmdStorageBucketKeyShow) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("show [<remote>:]<pool> <bucket> <key>")
+	cmd.Short = i18n.G("Show storage bucket key configurations")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Show storage bucket key configurations`))
+	cmd.Flags().StringVar(&c.storageBucket.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdStorageBucketKeyShow) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 3, 3)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	client, poolName, err := c.storageBucket.parseResource(args[0])
+	if err != nil {
+		return err
+	}
+
+	// Show storage bucket key config
+	key, _, err := client.GetStoragePoolBucketKey(poolName, args[1], args[2])
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(&key)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s", data)
+
+	return nil
+}
+
+// Key create
+type cmdStorageBucketKeyCreate struct {
+	global        *cmdGlobal
+	storageBucket *cmdStorageBucket
+
+	flagRole      string
+	flagAccessKey string
+	flagSecretKey string
+}
+
+func (c *cmdStorageBucketKeyCreate) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("create [<remote>:]<pool> <bucket> <key>")
+	cmd.Short = i18n.G("Create new storage bucket keys")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Create new storage bucket keys
+
+Access and secret keys are generated by the server unless provided.`))
+	cmd.Example = cli.FormatSection("", i18n.G(
+		`lxc storage bucket key create default b1 k1 --role=admin
+    Create a key with read and write access to bucket b1`))
+
+	cmd.Flags().StringVar(&c.flagRole, "role", "read-only", i18n.G("Role (admin or read-only)")+"``")
+	cmd.Flags().StringVar(&c.flagAccessKey, "access-key", "", i18n.G("Access key (auto-generated if empty)")+"``")
+	cmd.Flags().StringVar(&c.flagSecretKey, "secret-key", "", i18n.G("Secret key (auto-generated if empty)")+"``")
+	cmd.Flags().StringVar(&c.storageBucket.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdStorageBucketKeyCreate) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 3, 3)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	client, poolName, err := c.storageBucket.parseResource(args[0])
+	if err != nil {
+		return err
+	}
+
+	// If stdin isn't a terminal, read yaml from it.
+	var keyPut api.StorageBucketKeyPut
+	if !termios.IsTerminal(getStdinFd()) {
+		contents, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+
+		err = yaml.UnmarshalStrict(contents, &keyPut)
+		if err != nil {
+			return err
+		}
+	}
+
+	if keyPut.Role == "" || cmd.Flags().Changed("role") {
+		keyPut.Role = c.flagRole
+	}
+
+	if c.flagAccessKey != "" {
+		keyPut.AccessKey = c.flagAccessKey
+	}
+
+	if c.flagSecretKey != "" {
+		keyPut.SecretKey = c.flagSecretKey
+	}
+
+	// Create the storage bucket key.
+	key := api.StorageBucketKeysPost{
+		Name:                args[2],
+		StorageBucketKeyPut: keyPut,
+	}
+
+	err = client.CreateStoragePoolBucketKey(poolName, args[1], key)
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		// Show the credentials, as they may have been generated by the server.
+		newKey, _, err := client.GetStoragePoolBucketKey(poolName, args[1], args[2])
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf(i18n.G("Storage bucket key %s added")+"\n", args[2])
+		fmt.Printf(i18n.G("Access key: %s")+"\n", newKey.AccessKey)
+		fmt.Printf(i18n.G("Secret key: %s")+"\n", newKey.SecretKey)
+	}
+
+	return nil
+}
+
+// Key edit
+type cmdStorageBucketKeyEdit struct {
+	global        *cmdGlobal
+	storageBucket *cmdStorageBucket
+}
+
+func (c *cmdStorageBucketKeyEdit) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("edit [<remote>:]<pool> <bucket> <key>")
+	cmd.Short = i18n.G("Edit storage bucket key as YAML")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Edit storage bucket key as YAML`))
+
+	cmd.Flags().StringVar(&c.storageBucket.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdStorageBucketKeyEdit) helpTemplate() string {
+	return i18n.G(
+		`### This is a YAML representation of the storage bucket key.
+### Any line starting with a '# will be ignored.
+###
+### An example would look like:
+### name: k1
+### description: Backup access
+### role: read-only
+### access_key: 0123456789abcdef0123
+### secret_key: 0123456789abcdef0123456789abcdef01234567
+###
+### Note that the name cannot be changed.`)
+}
+
+func (c *cmdStorageBucketKeyEdit) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 3, 3)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	client, poolName, err := c.storageBucket.parseResource(args[0])
+	if err != nil {
+		return err
+	}
+
+	// If stdin isn't a terminal, read text from it
+	if !termios.IsTerminal(getStdinFd()) {
+		contents, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+
+		newdata := api.StorageBucketKeyPut{}
+		err = yaml.UnmarshalStrict(contents, &newdata)
+		if err != nil {
+			return err
+		}
+
+		return client.UpdateStoragePoolBucketKey(poolName, args[1], args[2], newdata, "")
+	}
+
+	// Get the current config.
+	key, etag, err := client.GetStoragePoolBucketKey(poolName, args[1], args[2])
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(&key)
+	if err != nil {
+		return err
+	}
+
+	// Spawn the editor
+	content, err := shared.TextEditor("", []byte(c.helpTemplate()+"\n\n"+string(data)))
+	if err != nil {
+		return err
+	}
+
+	for {
+		// Parse the text received from the editor.
+		newdata := api.StorageBucketKey{} // We show the full info, but only send the writable fields.
+		err = yaml.UnmarshalStrict(content, &newdata)
+		if err == nil {
+			err = client.UpdateStoragePoolBucketKey(poolName, args[1], key.Name, newdata.Writable(), etag)
+		}
+
+		// Respawn the editor
+		if err != nil {
+			fmt.Fprintf(os.Stderr, i18n.G("Config parsing error: %s")+"\n", err)
+			fmt.Println(i18n.G("Press enter to open the editor again"))
+
+			_, err := os.Stdin.Read(make([]byte, 1))
+			if err != nil {
+				return err
+			}
+
+			content, err = shared.TextEditor("", content)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		break
+	}
+
+	return nil
+}
+
+// Key delete
+type cmdStorageBucketKeyDelete struct {
+	global        *cmdGlobal
+	storageBucket *cmdStorageBucket
+}
+
+func (c *cmdStorageBucketKeyDelete) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("delete [<remote>:]<pool> <bucket> <key>")
+	cmd.Aliases = []string{"rm"}
+	cmd.Short = i18n.G("Delete storage bucket keys")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Delete storage bucket keys`))
+
+	cmd.Flags().StringVar(&c.storageBucket.flagTarget, "target", "", i18n.G("Cluster member name")+"``")
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdStorageBucketKeyDelete) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 3, 3)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	client, poolName, err := c.storageBucket.parseResource(args[0])
+	if err != nil {
+		return err
+	}
+
+	// Delete the storage bucket key.
+	err = client.DeleteStoragePoolBucketKey(poolName, args[1], args[2])
+	if err != nil {
+		return err
+	}
+
+	if !c.global.flagQuiet {
+		fmt.Printf(i18n.G("Storage bucket key %s removed")+"\n", args[2])
+	}
+
+	return nil
+}
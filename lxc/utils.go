@@ -6,10 +6,12 @@ import (
 	"os"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 
 	lxd "github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/i18n"
 	"github.com/lxc/lxd/shared/termios"
@@ -256,3 +258,12 @@ func getConfig(args ...string) (map[string]string, error) {
 
 	return values, nil
 }
+
+// tokenExpiry renders the expiry date of a join or certificate add token.
+func tokenExpiry(expiresAt time.Time) string {
+	if !shared.TimeIsSet(expiresAt) {
+		return i18n.G("never")
+	}
+
+	return expiresAt.UTC().Format("2006/01/02 15:04 UTC")
+}
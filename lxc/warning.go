@@ -0,0 +1,310 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+
+	"github.com/lxc/lxd/lxc/utils"
+	"github.com/lxc/lxd/shared/api"
+	cli "github.com/lxc/lxd/shared/cmd"
+	"github.com/lxc/lxd/shared/i18n"
+)
+
+type cmdWarning struct {
+	global *cmdGlobal
+}
+
+func (c *cmdWarning) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("warning")
+	cmd.Short = i18n.G("Manage warnings")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Manage warnings`))
+
+	// Acknowledge
+	warningAcknowledgeCmd := cmdWarningAcknowledge{global: c.global, warning: c}
+	cmd.AddCommand(warningAcknowledgeCmd.Command())
+
+	// Delete
+	warningDeleteCmd := cmdWarningDelete{global: c.global, warning: c}
+	cmd.AddCommand(warningDeleteCmd.Command())
+
+	// List
+	warningListCmd := cmdWarningList{global: c.global, warning: c}
+	cmd.AddCommand(warningListCmd.Command())
+
+	// Show
+	warningShowCmd := cmdWarningShow{global: c.global, warning: c}
+	cmd.AddCommand(warningShowCmd.Command())
+
+	return cmd
+}
+
+// Acknowledge
+type cmdWarningAcknowledge struct {
+	global  *cmdGlobal
+	warning *cmdWarning
+}
+
+func (c *cmdWarningAcknowledge) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("acknowledge [<remote>:]<warning-uuid>")
+	cmd.Aliases = []string{"ack"}
+	cmd.Short = i18n.G("Acknowledge warning")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Acknowledge warning
+
+Acknowledged warnings are hidden from the default warning list.`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdWarningAcknowledge) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing warning UUID"))
+	}
+
+	// Acknowledge the warning
+	err = resource.server.UpdateWarning(resource.name, api.WarningPut{Status: api.WarningStatusAcknowledged}, "")
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Delete
+type cmdWarningDelete struct {
+	global  *cmdGlobal
+	warning *cmdWarning
+
+	flagAll bool
+}
+
+func (c *cmdWarningDelete) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("delete [<remote>:]<warning-uuid>")
+	cmd.Aliases = []string{"rm"}
+	cmd.Short = i18n.G("Delete warning")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Delete warning
+
+Warnings which are still relevant will be raised again by LXD.`))
+	cmd.Flags().BoolVarP(&c.flagAll, "all", "a", false, i18n.G("Delete all warnings"))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdWarningDelete) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 0, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	remote := ""
+	if len(args) > 0 {
+		remote = args[0]
+	}
+
+	resources, err := c.global.ParseServers(remote)
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if !c.flagAll {
+		if resource.name == "" {
+			return fmt.Errorf(i18n.G("Missing warning UUID"))
+		}
+
+		return resource.server.DeleteWarning(resource.name)
+	}
+
+	if resource.name != "" {
+		return fmt.Errorf(i18n.G("No warning UUID can be specified when using --all"))
+	}
+
+	// Delete all the warnings
+	UUIDs, err := resource.server.GetWarningUUIDs()
+	if err != nil {
+		return err
+	}
+
+	for _, UUID := range UUIDs {
+		err = resource.server.DeleteWarning(UUID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// List
+type cmdWarningList struct {
+	global  *cmdGlobal
+	warning *cmdWarning
+
+	flagAll    bool
+	flagFormat string
+}
+
+func (c *cmdWarningList) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("list [<remote>:]")
+	cmd.Aliases = []string{"ls"}
+	cmd.Short = i18n.G("List warnings")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`List warnings
+
+By default, acknowledged and resolved warnings are hidden.`))
+	cmd.Flags().BoolVarP(&c.flagAll, "all", "a", false, i18n.G("List all warnings"))
+	cmd.Flags().StringVar(&c.flagFormat, "format", "table", i18n.G("Format (csv|json|table|yaml)")+"``")
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdWarningList) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 0, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	remote := ""
+	if len(args) == 1 {
+		remote = args[0]
+	}
+
+	resources, err := c.global.ParseServers(remote)
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+	if resource.name != "" {
+		return fmt.Errorf(i18n.G("Filtering isn't supported yet"))
+	}
+
+	// Get warnings
+	allWarnings, err := resource.server.GetWarnings()
+	if err != nil {
+		return err
+	}
+
+	warnings := []api.Warning{}
+	for _, w := range allWarnings {
+		if !c.flagAll && w.Status != api.WarningStatusNew {
+			continue
+		}
+
+		warnings = append(warnings, w)
+	}
+
+	// Render the table
+	data := [][]string{}
+	for _, w := range warnings {
+		entry := []string{w.UUID, w.Type, strings.ToUpper(w.Status), strings.ToUpper(w.Severity), fmt.Sprintf("%d", w.Count), w.Project, w.LastSeenAt.UTC().Format("2006/01/02 15:04 UTC")}
+		if resource.server.IsClustered() {
+			entry = append(entry, w.Location)
+		}
+
+		data = append(data, entry)
+	}
+	sort.Sort(byName(data))
+
+	header := []string{
+		i18n.G("UUID"),
+		i18n.G("TYPE"),
+		i18n.G("STATUS"),
+		i18n.G("SEVERITY"),
+		i18n.G("COUNT"),
+		i18n.G("PROJECT"),
+		i18n.G("LAST SEEN")}
+	if resource.server.IsClustered() {
+		header = append(header, i18n.G("LOCATION"))
+	}
+
+	return utils.RenderTable(c.flagFormat, header, data, warnings)
+}
+
+// Show
+type cmdWarningShow struct {
+	global  *cmdGlobal
+	warning *cmdWarning
+}
+
+func (c *cmdWarningShow) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+	cmd.Use = i18n.G("show [<remote>:]<warning-uuid>")
+	cmd.Short = i18n.G("Show warning")
+	cmd.Long = cli.FormatSection(i18n.G("Description"), i18n.G(
+		`Show warning`))
+
+	cmd.RunE = c.Run
+
+	return cmd
+}
+
+func (c *cmdWarningShow) Run(cmd *cobra.Command, args []string) error {
+	// Sanity checks
+	exit, err := c.global.CheckArgs(cmd, args, 1, 1)
+	if exit {
+		return err
+	}
+
+	// Parse remote
+	resources, err := c.global.ParseServers(args[0])
+	if err != nil {
+		return err
+	}
+
+	resource := resources[0]
+
+	if resource.name == "" {
+		return fmt.Errorf(i18n.G("Missing warning UUID"))
+	}
+
+	// Get the warning
+	warning, _, err := resource.server.GetWarning(resource.name)
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(&warning)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%s", data)
+
+	return nil
+}
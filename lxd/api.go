@@ -49,6 +49,10 @@ func restServer(d *Daemon) *http.Server {
 		d.createCmd(mux, "internal", c)
 	}
 
+	for _, c := range apiSimpleStreams {
+		d.createCmd(mux, "streams", c)
+	}
+
 	mux.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		logger.Info("Sending top level 404", log.Ctx{"url": r.URL})
 		w.Header().Set("Content-Type", "application/json")
@@ -66,6 +70,13 @@ type lxdHttpServer struct {
 func (s *lxdHttpServer) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
 	// Set CORS headers, unless this is an internal request.
 	if !strings.HasPrefix(req.URL.Path, "/internal") {
+		// While waiting for the other cluster members to be upgraded, only serve read requests.
+		resp := clusterUpgradeWaitingResponse(s.d, req)
+		if resp != nil {
+			resp.Render(rw)
+			return
+		}
+
 		<-s.d.setupChan
 		err := s.d.cluster.Transaction(func(tx *db.ClusterTx) error {
 			config, err := cluster.ConfigLoad(tx)
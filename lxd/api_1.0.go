@@ -36,8 +36,12 @@ var api10 = []APIEndpoint{
 	certificateCmd,
 	certificatesCmd,
 	clusterCmd,
+	clusterGroupCmd,
+	clusterGroupsCmd,
 	clusterNodeCmd,
+	clusterNodeStateCmd,
 	clusterNodesCmd,
+	clusterUpgradeCmd,
 	instanceBackupCmd,
 	instanceBackupExportCmd,
 	instanceBackupsCmd,
@@ -61,8 +65,15 @@ var api10 = []APIEndpoint{
 	imageRefreshCmd,
 	imagesCmd,
 	imageSecretCmd,
+	metricsCmd,
+	networkACLCmd,
+	networkACLsCmd,
 	networkCmd,
+	networkForwardCmd,
+	networkForwardsCmd,
 	networkLeasesCmd,
+	networkLoadBalancerCmd,
+	networkLoadBalancersCmd,
 	networksCmd,
 	networkStateCmd,
 	operationCmd,
@@ -74,16 +85,27 @@ var api10 = []APIEndpoint{
 	projectCmd,
 	projectsCmd,
 	storagePoolCmd,
+	storagePoolMigrateCmd,
+	storagePoolBucketsCmd,
+	storagePoolBucketCmd,
+	storagePoolBucketKeysCmd,
+	storagePoolBucketKeyCmd,
 	storagePoolResourcesCmd,
 	storagePoolsCmd,
 	storagePoolVolumesCmd,
 	storagePoolVolumeSnapshotsTypeCmd,
 	storagePoolVolumeSnapshotTypeCmd,
+	storagePoolVolumeTypeCustomBackupsCmd,
+	storagePoolVolumeTypeCustomBackupCmd,
+	storagePoolVolumeTypeCustomBackupExportCmd,
+	storagePoolVolumeTypeStateCmd,
 	storagePoolVolumesTypeCmd,
 	storagePoolVolumeTypeContainerCmd,
 	storagePoolVolumeTypeCustomCmd,
 	storagePoolVolumeTypeImageCmd,
 	storagePoolVolumeTypeVMCmd,
+	warningsCmd,
+	warningCmd,
 }
 
 func api10Get(d *Daemon, r *http.Request) response.Response {
@@ -253,6 +275,12 @@ func api10Get(d *Daemon, r *http.Request) response.Response {
 		return response.InternalError(err)
 	}
 
+	// The server configuration isn't visible to restricted clients.
+	_, restricted := d.userRestrictedProjects(r)
+	if restricted {
+		fullSrv.Config = map[string]interface{}{}
+	}
+
 	return response.SyncResponseETag(true, fullSrv, fullSrv.Config)
 }
 
@@ -561,6 +589,14 @@ func doApi10UpdateTriggers(d *Daemon, nodeChanged, clusterChanged map[string]str
 		}
 	}
 
+	value, ok = nodeChanged["core.storage_buckets_address"]
+	if ok {
+		err := d.endpoints.StorageBucketsUpdateAddress(value)
+		if err != nil {
+			return err
+		}
+	}
+
 	value, ok = nodeChanged["storage.backups_volume"]
 	if ok {
 		err := daemonStorageMove(s, "backups", value)
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"crypto/x509"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -19,10 +21,12 @@ import (
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/node"
 	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/lxd/project"
 	"github.com/lxc/lxd/lxd/response"
 	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
 	"github.com/lxc/lxd/shared/logger"
 	"github.com/lxc/lxd/shared/osarch"
 	"github.com/lxc/lxd/shared/version"
@@ -31,21 +35,22 @@ import (
 var clusterCmd = APIEndpoint{
 	Path: "cluster",
 
-	Get: APIEndpointAction{Handler: clusterGet, AccessHandler: allowAuthenticated},
+	Get: APIEndpointAction{Handler: clusterGet, AccessHandler: allowUnrestricted},
 	Put: APIEndpointAction{Handler: clusterPut},
 }
 
 var clusterNodesCmd = APIEndpoint{
 	Path: "cluster/members",
 
-	Get: APIEndpointAction{Handler: clusterNodesGet, AccessHandler: allowAuthenticated},
+	Get:  APIEndpointAction{Handler: clusterNodesGet, AccessHandler: allowUnrestricted},
+	Post: APIEndpointAction{Handler: clusterNodesPost},
 }
 
 var clusterNodeCmd = APIEndpoint{
 	Path: "cluster/members/{name}",
 
 	Delete: APIEndpointAction{Handler: clusterNodeDelete},
-	Get:    APIEndpointAction{Handler: clusterNodeGet, AccessHandler: allowAuthenticated},
+	Get:    APIEndpointAction{Handler: clusterNodeGet, AccessHandler: allowUnrestricted},
 	Patch:  APIEndpointAction{Handler: clusterNodePatch},
 	Put:    APIEndpointAction{Handler: clusterNodePut},
 	Post:   APIEndpointAction{Handler: clusterNodePost},
@@ -192,6 +197,14 @@ func clusterPut(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(err)
 	}
 
+	// A join token provides the address, certificate and secret of the cluster to join.
+	if req.ClusterToken != "" {
+		err = clusterJoinTokenApply(&req)
+		if err != nil {
+			return response.BadRequest(err)
+		}
+	}
+
 	// Sanity checks
 	if req.ServerName == "" && req.Enabled {
 		return response.BadRequest(fmt.Errorf("ServerName is required when enabling clustering"))
@@ -215,6 +228,52 @@ func clusterPut(d *Daemon, r *http.Request) response.Response {
 	return clusterPutJoin(d, req)
 }
 
+// clusterJoinTokenApply fills the cluster address, certificate and password of a join request from its join token.
+func clusterJoinTokenApply(req *api.ClusterPut) error {
+	joinToken, err := shared.JoinTokenDecode(req.ClusterToken)
+	if err != nil {
+		return err
+	}
+
+	if req.ServerName == "" {
+		req.ServerName = joinToken.ServerName
+	} else if req.ServerName != joinToken.ServerName {
+		return fmt.Errorf("Join token was issued for member %q", joinToken.ServerName)
+	}
+
+	address, cert, err := clusterJoinTokenTarget(joinToken)
+	if err != nil {
+		return err
+	}
+
+	req.Enabled = true
+	req.ClusterAddress = address
+	req.ClusterCertificate = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+	req.ClusterPassword = joinToken.Secret
+
+	return nil
+}
+
+// clusterJoinTokenTarget returns the first reachable cluster member address of the join token along with the
+// member's certificate, which must match the fingerprint of the token.
+func clusterJoinTokenTarget(joinToken *api.ClusterMemberJoinToken) (string, *x509.Certificate, error) {
+	for _, address := range joinToken.Addresses {
+		cert, err := shared.GetRemoteCertificate(fmt.Sprintf("https://%s", address), version.UserAgent)
+		if err != nil {
+			logger.Warn("Failed to connect to cluster member", log.Ctx{"address": address, "err": err})
+			continue
+		}
+
+		if shared.CertFingerprint(cert) != joinToken.Fingerprint {
+			return "", nil, fmt.Errorf("Certificate fingerprint of cluster member %q doesn't match the join token", address)
+		}
+
+		return address, cert, nil
+	}
+
+	return "", nil, fmt.Errorf("None of the cluster members of the join token could be reached")
+}
+
 func clusterPutBootstrap(d *Daemon, req api.ClusterPut) response.Response {
 	run := func(op *operations.Operation) error {
 		// Start clustering tasks
@@ -370,7 +429,7 @@ func clusterPutJoin(d *Daemon, req api.ClusterPut) response.Response {
 		// If the user has provided a cluster password, setup the trust
 		// relationship by adding our own certificate to the cluster.
 		if req.ClusterPassword != "" {
-			err = cluster.SetupTrust(string(cert.PublicKey()), req.ClusterAddress,
+			err = cluster.SetupTrust(string(cert.PublicKey()), req.ServerName, req.ClusterAddress,
 				string(req.ClusterCertificate), req.ClusterPassword)
 			if err != nil {
 				return errors.Wrap(err, "Failed to setup cluster trust")
@@ -413,13 +472,13 @@ func clusterPutJoin(d *Daemon, req api.ClusterPut) response.Response {
 		}
 
 		networks := []api.Network{}
-		networkNames, err := d.cluster.GetNetworks()
+		networkNames, err := d.cluster.GetNetworks(project.Default)
 		if err != nil && err != db.ErrNoSuchObject {
 			return err
 		}
 
 		for _, name := range networkNames {
-			_, network, err := d.cluster.GetNetworkInAnyState(name)
+			_, network, err := d.cluster.GetNetworkInAnyState(project.Default, name)
 			if err != nil {
 				return err
 			}
@@ -852,6 +911,137 @@ func clusterNodesGet(d *Daemon, r *http.Request) response.Response {
 	return response.SyncResponse(true, result)
 }
 
+// clusterNodesPost issues a single-use token allowing a new member to join the cluster without knowing the trust
+// password.
+func clusterNodesPost(d *Daemon, r *http.Request) response.Response {
+	req := api.ClusterMembersPost{}
+
+	// Parse the request
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if req.ServerName == "" {
+		return response.BadRequest(fmt.Errorf("No server name provided"))
+	}
+
+	clustered, err := cluster.Enabled(d.db)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if !clustered {
+		return response.BadRequest(fmt.Errorf("This server is not clustered"))
+	}
+
+	var expiry string
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		nodes, err := tx.GetNodes()
+		if err != nil {
+			return err
+		}
+
+		for _, node := range nodes {
+			if node.Name == req.ServerName {
+				return fmt.Errorf("The cluster already has a member with name %q", req.ServerName)
+			}
+		}
+
+		config, err := cluster.ConfigLoad(tx)
+		if err != nil {
+			return err
+		}
+
+		expiry = config.JoinTokenExpiry()
+		return nil
+	})
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	// Only one pending join token is allowed per member name.
+	ops, err := operationsGetByType(d, db.OperationClusterJoinToken)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	for _, op := range ops {
+		if op.StatusCode != api.Running {
+			continue
+		}
+
+		joinToken, err := op.ToClusterJoinToken()
+		if err == nil && joinToken.ServerName == req.ServerName {
+			return response.BadRequest(fmt.Errorf("A join token already exists for member %q", req.ServerName))
+		}
+	}
+
+	addresses, err := tokenAddresses(d)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	meta := shared.Jmap{
+		"server_name": req.ServerName,
+		"fingerprint": d.endpoints.NetworkCert().Fingerprint(),
+		"addresses":   addresses,
+	}
+
+	op, err := tokenOperationCreate(d, db.OperationClusterJoinToken, meta, expiry)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return operations.OperationResponse(op)
+}
+
+// tokenAddresses returns the addresses to include in join and certificate add tokens. These are the addresses
+// of the online members when clustered, and the addresses the API listens on otherwise.
+func tokenAddresses(d *Daemon) ([]string, error) {
+	clustered, err := cluster.Enabled(d.db)
+	if err != nil {
+		return nil, err
+	}
+
+	if !clustered {
+		address, err := node.HTTPSAddress(d.db)
+		if err != nil {
+			return nil, err
+		}
+
+		return util.ListenAddresses(address)
+	}
+
+	addresses := []string{}
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		offlineThreshold, err := tx.GetNodeOfflineThreshold()
+		if err != nil {
+			return err
+		}
+
+		nodes, err := tx.GetNodes()
+		if err != nil {
+			return err
+		}
+
+		for _, node := range nodes {
+			if node.IsOffline(offlineThreshold) {
+				continue
+			}
+
+			addresses = append(addresses, node.Address)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return addresses, nil
+}
+
 func clusterNodeGet(d *Daemon, r *http.Request) response.Response {
 	name := mux.Vars(r)["name"]
 
@@ -940,6 +1130,14 @@ func clusterNodePut(d *Daemon, r *http.Request) response.Response {
 			return errors.Wrap(err, "Update failure domain")
 		}
 
+		// A member always belongs to at least one group, so no groups means no change.
+		if len(req.Groups) > 0 {
+			err = tx.UpdateNodeClusterGroups(nodeInfo.ID, req.Groups)
+			if err != nil {
+				return errors.Wrap(err, "Update cluster groups")
+			}
+		}
+
 		return nil
 	})
 	if err != nil {
@@ -1023,15 +1221,17 @@ func clusterNodeDelete(d *Daemon, r *http.Request) response.Response {
 			return response.SmartError(err)
 		}
 
-		networks, err := d.cluster.GetNetworks()
+		networks, err := d.cluster.GetNetworksAllProjects()
 		if err != nil {
 			return response.SmartError(err)
 		}
 
-		for _, name := range networks {
-			err := client.DeleteNetwork(name)
-			if err != nil {
-				return response.SmartError(err)
+		for networkProject, networkNames := range networks {
+			for _, name := range networkNames {
+				err := client.UseProject(networkProject).DeleteNetwork(name)
+				if err != nil {
+					return response.SmartError(err)
+				}
 			}
 		}
 
@@ -1501,7 +1701,7 @@ func clusterCheckStoragePoolsMatch(cluster *db.Cluster, reqPools []api.StoragePo
 }
 
 func clusterCheckNetworksMatch(cluster *db.Cluster, reqNetworks []api.Network) error {
-	networkNames, err := cluster.GetNonPendingNetworks()
+	networkNames, err := cluster.GetNonPendingNetworks(project.Default)
 	if err != nil && err != db.ErrNoSuchObject {
 		return err
 	}
@@ -1512,7 +1712,7 @@ func clusterCheckNetworksMatch(cluster *db.Cluster, reqNetworks []api.Network) e
 				continue
 			}
 			found = true
-			_, network, err := cluster.GetNetworkInAnyState(name)
+			_, network, err := cluster.GetNetworkInAnyState(project.Default, name)
 			if err != nil {
 				return err
 			}
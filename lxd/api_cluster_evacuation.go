@@ -0,0 +1,732 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/node"
+	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/lxd/project"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/revert"
+	"github.com/lxc/lxd/lxd/task"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+var clusterNodeStateCmd = APIEndpoint{
+	Path: "cluster/members/{name}/state",
+
+	Post: APIEndpointAction{Handler: clusterNodeStatePost},
+}
+
+// Evacuate or restore a cluster member.
+func clusterNodeStatePost(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	clustered, err := cluster.Enabled(d.db)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if !clustered {
+		return response.BadRequest(fmt.Errorf("This server is not clustered"))
+	}
+
+	// Instances are moved from and to the member itself, so forward the request to it.
+	resp := forwardedResponseToNode(d, r, name)
+	if resp != nil {
+		return resp
+	}
+
+	req := api.ClusterMemberStatePost{}
+
+	// Parse the request
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	switch req.Action {
+	case "evacuate":
+		return evacuateClusterMember(d, name)
+	case "restore":
+		return restoreClusterMember(d, name)
+	}
+
+	return response.BadRequest(fmt.Errorf("Unknown action %q", req.Action))
+}
+
+// evacuateClusterMember moves all the instances off the local member, or stops them depending on their
+// cluster.evacuate policy. The member is marked as evacuated first so that no new instances get placed on it, and
+// gets its previous state back if not all instances could be moved or stopped.
+func evacuateClusterMember(d *Daemon, nodeName string) response.Response {
+	var node db.NodeInfo
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		node, err = tx.GetNodeByName(nodeName)
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if node.State == db.ClusterMemberStateEvacuated {
+		return response.BadRequest(fmt.Errorf("Cluster member is already evacuated"))
+	}
+
+	setState := func(state int) error {
+		return d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			return tx.UpdateNodeClusterState(node.ID, state)
+		})
+	}
+
+	err = setState(db.ClusterMemberStateEvacuated)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	revertState := func() {
+		err := setState(node.State)
+		if err != nil {
+			logger.Error("Failed to revert cluster member state", log.Ctx{"member": nodeName, "err": err})
+		}
+	}
+
+	run := func(op *operations.Operation) error {
+		revert := revert.New()
+		defer revert.Fail()
+		revert.Add(revertState)
+
+		instances, err := instance.LoadNodeAll(d.State(), instancetype.Any)
+		if err != nil {
+			return errors.Wrap(err, "Failed to load instances")
+		}
+
+		metadata := map[string]interface{}{}
+		for _, inst := range instances {
+			isRunning := inst.IsRunning()
+			if isRunning {
+				metadata["evacuation_progress"] = fmt.Sprintf("Stopping %q in project %q", inst.Name(), inst.Project())
+				op.UpdateMetadata(metadata)
+
+				err = evacuationStopInstance(inst)
+				if err != nil {
+					return errors.Wrapf(err, "Failed to stop instance %q in project %q", inst.Name(), inst.Project())
+				}
+			}
+
+			if evacuationMode(inst) == "stop" {
+				// Have the instance started again when the member is restored.
+				if isRunning {
+					err = inst.VolatileSet(map[string]string{"volatile.last_state.power": "RUNNING"})
+					if err != nil {
+						return err
+					}
+				}
+
+				continue
+			}
+
+			// Find a new home for the instance.
+			var targetNode string
+			err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+				p, err := tx.GetProject(inst.Project())
+				if err != nil {
+					return err
+				}
+
+				allowedGroups := project.GetRestrictedClusterGroups(p.Config)
+				targetNode, err = tx.GetNodeWithLeastInstances([]int{inst.Architecture()}, "", allowedGroups, inst.Project(), inst.LocalConfig()["cluster.application"])
+				return err
+			})
+			if err != nil {
+				return err
+			}
+
+			if targetNode == "" {
+				return fmt.Errorf("No cluster member available for instance %q in project %q", inst.Name(), inst.Project())
+			}
+
+			metadata["evacuation_progress"] = fmt.Sprintf("Migrating %q in project %q to %q", inst.Name(), inst.Project(), targetNode)
+			op.UpdateMetadata(metadata)
+
+			err = evacuationMigrateInstance(d, inst, targetNode)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to migrate instance %q in project %q", inst.Name(), inst.Project())
+			}
+
+			// Record where the instance comes from, so that it can be moved back on restore.
+			err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+				id, err := tx.GetInstanceID(inst.Project(), inst.Name())
+				if err != nil {
+					return err
+				}
+
+				return tx.UpdateInstanceConfig(int(id), map[string]string{"volatile.evacuate.origin": nodeName})
+			})
+			if err != nil {
+				return errors.Wrapf(err, "Failed to record origin of instance %q in project %q", inst.Name(), inst.Project())
+			}
+
+			if !isRunning {
+				continue
+			}
+
+			metadata["evacuation_progress"] = fmt.Sprintf("Starting %q in project %q", inst.Name(), inst.Project())
+			op.UpdateMetadata(metadata)
+
+			client, err := cluster.ConnectIfInstanceIsRemote(d.cluster, inst.Project(), inst.Name(), d.endpoints.NetworkCert(), inst.Type())
+			if err != nil {
+				return err
+			}
+
+			startOp, err := client.UseProject(inst.Project()).UpdateInstanceState(inst.Name(), api.InstanceStatePut{Action: "start", Timeout: -1}, "")
+			if err != nil {
+				return errors.Wrapf(err, "Failed to start instance %q in project %q", inst.Name(), inst.Project())
+			}
+
+			err = startOp.Wait()
+			if err != nil {
+				return errors.Wrapf(err, "Failed to start instance %q in project %q", inst.Name(), inst.Project())
+			}
+		}
+
+		revert.Success()
+		return nil
+	}
+
+	op, err := operations.OperationCreate(d.State(), "", operations.OperationClassTask, db.OperationClusterMemberEvacuate, nil, nil, run, nil, nil)
+	if err != nil {
+		revertState()
+		return response.InternalError(err)
+	}
+
+	return operations.OperationResponse(op)
+}
+
+// restoreClusterMember moves the instances which were evacuated from the local member back to it and starts
+// the local instances which were stopped during the evacuation.
+func restoreClusterMember(d *Daemon, nodeName string) response.Response {
+	var node db.NodeInfo
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		node, err = tx.GetNodeByName(nodeName)
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if node.State != db.ClusterMemberStateEvacuated {
+		return response.BadRequest(fmt.Errorf("Cluster member isn't evacuated"))
+	}
+
+	run := func(op *operations.Operation) error {
+		// The member must be usable again before the instances can be moved back to it.
+		err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			return tx.UpdateNodeClusterState(node.ID, db.ClusterMemberStateCreated)
+		})
+		if err != nil {
+			return err
+		}
+
+		var evacuated []db.Instance
+		err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			instances, err := tx.GetInstances(db.InstanceFilter{Type: instancetype.Any})
+			if err != nil {
+				return err
+			}
+
+			for _, inst := range instances {
+				if inst.Config["volatile.evacuate.origin"] == nodeName {
+					evacuated = append(evacuated, inst)
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			return errors.Wrap(err, "Failed to load evacuated instances")
+		}
+
+		metadata := map[string]interface{}{}
+		for _, dbInst := range evacuated {
+			metadata["evacuation_progress"] = fmt.Sprintf("Migrating %q in project %q back", dbInst.Name, dbInst.Project)
+			op.UpdateMetadata(metadata)
+
+			client, err := cluster.ConnectIfInstanceIsRemote(d.cluster, dbInst.Project, dbInst.Name, d.endpoints.NetworkCert(), dbInst.Type)
+			if err != nil {
+				return err
+			}
+
+			if client == nil {
+				// Already back on this member.
+				continue
+			}
+
+			client = client.UseProject(dbInst.Project)
+
+			state, _, err := client.GetInstanceState(dbInst.Name)
+			if err != nil {
+				return err
+			}
+
+			isRunning := state.StatusCode == api.Running
+			if isRunning {
+				stopOp, err := client.UpdateInstanceState(dbInst.Name, api.InstanceStatePut{Action: "stop", Timeout: -1}, "")
+				if err != nil {
+					return errors.Wrapf(err, "Failed to stop instance %q in project %q", dbInst.Name, dbInst.Project)
+				}
+
+				err = stopOp.Wait()
+				if err != nil {
+					return errors.Wrapf(err, "Failed to stop instance %q in project %q", dbInst.Name, dbInst.Project)
+				}
+			}
+
+			// Have the member currently hosting the instance move it here.
+			migrateOp, err := client.UseTarget(nodeName).MigrateInstance(dbInst.Name, api.InstancePost{Name: dbInst.Name, Migration: true})
+			if err != nil {
+				return errors.Wrapf(err, "Failed to migrate instance %q in project %q", dbInst.Name, dbInst.Project)
+			}
+
+			err = migrateOp.Wait()
+			if err != nil {
+				return errors.Wrapf(err, "Failed to migrate instance %q in project %q", dbInst.Name, dbInst.Project)
+			}
+
+			inst, err := instance.LoadByProjectAndName(d.State(), dbInst.Project, dbInst.Name)
+			if err != nil {
+				return err
+			}
+
+			err = inst.VolatileSet(map[string]string{"volatile.evacuate.origin": ""})
+			if err != nil {
+				return err
+			}
+
+			if !isRunning {
+				continue
+			}
+
+			metadata["evacuation_progress"] = fmt.Sprintf("Starting %q in project %q", inst.Name(), inst.Project())
+			op.UpdateMetadata(metadata)
+
+			err = inst.Start(false)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to start instance %q in project %q", inst.Name(), inst.Project())
+			}
+		}
+
+		// Start the local instances which were stopped during the evacuation.
+		instances, err := instance.LoadNodeAll(d.State(), instancetype.Any)
+		if err != nil {
+			return errors.Wrap(err, "Failed to load instances")
+		}
+
+		for _, inst := range instances {
+			if inst.IsRunning() || inst.LocalConfig()["volatile.last_state.power"] != "RUNNING" {
+				continue
+			}
+
+			metadata["evacuation_progress"] = fmt.Sprintf("Starting %q in project %q", inst.Name(), inst.Project())
+			op.UpdateMetadata(metadata)
+
+			err = inst.Start(false)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to start instance %q in project %q", inst.Name(), inst.Project())
+			}
+		}
+
+		return nil
+	}
+
+	op, err := operations.OperationCreate(d.State(), "", operations.OperationClassTask, db.OperationClusterMemberRestore, nil, nil, run, nil, nil)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return operations.OperationResponse(op)
+}
+
+// evacuationMode returns the effective cluster.evacuate policy of an instance.
+// With the "auto" policy, instances using devices tied to the local host are stopped and all others migrated.
+func evacuationMode(inst instance.Instance) string {
+	mode := inst.ExpandedConfig()["cluster.evacuate"]
+	if mode != "" && mode != "auto" {
+		return mode
+	}
+
+	for _, dev := range inst.ExpandedDevices() {
+		switch dev["type"] {
+		case "gpu", "infiniband", "unix-block", "unix-char", "usb":
+			return "stop"
+		case "nic":
+			if shared.StringInSlice(dev["nictype"], []string{"physical", "sriov"}) {
+				return "stop"
+			}
+		case "disk":
+			if dev["path"] != "/" && dev["pool"] == "" && dev["source"] != "" {
+				return "stop"
+			}
+		}
+	}
+
+	return "migrate"
+}
+
+// evacuationStopInstance cleanly shuts down the instance, forcefully stopping it if it doesn't shut down within
+// its boot.host_shutdown_timeout.
+func evacuationStopInstance(inst instance.Instance) error {
+	timeoutSeconds := 30
+	value, ok := inst.ExpandedConfig()["boot.host_shutdown_timeout"]
+	if ok {
+		timeoutSeconds, _ = strconv.Atoi(value)
+	}
+
+	err := inst.Shutdown(time.Duration(timeoutSeconds) * time.Second)
+	if err != nil {
+		logger.Warn("Failed to shut down instance, stopping it", log.Ctx{"project": inst.Project(), "instance": inst.Name(), "err": err})
+
+		err = inst.Stop(false)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// evacuationMigrateInstance moves a stopped local instance to the given member. Instances on ceph are relocated
+// by remapping their volume, all others are copied.
+func evacuationMigrateInstance(d *Daemon, inst instance.Instance, targetNode string) error {
+	poolName, err := inst.StoragePool()
+	if err != nil {
+		return errors.Wrap(err, "Failed to get instance's storage pool name")
+	}
+
+	_, pool, err := d.cluster.GetStoragePool(poolName)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get instance's storage pool")
+	}
+
+	if pool.Driver == "ceph" {
+		return migrateClusterInstanceWithCeph(d, inst, inst.Project(), inst.Name(), inst.Name(), targetNode, inst.Type(), false)
+	}
+
+	return migrateClusterInstance(d, inst, inst.Name(), inst.Name(), targetNode)
+}
+
+// autoHealClusterTask moves the instances of the members which have been offline for longer than
+// cluster.healing_threshold to the other members. Only the leader runs it.
+func autoHealClusterTask(d *Daemon) (task.Func, task.Schedule) {
+	f := func(ctx context.Context) {
+		// Only the leader heals offline members, so that each of them is healed once.
+		localAddress, err := node.ClusterAddress(d.db)
+		if err != nil {
+			logger.Errorf("Failed to get current node address: %v", err)
+			return
+		}
+
+		leader, err := d.gateway.LeaderAddress()
+		if err != nil {
+			logger.Errorf("Failed to get leader node address: %v", err)
+			return
+		}
+
+		if localAddress != leader {
+			return
+		}
+
+		var offlineNodes []db.NodeInfo
+		err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			config, err := cluster.ConfigLoad(tx)
+			if err != nil {
+				return err
+			}
+
+			healingThreshold := config.HealingThreshold()
+			if healingThreshold == 0 {
+				return nil
+			}
+
+			nodes, err := tx.GetNodes()
+			if err != nil {
+				return err
+			}
+
+			offlineNodes = healingOfflineMembers(nodes, config.OfflineThreshold(), healingThreshold)
+			return nil
+		})
+		if err != nil {
+			logger.Error("Failed to load offline cluster members", log.Ctx{"err": err})
+			return
+		}
+
+		for _, offlineNode := range offlineNodes {
+			nodeName := offlineNode.Name
+
+			opRun := func(op *operations.Operation) error {
+				return healClusterMember(d, op, nodeName)
+			}
+
+			op, err := operations.OperationCreate(d.State(), "", operations.OperationClassTask, db.OperationClusterMemberHeal, nil, nil, opRun, nil, nil)
+			if err != nil {
+				logger.Error("Failed to start cluster member healing operation", log.Ctx{"member": nodeName, "err": err})
+				continue
+			}
+
+			logger.Info("Healing offline cluster member", log.Ctx{"member": nodeName})
+			ch, err := op.Run()
+			if err == nil {
+				err = <-ch
+			}
+
+			if err != nil {
+				logger.Error("Failed to heal cluster member", log.Ctx{"member": nodeName, "err": err})
+				continue
+			}
+
+			logger.Info("Done healing offline cluster member", log.Ctx{"member": nodeName})
+		}
+	}
+
+	return f, task.Every(time.Minute)
+}
+
+// healingOfflineMembers returns the members which are offline for longer than both the offline and the healing
+// thresholds and haven't been evacuated yet.
+func healingOfflineMembers(nodes []db.NodeInfo, offlineThreshold time.Duration, healingThreshold time.Duration) []db.NodeInfo {
+	offlineNodes := []db.NodeInfo{}
+	for _, node := range nodes {
+		// Evacuated members were either emptied on purpose or already healed.
+		if node.State == db.ClusterMemberStateEvacuated {
+			continue
+		}
+
+		if node.IsOffline(offlineThreshold) && node.IsOffline(healingThreshold) {
+			offlineNodes = append(offlineNodes, node)
+		}
+	}
+
+	return offlineNodes
+}
+
+// healClusterMember moves the instances of an offline member which are backed by ceph to the other members and
+// starts those which were running. Once all of them are moved, the member is marked as evacuated so that its
+// instances are moved back to it when it's restored. If some can't be moved, the member is healed again on the
+// next run, starting from the instances still on it.
+func healClusterMember(d *Daemon, op *operations.Operation, nodeName string) error {
+	var node db.NodeInfo
+	var instances []db.Instance
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		node, err = tx.GetNodeByName(nodeName)
+		if err != nil {
+			return err
+		}
+
+		instances, err = tx.GetInstances(db.InstanceFilter{Node: nodeName, Type: instancetype.Any})
+		return err
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed to load instances")
+	}
+
+	// Only instances on ceph can be brought up elsewhere without their member.
+	healable := []db.Instance{}
+	for _, dbInst := range instances {
+		poolName, err := d.cluster.GetInstancePool(dbInst.Project, dbInst.Name)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to get storage pool of instance %q in project %q", dbInst.Name, dbInst.Project)
+		}
+
+		_, pool, err := d.cluster.GetStoragePool(poolName)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to get storage pool of instance %q in project %q", dbInst.Name, dbInst.Project)
+		}
+
+		if pool.Driver == "ceph" {
+			healable = append(healable, dbInst)
+		}
+	}
+
+	if len(healable) == 0 {
+		return nil
+	}
+
+	metadata := map[string]interface{}{}
+	for _, dbInst := range healable {
+		inst, err := instance.LoadByProjectAndName(d.State(), dbInst.Project, dbInst.Name)
+		if err != nil {
+			return err
+		}
+
+		var targetNode string
+		err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			p, err := tx.GetProject(inst.Project())
+			if err != nil {
+				return err
+			}
+
+			allowedGroups := project.GetRestrictedClusterGroups(p.Config)
+			targetNode, err = tx.GetNodeWithLeastInstances([]int{inst.Architecture()}, "", allowedGroups, inst.Project(), inst.LocalConfig()["cluster.application"])
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		if targetNode == "" {
+			return fmt.Errorf("No cluster member available for instance %q in project %q", inst.Name(), inst.Project())
+		}
+
+		metadata["evacuation_progress"] = fmt.Sprintf("Moving %q in project %q to %q", inst.Name(), inst.Project(), targetNode)
+		op.UpdateMetadata(metadata)
+
+		err = migrateClusterInstanceWithCeph(d, inst, inst.Project(), inst.Name(), inst.Name(), targetNode, inst.Type(), true)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to move instance %q in project %q", inst.Name(), inst.Project())
+		}
+
+		// Record where the instance comes from, so that it can be moved back on restore and stopped there if
+		// the member comes back with it still running.
+		err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			return tx.UpdateInstanceConfig(dbInst.ID, map[string]string{"volatile.evacuate.origin": nodeName})
+		})
+		if err != nil {
+			return errors.Wrapf(err, "Failed to record origin of instance %q in project %q", inst.Name(), inst.Project())
+		}
+
+		d.State().Events.SendLifecycle(inst.Project(), "instance-healed",
+			fmt.Sprintf("/1.0/instances/%s", inst.Name()), map[string]interface{}{"origin": nodeName, "target": targetNode})
+
+		if inst.LocalConfig()["volatile.last_state.power"] != "RUNNING" {
+			continue
+		}
+
+		metadata["evacuation_progress"] = fmt.Sprintf("Starting %q in project %q", inst.Name(), inst.Project())
+		op.UpdateMetadata(metadata)
+
+		err = healingStartInstance(d, inst.Project(), inst.Name(), inst.Type())
+		if err != nil {
+			return errors.Wrapf(err, "Failed to start instance %q in project %q", inst.Name(), inst.Project())
+		}
+	}
+
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		return tx.UpdateNodeClusterState(node.ID, db.ClusterMemberStateEvacuated)
+	})
+	if err != nil {
+		return err
+	}
+
+	d.State().Events.SendLifecycle("", "cluster-member-healed", fmt.Sprintf("/1.0/cluster/members/%s", nodeName), nil)
+
+	return nil
+}
+
+// healingStartInstance starts an instance which was just moved to the given member, which may be the local one.
+func healingStartInstance(d *Daemon, projectName string, name string, instanceType instancetype.Type) error {
+	client, err := cluster.ConnectIfInstanceIsRemote(d.cluster, projectName, name, d.endpoints.NetworkCert(), instanceType)
+	if err != nil {
+		return err
+	}
+
+	if client == nil {
+		inst, err := instance.LoadByProjectAndName(d.State(), projectName, name)
+		if err != nil {
+			return err
+		}
+
+		return inst.Start(false)
+	}
+
+	startOp, err := client.UseProject(projectName).UpdateInstanceState(name, api.InstanceStatePut{Action: "start", Timeout: -1}, "")
+	if err != nil {
+		return err
+	}
+
+	return startOp.Wait()
+}
+
+// clusterHealingFence stops the local copies of the instances which were moved to other members while heartbeats
+// didn't reach this member for the given amount of time, so that they don't keep running on two members at once.
+func clusterHealingFence(d *Daemon, gap time.Duration) error {
+	var localName string
+	var healed []db.Instance
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		offlineThreshold, err := tx.GetNodeOfflineThreshold()
+		if err != nil {
+			return err
+		}
+
+		// The leader doesn't heal members which didn't even go offline.
+		if gap <= offlineThreshold {
+			return nil
+		}
+
+		localName, err = tx.GetLocalNodeName()
+		if err != nil {
+			return err
+		}
+
+		instances, err := tx.GetInstances(db.InstanceFilter{Type: instancetype.Any})
+		if err != nil {
+			return err
+		}
+
+		healed = healingMovedInstances(instances, localName)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, dbInst := range healed {
+		inst, err := instance.LoadByProjectAndName(d.State(), dbInst.Project, dbInst.Name)
+		if err != nil {
+			return err
+		}
+
+		if !inst.IsRunning() {
+			continue
+		}
+
+		logger.Warn("Stopping instance moved to another cluster member while this one was unreachable", log.Ctx{"project": inst.Project(), "instance": inst.Name(), "member": dbInst.Node})
+
+		// The instance record belongs to the member now running it, so this only releases the local resources.
+		err = inst.Stop(false)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to stop instance %q in project %q", inst.Name(), inst.Project())
+		}
+	}
+
+	return nil
+}
+
+// healingMovedInstances returns the instances which were moved away from the given member while it was offline.
+func healingMovedInstances(instances []db.Instance, nodeName string) []db.Instance {
+	moved := []db.Instance{}
+	for _, inst := range instances {
+		if inst.Node != nodeName && inst.Config["volatile.evacuate.origin"] == nodeName {
+			moved = append(moved, inst)
+		}
+	}
+
+	return moved
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/version"
+)
+
+var clusterGroupsCmd = APIEndpoint{
+	Path: "cluster/groups",
+
+	Get:  APIEndpointAction{Handler: clusterGroupsGet, AccessHandler: allowUnrestricted},
+	Post: APIEndpointAction{Handler: clusterGroupsPost},
+}
+
+var clusterGroupCmd = APIEndpoint{
+	Path: "cluster/groups/{name}",
+
+	Delete: APIEndpointAction{Handler: clusterGroupDelete},
+	Get:    APIEndpointAction{Handler: clusterGroupGet, AccessHandler: allowUnrestricted},
+	Patch:  APIEndpointAction{Handler: clusterGroupPatch},
+	Post:   APIEndpointAction{Handler: clusterGroupPost},
+	Put:    APIEndpointAction{Handler: clusterGroupPut},
+}
+
+// List the cluster groups.
+func clusterGroupsGet(d *Daemon, r *http.Request) response.Response {
+	recursion := util.IsRecursionRequest(r)
+
+	var groups []*api.ClusterGroup
+	var names []string
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		names, err = tx.GetClusterGroupNames()
+		if err != nil {
+			return err
+		}
+
+		if !recursion {
+			return nil
+		}
+
+		for _, name := range names {
+			group, err := tx.GetClusterGroup(name)
+			if err != nil {
+				return errors.Wrapf(err, "Failed loading cluster group %q", name)
+			}
+
+			groups = append(groups, group)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if recursion {
+		return response.SyncResponse(true, groups)
+	}
+
+	urls := []string{}
+	for _, name := range names {
+		urls = append(urls, fmt.Sprintf("/%s/cluster/groups/%s", version.APIVersion, name))
+	}
+
+	return response.SyncResponse(true, urls)
+}
+
+// Create a new cluster group.
+func clusterGroupsPost(d *Daemon, r *http.Request) response.Response {
+	req := api.ClusterGroupsPost{}
+
+	// Parse the request
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	err = clusterGroupValidateName(req.Name)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	clustered, err := cluster.Enabled(d.db)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if !clustered {
+		return response.BadRequest(fmt.Errorf("This server is not clustered"))
+	}
+
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		names, err := tx.GetClusterGroupNames()
+		if err != nil {
+			return err
+		}
+
+		if shared.StringInSlice(req.Name, names) {
+			return fmt.Errorf("Cluster group %q already exists", req.Name)
+		}
+
+		_, err = tx.CreateClusterGroup(req.Name, req.Description)
+		if err != nil {
+			return err
+		}
+
+		return tx.UpdateClusterGroupMembers(req.Name, req.Members)
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponseLocation(true, nil, fmt.Sprintf("/%s/cluster/groups/%s", version.APIVersion, req.Name))
+}
+
+// Get a cluster group.
+func clusterGroupGet(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	var group *api.ClusterGroup
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		group, err = tx.GetClusterGroup(name)
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponseETag(true, group, group.Writable())
+}
+
+// Rename a cluster group.
+func clusterGroupPost(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	if name == "default" {
+		return response.Forbidden(fmt.Errorf("The default cluster group cannot be renamed"))
+	}
+
+	req := api.ClusterGroupPost{}
+
+	// Parse the request
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	err = clusterGroupValidateName(req.Name)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		names, err := tx.GetClusterGroupNames()
+		if err != nil {
+			return err
+		}
+
+		if shared.StringInSlice(req.Name, names) {
+			return fmt.Errorf("Cluster group %q already exists", req.Name)
+		}
+
+		return tx.RenameClusterGroup(name, req.Name)
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponseLocation(true, nil, fmt.Sprintf("/%s/cluster/groups/%s", version.APIVersion, req.Name))
+}
+
+// Replace the description and members of a cluster group.
+func clusterGroupPut(d *Daemon, r *http.Request) response.Response {
+	return clusterGroupUpdate(d, r, false)
+}
+
+// Update the description and members of a cluster group, keeping the fields missing from the request.
+func clusterGroupPatch(d *Daemon, r *http.Request) response.Response {
+	return clusterGroupUpdate(d, r, true)
+}
+
+func clusterGroupUpdate(d *Daemon, r *http.Request, patch bool) response.Response {
+	name := mux.Vars(r)["name"]
+
+	var group *api.ClusterGroup
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		group, err = tx.GetClusterGroup(name)
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Validate the ETag
+	err = util.EtagCheck(r, group.Writable())
+	if err != nil {
+		return response.PreconditionFailed(err)
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	rdr1 := ioutil.NopCloser(bytes.NewBuffer(body))
+	rdr2 := ioutil.NopCloser(bytes.NewBuffer(body))
+
+	reqRaw := shared.Jmap{}
+	err = json.NewDecoder(rdr1).Decode(&reqRaw)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	req := api.ClusterGroupPut{}
+	err = json.NewDecoder(rdr2).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	// Check what was actually set in the query
+	if patch {
+		_, err = reqRaw.GetString("description")
+		if err != nil {
+			req.Description = group.Description
+		}
+
+		_, ok := reqRaw["members"]
+		if !ok {
+			req.Members = group.Members
+		}
+	}
+
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		err := clusterGroupCheckRemovedMembers(tx, name, group.Members, req.Members)
+		if err != nil {
+			return err
+		}
+
+		err = tx.UpdateClusterGroup(name, req.Description)
+		if err != nil {
+			return err
+		}
+
+		return tx.UpdateClusterGroupMembers(name, req.Members)
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// Delete a cluster group.
+func clusterGroupDelete(d *Daemon, r *http.Request) response.Response {
+	name := mux.Vars(r)["name"]
+
+	if name == "default" {
+		return response.Forbidden(fmt.Errorf("The default cluster group cannot be deleted"))
+	}
+
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		group, err := tx.GetClusterGroup(name)
+		if err != nil {
+			return err
+		}
+
+		if len(group.Members) > 0 {
+			return fmt.Errorf("Only empty cluster groups can be deleted")
+		}
+
+		return tx.DeleteClusterGroup(name)
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// clusterGroupValidateName checks the name of a new cluster group.
+func clusterGroupValidateName(name string) error {
+	if name == "" {
+		return fmt.Errorf("No name provided")
+	}
+
+	if strings.ContainsAny(name, "/ ") {
+		return fmt.Errorf("Cluster group names may not contain slashes or spaces")
+	}
+
+	if strings.HasPrefix(name, "@") {
+		return fmt.Errorf("Cluster group names may not start with %q", "@")
+	}
+
+	return nil
+}
+
+// clusterGroupCheckRemovedMembers checks that the members removed from a cluster group still belong to at least
+// one other group.
+func clusterGroupCheckRemovedMembers(tx *db.ClusterTx, name string, members []string, newMembers []string) error {
+	for _, member := range members {
+		if shared.StringInSlice(member, newMembers) {
+			continue
+		}
+
+		node, err := tx.GetNodeByName(member)
+		if err != nil {
+			return errors.Wrapf(err, "Failed loading cluster member %q", member)
+		}
+
+		groups, err := tx.GetNodeClusterGroups(node.ID)
+		if err != nil {
+			return err
+		}
+
+		if len(groups) == 1 && groups[0] == name {
+			return fmt.Errorf("Cluster member %q must belong to at least one cluster group", member)
+		}
+	}
+
+	return nil
+}
@@ -1,10 +1,14 @@
 package main
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/base64"
 	"encoding/pem"
 	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
 	"time"
@@ -685,3 +689,149 @@ func (f *clusterFixture) Leader() *Daemon {
 	f.t.Fatal("failed to get leader address")
 	return nil
 }
+
+// Cluster members are drained for upgrade one at a time, and never the last
+// one.
+func TestClusterUpgradeCheckDrain(t *testing.T) {
+	upgrade := &api.ClusterUpgrade{
+		InProgress: true,
+		Members: []api.ClusterUpgradeMember{
+			{ServerName: "node1", Status: "Evacuated", Upgraded: true},
+			{ServerName: "node2", Status: "Online"},
+			{ServerName: "node3", Status: "Online"},
+		},
+	}
+
+	assert.NoError(t, clusterUpgradeCheckDrain(upgrade, "node2"))
+	assert.EqualError(t, clusterUpgradeCheckDrain(upgrade, "node1"), `Cluster member "node1" is already upgraded`)
+	assert.EqualError(t, clusterUpgradeCheckDrain(upgrade, "node4"), `Cluster member "node4" not found`)
+
+	upgrade.Members[1].Status = "Evacuated"
+	assert.EqualError(t, clusterUpgradeCheckDrain(upgrade, "node3"), `Cluster member "node2" is still being upgraded`)
+
+	upgrade.Members[1].Upgraded = true
+	assert.EqualError(t, clusterUpgradeCheckDrain(upgrade, "node3"), `No other cluster member can take over the instances of "node3", upgrade it without draining it`)
+}
+
+// The upgrade status can't be computed without any member, e.g. while all of them are still pending.
+func TestClusterUpgradeStatus(t *testing.T) {
+	_, err := clusterUpgradeStatus(nil, time.Minute)
+	assert.EqualError(t, err, "No cluster member found")
+
+	nodes := []db.NodeInfo{
+		{Name: "node1", Heartbeat: time.Now(), Schema: 1, APIExtensions: 2},
+		{Name: "node2", Heartbeat: time.Now(), Schema: 1, APIExtensions: 1},
+	}
+
+	upgrade, err := clusterUpgradeStatus(nodes, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, upgrade.InProgress)
+	require.Len(t, upgrade.Members, 2)
+	assert.True(t, upgrade.Members[0].Upgraded)
+	assert.False(t, upgrade.Members[1].Upgraded)
+}
+
+// Only members offline for longer than both thresholds and not evacuated yet
+// get healed.
+func TestHealingOfflineMembers(t *testing.T) {
+	now := time.Now()
+	nodes := []db.NodeInfo{
+		{Name: "online", Heartbeat: now},
+		{Name: "offline", Heartbeat: now.Add(-time.Minute)},
+		{Name: "unhealthy", Heartbeat: now.Add(-10 * time.Minute)},
+		{Name: "healed", Heartbeat: now.Add(-10 * time.Minute), State: db.ClusterMemberStateEvacuated},
+	}
+
+	names := func(nodes []db.NodeInfo) []string {
+		result := []string{}
+		for _, node := range nodes {
+			result = append(result, node.Name)
+		}
+
+		return result
+	}
+
+	assert.Equal(t, []string{"unhealthy"}, names(healingOfflineMembers(nodes, 20*time.Second, 5*time.Minute)))
+	assert.Equal(t, []string{"offline", "unhealthy"}, names(healingOfflineMembers(nodes, 20*time.Second, 30*time.Second)))
+
+	// The healing threshold doesn't make members offline sooner.
+	assert.Equal(t, []string{"unhealthy"}, names(healingOfflineMembers(nodes, 5*time.Minute, 30*time.Second)))
+}
+
+// A member coming back only stops the instances which were moved away from it.
+func TestHealingMovedInstances(t *testing.T) {
+	instances := []db.Instance{
+		{Name: "moved", Node: "node2", Config: map[string]string{"volatile.evacuate.origin": "node1"}},
+		{Name: "local", Node: "node1", Config: map[string]string{}},
+		{Name: "restored", Node: "node1", Config: map[string]string{"volatile.evacuate.origin": "node1"}},
+		{Name: "other", Node: "node3", Config: map[string]string{"volatile.evacuate.origin": "node2"}},
+	}
+
+	moved := healingMovedInstances(instances, "node1")
+	require.Len(t, moved, 1)
+	assert.Equal(t, "moved", moved[0].Name)
+
+	assert.Empty(t, healingMovedInstances(instances, "node4"))
+}
+
+// A member waiting for the others to be upgraded only serves read requests,
+// from local clients or from network clients with full access.
+func TestClusterUpgradeWaitingCheck(t *testing.T) {
+	newCert := func() (string, *x509.Certificate) {
+		certPEM, _, err := shared.GenerateMemCert(true, false)
+		require.NoError(t, err)
+
+		certBlock, _ := pem.Decode(certPEM)
+		require.NotNil(t, certBlock)
+
+		cert, err := x509.ParseCertificate(certBlock.Bytes)
+		require.NoError(t, err)
+
+		return string(certPEM), cert
+	}
+
+	trustedPEM, trusted := newCert()
+	restrictedPEM, restricted := newCert()
+	metricsPEM, metrics := newCert()
+	_, untrusted := newCert()
+
+	certs := clusterUpgradeWaitingCerts([]db.Certificate{
+		{Fingerprint: shared.CertFingerprint(trusted), Type: db.CertificateTypeClient, Certificate: trustedPEM},
+		{Fingerprint: shared.CertFingerprint(restricted), Type: db.CertificateTypeClient, Certificate: restrictedPEM, Restricted: true},
+		{Fingerprint: shared.CertFingerprint(metrics), Type: db.CertificateTypeMetrics, Certificate: metricsPEM},
+		{Fingerprint: "invalid", Type: db.CertificateTypeClient, Certificate: "invalid"},
+	})
+	require.Len(t, certs, 1)
+
+	newRequest := func(method string, cert *x509.Certificate) *http.Request {
+		r := httptest.NewRequest(method, "/1.0/instances", nil)
+		if cert == nil {
+			r.RemoteAddr = "@"
+		} else {
+			r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+		}
+
+		return r
+	}
+
+	readOnly := "This cluster member is read-only until all cluster members are upgraded"
+	otherMember := "This cluster member is waiting for the other members to be upgraded, use another member"
+
+	for _, cert := range []*x509.Certificate{nil, trusted} {
+		assert.NoError(t, clusterUpgradeWaitingCheck(newRequest("GET", cert), certs, nil, false))
+		assert.NoError(t, clusterUpgradeWaitingCheck(newRequest("HEAD", cert), certs, nil, false))
+
+		for _, method := range []string{"POST", "PUT", "PATCH", "DELETE"} {
+			assert.EqualError(t, clusterUpgradeWaitingCheck(newRequest(method, cert), certs, nil, false), readOnly)
+		}
+	}
+
+	// Requests are forwarded with the cluster certificate, so clients with restricted access aren't served.
+	for _, cert := range []*x509.Certificate{restricted, metrics, untrusted} {
+		assert.EqualError(t, clusterUpgradeWaitingCheck(newRequest("GET", cert), certs, nil, false), otherMember)
+	}
+
+	r := newRequest("GET", trusted)
+	r.TLS = nil
+	assert.EqualError(t, clusterUpgradeWaitingCheck(r, certs, nil, false), otherMember)
+}
@@ -0,0 +1,347 @@
+package main
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+var clusterUpgradeCmd = APIEndpoint{
+	Path: "cluster/upgrade",
+
+	Get:  APIEndpointAction{Handler: clusterUpgradeGet, AccessHandler: allowUnrestricted},
+	Post: APIEndpointAction{Handler: clusterUpgradePost},
+}
+
+// Report the version of each cluster member during a rolling upgrade.
+func clusterUpgradeGet(d *Daemon, r *http.Request) response.Response {
+	clustered, err := cluster.Enabled(d.db)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if !clustered {
+		return response.BadRequest(fmt.Errorf("This server is not clustered"))
+	}
+
+	upgrade, _, err := clusterUpgradeLoad(d.cluster)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, upgrade)
+}
+
+// Drain a cluster member before upgrading it, making sure that members get upgraded one at a time.
+func clusterUpgradePost(d *Daemon, r *http.Request) response.Response {
+	clustered, err := cluster.Enabled(d.db)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if !clustered {
+		return response.BadRequest(fmt.Errorf("This server is not clustered"))
+	}
+
+	// Keep the body around, since the request is forwarded to the member being drained.
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	req := api.ClusterUpgradePost{}
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if req.Member == "" {
+		return response.BadRequest(fmt.Errorf("No cluster member provided"))
+	}
+
+	upgrade, _, err := clusterUpgradeLoad(d.cluster)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = clusterUpgradeCheckDrain(upgrade, req.Member)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	// Instances are moved off the member by the member itself.
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	resp := forwardedResponseToNode(d, r, req.Member)
+	if resp != nil {
+		return resp
+	}
+
+	return evacuateClusterMember(d, req.Member)
+}
+
+// clusterUpgradeLoad returns the upgrade status of the cluster along with its members, in the same order.
+func clusterUpgradeLoad(clusterDB *db.Cluster) (*api.ClusterUpgrade, []db.NodeInfo, error) {
+	var nodes []db.NodeInfo
+	var offlineThreshold time.Duration
+	err := clusterDB.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		nodes, err = tx.GetNodes()
+		if err != nil {
+			return err
+		}
+
+		offlineThreshold, err = tx.GetNodeOfflineThreshold()
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	upgrade, err := clusterUpgradeStatus(nodes, offlineThreshold)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return upgrade, nodes, nil
+}
+
+// clusterUpgradeStatus returns the upgrade status of the cluster made of the given members, in the same order.
+func clusterUpgradeStatus(nodes []db.NodeInfo, offlineThreshold time.Duration) (*api.ClusterUpgrade, error) {
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("No cluster member found")
+	}
+
+	// Find the most recent version in the cluster.
+	version := nodes[0].Version()
+	for _, node := range nodes {
+		n, err := util.CompareVersions(version, node.Version())
+		if err != nil {
+			return nil, err
+		}
+
+		if n == 2 {
+			version = node.Version()
+		}
+	}
+
+	upgrade := &api.ClusterUpgrade{
+		Members: make([]api.ClusterUpgradeMember, len(nodes)),
+	}
+
+	for i, node := range nodes {
+		if node.Version() != version {
+			upgrade.InProgress = true
+		}
+
+		upgrade.Members[i] = api.ClusterUpgradeMember{
+			ServerName:    node.Name,
+			Schema:        node.Schema,
+			APIExtensions: node.APIExtensions,
+			Status:        "Online",
+		}
+
+		if node.IsOffline(offlineThreshold) {
+			upgrade.Members[i].Status = "Offline"
+		} else if node.State == db.ClusterMemberStateEvacuated {
+			upgrade.Members[i].Status = "Evacuated"
+		}
+	}
+
+	// Members only count as upgraded while others still run the previous version.
+	if upgrade.InProgress {
+		for i, node := range nodes {
+			upgrade.Members[i].Upgraded = node.Version() == version
+			if upgrade.Members[i].Upgraded && upgrade.Members[i].Status == "Online" {
+				upgrade.Members[i].Status = "Blocked"
+			}
+		}
+	}
+
+	return upgrade, nil
+}
+
+// clusterUpgradeCheckDrain checks that the given member can be drained to be upgraded next.
+func clusterUpgradeCheckDrain(upgrade *api.ClusterUpgrade, name string) error {
+	var member *api.ClusterUpgradeMember
+	available := 0
+	for i := range upgrade.Members {
+		other := &upgrade.Members[i]
+		if other.ServerName == name {
+			member = other
+			continue
+		}
+
+		if other.Status == "Evacuated" && !other.Upgraded {
+			return fmt.Errorf("Cluster member %q is still being upgraded", other.ServerName)
+		}
+
+		if other.Status == "Online" {
+			available++
+		}
+	}
+
+	if member == nil {
+		return fmt.Errorf("Cluster member %q not found", name)
+	}
+
+	if member.Upgraded {
+		return fmt.Errorf("Cluster member %q is already upgraded", name)
+	}
+
+	if member.Status != "Online" {
+		return fmt.Errorf("Cluster member %q is %s", name, member.Status)
+	}
+
+	if available == 0 {
+		return fmt.Errorf("No other cluster member can take over the instances of %q, upgrade it without draining it", name)
+	}
+
+	return nil
+}
+
+// clusterUpgradeWaitingResponse serves the requests received while this member waits for the other members to be
+// upgraded. Read requests are forwarded to a member still running the previous version and all others are refused.
+// It returns nil if the request should wait for this member to be ready instead.
+func clusterUpgradeWaitingResponse(d *Daemon, r *http.Request) response.Response {
+	d.upgradeWaitingLock.Lock()
+	clusterDB := d.upgradeWaitingCluster
+	d.upgradeWaitingLock.Unlock()
+
+	// Requests from the other members wait as usual.
+	if clusterDB == nil || d.isClusterRequest(r) {
+		return nil
+	}
+
+	// The trusted certificates aren't loaded yet, get them from the database. Its schema is still the one of the
+	// previous version, so only what all schema versions have is queried.
+	var certs map[string]x509.Certificate
+	var trustCACertificates bool
+	if r.RemoteAddr != "@" {
+		var dbCerts []db.Certificate
+		err := clusterDB.Transaction(func(tx *db.ClusterTx) error {
+			var err error
+			dbCerts, err = tx.GetUnrestrictedClientCertificates()
+			return err
+		})
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		certs = clusterUpgradeWaitingCerts(dbCerts)
+
+		trustCACertificates, err = cluster.ConfigGetBool(clusterDB, "core.trust_ca_certificates")
+		if err != nil {
+			return response.SmartError(err)
+		}
+	}
+
+	err := clusterUpgradeWaitingCheck(r, certs, d.endpoints.NetworkCert(), trustCACertificates)
+	if err != nil {
+		return response.Unavailable(err)
+	}
+
+	var nodes []db.NodeInfo
+	var offlineThreshold time.Duration
+	err = clusterDB.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		nodes, err = tx.GetNodesVersions()
+		if err != nil {
+			return err
+		}
+
+		offlineThreshold, err = tx.GetNodeOfflineThreshold()
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	upgrade, err := clusterUpgradeStatus(nodes, offlineThreshold)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	address := ""
+	for i, member := range upgrade.Members {
+		if !member.Upgraded && member.Status != "Offline" {
+			address = nodes[i].Address
+			break
+		}
+	}
+
+	if address == "" {
+		return response.Unavailable(fmt.Errorf("No cluster member running the previous version is available"))
+	}
+
+	client, err := cluster.Connect(address, d.endpoints.NetworkCert(), false)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.ForwardedResponse(client, r)
+}
+
+// clusterUpgradeWaitingCheck checks that a request can be served while this member waits for the other members to
+// be upgraded. Only read requests are served, from local clients or from network clients trusted with the given
+// certificates. As the requests are forwarded using the cluster certificate, those must give full access.
+func clusterUpgradeWaitingCheck(r *http.Request, certs map[string]x509.Certificate, networkCert *shared.CertInfo, trustCACertificates bool) error {
+	if !shared.StringInSlice(r.Method, []string{"GET", "HEAD"}) {
+		return fmt.Errorf("This cluster member is read-only until all cluster members are upgraded")
+	}
+
+	if r.RemoteAddr == "@" {
+		return nil
+	}
+
+	if r.TLS != nil {
+		for i := range r.TLS.PeerCertificates {
+			trusted, _ := util.CheckTrustState(*r.TLS.PeerCertificates[i], certs, networkCert, trustCACertificates)
+			if trusted {
+				return nil
+			}
+		}
+	}
+
+	return fmt.Errorf("This cluster member is waiting for the other members to be upgraded, use another member")
+}
+
+// clusterUpgradeWaitingCerts returns the client certificates which aren't restricted to some projects, indexed by
+// fingerprint.
+func clusterUpgradeWaitingCerts(dbCerts []db.Certificate) map[string]x509.Certificate {
+	certs := map[string]x509.Certificate{}
+	for _, dbCert := range dbCerts {
+		if dbCert.Type != db.CertificateTypeClient || dbCert.Restricted {
+			continue
+		}
+
+		certBlock, _ := pem.Decode([]byte(dbCert.Certificate))
+		if certBlock == nil {
+			logger.Warn("Failed decoding certificate", log.Ctx{"name": dbCert.Name})
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(certBlock.Bytes)
+		if err != nil {
+			logger.Warn("Failed parsing certificate", log.Ctx{"name": dbCert.Name, "err": err})
+			continue
+		}
+
+		certs[dbCert.Fingerprint] = *cert
+	}
+
+	return certs
+}
@@ -46,6 +46,7 @@ var apiInternal = []APIEndpoint{
 	internalClusterRebalanceCmd,
 	internalClusterAssignCmd,
 	internalClusterContainerMovedCmd,
+	internalClusterInstanceMoveCmd,
 	internalGarbageCollectorCmd,
 	internalRAFTSnapshotCmd,
 	internalClusterHandoverCmd,
@@ -22,7 +22,8 @@ import (
 	"github.com/lxc/lxd/shared/version"
 )
 
-var projectFeatures = []string{"features.images", "features.profiles", "features.storage.volumes"}
+var projectFeatures = []string{"features.images", "features.profiles", "features.storage.volumes", "features.networks"}
+var projectFeaturesDefaults = []string{"features.images", "features.profiles", "features.storage.volumes"}
 
 var projectsCmd = APIEndpoint{
 	Path: "projects",
@@ -100,7 +101,7 @@ func projectsPost(d *Daemon, r *http.Request) response.Response {
 	if project.Config == nil {
 		project.Config = map[string]string{}
 	}
-	for _, feature := range projectFeatures {
+	for _, feature := range projectFeaturesDefaults {
 		_, ok := project.Config[feature]
 		if !ok {
 			project.Config[feature] = "true"
@@ -126,6 +127,11 @@ func projectsPost(d *Daemon, r *http.Request) response.Response {
 
 	var id int64
 	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		err = projecthelpers.CheckNetworkRestrictions(tx, project.Name, project.Config)
+		if err != nil {
+			return err
+		}
+
 		id, err = tx.CreateProject(project)
 		if err != nil {
 			return errors.Wrap(err, "Add project to database")
@@ -526,6 +532,7 @@ var projectConfigKeys = map[string]func(value string) error{
 	"features.profiles":              validate.Optional(validate.IsBool),
 	"features.images":                validate.Optional(validate.IsBool),
 	"features.storage.volumes":       validate.Optional(validate.IsBool),
+	"features.networks":              validate.Optional(validate.IsBool),
 	"limits.containers":              validate.Optional(validate.IsUint32),
 	"limits.virtual-machines":        validate.Optional(validate.IsUint32),
 	"limits.memory":                  validate.Optional(validate.IsSize),
@@ -547,6 +554,9 @@ var projectConfigKeys = map[string]func(value string) error{
 	"restricted.devices.usb":               isEitherAllowOrBlock,
 	"restricted.devices.nic":               isEitherAllowOrBlockOrManaged,
 	"restricted.devices.disk":              isEitherAllowOrBlockOrManaged,
+	"restricted.networks.uplinks":          validate.IsAny,
+	"restricted.networks.subnets":          validate.Optional(projecthelpers.ValidateNetworkSubnets),
+	"restricted.cluster.groups":            validate.IsAny,
 }
 
 func projectValidateConfig(config map[string]string) error {
@@ -3,13 +3,19 @@ package main
 import (
 	"bytes"
 	"fmt"
+	"hash/fnv"
 	"io"
+	"io/ioutil"
+	"math/rand"
 	"os"
+	"path/filepath"
 	"time"
 
 	"context"
 
+	securejoin "github.com/cyphar/filepath-securejoin"
 	"github.com/pkg/errors"
+	"gopkg.in/robfig/cron.v2"
 	"gopkg.in/yaml.v2"
 
 	"github.com/lxc/lxd/lxd/backup"
@@ -22,6 +28,7 @@ import (
 	"github.com/lxc/lxd/lxd/revert"
 	"github.com/lxc/lxd/lxd/state"
 	storagePools "github.com/lxc/lxd/lxd/storage"
+	storageDrivers "github.com/lxc/lxd/lxd/storage/drivers"
 	"github.com/lxc/lxd/lxd/task"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
@@ -52,6 +59,19 @@ func backupCreate(s *state.State, args db.InstanceBackup, sourceInst instance.In
 		args.OptimizedStorage = false
 	}
 
+	// Find the snapshot an incremental backup is based on.
+	parentSnapshot := ""
+	if args.Parent != "" {
+		if !args.OptimizedStorage || args.InstanceOnly {
+			return fmt.Errorf("Incremental backups require optimized storage and must include snapshots")
+		}
+
+		parentSnapshot, err = backupParentSnapshot(s, sourceInst, args.Parent)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Create the database entry.
 	err = s.Cluster.CreateInstanceBackup(args)
 	if err != nil {
@@ -141,7 +161,7 @@ func backupCreate(s *state.State, args db.InstanceBackup, sourceInst instance.In
 
 	// Write index file.
 	logger.Debug("Adding backup index file")
-	err = backupWriteIndex(sourceInst, pool, b.OptimizedStorage(), !b.InstanceOnly(), tarWriter)
+	err = backupWriteIndex(sourceInst, pool, b.OptimizedStorage(), !b.InstanceOnly(), b.Parent(), parentSnapshot, tarWriter)
 
 	// Check compression errors.
 	if compressErr != nil {
@@ -153,7 +173,7 @@ func backupCreate(s *state.State, args db.InstanceBackup, sourceInst instance.In
 		return errors.Wrapf(err, "Error writing backup index file")
 	}
 
-	err = pool.BackupInstance(sourceInst, tarWriter, b.OptimizedStorage(), !b.InstanceOnly(), nil)
+	err = pool.BackupInstance(sourceInst, tarWriter, b.OptimizedStorage(), !b.InstanceOnly(), parentSnapshot, nil)
 	if err != nil {
 		return errors.Wrap(err, "Backup create")
 	}
@@ -175,12 +195,60 @@ func backupCreate(s *state.State, args db.InstanceBackup, sourceInst instance.In
 		return errors.Wrap(err, "Error writing tarball")
 	}
 
+	_, backupName, _ := shared.InstanceGetParentAndSnapshotName(b.Name())
+	s.Events.SendLifecycle(sourceInst.Project(), "instance-backup-created",
+		fmt.Sprintf("/1.0/instances/%s/backups/%s", sourceInst.Name(), backupName), nil)
+
 	revert.Success()
 	return nil
 }
 
+// backupParentSnapshot returns the name of the snapshot an incremental backup based on the given parent backup
+// is relative to. This is the most recent snapshot contained in the parent backup chain.
+func backupParentSnapshot(s *state.State, sourceInst instance.Instance, parentName string) (string, error) {
+	parent, err := instance.BackupLoadByName(s, sourceInst.Project(), parentName)
+	if err != nil {
+		return "", errors.Wrapf(err, "Load parent backup %q", parentName)
+	}
+
+	if !parent.OptimizedStorage() || parent.InstanceOnly() {
+		return "", fmt.Errorf("Parent backup %q must be an optimized backup including snapshots", parentName)
+	}
+
+	// Read the index of the parent backup tarball.
+	parentPath := shared.VarPath("backups", project.Instance(sourceInst.Project(), parentName))
+	f, err := os.Open(parentPath)
+	if err != nil {
+		return "", errors.Wrapf(err, "Error opening parent backup tarball %q", parentPath)
+	}
+	defer f.Close()
+
+	parentInfo, err := backup.GetInfo(f)
+	if err != nil {
+		return "", errors.Wrapf(err, "Error reading parent backup %q", parentName)
+	}
+
+	snapName := parentInfo.ParentSnapshot
+	if len(parentInfo.Snapshots) > 0 {
+		snapName = parentInfo.Snapshots[len(parentInfo.Snapshots)-1]
+	}
+
+	if snapName == "" {
+		return "", fmt.Errorf("Parent backup %q doesn't contain any snapshot to base an incremental backup on", parentName)
+	}
+
+	// The snapshot is needed to generate the deltas from.
+	_, err = instance.LoadByProjectAndName(s, sourceInst.Project(), fmt.Sprintf("%s%s%s", sourceInst.Name(), shared.SnapshotDelimiter, snapName))
+	if err != nil {
+		return "", errors.Wrapf(err, "Load snapshot %q the parent backup is based on", snapName)
+	}
+
+	return snapName, nil
+}
+
 // backupWriteIndex generates an index.yaml file and then writes it to the root of the backup tarball.
-func backupWriteIndex(sourceInst instance.Instance, pool storagePools.Pool, optimized bool, snapshots bool, tarWriter *instancewriter.InstanceTarWriter) error {
+// For incremental backups, only the snapshots taken after the parent snapshot are listed.
+func backupWriteIndex(sourceInst instance.Instance, pool storagePools.Pool, optimized bool, snapshots bool, parent string, parentSnapshot string, tarWriter *instancewriter.InstanceTarWriter) error {
 	// Indicate whether the driver will include a driver-specific optimized header.
 	poolDriverOptimizedHeader := false
 	if optimized {
@@ -192,19 +260,31 @@ func backupWriteIndex(sourceInst instance.Instance, pool storagePools.Pool, opti
 		Pool:             pool.Name(),
 		Snapshots:        []string{},
 		Backend:          pool.Driver().Info().Name,
-		Type:             api.InstanceType(sourceInst.Type().String()),
+		Type:             backup.Type(sourceInst.Type().String()),
 		OptimizedStorage: &optimized,
 		OptimizedHeader:  &poolDriverOptimizedHeader,
 	}
 
+	if parent != "" {
+		_, indexInfo.Parent, _ = shared.InstanceGetParentAndSnapshotName(parent)
+		indexInfo.ParentSnapshot = parentSnapshot
+	}
+
 	if snapshots {
 		snaps, err := sourceInst.Snapshots()
 		if err != nil {
 			return err
 		}
 
+		// Snapshots up to the parent snapshot are already contained in the parent backup chain.
+		skip := parentSnapshot != ""
 		for _, snap := range snaps {
 			_, snapName, _ := shared.InstanceGetParentAndSnapshotName(snap.Name())
+			if skip {
+				skip = snapName != parentSnapshot
+				continue
+			}
+
 			indexInfo.Snapshots = append(indexInfo.Snapshots, snapName)
 		}
 	}
@@ -232,6 +312,220 @@ func backupWriteIndex(sourceInst instance.Instance, pool storagePools.Pool, opti
 	return nil
 }
 
+// Create a new custom volume backup.
+func volumeBackupCreate(s *state.State, args db.StoragePoolVolumeBackup, projectName string, poolName string, volumeName string) error {
+	logger := logging.AddContext(logger.Log, log.Ctx{"project": projectName, "pool": poolName, "volume": volumeName, "name": args.Name})
+	logger.Debug("Volume backup started")
+	defer logger.Debug("Volume backup finished")
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	// Get storage pool.
+	pool, err := storagePools.GetPoolByName(s, poolName)
+	if err != nil {
+		return errors.Wrap(err, "Load storage pool")
+	}
+
+	_, vol, err := s.Cluster.GetLocalStoragePoolVolume(projectName, volumeName, db.StoragePoolVolumeTypeCustom, pool.ID())
+	if err != nil {
+		return errors.Wrap(err, "Load storage volume")
+	}
+
+	// Ignore requests for optimized backups when pool driver doesn't support it.
+	if args.OptimizedStorage && !pool.Driver().Info().OptimizedBackups {
+		args.OptimizedStorage = false
+	}
+
+	// Create the database entry.
+	err = s.Cluster.CreateStoragePoolVolumeBackup(args)
+	if err != nil {
+		if err == db.ErrAlreadyDefined {
+			return fmt.Errorf("Backup %q already exists", args.Name)
+		}
+
+		return errors.Wrap(err, "Insert backup info into database")
+	}
+
+	// Get the backup struct.
+	b, err := volumeBackupLoadByName(s, projectName, poolName, args.Name)
+	if err != nil {
+		return errors.Wrap(err, "Load backup object")
+	}
+
+	revert.Add(func() { s.Cluster.DeleteStoragePoolVolumeBackup(b.ID()) })
+
+	// Detect compression method.
+	var compress string
+	b.SetCompressionAlgorithm(args.CompressionAlgorithm)
+	if b.CompressionAlgorithm() != "" {
+		compress = b.CompressionAlgorithm()
+	} else {
+		compress, err = cluster.ConfigGetString(s.Cluster, "backups.compression_algorithm")
+		if err != nil {
+			return err
+		}
+	}
+
+	// Create the target path if needed.
+	backupsPath := backup.VolumeBackupsPath(projectName, poolName, volumeName)
+	if !shared.PathExists(backupsPath) {
+		err := os.MkdirAll(backupsPath, 0700)
+		if err != nil {
+			return err
+		}
+
+		revert.Add(func() { os.Remove(backupsPath) })
+	}
+
+	target := b.Path()
+
+	// Setup the tarball writer.
+	logger.Debug("Opening backup tarball for writing", log.Ctx{"path": target})
+	tarFileWriter, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return errors.Wrapf(err, "Error opening backup tarball for writing %q", target)
+	}
+	defer tarFileWriter.Close()
+	revert.Add(func() { os.Remove(target) })
+
+	// Create the tarball.
+	tarPipeReader, tarPipeWriter := io.Pipe()
+	defer tarPipeWriter.Close() // Ensure that go routine below always ends.
+	tarWriter := instancewriter.NewInstanceTarWriter(tarPipeWriter, nil)
+
+	// Setup tar writer go routine, with optional compression.
+	tarWriterRes := make(chan error, 0)
+	var compressErr error
+
+	go func(resCh chan<- error) {
+		logger.Debug("Started backup tarball writer")
+		defer logger.Debug("Finished backup tarball writer")
+		if compress != "none" {
+			compressErr = compressFile(compress, tarPipeReader, tarFileWriter)
+
+			// If a compression error occurred, close the tarPipeWriter to end the export.
+			if compressErr != nil {
+				tarPipeWriter.Close()
+			}
+		} else {
+			_, err = io.Copy(tarFileWriter, tarPipeReader)
+		}
+		resCh <- err
+	}(tarWriterRes)
+
+	// Write index file.
+	logger.Debug("Adding backup index file")
+	err = volumeBackupWriteIndex(s, projectName, volumeName, vol, pool, b.OptimizedStorage(), !b.VolumeOnly(), tarWriter)
+
+	// Check compression errors.
+	if compressErr != nil {
+		return compressErr
+	}
+
+	// Check volumeBackupWriteIndex for errors.
+	if err != nil {
+		return errors.Wrapf(err, "Error writing backup index file")
+	}
+
+	err = pool.BackupCustomVolume(projectName, volumeName, tarWriter, b.OptimizedStorage(), !b.VolumeOnly(), nil)
+	if err != nil {
+		return errors.Wrap(err, "Backup create")
+	}
+
+	// Close off the tarball file.
+	err = tarWriter.Close()
+	if err != nil {
+		return errors.Wrap(err, "Error closing tarball writer")
+	}
+
+	// Close off the tarball pipe writer (this will end the go routine above).
+	err = tarPipeWriter.Close()
+	if err != nil {
+		return errors.Wrap(err, "Error closing tarball pipe writer")
+	}
+
+	err = <-tarWriterRes
+	if err != nil {
+		return errors.Wrap(err, "Error writing tarball")
+	}
+
+	_, backupName, _ := shared.InstanceGetParentAndSnapshotName(b.Name())
+	s.Events.SendLifecycle(projectName, "storage-volume-backup-created",
+		fmt.Sprintf("/1.0/storage-pools/%s/volumes/custom/%s/backups/%s", poolName, volumeName, backupName), nil)
+
+	revert.Success()
+	return nil
+}
+
+// volumeBackupWriteIndex generates an index.yaml file for a custom volume and then writes it to the root of
+// the backup tarball.
+func volumeBackupWriteIndex(s *state.State, projectName string, volumeName string, vol *api.StorageVolume, pool storagePools.Pool, optimized bool, snapshots bool, tarWriter *instancewriter.InstanceTarWriter) error {
+	// Indicate whether the driver will include a driver-specific optimized header.
+	poolDriverOptimizedHeader := false
+	if optimized {
+		poolDriverOptimizedHeader = pool.Driver().Info().OptimizedBackupHeader
+	}
+
+	indexInfo := backup.Info{
+		Name:             volumeName,
+		Pool:             pool.Name(),
+		Snapshots:        []string{},
+		Backend:          pool.Driver().Info().Name,
+		Type:             backup.TypeCustom,
+		OptimizedStorage: &optimized,
+		OptimizedHeader:  &poolDriverOptimizedHeader,
+		Volume:           &vol.StorageVolumePut,
+		ContentType:      vol.ContentType,
+	}
+
+	if snapshots {
+		snaps, err := s.Cluster.GetLocalStoragePoolVolumeSnapshotsWithType(projectName, volumeName, db.StoragePoolVolumeTypeCustom, pool.ID())
+		if err != nil {
+			return err
+		}
+
+		for _, snap := range snaps {
+			_, snapName, _ := shared.InstanceGetParentAndSnapshotName(snap.Name)
+			indexInfo.Snapshots = append(indexInfo.Snapshots, snapName)
+		}
+	}
+
+	// Convert to YAML.
+	indexData, err := yaml.Marshal(&indexInfo)
+	if err != nil {
+		return err
+	}
+	r := bytes.NewReader(indexData)
+
+	indexFileInfo := instancewriter.FileInfo{
+		FileName:    "backup/index.yaml",
+		FileSize:    int64(len(indexData)),
+		FileMode:    0644,
+		FileModTime: time.Now(),
+	}
+
+	// Write to tarball.
+	err = tarWriter.WriteFileFromReader(r, &indexFileInfo)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// volumeBackupLoadByName loads a custom volume backup from the database.
+func volumeBackupLoadByName(s *state.State, projectName string, poolName string, backupName string) (*backup.VolumeBackup, error) {
+	b, err := s.Cluster.GetStoragePoolVolumeBackup(projectName, poolName, backupName)
+	if err != nil {
+		return nil, err
+	}
+
+	volumeName, _, _ := shared.InstanceGetParentAndSnapshotName(b.Name)
+
+	return backup.NewVolumeBackup(s, projectName, poolName, volumeName, b.ID, b.Name, b.CreationDate, b.ExpiryDate, b.VolumeOnly, b.OptimizedStorage), nil
+}
+
 func pruneExpiredContainerBackupsTask(d *Daemon) (task.Func, task.Schedule) {
 	f := func(ctx context.Context) {
 		opRun := func(op *operations.Operation) error {
@@ -286,6 +580,546 @@ func pruneExpiredContainerBackups(ctx context.Context, d *Daemon) error {
 		if err != nil {
 			return errors.Wrapf(err, "Error deleting instance backup %s", b.Name)
 		}
+
+		// Remove the exported copy of scheduled backups.
+		target := inst.ExpandedConfig()["backups.target"]
+		if target != "" {
+			_, backupName, _ := shared.InstanceGetParentAndSnapshotName(b.Name)
+			exportName := fmt.Sprintf("%s_%s", project.Instance(inst.Project(), inst.Name()), backupName)
+
+			err = backupExportDelete(d.State(), inst.Project(), target, exportName)
+			if err != nil {
+				logger.Warn("Failed to remove exported instance backup", log.Ctx{"err": err, "project": inst.Project(), "instance": inst.Name(), "backup": b.Name, "target": target})
+			}
+		}
+	}
+
+	// Get the list of expired custom volume backups.
+	volumeBackups, err := d.cluster.GetExpiredStorageVolumeBackups()
+	if err != nil {
+		return errors.Wrap(err, "Unable to retrieve the list of expired volume backups")
+	}
+
+	for _, b := range volumeBackups {
+		volumeName, _, _ := shared.InstanceGetParentAndSnapshotName(b.Name)
+		volBackup := backup.NewVolumeBackup(d.State(), b.ProjectName, b.PoolName, volumeName, b.ID, b.Name, b.CreationDate, b.ExpiryDate, b.VolumeOnly, b.OptimizedStorage)
+		err = volBackup.Delete()
+		if err != nil {
+			return errors.Wrapf(err, "Error deleting volume backup %s", b.Name)
+		}
+
+		// Remove the exported copy of scheduled backups.
+		target, err := backupVolumeTarget(d.State(), b.ProjectName, b.PoolName, volumeName)
+		if err != nil {
+			logger.Warn("Failed to get volume backup target", log.Ctx{"err": err, "project": b.ProjectName, "pool": b.PoolName, "volume": volumeName})
+			continue
+		}
+
+		if target != "" {
+			_, backupName, _ := shared.InstanceGetParentAndSnapshotName(b.Name)
+			exportName := fmt.Sprintf("%s_%s_%s", b.PoolName, project.StorageVolume(b.ProjectName, volumeName), backupName)
+
+			err = backupExportDelete(d.State(), b.ProjectName, target, exportName)
+			if err != nil {
+				logger.Warn("Failed to remove exported volume backup", log.Ctx{"err": err, "project": b.ProjectName, "pool": b.PoolName, "volume": volumeName, "backup": b.Name, "target": target})
+			}
+		}
+	}
+
+	return nil
+}
+
+// backupScheduleDue returns whether the backups.schedule cron expression is due at the given time.
+func backupScheduleDue(schedule string, now time.Time) bool {
+	if schedule == "" {
+		return false
+	}
+
+	// Extend our schedule to one that is accepted by the used cron parser.
+	sched, err := cron.Parse(fmt.Sprintf("* %s", schedule))
+	if err != nil {
+		return false
+	}
+
+	// Truncate the time now back to the start of the minute, before passing to the cron scheduler, as it
+	// will add 1s to the scheduled time and we don't want the next scheduled time to roll over to the next
+	// minute and break the time comparison below.
+	now = now.Truncate(time.Minute)
+
+	// Ignore everything that is more precise than minutes.
+	next := sched.Next(now).Truncate(time.Minute)
+
+	return now.Equal(next)
+}
+
+// backupVolumeIsLocal returns whether this member is in charge of the scheduled backups of a custom volume.
+// Volumes on local pools are handled by the member they live on, volumes on remote pools by a stable random
+// online member.
+func backupVolumeIsLocal(d *Daemon, v db.StorageVolumeArgs, onlineNodeIDs []int64) (bool, error) {
+	pool, err := storagePools.GetPoolByName(d.State(), v.PoolName)
+	if err != nil {
+		return false, err
+	}
+
+	if !pool.Driver().Info().Remote {
+		_, _, err = d.cluster.GetLocalStoragePoolVolume(v.ProjectName, v.Name, db.StoragePoolVolumeTypeCustom, pool.ID())
+		if err != nil {
+			if err == db.ErrNoSuchObject {
+				return false, nil
+			}
+
+			return false, err
+		}
+
+		return true, nil
+	}
+
+	if len(onlineNodeIDs) <= 1 {
+		return true, nil
+	}
+
+	hash := fnv.New64a()
+	_, err = io.WriteString(hash, fmt.Sprintf("%d", v.ID))
+	if err != nil {
+		return false, err
+	}
+
+	r := rand.New(rand.NewSource(int64(hash.Sum64())))
+	selectedNodeID := onlineNodeIDs[r.Int63n(int64(len(onlineNodeIDs)))]
+
+	return d.cluster.GetNodeID() == selectedNodeID, nil
+}
+
+// backupOnlineNodeIDs returns the IDs of the online cluster members.
+func backupOnlineNodeIDs(d *Daemon) ([]int64, error) {
+	nodes, err := cluster.List(d.State(), d.gateway)
+	if err != nil {
+		return nil, err
+	}
+
+	nodeIDs := []int64{}
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		for _, node := range nodes {
+			if node.Status != "Online" {
+				continue
+			}
+
+			info, err := tx.GetNodeByName(node.ServerName)
+			if err != nil {
+				return err
+			}
+
+			nodeIDs = append(nodeIDs, info.ID)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return nodeIDs, nil
+}
+
+func autoCreateBackupsTask(d *Daemon) (task.Func, task.Schedule) {
+	f := func(ctx context.Context) {
+		now := time.Now()
+
+		// Load all local instances.
+		allInstances, err := instance.LoadNodeAll(d.State(), instancetype.Any)
+		if err != nil {
+			logger.Error("Failed to load instances for scheduled backups", log.Ctx{"err": err})
+			return
+		}
+
+		// Figure out which instances need backing up (if any).
+		instances := []instance.Instance{}
+		for _, inst := range allInstances {
+			if !backupScheduleDue(inst.ExpandedConfig()["backups.schedule"], now) {
+				continue
+			}
+
+			instances = append(instances, inst)
+		}
+
+		// Figure out which custom volumes need backing up (if any).
+		allVolumes, err := d.cluster.GetStoragePoolVolumesWithType(db.StoragePoolVolumeTypeCustom)
+		if err != nil {
+			logger.Error("Failed to load custom volumes for scheduled backups", log.Ctx{"err": err})
+			return
+		}
+
+		var onlineNodeIDs []int64
+		volumes := []db.StorageVolumeArgs{}
+		for _, v := range allVolumes {
+			if !backupScheduleDue(v.Config["backups.schedule"], now) {
+				continue
+			}
+
+			if onlineNodeIDs == nil {
+				onlineNodeIDs, err = backupOnlineNodeIDs(d)
+				if err != nil {
+					logger.Error("Failed to get online cluster members for scheduled backups", log.Ctx{"err": err})
+					return
+				}
+			}
+
+			isLocal, err := backupVolumeIsLocal(d, v, onlineNodeIDs)
+			if err != nil {
+				logger.Error("Failed to check custom volume location for scheduled backups", log.Ctx{"err": err, "project": v.ProjectName, "pool": v.PoolName, "volume": v.Name})
+				continue
+			}
+
+			if !isLocal {
+				continue
+			}
+
+			volumes = append(volumes, v)
+		}
+
+		if len(instances) > 0 {
+			opRun := func(op *operations.Operation) error {
+				return autoCreateInstanceBackups(d, instances, now)
+			}
+
+			op, err := operations.OperationCreate(d.State(), "", operations.OperationClassTask, db.OperationBackupCreate, nil, nil, opRun, nil, nil)
+			if err != nil {
+				logger.Error("Failed to start create backup operation", log.Ctx{"err": err})
+			} else {
+				logger.Info("Creating scheduled instance backups")
+
+				_, err = op.Run()
+				if err != nil {
+					logger.Error("Failed to create scheduled instance backups", log.Ctx{"err": err})
+				}
+
+				logger.Info("Done creating scheduled instance backups")
+			}
+		}
+
+		if len(volumes) > 0 {
+			opRun := func(op *operations.Operation) error {
+				return autoCreateVolumeBackups(d, volumes, now)
+			}
+
+			op, err := operations.OperationCreate(d.State(), "", operations.OperationClassTask, db.OperationCustomVolumeBackupCreate, nil, nil, opRun, nil, nil)
+			if err != nil {
+				logger.Error("Failed to start create volume backup operation", log.Ctx{"err": err})
+			} else {
+				logger.Info("Creating scheduled volume backups")
+
+				_, err = op.Run()
+				if err != nil {
+					logger.Error("Failed to create scheduled volume backups", log.Ctx{"err": err})
+				}
+
+				logger.Info("Done creating scheduled volume backups")
+			}
+		}
+	}
+
+	first := true
+	schedule := func() (time.Duration, error) {
+		interval := time.Minute
+
+		if first {
+			first = false
+			return interval, task.ErrSkip
+		}
+
+		return interval, nil
+	}
+
+	return f, schedule
+}
+
+// backupScheduledName returns the name of a backup created by the scheduler at the given time.
+func backupScheduledName(now time.Time) string {
+	return fmt.Sprintf("scheduled-%s", now.UTC().Format("20060102-1504"))
+}
+
+func autoCreateInstanceBackups(d *Daemon, instances []instance.Instance, now time.Time) error {
+	s := d.State()
+
+	for _, inst := range instances {
+		err := autoCreateInstanceBackup(s, inst, now)
+		if err != nil {
+			logger.Error("Failed to create scheduled instance backup", log.Ctx{"err": err, "project": inst.Project(), "instance": inst.Name()})
+
+			err = s.Cluster.UpsertWarningLocalNode(inst.Project(), db.EntityTypeInstance, inst.ID(), db.WarningTypeScheduledBackupFailed, err.Error())
+			if err != nil {
+				logger.Warn("Failed to create warning", log.Ctx{"err": err})
+			}
+
+			continue
+		}
+
+		err = s.Cluster.ResolveWarningsByLocalNodeAndEntity(db.WarningTypeScheduledBackupFailed, db.EntityTypeInstance, inst.ID())
+		if err != nil {
+			logger.Warn("Failed to resolve warning", log.Ctx{"err": err})
+		}
+	}
+
+	return nil
+}
+
+// autoCreateInstanceBackup creates a scheduled backup of an instance and exports it to its backups.target.
+func autoCreateInstanceBackup(s *state.State, inst instance.Instance, now time.Time) error {
+	config := inst.ExpandedConfig()
+
+	expiry, err := shared.GetSnapshotExpiry(now, config["backups.schedule.expiry"])
+	if err != nil {
+		return errors.Wrap(err, "Get backup expiry")
+	}
+
+	backupName := backupScheduledName(now)
+	args := db.InstanceBackup{
+		Name:         inst.Name() + shared.SnapshotDelimiter + backupName,
+		InstanceID:   inst.ID(),
+		CreationDate: now,
+		ExpiryDate:   expiry,
+	}
+
+	err = backupCreate(s, args, inst)
+	if err != nil {
+		return err
+	}
+
+	if config["backups.target"] == "" {
+		return nil
+	}
+
+	sourcePath := shared.VarPath("backups", project.Instance(inst.Project(), args.Name))
+	exportName := fmt.Sprintf("%s_%s", project.Instance(inst.Project(), inst.Name()), backupName)
+
+	exportPath, err := backupExport(s, inst.Project(), config["backups.target"], sourcePath, exportName)
+	if err != nil {
+		return errors.Wrapf(err, "Export backup %q", backupName)
+	}
+
+	s.Events.SendLifecycle(inst.Project(), "instance-backup-exported",
+		fmt.Sprintf("/1.0/instances/%s/backups/%s", inst.Name(), backupName),
+		map[string]interface{}{
+			"target": config["backups.target"],
+			"path":   exportPath,
+		})
+
+	return nil
+}
+
+func autoCreateVolumeBackups(d *Daemon, volumes []db.StorageVolumeArgs, now time.Time) error {
+	s := d.State()
+
+	for _, v := range volumes {
+		err := autoCreateVolumeBackup(s, v, now)
+		if err != nil {
+			logger.Error("Failed to create scheduled volume backup", log.Ctx{"err": err, "project": v.ProjectName, "pool": v.PoolName, "volume": v.Name})
+
+			err = s.Cluster.UpsertWarningLocalNode(v.ProjectName, db.EntityTypeStorageVolume, int(v.ID), db.WarningTypeScheduledBackupFailed, err.Error())
+			if err != nil {
+				logger.Warn("Failed to create warning", log.Ctx{"err": err})
+			}
+
+			continue
+		}
+
+		err = s.Cluster.ResolveWarningsByLocalNodeAndEntity(db.WarningTypeScheduledBackupFailed, db.EntityTypeStorageVolume, int(v.ID))
+		if err != nil {
+			logger.Warn("Failed to resolve warning", log.Ctx{"err": err})
+		}
+	}
+
+	return nil
+}
+
+// autoCreateVolumeBackup creates a scheduled backup of a custom volume and exports it to its backups.target.
+func autoCreateVolumeBackup(s *state.State, v db.StorageVolumeArgs, now time.Time) error {
+	expiry, err := shared.GetSnapshotExpiry(now, v.Config["backups.schedule.expiry"])
+	if err != nil {
+		return errors.Wrap(err, "Get backup expiry")
+	}
+
+	backupName := backupScheduledName(now)
+	args := db.StoragePoolVolumeBackup{
+		Name:         v.Name + shared.SnapshotDelimiter + backupName,
+		VolumeID:     v.ID,
+		CreationDate: now,
+		ExpiryDate:   expiry,
+	}
+
+	err = volumeBackupCreate(s, args, v.ProjectName, v.PoolName, v.Name)
+	if err != nil {
+		return err
+	}
+
+	if v.Config["backups.target"] == "" {
+		return nil
+	}
+
+	b, err := volumeBackupLoadByName(s, v.ProjectName, v.PoolName, args.Name)
+	if err != nil {
+		return errors.Wrap(err, "Load backup object")
+	}
+
+	exportName := fmt.Sprintf("%s_%s_%s", v.PoolName, project.StorageVolume(v.ProjectName, v.Name), backupName)
+
+	exportPath, err := backupExport(s, v.ProjectName, v.Config["backups.target"], b.Path(), exportName)
+	if err != nil {
+		return errors.Wrapf(err, "Export backup %q", backupName)
+	}
+
+	s.Events.SendLifecycle(v.ProjectName, "storage-volume-backup-exported",
+		fmt.Sprintf("/1.0/storage-pools/%s/volumes/custom/%s/backups/%s", v.PoolName, v.Name, backupName),
+		map[string]interface{}{
+			"target": v.Config["backups.target"],
+			"path":   exportPath,
+		})
+
+	return nil
+}
+
+// backupTargetPath returns the local path of a backups.target along with a function releasing it once done.
+// The target volume is mounted for the duration of the access.
+func backupTargetPath(s *state.State, projectName string, value string) (string, func(), error) {
+	target, err := backup.ParseTarget(value)
+	if err != nil {
+		return "", nil, err
+	}
+
+	volProjectName, err := project.StorageVolumeProject(s.Cluster, projectName, db.StoragePoolVolumeTypeCustom)
+	if err != nil {
+		return "", nil, err
+	}
+
+	pool, err := storagePools.GetPoolByName(s, target.Pool)
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "Load target storage pool %q", target.Pool)
+	}
+
+	_, err = pool.MountCustomVolume(volProjectName, target.Volume, nil)
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "Mount target volume %q", target.Volume)
+	}
+
+	release := func() {
+		_, err := pool.UnmountCustomVolume(volProjectName, target.Volume, nil)
+		if err != nil {
+			logger.Warn("Failed to unmount backup target volume", log.Ctx{"err": err, "pool": target.Pool, "volume": target.Volume})
+		}
+	}
+
+	volPath := storageDrivers.GetVolumeMountPath(target.Pool, storageDrivers.VolumeTypeCustom, project.StorageVolume(volProjectName, target.Volume))
+
+	// Resolve the path inside the volume, without following symlinks out of it.
+	targetPath, err := securejoin.SecureJoin(volPath, target.Path)
+	if err != nil {
+		release()
+		return "", nil, err
+	}
+
+	return targetPath, release, nil
+}
+
+// backupVolumeTarget returns the backups.target of a custom volume.
+func backupVolumeTarget(s *state.State, projectName string, poolName string, volumeName string) (string, error) {
+	poolID, err := s.Cluster.GetStoragePoolID(poolName)
+	if err != nil {
+		return "", err
+	}
+
+	_, vol, err := s.Cluster.GetLocalStoragePoolVolume(projectName, volumeName, db.StoragePoolVolumeTypeCustom, poolID)
+	if err != nil {
+		return "", err
+	}
+
+	return vol.Config["backups.target"], nil
+}
+
+// backupExport copies a backup tarball into a backups.target, naming it after exportName followed by the
+// extension matching its compression. Returns the path of the exported file.
+func backupExport(s *state.State, projectName string, target string, sourcePath string, exportName string) (string, error) {
+	targetPath, release, err := backupTargetPath(s, projectName, target)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+
+	return backupExportFile(targetPath, sourcePath, exportName)
+}
+
+// backupExportFile copies a backup tarball into the targetPath directory, see backupExport.
+func backupExportFile(targetPath string, sourcePath string, exportName string) (string, error) {
+	err := os.MkdirAll(targetPath, 0700)
+	if err != nil {
+		return "", err
+	}
+
+	source, err := os.Open(sourcePath)
+	if err != nil {
+		return "", err
+	}
+	defer source.Close()
+
+	_, ext, _, err := shared.DetectCompressionFile(source)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = source.Seek(0, 0)
+	if err != nil {
+		return "", err
+	}
+
+	// Write to a temporary file first so that partial exports are never mistaken for complete ones.
+	exportPath := filepath.Join(targetPath, exportName+ext)
+	tmpFile, err := ioutil.TempFile(targetPath, ".lxd_backup_")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmpFile.Name())
+	defer tmpFile.Close()
+
+	_, err = io.Copy(tmpFile, source)
+	if err != nil {
+		return "", err
+	}
+
+	err = tmpFile.Close()
+	if err != nil {
+		return "", err
+	}
+
+	err = os.Rename(tmpFile.Name(), exportPath)
+	if err != nil {
+		return "", err
+	}
+
+	return exportPath, nil
+}
+
+// backupExportDelete removes the exported copies of a scheduled backup from a backups.target.
+func backupExportDelete(s *state.State, projectName string, target string, exportName string) error {
+	targetPath, release, err := backupTargetPath(s, projectName, target)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	return backupExportFileDelete(targetPath, exportName)
+}
+
+// backupExportFileDelete removes the exported copies of a scheduled backup from the targetPath directory.
+func backupExportFileDelete(targetPath string, exportName string) error {
+	paths, err := filepath.Glob(filepath.Join(targetPath, exportName) + ".*")
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		err = os.Remove(path)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
 	}
 
 	return nil
@@ -17,6 +17,21 @@ import (
 	"github.com/lxc/lxd/shared/api"
 )
 
+// Type indicates the type of backup.
+type Type string
+
+// TypeUnknown defines the backup type value for unknown backups.
+const TypeUnknown = Type("")
+
+// TypeContainer defines the backup type value for a container.
+const TypeContainer = Type("container")
+
+// TypeVM defines the backup type value for a virtual-machine.
+const TypeVM = Type("virtual-machine")
+
+// TypeCustom defines the backup type value for a custom volume.
+const TypeCustom = Type("custom")
+
 // Instance represents the backup relevant subset of a LXD instance.
 // This is used rather than instance.Instance to avoid import loops.
 type Instance interface {
@@ -26,14 +41,48 @@ type Instance interface {
 
 // Info represents exported backup information.
 type Info struct {
-	Project          string           `json:"-" yaml:"-"` // Project is set during import based on current project.
-	Name             string           `json:"name" yaml:"name"`
-	Backend          string           `json:"backend" yaml:"backend"`
-	Pool             string           `json:"pool" yaml:"pool"`
-	Snapshots        []string         `json:"snapshots,omitempty" yaml:"snapshots,omitempty"`
-	OptimizedStorage *bool            `json:"optimized,omitempty" yaml:"optimized,omitempty"`               // Optional field to handle older optimized backups that don't have this field.
-	OptimizedHeader  *bool            `json:"optimized_header,omitempty" yaml:"optimized_header,omitempty"` // Optional field to handle older optimized backups that don't have this field.
-	Type             api.InstanceType `json:"type" yaml:"type"`
+	Project          string   `json:"-" yaml:"-"` // Project is set during import based on current project.
+	Name             string   `json:"name" yaml:"name"`
+	Backend          string   `json:"backend" yaml:"backend"`
+	Pool             string   `json:"pool" yaml:"pool"`
+	Snapshots        []string `json:"snapshots,omitempty" yaml:"snapshots,omitempty"`
+	OptimizedStorage *bool    `json:"optimized,omitempty" yaml:"optimized,omitempty"`               // Optional field to handle older optimized backups that don't have this field.
+	OptimizedHeader  *bool    `json:"optimized_header,omitempty" yaml:"optimized_header,omitempty"` // Optional field to handle older optimized backups that don't have this field.
+	Type             Type     `json:"type" yaml:"type"`
+
+	// Only set for custom volume backups.
+	Volume      *api.StorageVolumePut `json:"volume,omitempty" yaml:"volume,omitempty"`
+	ContentType string                `json:"content_type,omitempty" yaml:"content_type,omitempty"`
+
+	// Only set for incremental backups. Parent is the name of the backup this one is based on and
+	// ParentSnapshot the snapshot the contained volume streams are relative to.
+	Parent         string `json:"parent,omitempty" yaml:"parent,omitempty"`
+	ParentSnapshot string `json:"parent_snapshot,omitempty" yaml:"parent_snapshot,omitempty"`
+}
+
+// ValidateParent checks that an incremental backup can be applied on top of an instance that has the
+// given snapshots (oldest first). The snapshot the backup is based on must be the most recent one and
+// none of the snapshots contained in the backup may exist yet.
+func (i *Info) ValidateParent(snapshots []string) error {
+	if i.Parent == "" {
+		return fmt.Errorf("Backup %q isn't an incremental backup", i.Name)
+	}
+
+	if i.ParentSnapshot == "" {
+		return fmt.Errorf("Incremental backup is missing its parent snapshot")
+	}
+
+	if len(snapshots) == 0 || snapshots[len(snapshots)-1] != i.ParentSnapshot {
+		return fmt.Errorf("Incremental backup requires %q to be the most recent snapshot, restore parent backup %q first", i.ParentSnapshot, i.Parent)
+	}
+
+	for _, snapName := range i.Snapshots {
+		if shared.StringInSlice(snapName, snapshots) {
+			return fmt.Errorf("Snapshot %q contained in the backup already exists", snapName)
+		}
+	}
+
+	return nil
 }
 
 // GetInfo extracts backup information from a given ReadSeeker.
@@ -80,8 +129,8 @@ func GetInfo(r io.ReadSeeker) (*Info, error) {
 			hasIndexFile = true
 
 			// Default to container if index doesn't specify instance type.
-			if result.Type == api.InstanceTypeAny {
-				result.Type = api.InstanceTypeContainer
+			if result.Type == TypeUnknown {
+				result.Type = TypeContainer
 			}
 
 			// Default to no optimized header if not specified.
@@ -101,8 +150,8 @@ func GetInfo(r io.ReadSeeker) (*Info, error) {
 			}
 		}
 
-		// If the tarball contains a binary dump of the container, then this is an optimized backup.
-		if hdr.Name == "backup/container.bin" {
+		// If the tarball contains a binary dump of the container or volume, then this is an optimized backup.
+		if hdr.Name == "backup/container.bin" || hdr.Name == "backup/volume.bin" {
 			optimizedStorageTrue := true
 			result.OptimizedStorage = &optimizedStorageTrue
 
@@ -134,10 +183,11 @@ type Backup struct {
 	instanceOnly         bool
 	optimizedStorage     bool
 	compressionAlgorithm string
+	parent               string
 }
 
 // New instantiates a new Backup struct.
-func New(state *state.State, inst Instance, ID int, name string, creationDate, expiryDate time.Time, instanceOnly, optimizedStorage bool) *Backup {
+func New(state *state.State, inst Instance, ID int, name string, creationDate, expiryDate time.Time, instanceOnly, optimizedStorage bool, parent string) *Backup {
 	return &Backup{
 		state:            state,
 		instance:         inst,
@@ -147,6 +197,7 @@ func New(state *state.State, inst Instance, ID int, name string, creationDate, e
 		expiryDate:       expiryDate,
 		instanceOnly:     instanceOnly,
 		optimizedStorage: optimizedStorage,
+		parent:           parent,
 	}
 }
 
@@ -176,6 +227,11 @@ func (b *Backup) OptimizedStorage() bool {
 	return b.optimizedStorage
 }
 
+// Parent returns the name of the backup this one is incremental to (empty for full backups).
+func (b *Backup) Parent() string {
+	return b.parent
+}
+
 // Rename renames a container backup
 func (b *Backup) Rename(newName string) error {
 	oldBackupPath := shared.VarPath("backups", project.Instance(b.instance.Project(), b.name))
@@ -221,6 +277,11 @@ func (b *Backup) Delete() error {
 
 // Render returns an InstanceBackup struct of the backup.
 func (b *Backup) Render() *api.InstanceBackup {
+	parent := ""
+	if b.parent != "" {
+		parent = strings.SplitN(b.parent, "/", 2)[1]
+	}
+
 	return &api.InstanceBackup{
 		Name:             strings.SplitN(b.name, "/", 2)[1],
 		CreatedAt:        b.creationDate,
@@ -228,6 +289,7 @@ func (b *Backup) Render() *api.InstanceBackup {
 		InstanceOnly:     b.instanceOnly,
 		ContainerOnly:    b.instanceOnly,
 		OptimizedStorage: b.optimizedStorage,
+		Parent:           parent,
 	}
 }
 
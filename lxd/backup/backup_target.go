@@ -0,0 +1,39 @@
+package backup
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Target represents the destination scheduled backups are exported to, a path inside a custom storage volume.
+type Target struct {
+	Pool   string
+	Volume string
+	Path   string
+}
+
+// ParseTarget parses the value of a backups.target config key, of the form <pool>/<volume>[/<path>].
+func ParseTarget(value string) (*Target, error) {
+	if value == "" {
+		return nil, fmt.Errorf("Backup target cannot be empty")
+	}
+
+	fields := strings.SplitN(value, "/", 3)
+	if len(fields) < 2 || fields[0] == "" || fields[1] == "" {
+		return nil, fmt.Errorf("Backup target must be of the form <pool>/<volume>[/<path>]")
+	}
+
+	target := &Target{
+		Pool:   fields[0],
+		Volume: fields[1],
+		Path:   "/",
+	}
+
+	// Keep the path inside the volume.
+	if len(fields) == 3 {
+		target.Path = filepath.Clean("/" + fields[2])
+	}
+
+	return target, nil
+}
@@ -0,0 +1,35 @@
+package backup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		value  string
+		target *Target
+		err    string
+	}{
+		{"default/backups", &Target{Pool: "default", Volume: "backups", Path: "/"}, ""},
+		{"default/backups/", &Target{Pool: "default", Volume: "backups", Path: "/"}, ""},
+		{"default/backups/nightly/c1", &Target{Pool: "default", Volume: "backups", Path: "/nightly/c1"}, ""},
+		{"default/backups/../../../etc", &Target{Pool: "default", Volume: "backups", Path: "/etc"}, ""},
+		{"", nil, "Backup target cannot be empty"},
+		{"/srv/backups", nil, "Backup target must be of the form <pool>/<volume>[/<path>]"},
+		{"default", nil, "Backup target must be of the form <pool>/<volume>[/<path>]"},
+		{"default/", nil, "Backup target must be of the form <pool>/<volume>[/<path>]"},
+	}
+
+	for _, test := range tests {
+		target, err := ParseTarget(test.value)
+		if test.err != "" {
+			assert.EqualError(t, err, test.err, test.value)
+			continue
+		}
+
+		assert.NoError(t, err, test.value)
+		assert.Equal(t, test.target, target, test.value)
+	}
+}
@@ -0,0 +1,69 @@
+package backup
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInfo_ValidateParent(t *testing.T) {
+	info := Info{
+		Name:           "c1",
+		Parent:         "c1/backup0",
+		ParentSnapshot: "snap1",
+		Snapshots:      []string{"snap2", "snap3"},
+	}
+
+	// The parent snapshot must be the most recent one and the snapshots of the backup must be new.
+	assert.NoError(t, info.ValidateParent([]string{"snap0", "snap1"}))
+
+	tests := []struct {
+		name      string
+		info      Info
+		snapshots []string
+		err       string
+	}{
+		{
+			name:      "full backup",
+			info:      Info{Name: "c1"},
+			snapshots: []string{"snap1"},
+			err:       `Backup "c1" isn't an incremental backup`,
+		},
+		{
+			name:      "missing parent snapshot name",
+			info:      Info{Name: "c1", Parent: "c1/backup0"},
+			snapshots: []string{"snap1"},
+			err:       "Incremental backup is missing its parent snapshot",
+		},
+		{
+			name:      "no snapshots",
+			info:      info,
+			snapshots: nil,
+			err:       `Incremental backup requires "snap1" to be the most recent snapshot, restore parent backup "c1/backup0" first`,
+		},
+		{
+			name:      "missing parent snapshot",
+			info:      info,
+			snapshots: []string{"snap0"},
+			err:       `Incremental backup requires "snap1" to be the most recent snapshot, restore parent backup "c1/backup0" first`,
+		},
+		{
+			name:      "mismatched parent snapshot",
+			info:      info,
+			snapshots: []string{"snap1", "snap4"},
+			err:       `Incremental backup requires "snap1" to be the most recent snapshot, restore parent backup "c1/backup0" first`,
+		},
+		{
+			name:      "already restored",
+			info:      Info{Name: "c1", Parent: "c1/backup0", ParentSnapshot: "snap2", Snapshots: []string{"snap2", "snap3"}},
+			snapshots: []string{"snap1", "snap2"},
+			err:       `Snapshot "snap2" contained in the backup already exists`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.EqualError(t, test.info.ValidateParent(test.snapshots), test.err)
+		})
+	}
+}
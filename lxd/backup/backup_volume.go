@@ -0,0 +1,158 @@
+package backup
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lxc/lxd/lxd/project"
+	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// VolumeBackup represents a custom volume backup.
+type VolumeBackup struct {
+	state       *state.State
+	projectName string
+	poolName    string
+	volumeName  string
+
+	// Properties
+	id                   int
+	name                 string
+	creationDate         time.Time
+	expiryDate           time.Time
+	volumeOnly           bool
+	optimizedStorage     bool
+	compressionAlgorithm string
+}
+
+// NewVolumeBackup instantiates a new VolumeBackup struct.
+func NewVolumeBackup(state *state.State, projectName, poolName, volumeName string, ID int, name string, creationDate, expiryDate time.Time, volumeOnly, optimizedStorage bool) *VolumeBackup {
+	return &VolumeBackup{
+		state:            state,
+		projectName:      projectName,
+		poolName:         poolName,
+		volumeName:       volumeName,
+		id:               ID,
+		name:             name,
+		creationDate:     creationDate,
+		expiryDate:       expiryDate,
+		volumeOnly:       volumeOnly,
+		optimizedStorage: optimizedStorage,
+	}
+}
+
+// VolumeBackupsPath returns the path of the directory holding the backups of a custom volume.
+func VolumeBackupsPath(projectName, poolName, volumeName string) string {
+	return shared.VarPath("backups", "custom", poolName, project.StorageVolume(projectName, volumeName))
+}
+
+// CompressionAlgorithm returns the compression used for the tarball.
+func (b *VolumeBackup) CompressionAlgorithm() string {
+	return b.compressionAlgorithm
+}
+
+// SetCompressionAlgorithm sets the tarball compression.
+func (b *VolumeBackup) SetCompressionAlgorithm(compression string) {
+	b.compressionAlgorithm = compression
+}
+
+// VolumeOnly returns whether only the volume itself is to be backed up.
+func (b *VolumeBackup) VolumeOnly() bool {
+	return b.volumeOnly
+}
+
+// ID returns the database ID of the backup.
+func (b *VolumeBackup) ID() int {
+	return b.id
+}
+
+// Name returns the name of the backup.
+func (b *VolumeBackup) Name() string {
+	return b.name
+}
+
+// OptimizedStorage returns whether the backup is to be performed using
+// optimization supported by the storage driver.
+func (b *VolumeBackup) OptimizedStorage() bool {
+	return b.optimizedStorage
+}
+
+// Path returns the path of the backup tarball.
+func (b *VolumeBackup) Path() string {
+	return shared.VarPath("backups", "custom", b.poolName, project.StorageVolume(b.projectName, b.name))
+}
+
+// Rename renames a custom volume backup.
+func (b *VolumeBackup) Rename(newName string) error {
+	oldBackupPath := b.Path()
+	newBackupPath := shared.VarPath("backups", "custom", b.poolName, project.StorageVolume(b.projectName, newName))
+
+	// Create the new backup path
+	backupsPath := VolumeBackupsPath(b.projectName, b.poolName, b.volumeName)
+	if !shared.PathExists(backupsPath) {
+		err := os.MkdirAll(backupsPath, 0700)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Rename the backup tarball
+	err := os.Rename(oldBackupPath, newBackupPath)
+	if err != nil {
+		return err
+	}
+
+	// Rename the database record
+	err = b.state.Cluster.RenameStoragePoolVolumeBackup(b.id, newName)
+	if err != nil {
+		return err
+	}
+
+	b.name = newName
+
+	return nil
+}
+
+// Delete removes a custom volume backup.
+func (b *VolumeBackup) Delete() error {
+	// Delete the on-disk data
+	backupPath := b.Path()
+	if shared.PathExists(backupPath) {
+		err := os.RemoveAll(backupPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Check if we can remove the volume directory
+	backupsPath := VolumeBackupsPath(b.projectName, b.poolName, b.volumeName)
+	empty, _ := shared.PathIsEmpty(backupsPath)
+	if empty {
+		err := os.Remove(backupsPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Remove the database record
+	err := b.state.Cluster.DeleteStoragePoolVolumeBackup(b.id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Render returns a StoragePoolVolumeBackup struct of the backup.
+func (b *VolumeBackup) Render() *api.StoragePoolVolumeBackup {
+	return &api.StoragePoolVolumeBackup{
+		Name:             strings.SplitN(b.name, "/", 2)[1],
+		CreatedAt:        b.creationDate,
+		ExpiresAt:        b.expiryDate,
+		VolumeOnly:       b.volumeOnly,
+		OptimizedStorage: b.optimizedStorage,
+	}
+}
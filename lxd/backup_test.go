@@ -0,0 +1,78 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupScheduleDue(t *testing.T) {
+	now := time.Date(2021, time.March, 1, 2, 0, 30, 0, time.UTC)
+
+	assert.True(t, backupScheduleDue("0 2 * * *", now))
+	assert.True(t, backupScheduleDue("*/15 * * * *", now))
+	assert.False(t, backupScheduleDue("1 2 * * *", now))
+	assert.False(t, backupScheduleDue("0 3 * * *", now))
+	assert.False(t, backupScheduleDue("0 2 * * 0", now))
+
+	// Disabled or invalid schedules are never due.
+	assert.False(t, backupScheduleDue("", now))
+	assert.False(t, backupScheduleDue("invalid", now))
+}
+
+func TestBackupExportFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lxd-backup-export-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// A gzip compressed tarball (magic header followed by some data).
+	sourcePath := filepath.Join(dir, "backup")
+	err = ioutil.WriteFile(sourcePath, []byte{0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00}, 0600)
+	require.NoError(t, err)
+
+	// The target directory is created as needed.
+	targetPath := filepath.Join(dir, "target", "nightly")
+	exportPath, err := backupExportFile(targetPath, sourcePath, "p1_c1_scheduled-20210301-0200")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(targetPath, "p1_c1_scheduled-20210301-0200.tar.gz"), exportPath)
+
+	content, err := ioutil.ReadFile(exportPath)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00}, content)
+
+	// No temporary file is left behind.
+	entries, err := ioutil.ReadDir(targetPath)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	// Only the exported copies of the given backup are removed.
+	otherPath, err := backupExportFile(targetPath, sourcePath, "p1_c1_scheduled-20210302-0200")
+	require.NoError(t, err)
+
+	err = backupExportFileDelete(targetPath, "p1_c1_scheduled-20210301-0200")
+	require.NoError(t, err)
+	assert.NoFileExists(t, exportPath)
+	assert.FileExists(t, otherPath)
+
+	// Removing a backup that wasn't exported isn't an error.
+	err = backupExportFileDelete(targetPath, "p1_c1_scheduled-20210301-0200")
+	assert.NoError(t, err)
+}
+
+func TestBackupExportFileMissingSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lxd-backup-export-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = backupExportFile(filepath.Join(dir, "target"), filepath.Join(dir, "missing"), "p1_c1_scheduled-20210301-0200")
+	assert.Error(t, err)
+
+	entries, err := ioutil.ReadDir(filepath.Join(dir, "target"))
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
@@ -15,6 +15,7 @@ import (
 	lxd "github.com/lxc/lxd/client"
 	"github.com/lxc/lxd/lxd/cluster"
 	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/operations"
 	"github.com/lxc/lxd/lxd/response"
 	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
@@ -28,7 +29,7 @@ import (
 var certificatesCmd = APIEndpoint{
 	Path: "certificates",
 
-	Get:  APIEndpointAction{Handler: certificatesGet, AccessHandler: allowAuthenticated},
+	Get:  APIEndpointAction{Handler: certificatesGet, AccessHandler: allowUnrestricted},
 	Post: APIEndpointAction{Handler: certificatesPost, AllowUntrusted: true},
 }
 
@@ -36,7 +37,7 @@ var certificateCmd = APIEndpoint{
 	Path: "certificates/{fingerprint}",
 
 	Delete: APIEndpointAction{Handler: certificateDelete},
-	Get:    APIEndpointAction{Handler: certificateGet, AccessHandler: allowAuthenticated},
+	Get:    APIEndpointAction{Handler: certificateGet, AccessHandler: allowUnrestricted},
 	Patch:  APIEndpointAction{Handler: certificatePatch},
 	Put:    APIEndpointAction{Handler: certificatePut},
 }
@@ -62,20 +63,29 @@ func certificatesGet(d *Daemon, r *http.Request) response.Response {
 			resp.Fingerprint = baseCert.Fingerprint
 			resp.Certificate = baseCert.Certificate
 			resp.Name = baseCert.Name
-			if baseCert.Type == 1 {
-				resp.Type = "client"
-			} else {
-				resp.Type = "unknown"
+			resp.Type = db.CertificateTypeToAPIType(baseCert.Type)
+			resp.Restricted = baseCert.Restricted
+			resp.Projects = []string{}
+
+			err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+				resp.Projects, err = tx.GetCertificateProjects(baseCert.ID)
+				return err
+			})
+			if err != nil {
+				return response.SmartError(err)
 			}
+
 			certResponses = append(certResponses, resp)
 		}
 		return response.SyncResponse(true, certResponses)
 	}
 
 	body := []string{}
-	for _, cert := range d.clientCerts {
-		fingerprint := fmt.Sprintf("/%s/certificates/%s", version.APIVersion, shared.CertFingerprint(&cert))
-		body = append(body, fingerprint)
+	for _, certs := range []map[string]x509.Certificate{d.clientCerts, d.metricsCerts} {
+		for _, cert := range certs {
+			fingerprint := fmt.Sprintf("/%s/certificates/%s", version.APIVersion, shared.CertFingerprint(&cert))
+			body = append(body, fingerprint)
+		}
 	}
 
 	return response.SyncResponse(true, body)
@@ -83,18 +93,37 @@ func certificatesGet(d *Daemon, r *http.Request) response.Response {
 
 func readSavedClientCAList(d *Daemon) {
 	d.clientCerts = map[string]x509.Certificate{}
+	d.metricsCerts = map[string]x509.Certificate{}
+	restrictedCerts := map[string][]string{}
 
 	var dbCerts []db.Certificate
 	var err error
 	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
 		dbCerts, err = tx.GetCertificates(db.CertificateFilter{})
-		return err
+		if err != nil {
+			return err
+		}
+
+		for _, dbCert := range dbCerts {
+			if !dbCert.Restricted {
+				continue
+			}
+
+			restrictedCerts[dbCert.Fingerprint], err = tx.GetCertificateProjects(dbCert.ID)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
 	})
 	if err != nil {
 		logger.Infof("Error reading certificates from database: %s", err)
 		return
 	}
 
+	d.restrictedCerts = restrictedCerts
+
 	for _, dbCert := range dbCerts {
 		certBlock, _ := pem.Decode([]byte(dbCert.Certificate))
 		if certBlock == nil {
@@ -108,7 +137,11 @@ func readSavedClientCAList(d *Daemon) {
 			continue
 		}
 
-		d.clientCerts[shared.CertFingerprint(cert)] = *cert
+		if dbCert.Type == db.CertificateTypeMetrics {
+			d.metricsCerts[shared.CertFingerprint(cert)] = *cert
+		} else {
+			d.clientCerts[shared.CertFingerprint(cert)] = *cert
+		}
 	}
 }
 
@@ -125,20 +158,70 @@ func certificatesPost(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
-	trusted, _, protocol, err := d.Authenticate(r)
+	trusted, _, _, err := d.Authenticate(r)
 	if err != nil {
 		return response.SmartError(err)
 	}
 
-	if (!trusted || (protocol == "candid" && !d.userIsAdmin(r))) && util.PasswordCheck(secret, req.Password) != nil {
-		if req.Password != "" {
-			logger.Warn("Bad trust password", log.Ctx{"url": r.URL.RequestURI(), "ip": r.RemoteAddr})
+	admin := trusted && d.userIsAdmin(r)
+
+	// Only administrators can issue certificate add tokens.
+	if req.Token {
+		if !admin {
+			return response.Forbidden(nil)
 		}
-		return response.Forbidden(nil)
+
+		return certificateTokenCreate(d, req)
 	}
 
-	if req.Type != "client" {
-		return response.BadRequest(fmt.Errorf("Unknown request type %s", req.Type))
+	// The password may also be the secret of a cluster member join token or of a certificate add token. The token
+	// is only consumed once the request is known to be valid.
+	var tokenOp *api.Operation
+	if !admin && util.PasswordCheck(secret, req.Password) != nil {
+		tokenOp, err = tokenOperationValid(d, db.OperationClusterJoinToken, req.Password)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		if tokenOp != nil {
+			joinToken, err := tokenOp.ToClusterJoinToken()
+			if err != nil {
+				return response.SmartError(err)
+			}
+
+			// Join tokens are issued for a specific member name.
+			if req.Name != cluster.TrustCertificateName(joinToken.ServerName) {
+				return response.Forbidden(fmt.Errorf("Join token was issued for member %q", joinToken.ServerName))
+			}
+		} else {
+			tokenOp, err = tokenOperationValid(d, db.OperationCertificateAddToken, req.Password)
+			if err != nil {
+				return response.SmartError(err)
+			}
+
+			if tokenOp == nil {
+				if req.Password != "" {
+					logger.Warn("Bad trust password", log.Ctx{"url": r.URL.RequestURI(), "ip": r.RemoteAddr})
+				}
+				return response.Forbidden(nil)
+			}
+
+			// Use the name and restrictions chosen by the administrator who issued the token.
+			req.CertificatePut, err = certificateTokenRequest(tokenOp)
+			if err != nil {
+				return response.SmartError(err)
+			}
+		}
+	}
+
+	dbType, err := db.CertificateAPITypeToDBType(req.Type)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	err = certificateValidateRestriction(d, dbType, req.Restricted, req.Projects)
+	if err != nil {
+		return response.BadRequest(err)
 	}
 
 	// Extract the certificate
@@ -173,18 +256,35 @@ func certificatesPost(d *Daemon, r *http.Request) response.Response {
 
 	fingerprint := shared.CertFingerprint(cert)
 
+	if tokenOp != nil {
+		err = tokenOperationConsume(d, tokenOp)
+		if err != nil {
+			return response.Forbidden(err)
+		}
+	}
+
 	if d.clientCerts == nil {
 		d.clientCerts = map[string]x509.Certificate{}
 	}
 
+	if d.metricsCerts == nil {
+		d.metricsCerts = map[string]x509.Certificate{}
+	}
+
+	// Pick the local cache for this certificate type
+	certs := d.clientCerts
+	if dbType == db.CertificateTypeMetrics {
+		certs = d.metricsCerts
+	}
+
 	if !isClusterNotification(r) {
 		// Check if we already have the certificate
 		existingCert, _ := d.cluster.GetCertificate(fingerprint)
 		if existingCert != nil {
 			// Deal with the cache being potentially out of sync
-			_, ok := d.clientCerts[fingerprint]
+			_, ok := certs[fingerprint]
 			if !ok {
-				d.clientCerts[fingerprint] = *cert
+				certs[fingerprint] = *cert
 				return response.SyncResponseLocation(true, nil, fmt.Sprintf("/%s/certificates/%s", version.APIVersion, fingerprint))
 			}
 
@@ -194,12 +294,13 @@ func certificatesPost(d *Daemon, r *http.Request) response.Response {
 		// Store the certificate in the cluster database
 		dbCert := db.Certificate{
 			Fingerprint: shared.CertFingerprint(cert),
-			Type:        1,
+			Type:        dbType,
 			Name:        name,
 			Certificate: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})),
+			Restricted:  req.Restricted,
 		}
 
-		err = d.cluster.CreateCertificate(dbCert)
+		err = d.cluster.CreateCertificateWithProjects(dbCert, req.Projects)
 		if err != nil {
 			return response.SmartError(err)
 		}
@@ -210,25 +311,108 @@ func certificatesPost(d *Daemon, r *http.Request) response.Response {
 		if err != nil {
 			return response.SmartError(err)
 		}
-		req := api.CertificatesPost{
+		notifyReq := api.CertificatesPost{
 			Certificate: base64.StdEncoding.EncodeToString(cert.Raw),
 		}
-		req.Name = name
-		req.Type = "client"
+		notifyReq.Name = name
+		notifyReq.Type = db.CertificateTypeToAPIType(dbType)
+		notifyReq.Restricted = req.Restricted
+		notifyReq.Projects = req.Projects
 
 		err = notifier(func(client lxd.InstanceServer) error {
-			return client.CreateCertificate(req)
+			return client.CreateCertificate(notifyReq)
 		})
 		if err != nil {
 			return response.SmartError(err)
 		}
 	}
 
-	d.clientCerts[shared.CertFingerprint(cert)] = *cert
+	// Restricted certificates must not be trusted before their projects are known.
+	if req.Restricted {
+		readSavedClientCAList(d)
+	} else {
+		certs[shared.CertFingerprint(cert)] = *cert
+	}
 
 	return response.SyncResponseLocation(true, nil, fmt.Sprintf("/%s/certificates/%s", version.APIVersion, fingerprint))
 }
 
+// certificateTokenCreate issues a single-use token allowing a new client to add its certificate without knowing
+// the trust password.
+func certificateTokenCreate(d *Daemon, req api.CertificatesPost) response.Response {
+	if req.Name == "" {
+		return response.BadRequest(fmt.Errorf("A client name must be provided"))
+	}
+
+	if req.Type != "" && req.Type != api.CertificateTypeClient {
+		return response.BadRequest(fmt.Errorf("Tokens can only be used to add client certificates"))
+	}
+
+	err := certificateValidateRestriction(d, db.CertificateTypeClient, req.Restricted, req.Projects)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	addresses, err := tokenAddresses(d)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	var expiry string
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		config, err := cluster.ConfigLoad(tx)
+		if err != nil {
+			return err
+		}
+
+		expiry = config.RemoteTokenExpiry()
+		return nil
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	meta := shared.Jmap{
+		"client_name": req.Name,
+		"fingerprint": d.endpoints.NetworkCert().Fingerprint(),
+		"addresses":   addresses,
+		"restricted":  req.Restricted,
+		"projects":    req.Projects,
+	}
+
+	op, err := tokenOperationCreate(d, db.OperationCertificateAddToken, meta, expiry)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return operations.OperationResponse(op)
+}
+
+// certificateTokenRequest returns the certificate fields recorded in a certificate add token operation.
+func certificateTokenRequest(op *api.Operation) (api.CertificatePut, error) {
+	req := api.CertificatePut{}
+
+	addToken, err := op.ToCertificateAddToken()
+	if err != nil {
+		return req, err
+	}
+
+	data, err := json.Marshal(op.Metadata)
+	if err != nil {
+		return req, err
+	}
+
+	err = json.Unmarshal(data, &req)
+	if err != nil {
+		return req, err
+	}
+
+	req.Name = addToken.ClientName
+	req.Type = api.CertificateTypeClient
+
+	return req, nil
+}
+
 func certificateGet(d *Daemon, r *http.Request) response.Response {
 	fingerprint := mux.Vars(r)["fingerprint"]
 
@@ -240,10 +424,10 @@ func certificateGet(d *Daemon, r *http.Request) response.Response {
 	return response.SyncResponseETag(true, cert, cert)
 }
 
-func doCertificateGet(db *db.Cluster, fingerprint string) (api.Certificate, error) {
+func doCertificateGet(dbCluster *db.Cluster, fingerprint string) (api.Certificate, error) {
 	resp := api.Certificate{}
 
-	dbCertInfo, err := db.GetCertificate(fingerprint)
+	dbCertInfo, err := dbCluster.GetCertificate(fingerprint)
 	if err != nil {
 		return resp, err
 	}
@@ -251,10 +435,16 @@ func doCertificateGet(db *db.Cluster, fingerprint string) (api.Certificate, erro
 	resp.Fingerprint = dbCertInfo.Fingerprint
 	resp.Certificate = dbCertInfo.Certificate
 	resp.Name = dbCertInfo.Name
-	if dbCertInfo.Type == 1 {
-		resp.Type = "client"
-	} else {
-		resp.Type = "unknown"
+	resp.Type = db.CertificateTypeToAPIType(dbCertInfo.Type)
+	resp.Restricted = dbCertInfo.Restricted
+	resp.Projects = []string{}
+
+	err = dbCluster.Transaction(func(tx *db.ClusterTx) error {
+		resp.Projects, err = tx.GetCertificateProjects(dbCertInfo.ID)
+		return err
+	})
+	if err != nil {
+		return resp, err
 	}
 
 	return resp, nil
@@ -279,7 +469,7 @@ func certificatePut(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(err)
 	}
 
-	return doCertificateUpdate(d, fingerprint, req)
+	return doCertificateUpdate(d, r, fingerprint, req)
 }
 
 func certificatePatch(d *Daemon, r *http.Request) response.Response {
@@ -314,15 +504,82 @@ func certificatePatch(d *Daemon, r *http.Request) response.Response {
 		req.Type = value
 	}
 
-	return doCertificateUpdate(d, fingerprint, req.Writable())
+	// Get restricted
+	restricted, err := reqRaw.GetBool("restricted")
+	if err == nil {
+		req.Restricted = restricted
+	}
+
+	// Get projects
+	_, ok := reqRaw["projects"]
+	if ok {
+		values, ok := reqRaw["projects"].([]interface{})
+		if !ok {
+			return response.BadRequest(fmt.Errorf("Invalid projects"))
+		}
+
+		req.Projects = []string{}
+		for _, value := range values {
+			project, ok := value.(string)
+			if !ok {
+				return response.BadRequest(fmt.Errorf("Invalid projects"))
+			}
+
+			req.Projects = append(req.Projects, project)
+		}
+	}
+
+	return doCertificateUpdate(d, r, fingerprint, req.Writable())
 }
 
-func doCertificateUpdate(d *Daemon, fingerprint string, req api.CertificatePut) response.Response {
-	if req.Type != "client" {
-		return response.BadRequest(fmt.Errorf("Unknown request type %s", req.Type))
+func doCertificateUpdate(d *Daemon, r *http.Request, fingerprint string, req api.CertificatePut) response.Response {
+	// The certificate was already updated by the notifying member, only refresh the local caches.
+	if isClusterNotification(r) {
+		readSavedClientCAList(d)
+		return response.EmptySyncResponse
+	}
+
+	dbType, err := db.CertificateAPITypeToDBType(req.Type)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	// Unrestricted certificates have access to all projects.
+	if !req.Restricted {
+		req.Projects = nil
+	}
+
+	err = certificateValidateRestriction(d, dbType, req.Restricted, req.Projects)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	dbCert, err := d.cluster.GetCertificate(fingerprint)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	dbCert.Name = req.Name
+	dbCert.Type = dbType
+	dbCert.Restricted = req.Restricted
+
+	err = d.cluster.UpdateCertificateWithProjects(fingerprint, *dbCert, req.Projects)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Reload the local caches as the certificate type or restrictions may have changed
+	readSavedClientCAList(d)
+
+	// Notify other nodes so they reload their caches too.
+	notifier, err := cluster.NewNotifier(d.State(), d.endpoints.NetworkCert(), cluster.NotifyAlive)
+	if err != nil {
+		return response.SmartError(err)
 	}
 
-	err := d.cluster.RenameCertificate(fingerprint, req.Name)
+	err = notifier(func(client lxd.InstanceServer) error {
+		return client.UpdateCertificate(fingerprint, req, "")
+	})
 	if err != nil {
 		return response.SmartError(err)
 	}
@@ -330,9 +587,42 @@ func doCertificateUpdate(d *Daemon, fingerprint string, req api.CertificatePut)
 	return response.EmptySyncResponse
 }
 
+// certificateValidateRestriction checks that only client certificates are restricted and that all the projects
+// they are restricted to exist.
+func certificateValidateRestriction(d *Daemon, dbType int, restricted bool, projects []string) error {
+	if !restricted {
+		if len(projects) > 0 {
+			return fmt.Errorf("Projects can only be set on restricted certificates")
+		}
+
+		return nil
+	}
+
+	if dbType != db.CertificateTypeClient {
+		return fmt.Errorf("Only client certificates can be restricted")
+	}
+
+	return d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		for _, project := range projects {
+			_, err := tx.GetProjectID(project)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to get project %q", project)
+			}
+		}
+
+		return nil
+	})
+}
+
 func certificateDelete(d *Daemon, r *http.Request) response.Response {
 	fingerprint := mux.Vars(r)["fingerprint"]
 
+	// The certificate was already deleted by the notifying member, only refresh the local caches.
+	if isClusterNotification(r) {
+		readSavedClientCAList(d)
+		return response.EmptySyncResponse
+	}
+
 	certInfo, err := d.cluster.GetCertificate(fingerprint)
 	if err != nil {
 		return response.NotFound(err)
@@ -344,5 +634,18 @@ func certificateDelete(d *Daemon, r *http.Request) response.Response {
 	}
 	readSavedClientCAList(d)
 
+	// Notify other nodes so they stop trusting the certificate.
+	notifier, err := cluster.NewNotifier(d.State(), d.endpoints.NetworkCert(), cluster.NotifyAlive)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = notifier(func(client lxd.InstanceServer) error {
+		return client.DeleteCertificate(certInfo.Fingerprint)
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	return response.EmptySyncResponse
 }
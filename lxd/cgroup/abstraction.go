@@ -1,7 +1,11 @@
 package cgroup
 
 import (
+	"bufio"
 	"fmt"
+	"os"
+	"strconv"
+	"strings"
 )
 
 // CGroup represents the main cgroup abstraction.
@@ -323,3 +327,212 @@ func (cg *CGroup) SetMaxHugepages(pageType string, value string) error {
 	}
 	return ErrUnknownVersion
 }
+
+// GetMemoryStats returns the content of the memory.stat file as a map of counters.
+func (cg *CGroup) GetMemoryStats() (map[string]uint64, error) {
+	var stats string
+	var err error
+
+	version := cgControllers["memory"]
+	switch version {
+	case Unavailable:
+		return nil, ErrControllerMissing
+	case V1:
+		fallthrough
+	case V2:
+		stats, err = cg.rw.Get(version, "memory", "memory.stat")
+	default:
+		return nil, ErrUnknownVersion
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	out := map[string]uint64{}
+	for _, line := range strings.Split(stats, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		out[fields[0]] = value
+	}
+
+	return out, nil
+}
+
+// GetIOStats returns the I/O statistics of the block devices, indexed by device name.
+func (cg *CGroup) GetIOStats() (map[string]*IOStats, error) {
+	devices, err := getBlockDevices()
+	if err != nil {
+		return nil, err
+	}
+
+	out := map[string]*IOStats{}
+
+	// Returns the stats for the device with the given major:minor, if it's a known device.
+	deviceStats := func(devNum string) *IOStats {
+		name, ok := devices[devNum]
+		if !ok {
+			return nil
+		}
+
+		if out[name] == nil {
+			out[name] = &IOStats{}
+		}
+
+		return out[name]
+	}
+
+	version := cgControllers["blkio"]
+	if version == Unavailable {
+		version = cgControllers["io"]
+	}
+
+	switch version {
+	case Unavailable:
+		return nil, ErrControllerMissing
+	case V1:
+		val, err := cg.rw.Get(version, "blkio", "blkio.throttle.io_service_bytes_recursive")
+		if err != nil {
+			return nil, err
+		}
+
+		for _, line := range strings.Split(val, "\n") {
+			// Skip the "Total" line.
+			fields := strings.Fields(line)
+			if len(fields) != 3 {
+				continue
+			}
+
+			stats := deviceStats(fields[0])
+			if stats == nil {
+				continue
+			}
+
+			value, err := strconv.ParseUint(fields[2], 10, 64)
+			if err != nil {
+				return nil, err
+			}
+
+			switch fields[1] {
+			case "Read":
+				stats.ReadBytes += value
+			case "Write":
+				stats.WrittenBytes += value
+			}
+		}
+
+		val, err = cg.rw.Get(version, "blkio", "blkio.throttle.io_serviced_recursive")
+		if err != nil {
+			return nil, err
+		}
+
+		for _, line := range strings.Split(val, "\n") {
+			fields := strings.Fields(line)
+			if len(fields) != 3 {
+				continue
+			}
+
+			stats := deviceStats(fields[0])
+			if stats == nil {
+				continue
+			}
+
+			value, err := strconv.ParseUint(fields[2], 10, 64)
+			if err != nil {
+				return nil, err
+			}
+
+			switch fields[1] {
+			case "Read":
+				stats.ReadsCompleted += value
+			case "Write":
+				stats.WritesCompleted += value
+			}
+		}
+
+		return out, nil
+	case V2:
+		val, err := cg.rw.Get(version, "io", "io.stat")
+		if err != nil {
+			return nil, err
+		}
+
+		// Lines look like: 8:0 rbytes=1024 wbytes=2048 rios=1 wios=2 dbytes=0 dios=0
+		for _, line := range strings.Split(val, "\n") {
+			fields := strings.Fields(line)
+			if len(fields) < 2 {
+				continue
+			}
+
+			stats := deviceStats(fields[0])
+			if stats == nil {
+				continue
+			}
+
+			for _, field := range fields[1:] {
+				entry := strings.SplitN(field, "=", 2)
+				if len(entry) != 2 {
+					continue
+				}
+
+				value, err := strconv.ParseUint(entry[1], 10, 64)
+				if err != nil {
+					return nil, err
+				}
+
+				switch entry[0] {
+				case "rbytes":
+					stats.ReadBytes += value
+				case "wbytes":
+					stats.WrittenBytes += value
+				case "rios":
+					stats.ReadsCompleted += value
+				case "wios":
+					stats.WritesCompleted += value
+				}
+			}
+		}
+
+		return out, nil
+	}
+
+	return nil, ErrUnknownVersion
+}
+
+// getBlockDevices returns the block devices known to the kernel, indexed by "major:minor".
+func getBlockDevices() (map[string]string, error) {
+	f, err := os.Open("/proc/partitions")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	devices := map[string]string{}
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		// Lines look like: major minor #blocks name
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 4 {
+			continue
+		}
+
+		_, err := strconv.ParseUint(fields[0], 10, 32)
+		if err != nil {
+			// Header line.
+			continue
+		}
+
+		devices[fmt.Sprintf("%s:%s", fields[0], fields[1])] = fields[3]
+	}
+
+	return devices, scanner.Err()
+}
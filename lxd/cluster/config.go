@@ -15,6 +15,7 @@ import (
 
 	"github.com/lxc/lxd/lxd/config"
 	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/shared"
 )
 
 // Config holds cluster-wide configuration values.
@@ -102,6 +103,21 @@ func (c *Config) RemoteCacheExpiry() int64 {
 	return c.m.GetInt64("images.remote_cache_expiry")
 }
 
+// ImagesSimpleStreamsServer returns whether the public images are published as a simplestreams feed.
+func (c *Config) ImagesSimpleStreamsServer() bool {
+	return c.m.GetBool("images.simplestreams_server")
+}
+
+// JoinTokenExpiry returns the expiry expression of cluster member join tokens.
+func (c *Config) JoinTokenExpiry() string {
+	return c.m.GetString("cluster.join_token_expiry")
+}
+
+// RemoteTokenExpiry returns the expiry expression of certificate add tokens.
+func (c *Config) RemoteTokenExpiry() string {
+	return c.m.GetString("core.remote_token_expiry")
+}
+
 // ProxyHTTPS returns the configured HTTPS proxy, if any.
 func (c *Config) ProxyHTTPS() string {
 	return c.m.GetString("core.proxy_https")
@@ -132,6 +148,13 @@ func (c *Config) OfflineThreshold() time.Duration {
 	return time.Duration(n) * time.Second
 }
 
+// HealingThreshold returns the configured healing threshold, i.e. the number of seconds after which the instances
+// of an offline member get moved to other members. Zero means healing is disabled.
+func (c *Config) HealingThreshold() time.Duration {
+	n := c.m.GetInt64("cluster.healing_threshold")
+	return time.Duration(n) * time.Second
+}
+
 // ImagesMinimalReplica returns the numbers of nodes for cluster images replication
 func (c *Config) ImagesMinimalReplica() int64 {
 	return c.m.GetInt64("cluster.images_minimal_replica")
@@ -243,6 +266,8 @@ var ConfigSchema = config.Schema{
 	"cluster.images_minimal_replica": {Type: config.Int64, Default: "3", Validator: imageMinimalReplicaValidator},
 	"cluster.max_voters":             {Type: config.Int64, Default: "3", Validator: maxVotersValidator},
 	"cluster.max_standby":            {Type: config.Int64, Default: "2", Validator: maxStandByValidator},
+	"cluster.join_token_expiry":      {Default: "3H", Validator: expiryValidator},
+	"cluster.healing_threshold":      {Type: config.Int64, Default: "0", Validator: healingThresholdValidator},
 	"core.https_allowed_headers":     {},
 	"core.https_allowed_methods":     {},
 	"core.https_allowed_origin":      {},
@@ -250,6 +275,7 @@ var ConfigSchema = config.Schema{
 	"core.proxy_http":                {},
 	"core.proxy_https":               {},
 	"core.proxy_ignore_hosts":        {},
+	"core.remote_token_expiry":       {Validator: expiryValidator},
 	"core.trust_password":            {Hidden: true, Setter: passwordSetter},
 	"core.trust_ca_certificates":     {Type: config.Bool},
 	"candid.api.key":                 {},
@@ -260,6 +286,7 @@ var ConfigSchema = config.Schema{
 	"images.auto_update_interval":    {Type: config.Int64, Default: "6"},
 	"images.compression_algorithm":   {Default: "gzip", Validator: validateCompression},
 	"images.remote_cache_expiry":     {Type: config.Int64, Default: "10"},
+	"images.simplestreams_server":    {Type: config.Bool},
 	"maas.api.key":                   {},
 	"maas.api.url":                   {},
 	"rbac.agent.url":                 {},
@@ -305,6 +332,29 @@ func offlineThresholdValidator(value string) error {
 	return nil
 }
 
+func healingThresholdValidator(value string) error {
+	threshold, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("Healing threshold is not a number")
+	}
+
+	// Zero disables healing, anything else must leave the heartbeats time to detect the member as offline.
+	if threshold != 0 && threshold <= heartbeatInterval {
+		return fmt.Errorf("Value must be 0 or greater than '%d'", heartbeatInterval)
+	}
+
+	return nil
+}
+
+func expiryValidator(value string) error {
+	_, err := shared.GetSnapshotExpiry(time.Time{}, value)
+	if err != nil {
+		return fmt.Errorf("Invalid expiry expression")
+	}
+
+	return nil
+}
+
 func imageMinimalReplicaValidator(value string) error {
 	count, err := strconv.Atoi(value)
 	if err != nil {
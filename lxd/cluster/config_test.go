@@ -64,6 +64,24 @@ func TestConfigLoad_OfflineThresholdValidator(t *testing.T) {
 
 }
 
+// Healing threshold must be either zero or greater than the heartbeat interval.
+func TestConfigLoad_HealingThresholdValidator(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	config, err := cluster.ConfigLoad(tx)
+	require.NoError(t, err)
+
+	assert.Equal(t, float64(0), config.HealingThreshold().Seconds())
+
+	_, err = config.Patch(map[string]interface{}{"cluster.healing_threshold": "5"})
+	require.EqualError(t, err, "cannot set 'cluster.healing_threshold' to '5': Value must be 0 or greater than '10'")
+
+	_, err = config.Patch(map[string]interface{}{"cluster.healing_threshold": "60"})
+	require.NoError(t, err)
+	assert.Equal(t, float64(60), config.HealingThreshold().Seconds())
+}
+
 // Max number of voters must be odd.
 func TestConfigLoad_MaxVotersValidator(t *testing.T) {
 	tx, cleanup := db.NewTestClusterTx(t)
@@ -158,10 +158,16 @@ func ConnectIfVolumeIsRemote(cluster *db.Cluster, poolID int64, volumeName strin
 	return Connect(address, cert, false)
 }
 
+// TrustCertificateName returns the name of the trusted certificate of the
+// cluster member with the given name.
+func TrustCertificateName(serverName string) string {
+	return fmt.Sprintf("lxd.cluster.%s", serverName)
+}
+
 // SetupTrust is a convenience around InstanceServer.CreateCertificate that
-// adds the given client certificate to the trusted pool of the cluster at the
-// given address, using the given password.
-func SetupTrust(cert, targetAddress, targetCert, targetPassword string) error {
+// adds the given client certificate of the member with the given name to the
+// trusted pool of the cluster at the given address, using the given password.
+func SetupTrust(cert, serverName, targetAddress, targetCert, targetPassword string) error {
 	// Connect to the target cluster node.
 	args := &lxd.ConnectionArgs{
 		TLSServerCert: targetCert,
@@ -184,12 +190,7 @@ func SetupTrust(cert, targetAddress, targetCert, targetPassword string) error {
 		Certificate: certificate,
 	}
 
-	fingerprint, err := shared.CertFingerprintStr(cert)
-	if err != nil {
-		return errors.Wrap(err, "failed to calculate fingerprint")
-	}
-
-	post.Name = fmt.Sprintf("lxd.cluster.%s", fingerprint)
+	post.Name = TrustCertificateName(serverName)
 	post.Type = "client"
 
 	err = target.CreateCertificate(post)
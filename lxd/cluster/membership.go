@@ -12,6 +12,7 @@ import (
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/db/cluster"
 	"github.com/lxc/lxd/lxd/node"
+	"github.com/lxc/lxd/lxd/project"
 	"github.com/lxc/lxd/lxd/state"
 	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
@@ -407,22 +408,30 @@ func Join(state *state.State, gateway *Gateway, cert *shared.CertInfo, name stri
 		}
 
 		// Networks.
-		ids, err = tx.GetNonPendingNetworkIDs()
+		netids, err := tx.GetNonPendingNetworkIDs()
 		if err != nil {
 			return errors.Wrap(err, "failed to get cluster network IDs")
 		}
-		for name, id := range ids {
-			config, ok := networks[name]
-			if !ok {
-				return fmt.Errorf("joining node has no config for network %s", name)
-			}
-			err := tx.NetworkNodeJoin(id, node.ID)
-			if err != nil {
-				return errors.Wrap(err, "failed to add joining node's to the network")
-			}
-			err = tx.CreateNetworkConfig(id, node.ID, config)
-			if err != nil {
-				return errors.Wrap(err, "failed to add joining node's network config")
+		for networkProject, networkIDs := range netids {
+			for name, id := range networkIDs {
+				err := tx.NetworkNodeJoin(id, node.ID)
+				if err != nil {
+					return errors.Wrap(err, "failed to add joining node's to the network")
+				}
+
+				// Only networks in the default project have node-specific config.
+				if networkProject != project.Default {
+					continue
+				}
+
+				config, ok := networks[name]
+				if !ok {
+					return fmt.Errorf("joining node has no config for network %s", name)
+				}
+				err = tx.CreateNetworkConfig(id, node.ID, config)
+				if err != nil {
+					return errors.Wrap(err, "failed to add joining node's network config")
+				}
 			}
 		}
 
@@ -898,6 +907,7 @@ func List(state *state.State, gateway *Gateway) ([]api.ClusterMember, error) {
 	var err error
 	var nodes []db.NodeInfo
 	var offlineThreshold time.Duration
+	var nodesGroups map[int64][]string
 	domains := map[string]string{}
 
 	err = state.Cluster.Transaction(func(tx *db.ClusterTx) error {
@@ -926,6 +936,11 @@ func List(state *state.State, gateway *Gateway) ([]api.ClusterMember, error) {
 			domains[node.Address] = domainsNames[domainID]
 		}
 
+		nodesGroups, err = tx.GetNodesClusterGroups()
+		if err != nil {
+			return errors.Wrap(err, "Load nodes cluster groups")
+		}
+
 		return nil
 	})
 	if err != nil {
@@ -973,11 +988,18 @@ func List(state *state.State, gateway *Gateway) ([]api.ClusterMember, error) {
 			return nil, err
 		}
 		result[i].FailureDomain = domains[node.Address]
+		result[i].Groups = nodesGroups[node.ID]
+		if result[i].Groups == nil {
+			result[i].Groups = []string{}
+		}
 
 		if node.IsOffline(offlineThreshold) {
 			result[i].Status = "Offline"
 			result[i].Message = fmt.Sprintf(
 				"no heartbeat since %s", now.Sub(node.Heartbeat))
+		} else if node.State == db.ClusterMemberStateEvacuated {
+			result[i].Status = "Evacuated"
+			result[i].Message = "unavailable due to maintenance"
 		} else {
 			result[i].Status = "Online"
 			result[i].Message = "fully operational"
@@ -270,7 +270,7 @@ func TestJoin(t *testing.T) {
 
 	err = cluster.Bootstrap(targetState, targetGateway, "buzz")
 	require.NoError(t, err)
-	_, err = targetState.Cluster.GetNetworks()
+	_, err = targetState.Cluster.GetNetworks("default")
 	require.NoError(t, err)
 
 	// Setup a joining node
@@ -62,6 +62,11 @@ import (
 // A Daemon can respond to requests from a shared client.
 type Daemon struct {
 	clientCerts  map[string]x509.Certificate
+	metricsCerts map[string]x509.Certificate
+
+	// Projects of the restricted client certificates, indexed by fingerprint.
+	restrictedCerts map[string][]string
+
 	os           *sys.OS
 	db           *db.Node
 	firewall     firewall.Firewall
@@ -97,6 +102,11 @@ type Daemon struct {
 	// Stores last heartbeat node information to detect node changes.
 	lastNodeList *cluster.APIHeartbeat
 
+	// Cluster database used while this member waits for the other members
+	// to be upgraded, nil otherwise.
+	upgradeWaitingCluster *db.Cluster
+	upgradeWaitingLock    sync.Mutex
+
 	// Serialize changes to cluster membership (joins, leaves, role
 	// changes).
 	clusterMembershipMutex   sync.RWMutex
@@ -219,6 +229,27 @@ func allowAuthenticated(d *Daemon, r *http.Request) response.Response {
 	return response.EmptySyncResponse
 }
 
+// allowUnrestricted denies access to restricted client certificates, all other authenticated clients are allowed.
+func allowUnrestricted(d *Daemon, r *http.Request) response.Response {
+	_, restricted := d.userRestrictedProjects(r)
+	if restricted {
+		return response.Forbidden(nil)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// allowAuthenticatedProject allows all authenticated clients, except restricted client certificates querying a
+// project they don't have access to.
+func allowAuthenticatedProject(d *Daemon, r *http.Request) response.Response {
+	_, restricted := d.userRestrictedProjects(r)
+	if restricted && !d.userHasPermission(r, projectParam(r), "view") {
+		return response.Forbidden(nil)
+	}
+
+	return response.EmptySyncResponse
+}
+
 // allowProjectPermission is a wrapper to check access against the project, its features and RBAC permission
 func allowProjectPermission(feature string, permission string) func(d *Daemon, r *http.Request) response.Response {
 	return func(d *Daemon, r *http.Request) response.Response {
@@ -260,15 +291,8 @@ func (d *Daemon) checkTrustedClient(r *http.Request) error {
 // This does not perform authorization, only validates authentication
 func (d *Daemon) Authenticate(r *http.Request) (bool, string, string, error) {
 	// Allow internal cluster traffic
-	if r.TLS != nil {
-		cert, _ := x509.ParseCertificate(d.endpoints.NetworkCert().KeyPair().Certificate[0])
-		clusterCerts := map[string]x509.Certificate{"0": *cert}
-		for i := range r.TLS.PeerCertificates {
-			trusted, _ := util.CheckTrustState(*r.TLS.PeerCertificates[i], clusterCerts, nil, false)
-			if trusted {
-				return true, "", "cluster", nil
-			}
-		}
+	if d.isClusterRequest(r) {
+		return true, "", "cluster", nil
 	}
 
 	// Local unix socket queries
@@ -331,10 +355,38 @@ func (d *Daemon) Authenticate(r *http.Request) (bool, string, string, error) {
 		}
 	}
 
+	// Metrics certificates are only valid for the metrics endpoint
+	if r.URL.Path == fmt.Sprintf("/%s/metrics", version.APIVersion) {
+		for i := range r.TLS.PeerCertificates {
+			trusted, username := util.CheckTrustState(*r.TLS.PeerCertificates[i], d.metricsCerts, d.endpoints.NetworkCert(), false)
+			if trusted {
+				return true, username, "tls", nil
+			}
+		}
+	}
+
 	// Reject unauthorized
 	return false, "", "", nil
 }
 
+// isClusterRequest returns whether the request was made by another cluster member, using the cluster certificate.
+func (d *Daemon) isClusterRequest(r *http.Request) bool {
+	if r.TLS == nil {
+		return false
+	}
+
+	cert, _ := x509.ParseCertificate(d.endpoints.NetworkCert().KeyPair().Certificate[0])
+	clusterCerts := map[string]x509.Certificate{"0": *cert}
+	for i := range r.TLS.PeerCertificates {
+		trusted, _ := util.CheckTrustState(*r.TLS.PeerCertificates[i], clusterCerts, nil, false)
+		if trusted {
+			return true
+		}
+	}
+
+	return false
+}
+
 func writeMacaroonsRequiredResponse(b *identchecker.Bakery, r *http.Request, w http.ResponseWriter, derr *bakery.DischargeRequiredError, expiry int64) {
 	ctx := httpbakery.ContextWithRequest(context.TODO(), r)
 	caveats := append(derr.Caveats,
@@ -569,6 +621,11 @@ func (d *Daemon) Init() error {
 }
 
 func (d *Daemon) init() error {
+	var dbWarnings []db.Warning
+
+	// Keep track of when the daemon started, so that stale startup warnings can be resolved.
+	startTime := time.Now().UTC()
+
 	// Setup logger
 	events.LoggingServer = d.events
 
@@ -597,11 +654,13 @@ func (d *Daemon) init() error {
 	trace := d.config.Trace
 
 	/* Initialize the operating system facade */
-	err = d.os.Init()
+	osWarnings, err := d.os.Init()
 	if err != nil {
 		return err
 	}
 
+	dbWarnings = append(dbWarnings, osWarnings...)
+
 	// Bump some kernel limits to avoid issues
 	for _, limit := range []int{unix.RLIMIT_NOFILE} {
 		rLimit := unix.Rlimit{}
@@ -668,6 +727,10 @@ func (d *Daemon) init() error {
 		logger.Infof(" - uevent injection: yes")
 	} else {
 		logger.Infof(" - uevent injection: no")
+		dbWarnings = append(dbWarnings, db.Warning{
+			TypeCode:    db.WarningTypeMissingUeventInjection,
+			LastMessage: "Hotplugging of USB and unix devices into containers won't be available",
+		})
 	}
 
 	d.os.SeccompListener = canUseSeccompListener()
@@ -675,6 +738,10 @@ func (d *Daemon) init() error {
 		logger.Infof(" - seccomp listener: yes")
 	} else {
 		logger.Infof(" - seccomp listener: no")
+		dbWarnings = append(dbWarnings, db.Warning{
+			TypeCode:    db.WarningTypeMissingSeccompListener,
+			LastMessage: "System call interception won't be available",
+		})
 	}
 
 	d.os.SeccompListenerContinue = canUseSeccompListenerContinue()
@@ -805,6 +872,11 @@ func (d *Daemon) init() error {
 		return errors.Wrap(err, "Failed to fetch debug address")
 	}
 
+	storageBucketsAddress, err := node.StorageBucketsAddress(d.db)
+	if err != nil {
+		return errors.Wrap(err, "Failed to fetch storage buckets address")
+	}
+
 	/* Setup the web server */
 	config := &endpoints.Config{
 		Dir:                  d.os.VarDir,
@@ -816,6 +888,9 @@ func (d *Daemon) init() error {
 		NetworkAddress:       address,
 		ClusterAddress:       clusterAddress,
 		DebugAddress:         debugAddress,
+
+		StorageBucketsServer:  storageBucketsServer(d),
+		StorageBucketsAddress: storageBucketsAddress,
 	}
 	d.endpoints, err = endpoints.Up(config)
 	if err != nil {
@@ -876,7 +951,18 @@ func (d *Daemon) init() error {
 			// leader.
 			d.gateway.Cluster = d.cluster
 			stop, _ := task.Start(cluster.HeartbeatTask(d.gateway))
+
+			// Meanwhile, serve local clients in read-only mode.
+			d.upgradeWaitingLock.Lock()
+			d.upgradeWaitingCluster = d.cluster
+			d.upgradeWaitingLock.Unlock()
+
 			d.gateway.WaitUpgradeNotification()
+
+			d.upgradeWaitingLock.Lock()
+			d.upgradeWaitingCluster = nil
+			d.upgradeWaitingLock.Unlock()
+
 			stop(time.Second)
 			d.gateway.Cluster = nil
 
@@ -890,6 +976,34 @@ func (d *Daemon) init() error {
 	d.firewall = firewall.New()
 	logger.Infof("Firewall loaded driver %q", d.firewall)
 
+	_, err = d.firewall.Compat()
+	if err != nil {
+		dbWarnings = append(dbWarnings, db.Warning{
+			TypeCode:    db.WarningTypeFirewallNotCompatible,
+			LastMessage: fmt.Sprintf("Falling back to the %q firewall driver, some features may not work as expected: %v", d.firewall, err),
+		})
+	}
+
+	// Record the warnings detected during startup and resolve the ones which are gone.
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		for _, w := range dbWarnings {
+			err := tx.UpsertWarningLocalNode("", db.EntityTypeNone, 0, w.TypeCode, w.LastMessage)
+			if err != nil {
+				return err
+			}
+		}
+
+		return tx.ResolveWarningsByLocalNodeOlderThan(startTime,
+			db.WarningTypeAppArmorNotAvailable,
+			db.WarningTypeMissingSeccompListener,
+			db.WarningTypeMissingUeventInjection,
+			db.WarningTypeFirewallNotCompatible,
+		)
+	})
+	if err != nil {
+		logger.Warn("Failed to record startup warnings", log.Ctx{"err": err})
+	}
+
 	err = cluster.NotifyUpgradeCompleted(d.State(), certInfo)
 	if err != nil {
 		// Ignore the error, since it's not fatal for this particular
@@ -1088,6 +1202,9 @@ func (d *Daemon) startClusterTasks() {
 	// Auto-sync images across the cluster (daily)
 	d.clusterTasks.Add(autoSyncImagesTask(d))
 
+	// Heal the members which have been offline for too long
+	d.clusterTasks.Add(autoHealClusterTask(d))
+
 	// Start all background tasks
 	d.clusterTasks.Start()
 }
@@ -1141,6 +1258,18 @@ func (d *Daemon) Ready() error {
 
 		// Take snapshot of custom volumes (minutely check of configurable cron expression)
 		d.tasks.Add(autoCreateCustomVolumeSnapshotsTask(d))
+
+		// Take backups of instances and custom volumes (minutely check of configurable cron expression)
+		d.tasks.Add(autoCreateBackupsTask(d))
+
+		// Remove resolved warnings (daily)
+		d.tasks.Add(pruneResolvedWarningsTask(d))
+
+		// Generate the deltas of the published images (hourly)
+		d.tasks.Add(simpleStreamsDeltasTask(d))
+
+		// Check that the host resources can be retrieved (hourly)
+		d.tasks.Add(hostResourcesCheckTask(d))
 	}
 
 	// Start all background tasks
@@ -1418,7 +1547,26 @@ func (d *Daemon) setupRBACServer(rbacURL string, rbacKey string, rbacExpiry int6
 	return nil
 }
 
+// userRestrictedProjects returns the projects a request coming from a restricted client certificate has access
+// to, along with whether the client certificate is restricted.
+func (d *Daemon) userRestrictedProjects(r *http.Request) ([]string, bool) {
+	if r.Context().Value("protocol") != "tls" {
+		return nil, false
+	}
+
+	username, _ := r.Context().Value("username").(string)
+	projects, restricted := d.restrictedCerts[username]
+
+	return projects, restricted
+}
+
 func (d *Daemon) userIsAdmin(r *http.Request) bool {
+	// Restricted client certificates are limited to their projects.
+	_, restricted := d.userRestrictedProjects(r)
+	if restricted {
+		return false
+	}
+
 	if d.externalAuth == nil || d.rbac == nil || r.RemoteAddr == "@" {
 		return true
 	}
@@ -1435,6 +1583,12 @@ func (d *Daemon) userIsAdmin(r *http.Request) bool {
 }
 
 func (d *Daemon) userHasPermission(r *http.Request, project string, permission string) bool {
+	// Restricted client certificates can do anything within their projects but change the projects themselves.
+	projects, restricted := d.userRestrictedProjects(r)
+	if restricted {
+		return permission != "manage-projects" && shared.StringInSlice(project, projects)
+	}
+
 	if d.externalAuth == nil || d.rbac == nil || r.RemoteAddr == "@" {
 		return true
 	}
@@ -1567,6 +1721,17 @@ func (d *Daemon) NodeRefreshTask(heartbeatData *cluster.APIHeartbeat) {
 		}
 	}
 
+	// If heartbeats stopped reaching this member for a while, the leader may have moved some of its instances
+	// to other members in the meantime.
+	if d.lastNodeList != nil && !d.lastNodeList.Time.IsZero() {
+		go func(gap time.Duration) {
+			err := clusterHealingFence(d, gap)
+			if err != nil {
+				logger.Errorf("Failed to stop instances moved to other cluster members: %v", err)
+			}
+		}(heartbeatData.Time.Sub(d.lastNodeList.Time))
+	}
+
 	isDegraded := false
 	hasNodesNotPartOfRaft := false
 	voters := 0
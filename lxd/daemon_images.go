@@ -46,7 +46,7 @@ func (d *Daemon) ImageDownload(op *operations.Operation, server string, protocol
 	fp := alias
 
 	// Attempt to resolve the alias
-	if shared.StringInSlice(protocol, []string{"lxd", "simplestreams"}) {
+	if shared.StringInSlice(protocol, []string{"lxd", "simplestreams", "oci"}) {
 		args := &lxd.ConnectionArgs{
 			TLSServerCert: certificate,
 			UserAgent:     version.UserAgent,
@@ -61,6 +61,12 @@ func (d *Daemon) ImageDownload(op *operations.Operation, server string, protocol
 			if err != nil {
 				return nil, err
 			}
+		} else if protocol == "oci" {
+			// Setup OCI registry client
+			remote, err = lxd.ConnectOCI(server, args)
+			if err != nil {
+				return nil, err
+			}
 		} else {
 			// Setup simplestreams client
 			remote, err = lxd.ConnectSimpleStreams(server, args)
@@ -257,7 +263,7 @@ func (d *Daemon) ImageDownload(op *operations.Operation, server string, protocol
 		op.SetCanceler(canceler)
 	}
 
-	if protocol == "lxd" || protocol == "simplestreams" {
+	if protocol == "lxd" || protocol == "simplestreams" || protocol == "oci" {
 		// Create the target files
 		dest, err := os.Create(destName)
 		if err != nil {
@@ -300,45 +306,83 @@ func (d *Daemon) ImageDownload(op *operations.Operation, server string, protocol
 				info.Size, budget)
 		}
 
-		// Download the image
-		var resp *lxd.ImageFileResponse
-		request := lxd.ImageFileRequest{
-			MetaFile:        io.WriteSeeker(dest),
-			RootfsFile:      io.WriteSeeker(destRootfs),
-			ProgressHandler: progress,
-			Canceler:        canceler,
-			DeltaSourceRetriever: func(fingerprint string, file string) string {
-				path := shared.VarPath("images", fmt.Sprintf("%s.%s", fingerprint, file))
-				if shared.PathExists(path) {
-					return path
+		// Download the image, rebuilding the rootfs from a delta against a local image if allowed
+		downloadFiles := func(useDeltas bool) (*lxd.ImageFileResponse, error) {
+			for _, f := range []*os.File{dest, destRootfs} {
+				_, err := f.Seek(0, 0)
+				if err != nil {
+					return nil, err
 				}
+			}
 
-				return ""
-			},
-		}
+			request := lxd.ImageFileRequest{
+				MetaFile:        io.WriteSeeker(dest),
+				RootfsFile:      io.WriteSeeker(destRootfs),
+				ProgressHandler: progress,
+				Canceler:        canceler,
+			}
 
-		if secret != "" {
-			resp, err = remote.GetPrivateImageFile(fp, secret, request)
-		} else {
-			resp, err = remote.GetImageFile(fp, request)
-		}
-		if err != nil {
-			return nil, err
-		}
+			if useDeltas {
+				request.DeltaSourceRetriever = func(fingerprint string, file string) string {
+					path := shared.VarPath("images", fmt.Sprintf("%s.%s", fingerprint, file))
+					if shared.PathExists(path) {
+						return path
+					}
+
+					return ""
+				}
+			}
+
+			var resp *lxd.ImageFileResponse
+			if secret != "" {
+				resp, err = remote.GetPrivateImageFile(fp, secret, request)
+			} else {
+				resp, err = remote.GetImageFile(fp, request)
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			// Truncate down to size
+			if resp.RootfsSize > 0 {
+				err = destRootfs.Truncate(resp.RootfsSize)
+				if err != nil {
+					return nil, err
+				}
+			}
 
-		// Truncate down to size
-		if resp.RootfsSize > 0 {
-			err = destRootfs.Truncate(resp.RootfsSize)
+			err = dest.Truncate(resp.MetaSize)
 			if err != nil {
 				return nil, err
 			}
+
+			return resp, nil
 		}
 
-		err = dest.Truncate(resp.MetaSize)
+		resp, err := downloadFiles(true)
 		if err != nil {
 			return nil, err
 		}
 
+		// Simplestreams files are only verified one by one, so check the image as a whole matches its
+		// fingerprint and download it in full if it doesn't (e.g. a delta was applied to the wrong source).
+		if protocol == "simplestreams" {
+			err = imageVerifyFingerprint(destName, resp.RootfsSize > 0, info.Fingerprint)
+			if err != nil {
+				logger.Warn("Downloaded image doesn't match its fingerprint, downloading it again in full", log.Ctx{"fingerprint": info.Fingerprint, "err": err})
+
+				resp, err = downloadFiles(false)
+				if err != nil {
+					return nil, err
+				}
+
+				err = imageVerifyFingerprint(destName, resp.RootfsSize > 0, info.Fingerprint)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+
 		// Deal with unified images
 		if resp.RootfsSize == 0 {
 			err := os.Remove(destName + ".rootfs")
@@ -346,6 +390,11 @@ func (d *Daemon) ImageDownload(op *operations.Operation, server string, protocol
 				return nil, err
 			}
 		}
+
+		// OCI images are built locally out of their layers
+		if protocol == "oci" {
+			info.Size = resp.MetaSize
+		}
 	} else if protocol == "direct" {
 		// Setup HTTP client
 		httpClient, err := util.HTTPClient(certificate, d.proxy)
@@ -490,3 +539,33 @@ func (d *Daemon) ImageDownload(op *operations.Operation, server string, protocol
 	logger.Info("Image downloaded", ctxMap)
 	return info, nil
 }
+
+// imageVerifyFingerprint checks that the image stored at the given path (and its rootfs file for split images)
+// matches the given fingerprint.
+func imageVerifyFingerprint(path string, split bool, fingerprint string) error {
+	paths := []string{path}
+	if split {
+		paths = append(paths, path+".rootfs")
+	}
+
+	hash := sha256.New()
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+
+		_, err = io.Copy(hash, f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	result := fmt.Sprintf("%x", hash.Sum(nil))
+	if result != fingerprint {
+		return fmt.Errorf("Image fingerprint mismatch: %s != %s", result, fingerprint)
+	}
+
+	return nil
+}
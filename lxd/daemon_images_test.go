@@ -0,0 +1,46 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImageVerifyFingerprint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lxd-images-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "image")
+	err = ioutil.WriteFile(path, []byte("meta"), 0644)
+	require.NoError(t, err)
+
+	err = ioutil.WriteFile(path+".rootfs", []byte("rootfs"), 0644)
+	require.NoError(t, err)
+
+	unified := fmt.Sprintf("%x", sha256.Sum256([]byte("meta")))
+	split := fmt.Sprintf("%x", sha256.Sum256([]byte("metarootfs")))
+
+	// Split images are fingerprinted over both files.
+	assert.NoError(t, imageVerifyFingerprint(path, true, split))
+	assert.EqualError(t, imageVerifyFingerprint(path, true, unified), fmt.Sprintf("Image fingerprint mismatch: %s != %s", split, unified))
+
+	// Unified images only have the one file.
+	assert.NoError(t, imageVerifyFingerprint(path, false, unified))
+	assert.EqualError(t, imageVerifyFingerprint(path, false, split), fmt.Sprintf("Image fingerprint mismatch: %s != %s", unified, split))
+
+	// A rootfs rebuilt from the wrong source is caught.
+	err = ioutil.WriteFile(path+".rootfs", []byte("rootfs-corrupted"), 0644)
+	require.NoError(t, err)
+	assert.Error(t, imageVerifyFingerprint(path, true, split))
+
+	err = os.Remove(path + ".rootfs")
+	require.NoError(t, err)
+	assert.Error(t, imageVerifyFingerprint(path, true, split))
+}
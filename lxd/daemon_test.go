@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/lxd/response"
+)
+
+// newRestrictedTestRequest returns a request coming from the client certificate with the given fingerprint over the
+// given protocol, as set up by the API router once the request is authenticated.
+func newRestrictedTestRequest(t *testing.T, protocol string, fingerprint string, url string) *http.Request {
+	r, err := http.NewRequest("GET", url, nil)
+	require.NoError(t, err)
+
+	ctx := context.WithValue(context.WithValue(r.Context(), "username", fingerprint), "protocol", protocol)
+	return r.WithContext(ctx)
+}
+
+func TestDaemon_RestrictedCertificates(t *testing.T) {
+	d := &Daemon{restrictedCerts: map[string][]string{"restricted": {"p1"}}}
+
+	restricted := newRestrictedTestRequest(t, "tls", "restricted", "/1.0")
+	unrestricted := newRestrictedTestRequest(t, "tls", "unrestricted", "/1.0")
+	unix := newRestrictedTestRequest(t, "unix", "restricted", "/1.0")
+
+	projects, isRestricted := d.userRestrictedProjects(restricted)
+	assert.True(t, isRestricted)
+	assert.Equal(t, []string{"p1"}, projects)
+
+	// Restrictions only apply to client certificates.
+	for _, r := range []*http.Request{unrestricted, unix} {
+		_, isRestricted := d.userRestrictedProjects(r)
+		assert.False(t, isRestricted)
+		assert.True(t, d.userIsAdmin(r))
+		assert.True(t, d.userHasPermission(r, "p2", "manage-projects"))
+		assert.Equal(t, response.EmptySyncResponse, allowUnrestricted(d, r))
+	}
+
+	// Restricted certificates can do anything within their projects but manage them.
+	assert.False(t, d.userIsAdmin(restricted))
+	assert.True(t, d.userHasPermission(restricted, "p1", "view"))
+	assert.True(t, d.userHasPermission(restricted, "p1", "manage-containers"))
+	assert.False(t, d.userHasPermission(restricted, "p1", "manage-projects"))
+	assert.False(t, d.userHasPermission(restricted, "p2", "view"))
+	assert.False(t, d.userHasPermission(restricted, "default", "view"))
+	assert.Equal(t, response.Forbidden(nil), allowUnrestricted(d, restricted))
+}
+
+func TestAllowAuthenticatedProject(t *testing.T) {
+	d := &Daemon{restrictedCerts: map[string][]string{"restricted": {"p1"}}}
+
+	r := newRestrictedTestRequest(t, "tls", "restricted", "/1.0/warnings?project=p1")
+	assert.Equal(t, response.EmptySyncResponse, allowAuthenticatedProject(d, r))
+
+	// The default project is used when none is given.
+	for _, url := range []string{"/1.0/warnings?project=p2", "/1.0/warnings"} {
+		r := newRestrictedTestRequest(t, "tls", "restricted", url)
+		assert.Equal(t, response.Forbidden(nil), allowAuthenticatedProject(d, r))
+
+		r = newRestrictedTestRequest(t, "tls", "unrestricted", url)
+		assert.Equal(t, response.EmptySyncResponse, allowAuthenticatedProject(d, r))
+	}
+}
+
+func TestOperationAllowed(t *testing.T) {
+	d := &Daemon{restrictedCerts: map[string][]string{"restricted": {"p1"}}}
+
+	restricted := newRestrictedTestRequest(t, "tls", "restricted", "/1.0/operations")
+	unrestricted := newRestrictedTestRequest(t, "tls", "unrestricted", "/1.0/operations")
+
+	opP1, err := operations.OperationCreate(nil, "p1", operations.OperationClassTask, db.OperationSnapshotCreate, nil, nil, nil, nil, nil)
+	require.NoError(t, err)
+
+	opP2, err := operations.OperationCreate(nil, "p2", operations.OperationClassTask, db.OperationSnapshotCreate, nil, nil, nil, nil, nil)
+	require.NoError(t, err)
+
+	opNoProject, err := operations.OperationCreate(nil, "", operations.OperationClassTask, db.OperationSnapshotCreate, nil, nil, nil, nil, nil)
+	require.NoError(t, err)
+
+	opToken, err := operations.OperationCreate(nil, "p1", operations.OperationClassToken, db.OperationCertificateAddToken, nil, nil, nil, nil, nil)
+	require.NoError(t, err)
+
+	// Restricted certificates only see the operations of their projects and never tokens.
+	assert.True(t, operationAllowed(d, restricted, opP1))
+	assert.False(t, operationAllowed(d, restricted, opP2))
+	assert.False(t, operationAllowed(d, restricted, opNoProject))
+	assert.False(t, operationAllowed(d, restricted, opToken))
+
+	for _, op := range []*operations.Operation{opP1, opP2, opNoProject, opToken} {
+		assert.True(t, operationAllowed(d, unrestricted, op))
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/lxc/lxd/lxd/db/query"
 	"github.com/lxc/lxd/shared/logger"
 
 	log "github.com/lxc/lxd/shared/log15"
@@ -22,6 +23,7 @@ type InstanceBackup struct {
 	InstanceOnly         bool
 	OptimizedStorage     bool
 	CompressionAlgorithm string
+	Parent               string
 }
 
 // Returns the ID of the instance backup with the given name.
@@ -48,7 +50,8 @@ func (c *Cluster) GetInstanceBackup(project, name string) (InstanceBackup, error
 	q := `
 SELECT instances_backups.id, instances_backups.instance_id,
        instances_backups.creation_date, instances_backups.expiry_date,
-       instances_backups.container_only, instances_backups.optimized_storage
+       instances_backups.container_only, instances_backups.optimized_storage,
+       instances_backups.parent
     FROM instances_backups
     JOIN instances ON instances.id=instances_backups.instance_id
     JOIN projects ON projects.id=instances.project_id
@@ -56,7 +59,7 @@ SELECT instances_backups.id, instances_backups.instance_id,
 `
 	arg1 := []interface{}{project, name}
 	arg2 := []interface{}{&args.ID, &args.InstanceID, &args.CreationDate,
-		&args.ExpiryDate, &instanceOnlyInt, &optimizedStorageInt}
+		&args.ExpiryDate, &instanceOnlyInt, &optimizedStorageInt, &args.Parent}
 	err := dbQueryRowScan(c, q, arg1, arg2)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -118,7 +121,7 @@ func (c *Cluster) CreateInstanceBackup(args InstanceBackup) error {
 			optimizedStorageInt = 1
 		}
 
-		str := fmt.Sprintf("INSERT INTO instances_backups (instance_id, name, creation_date, expiry_date, container_only, optimized_storage) VALUES (?, ?, ?, ?, ?, ?)")
+		str := fmt.Sprintf("INSERT INTO instances_backups (instance_id, name, creation_date, expiry_date, container_only, optimized_storage, parent) VALUES (?, ?, ?, ?, ?, ?, ?)")
 		stmt, err := tx.tx.Prepare(str)
 		if err != nil {
 			return err
@@ -126,7 +129,7 @@ func (c *Cluster) CreateInstanceBackup(args InstanceBackup) error {
 		defer stmt.Close()
 		result, err := stmt.Exec(args.InstanceID, args.Name,
 			args.CreationDate.Unix(), args.ExpiryDate.Unix(), instanceOnlyInt,
-			optimizedStorageInt)
+			optimizedStorageInt, args.Parent)
 		if err != nil {
 			return err
 		}
@@ -178,6 +181,12 @@ func (c *Cluster) RenameInstanceBackup(oldName, newName string) error {
 			return err
 		}
 
+		// Keep incremental backups pointing at their renamed parent.
+		_, err = tx.tx.Exec("UPDATE instances_backups SET parent = ? WHERE parent = ?", newName, oldName)
+		if err != nil {
+			return err
+		}
+
 		return nil
 	})
 	return err
@@ -225,3 +234,176 @@ func (c *Cluster) GetExpiredInstanceBackups() ([]InstanceBackup, error) {
 
 	return result, nil
 }
+
+// StoragePoolVolumeBackup is a value object holding all db-related details about a custom volume backup.
+type StoragePoolVolumeBackup struct {
+	ID                   int
+	VolumeID             int64
+	Name                 string
+	CreationDate         time.Time
+	ExpiryDate           time.Time
+	VolumeOnly           bool
+	OptimizedStorage     bool
+	CompressionAlgorithm string
+
+	// Set when loading the backup from the database.
+	ProjectName string
+	PoolName    string
+}
+
+// Returns the custom volume backups of the local node matching the given condition.
+func (c *ClusterTx) getStoragePoolVolumeBackups(where string, args ...interface{}) ([]StoragePoolVolumeBackup, error) {
+	q := fmt.Sprintf(`
+SELECT storage_volumes_backups.id, storage_volumes_backups.storage_volume_id, storage_volumes_backups.name,
+       storage_volumes_backups.creation_date, storage_volumes_backups.expiry_date,
+       storage_volumes_backups.volume_only, storage_volumes_backups.optimized_storage,
+       projects.name, storage_pools.name
+  FROM storage_volumes_backups
+  JOIN storage_volumes ON storage_volumes.id = storage_volumes_backups.storage_volume_id
+  JOIN storage_pools ON storage_pools.id = storage_volumes.storage_pool_id
+  JOIN projects ON projects.id = storage_volumes.project_id
+  WHERE storage_volumes.node_id = ? AND %s
+  ORDER BY storage_volumes_backups.id`, where)
+
+	stmt, err := c.tx.Prepare(q)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	backups := []StoragePoolVolumeBackup{}
+	dest := func(i int) []interface{} {
+		backups = append(backups, StoragePoolVolumeBackup{})
+		return []interface{}{
+			&backups[i].ID,
+			&backups[i].VolumeID,
+			&backups[i].Name,
+			&backups[i].CreationDate,
+			&backups[i].ExpiryDate,
+			&backups[i].VolumeOnly,
+			&backups[i].OptimizedStorage,
+			&backups[i].ProjectName,
+			&backups[i].PoolName,
+		}
+	}
+
+	args = append([]interface{}{c.nodeID}, args...)
+	err = query.SelectObjects(stmt, dest, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return backups, nil
+}
+
+// GetStoragePoolVolumeBackups returns all the backups of the custom volume with the given name.
+func (c *Cluster) GetStoragePoolVolumeBackups(projectName string, volumeName string, poolID int64) ([]StoragePoolVolumeBackup, error) {
+	var backups []StoragePoolVolumeBackup
+	err := c.Transaction(func(tx *ClusterTx) error {
+		volumeID, err := tx.storagePoolVolumeGetTypeID(projectName, volumeName, StoragePoolVolumeTypeCustom, poolID, c.nodeID)
+		if err != nil {
+			return err
+		}
+
+		backups, err = tx.getStoragePoolVolumeBackups("storage_volumes_backups.storage_volume_id = ?", volumeID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return backups, nil
+}
+
+// GetStoragePoolVolumeBackup returns the custom volume backup with the given name.
+func (c *Cluster) GetStoragePoolVolumeBackup(projectName string, poolName string, backupName string) (StoragePoolVolumeBackup, error) {
+	var backups []StoragePoolVolumeBackup
+	err := c.Transaction(func(tx *ClusterTx) error {
+		var err error
+		backups, err = tx.getStoragePoolVolumeBackups("projects.name = ? AND storage_pools.name = ? AND storage_volumes_backups.name = ?", projectName, poolName, backupName)
+		return err
+	})
+	if err != nil {
+		return StoragePoolVolumeBackup{}, err
+	}
+
+	if len(backups) == 0 {
+		return StoragePoolVolumeBackup{}, ErrNoSuchObject
+	}
+
+	return backups[0], nil
+}
+
+// CreateStoragePoolVolumeBackup creates a new custom volume backup.
+func (c *Cluster) CreateStoragePoolVolumeBackup(args StoragePoolVolumeBackup) error {
+	return c.Transaction(func(tx *ClusterTx) error {
+		count, err := query.Count(tx.tx, "storage_volumes_backups", "storage_volume_id = ? AND name = ?", args.VolumeID, args.Name)
+		if err != nil {
+			return err
+		}
+
+		if count > 0 {
+			return ErrAlreadyDefined
+		}
+
+		_, err = tx.tx.Exec(`
+INSERT INTO storage_volumes_backups (storage_volume_id, name, creation_date, expiry_date, volume_only, optimized_storage)
+  VALUES (?, ?, ?, ?, ?, ?)
+`, args.VolumeID, args.Name, args.CreationDate, args.ExpiryDate, args.VolumeOnly, args.OptimizedStorage)
+		if err != nil {
+			return fmt.Errorf("Error inserting %q into database: %v", args.Name, err)
+		}
+
+		return nil
+	})
+}
+
+// DeleteStoragePoolVolumeBackup removes the custom volume backup with the given ID from the database.
+func (c *Cluster) DeleteStoragePoolVolumeBackup(id int) error {
+	return c.Transaction(func(tx *ClusterTx) error {
+		deleted, err := query.DeleteObject(tx.tx, "storage_volumes_backups", int64(id))
+		if err != nil {
+			return err
+		}
+
+		if !deleted {
+			return ErrNoSuchObject
+		}
+
+		return nil
+	})
+}
+
+// RenameStoragePoolVolumeBackup renames the custom volume backup with the given ID.
+func (c *Cluster) RenameStoragePoolVolumeBackup(id int, newName string) error {
+	return exec(c, "UPDATE storage_volumes_backups SET name = ? WHERE id = ?", newName, id)
+}
+
+// GetExpiredStorageVolumeBackups returns the expired custom volume backups of the local node.
+func (c *Cluster) GetExpiredStorageVolumeBackups() ([]StoragePoolVolumeBackup, error) {
+	var backups []StoragePoolVolumeBackup
+	err := c.Transaction(func(tx *ClusterTx) error {
+		var err error
+		backups, err = tx.getStoragePoolVolumeBackups("1")
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	expired := []StoragePoolVolumeBackup{}
+	for _, backup := range backups {
+		// Since zero time causes some issues due to timezones, we check the
+		// unix timestamp instead of IsZero().
+		if backup.ExpiryDate.Unix() <= 0 {
+			// Backup doesn't expire
+			continue
+		}
+
+		if time.Now().Unix()-backup.ExpiryDate.Unix() >= 0 {
+			expired = append(expired, backup)
+		}
+	}
+
+	return expired, nil
+}
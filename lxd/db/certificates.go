@@ -2,6 +2,16 @@
 
 package db
 
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/db/query"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+)
+
 // Code generation directives.
 //
 //go:generate -command mapper lxd-generate db mapper -t certificates.mapper.go
@@ -13,6 +23,7 @@ package db
 //go:generate mapper stmt -p db -e certificate create struct=Certificate
 //go:generate mapper stmt -p db -e certificate delete
 //go:generate mapper stmt -p db -e certificate rename
+//go:generate mapper stmt -p db -e certificate update struct=Certificate
 //
 //go:generate mapper method -p db -e certificate List
 //go:generate mapper method -p db -e certificate Get
@@ -21,6 +32,37 @@ package db
 //go:generate mapper method -p db -e certificate Create struct=Certificate
 //go:generate mapper method -p db -e certificate Delete
 //go:generate mapper method -p db -e certificate Rename
+//go:generate mapper method -p db -e certificate Update struct=Certificate
+
+// Certificate types.
+const (
+	CertificateTypeClient  = 1
+	CertificateTypeMetrics = 2
+)
+
+// CertificateTypeToAPIType returns the API equivalent of a database certificate type.
+func CertificateTypeToAPIType(certType int) string {
+	switch certType {
+	case CertificateTypeClient:
+		return api.CertificateTypeClient
+	case CertificateTypeMetrics:
+		return api.CertificateTypeMetrics
+	}
+
+	return api.CertificateTypeUnknown
+}
+
+// CertificateAPITypeToDBType returns the database equivalent of an API certificate type.
+func CertificateAPITypeToDBType(certType string) (int, error) {
+	switch certType {
+	case api.CertificateTypeClient:
+		return CertificateTypeClient, nil
+	case api.CertificateTypeMetrics:
+		return CertificateTypeMetrics, nil
+	}
+
+	return -1, fmt.Errorf("Unknown certificate type %q", certType)
+}
 
 // Certificate is here to pass the certificates content
 // from the database around
@@ -30,6 +72,7 @@ type Certificate struct {
 	Type        int
 	Name        string
 	Certificate string
+	Restricted  bool
 }
 
 // CertificateFilter can be used to filter results yielded by GetCertInfos
@@ -60,6 +103,20 @@ func (c *Cluster) CreateCertificate(cert Certificate) error {
 	return err
 }
 
+// CreateCertificateWithProjects stores a CertInfo object in the db along with the projects a restricted
+// certificate has access to, it will ignore the ID field from the CertInfo.
+func (c *Cluster) CreateCertificateWithProjects(cert Certificate, projectNames []string) error {
+	err := c.Transaction(func(tx *ClusterTx) error {
+		id, err := tx.CreateCertificate(cert)
+		if err != nil {
+			return err
+		}
+
+		return tx.UpdateCertificateProjects(int(id), projectNames)
+	})
+	return err
+}
+
 // DeleteCertificate deletes a certificate from the db.
 func (c *Cluster) DeleteCertificate(fingerprint string) error {
 	err := c.Transaction(func(tx *ClusterTx) error {
@@ -68,10 +125,106 @@ func (c *Cluster) DeleteCertificate(fingerprint string) error {
 	return err
 }
 
-// RenameCertificate updates a certificate's name.
-func (c *Cluster) RenameCertificate(fingerprint string, name string) error {
+// UpdateCertificate updates a certificate in the db.
+func (c *Cluster) UpdateCertificate(fingerprint string, cert Certificate) error {
+	err := c.Transaction(func(tx *ClusterTx) error {
+		return tx.UpdateCertificate(fingerprint, cert)
+	})
+	return err
+}
+
+// UpdateCertificateWithProjects updates a certificate and the projects it has access to in the db.
+func (c *Cluster) UpdateCertificateWithProjects(fingerprint string, cert Certificate, projectNames []string) error {
 	err := c.Transaction(func(tx *ClusterTx) error {
-		return c.RenameCertificate(fingerprint, name)
+		err := tx.UpdateCertificate(fingerprint, cert)
+		if err != nil {
+			return err
+		}
+
+		return tx.UpdateCertificateProjects(cert.ID, projectNames)
 	})
 	return err
 }
+
+// GetUnrestrictedClientCertificates returns the client certificates which aren't restricted to some projects.
+//
+// Only the columns which all schema versions have are used, so that it works while the schema of the database is
+// still older than the one of this node, e.g. during a rolling upgrade.
+func (c *ClusterTx) GetUnrestrictedClientCertificates() ([]Certificate, error) {
+	// Certificates can only be restricted once the restricted column exists.
+	restricted, err := query.SelectStrings(c.tx, "SELECT fingerprint FROM certificates WHERE restricted = 1")
+	if err != nil && err.Error() != "no such column: restricted" {
+		return nil, errors.Wrap(err, "Failed to fetch restricted certificates")
+	}
+
+	certs := []Certificate{}
+	dest := func(i int) []interface{} {
+		certs = append(certs, Certificate{})
+		return []interface{}{
+			&certs[i].ID,
+			&certs[i].Fingerprint,
+			&certs[i].Type,
+			&certs[i].Name,
+			&certs[i].Certificate,
+		}
+	}
+
+	stmt, err := c.tx.Prepare("SELECT id, fingerprint, type, name, certificate FROM certificates WHERE type = ? ORDER BY fingerprint")
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	err = query.SelectObjects(stmt, dest, CertificateTypeClient)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch certificates")
+	}
+
+	unrestricted := []Certificate{}
+	for _, cert := range certs {
+		if !shared.StringInSlice(cert.Fingerprint, restricted) {
+			unrestricted = append(unrestricted, cert)
+		}
+	}
+
+	return unrestricted, nil
+}
+
+// GetCertificateProjects returns the names of the projects a restricted certificate has access to.
+func (c *ClusterTx) GetCertificateProjects(id int) ([]string, error) {
+	q := `
+SELECT projects.name
+  FROM certificates_projects
+  JOIN projects ON projects.id = certificates_projects.project_id
+ WHERE certificates_projects.certificate_id = ?
+ ORDER BY projects.name
+`
+	projectNames, err := query.SelectStrings(c.tx, q, id)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch certificate projects")
+	}
+
+	return projectNames, nil
+}
+
+// UpdateCertificateProjects sets the projects a restricted certificate has access to.
+func (c *ClusterTx) UpdateCertificateProjects(id int, projectNames []string) error {
+	_, err := c.tx.Exec("DELETE FROM certificates_projects WHERE certificate_id = ?", id)
+	if err != nil {
+		return errors.Wrap(err, "Failed to delete certificate projects")
+	}
+
+	for _, projectName := range projectNames {
+		projectID, err := c.GetProjectID(projectName)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to get project %q", projectName)
+		}
+
+		_, err = c.tx.Exec("INSERT INTO certificates_projects (certificate_id, project_id) VALUES (?, ?)", id, projectID)
+		if err != nil {
+			return errors.Wrap(err, "Failed to add certificate project")
+		}
+	}
+
+	return nil
+}
@@ -16,13 +16,13 @@ import (
 var _ = api.ServerEnvironment{}
 
 var certificateObjects = cluster.RegisterStmt(`
-SELECT certificates.id, certificates.fingerprint, certificates.type, certificates.name, certificates.certificate
+SELECT certificates.id, certificates.fingerprint, certificates.type, certificates.name, certificates.certificate, certificates.restricted
   FROM certificates
   ORDER BY certificates.fingerprint
 `)
 
 var certificateObjectsByFingerprint = cluster.RegisterStmt(`
-SELECT certificates.id, certificates.fingerprint, certificates.type, certificates.name, certificates.certificate
+SELECT certificates.id, certificates.fingerprint, certificates.type, certificates.name, certificates.certificate, certificates.restricted
   FROM certificates
   WHERE certificates.fingerprint LIKE ? ORDER BY certificates.fingerprint
 `)
@@ -33,8 +33,8 @@ SELECT certificates.id FROM certificates
 `)
 
 var certificateCreate = cluster.RegisterStmt(`
-INSERT INTO certificates (fingerprint, type, name, certificate)
-  VALUES (?, ?, ?, ?)
+INSERT INTO certificates (fingerprint, type, name, certificate, restricted)
+  VALUES (?, ?, ?, ?, ?)
 `)
 
 var certificateDelete = cluster.RegisterStmt(`
@@ -45,6 +45,12 @@ var certificateRename = cluster.RegisterStmt(`
 UPDATE certificates SET name = ? WHERE fingerprint = ?
 `)
 
+var certificateUpdate = cluster.RegisterStmt(`
+UPDATE certificates
+  SET fingerprint = ?, type = ?, name = ?, certificate = ?, restricted = ?
+ WHERE id = ?
+`)
+
 // GetCertificates returns all available certificates.
 func (c *ClusterTx) GetCertificates(filter CertificateFilter) ([]Certificate, error) {
 	// Result slice.
@@ -79,6 +85,7 @@ func (c *ClusterTx) GetCertificates(filter CertificateFilter) ([]Certificate, er
 			&objects[i].Type,
 			&objects[i].Name,
 			&objects[i].Certificate,
+			&objects[i].Restricted,
 		}
 	}
 
@@ -164,13 +171,14 @@ func (c *ClusterTx) CreateCertificate(object Certificate) (int64, error) {
 		return -1, fmt.Errorf("This certificate already exists")
 	}
 
-	args := make([]interface{}, 4)
+	args := make([]interface{}, 5)
 
 	// Populate the statement arguments.
 	args[0] = object.Fingerprint
 	args[1] = object.Type
 	args[2] = object.Name
 	args[3] = object.Certificate
+	args[4] = object.Restricted
 
 	// Prepared statement to use.
 	stmt := c.stmt(certificateCreate)
@@ -225,3 +233,27 @@ func (c *ClusterTx) RenameCertificate(fingerprint string, to string) error {
 	}
 	return nil
 }
+
+// UpdateCertificate updates the certificate matching the given key parameters.
+func (c *ClusterTx) UpdateCertificate(fingerprint string, object Certificate) error {
+	id, err := c.GetCertificateID(fingerprint)
+	if err != nil {
+		return errors.Wrap(err, "Get certificate")
+	}
+
+	stmt := c.stmt(certificateUpdate)
+	result, err := stmt.Exec(object.Fingerprint, object.Type, object.Name, object.Certificate, object.Restricted, id)
+	if err != nil {
+		return errors.Wrap(err, "Update certificate")
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "Fetch affected rows")
+	}
+	if n != 1 {
+		return fmt.Errorf("Query updated %d rows instead of 1", n)
+	}
+
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/shared/api"
 )
 
 func TestGetCertificate(t *testing.T) {
@@ -22,3 +23,52 @@ func TestGetCertificate(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, cert.Fingerprint, "foobar")
 }
+
+func TestUpdateCertificate(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	_, err := tx.CreateCertificate(db.Certificate{Fingerprint: "foobar", Type: db.CertificateTypeClient, Name: "foo"})
+	require.NoError(t, err)
+
+	err = tx.UpdateCertificate("foobar", db.Certificate{Fingerprint: "foobar", Type: db.CertificateTypeMetrics, Name: "bar"})
+	require.NoError(t, err)
+
+	cert, err := tx.GetCertificate("foobar")
+	require.NoError(t, err)
+	assert.Equal(t, "bar", cert.Name)
+	assert.Equal(t, db.CertificateTypeMetrics, cert.Type)
+}
+
+func TestUpdateCertificateProjects(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	_, err := tx.CreateProject(api.ProjectsPost{Name: "p1"})
+	require.NoError(t, err)
+
+	id, err := tx.CreateCertificate(db.Certificate{Fingerprint: "foobar", Type: db.CertificateTypeClient, Restricted: true})
+	require.NoError(t, err)
+
+	err = tx.UpdateCertificateProjects(int(id), []string{"p1", "default"})
+	require.NoError(t, err)
+
+	cert, err := tx.GetCertificate("foobar")
+	require.NoError(t, err)
+	assert.True(t, cert.Restricted)
+
+	projects, err := tx.GetCertificateProjects(cert.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"default", "p1"}, projects)
+
+	err = tx.UpdateCertificateProjects(cert.ID, []string{"p1"})
+	require.NoError(t, err)
+
+	projects, err = tx.GetCertificateProjects(cert.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"p1"}, projects)
+
+	// Unknown projects are rejected.
+	err = tx.UpdateCertificateProjects(cert.ID, []string{"p2"})
+	require.Error(t, err)
+}
@@ -179,6 +179,16 @@ INSERT INTO nodes(id, name, address, schema, api_extensions, arch) VALUES(1, 'no
 				return err
 			}
 
+			// Default cluster group
+			stmt = `
+INSERT INTO cluster_groups (id, name, description) VALUES (1, 'default', 'Default cluster group');
+INSERT INTO nodes_cluster_groups (node_id, group_id) VALUES (1, 1);
+`
+			_, err = tx.Exec(stmt)
+			if err != nil {
+				return err
+			}
+
 			// Default project
 			stmt = `
 INSERT INTO projects (name, description) VALUES ('default', 'Default LXD project');
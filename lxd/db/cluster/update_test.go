@@ -717,3 +717,244 @@ func TestUpdateFromV26_WithVolumes(t *testing.T) {
 
 	assert.Equal(t, ids[0], 2)
 }
+
+func TestUpdateFromV35(t *testing.T) {
+	schema := cluster.Schema()
+	db, err := schema.ExerciseUpdate(36, nil)
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec("INSERT INTO networks_acls VALUES (1, 1, 'acl1', '', '[]', '[]')")
+	require.NoError(t, err)
+
+	_, err = db.Exec("INSERT INTO networks_acls_config VALUES (1, 1, 'user.foo', 'bar')")
+	require.NoError(t, err)
+
+	// Unique constraint on project_id/name.
+	_, err = db.Exec("INSERT INTO networks_acls VALUES (2, 1, 'acl1', '', '[]', '[]')")
+	require.Error(t, err)
+
+	// Config is removed along with the ACL.
+	_, err = db.Exec("DELETE FROM networks_acls WHERE id = 1")
+	require.NoError(t, err)
+
+	tx, err := db.Begin()
+	require.NoError(t, err)
+
+	defer tx.Rollback()
+	ids, err := query.SelectIntegers(tx, "SELECT id FROM networks_acls_config")
+	require.NoError(t, err)
+	assert.Len(t, ids, 0)
+}
+
+func TestUpdateFromV36(t *testing.T) {
+	schema := cluster.Schema()
+	db, err := schema.ExerciseUpdate(37, nil)
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec("INSERT INTO nodes (id, name, address, schema, api_extensions, arch) VALUES (1, 'n1', '1.2.3.4:666', 1, 32, 1)")
+	require.NoError(t, err)
+
+	_, err = db.Exec("INSERT INTO warnings VALUES (1, 1, NULL, NULL, NULL, 'uuid1', 1, 1, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP, NULL, 'msg', 1)")
+	require.NoError(t, err)
+
+	// Unique constraint on node_id/project_id/entity_type_code/entity_id/type_code, even with NULL values.
+	_, err = db.Exec("INSERT INTO warnings VALUES (2, 1, NULL, NULL, NULL, 'uuid2', 1, 1, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP, NULL, 'msg', 1)")
+	require.Error(t, err)
+
+	// Same type on another entity is fine.
+	_, err = db.Exec("INSERT INTO warnings VALUES (2, 1, NULL, 1, 1, 'uuid2', 1, 1, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP, NULL, 'msg', 1)")
+	require.NoError(t, err)
+
+	// Warnings are removed along with the node.
+	_, err = db.Exec("DELETE FROM nodes WHERE id = 1")
+	require.NoError(t, err)
+
+	tx, err := db.Begin()
+	require.NoError(t, err)
+
+	defer tx.Rollback()
+	ids, err := query.SelectIntegers(tx, "SELECT id FROM warnings")
+	require.NoError(t, err)
+	assert.Len(t, ids, 0)
+}
+
+func TestUpdateFromV39(t *testing.T) {
+	schema := cluster.Schema()
+	db, err := schema.ExerciseUpdate(40, nil)
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec("INSERT INTO networks (id, project_id, name) VALUES (1, 1, 'ovn1')")
+	require.NoError(t, err)
+
+	_, err = db.Exec("INSERT INTO networks_forwards VALUES (1, 1, '192.0.2.1', '', '[]')")
+	require.NoError(t, err)
+
+	_, err = db.Exec("INSERT INTO networks_forwards_config VALUES (1, 1, 'target_address', '10.0.0.2')")
+	require.NoError(t, err)
+
+	_, err = db.Exec("INSERT INTO networks_load_balancers VALUES (1, 1, '192.0.2.2', '', '[]', '[]')")
+	require.NoError(t, err)
+
+	_, err = db.Exec("INSERT INTO networks_load_balancers_config VALUES (1, 1, 'user.foo', 'bar')")
+	require.NoError(t, err)
+
+	// Unique constraint on network_id/listen_address.
+	_, err = db.Exec("INSERT INTO networks_forwards VALUES (2, 1, '192.0.2.1', '', '[]')")
+	require.Error(t, err)
+
+	_, err = db.Exec("INSERT INTO networks_load_balancers VALUES (2, 1, '192.0.2.2', '', '[]', '[]')")
+	require.Error(t, err)
+
+	// Forwards and load balancers are removed along with the network.
+	_, err = db.Exec("DELETE FROM networks WHERE id = 1")
+	require.NoError(t, err)
+
+	tx, err := db.Begin()
+	require.NoError(t, err)
+
+	defer tx.Rollback()
+
+	for _, table := range []string{"networks_forwards", "networks_forwards_config", "networks_load_balancers", "networks_load_balancers_config"} {
+		ids, err := query.SelectIntegers(tx, fmt.Sprintf("SELECT id FROM %s", table))
+		require.NoError(t, err)
+		assert.Len(t, ids, 0)
+	}
+}
+
+func TestUpdateFromV40(t *testing.T) {
+	schema := cluster.Schema()
+	db, err := schema.ExerciseUpdate(41, nil)
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec("INSERT INTO projects (id, name) VALUES (2, 'p1')")
+	require.NoError(t, err)
+
+	_, err = db.Exec("INSERT INTO networks (id, project_id, name) VALUES (1, 2, 'ovn1')")
+	require.NoError(t, err)
+
+	_, err = db.Exec("INSERT INTO networks_acls (id, project_id, name, description, ingress, egress) VALUES (1, 2, 'acl1', '', '[]', '[]')")
+	require.NoError(t, err)
+
+	tx, err := db.Begin()
+	require.NoError(t, err)
+
+	defer tx.Rollback()
+
+	usedBy, err := query.SelectStrings(tx, "SELECT value FROM projects_used_by_ref WHERE name = 'p1'")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"/1.0/networks/ovn1?project=p1", "/1.0/network-acls/acl1?project=p1"}, usedBy)
+}
+
+func TestUpdateFromV41(t *testing.T) {
+	schema := cluster.Schema()
+	db, err := schema.ExerciseUpdate(42, nil)
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec("INSERT INTO nodes VALUES (1, 'one', '', '1.1.1.1', 666, 999, ?, 0)", time.Now())
+	require.NoError(t, err)
+
+	_, err = db.Exec("INSERT INTO storage_pools (id, name, driver, description) VALUES (1, 'default', 'dir', '')")
+	require.NoError(t, err)
+
+	_, err = db.Exec("INSERT INTO storage_buckets VALUES (1, 'b1', 1, 1, '', 1)")
+	require.NoError(t, err)
+
+	_, err = db.Exec("INSERT INTO storage_buckets_config VALUES (1, 1, 'size', '1GiB')")
+	require.NoError(t, err)
+
+	_, err = db.Exec("INSERT INTO storage_buckets_keys VALUES (1, 1, 'admin', '', 'admin', 'access1', 'secret1')")
+	require.NoError(t, err)
+
+	// Unique constraint on pool/node/project/name.
+	_, err = db.Exec("INSERT INTO storage_buckets VALUES (2, 'b1', 1, 1, '', 1)")
+	require.Error(t, err)
+
+	// Access keys are globally unique.
+	_, err = db.Exec("INSERT INTO storage_buckets VALUES (2, 'b2', 1, 1, '', 1)")
+	require.NoError(t, err)
+
+	_, err = db.Exec("INSERT INTO storage_buckets_keys VALUES (2, 2, 'admin', '', 'admin', 'access1', 'secret2')")
+	require.Error(t, err)
+
+	// Buckets, their config and keys are removed along with the pool.
+	_, err = db.Exec("DELETE FROM storage_pools WHERE id = 1")
+	require.NoError(t, err)
+
+	tx, err := db.Begin()
+	require.NoError(t, err)
+
+	defer tx.Rollback()
+
+	for _, table := range []string{"storage_buckets", "storage_buckets_config", "storage_buckets_keys"} {
+		ids, err := query.SelectIntegers(tx, fmt.Sprintf("SELECT id FROM %s", table))
+		require.NoError(t, err)
+		assert.Len(t, ids, 0)
+	}
+}
+
+func TestUpdateFromV43(t *testing.T) {
+	schema := cluster.Schema()
+	db, err := schema.ExerciseUpdate(44, nil)
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = db.Exec("INSERT INTO certificates VALUES (1, 'abc', 1, 'foo', 'cert', 1)")
+	require.NoError(t, err)
+
+	_, err = db.Exec("INSERT INTO certificates_projects VALUES (1, 1)")
+	require.NoError(t, err)
+
+	// Unique constraint on certificate/project.
+	_, err = db.Exec("INSERT INTO certificates_projects VALUES (1, 1)")
+	require.Error(t, err)
+
+	// Deleting the certificate removes its projects.
+	_, err = db.Exec("DELETE FROM certificates WHERE id = 1")
+	require.NoError(t, err)
+
+	result, err := db.Exec("DELETE FROM certificates_projects")
+	require.NoError(t, err)
+	n, err := result.RowsAffected()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+}
+
+func TestUpdateFromV44(t *testing.T) {
+	schema := cluster.Schema()
+	db, err := schema.ExerciseUpdate(45, func(db *sql.DB) {
+		_, err := db.Exec("INSERT INTO nodes VALUES (1, 'n1', '', '1.2.3.4:666', 1, 32, ?, 0, 1, NULL, 0)", time.Now())
+		require.NoError(t, err)
+
+		_, err = db.Exec("INSERT INTO nodes VALUES (2, 'n2', '', '5.6.7.8:666', 1, 32, ?, 0, 1, NULL, 0)", time.Now())
+		require.NoError(t, err)
+	})
+	require.NoError(t, err)
+	defer db.Close()
+
+	// Existing nodes are in the default group.
+	tx, err := db.Begin()
+	require.NoError(t, err)
+
+	defer tx.Rollback()
+
+	ids, err := query.SelectIntegers(tx, "SELECT node_id FROM nodes_cluster_groups WHERE group_id = 1 ORDER BY node_id")
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, ids)
+
+	// Unique constraint on node/group.
+	_, err = tx.Exec("INSERT INTO nodes_cluster_groups VALUES (1, 1)")
+	require.Error(t, err)
+
+	// Deleting the group removes its memberships.
+	_, err = tx.Exec("DELETE FROM cluster_groups WHERE id = 1")
+	require.NoError(t, err)
+
+	ids, err = query.SelectIntegers(tx, "SELECT node_id FROM nodes_cluster_groups")
+	require.NoError(t, err)
+	assert.Len(t, ids, 0)
+}
@@ -0,0 +1,210 @@
+// +build linux,cgo,!agent
+
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/db/query"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// GetClusterGroupNames returns the names of all cluster groups.
+func (c *ClusterTx) GetClusterGroupNames() ([]string, error) {
+	return query.SelectStrings(c.tx, "SELECT name FROM cluster_groups ORDER BY name")
+}
+
+// GetClusterGroup returns the cluster group with the given name, along with the names of its members.
+func (c *ClusterTx) GetClusterGroup(name string) (*api.ClusterGroup, error) {
+	group := api.ClusterGroup{
+		Name: name,
+	}
+
+	var id int64
+	err := c.tx.QueryRow("SELECT id, description FROM cluster_groups WHERE name = ?", name).Scan(&id, &group.Description)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNoSuchObject
+		}
+
+		return nil, err
+	}
+
+	group.Members, err = query.SelectStrings(c.tx, `
+SELECT nodes.name FROM nodes
+  JOIN nodes_cluster_groups ON nodes_cluster_groups.node_id = nodes.id
+ WHERE nodes_cluster_groups.group_id = ?
+ ORDER BY nodes.name
+`, id)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to load cluster group members")
+	}
+
+	return &group, nil
+}
+
+// CreateClusterGroup adds a new cluster group without any member.
+func (c *ClusterTx) CreateClusterGroup(name string, description string) (int64, error) {
+	result, err := c.tx.Exec("INSERT INTO cluster_groups (name, description) VALUES (?, ?)", name, description)
+	if err != nil {
+		return -1, err
+	}
+
+	return result.LastInsertId()
+}
+
+// UpdateClusterGroup changes the description of the cluster group with the given name.
+func (c *ClusterTx) UpdateClusterGroup(name string, description string) error {
+	result, err := c.tx.Exec("UPDATE cluster_groups SET description = ? WHERE name = ?", description, name)
+	if err != nil {
+		return err
+	}
+
+	return clusterGroupCheckAffected(result)
+}
+
+// RenameClusterGroup renames the cluster group with the given name.
+func (c *ClusterTx) RenameClusterGroup(name string, newName string) error {
+	result, err := c.tx.Exec("UPDATE cluster_groups SET name = ? WHERE name = ?", newName, name)
+	if err != nil {
+		return err
+	}
+
+	return clusterGroupCheckAffected(result)
+}
+
+// DeleteClusterGroup removes the cluster group with the given name.
+func (c *ClusterTx) DeleteClusterGroup(name string) error {
+	result, err := c.tx.Exec("DELETE FROM cluster_groups WHERE name = ?", name)
+	if err != nil {
+		return err
+	}
+
+	return clusterGroupCheckAffected(result)
+}
+
+// UpdateClusterGroupMembers replaces the members of the cluster group with the given name with the nodes of the
+// given names.
+func (c *ClusterTx) UpdateClusterGroupMembers(name string, members []string) error {
+	var id int64
+	err := c.tx.QueryRow("SELECT id FROM cluster_groups WHERE name = ?", name).Scan(&id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return ErrNoSuchObject
+		}
+
+		return err
+	}
+
+	_, err = c.tx.Exec("DELETE FROM nodes_cluster_groups WHERE group_id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	for _, member := range members {
+		node, err := c.GetNodeByName(member)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to load cluster member %q", member)
+		}
+
+		_, err = c.tx.Exec("INSERT OR IGNORE INTO nodes_cluster_groups (node_id, group_id) VALUES (?, ?)", node.ID, id)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetNodeClusterGroups returns the names of the cluster groups the node with the given ID belongs to.
+func (c *ClusterTx) GetNodeClusterGroups(id int64) ([]string, error) {
+	return query.SelectStrings(c.tx, `
+SELECT cluster_groups.name FROM cluster_groups
+  JOIN nodes_cluster_groups ON nodes_cluster_groups.group_id = cluster_groups.id
+ WHERE nodes_cluster_groups.node_id = ?
+ ORDER BY cluster_groups.name
+`, id)
+}
+
+// GetNodesClusterGroups returns a map associating each node ID with the names of the cluster groups it belongs
+// to.
+func (c *ClusterTx) GetNodesClusterGroups() (map[int64][]string, error) {
+	stmt, err := c.tx.Prepare(`
+SELECT nodes_cluster_groups.node_id, cluster_groups.name FROM cluster_groups
+  JOIN nodes_cluster_groups ON nodes_cluster_groups.group_id = cluster_groups.id
+ ORDER BY cluster_groups.name
+`)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows := []struct {
+		NodeID int64
+		Name   string
+	}{}
+
+	dest := func(i int) []interface{} {
+		rows = append(rows, struct {
+			NodeID int64
+			Name   string
+		}{})
+		return []interface{}{&rows[len(rows)-1].NodeID, &rows[len(rows)-1].Name}
+	}
+
+	err = query.SelectObjects(stmt, dest)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := map[int64][]string{}
+	for _, row := range rows {
+		groups[row.NodeID] = append(groups[row.NodeID], row.Name)
+	}
+
+	return groups, nil
+}
+
+// UpdateNodeClusterGroups replaces the cluster groups the node with the given ID belongs to.
+func (c *ClusterTx) UpdateNodeClusterGroups(id int64, groups []string) error {
+	_, err := c.tx.Exec("DELETE FROM nodes_cluster_groups WHERE node_id = ?", id)
+	if err != nil {
+		return err
+	}
+
+	for _, group := range groups {
+		var groupID int64
+		err := c.tx.QueryRow("SELECT id FROM cluster_groups WHERE name = ?", group).Scan(&groupID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("Cluster group %q doesn't exist", group)
+			}
+
+			return err
+		}
+
+		_, err = c.tx.Exec("INSERT OR IGNORE INTO nodes_cluster_groups (node_id, group_id) VALUES (?, ?)", id, groupID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// clusterGroupCheckAffected returns ErrNoSuchObject if the statement didn't affect exactly one cluster group.
+func clusterGroupCheckAffected(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n != 1 {
+		return ErrNoSuchObject
+	}
+
+	return nil
+}
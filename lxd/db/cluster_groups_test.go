@@ -0,0 +1,82 @@
+// +build linux,cgo,!agent
+
+package db_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/lxd/db"
+)
+
+// New nodes are added to the default cluster group.
+func TestClusterGroups_Default(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	id, err := tx.CreateNode("buzz", "1.2.3.4:666")
+	require.NoError(t, err)
+
+	groups, err := tx.GetNodeClusterGroups(id)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"default"}, groups)
+
+	group, err := tx.GetClusterGroup("default")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"buzz", "none"}, group.Members)
+}
+
+// Create, update, rename and delete a cluster group.
+func TestClusterGroups_Lifecycle(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	id, err := tx.CreateNode("buzz", "1.2.3.4:666")
+	require.NoError(t, err)
+
+	_, err = tx.CreateClusterGroup("foo", "Foo group")
+	require.NoError(t, err)
+
+	err = tx.UpdateClusterGroupMembers("foo", []string{"buzz"})
+	require.NoError(t, err)
+
+	err = tx.UpdateClusterGroup("foo", "Bar group")
+	require.NoError(t, err)
+
+	err = tx.RenameClusterGroup("foo", "bar")
+	require.NoError(t, err)
+
+	names, err := tx.GetClusterGroupNames()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bar", "default"}, names)
+
+	group, err := tx.GetClusterGroup("bar")
+	require.NoError(t, err)
+	assert.Equal(t, "Bar group", group.Description)
+	assert.Equal(t, []string{"buzz"}, group.Members)
+
+	nodesGroups, err := tx.GetNodesClusterGroups()
+	require.NoError(t, err)
+	assert.Equal(t, map[int64][]string{1: {"default"}, id: {"bar", "default"}}, nodesGroups)
+
+	err = tx.DeleteClusterGroup("bar")
+	require.NoError(t, err)
+
+	_, err = tx.GetClusterGroup("bar")
+	assert.Equal(t, db.ErrNoSuchObject, err)
+
+	groups, err := tx.GetNodeClusterGroups(id)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"default"}, groups)
+}
+
+// Setting the groups of a node fails if one of them doesn't exist.
+func TestUpdateNodeClusterGroups_NotFound(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	err := tx.UpdateNodeClusterGroups(1, []string{"default", "foo"})
+	assert.EqualError(t, err, `Cluster group "foo" doesn't exist`)
+}
@@ -504,6 +504,77 @@ func (c *ClusterTx) UpdateInstanceNode(project, oldName, newName, newNode string
 	return nil
 }
 
+// UpdateInstanceNodeID changes the node an instance is associated with, keeping its name.
+//
+// It's meant to be used when live migrating a running instance from one cluster
+// node to another, once the instance has been restored on the new node.
+func (c *ClusterTx) UpdateInstanceNodeID(project, name string, nodeID int64) error {
+	instanceID, err := c.GetInstanceID(project, name)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get instance's ID")
+	}
+
+	result, err := c.tx.Exec("UPDATE instances SET node_id=? WHERE id=?", nodeID, instanceID)
+	if err != nil {
+		return errors.Wrap(err, "Failed to update instance's node ID")
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "Failed to get rows affected by instance update")
+	}
+
+	if n != 1 {
+		return fmt.Errorf("Unexpected number of updated rows in instances table: %d", n)
+	}
+
+	return nil
+}
+
+// UpdateInstanceRootDiskPool points the root disk device of an instance and of its snapshots at the given
+// storage pool. If the instance's root disk device comes from a profile, the given rootDev is added to the
+// instance's own devices so that only this instance uses the new pool.
+func (c *ClusterTx) UpdateInstanceRootDiskPool(project, name string, rootDevName string, rootDev map[string]string, poolName string) error {
+	object, err := c.GetInstance(project, name)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get instance")
+	}
+
+	dev := map[string]string{}
+	for k, v := range rootDev {
+		dev[k] = v
+	}
+	dev["pool"] = poolName
+
+	if object.Devices == nil {
+		object.Devices = map[string]map[string]string{}
+	}
+	object.Devices[rootDevName] = dev
+
+	err = c.UpdateInstance(project, name, *object)
+	if err != nil {
+		return errors.Wrap(err, "Failed to update instance's devices")
+	}
+
+	instanceID, err := c.GetInstanceID(project, name)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get instance's ID")
+	}
+
+	stmt := `
+UPDATE instances_snapshots_devices_config SET value=? WHERE key='pool' AND instance_snapshot_device_id IN (
+  SELECT instances_snapshots_devices.id FROM instances_snapshots_devices
+    JOIN instances_snapshots ON instances_snapshots.id = instances_snapshots_devices.instance_snapshot_id
+    WHERE instances_snapshots.instance_id=? AND instances_snapshots_devices.name=?
+)`
+	_, err = c.tx.Exec(stmt, poolName, instanceID, rootDevName)
+	if err != nil {
+		return errors.Wrap(err, "Failed to update instance snapshots' devices")
+	}
+
+	return nil
+}
+
 // GetLocalInstancesInProject retuurns all instances of the given type on the
 // local node within the given project.
 func (c *ClusterTx) GetLocalInstancesInProject(project string, instanceType instancetype.Type) ([]Instance, error) {
@@ -300,6 +300,66 @@ func TestCreateInstance_Snapshot(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestUpdateInstanceRootDiskPool(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	profile := db.Profile{
+		Project: "default",
+		Name:    "profile1",
+		Devices: map[string]map[string]string{"root": {"type": "disk", "path": "/", "pool": "p1"}},
+	}
+
+	_, err := tx.CreateProfile(profile)
+	require.NoError(t, err)
+
+	instance := db.Instance{
+		Project:      "default",
+		Name:         "c1",
+		Node:         "none",
+		Type:         instancetype.Container,
+		Architecture: 1,
+		Devices: map[string]map[string]string{
+			"data": {"type": "disk", "path": "/data", "pool": "p1", "source": "data"},
+		},
+		Profiles: []string{"profile1"},
+	}
+
+	_, err = tx.CreateInstance(instance)
+	require.NoError(t, err)
+
+	snapshot := db.InstanceSnapshot{
+		Project:  "default",
+		Instance: "c1",
+		Name:     "snap0",
+		Devices: map[string]map[string]string{
+			"root": {"type": "disk", "path": "/", "pool": "p1"},
+			"data": {"type": "disk", "path": "/data", "pool": "p1", "source": "data"},
+		},
+	}
+
+	_, err = tx.CreateInstanceSnapshot(snapshot)
+	require.NoError(t, err)
+
+	err = tx.UpdateInstanceRootDiskPool("default", "c1", "root", profile.Devices["root"], "p2")
+	require.NoError(t, err)
+
+	// The root disk device is overridden locally, leaving the profile and other disks untouched.
+	c, err := tx.GetInstance("default", "c1")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"type": "disk", "path": "/", "pool": "p2"}, c.Devices["root"])
+	assert.Equal(t, "p1", c.Devices["data"]["pool"])
+
+	p, err := tx.GetProfile("default", "profile1")
+	require.NoError(t, err)
+	assert.Equal(t, "p1", p.Devices["root"]["pool"])
+
+	s, err := tx.GetInstanceSnapshot("default", "c1", "snap0")
+	require.NoError(t, err)
+	assert.Equal(t, "p2", s.Devices["root"]["pool"])
+	assert.Equal(t, "p1", s.Devices["data"]["pool"])
+}
+
 // Containers are grouped by node address.
 func TestGetInstanceNamesByNodeAddress(t *testing.T) {
 	tx, cleanup := db.NewTestClusterTx(t)
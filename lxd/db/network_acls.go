@@ -0,0 +1,246 @@
+// +build linux,cgo,!agent
+
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+// GetNetworkACLs returns the names of existing Network ACLs.
+func (c *Cluster) GetNetworkACLs(project string) ([]string, error) {
+	q := `SELECT name FROM networks_acls
+		WHERE project_id = (SELECT id FROM projects WHERE name = ? LIMIT 1)
+		ORDER BY id
+	`
+	inargs := []interface{}{project}
+
+	var name string
+	outfmt := []interface{}{name}
+	result, err := queryScan(c, q, inargs, outfmt)
+	if err != nil {
+		return nil, err
+	}
+
+	response := []string{}
+	for _, r := range result {
+		response = append(response, r[0].(string))
+	}
+
+	return response, nil
+}
+
+// GetNetworkACL returns the Network ACL with the given name in the given project.
+func (c *Cluster) GetNetworkACL(project string, name string) (int64, *api.NetworkACL, error) {
+	id := int64(-1)
+	var ingressJSON string
+	var egressJSON string
+
+	acl := api.NetworkACL{
+		NetworkACLPost: api.NetworkACLPost{
+			Name: name,
+		},
+	}
+
+	q := `
+		SELECT id, description, ingress, egress
+		FROM networks_acls
+		WHERE project_id = (SELECT id FROM projects WHERE name = ? LIMIT 1) AND name=?
+		LIMIT 1
+	`
+	arg1 := []interface{}{project, name}
+	arg2 := []interface{}{&id, &acl.Description, &ingressJSON, &egressJSON}
+
+	err := dbQueryRowScan(c, q, arg1, arg2)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return -1, nil, ErrNoSuchObject
+		}
+
+		return -1, nil, err
+	}
+
+	acl.Ingress = []api.NetworkACLRule{}
+	if ingressJSON != "" {
+		err = json.Unmarshal([]byte(ingressJSON), &acl.Ingress)
+		if err != nil {
+			return -1, nil, errors.Wrapf(err, "Failed unmarshalling ingress rules")
+		}
+	}
+
+	acl.Egress = []api.NetworkACLRule{}
+	if egressJSON != "" {
+		err = json.Unmarshal([]byte(egressJSON), &acl.Egress)
+		if err != nil {
+			return -1, nil, errors.Wrapf(err, "Failed unmarshalling egress rules")
+		}
+	}
+
+	acl.Config, err = c.networkACLConfig(id)
+	if err != nil {
+		return -1, nil, errors.Wrapf(err, "Failed loading config")
+	}
+
+	return id, &acl, nil
+}
+
+// networkACLConfig returns the config map of the Network ACL with the given ID.
+func (c *Cluster) networkACLConfig(id int64) (map[string]string, error) {
+	var key, value string
+	q := `
+		SELECT key, value
+		FROM networks_acls_config
+		WHERE network_acl_id=?
+	`
+	inargs := []interface{}{id}
+	outfmt := []interface{}{key, value}
+	results, err := queryScan(c, q, inargs, outfmt)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get network ACL config for ID %d", id)
+	}
+
+	config := make(map[string]string, len(results))
+
+	for _, r := range results {
+		key = r[0].(string)
+		value = r[1].(string)
+
+		config[key] = value
+	}
+
+	return config, nil
+}
+
+// CreateNetworkACL creates a new Network ACL.
+func (c *Cluster) CreateNetworkACL(project string, info *api.NetworkACLsPost) (int64, error) {
+	var id int64
+
+	ingressJSON, egressJSON, err := networkACLRulesToJSON(info.Ingress, info.Egress)
+	if err != nil {
+		return -1, err
+	}
+
+	err = c.Transaction(func(tx *ClusterTx) error {
+		result, err := tx.tx.Exec(`
+			INSERT INTO networks_acls (project_id, name, description, ingress, egress)
+			VALUES ((SELECT id FROM projects WHERE name = ? LIMIT 1), ?, ?, ?, ?)
+		`, project, info.Name, info.Description, ingressJSON, egressJSON)
+		if err != nil {
+			return err
+		}
+
+		id, err = result.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		err = networkACLConfigAdd(tx.tx, id, info.Config)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		id = -1
+	}
+
+	return id, err
+}
+
+// UpdateNetworkACL updates the Network ACL with the given ID.
+func (c *Cluster) UpdateNetworkACL(id int64, config *api.NetworkACLPut) error {
+	ingressJSON, egressJSON, err := networkACLRulesToJSON(config.Ingress, config.Egress)
+	if err != nil {
+		return err
+	}
+
+	return c.Transaction(func(tx *ClusterTx) error {
+		_, err := tx.tx.Exec(`
+			UPDATE networks_acls
+			SET description=?, ingress = ?, egress = ?
+			WHERE id=?
+		`, config.Description, ingressJSON, egressJSON, id)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.tx.Exec("DELETE FROM networks_acls_config WHERE network_acl_id=?", id)
+		if err != nil {
+			return err
+		}
+
+		err = networkACLConfigAdd(tx.tx, id, config.Config)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+// RenameNetworkACL renames a Network ACL.
+func (c *Cluster) RenameNetworkACL(id int64, newName string) error {
+	return c.Transaction(func(tx *ClusterTx) error {
+		_, err := tx.tx.Exec("UPDATE networks_acls SET name=? WHERE id=?", newName, id)
+		return err
+	})
+}
+
+// DeleteNetworkACL deletes the Network ACL.
+func (c *Cluster) DeleteNetworkACL(id int64) error {
+	return c.Transaction(func(tx *ClusterTx) error {
+		_, err := tx.tx.Exec("DELETE FROM networks_acls WHERE id=?", id)
+		return err
+	})
+}
+
+// networkACLConfigAdd inserts Network ACL config keys.
+func networkACLConfigAdd(tx *sql.Tx, id int64, config map[string]string) error {
+	stmt, err := tx.Prepare("INSERT INTO networks_acls_config (network_acl_id, key, value) VALUES(?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for k, v := range config {
+		if v == "" {
+			continue
+		}
+
+		_, err = stmt.Exec(id, k, v)
+		if err != nil {
+			return errors.Wrapf(err, "Failed inserting config")
+		}
+	}
+
+	return nil
+}
+
+// networkACLRulesToJSON marshals the ingress and egress rules for storage.
+func networkACLRulesToJSON(ingress []api.NetworkACLRule, egress []api.NetworkACLRule) (string, string, error) {
+	if ingress == nil {
+		ingress = []api.NetworkACLRule{}
+	}
+
+	if egress == nil {
+		egress = []api.NetworkACLRule{}
+	}
+
+	ingressJSON, err := json.Marshal(ingress)
+	if err != nil {
+		return "", "", errors.Wrapf(err, "Failed marshalling ingress rules")
+	}
+
+	egressJSON, err := json.Marshal(egress)
+	if err != nil {
+		return "", "", errors.Wrapf(err, "Failed marshalling egress rules")
+	}
+
+	return string(ingressJSON), string(egressJSON), nil
+}
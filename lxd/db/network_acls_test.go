@@ -0,0 +1,62 @@
+// +build linux,cgo,!agent
+
+package db_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/project"
+	"github.com/lxc/lxd/shared/api"
+)
+
+func TestNetworkACLs(t *testing.T) {
+	cluster, cleanup := db.NewTestCluster(t)
+	defer cleanup()
+
+	req := &api.NetworkACLsPost{}
+	req.Name = "web"
+	req.Description = "Web servers"
+	req.Ingress = []api.NetworkACLRule{
+		{Action: "allow", Source: "10.0.0.0/8", Protocol: "tcp", DestinationPort: "443"},
+	}
+	req.Config = map[string]string{"user.foo": "bar"}
+
+	id, err := cluster.CreateNetworkACL(project.Default, req)
+	require.NoError(t, err)
+	assert.True(t, id > 0)
+
+	names, err := cluster.GetNetworkACLs(project.Default)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"web"}, names)
+
+	_, acl, err := cluster.GetNetworkACL(project.Default, "web")
+	require.NoError(t, err)
+	assert.Equal(t, "Web servers", acl.Description)
+	assert.Equal(t, req.Ingress, acl.Ingress)
+	assert.Equal(t, []api.NetworkACLRule{}, acl.Egress)
+	assert.Equal(t, map[string]string{"user.foo": "bar"}, acl.Config)
+
+	put := acl.Writable()
+	put.Egress = []api.NetworkACLRule{{Action: "drop", Destination: "192.0.2.1"}}
+	put.Config = map[string]string{}
+	err = cluster.UpdateNetworkACL(id, &put)
+	require.NoError(t, err)
+
+	err = cluster.RenameNetworkACL(id, "web2")
+	require.NoError(t, err)
+
+	_, acl, err = cluster.GetNetworkACL(project.Default, "web2")
+	require.NoError(t, err)
+	assert.Equal(t, put.Egress, acl.Egress)
+	assert.Equal(t, map[string]string{}, acl.Config)
+
+	err = cluster.DeleteNetworkACL(id)
+	require.NoError(t, err)
+
+	_, _, err = cluster.GetNetworkACL(project.Default, "web2")
+	assert.Equal(t, db.ErrNoSuchObject, err)
+}
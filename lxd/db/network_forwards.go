@@ -0,0 +1,230 @@
+// +build linux,cgo,!agent
+
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/db/query"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// GetNetworkForwardListenAddresses returns the listen addresses of the existing forwards of a network.
+func (c *Cluster) GetNetworkForwardListenAddresses(networkID int64) ([]string, error) {
+	q := `SELECT listen_address FROM networks_forwards
+		WHERE network_id = ?
+		ORDER BY id
+	`
+	inargs := []interface{}{networkID}
+
+	var listenAddress string
+	outfmt := []interface{}{listenAddress}
+	result, err := queryScan(c, q, inargs, outfmt)
+	if err != nil {
+		return nil, err
+	}
+
+	response := []string{}
+	for _, r := range result {
+		response = append(response, r[0].(string))
+	}
+
+	return response, nil
+}
+
+// GetNetworkForward returns the forward with the given listen address on the given network.
+func (c *Cluster) GetNetworkForward(networkID int64, listenAddress string) (int64, *api.NetworkForward, error) {
+	id := int64(-1)
+	var portsJSON string
+
+	forward := api.NetworkForward{
+		ListenAddress: listenAddress,
+	}
+
+	q := `
+		SELECT id, description, ports
+		FROM networks_forwards
+		WHERE network_id = ? AND listen_address = ?
+		LIMIT 1
+	`
+	arg1 := []interface{}{networkID, listenAddress}
+	arg2 := []interface{}{&id, &forward.Description, &portsJSON}
+
+	err := dbQueryRowScan(c, q, arg1, arg2)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return -1, nil, ErrNoSuchObject
+		}
+
+		return -1, nil, err
+	}
+
+	forward.Ports = []api.NetworkForwardPort{}
+	if portsJSON != "" {
+		err = json.Unmarshal([]byte(portsJSON), &forward.Ports)
+		if err != nil {
+			return -1, nil, errors.Wrapf(err, "Failed unmarshalling ports")
+		}
+	}
+
+	forward.Config, err = c.networkForwardConfig(id)
+	if err != nil {
+		return -1, nil, errors.Wrapf(err, "Failed loading config")
+	}
+
+	return id, &forward, nil
+}
+
+// networkForwardConfig returns the config map of the network forward with the given ID.
+func (c *Cluster) networkForwardConfig(id int64) (map[string]string, error) {
+	var key, value string
+	q := `
+		SELECT key, value
+		FROM networks_forwards_config
+		WHERE network_forward_id=?
+	`
+	inargs := []interface{}{id}
+	outfmt := []interface{}{key, value}
+	results, err := queryScan(c, q, inargs, outfmt)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get network forward config for ID %d", id)
+	}
+
+	config := make(map[string]string, len(results))
+
+	for _, r := range results {
+		key = r[0].(string)
+		value = r[1].(string)
+
+		config[key] = value
+	}
+
+	return config, nil
+}
+
+// CreateNetworkForward creates a new network forward.
+func (c *Cluster) CreateNetworkForward(networkID int64, info *api.NetworkForwardsPost) (int64, error) {
+	var id int64
+
+	portsJSON, err := networkForwardPortsToJSON(info.Ports)
+	if err != nil {
+		return -1, err
+	}
+
+	err = c.Transaction(func(tx *ClusterTx) error {
+		result, err := tx.tx.Exec(`
+			INSERT INTO networks_forwards (network_id, listen_address, description, ports)
+			VALUES (?, ?, ?, ?)
+		`, networkID, info.ListenAddress, info.Description, portsJSON)
+		if err != nil {
+			return err
+		}
+
+		id, err = result.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		err = networkForwardConfigAdd(tx.tx, id, info.Config)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		id = -1
+	}
+
+	return id, err
+}
+
+// UpdateNetworkForward updates the network forward with the given ID.
+func (c *Cluster) UpdateNetworkForward(id int64, info *api.NetworkForwardPut) error {
+	portsJSON, err := networkForwardPortsToJSON(info.Ports)
+	if err != nil {
+		return err
+	}
+
+	return c.Transaction(func(tx *ClusterTx) error {
+		_, err := tx.tx.Exec(`
+			UPDATE networks_forwards
+			SET description = ?, ports = ?
+			WHERE id = ?
+		`, info.Description, portsJSON, id)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.tx.Exec("DELETE FROM networks_forwards_config WHERE network_forward_id=?", id)
+		if err != nil {
+			return err
+		}
+
+		err = networkForwardConfigAdd(tx.tx, id, info.Config)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+// DeleteNetworkForward deletes the network forward.
+func (c *Cluster) DeleteNetworkForward(id int64) error {
+	return c.Transaction(func(tx *ClusterTx) error {
+		_, err := tx.tx.Exec("DELETE FROM networks_forwards WHERE id=?", id)
+		return err
+	})
+}
+
+// GetNetworkListenAddresses returns the listen addresses used by the forwards and load balancers of a network.
+func (c *ClusterTx) GetNetworkListenAddresses(networkID int64) ([]string, error) {
+	q := `
+		SELECT listen_address FROM networks_forwards WHERE network_id = ?
+		UNION
+		SELECT listen_address FROM networks_load_balancers WHERE network_id = ?
+	`
+
+	return query.SelectStrings(c.tx, q, networkID, networkID)
+}
+
+// networkForwardConfigAdd inserts network forward config keys.
+func networkForwardConfigAdd(tx *sql.Tx, id int64, config map[string]string) error {
+	stmt, err := tx.Prepare("INSERT INTO networks_forwards_config (network_forward_id, key, value) VALUES(?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for k, v := range config {
+		if v == "" {
+			continue
+		}
+
+		_, err = stmt.Exec(id, k, v)
+		if err != nil {
+			return errors.Wrapf(err, "Failed inserting config")
+		}
+	}
+
+	return nil
+}
+
+// networkForwardPortsToJSON marshals the forward ports for storage.
+func networkForwardPortsToJSON(ports []api.NetworkForwardPort) (string, error) {
+	if ports == nil {
+		ports = []api.NetworkForwardPort{}
+	}
+
+	portsJSON, err := json.Marshal(ports)
+	if err != nil {
+		return "", errors.Wrapf(err, "Failed marshalling ports")
+	}
+
+	return string(portsJSON), nil
+}
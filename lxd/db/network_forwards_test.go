@@ -0,0 +1,109 @@
+// +build linux,cgo,!agent
+
+package db_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/project"
+	"github.com/lxc/lxd/shared/api"
+)
+
+func TestNetworkForwards(t *testing.T) {
+	cluster, cleanup := db.NewTestCluster(t)
+	defer cleanup()
+
+	networkID, err := cluster.CreateNetwork(project.Default, "ovn0", "", db.NetworkTypeOVN, map[string]string{})
+	require.NoError(t, err)
+
+	req := &api.NetworkForwardsPost{}
+	req.ListenAddress = "192.0.2.1"
+	req.Description = "Web"
+	req.Config = map[string]string{"target_address": "10.0.0.2"}
+	req.Ports = []api.NetworkForwardPort{
+		{Protocol: "tcp", ListenPort: "80,443", TargetAddress: "10.0.0.3"},
+	}
+
+	id, err := cluster.CreateNetworkForward(networkID, req)
+	require.NoError(t, err)
+	assert.True(t, id > 0)
+
+	listenAddresses, err := cluster.GetNetworkForwardListenAddresses(networkID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"192.0.2.1"}, listenAddresses)
+
+	_, forward, err := cluster.GetNetworkForward(networkID, "192.0.2.1")
+	require.NoError(t, err)
+	assert.Equal(t, "Web", forward.Description)
+	assert.Equal(t, req.Ports, forward.Ports)
+	assert.Equal(t, map[string]string{"target_address": "10.0.0.2"}, forward.Config)
+
+	put := forward.Writable()
+	put.Ports = nil
+	put.Config = map[string]string{"user.foo": "bar"}
+	err = cluster.UpdateNetworkForward(id, &put)
+	require.NoError(t, err)
+
+	_, forward, err = cluster.GetNetworkForward(networkID, "192.0.2.1")
+	require.NoError(t, err)
+	assert.Equal(t, []api.NetworkForwardPort{}, forward.Ports)
+	assert.Equal(t, map[string]string{"user.foo": "bar"}, forward.Config)
+
+	err = cluster.DeleteNetworkForward(id)
+	require.NoError(t, err)
+
+	_, _, err = cluster.GetNetworkForward(networkID, "192.0.2.1")
+	assert.Equal(t, db.ErrNoSuchObject, err)
+}
+
+func TestNetworkLoadBalancers(t *testing.T) {
+	cluster, cleanup := db.NewTestCluster(t)
+	defer cleanup()
+
+	networkID, err := cluster.CreateNetwork(project.Default, "ovn0", "", db.NetworkTypeOVN, map[string]string{})
+	require.NoError(t, err)
+
+	req := &api.NetworkLoadBalancersPost{}
+	req.ListenAddress = "192.0.2.2"
+	req.Backends = []api.NetworkLoadBalancerBackend{
+		{Name: "web1", TargetAddress: "10.0.0.2"},
+		{Name: "web2", TargetAddress: "10.0.0.3", TargetPort: "8080"},
+	}
+	req.Ports = []api.NetworkLoadBalancerPort{
+		{Protocol: "tcp", ListenPort: "80", TargetBackend: []string{"web1", "web2"}},
+	}
+
+	id, err := cluster.CreateNetworkLoadBalancer(networkID, req)
+	require.NoError(t, err)
+
+	_, err = cluster.CreateNetworkForward(networkID, &api.NetworkForwardsPost{ListenAddress: "192.0.2.1"})
+	require.NoError(t, err)
+
+	listenAddresses, err := cluster.GetNetworkLoadBalancerListenAddresses(networkID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"192.0.2.2"}, listenAddresses)
+
+	_, loadBalancer, err := cluster.GetNetworkLoadBalancer(networkID, "192.0.2.2")
+	require.NoError(t, err)
+	assert.Equal(t, req.Backends, loadBalancer.Backends)
+	assert.Equal(t, req.Ports, loadBalancer.Ports)
+	assert.Equal(t, map[string]string{}, loadBalancer.Config)
+
+	// Listen addresses of both forwards and load balancers are reported.
+	err = cluster.Transaction(func(tx *db.ClusterTx) error {
+		listenAddresses, err = tx.GetNetworkListenAddresses(networkID)
+		return err
+	})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"192.0.2.1", "192.0.2.2"}, listenAddresses)
+
+	err = cluster.DeleteNetworkLoadBalancer(id)
+	require.NoError(t, err)
+
+	_, _, err = cluster.GetNetworkLoadBalancer(networkID, "192.0.2.2")
+	assert.Equal(t, db.ErrNoSuchObject, err)
+}
@@ -0,0 +1,236 @@
+// +build linux,cgo,!agent
+
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+// GetNetworkLoadBalancerListenAddresses returns the listen addresses of the existing load balancers of a network.
+func (c *Cluster) GetNetworkLoadBalancerListenAddresses(networkID int64) ([]string, error) {
+	q := `SELECT listen_address FROM networks_load_balancers
+		WHERE network_id = ?
+		ORDER BY id
+	`
+	inargs := []interface{}{networkID}
+
+	var listenAddress string
+	outfmt := []interface{}{listenAddress}
+	result, err := queryScan(c, q, inargs, outfmt)
+	if err != nil {
+		return nil, err
+	}
+
+	response := []string{}
+	for _, r := range result {
+		response = append(response, r[0].(string))
+	}
+
+	return response, nil
+}
+
+// GetNetworkLoadBalancer returns the load balancer with the given listen address on the given network.
+func (c *Cluster) GetNetworkLoadBalancer(networkID int64, listenAddress string) (int64, *api.NetworkLoadBalancer, error) {
+	id := int64(-1)
+	var backendsJSON string
+	var portsJSON string
+
+	loadBalancer := api.NetworkLoadBalancer{
+		ListenAddress: listenAddress,
+	}
+
+	q := `
+		SELECT id, description, backends, ports
+		FROM networks_load_balancers
+		WHERE network_id = ? AND listen_address = ?
+		LIMIT 1
+	`
+	arg1 := []interface{}{networkID, listenAddress}
+	arg2 := []interface{}{&id, &loadBalancer.Description, &backendsJSON, &portsJSON}
+
+	err := dbQueryRowScan(c, q, arg1, arg2)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return -1, nil, ErrNoSuchObject
+		}
+
+		return -1, nil, err
+	}
+
+	loadBalancer.Backends = []api.NetworkLoadBalancerBackend{}
+	if backendsJSON != "" {
+		err = json.Unmarshal([]byte(backendsJSON), &loadBalancer.Backends)
+		if err != nil {
+			return -1, nil, errors.Wrapf(err, "Failed unmarshalling backends")
+		}
+	}
+
+	loadBalancer.Ports = []api.NetworkLoadBalancerPort{}
+	if portsJSON != "" {
+		err = json.Unmarshal([]byte(portsJSON), &loadBalancer.Ports)
+		if err != nil {
+			return -1, nil, errors.Wrapf(err, "Failed unmarshalling ports")
+		}
+	}
+
+	loadBalancer.Config, err = c.networkLoadBalancerConfig(id)
+	if err != nil {
+		return -1, nil, errors.Wrapf(err, "Failed loading config")
+	}
+
+	return id, &loadBalancer, nil
+}
+
+// networkLoadBalancerConfig returns the config map of the network load balancer with the given ID.
+func (c *Cluster) networkLoadBalancerConfig(id int64) (map[string]string, error) {
+	var key, value string
+	q := `
+		SELECT key, value
+		FROM networks_load_balancers_config
+		WHERE network_load_balancer_id=?
+	`
+	inargs := []interface{}{id}
+	outfmt := []interface{}{key, value}
+	results, err := queryScan(c, q, inargs, outfmt)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get network load balancer config for ID %d", id)
+	}
+
+	config := make(map[string]string, len(results))
+
+	for _, r := range results {
+		key = r[0].(string)
+		value = r[1].(string)
+
+		config[key] = value
+	}
+
+	return config, nil
+}
+
+// CreateNetworkLoadBalancer creates a new network load balancer.
+func (c *Cluster) CreateNetworkLoadBalancer(networkID int64, info *api.NetworkLoadBalancersPost) (int64, error) {
+	var id int64
+
+	backendsJSON, portsJSON, err := networkLoadBalancerToJSON(info.Backends, info.Ports)
+	if err != nil {
+		return -1, err
+	}
+
+	err = c.Transaction(func(tx *ClusterTx) error {
+		result, err := tx.tx.Exec(`
+			INSERT INTO networks_load_balancers (network_id, listen_address, description, backends, ports)
+			VALUES (?, ?, ?, ?, ?)
+		`, networkID, info.ListenAddress, info.Description, backendsJSON, portsJSON)
+		if err != nil {
+			return err
+		}
+
+		id, err = result.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		err = networkLoadBalancerConfigAdd(tx.tx, id, info.Config)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		id = -1
+	}
+
+	return id, err
+}
+
+// UpdateNetworkLoadBalancer updates the network load balancer with the given ID.
+func (c *Cluster) UpdateNetworkLoadBalancer(id int64, info *api.NetworkLoadBalancerPut) error {
+	backendsJSON, portsJSON, err := networkLoadBalancerToJSON(info.Backends, info.Ports)
+	if err != nil {
+		return err
+	}
+
+	return c.Transaction(func(tx *ClusterTx) error {
+		_, err := tx.tx.Exec(`
+			UPDATE networks_load_balancers
+			SET description = ?, backends = ?, ports = ?
+			WHERE id = ?
+		`, info.Description, backendsJSON, portsJSON, id)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.tx.Exec("DELETE FROM networks_load_balancers_config WHERE network_load_balancer_id=?", id)
+		if err != nil {
+			return err
+		}
+
+		err = networkLoadBalancerConfigAdd(tx.tx, id, info.Config)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+// DeleteNetworkLoadBalancer deletes the network load balancer.
+func (c *Cluster) DeleteNetworkLoadBalancer(id int64) error {
+	return c.Transaction(func(tx *ClusterTx) error {
+		_, err := tx.tx.Exec("DELETE FROM networks_load_balancers WHERE id=?", id)
+		return err
+	})
+}
+
+// networkLoadBalancerConfigAdd inserts network load balancer config keys.
+func networkLoadBalancerConfigAdd(tx *sql.Tx, id int64, config map[string]string) error {
+	stmt, err := tx.Prepare("INSERT INTO networks_load_balancers_config (network_load_balancer_id, key, value) VALUES(?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for k, v := range config {
+		if v == "" {
+			continue
+		}
+
+		_, err = stmt.Exec(id, k, v)
+		if err != nil {
+			return errors.Wrapf(err, "Failed inserting config")
+		}
+	}
+
+	return nil
+}
+
+// networkLoadBalancerToJSON marshals the load balancer backends and ports for storage.
+func networkLoadBalancerToJSON(backends []api.NetworkLoadBalancerBackend, ports []api.NetworkLoadBalancerPort) (string, string, error) {
+	if backends == nil {
+		backends = []api.NetworkLoadBalancerBackend{}
+	}
+
+	if ports == nil {
+		ports = []api.NetworkLoadBalancerPort{}
+	}
+
+	backendsJSON, err := json.Marshal(backends)
+	if err != nil {
+		return "", "", errors.Wrapf(err, "Failed marshalling backends")
+	}
+
+	portsJSON, err := json.Marshal(ports)
+	if err != nil {
+		return "", "", errors.Wrapf(err, "Failed marshalling ports")
+	}
+
+	return string(backendsJSON), string(portsJSON), nil
+}
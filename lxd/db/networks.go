@@ -17,16 +17,19 @@ import (
 // GetNetworksLocalConfig returns a map associating each network name to its
 // node-specific config values on the local node (i.e. the ones where node_id
 // equals the ID of the local node).
+//
+// Only networks in the default project can have node-specific config, so other
+// projects are skipped.
 func (c *ClusterTx) GetNetworksLocalConfig() (map[string]map[string]string, error) {
-	names, err := query.SelectStrings(c.tx, "SELECT name FROM networks")
+	names, err := query.SelectStrings(c.tx, "SELECT networks.name FROM networks JOIN projects ON projects.id = networks.project_id WHERE projects.name = 'default'")
 	if err != nil {
 		return nil, err
 	}
 	networks := make(map[string]map[string]string, len(names))
 	for _, name := range names {
-		table := "networks_config JOIN networks ON networks.id=networks_config.network_id"
+		table := "networks_config JOIN networks ON networks.id=networks_config.network_id JOIN projects ON projects.id=networks.project_id"
 		config, err := query.SelectConfig(
-			c.tx, table, "networks.name=? AND networks_config.node_id=?",
+			c.tx, table, "projects.name='default' AND networks.name=? AND networks_config.node_id=?",
 			name, c.nodeID)
 		if err != nil {
 			return nil, err
@@ -36,23 +39,26 @@ func (c *ClusterTx) GetNetworksLocalConfig() (map[string]map[string]string, erro
 	return networks, nil
 }
 
-// GetNonPendingNetworkIDs returns a map associating each network name to its ID.
+// GetNonPendingNetworkIDs returns a map associating each project name to a map
+// of its network names and IDs.
 //
 // Pending networks are skipped.
-func (c *ClusterTx) GetNonPendingNetworkIDs() (map[string]int64, error) {
+func (c *ClusterTx) GetNonPendingNetworkIDs() (map[string]map[string]int64, error) {
 	networks := []struct {
-		id   int64
-		name string
+		id      int64
+		name    string
+		project string
 	}{}
 	dest := func(i int) []interface{} {
 		networks = append(networks, struct {
-			id   int64
-			name string
+			id      int64
+			name    string
+			project string
 		}{})
-		return []interface{}{&networks[i].id, &networks[i].name}
+		return []interface{}{&networks[i].id, &networks[i].name, &networks[i].project}
 
 	}
-	stmt, err := c.tx.Prepare("SELECT id, name FROM networks WHERE NOT state=?")
+	stmt, err := c.tx.Prepare("SELECT networks.id, networks.name, projects.name FROM networks JOIN projects ON projects.id = networks.project_id WHERE NOT networks.state=?")
 	if err != nil {
 		return nil, err
 	}
@@ -61,24 +67,28 @@ func (c *ClusterTx) GetNonPendingNetworkIDs() (map[string]int64, error) {
 	if err != nil {
 		return nil, err
 	}
-	ids := map[string]int64{}
+	ids := map[string]map[string]int64{}
 	for _, network := range networks {
-		ids[network.name] = network.id
+		if ids[network.project] == nil {
+			ids[network.project] = map[string]int64{}
+		}
+
+		ids[network.project][network.name] = network.id
 	}
 	return ids, nil
 }
 
-// GetNonPendingNetworks returns a map of api.Network associated to network ID.
+// GetNonPendingNetworks returns a map of api.Network associated to network ID for the given project.
 //
 // Pending networks are skipped.
-func (c *ClusterTx) GetNonPendingNetworks() (map[int64]api.Network, error) {
-	stmt, err := c.tx.Prepare("SELECT id, name, description, type, state FROM networks WHERE state != ?")
+func (c *ClusterTx) GetNonPendingNetworks(project string) (map[int64]api.Network, error) {
+	stmt, err := c.tx.Prepare("SELECT id, name, description, type, state FROM networks WHERE project_id = (SELECT id FROM projects WHERE name = ?) AND state != ?")
 	if err != nil {
 		return nil, err
 	}
 	defer stmt.Close()
 
-	rows, err := stmt.Query(networkPending)
+	rows, err := stmt.Query(project, networkPending)
 	if err != nil {
 		return nil, err
 	}
@@ -129,10 +139,10 @@ func (c *ClusterTx) GetNonPendingNetworks() (map[int64]api.Network, error) {
 	return networks, nil
 }
 
-// GetNetworkID returns the ID of the network with the given name.
-func (c *ClusterTx) GetNetworkID(name string) (int64, error) {
-	stmt := "SELECT id FROM networks WHERE name=?"
-	ids, err := query.SelectIntegers(c.tx, stmt, name)
+// GetNetworkID returns the ID of the network with the given name in the given project.
+func (c *ClusterTx) GetNetworkID(project string, name string) (int64, error) {
+	stmt := "SELECT id FROM networks WHERE project_id = (SELECT id FROM projects WHERE name = ?) AND name=?"
+	ids, err := query.SelectIntegers(c.tx, stmt, project, name)
 	if err != nil {
 		return -1, err
 	}
@@ -228,13 +238,13 @@ func (c *ClusterTx) CreatePendingNetwork(node string, projectName string, name s
 		return []interface{}{&network.id, &network.state, &network.netType}
 	}
 
-	stmt, err := c.tx.Prepare("SELECT id, state, type FROM networks WHERE name=?")
+	stmt, err := c.tx.Prepare("SELECT id, state, type FROM networks WHERE project_id = (SELECT id FROM projects WHERE name = ?) AND name=?")
 	if err != nil {
 		return err
 	}
 	defer stmt.Close()
 
-	err = query.SelectObjects(stmt, dest, name)
+	err = query.SelectObjects(stmt, dest, projectName, name)
 	if err != nil {
 		return err
 	}
@@ -301,18 +311,18 @@ func (c *ClusterTx) CreatePendingNetwork(node string, projectName string, name s
 }
 
 // NetworkCreated sets the state of the given network to "Created".
-func (c *ClusterTx) NetworkCreated(name string) error {
-	return c.networkState(name, networkCreated)
+func (c *ClusterTx) NetworkCreated(project string, name string) error {
+	return c.networkState(project, name, networkCreated)
 }
 
 // NetworkErrored sets the state of the given network to "Errored".
-func (c *ClusterTx) NetworkErrored(name string) error {
-	return c.networkState(name, networkErrored)
+func (c *ClusterTx) NetworkErrored(project string, name string) error {
+	return c.networkState(project, name, networkErrored)
 }
 
-func (c *ClusterTx) networkState(name string, state int) error {
-	stmt := "UPDATE networks SET state=? WHERE name=?"
-	result, err := c.tx.Exec(stmt, state, name)
+func (c *ClusterTx) networkState(project string, name string, state int) error {
+	stmt := "UPDATE networks SET state=? WHERE project_id = (SELECT id FROM projects WHERE name = ?) AND name=?"
+	result, err := c.tx.Exec(stmt, state, project, name)
 	if err != nil {
 		return err
 	}
@@ -362,19 +372,30 @@ func (c *ClusterTx) networkNodes(networkID int64) ([]string, error) {
 	return nodes, nil
 }
 
-// GetNetworks returns the names of existing networks.
-func (c *Cluster) GetNetworks() ([]string, error) {
-	return c.networks("")
+// GetNetworks returns the names of existing networks in the given project.
+func (c *Cluster) GetNetworks(project string) ([]string, error) {
+	return c.networks(project, "")
+}
+
+// GetNonPendingNetworks returns the names of all networks in the given project that are not pending.
+func (c *Cluster) GetNonPendingNetworks(project string) ([]string, error) {
+	return c.networks(project, "NOT state=?", networkPending)
+}
+
+// GetNetworksAllProjects returns a map of project name to the names of the networks in that project.
+func (c *Cluster) GetNetworksAllProjects() (map[string][]string, error) {
+	return c.networksAllProjects("")
 }
 
-// GetNonPendingNetworks returns the names of all networks that are not pending.
-func (c *Cluster) GetNonPendingNetworks() ([]string, error) {
-	return c.networks("NOT state=?", networkPending)
+// GetNonPendingNetworksAllProjects returns a map of project name to the names of the networks in that project
+// that are not pending.
+func (c *Cluster) GetNonPendingNetworksAllProjects() (map[string][]string, error) {
+	return c.networksAllProjects("NOT networks.state=?", networkPending)
 }
 
-// Get all networks matching the given WHERE filter (if given).
-func (c *Cluster) networks(where string, args ...interface{}) ([]string, error) {
-	q := "SELECT name FROM networks"
+// Get all networks in all projects matching the given WHERE filter (if given).
+func (c *Cluster) networksAllProjects(where string, args ...interface{}) (map[string][]string, error) {
+	q := "SELECT projects.name, networks.name FROM networks JOIN projects ON projects.id = networks.project_id"
 	inargs := []interface{}{}
 
 	if where != "" {
@@ -384,6 +405,35 @@ func (c *Cluster) networks(where string, args ...interface{}) ([]string, error)
 		}
 	}
 
+	var projectName string
+	var name string
+	outfmt := []interface{}{projectName, name}
+	result, err := queryScan(c, q, inargs, outfmt)
+	if err != nil {
+		return nil, err
+	}
+
+	response := map[string][]string{}
+	for _, r := range result {
+		projectName := r[0].(string)
+		response[projectName] = append(response[projectName], r[1].(string))
+	}
+
+	return response, nil
+}
+
+// Get all networks in the given project matching the given WHERE filter (if given).
+func (c *Cluster) networks(project string, where string, args ...interface{}) ([]string, error) {
+	q := "SELECT name FROM networks WHERE project_id = (SELECT id FROM projects WHERE name = ?)"
+	inargs := []interface{}{project}
+
+	if where != "" {
+		q += fmt.Sprintf(" AND %s", where)
+		for _, arg := range args {
+			inargs = append(inargs, arg)
+		}
+	}
+
 	var name string
 	outfmt := []interface{}{name}
 	result, err := queryScan(c, q, inargs, outfmt)
@@ -417,23 +467,23 @@ const (
 	NetworkTypeOVN                        // Network type ovn.
 )
 
-// GetNetworkInAnyState returns the network with the given name.
+// GetNetworkInAnyState returns the network with the given name in the given project.
 //
 // The network can be in any state.
-func (c *Cluster) GetNetworkInAnyState(name string) (int64, *api.Network, error) {
-	return c.getNetwork(name, false)
+func (c *Cluster) GetNetworkInAnyState(project string, name string) (int64, *api.Network, error) {
+	return c.getNetwork(project, name, false)
 }
 
-// Get the network with the given name. If onlyCreated is true, only return
+// Get the network with the given name in the given project. If onlyCreated is true, only return
 // networks in the created state.
-func (c *Cluster) getNetwork(name string, onlyCreated bool) (int64, *api.Network, error) {
+func (c *Cluster) getNetwork(project string, name string, onlyCreated bool) (int64, *api.Network, error) {
 	description := sql.NullString{}
 	id := int64(-1)
 	state := 0
 	var netType NetworkType
 
-	q := "SELECT id, description, state, type FROM networks WHERE name=?"
-	arg1 := []interface{}{name}
+	q := "SELECT id, description, state, type FROM networks WHERE project_id = (SELECT id FROM projects WHERE name = ?) AND name=?"
+	arg1 := []interface{}{project, name}
 	arg2 := []interface{}{&id, &description, &state, &netType}
 	if onlyCreated {
 		q += " AND state=?"
@@ -528,7 +578,7 @@ func (c *Cluster) GetNetworkWithInterface(devName string) (int64, *api.Network,
 	name := ""
 	value := ""
 
-	q := "SELECT networks.id, networks.name, networks_config.value FROM networks LEFT JOIN networks_config ON networks.id=networks_config.network_id WHERE networks_config.key=\"bridge.external_interfaces\" AND networks_config.node_id=?"
+	q := "SELECT networks.id, networks.name, networks_config.value FROM networks LEFT JOIN networks_config ON networks.id=networks_config.network_id WHERE networks_config.key=\"bridge.external_interfaces\" AND networks_config.node_id=? AND networks.project_id = (SELECT id FROM projects WHERE name = 'default')"
 	arg1 := []interface{}{c.nodeID}
 	arg2 := []interface{}{id, name, value}
 	result, err := queryScan(c, q, arg1, arg2)
@@ -647,9 +697,9 @@ func (c *Cluster) CreateNetwork(projectName string, name string, description str
 	return id, err
 }
 
-// UpdateNetwork updates the network with the given name.
-func (c *Cluster) UpdateNetwork(name, description string, config map[string]string) error {
-	id, netInfo, err := c.GetNetworkInAnyState(name)
+// UpdateNetwork updates the network with the given name in the given project.
+func (c *Cluster) UpdateNetwork(project string, name, description string, config map[string]string) error {
+	id, netInfo, err := c.GetNetworkInAnyState(project, name)
 	if err != nil {
 		return err
 	}
@@ -662,7 +712,7 @@ func (c *Cluster) UpdateNetwork(name, description string, config map[string]stri
 
 		// Update network status if change applied successfully.
 		if netInfo.Status == api.NetworkStatusErrored {
-			err = tx.NetworkCreated(name)
+			err = tx.NetworkCreated(project, name)
 			if err != nil {
 				return err
 			}
@@ -721,9 +771,9 @@ func clearNetworkConfig(tx *sql.Tx, networkID, nodeID int64) error {
 	return nil
 }
 
-// DeleteNetwork deletes the network with the given name.
-func (c *Cluster) DeleteNetwork(name string) error {
-	id, _, err := c.GetNetworkInAnyState(name)
+// DeleteNetwork deletes the network with the given name in the given project.
+func (c *Cluster) DeleteNetwork(project string, name string) error {
+	id, _, err := c.GetNetworkInAnyState(project, name)
 	if err != nil {
 		return err
 	}
@@ -736,9 +786,9 @@ func (c *Cluster) DeleteNetwork(name string) error {
 	return nil
 }
 
-// RenameNetwork renames a network.
-func (c *Cluster) RenameNetwork(oldName string, newName string) error {
-	id, _, err := c.GetNetworkInAnyState(oldName)
+// RenameNetwork renames a network in the given project.
+func (c *Cluster) RenameNetwork(project string, oldName string, newName string) error {
+	id, _, err := c.GetNetworkInAnyState(project, oldName)
 	if err != nil {
 		return err
 	}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/project"
+	"github.com/lxc/lxd/shared/api"
 )
 
 // The GetNetworksLocalConfigs method returns only node-specific config values.
@@ -37,6 +38,47 @@ func TestGetNetworksLocalConfigs(t *testing.T) {
 	})
 }
 
+// Networks with the same name can exist in different projects.
+func TestGetNetworkInAnyState_Projects(t *testing.T) {
+	cluster, cleanup := db.NewTestCluster(t)
+	defer cleanup()
+
+	err := cluster.Transaction(func(tx *db.ClusterTx) error {
+		_, err := tx.CreateProject(api.ProjectsPost{Name: "p1"})
+		return err
+	})
+	require.NoError(t, err)
+
+	defaultID, err := cluster.CreateNetwork(project.Default, "net1", "", db.NetworkTypeBridge, map[string]string{})
+	require.NoError(t, err)
+
+	projectID, err := cluster.CreateNetwork("p1", "net1", "", db.NetworkTypeOVN, map[string]string{"network": "net1"})
+	require.NoError(t, err)
+	assert.NotEqual(t, defaultID, projectID)
+
+	id, network, err := cluster.GetNetworkInAnyState("p1", "net1")
+	require.NoError(t, err)
+	assert.Equal(t, projectID, id)
+	assert.Equal(t, "ovn", network.Type)
+
+	names, err := cluster.GetNetworks(project.Default)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"net1"}, names)
+
+	all, err := cluster.GetNetworksAllProjects()
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{project.Default: {"net1"}, "p1": {"net1"}}, all)
+
+	err = cluster.DeleteNetwork("p1", "net1")
+	require.NoError(t, err)
+
+	_, _, err = cluster.GetNetworkInAnyState("p1", "net1")
+	assert.Equal(t, db.ErrNoSuchObject, err)
+
+	_, _, err = cluster.GetNetworkInAnyState(project.Default, "net1")
+	require.NoError(t, err)
+}
+
 func TestCreatePendingNetwork(t *testing.T) {
 	tx, cleanup := db.NewTestClusterTx(t)
 	defer cleanup()
@@ -50,7 +92,7 @@ func TestCreatePendingNetwork(t *testing.T) {
 	err = tx.CreatePendingNetwork("buzz", project.Default, "network1", db.NetworkTypeBridge, config)
 	require.NoError(t, err)
 
-	networkID, err := tx.GetNetworkID("network1")
+	networkID, err := tx.GetNetworkID(project.Default, "network1")
 	require.NoError(t, err)
 	assert.True(t, networkID > 0)
 
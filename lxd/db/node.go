@@ -32,6 +32,12 @@ const ClusterRoleDatabase = ClusterRole("database")
 // only contain LXD-specific cluster roles.
 var ClusterRoles = map[int]ClusterRole{}
 
+// Numeric type codes identifying the state of a cluster member.
+const (
+	ClusterMemberStateCreated   = 0
+	ClusterMemberStateEvacuated = 1
+)
+
 // NodeInfo holds information about a single LXD instance in a cluster.
 type NodeInfo struct {
 	ID            int64     // Stable node identifier
@@ -43,6 +49,7 @@ type NodeInfo struct {
 	Heartbeat     time.Time // Timestamp of the last heartbeat
 	Roles         []string  // List of cluster roles
 	Architecture  int       // Node architecture
+	State         int       // Node state
 }
 
 // IsOffline returns true if the last successful heartbeat time of the node is
@@ -188,6 +195,39 @@ func (c *ClusterTx) GetNodes() ([]NodeInfo, error) {
 	return c.nodes(false /* not pending */, "")
 }
 
+// GetNodesVersions returns all LXD nodes part of the cluster, with only their ID, name, address, versions and
+// heartbeat filled.
+//
+// Only the columns which all schema versions have are used, so that it works while the schema of the database is
+// still older than the one of this node, e.g. during a rolling upgrade.
+func (c *ClusterTx) GetNodesVersions() ([]NodeInfo, error) {
+	nodes := []NodeInfo{}
+	dest := func(i int) []interface{} {
+		nodes = append(nodes, NodeInfo{})
+		return []interface{}{
+			&nodes[i].ID,
+			&nodes[i].Name,
+			&nodes[i].Address,
+			&nodes[i].Schema,
+			&nodes[i].APIExtensions,
+			&nodes[i].Heartbeat,
+		}
+	}
+
+	stmt, err := c.tx.Prepare("SELECT id, name, address, schema, api_extensions, heartbeat FROM nodes WHERE pending=0 ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	err = query.SelectObjects(stmt, dest)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch nodes")
+	}
+
+	return nodes, nil
+}
+
 // GetNodesCount returns the number of nodes in the LXD cluster.
 //
 // Since there's always at least one node row, even when not-clustered, the
@@ -226,6 +266,25 @@ func (c *ClusterTx) RenameNode(old, new string) error {
 	return nil
 }
 
+// UpdateNodeClusterState changes the state of the node with the given ID.
+func (c *ClusterTx) UpdateNodeClusterState(id int64, state int) error {
+	result, err := c.tx.Exec("UPDATE nodes SET state=? WHERE id=?", state, id)
+	if err != nil {
+		return errors.Wrap(err, "Failed to update node state")
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "Failed to get rows count")
+	}
+
+	if n != 1 {
+		return fmt.Errorf("Expected to update one row, not %d", n)
+	}
+
+	return nil
+}
+
 // Nodes returns all LXD nodes part of the cluster.
 func (c *ClusterTx) nodes(pending bool, where string, args ...interface{}) ([]NodeInfo, error) {
 	// Get node roles
@@ -277,6 +336,7 @@ func (c *ClusterTx) nodes(pending bool, where string, args ...interface{}) ([]No
 			&nodes[i].APIExtensions,
 			&nodes[i].Heartbeat,
 			&nodes[i].Architecture,
+			&nodes[i].State,
 		}
 	}
 	if pending {
@@ -286,7 +346,7 @@ func (c *ClusterTx) nodes(pending bool, where string, args ...interface{}) ([]No
 	}
 
 	// Get the node entries
-	sql = "SELECT id, name, address, description, schema, api_extensions, heartbeat, arch FROM nodes WHERE pending=?"
+	sql = "SELECT id, name, address, description, schema, api_extensions, heartbeat, arch, state FROM nodes WHERE pending=?"
 	if where != "" {
 		sql += fmt.Sprintf("AND %s ", where)
 	}
@@ -330,7 +390,18 @@ func (c *ClusterTx) CreateNode(name string, address string) (int64, error) {
 func (c *ClusterTx) CreateNodeWithArch(name string, address string, arch int) (int64, error) {
 	columns := []string{"name", "address", "schema", "api_extensions", "arch"}
 	values := []interface{}{name, address, cluster.SchemaVersion, version.APIExtensionsCount(), arch}
-	return query.UpsertObject(c.tx, "nodes", columns, values)
+	id, err := query.UpsertObject(c.tx, "nodes", columns, values)
+	if err != nil {
+		return -1, err
+	}
+
+	// New nodes start in the default cluster group.
+	_, err = c.tx.Exec("INSERT INTO nodes_cluster_groups (node_id, group_id) SELECT ?, id FROM cluster_groups WHERE name = 'default'", id)
+	if err != nil {
+		return -1, errors.Wrap(err, "Failed to add node to default cluster group")
+	}
+
+	return id, nil
 }
 
 // SetNodePendingFlag toggles the pending flag for the node. A node is pending when
@@ -761,8 +832,18 @@ func (c *ClusterTx) GetNodeOfflineThreshold() (time.Duration, error) {
 // GetNodeWithLeastInstances returns the name of the non-offline node with with
 // the least number of containers (either already created or being created with
 // an operation). If archs is not empty, then return only nodes with an
-// architecture in that list.
-func (c *ClusterTx) GetNodeWithLeastInstances(archs []int) (string, error) {
+// architecture in that list. If group is not empty, then return only nodes of
+// that cluster group, and if allowedGroups is not nil, only nodes belonging to
+// at least one of those groups.
+//
+// Nodes already upgraded to a more recent version than this one are skipped,
+// since they don't serve requests until all nodes are upgraded.
+//
+// If application is not empty, nodes in the failure domains running the
+// fewest instances of the project with that "cluster.application" are
+// preferred, so that the replicas of an application are spread across
+// failure domains.
+func (c *ClusterTx) GetNodeWithLeastInstances(archs []int, group string, allowedGroups []string, project string, application string) (string, error) {
 	threshold, err := c.GetNodeOfflineThreshold()
 	if err != nil {
 		return "", errors.Wrap(err, "failed to get offline threshold")
@@ -773,13 +854,58 @@ func (c *ClusterTx) GetNodeWithLeastInstances(archs []int) (string, error) {
 		return "", errors.Wrap(err, "failed to get current nodes")
 	}
 
+	var nodesGroups map[int64][]string
+	if group != "" || allowedGroups != nil {
+		nodesGroups, err = c.GetNodesClusterGroups()
+		if err != nil {
+			return "", errors.Wrap(err, "Failed to get cluster groups")
+		}
+	}
+
+	var domains map[string]uint64
+	var replicas map[uint64]int
+	if application != "" {
+		domains, err = c.GetNodesFailureDomains()
+		if err != nil {
+			return "", errors.Wrap(err, "Failed to get failure domains")
+		}
+
+		replicas, err = c.getFailureDomainsApplicationCount(domains, project, application)
+		if err != nil {
+			return "", errors.Wrap(err, "Failed to get application instances count")
+		}
+	}
+
 	name := ""
 	containers := -1
+	domainReplicas := -1
 	for _, node := range nodes {
-		if node.IsOffline(threshold) {
+		if node.State == ClusterMemberStateEvacuated || node.IsOffline(threshold) {
+			continue
+		}
+
+		if node.Schema > cluster.SchemaVersion || node.APIExtensions > version.APIExtensionsCount() {
+			continue
+		}
+
+		if group != "" && !shared.StringInSlice(group, nodesGroups[node.ID]) {
 			continue
 		}
 
+		if allowedGroups != nil {
+			allowed := false
+			for _, nodeGroup := range nodesGroups[node.ID] {
+				if shared.StringInSlice(nodeGroup, allowedGroups) {
+					allowed = true
+					break
+				}
+			}
+
+			if !allowed {
+				continue
+			}
+		}
+
 		if len(archs) > 0 {
 			// Get personalities too.
 			personalities, err := osarch.ArchitecturePersonalities(node.Architecture)
@@ -816,14 +942,40 @@ func (c *ClusterTx) GetNodeWithLeastInstances(archs []int) (string, error) {
 		}
 
 		count := created + pending
-		if containers == -1 || count < containers {
+
+		// A failure domain with fewer replicas of the application wins over a less busy node.
+		nodeReplicas := replicas[domains[node.Address]]
+		if containers == -1 || nodeReplicas < domainReplicas || (nodeReplicas == domainReplicas && count < containers) {
 			containers = count
+			domainReplicas = nodeReplicas
 			name = node.Name
 		}
 	}
 	return name, nil
 }
 
+// getFailureDomainsApplicationCount returns a map associating failure domain IDs with the number of instances
+// of the given project and application they run.
+func (c *ClusterTx) getFailureDomainsApplicationCount(domains map[string]uint64, project string, application string) (map[uint64]int, error) {
+	addresses, err := query.SelectStrings(c.tx, `
+SELECT nodes.address FROM instances
+  JOIN instances_config ON instances_config.instance_id = instances.id
+  JOIN projects ON projects.id = instances.project_id
+  JOIN nodes ON nodes.id = instances.node_id
+ WHERE projects.name = ? AND instances_config.key = 'cluster.application' AND instances_config.value = ?
+`, project, application)
+	if err != nil {
+		return nil, err
+	}
+
+	replicas := map[uint64]int{}
+	for _, address := range addresses {
+		replicas[domains[address]]++
+	}
+
+	return replicas, nil
+}
+
 // SetNodeVersion updates the schema and API version of the node with the
 // given id. This is used only in tests.
 func (c *ClusterTx) SetNodeVersion(id int64, version [2]int) error {
@@ -57,6 +57,29 @@ func TestGetNodesCount(t *testing.T) {
 	assert.Equal(t, 2, count)
 }
 
+// The versions of the nodes can be listed without the columns of newer schemas.
+func TestGetNodesVersions(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	id, err := tx.CreateNode("buzz", "1.2.3.4:666")
+	require.NoError(t, err)
+
+	_, err = tx.CreateNode("rusp", "5.6.7.8:666")
+	require.NoError(t, err)
+
+	err = tx.SetNodePendingFlag(id, true)
+	require.NoError(t, err)
+
+	nodes, err := tx.GetNodesVersions()
+	require.NoError(t, err)
+	require.Len(t, nodes, 2)
+	assert.Equal(t, "rusp", nodes[1].Name)
+	assert.Equal(t, "5.6.7.8:666", nodes[1].Address)
+	assert.Equal(t, [2]int{cluster.SchemaVersion, len(version.APIExtensions)}, nodes[1].Version())
+	assert.False(t, nodes[1].IsOffline(20*time.Second))
+}
+
 func TestNodeIsOutdated_SingleNode(t *testing.T) {
 	tx, cleanup := db.NewTestClusterTx(t)
 	defer cleanup()
@@ -314,7 +337,7 @@ INSERT INTO instances (id, node_id, name, architecture, type, project_id) VALUES
 `)
 	require.NoError(t, err)
 
-	name, err := tx.GetNodeWithLeastInstances(nil)
+	name, err := tx.GetNodeWithLeastInstances(nil, "", nil, "", "")
 	require.NoError(t, err)
 	assert.Equal(t, "buzz", name)
 }
@@ -338,13 +361,61 @@ INSERT INTO instances (id, node_id, name, architecture, type, project_id) VALUES
 	err = tx.SetNodeHeartbeat("0.0.0.0", time.Now().Add(-time.Minute))
 	require.NoError(t, err)
 
-	name, err := tx.GetNodeWithLeastInstances(nil)
+	name, err := tx.GetNodeWithLeastInstances(nil, "", nil, "", "")
 	require.NoError(t, err)
 	assert.Equal(t, "buzz", name)
 }
 
+// A node already upgraded to a more recent version is not considered.
+func TestGetNodeWithLeastInstances_Upgraded(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	id, err := tx.CreateNode("buzz", "1.2.3.4:666")
+	require.NoError(t, err)
+
+	err = tx.SetNodeVersion(id, [2]int{cluster.SchemaVersion + 1, len(version.APIExtensions)})
+	require.NoError(t, err)
+
+	// Add a container to the default node.
+	_, err = tx.Tx().Exec(`
+INSERT INTO instances (id, node_id, name, architecture, type, project_id) VALUES (1, 1, 'foo', 1, 1, 1)
+`)
+	require.NoError(t, err)
+
+	name, err := tx.GetNodeWithLeastInstances(nil, "", nil, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, "none", name)
+}
+
 // If there are 2 online nodes, and a container is pending on one of them,
 // return the address of the other one number of containers.
+// An evacuated node is not considered.
+func TestGetNodeWithLeastInstances_Evacuated(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	id, err := tx.CreateNode("buzz", "1.2.3.4:666")
+	require.NoError(t, err)
+
+	err = tx.UpdateNodeClusterState(id, db.ClusterMemberStateEvacuated)
+	require.NoError(t, err)
+
+	// Add a container to the default node (ID 1)
+	_, err = tx.Tx().Exec(`
+INSERT INTO instances (id, node_id, name, architecture, type, project_id) VALUES (1, 1, 'foo', 1, 1, 1)
+`)
+	require.NoError(t, err)
+
+	name, err := tx.GetNodeWithLeastInstances(nil, "", nil, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, "none", name)
+
+	node, err := tx.GetNodeByName("buzz")
+	require.NoError(t, err)
+	assert.Equal(t, db.ClusterMemberStateEvacuated, node.State)
+}
+
 func TestGetNodeWithLeastInstances_Pending(t *testing.T) {
 	tx, cleanup := db.NewTestClusterTx(t)
 	defer cleanup()
@@ -358,7 +429,7 @@ INSERT INTO operations (id, uuid, node_id, type, project_id) VALUES (1, 'abc', 1
 `, db.OperationContainerCreate)
 	require.NoError(t, err)
 
-	name, err := tx.GetNodeWithLeastInstances(nil)
+	name, err := tx.GetNodeWithLeastInstances(nil, "", nil, "", "")
 	require.NoError(t, err)
 	assert.Equal(t, "buzz", name)
 }
@@ -387,7 +458,80 @@ INSERT INTO instances (id, node_id, name, architecture, type, project_id) VALUES
 	require.NoError(t, err)
 
 	// The local node is returned despite it has more containers.
-	name, err := tx.GetNodeWithLeastInstances([]int{localArch})
+	name, err := tx.GetNodeWithLeastInstances([]int{localArch}, "", nil, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, "none", name)
+}
+
+// If a cluster group is selected or the allowed groups are restricted, return
+// only nodes of those groups.
+func TestGetNodeWithLeastInstances_ClusterGroup(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	id, err := tx.CreateNode("buzz", "1.2.3.4:666")
+	require.NoError(t, err)
+
+	_, err = tx.CreateClusterGroup("foo", "")
+	require.NoError(t, err)
+
+	err = tx.UpdateNodeClusterGroups(id, []string{"foo"})
+	require.NoError(t, err)
+
+	// Add a container to the newly created node.
+	_, err = tx.Tx().Exec(`
+INSERT INTO instances (id, node_id, name, architecture, type, project_id) VALUES (1, ?, 'foo', 1, 1, 1)
+`, id)
+	require.NoError(t, err)
+
+	name, err := tx.GetNodeWithLeastInstances(nil, "foo", nil, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, "buzz", name)
+
+	name, err = tx.GetNodeWithLeastInstances(nil, "", []string{"foo"}, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, "buzz", name)
+
+	// The selected group is not allowed.
+	name, err = tx.GetNodeWithLeastInstances(nil, "foo", []string{"default"}, "", "")
+	require.NoError(t, err)
+	assert.Equal(t, "", name)
+}
+
+// If an application is given, prefer nodes in failure domains with the fewest
+// instances of that application, even if they have more instances overall.
+func TestGetNodeWithLeastInstances_Application(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	id, err := tx.CreateNode("buzz", "1.2.3.4:666")
+	require.NoError(t, err)
+
+	assert.NoError(t, tx.UpdateNodeFailureDomain(id, "foo"))
+
+	// Add two containers to the default node (ID 1) and a replica of the
+	// application to the newly created node.
+	_, err = tx.Tx().Exec(`
+INSERT INTO instances (id, node_id, name, architecture, type, project_id) VALUES (1, 1, 'c1', 1, 1, 1);
+INSERT INTO instances (id, node_id, name, architecture, type, project_id) VALUES (2, 1, 'c2', 1, 1, 1);
+`)
+	require.NoError(t, err)
+
+	_, err = tx.Tx().Exec(`
+INSERT INTO instances (id, node_id, name, architecture, type, project_id) VALUES (3, ?, 'web1', 1, 1, 1)
+`, id)
+	require.NoError(t, err)
+
+	_, err = tx.Tx().Exec(`
+INSERT INTO instances_config (instance_id, key, value) VALUES (3, 'cluster.application', 'web')
+`)
+	require.NoError(t, err)
+
+	name, err := tx.GetNodeWithLeastInstances(nil, "", nil, "default", "")
+	require.NoError(t, err)
+	assert.Equal(t, "buzz", name)
+
+	name, err = tx.GetNodeWithLeastInstances(nil, "", nil, "default", "web")
 	require.NoError(t, err)
 	assert.Equal(t, "none", name)
 }
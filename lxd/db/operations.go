@@ -59,6 +59,11 @@ func (c *ClusterTx) GetOperationByUUID(uuid string) (Operation, error) {
 	}
 }
 
+// GetOperationsOfType returns all operations of the given type across the cluster.
+func (c *ClusterTx) GetOperationsOfType(opType OperationType) ([]Operation, error) {
+	return c.operations("type=?", opType)
+}
+
 // CreateOperation adds a new operations to the table.
 func (c *ClusterTx) CreateOperation(project, uuid string, typ OperationType) (int64, error) {
 	var projectID interface{}
@@ -69,3 +69,24 @@ func TestOperationNoProject(t *testing.T) {
 	_, err = tx.GetOperationByUUID("abcd")
 	assert.Equal(t, db.ErrNoSuchObject, err)
 }
+
+// Get the operations of a given type.
+func TestGetOperationsOfType(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	_, err := tx.CreateOperation("", "abcd", db.OperationClusterJoinToken)
+	require.NoError(t, err)
+
+	_, err = tx.CreateOperation("default", "efgh", db.OperationContainerCreate)
+	require.NoError(t, err)
+
+	operations, err := tx.GetOperationsOfType(db.OperationClusterJoinToken)
+	require.NoError(t, err)
+	require.Len(t, operations, 1)
+	assert.Equal(t, "abcd", operations[0].UUID)
+
+	operations, err = tx.GetOperationsOfType(db.OperationCertificateAddToken)
+	require.NoError(t, err)
+	assert.Len(t, operations, 0)
+}
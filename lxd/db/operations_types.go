@@ -56,6 +56,18 @@ const (
 	OperationBackupsExpire
 	OperationSnapshotsExpire
 	OperationCustomVolumeSnapshotsExpire
+	OperationWarningsPruneResolved
+	OperationClusterMemberEvacuate
+	OperationClusterMemberRestore
+	OperationCustomVolumeBackupCreate
+	OperationCustomVolumeBackupRemove
+	OperationCustomVolumeBackupRename
+	OperationCustomVolumeBackupRestore
+	OperationStoragePoolMigrate
+	OperationImagesGenerateDeltas
+	OperationClusterJoinToken
+	OperationCertificateAddToken
+	OperationClusterMemberHeal
 )
 
 // Description return a human-readable description of the operation type.
@@ -153,6 +165,30 @@ func (t OperationType) Description() string {
 		return "Cleaning up expired instance snapshots"
 	case OperationCustomVolumeSnapshotsExpire:
 		return "Cleaning up expired volume snapshots"
+	case OperationWarningsPruneResolved:
+		return "Pruning resolved warnings"
+	case OperationClusterMemberEvacuate:
+		return "Evacuating cluster member"
+	case OperationClusterMemberRestore:
+		return "Restoring cluster member"
+	case OperationCustomVolumeBackupCreate:
+		return "Creating custom volume backup"
+	case OperationCustomVolumeBackupRemove:
+		return "Deleting custom volume backup"
+	case OperationCustomVolumeBackupRename:
+		return "Renaming custom volume backup"
+	case OperationCustomVolumeBackupRestore:
+		return "Restoring custom volume backup"
+	case OperationStoragePoolMigrate:
+		return "Migrating storage pool"
+	case OperationImagesGenerateDeltas:
+		return "Generating image deltas"
+	case OperationClusterJoinToken:
+		return "Executing join token"
+	case OperationCertificateAddToken:
+		return "Executing certificate add token"
+	case OperationClusterMemberHeal:
+		return "Healing cluster member"
 	default:
 		return "Executing operation"
 	}
@@ -221,9 +257,19 @@ func (t OperationType) Permission() string {
 		return "manage-images"
 	case OperationImagesSynchronize:
 		return "manage-images"
+	case OperationImagesGenerateDeltas:
+		return "manage-images"
 
 	case OperationCustomVolumeSnapshotsExpire:
 		return "operate-volumes"
+	case OperationCustomVolumeBackupCreate:
+		return "manage-storage-volumes"
+	case OperationCustomVolumeBackupRemove:
+		return "manage-storage-volumes"
+	case OperationCustomVolumeBackupRename:
+		return "manage-storage-volumes"
+	case OperationCustomVolumeBackupRestore:
+		return "manage-storage-volumes"
 	}
 
 	return ""
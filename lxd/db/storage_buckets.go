@@ -0,0 +1,332 @@
+// +build linux,cgo,!agent
+
+package db
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+// StorageBucketAccess represents the bucket and role an access key grants access to.
+type StorageBucketAccess struct {
+	PoolName    string
+	ProjectName string
+	BucketID    int64
+	BucketName  string
+	Config      map[string]string
+	Role        string
+	SecretKey   string
+}
+
+// GetStoragePoolBucketNames returns the names of the buckets of a project on the given storage pool
+// on this member.
+func (c *Cluster) GetStoragePoolBucketNames(projectName string, poolID int64) ([]string, error) {
+	q := `
+		SELECT storage_buckets.name FROM storage_buckets
+		JOIN projects ON projects.id = storage_buckets.project_id
+		WHERE projects.name = ? AND storage_buckets.storage_pool_id = ? AND storage_buckets.node_id = ?
+		ORDER BY storage_buckets.id
+	`
+	inargs := []interface{}{projectName, poolID, c.nodeID}
+
+	var name string
+	outfmt := []interface{}{name}
+	result, err := queryScan(c, q, inargs, outfmt)
+	if err != nil {
+		return nil, err
+	}
+
+	response := []string{}
+	for _, r := range result {
+		response = append(response, r[0].(string))
+	}
+
+	return response, nil
+}
+
+// GetStoragePoolBucket returns the bucket of a project with the given name on the given storage pool
+// on this member.
+func (c *Cluster) GetStoragePoolBucket(projectName string, poolID int64, name string) (int64, *api.StorageBucket, error) {
+	id := int64(-1)
+
+	bucket := api.StorageBucket{
+		Name: name,
+	}
+
+	q := `
+		SELECT storage_buckets.id, storage_buckets.description
+		FROM storage_buckets
+		JOIN projects ON projects.id = storage_buckets.project_id
+		WHERE projects.name = ? AND storage_buckets.storage_pool_id = ? AND storage_buckets.node_id = ? AND storage_buckets.name = ?
+		LIMIT 1
+	`
+	arg1 := []interface{}{projectName, poolID, c.nodeID, name}
+	arg2 := []interface{}{&id, &bucket.Description}
+
+	err := dbQueryRowScan(c, q, arg1, arg2)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return -1, nil, ErrNoSuchObject
+		}
+
+		return -1, nil, err
+	}
+
+	bucket.Config, err = c.storageBucketConfig(id)
+	if err != nil {
+		return -1, nil, errors.Wrapf(err, "Failed loading config")
+	}
+
+	return id, &bucket, nil
+}
+
+// storageBucketConfig returns the config map of the storage bucket with the given ID.
+func (c *Cluster) storageBucketConfig(id int64) (map[string]string, error) {
+	var key, value string
+	q := `
+		SELECT key, value
+		FROM storage_buckets_config
+		WHERE storage_bucket_id=?
+	`
+	inargs := []interface{}{id}
+	outfmt := []interface{}{key, value}
+	results, err := queryScan(c, q, inargs, outfmt)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get storage bucket config for ID %d", id)
+	}
+
+	config := make(map[string]string, len(results))
+
+	for _, r := range results {
+		key = r[0].(string)
+		value = r[1].(string)
+
+		config[key] = value
+	}
+
+	return config, nil
+}
+
+// CreateStoragePoolBucket creates a new bucket of a project on the given storage pool on this member.
+func (c *Cluster) CreateStoragePoolBucket(projectName string, poolID int64, info *api.StorageBucketsPost) (int64, error) {
+	var id int64
+
+	err := c.Transaction(func(tx *ClusterTx) error {
+		result, err := tx.tx.Exec(`
+			INSERT INTO storage_buckets (storage_pool_id, node_id, name, description, project_id)
+			VALUES (?, ?, ?, ?, (SELECT id FROM projects WHERE name = ?))
+		`, poolID, c.nodeID, info.Name, info.Description, projectName)
+		if err != nil {
+			return err
+		}
+
+		id, err = result.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		err = storageBucketConfigAdd(tx.tx, id, info.Config)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		id = -1
+	}
+
+	return id, err
+}
+
+// UpdateStoragePoolBucket updates the storage bucket with the given ID.
+func (c *Cluster) UpdateStoragePoolBucket(id int64, info *api.StorageBucketPut) error {
+	return c.Transaction(func(tx *ClusterTx) error {
+		_, err := tx.tx.Exec(`
+			UPDATE storage_buckets
+			SET description = ?
+			WHERE id = ?
+		`, info.Description, id)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.tx.Exec("DELETE FROM storage_buckets_config WHERE storage_bucket_id=?", id)
+		if err != nil {
+			return err
+		}
+
+		err = storageBucketConfigAdd(tx.tx, id, info.Config)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+}
+
+// DeleteStoragePoolBucket deletes the storage bucket with the given ID, along with its keys.
+func (c *Cluster) DeleteStoragePoolBucket(id int64) error {
+	return c.Transaction(func(tx *ClusterTx) error {
+		_, err := tx.tx.Exec("DELETE FROM storage_buckets WHERE id=?", id)
+		return err
+	})
+}
+
+// storageBucketConfigAdd inserts storage bucket config keys.
+func storageBucketConfigAdd(tx *sql.Tx, id int64, config map[string]string) error {
+	stmt, err := tx.Prepare("INSERT INTO storage_buckets_config (storage_bucket_id, key, value) VALUES(?, ?, ?)")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for k, v := range config {
+		if v == "" {
+			continue
+		}
+
+		_, err = stmt.Exec(id, k, v)
+		if err != nil {
+			return errors.Wrapf(err, "Failed inserting config")
+		}
+	}
+
+	return nil
+}
+
+// GetStoragePoolBucketKeyNames returns the names of the keys of the storage bucket with the given ID.
+func (c *Cluster) GetStoragePoolBucketKeyNames(bucketID int64) ([]string, error) {
+	q := `SELECT name FROM storage_buckets_keys
+		WHERE storage_bucket_id = ?
+		ORDER BY id
+	`
+	inargs := []interface{}{bucketID}
+
+	var name string
+	outfmt := []interface{}{name}
+	result, err := queryScan(c, q, inargs, outfmt)
+	if err != nil {
+		return nil, err
+	}
+
+	response := []string{}
+	for _, r := range result {
+		response = append(response, r[0].(string))
+	}
+
+	return response, nil
+}
+
+// GetStoragePoolBucketKey returns the key with the given name of the storage bucket with the given ID.
+func (c *Cluster) GetStoragePoolBucketKey(bucketID int64, name string) (int64, *api.StorageBucketKey, error) {
+	id := int64(-1)
+
+	key := api.StorageBucketKey{
+		Name: name,
+	}
+
+	q := `
+		SELECT id, description, role, access_key, secret_key
+		FROM storage_buckets_keys
+		WHERE storage_bucket_id = ? AND name = ?
+		LIMIT 1
+	`
+	arg1 := []interface{}{bucketID, name}
+	arg2 := []interface{}{&id, &key.Description, &key.Role, &key.AccessKey, &key.SecretKey}
+
+	err := dbQueryRowScan(c, q, arg1, arg2)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return -1, nil, ErrNoSuchObject
+		}
+
+		return -1, nil, err
+	}
+
+	return id, &key, nil
+}
+
+// CreateStoragePoolBucketKey creates a new key for the storage bucket with the given ID.
+func (c *Cluster) CreateStoragePoolBucketKey(bucketID int64, info *api.StorageBucketKeysPost) (int64, error) {
+	var id int64
+
+	err := c.Transaction(func(tx *ClusterTx) error {
+		result, err := tx.tx.Exec(`
+			INSERT INTO storage_buckets_keys (storage_bucket_id, name, description, role, access_key, secret_key)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`, bucketID, info.Name, info.Description, info.Role, info.AccessKey, info.SecretKey)
+		if err != nil {
+			return err
+		}
+
+		id, err = result.LastInsertId()
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		id = -1
+	}
+
+	return id, err
+}
+
+// UpdateStoragePoolBucketKey updates the storage bucket key with the given ID.
+func (c *Cluster) UpdateStoragePoolBucketKey(id int64, info *api.StorageBucketKeyPut) error {
+	return c.Transaction(func(tx *ClusterTx) error {
+		_, err := tx.tx.Exec(`
+			UPDATE storage_buckets_keys
+			SET description = ?, role = ?, access_key = ?, secret_key = ?
+			WHERE id = ?
+		`, info.Description, info.Role, info.AccessKey, info.SecretKey, id)
+		return err
+	})
+}
+
+// DeleteStoragePoolBucketKey deletes the storage bucket key with the given ID.
+func (c *Cluster) DeleteStoragePoolBucketKey(id int64) error {
+	return c.Transaction(func(tx *ClusterTx) error {
+		_, err := tx.tx.Exec("DELETE FROM storage_buckets_keys WHERE id=?", id)
+		return err
+	})
+}
+
+// GetStoragePoolBucketAccess returns the bucket on this member that the given access key grants access to.
+func (c *Cluster) GetStoragePoolBucketAccess(accessKey string) (*StorageBucketAccess, error) {
+	access := StorageBucketAccess{}
+
+	q := `
+		SELECT storage_pools.name, projects.name, storage_buckets.id, storage_buckets.name, storage_buckets_keys.role, storage_buckets_keys.secret_key
+		FROM storage_buckets_keys
+		JOIN storage_buckets ON storage_buckets.id = storage_buckets_keys.storage_bucket_id
+		JOIN storage_pools ON storage_pools.id = storage_buckets.storage_pool_id
+		JOIN projects ON projects.id = storage_buckets.project_id
+		WHERE storage_buckets_keys.access_key = ? AND storage_buckets.node_id = ?
+		LIMIT 1
+	`
+	arg1 := []interface{}{accessKey, c.nodeID}
+	arg2 := []interface{}{&access.PoolName, &access.ProjectName, &access.BucketID, &access.BucketName, &access.Role, &access.SecretKey}
+
+	err := dbQueryRowScan(c, q, arg1, arg2)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNoSuchObject
+		}
+
+		return nil, err
+	}
+
+	access.Config, err = c.storageBucketConfig(access.BucketID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed loading config")
+	}
+
+	return &access, nil
+}
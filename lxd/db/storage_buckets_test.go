@@ -0,0 +1,95 @@
+// +build linux,cgo,!agent
+
+package db_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/project"
+	"github.com/lxc/lxd/shared/api"
+)
+
+func TestStorageBuckets(t *testing.T) {
+	cluster, cleanup := db.NewTestCluster(t)
+	defer cleanup()
+
+	poolID, err := cluster.CreateStoragePool("default", "", "dir", nil)
+	require.NoError(t, err)
+
+	req := &api.StorageBucketsPost{}
+	req.Name = "b1"
+	req.Description = "Data"
+	req.Config = map[string]string{"size": "1GiB"}
+
+	id, err := cluster.CreateStoragePoolBucket(project.Default, poolID, req)
+	require.NoError(t, err)
+	assert.True(t, id > 0)
+
+	names, err := cluster.GetStoragePoolBucketNames(project.Default, poolID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b1"}, names)
+
+	_, bucket, err := cluster.GetStoragePoolBucket(project.Default, poolID, "b1")
+	require.NoError(t, err)
+	assert.Equal(t, "Data", bucket.Description)
+	assert.Equal(t, map[string]string{"size": "1GiB"}, bucket.Config)
+
+	put := bucket.Writable()
+	put.Config = map[string]string{"user.foo": "bar"}
+	err = cluster.UpdateStoragePoolBucket(id, &put)
+	require.NoError(t, err)
+
+	_, bucket, err = cluster.GetStoragePoolBucket(project.Default, poolID, "b1")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"user.foo": "bar"}, bucket.Config)
+
+	keyReq := &api.StorageBucketKeysPost{}
+	keyReq.Name = "reader"
+	keyReq.Role = "read-only"
+	keyReq.AccessKey = "access1"
+	keyReq.SecretKey = "secret1"
+
+	keyID, err := cluster.CreateStoragePoolBucketKey(id, keyReq)
+	require.NoError(t, err)
+
+	keyNames, err := cluster.GetStoragePoolBucketKeyNames(id)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"reader"}, keyNames)
+
+	access, err := cluster.GetStoragePoolBucketAccess("access1")
+	require.NoError(t, err)
+	assert.Equal(t, "default", access.PoolName)
+	assert.Equal(t, project.Default, access.ProjectName)
+	assert.Equal(t, "b1", access.BucketName)
+	assert.Equal(t, "read-only", access.Role)
+	assert.Equal(t, "secret1", access.SecretKey)
+	assert.Equal(t, map[string]string{"user.foo": "bar"}, access.Config)
+
+	_, key, err := cluster.GetStoragePoolBucketKey(id, "reader")
+	require.NoError(t, err)
+
+	keyPut := key.Writable()
+	keyPut.Role = "admin"
+	err = cluster.UpdateStoragePoolBucketKey(keyID, &keyPut)
+	require.NoError(t, err)
+
+	access, err = cluster.GetStoragePoolBucketAccess("access1")
+	require.NoError(t, err)
+	assert.Equal(t, "admin", access.Role)
+
+	err = cluster.DeleteStoragePoolBucketKey(keyID)
+	require.NoError(t, err)
+
+	_, err = cluster.GetStoragePoolBucketAccess("access1")
+	assert.Equal(t, db.ErrNoSuchObject, err)
+
+	err = cluster.DeleteStoragePoolBucket(id)
+	require.NoError(t, err)
+
+	_, _, err = cluster.GetStoragePoolBucket(project.Default, poolID, "b1")
+	assert.Equal(t, db.ErrNoSuchObject, err)
+}
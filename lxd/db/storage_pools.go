@@ -840,6 +840,20 @@ func (c *Cluster) UpdateStoragePool(poolName, description string, poolConfig map
 	return err
 }
 
+// UpdateStoragePoolDevices points all disk devices of instances, instance snapshots and profiles which use the
+// given storage pool to another storage pool.
+func (c *ClusterTx) UpdateStoragePoolDevices(oldPoolName string, newPoolName string) error {
+	for _, table := range []string{"instances_devices_config", "instances_snapshots_devices_config", "profiles_devices_config"} {
+		stmt := fmt.Sprintf("UPDATE %s SET value=? WHERE key='pool' AND value=?", table)
+		_, err := c.tx.Exec(stmt, newPoolName, oldPoolName)
+		if err != nil {
+			return errors.Wrapf(err, "Failed updating %q", table)
+		}
+	}
+
+	return nil
+}
+
 // Uupdate the storage pool description.
 func updateStoragePoolDescription(tx *sql.Tx, id int64, description string) error {
 	_, err := tx.Exec("UPDATE storage_pools SET description=? WHERE id=?", description, id)
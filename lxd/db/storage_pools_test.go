@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -240,3 +241,45 @@ func TestCreateStoragePoolVolume_Snapshot(t *testing.T) {
 	n = cluster.GetNextStorageVolumeSnapshotIndex("p2", "v1", 1, "snap%d")
 	assert.Equal(t, n, 0)
 }
+
+func TestUpdateStoragePoolDevices(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	profile := db.Profile{
+		Project: "default",
+		Name:    "profile1",
+		Devices: map[string]map[string]string{"root": {"type": "disk", "path": "/", "pool": "p1"}},
+	}
+
+	_, err := tx.CreateProfile(profile)
+	require.NoError(t, err)
+
+	container := db.Instance{
+		Project:      "default",
+		Name:         "c1",
+		Node:         "none",
+		Type:         instancetype.Container,
+		Architecture: 1,
+		Devices: map[string]map[string]string{
+			"data":  {"type": "disk", "path": "/data", "pool": "p1", "source": "data"},
+			"other": {"type": "disk", "path": "/other", "pool": "p2", "source": "other"},
+		},
+		Profiles: []string{"profile1"},
+	}
+
+	_, err = tx.CreateInstance(container)
+	require.NoError(t, err)
+
+	err = tx.UpdateStoragePoolDevices("p1", "p3")
+	require.NoError(t, err)
+
+	p, err := tx.GetProfile("default", "profile1")
+	require.NoError(t, err)
+	assert.Equal(t, "p3", p.Devices["root"]["pool"])
+
+	c, err := tx.GetInstance("default", "c1")
+	require.NoError(t, err)
+	assert.Equal(t, "p3", c.Devices["data"]["pool"])
+	assert.Equal(t, "p2", c.Devices["other"]["pool"])
+}
@@ -0,0 +1,496 @@
+// +build linux,cgo,!agent
+
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pborman/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/db/query"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/version"
+)
+
+// WarningType is a numeric code identifying the type of a warning.
+type WarningType int
+
+// Warning types. New types must be appended at the end, as the codes are stored in the database.
+const (
+	WarningTypeUndefined WarningType = iota
+	WarningTypeAppArmorNotAvailable
+	WarningTypeMissingSeccompListener
+	WarningTypeMissingUeventInjection
+	WarningTypeFirewallNotCompatible
+	WarningTypeImageAutoUpdateFailed
+	WarningTypeHostResourcesUnavailable
+	WarningTypeScheduledBackupFailed
+)
+
+// WarningTypeNames associates a warning type code to its name.
+var WarningTypeNames = map[WarningType]string{
+	WarningTypeUndefined:                "Undefined warning",
+	WarningTypeAppArmorNotAvailable:     "AppArmor support unavailable",
+	WarningTypeMissingSeccompListener:   "Missing seccomp listener support",
+	WarningTypeMissingUeventInjection:   "Missing uevent injection support",
+	WarningTypeFirewallNotCompatible:    "No compatible firewall driver",
+	WarningTypeImageAutoUpdateFailed:    "Failed to auto-update image",
+	WarningTypeHostResourcesUnavailable: "Failed to retrieve host resources",
+	WarningTypeScheduledBackupFailed:    "Failed to create scheduled backup",
+}
+
+// WarningSeverity is the severity of a warning.
+type WarningSeverity int
+
+// Warning severities.
+const (
+	WarningSeverityLow WarningSeverity = iota + 1
+	WarningSeverityModerate
+	WarningSeverityHigh
+)
+
+// WarningSeverityNames associates a warning severity to its name.
+var WarningSeverityNames = map[WarningSeverity]string{
+	WarningSeverityLow:      "low",
+	WarningSeverityModerate: "moderate",
+	WarningSeverityHigh:     "high",
+}
+
+// WarningSeverities associates a warning type code to its severity.
+var WarningSeverities = map[WarningType]WarningSeverity{
+	WarningTypeUndefined:                WarningSeverityLow,
+	WarningTypeAppArmorNotAvailable:     WarningSeverityLow,
+	WarningTypeMissingSeccompListener:   WarningSeverityLow,
+	WarningTypeMissingUeventInjection:   WarningSeverityLow,
+	WarningTypeFirewallNotCompatible:    WarningSeverityHigh,
+	WarningTypeImageAutoUpdateFailed:    WarningSeverityModerate,
+	WarningTypeHostResourcesUnavailable: WarningSeverityModerate,
+	WarningTypeScheduledBackupFailed:    WarningSeverityModerate,
+}
+
+// WarningStatus is the status of a warning.
+type WarningStatus int
+
+// Warning statuses.
+const (
+	WarningStatusNew WarningStatus = iota + 1
+	WarningStatusAcknowledged
+	WarningStatusResolved
+)
+
+// WarningStatuses associates a warning status to its API name.
+var WarningStatuses = map[WarningStatus]string{
+	WarningStatusNew:          api.WarningStatusNew,
+	WarningStatusAcknowledged: api.WarningStatusAcknowledged,
+	WarningStatusResolved:     api.WarningStatusResolved,
+}
+
+// WarningStatusFromAPI returns the status matching the given API status name.
+func WarningStatusFromAPI(status string) (WarningStatus, error) {
+	for code, name := range WarningStatuses {
+		if name == status {
+			return code, nil
+		}
+	}
+
+	return -1, fmt.Errorf("Unknown warning status %q", status)
+}
+
+// Entity types that warnings can refer to.
+const (
+	EntityTypeNone = iota - 1
+	_
+	EntityTypeImage
+	EntityTypeInstance
+	EntityTypeNetwork
+	EntityTypeProfile
+	EntityTypeStoragePool
+	EntityTypeStorageVolume
+)
+
+// entityURLs holds, for each entity type, the query returning the name and project of an entity, as well as
+// the API collection the entity belongs to.
+var entityURLs = map[int]struct {
+	query      string
+	collection string
+}{
+	EntityTypeImage:         {"SELECT images.fingerprint, projects.name FROM images JOIN projects ON projects.id = images.project_id WHERE images.id = ?", "images"},
+	EntityTypeInstance:      {"SELECT instances.name, projects.name FROM instances JOIN projects ON projects.id = instances.project_id WHERE instances.id = ?", "instances"},
+	EntityTypeNetwork:       {"SELECT networks.name, '' FROM networks WHERE networks.id = ?", "networks"},
+	EntityTypeProfile:       {"SELECT profiles.name, projects.name FROM profiles JOIN projects ON projects.id = profiles.project_id WHERE profiles.id = ?", "profiles"},
+	EntityTypeStoragePool:   {"SELECT storage_pools.name, '' FROM storage_pools WHERE storage_pools.id = ?", "storage-pools"},
+	EntityTypeStorageVolume: {"SELECT storage_pools.name || '/volumes/custom/' || storage_volumes.name, projects.name FROM storage_volumes JOIN storage_pools ON storage_pools.id = storage_volumes.storage_pool_id JOIN projects ON projects.id = storage_volumes.project_id WHERE storage_volumes.id = ?", "storage-pools"},
+}
+
+// Warning is a value object holding db-related details about a warning.
+type Warning struct {
+	ID             int
+	Node           string
+	Project        string
+	EntityTypeCode int
+	EntityID       int
+	UUID           string
+	TypeCode       WarningType
+	Status         WarningStatus
+	FirstSeenDate  time.Time
+	LastSeenDate   time.Time
+	UpdatedDate    time.Time
+	LastMessage    string
+	Count          int
+}
+
+// ToAPI returns the API representation of the warning, using the given entity URL.
+func (w Warning) ToAPI(entityURL string) api.Warning {
+	return api.Warning{
+		WarningPut: api.WarningPut{
+			Status: WarningStatuses[w.Status],
+		},
+		UUID:        w.UUID,
+		Location:    w.Node,
+		Project:     w.Project,
+		Type:        WarningTypeNames[w.TypeCode],
+		Count:       w.Count,
+		FirstSeenAt: w.FirstSeenDate,
+		LastSeenAt:  w.LastSeenDate,
+		LastMessage: w.LastMessage,
+		Severity:    WarningSeverityNames[WarningSeverities[w.TypeCode]],
+		EntityURL:   entityURL,
+	}
+}
+
+// WarningFilter can be used to filter results yielded by GetWarnings.
+// Zero values match any warning.
+type WarningFilter struct {
+	UUID     string
+	Project  string
+	Node     string
+	TypeCode WarningType
+	Status   WarningStatus
+}
+
+// GetWarnings returns all the warnings matching the given filter.
+func (c *ClusterTx) GetWarnings(filter WarningFilter) ([]Warning, error) {
+	where := []string{}
+	args := []interface{}{}
+
+	if filter.UUID != "" {
+		where = append(where, "warnings.uuid = ?")
+		args = append(args, filter.UUID)
+	}
+
+	if filter.Project != "" {
+		where = append(where, "projects.name = ?")
+		args = append(args, filter.Project)
+	}
+
+	if filter.Node != "" {
+		where = append(where, "nodes.name = ?")
+		args = append(args, filter.Node)
+	}
+
+	if filter.TypeCode != WarningTypeUndefined {
+		where = append(where, "warnings.type_code = ?")
+		args = append(args, filter.TypeCode)
+	}
+
+	if filter.Status != 0 {
+		where = append(where, "warnings.status = ?")
+		args = append(args, filter.Status)
+	}
+
+	q := `
+SELECT warnings.id, coalesce(nodes.name, ''), coalesce(projects.name, ''), coalesce(warnings.entity_type_code, -1),
+       coalesce(warnings.entity_id, -1), warnings.uuid, warnings.type_code, warnings.status,
+       warnings.first_seen_date, warnings.last_seen_date, warnings.updated_date, warnings.last_message, warnings.count
+  FROM warnings
+  LEFT JOIN nodes ON nodes.id = warnings.node_id
+  LEFT JOIN projects ON projects.id = warnings.project_id
+`
+	if len(where) > 0 {
+		q += fmt.Sprintf(" WHERE %s", strings.Join(where, " AND "))
+	}
+
+	q += " ORDER BY warnings.id"
+
+	stmt, err := c.tx.Prepare(q)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	warnings := []Warning{}
+	updatedDates := []sql.NullTime{}
+	dest := func(i int) []interface{} {
+		warnings = append(warnings, Warning{})
+		updatedDates = append(updatedDates, sql.NullTime{})
+		return []interface{}{
+			&warnings[i].ID,
+			&warnings[i].Node,
+			&warnings[i].Project,
+			&warnings[i].EntityTypeCode,
+			&warnings[i].EntityID,
+			&warnings[i].UUID,
+			&warnings[i].TypeCode,
+			&warnings[i].Status,
+			&warnings[i].FirstSeenDate,
+			&warnings[i].LastSeenDate,
+			&updatedDates[i],
+			&warnings[i].LastMessage,
+			&warnings[i].Count,
+		}
+	}
+
+	err = query.SelectObjects(stmt, dest, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to fetch warnings")
+	}
+
+	for i := range warnings {
+		if updatedDates[i].Valid {
+			warnings[i].UpdatedDate = updatedDates[i].Time
+		}
+	}
+
+	return warnings, nil
+}
+
+// GetWarning returns the warning with the given UUID.
+func (c *ClusterTx) GetWarning(UUID string) (*Warning, error) {
+	warnings, err := c.GetWarnings(WarningFilter{UUID: UUID})
+	if err != nil {
+		return nil, err
+	}
+
+	switch len(warnings) {
+	case 0:
+		return nil, ErrNoSuchObject
+	case 1:
+		return &warnings[0], nil
+	default:
+		return nil, fmt.Errorf("More than one warning matches")
+	}
+}
+
+// GetWarningEntityURL returns the API URL of the entity a warning refers to.
+// Returns an empty string if the warning doesn't refer to an entity.
+func (c *ClusterTx) GetWarningEntityURL(entityTypeCode int, entityID int) (string, error) {
+	entity, ok := entityURLs[entityTypeCode]
+	if !ok {
+		return "", nil
+	}
+
+	var name, projectName string
+	err := c.tx.QueryRow(entity.query, entityID).Scan(&name, &projectName)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			// The entity doesn't exist anymore.
+			return "", nil
+		}
+
+		return "", errors.Wrap(err, "Failed to get entity name")
+	}
+
+	// Escape each path segment, as the name of some entities includes the path below their parent entity.
+	segments := strings.Split(name, "/")
+	for i := range segments {
+		segments[i] = url.PathEscape(segments[i])
+	}
+
+	entityURL := fmt.Sprintf("/%s/%s/%s", version.APIVersion, entity.collection, strings.Join(segments, "/"))
+	if projectName != "" && projectName != "default" {
+		entityURL += fmt.Sprintf("?project=%s", url.QueryEscape(projectName))
+	}
+
+	return entityURL, nil
+}
+
+// UpsertWarningLocalNode records a warning for the local node. If a warning of the same type already exists for
+// the same project and entity, its message, last seen date and occurrence count are updated and a resolved
+// warning becomes new again. Otherwise a new warning is created.
+// The project name can be empty for warnings which aren't project specific and the entity type code can be
+// EntityTypeNone for warnings which don't refer to a specific entity.
+func (c *ClusterTx) UpsertWarningLocalNode(projectName string, entityTypeCode int, entityID int, typeCode WarningType, message string) error {
+	var projectID interface{}
+	if projectName != "" {
+		id, err := c.GetProjectID(projectName)
+		if err != nil {
+			return errors.Wrap(err, "Failed to get project ID")
+		}
+
+		projectID = id
+	}
+
+	var entityType, entity interface{}
+	if entityTypeCode != EntityTypeNone {
+		entityType = entityTypeCode
+		entity = entityID
+	}
+
+	now := time.Now().UTC()
+
+	q := `
+SELECT id, status FROM warnings
+ WHERE node_id = ? AND IFNULL(project_id, -1) = IFNULL(?, -1) AND IFNULL(entity_type_code, -1) = IFNULL(?, -1)
+   AND IFNULL(entity_id, -1) = IFNULL(?, -1) AND type_code = ?
+`
+	var id int64
+	var status WarningStatus
+	err := c.tx.QueryRow(q, c.nodeID, projectID, entityType, entity, typeCode).Scan(&id, &status)
+	if err != nil && err != sql.ErrNoRows {
+		return errors.Wrap(err, "Failed to get existing warning")
+	}
+
+	if err == sql.ErrNoRows {
+		q = `
+INSERT INTO warnings (node_id, project_id, entity_type_code, entity_id, uuid, type_code, status, first_seen_date, last_seen_date, last_message, count)
+  VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, 1)
+`
+		_, err = c.tx.Exec(q, c.nodeID, projectID, entityType, entity, uuid.NewRandom().String(), typeCode, WarningStatusNew, now, now, message)
+		if err != nil {
+			return errors.Wrap(err, "Failed to create warning")
+		}
+
+		return nil
+	}
+
+	if status == WarningStatusResolved {
+		// The problem came back.
+		_, err = c.tx.Exec("UPDATE warnings SET status = ?, updated_date = ? WHERE id = ?", WarningStatusNew, now, id)
+		if err != nil {
+			return errors.Wrap(err, "Failed to update warning status")
+		}
+	}
+
+	_, err = c.tx.Exec("UPDATE warnings SET last_message = ?, last_seen_date = ?, count = count + 1 WHERE id = ?", message, now, id)
+	if err != nil {
+		return errors.Wrap(err, "Failed to update warning")
+	}
+
+	return nil
+}
+
+// UpdateWarningStatus updates the status of the warning with the given UUID.
+func (c *ClusterTx) UpdateWarningStatus(UUID string, status WarningStatus) error {
+	result, err := c.tx.Exec("UPDATE warnings SET status = ?, updated_date = ? WHERE uuid = ?", status, time.Now().UTC(), UUID)
+	if err != nil {
+		return errors.Wrap(err, "Failed to update warning status")
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "Fetch affected rows")
+	}
+
+	if n != 1 {
+		return ErrNoSuchObject
+	}
+
+	return nil
+}
+
+// DeleteWarning deletes the warning with the given UUID.
+func (c *ClusterTx) DeleteWarning(UUID string) error {
+	result, err := c.tx.Exec("DELETE FROM warnings WHERE uuid = ?", UUID)
+	if err != nil {
+		return errors.Wrap(err, "Failed to delete warning")
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "Fetch affected rows")
+	}
+
+	if n != 1 {
+		return ErrNoSuchObject
+	}
+
+	return nil
+}
+
+// ResolveWarningsByLocalNodeAndType resolves the local node's warnings of the given type.
+func (c *ClusterTx) ResolveWarningsByLocalNodeAndType(typeCode WarningType) error {
+	return c.resolveWarnings("node_id = ? AND type_code = ?", c.nodeID, typeCode)
+}
+
+// ResolveWarningsByLocalNodeAndEntity resolves the local node's warnings of the given type which refer to the
+// given entity.
+func (c *ClusterTx) ResolveWarningsByLocalNodeAndEntity(typeCode WarningType, entityTypeCode int, entityID int) error {
+	return c.resolveWarnings("node_id = ? AND type_code = ? AND entity_type_code = ? AND entity_id = ?", c.nodeID, typeCode, entityTypeCode, entityID)
+}
+
+// ResolveWarningsByLocalNodeOlderThan resolves the local node's warnings of the given types which were last
+// seen before the given date.
+func (c *ClusterTx) ResolveWarningsByLocalNodeOlderThan(date time.Time, typeCodes ...WarningType) error {
+	if len(typeCodes) == 0 {
+		return nil
+	}
+
+	args := []interface{}{c.nodeID, date}
+	for _, typeCode := range typeCodes {
+		args = append(args, typeCode)
+	}
+
+	return c.resolveWarnings(fmt.Sprintf("node_id = ? AND last_seen_date < ? AND type_code IN %s", query.Params(len(typeCodes))), args...)
+}
+
+// resolveWarnings marks the unresolved warnings matching the given condition as resolved.
+// As this is called frequently from code paths which succeed most of the time, the warnings are looked up first
+// in order to avoid needless writes.
+func (c *ClusterTx) resolveWarnings(where string, args ...interface{}) error {
+	args = append(args, WarningStatusResolved)
+	where += " AND status != ?"
+
+	ids, err := query.SelectIntegers(c.tx, fmt.Sprintf("SELECT id FROM warnings WHERE %s", where), args...)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get warnings")
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	args = append([]interface{}{WarningStatusResolved, time.Now().UTC()}, args...)
+	_, err = c.tx.Exec(fmt.Sprintf("UPDATE warnings SET status = ?, updated_date = ? WHERE %s", where), args...)
+	if err != nil {
+		return errors.Wrap(err, "Failed to resolve warnings")
+	}
+
+	return nil
+}
+
+// DeleteResolvedWarningsOlderThan deletes the warnings which were resolved before the given date.
+func (c *ClusterTx) DeleteResolvedWarningsOlderThan(date time.Time) error {
+	_, err := c.tx.Exec("DELETE FROM warnings WHERE status = ? AND updated_date < ?", WarningStatusResolved, date)
+	if err != nil {
+		return errors.Wrap(err, "Failed to delete resolved warnings")
+	}
+
+	return nil
+}
+
+// UpsertWarningLocalNode records a warning for the local node, see ClusterTx.UpsertWarningLocalNode.
+func (c *Cluster) UpsertWarningLocalNode(projectName string, entityTypeCode int, entityID int, typeCode WarningType, message string) error {
+	return c.Transaction(func(tx *ClusterTx) error {
+		return tx.UpsertWarningLocalNode(projectName, entityTypeCode, entityID, typeCode, message)
+	})
+}
+
+// ResolveWarningsByLocalNodeAndType resolves the local node's warnings of the given type.
+func (c *Cluster) ResolveWarningsByLocalNodeAndType(typeCode WarningType) error {
+	return c.Transaction(func(tx *ClusterTx) error {
+		return tx.ResolveWarningsByLocalNodeAndType(typeCode)
+	})
+}
+
+// ResolveWarningsByLocalNodeAndEntity resolves the local node's warnings of the given type which refer to the
+// given entity.
+func (c *Cluster) ResolveWarningsByLocalNodeAndEntity(typeCode WarningType, entityTypeCode int, entityID int) error {
+	return c.Transaction(func(tx *ClusterTx) error {
+		return tx.ResolveWarningsByLocalNodeAndEntity(typeCode, entityTypeCode, entityID)
+	})
+}
@@ -0,0 +1,116 @@
+// +build linux,cgo,!agent
+
+package db_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/project"
+	"github.com/lxc/lxd/shared/api"
+)
+
+func TestWarnings(t *testing.T) {
+	cluster, cleanup := db.NewTestCluster(t)
+	defer cleanup()
+
+	err := cluster.UpsertWarningLocalNode("", db.EntityTypeNone, 0, db.WarningTypeAppArmorNotAvailable, "No AppArmor")
+	require.NoError(t, err)
+
+	err = cluster.UpsertWarningLocalNode("", db.EntityTypeNone, 0, db.WarningTypeAppArmorNotAvailable, "Still no AppArmor")
+	require.NoError(t, err)
+
+	err = cluster.UpsertWarningLocalNode(project.Default, db.EntityTypeImage, 1, db.WarningTypeImageAutoUpdateFailed, "Download failed")
+	require.NoError(t, err)
+
+	var warnings []db.Warning
+	err = cluster.Transaction(func(tx *db.ClusterTx) error {
+		warnings, err = tx.GetWarnings(db.WarningFilter{})
+		return err
+	})
+	require.NoError(t, err)
+	require.Len(t, warnings, 2)
+
+	w := warnings[0]
+	assert.Equal(t, "none", w.Node)
+	assert.Equal(t, "", w.Project)
+	assert.Equal(t, db.EntityTypeNone, w.EntityTypeCode)
+	assert.Equal(t, db.WarningStatusNew, w.Status)
+	assert.Equal(t, "Still no AppArmor", w.LastMessage)
+	assert.Equal(t, 2, w.Count)
+	assert.True(t, w.UpdatedDate.IsZero())
+
+	info := w.ToAPI("")
+	assert.Equal(t, api.WarningStatusNew, info.Status)
+	assert.Equal(t, "low", info.Severity)
+
+	assert.Equal(t, project.Default, warnings[1].Project)
+	assert.Equal(t, db.EntityTypeImage, warnings[1].EntityTypeCode)
+	assert.Equal(t, 1, warnings[1].EntityID)
+
+	err = cluster.Transaction(func(tx *db.ClusterTx) error {
+		err := tx.UpdateWarningStatus(w.UUID, db.WarningStatusAcknowledged)
+		if err != nil {
+			return err
+		}
+
+		err = tx.ResolveWarningsByLocalNodeAndEntity(db.WarningTypeImageAutoUpdateFailed, db.EntityTypeImage, 1)
+		if err != nil {
+			return err
+		}
+
+		warnings, err = tx.GetWarnings(db.WarningFilter{Status: db.WarningStatusResolved})
+		return err
+	})
+	require.NoError(t, err)
+	require.Len(t, warnings, 1)
+	assert.Equal(t, db.WarningTypeImageAutoUpdateFailed, warnings[0].TypeCode)
+	assert.False(t, warnings[0].UpdatedDate.IsZero())
+
+	// A resolved warning which occurs again becomes new.
+	err = cluster.UpsertWarningLocalNode(project.Default, db.EntityTypeImage, 1, db.WarningTypeImageAutoUpdateFailed, "Download failed again")
+	require.NoError(t, err)
+
+	err = cluster.Transaction(func(tx *db.ClusterTx) error {
+		warning, err := tx.GetWarning(warnings[0].UUID)
+		if err != nil {
+			return err
+		}
+
+		assert.Equal(t, db.WarningStatusNew, warning.Status)
+		assert.Equal(t, 2, warning.Count)
+
+		err = tx.ResolveWarningsByLocalNodeOlderThan(time.Now().Add(time.Minute), db.WarningTypeAppArmorNotAvailable)
+		if err != nil {
+			return err
+		}
+
+		warning, err = tx.GetWarning(w.UUID)
+		if err != nil {
+			return err
+		}
+
+		assert.Equal(t, db.WarningStatusResolved, warning.Status)
+
+		err = tx.DeleteResolvedWarningsOlderThan(time.Now().Add(time.Minute))
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.GetWarning(w.UUID)
+		assert.Equal(t, db.ErrNoSuchObject, err)
+
+		err = tx.DeleteWarning(warnings[0].UUID)
+		if err != nil {
+			return err
+		}
+
+		assert.Equal(t, db.ErrNoSuchObject, tx.DeleteWarning(warnings[0].UUID))
+		return nil
+	})
+	require.NoError(t, err)
+}
@@ -33,6 +33,12 @@ type RootFSEntryItem struct {
 	Opts []string // Describes the mount options associated with the filesystem.
 }
 
+// USBDeviceItem represents a single host USB device passed through to an instance.
+type USBDeviceItem struct {
+	DeviceName     string // The internal name for the USB device.
+	HostDevicePath string // Path to the USB device node on the host.
+}
+
 // RunConfig represents LXD defined run-time config used for device setup/cleanup.
 type RunConfig struct {
 	RootFS           RootFSEntryItem  // RootFS to setup.
@@ -42,4 +48,5 @@ type RunConfig struct {
 	Uevents          [][]string       // Uevents to inject.
 	PostHooks        []func() error   // Functions to be run after device attach/detach.
 	GPUDevice        []RunConfigItem  // GPU device configuration settings.
+	USBDevice        []USBDeviceItem  // USB devices to pass through.
 }
@@ -85,6 +85,7 @@ func (d *disk) validateConfig(instConf instance.ConfigReader) error {
 		"limits.write":      validate.IsAny,
 		"limits.max":        validate.IsAny,
 		"size":              validate.IsAny,
+		"size.state":        validate.Optional(validate.IsSize),
 		"pool":              validate.IsAny,
 		"propagation":       validatePropagation,
 		"raw.mount.options": validate.IsAny,
@@ -119,6 +120,16 @@ func (d *disk) validateConfig(instConf instance.ConfigReader) error {
 		return fmt.Errorf("Only the root disk may have a size quota")
 	}
 
+	if d.config["size.state"] != "" {
+		if d.config["path"] != "/" {
+			return fmt.Errorf("Only the root disk may have a state size quota")
+		}
+
+		if instConf.Type() != instancetype.VM {
+			return fmt.Errorf("The state size quota is only supported for virtual machines")
+		}
+	}
+
 	if d.config["recursive"] != "" && (d.config["path"] == "/" || !shared.IsDir(shared.HostPath(d.config["source"]))) {
 		return fmt.Errorf("The recursive option is only supported for additional bind-mounted paths")
 	}
@@ -589,9 +600,11 @@ func (d *disk) Update(oldDevices deviceConfig.Devices, isRunning bool) error {
 		// Deal with quota changes.
 		oldRootDiskDeviceSize := oldDevices[oldRootDiskDeviceKey]["size"]
 		newRootDiskDeviceSize := expandedDevices[newRootDiskDeviceKey]["size"]
+		oldRootDiskDeviceStateSize := oldDevices[oldRootDiskDeviceKey]["size.state"]
+		newRootDiskDeviceStateSize := expandedDevices[newRootDiskDeviceKey]["size.state"]
 
 		// Apply disk quota changes.
-		if newRootDiskDeviceSize != oldRootDiskDeviceSize {
+		if newRootDiskDeviceSize != oldRootDiskDeviceSize || newRootDiskDeviceStateSize != oldRootDiskDeviceStateSize {
 			err := d.applyQuota(newRootDiskDeviceSize)
 			if err == storagePools.ErrRunningQuotaResizeNotSupported {
 				// Save volatile apply_quota key for next boot if cannot apply now.
@@ -628,7 +641,7 @@ func (d *disk) applyQuota(newSize string) error {
 		return err
 	}
 
-	err = pool.SetInstanceQuota(d.inst, newSize, nil)
+	err = pool.SetInstanceQuota(d.inst, newSize, d.config["size.state"], nil)
 	if err != nil {
 		return err
 	}
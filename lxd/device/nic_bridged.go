@@ -23,7 +23,9 @@ import (
 	"github.com/lxc/lxd/lxd/instance"
 	"github.com/lxc/lxd/lxd/instance/instancetype"
 	"github.com/lxc/lxd/lxd/network"
+	"github.com/lxc/lxd/lxd/network/acl"
 	"github.com/lxc/lxd/lxd/network/openvswitch"
+	"github.com/lxc/lxd/lxd/project"
 	"github.com/lxc/lxd/lxd/revert"
 	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
@@ -44,6 +46,7 @@ func (d *nicBridged) validateConfig(instConf instance.ConfigReader) error {
 	}
 
 	var requiredFields []string
+	var networkProject string
 	optionalFields := []string{
 		"name",
 		"network",
@@ -61,6 +64,7 @@ func (d *nicBridged) validateConfig(instConf instance.ConfigReader) error {
 		"security.mac_filtering",
 		"security.ipv4_filtering",
 		"security.ipv6_filtering",
+		"security.acls",
 		"maas.subnet.ipv4",
 		"maas.subnet.ipv6",
 		"boot.priority",
@@ -79,7 +83,14 @@ func (d *nicBridged) validateConfig(instConf instance.ConfigReader) error {
 		}
 
 		// If network property is specified, lookup network settings and apply them to the device's config.
-		n, err := network.LoadByName(d.state, d.config["network"])
+		// project.NetworkProject is used here to get the project the network is in.
+		var err error
+		networkProject, err = project.NetworkProject(d.state.Cluster, instConf.Project())
+		if err != nil {
+			return errors.Wrapf(err, "Failed loading network project name")
+		}
+
+		n, err := network.LoadByName(d.state, networkProject, d.config["network"])
 		if err != nil {
 			return errors.Wrapf(err, "Error loading network config for %q", d.config["network"])
 		}
@@ -148,6 +159,11 @@ func (d *nicBridged) validateConfig(instConf instance.ConfigReader) error {
 	} else {
 		// If no network property supplied, then parent property is required.
 		requiredFields = append(requiredFields, "parent")
+
+		// ACLs are defined in the network's project, so they can only be used with a managed network.
+		if d.config["security.acls"] != "" {
+			return fmt.Errorf("Cannot use %q property without %q property", "security.acls", "network")
+		}
 	}
 
 	// Check that IP filtering isn't being used with VLAN filtering.
@@ -190,6 +206,11 @@ func (d *nicBridged) validateConfig(instConf instance.ConfigReader) error {
 		return nil
 	}
 
+	// Check that the ACLs exist.
+	rules["security.acls"] = func(value string) error {
+		return acl.ValidateNames(d.state, networkProject, value)
+	}
+
 	// Now run normal validation.
 	err := d.config.Validate(rules)
 	if err != nil {
@@ -215,7 +236,7 @@ func (d *nicBridged) validateEnvironment() error {
 // CanHotPlug returns whether the device can be managed whilst the instance is running, it also
 // returns a list of fields that can be updated without triggering a device remove & add.
 func (d *nicBridged) CanHotPlug() (bool, []string) {
-	return true, []string{"limits.ingress", "limits.egress", "limits.max", "ipv4.routes", "ipv6.routes", "ipv4.address", "ipv6.address", "security.mac_filtering", "security.ipv4_filtering", "security.ipv6_filtering"}
+	return true, []string{"limits.ingress", "limits.egress", "limits.max", "ipv4.routes", "ipv6.routes", "ipv4.address", "ipv6.address", "security.mac_filtering", "security.ipv4_filtering", "security.ipv6_filtering", "security.acls"}
 }
 
 // Add is run when a device is added to an instance whether or not the instance is running.
@@ -268,7 +289,7 @@ func (d *nicBridged) Start() (*deviceConfig.RunConfig, error) {
 	networkVethFillFromVolatile(d.config, saveData)
 
 	// Apply host-side routes.
-	err = networkSetupHostVethRoutes(d.state, d.config, nil, saveData)
+	err = networkSetupHostVethRoutes(d.state, d.inst.Project(), d.config, nil, saveData)
 	if err != nil {
 		return nil, err
 	}
@@ -370,7 +391,7 @@ func (d *nicBridged) Update(oldDevices deviceConfig.Devices, isRunning bool) err
 		}
 
 		// Apply host-side routes.
-		err = networkSetupHostVethRoutes(d.state, d.config, oldConfig, v)
+		err = networkSetupHostVethRoutes(d.state, d.inst.Project(), d.config, oldConfig, v)
 		if err != nil {
 			return err
 		}
@@ -444,7 +465,7 @@ func (d *nicBridged) postStop() error {
 		}
 	}
 
-	networkRemoveVethRoutes(d.state, d.config)
+	networkRemoveVethRoutes(d.state, d.inst.Project(), d.config)
 	d.removeFilters(d.config)
 
 	return nil
@@ -489,7 +510,7 @@ func (d *nicBridged) rebuildDnsmasqEntry() error {
 	dnsmasq.ConfigMutex.Lock()
 	defer dnsmasq.ConfigMutex.Unlock()
 
-	_, dbInfo, err := d.state.Cluster.GetNetworkInAnyState(d.config["parent"])
+	_, dbInfo, err := d.state.Cluster.GetNetworkInAnyState(project.Default, d.config["parent"])
 	if err != nil {
 		return err
 	}
@@ -544,11 +565,61 @@ func (d *nicBridged) setupHostFilters(oldConfig deviceConfig.Device) error {
 		}
 	}
 
+	// Apply the network ACLs, removing any old rules if they have been unset.
+	if d.config["security.acls"] != "" || (oldConfig != nil && oldConfig["security.acls"] != "") {
+		err := d.applyACLs()
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// applyACLs applies the rules of the NIC's network ACLs to the traffic going through its bridge port.
+// If no ACLs are set, any previously applied rules are removed.
+func (d *nicBridged) applyACLs() error {
+	if d.config["host_name"] == "" {
+		return fmt.Errorf("Failed to apply network ACLs: require host_name defined")
+	}
+
+	aclNames := acl.ParseNames(d.config["security.acls"])
+
+	// Bridged traffic only goes through iptables with br_netfilter. We won't try to load it as its default mode
+	// can cause unwanted traffic blocking.
+	if len(aclNames) > 0 && d.state.Firewall.String() == "xtables" {
+		sysctlVal, err := util.SysctlGet("net/bridge/bridge-nf-call-iptables")
+		if err != nil {
+			return errors.Wrapf(err, "security.acls requires br_netfilter be loaded")
+		}
+
+		if sysctlVal != "1\n" {
+			return fmt.Errorf("security.acls requires br_netfilter sysctl net.bridge.bridge-nf-call-iptables=1")
+		}
+	}
+
+	networkProject, err := project.NetworkProject(d.state.Cluster, d.inst.Project())
+	if err != nil {
+		return errors.Wrapf(err, "Failed loading network project name")
+	}
+
+	n, err := network.LoadByName(d.state, networkProject, d.config["network"])
+	if err != nil {
+		return errors.Wrapf(err, "Error loading network config for %q", d.config["network"])
+	}
+
+	return network.BridgeInstanceDevicePortApplyACLs(d.state, n, d.inst.Project(), d.inst.Name(), d.name, d.config["host_name"], aclNames)
+}
+
 // removeFilters removes any network level filters defined for the instance.
 func (d *nicBridged) removeFilters(m deviceConfig.Device) {
+	if m["security.acls"] != "" && m["host_name"] != "" {
+		err := d.state.Firewall.InstanceApplyACLRules(d.inst.Project(), d.inst.Name(), d.name, m["host_name"], nil)
+		if err != nil {
+			logger.Errorf("Failed to remove network ACL rules for %q: %v", d.name, err)
+		}
+	}
+
 	if m["hwaddr"] == "" {
 		logger.Errorf("Failed to remove network filters for %q: hwaddr not defined", d.name)
 		return
@@ -646,7 +717,7 @@ func (d *nicBridged) setFilters() (err error) {
 	IPv6 := net.ParseIP(d.config["ipv6.address"])
 
 	// Check if the parent is managed and load config. If parent is unmanaged continue anyway.
-	n, err := network.LoadByName(d.state, d.config["parent"])
+	n, err := network.LoadByName(d.state, project.Default, d.config["parent"])
 	if err != nil && err != db.ErrNoSuchObject {
 		return err
 	}
@@ -13,7 +13,9 @@ import (
 	"github.com/lxc/lxd/lxd/instance"
 	"github.com/lxc/lxd/lxd/instance/instancetype"
 	"github.com/lxc/lxd/lxd/network"
+	"github.com/lxc/lxd/lxd/network/acl"
 	"github.com/lxc/lxd/lxd/network/openvswitch"
+	"github.com/lxc/lxd/lxd/project"
 	"github.com/lxc/lxd/lxd/revert"
 	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
@@ -55,10 +57,17 @@ func (d *nicOVN) validateConfig(instConf instance.ConfigReader) error {
 		"ipv4.address",
 		"ipv6.address",
 		"boot.priority",
+		"security.acls",
+	}
+
+	// The NIC's network may be a non-default project, so lookup project and get network's project name.
+	networkProject, err := project.NetworkProject(d.state.Cluster, instConf.Project())
+	if err != nil {
+		return errors.Wrapf(err, "Failed loading network project name")
 	}
 
 	// Lookup network settings and apply them to the device's config.
-	n, err := network.LoadByName(d.state, d.config["network"])
+	n, err := network.LoadByName(d.state, networkProject, d.config["network"])
 	if err != nil {
 		return errors.Wrapf(err, "Error loading network config for %q", d.config["network"])
 	}
@@ -127,6 +136,11 @@ func (d *nicOVN) validateConfig(instConf instance.ConfigReader) error {
 
 	rules := nicValidationRules(requiredFields, optionalFields)
 
+	// Check that the ACLs exist.
+	rules["security.acls"] = func(value string) error {
+		return acl.ValidateNames(d.state, networkProject, value)
+	}
+
 	// Now run normal validation.
 	err = d.config.Validate(rules)
 	if err != nil {
@@ -157,7 +171,7 @@ func (d *nicOVN) validateEnvironment() error {
 // CanHotPlug returns whether the device can be managed whilst the instance is running, it also
 // returns a list of fields that can be updated without triggering a device remove & add.
 func (d *nicOVN) CanHotPlug() (bool, []string) {
-	return true, []string{}
+	return true, []string{"security.acls"}
 }
 
 // Add is run when a device is added to an instance whether or not the instance is running.
@@ -233,7 +247,7 @@ func (d *nicOVN) Start() (*deviceConfig.RunConfig, error) {
 	}
 
 	// Add new OVN logical switch port for instance.
-	logicalPortName, err := network.OVNInstanceDevicePortAdd(d.network, d.inst.ID(), d.inst.Name(), d.name, mac, ips)
+	logicalPortName, err := network.OVNInstanceDevicePortAdd(d.network, d.inst.ID(), d.inst.Name(), d.name, mac, ips, acl.ParseNames(d.config["security.acls"]))
 	if err != nil {
 		return nil, err
 	}
@@ -319,6 +333,14 @@ func (d *nicOVN) Update(oldDevices deviceConfig.Devices, isRunning bool) error {
 		if err != nil {
 			return err
 		}
+
+		// Apply the ACLs to the logical port.
+		if d.config["security.acls"] != oldConfig["security.acls"] {
+			err = network.OVNInstanceDevicePortApplyACLs(d.network, d.inst.ID(), d.name, acl.ParseNames(d.config["security.acls"]))
+			if err != nil {
+				return err
+			}
+		}
 	}
 
 	// If an IPv6 address has changed, if the instance is running we should bounce the host-side
@@ -344,6 +366,11 @@ func (d *nicOVN) Stop() (*deviceConfig.RunConfig, error) {
 		PostHooks: []func() error{d.postStop},
 	}
 
+	// Leave the logical switch port alone if it is now used by the instance on another cluster member.
+	if instance.IsMovedAway(d.state, d.inst) {
+		return &runConf, nil
+	}
+
 	err := network.OVNInstanceDevicePortDelete(d.network, d.inst.ID(), d.name)
 	if err != nil {
 		// Don't fail here as we still want the postStop hook to run to clean up the local veth pair.
@@ -94,7 +94,7 @@ func (d *nicP2P) Start() (*deviceConfig.RunConfig, error) {
 	networkVethFillFromVolatile(d.config, saveData)
 
 	// Apply host-side routes.
-	err = networkSetupHostVethRoutes(d.state, d.config, nil, saveData)
+	err = networkSetupHostVethRoutes(d.state, d.inst.Project(), d.config, nil, saveData)
 	if err != nil {
 		return nil, err
 	}
@@ -149,7 +149,7 @@ func (d *nicP2P) Update(oldDevices deviceConfig.Devices, isRunning bool) error {
 	networkVethFillFromVolatile(d.config, v)
 
 	// Apply host-side routes.
-	err = networkSetupHostVethRoutes(d.state, d.config, oldConfig, v)
+	err = networkSetupHostVethRoutes(d.state, d.inst.Project(), d.config, oldConfig, v)
 	if err != nil {
 		return err
 	}
@@ -8,20 +8,27 @@ import (
 	"github.com/pkg/errors"
 
 	deviceConfig "github.com/lxc/lxd/lxd/device/config"
+	"github.com/lxc/lxd/lxd/project"
 	"github.com/lxc/lxd/lxd/state"
 )
 
 // NICType resolves the NIC Type for the supplied NIC device config.
 // If the device "type" is "nic" and the "network" property is specified in the device config, then NIC type is
-// resolved from the network's type. Otherwise the device's "nictype" property is returned (which may be empty if
-// used with non-NIC device configs).
-func NICType(s *state.State, d deviceConfig.Device) (string, error) {
+// resolved from the network's type. The network is looked up in the effective network project of the device's
+// project. Otherwise the device's "nictype" property is returned (which may be empty if used with non-NIC device
+// configs).
+func NICType(s *state.State, deviceProjectName string, d deviceConfig.Device) (string, error) {
 	// NIC devices support resolving their "nictype" from their "network" property.
 	if d["type"] == "nic" {
 		if d["network"] != "" {
-			_, netInfo, err := s.Cluster.GetNetworkInAnyState(d["network"])
+			networkProjectName, err := project.NetworkProject(s.Cluster, deviceProjectName)
 			if err != nil {
-				return "", errors.Wrapf(err, "Failed to load network %q", d["network"])
+				return "", errors.Wrapf(err, "Failed to load network project for project %q", deviceProjectName)
+			}
+
+			_, netInfo, err := s.Cluster.GetNetworkInAnyState(networkProjectName, d["network"])
+			if err != nil {
+				return "", errors.Wrapf(err, "Failed to load network %q for project %q", d["network"], networkProjectName)
 			}
 
 			var nicType string
@@ -45,7 +45,7 @@ func (d *usb) isRequired() bool {
 
 // validateConfig checks the supplied config for correctness.
 func (d *usb) validateConfig(instConf instance.ConfigReader) error {
-	if !instanceSupported(instConf.Type(), instancetype.Container) {
+	if !instanceSupported(instConf.Type(), instancetype.Container, instancetype.VM) {
 		return ErrUnsupportedDevType
 	}
 
@@ -118,6 +118,15 @@ func (d *usb) Register() error {
 
 // Start is run when the device is added to the instance.
 func (d *usb) Start() (*deviceConfig.RunConfig, error) {
+	if d.inst.Type() == instancetype.VM {
+		return d.startVM()
+	}
+
+	return d.startContainer()
+}
+
+// startContainer starts the USB device for a container instance.
+func (d *usb) startContainer() (*deviceConfig.RunConfig, error) {
 	usbs, err := d.loadUsb()
 	if err != nil {
 		return nil, err
@@ -144,8 +153,46 @@ func (d *usb) Start() (*deviceConfig.RunConfig, error) {
 	return &runConf, nil
 }
 
+// startVM starts the USB device for a virtual machine instance.
+// The matching host devices are passed through to the VM as they are found when the device is started.
+func (d *usb) startVM() (*deviceConfig.RunConfig, error) {
+	usbs, err := d.loadUsb()
+	if err != nil {
+		return nil, err
+	}
+
+	runConf := deviceConfig.RunConfig{}
+
+	for _, usb := range usbs {
+		if !usbIsOurDevice(d.config, &usb) {
+			continue
+		}
+
+		// Use the host bus and device numbers to give each matching device a unique name.
+		fields := strings.Split(usb.Path, "/")
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("Invalid USB device path %q", usb.Path)
+		}
+
+		runConf.USBDevice = append(runConf.USBDevice, deviceConfig.USBDeviceItem{
+			DeviceName:     fmt.Sprintf("%s-%s-%s", d.name, fields[len(fields)-2], fields[len(fields)-1]),
+			HostDevicePath: usb.Path,
+		})
+	}
+
+	if d.isRequired() && len(runConf.USBDevice) <= 0 {
+		return nil, fmt.Errorf("Required USB device not found")
+	}
+
+	return &runConf, nil
+}
+
 // Stop is run when the device is removed from the instance.
 func (d *usb) Stop() (*deviceConfig.RunConfig, error) {
+	if d.inst.Type() == instancetype.VM {
+		return &deviceConfig.RunConfig{}, nil
+	}
+
 	// Unregister any USB event handlers for this device.
 	usbUnregisterHandler(d.inst, d.name)
 
@@ -56,6 +56,15 @@ type Config struct {
 	//
 	// It can be updated after the endpoints are up using UpdateDebugAddress().
 	DebugAddress string
+
+	// HTTP server for the S3 compatible storage buckets API.
+	StorageBucketsServer *http.Server
+
+	// StorageBucketsAddress sets the address for the storage buckets
+	// endpoint. If not set, the storage buckets endpoint won't be started.
+	//
+	// It can be updated after the endpoints are up using StorageBucketsUpdateAddress().
+	StorageBucketsAddress string
 }
 
 // Up brings up all applicable LXD endpoints and starts accepting HTTP
@@ -159,6 +168,8 @@ func (e *Endpoints) up(config *Config) error {
 		network: config.RestServer,
 		cluster: config.RestServer,
 		pprof:   pprofCreateServer(),
+
+		storageBuckets: config.StorageBucketsServer,
 	}
 	e.cert = config.Cert
 	e.inherited = map[kind]bool{}
@@ -254,6 +265,16 @@ func (e *Endpoints) up(config *Config) error {
 		e.serveHTTP(pprof)
 	}
 
+	if config.StorageBucketsAddress != "" {
+		e.listeners[storageBuckets], err = networkCreateListener(config.StorageBucketsAddress, e.cert)
+		if err != nil {
+			return err
+		}
+
+		logger.Infof("Starting storage buckets handler:")
+		e.serveHTTP(storageBuckets)
+	}
+
 	logger.Infof("Starting /dev/lxd handler:")
 	e.serveHTTP(devlxd)
 
@@ -306,6 +327,14 @@ func (e *Endpoints) Down() error {
 		}
 	}
 
+	if e.listeners[storageBuckets] != nil {
+		logger.Infof("Stopping storage buckets handler:")
+		err := e.closeListener(storageBuckets)
+		if err != nil {
+			return err
+		}
+	}
+
 	if e.tomb != nil {
 		e.tomb.Kill(nil)
 		e.tomb.Wait()
@@ -388,6 +417,7 @@ const (
 	network
 	pprof
 	cluster
+	storageBuckets
 )
 
 // Human-readable descriptions of the various kinds of endpoints.
@@ -397,4 +427,6 @@ var descriptions = map[kind]string{
 	network: "TCP socket",
 	pprof:   "pprof socket",
 	cluster: "cluster socket",
+
+	storageBuckets: "storage buckets socket",
 }
@@ -136,7 +136,14 @@ func (e *Endpoints) NetworkUpdateCert(cert *shared.CertInfo) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 	e.cert = cert
-	listener, ok := e.listeners[network]
+
+	// Update the storage buckets listener, if enabled, as it may be used without the network one.
+	listener, ok := e.listeners[storageBuckets]
+	if ok {
+		listener.(*networkListener).Config(cert)
+	}
+
+	listener, ok = e.listeners[network]
 	if !ok {
 		return
 	}
@@ -41,6 +41,25 @@ func TestEndpoints_NetworkUpdateCert(t *testing.T) {
 	assert.Error(t, httpGetOverTLSSocket(address, oldCert))
 }
 
+// The storage buckets endpoint gets the new TLS certificate even if the network endpoint isn't enabled.
+func TestEndpoints_NetworkUpdateCertStorageBuckets(t *testing.T) {
+	endpoints, config, cleanup := newEndpoints(t)
+	defer cleanup()
+
+	config.StorageBucketsServer = newServer()
+	config.StorageBucketsAddress = "127.0.0.1:0"
+	require.NoError(t, endpoints.Up(config))
+
+	oldCert := config.Cert
+	newCert := shared.TestingAltKeyPair()
+
+	endpoints.NetworkUpdateCert(newCert)
+
+	address := endpoints.StorageBucketsAddress()
+	assert.NoError(t, httpGetOverTLSSocket(address, newCert))
+	assert.Error(t, httpGetOverTLSSocket(address, oldCert))
+}
+
 // If socket-based activation is detected, it will be used for binding the API
 // Endpoints' unix socket.
 func TestEndpoints_NetworkSocketBasedActivation(t *testing.T) {
@@ -0,0 +1,65 @@
+package endpoints
+
+import (
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+// StorageBucketsAddress returns the network address of the storage buckets endpoint, or an empty string if
+// there's no storage buckets endpoint.
+func (e *Endpoints) StorageBucketsAddress() string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	listener := e.listeners[storageBuckets]
+	if listener == nil {
+		return ""
+	}
+
+	return listener.Addr().String()
+}
+
+// StorageBucketsUpdateAddress updates the address for the storage buckets endpoint, shutting it down and
+// restarting it.
+func (e *Endpoints) StorageBucketsUpdateAddress(address string) error {
+	if address != "" {
+		address = util.CanonicalNetworkAddress(address)
+	}
+
+	oldAddress := e.StorageBucketsAddress()
+	if address == oldAddress {
+		return nil
+	}
+
+	logger.Infof("Update storage buckets address")
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// Close the previous socket.
+	e.closeListener(storageBuckets)
+
+	// If turning off listening, we're done.
+	if address == "" {
+		return nil
+	}
+
+	listener, err := networkCreateListener(address, e.cert)
+	if err != nil {
+		// Attempt to revert to the previous address.
+		if oldAddress != "" {
+			listener, err1 := networkCreateListener(oldAddress, e.cert)
+			if err1 == nil {
+				e.listeners[storageBuckets] = listener
+				e.serveHTTP(storageBuckets)
+			}
+		}
+
+		return err
+	}
+
+	e.listeners[storageBuckets] = listener
+	e.serveHTTP(storageBuckets)
+
+	return nil
+}
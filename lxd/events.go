@@ -14,7 +14,7 @@ import (
 var eventsCmd = APIEndpoint{
 	Path: "events",
 
-	Get: APIEndpointAction{Handler: eventsGet, AccessHandler: allowAuthenticated},
+	Get: APIEndpointAction{Handler: eventsGet, AccessHandler: allowAuthenticatedProject},
 }
 
 type eventsServe struct {
@@ -32,9 +32,16 @@ func (r *eventsServe) String() string {
 
 func eventsSocket(d *Daemon, r *http.Request, w http.ResponseWriter) error {
 	project := projectParam(r)
+	_, restricted := d.userRestrictedProjects(r)
+
 	typeStr := r.FormValue("type")
 	if typeStr == "" {
 		typeStr = "logging,operation,lifecycle"
+
+		// Logging events aren't tied to a project, so restricted clients don't get them.
+		if restricted {
+			typeStr = "operation,lifecycle"
+		}
 	}
 
 	// Upgrade the connection to websocket
@@ -58,8 +65,8 @@ func eventsSocket(d *Daemon, r *http.Request, w http.ResponseWriter) error {
 
 	// If this request is an internal one initiated by another node wanting
 	// to watch the events on this node, set the listener to broadcast only
-	// local events.
-	listener, err := d.events.AddListener(project, c, strings.Split(typeStr, ","), serverName, isClusterNotification(r))
+	// local events. Restricted clients only get the events of their project.
+	listener, err := d.events.AddListener(project, c, strings.Split(typeStr, ","), serverName, isClusterNotification(r), restricted)
 	if err != nil {
 		return err
 	}
@@ -94,5 +101,10 @@ func eventsSocket(d *Daemon, r *http.Request, w http.ResponseWriter) error {
 }
 
 func eventsGet(d *Daemon, r *http.Request) response.Response {
+	_, restricted := d.userRestrictedProjects(r)
+	if restricted && shared.StringInSlice("logging", strings.Split(r.FormValue("type"), ",")) {
+		return response.Forbidden(nil)
+	}
+
 	return &eventsServe{req: r, d: d}
 }
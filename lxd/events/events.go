@@ -36,13 +36,15 @@ func NewServer(debug bool, verbose bool) *Server {
 }
 
 // AddListener creates and returns a new event listener.
-func (s *Server) AddListener(group string, connection *websocket.Conn, messageTypes []string, location string, noForward bool) (*Listener, error) {
+// A restricted listener only gets the events sent to its own group.
+func (s *Server) AddListener(group string, connection *websocket.Conn, messageTypes []string, location string, noForward bool, restricted bool) (*Listener, error) {
 	listener := &Listener{
 		group:        group,
 		connection:   connection,
 		messageTypes: messageTypes,
 		location:     location,
 		noForward:    noForward,
+		restricted:   restricted,
 		active:       make(chan bool, 1),
 		id:           uuid.NewRandom().String(),
 	}
@@ -117,6 +119,12 @@ func (s *Server) broadcast(group string, event api.Event, isForward bool) error
 			continue
 		}
 
+		// Events without a group (global ones and those forwarded from other nodes) may not belong to
+		// any of the projects a restricted listener has access to.
+		if group == "" && listener.restricted {
+			continue
+		}
+
 		if isForward && listener.noForward {
 			continue
 		}
@@ -187,6 +195,9 @@ type Listener struct {
 	// nodes. It only used by listeners created internally by LXD nodes
 	// connecting to other LXD nodes to get their local events only.
 	noForward bool
+
+	// If true, this listener only gets the events of its own group.
+	restricted bool
 }
 
 // MessageTypes returns a list of message types the listener will be notified of.
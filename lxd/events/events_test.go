@@ -0,0 +1,84 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// listen connects a new listener of the "operation" events of the given project and returns its connection.
+func listen(t *testing.T, s *Server, project string, restricted bool) (*websocket.Conn, func()) {
+	added := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := shared.WebsocketUpgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer c.Close()
+
+		listener, err := s.AddListener(project, c, []string{"operation"}, "", false, restricted)
+		require.NoError(t, err)
+		close(added)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		listener.Wait(ctx)
+	}))
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+	require.NoError(t, err)
+	<-added
+
+	return conn, func() {
+		conn.Close()
+		ts.Close()
+	}
+}
+
+// receive returns the descriptions of the operations received until no event came for a while.
+func receive(t *testing.T, conn *websocket.Conn) []string {
+	descriptions := []string{}
+	for {
+		conn.SetReadDeadline(time.Now().Add(500 * time.Millisecond))
+
+		event := api.Event{}
+		err := conn.ReadJSON(&event)
+		if err != nil {
+			return descriptions
+		}
+
+		op := api.Operation{}
+		require.NoError(t, json.Unmarshal(event.Metadata, &op))
+		descriptions = append(descriptions, op.Description)
+	}
+}
+
+// Token operations don't belong to any project, so restricted listeners never get their events.
+func TestServer_RestrictedListener(t *testing.T) {
+	s := NewServer(false, false)
+
+	restricted, cleanup := listen(t, s, "p1", true)
+	defer cleanup()
+
+	unrestricted, cleanup := listen(t, s, "p1", false)
+	defer cleanup()
+
+	token := api.Operation{Description: "Certificate add token", Metadata: map[string]interface{}{"secret": "abc"}}
+	require.NoError(t, s.Send("", "operation", token))
+	require.NoError(t, s.Send("p1", "operation", api.Operation{Description: "Creating instance"}))
+	require.NoError(t, s.Send("p2", "operation", api.Operation{Description: "Deleting instance"}))
+
+	s.Forward(1, api.Event{Type: "operation", Metadata: []byte(`{"description": "Forwarded token"}`)})
+
+	assert.Equal(t, []string{"Creating instance"}, receive(t, restricted))
+	assert.ElementsMatch(t, []string{"Certificate add token", "Creating instance", "Forwarded token"}, receive(t, unrestricted))
+}
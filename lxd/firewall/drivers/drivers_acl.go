@@ -0,0 +1,81 @@
+package drivers
+
+import (
+	"net"
+	"strings"
+)
+
+// ACLRule represents an ACL rule that can be added to a firewall.
+type ACLRule struct {
+	Direction       string // Either "ingress" or "egress".
+	Action          string // Either "allow", "reject" or "drop".
+	Source          string // Comma separated list of IPs or CIDR subnets.
+	Destination     string // Comma separated list of IPs or CIDR subnets.
+	Protocol        string // Either "", "tcp", "udp", "icmp4" or "icmp6".
+	SourcePort      string // Comma separated list of ports or ranges (e.g. "80,1000-2000").
+	DestinationPort string // Comma separated list of ports or ranges (e.g. "80,1000-2000").
+	ICMPType        string
+	ICMPCode        string
+}
+
+// hasSubjects returns true if the rule matches on source or destination addresses.
+func (r ACLRule) hasSubjects() bool {
+	return r.Source != "" || r.Destination != ""
+}
+
+// subjectsForIPVersion returns the source and destination subjects of the rule that belong to the specified IP
+// version. The returned boolean is false if the rule cannot match any traffic of that IP version.
+func (r ACLRule) subjectsForIPVersion(ipVersion uint) ([]string, []string, bool) {
+	if (ipVersion == 4 && r.Protocol == "icmp6") || (ipVersion == 6 && r.Protocol == "icmp4") {
+		return nil, nil, false
+	}
+
+	sources := aclSubjectsForIPVersion(r.Source, ipVersion)
+	if r.Source != "" && len(sources) == 0 {
+		return nil, nil, false
+	}
+
+	destinations := aclSubjectsForIPVersion(r.Destination, ipVersion)
+	if r.Destination != "" && len(destinations) == 0 {
+		return nil, nil, false
+	}
+
+	return sources, destinations, true
+}
+
+// aclSubjectsForIPVersion returns the IPs and subnets in the comma separated subjects list that belong to the
+// specified IP version.
+func aclSubjectsForIPVersion(subjects string, ipVersion uint) []string {
+	if subjects == "" {
+		return nil
+	}
+
+	var out []string
+	for _, subject := range strings.Split(subjects, ",") {
+		var ip net.IP
+		if strings.Contains(subject, "/") {
+			ip, _, _ = net.ParseCIDR(subject)
+		} else {
+			ip = net.ParseIP(subject)
+		}
+
+		if ip == nil {
+			continue
+		}
+
+		if (ip.To4() != nil) == (ipVersion == 4) {
+			out = append(out, subject)
+		}
+	}
+
+	return out
+}
+
+// aclPorts returns the comma separated list of ports as a slice.
+func aclPorts(ports string) []string {
+	if ports == "" {
+		return nil
+	}
+
+	return strings.Split(ports, ",")
+}
@@ -0,0 +1,52 @@
+package drivers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testInstanceACLRules = []ACLRule{
+	{Direction: "ingress", Action: "allow", Source: "10.0.0.2", Protocol: "tcp", DestinationPort: "22"},
+	{Direction: "ingress", Action: "reject"},
+	{Direction: "egress", Action: "drop", Destination: "10.0.0.3,fd00::3"},
+	{Direction: "egress", Action: "allow"},
+}
+
+func TestNftablesInstanceACLRules(t *testing.T) {
+	d := Nftables{}
+
+	ingress, egress := d.instanceACLRulesToNftRules("veth1", testInstanceACLRules)
+
+	assert.Equal(t, []string{
+		`oifname "veth1" ip saddr {10.0.0.2} tcp dport {22} accept`,
+		`oifname "veth1" drop`,
+	}, ingress)
+
+	assert.Equal(t, []string{
+		`iifname "veth1" ip daddr {10.0.0.3} drop`,
+		`iifname "veth1" ip6 daddr {fd00::3} drop`,
+		`iifname "veth1" accept`,
+	}, egress)
+}
+
+func TestXtablesInstanceACLRules(t *testing.T) {
+	d := Xtables{}
+
+	rules := d.instanceACLRulesToIptablesArgs("veth1", 4, testInstanceACLRules)
+
+	assert.Equal(t, [][]string{
+		{"-m", "conntrack", "--ctstate", "RELATED,ESTABLISHED", "-j", "RETURN"},
+		{"-m", "physdev", "--physdev-in", "veth1", "-p", "udp", "--dport", "67", "-j", "RETURN"},
+		{"-m", "physdev", "--physdev-out", "veth1", "-p", "udp", "--dport", "68", "-j", "RETURN"},
+		{"-m", "physdev", "--physdev-out", "veth1", "-s", "10.0.0.2", "-p", "tcp", "-m", "multiport", "--dports", "22", "-j", "RETURN"},
+		{"-m", "physdev", "--physdev-out", "veth1", "-j", "REJECT"},
+		{"-m", "physdev", "--physdev-in", "veth1", "-d", "10.0.0.3", "-j", "DROP"},
+		{"-m", "physdev", "--physdev-in", "veth1", "-j", "RETURN"},
+	}, rules)
+
+	// The IPv6 rules skip the rules only matching IPv4 addresses.
+	rules = d.instanceACLRulesToIptablesArgs("veth1", 6, testInstanceACLRules)
+	assert.Contains(t, rules, []string{"-m", "physdev", "--physdev-in", "veth1", "-d", "fd00::3", "-j", "DROP"})
+	assert.NotContains(t, rules, []string{"-m", "physdev", "--physdev-out", "veth1", "-s", "10.0.0.2", "-p", "tcp", "-m", "multiport", "--dports", "22", "-j", "RETURN"})
+}
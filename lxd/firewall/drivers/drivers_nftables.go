@@ -274,6 +274,129 @@ func (d Nftables) NetworkClear(networkName string, ipVersion uint) error {
 	return nil
 }
 
+// NetworkApplyACLRules applies the ACL rules to traffic forwarded to and from the network.
+// Rules are evaluated in order and an empty set of rules removes any existing ACL rules.
+func (d Nftables) NetworkApplyACLRules(networkName string, rules []ACLRule) error {
+	err := d.removeChains([]string{"inet"}, networkName, "aclfwd")
+	if err != nil {
+		return errors.Wrapf(err, "Failed clearing ACL rules for network %q", networkName)
+	}
+
+	if len(rules) == 0 {
+		return nil
+	}
+
+	nftRules := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		nftRules = append(nftRules, d.aclRuleToNftRules(networkName, rule)...)
+	}
+
+	tplFields := map[string]interface{}{
+		"namespace":      nftablesNamespace,
+		"chainSeparator": nftablesChainSeparator,
+		"networkName":    networkName,
+		"family":         "inet",
+		"rules":          nftRules,
+	}
+
+	err = d.applyNftConfig(nftablesNetACLSetup, tplFields)
+	if err != nil {
+		return errors.Wrapf(err, "Failed adding ACL rules for network %q", networkName)
+	}
+
+	return nil
+}
+
+// aclRuleToNftRules converts an ACL rule into nftables rules matching traffic going out of (ingress) or coming in
+// from (egress) the specified interface. A rule matching both IPv4 and IPv6 addresses is split into one nftables
+// rule per family.
+func (d Nftables) aclRuleToNftRules(interfaceName string, rule ACLRule) []string {
+	args := []string{}
+	if rule.Direction == "ingress" {
+		args = append(args, fmt.Sprintf("oifname %q", interfaceName))
+	} else {
+		args = append(args, fmt.Sprintf("iifname %q", interfaceName))
+	}
+
+	protoArgs := d.aclRuleProtocolArgs(rule)
+
+	verdict := "accept"
+	if rule.Action == "reject" || rule.Action == "drop" {
+		verdict = rule.Action
+	}
+
+	// If the rule doesn't match on addresses, a single rule is enough for both families.
+	if !rule.hasSubjects() {
+		parts := append(append(args, protoArgs...), verdict)
+		return []string{strings.Join(parts, " ")}
+	}
+
+	nftRules := []string{}
+	for _, ipVersion := range []uint{4, 6} {
+		sources, destinations, ok := rule.subjectsForIPVersion(ipVersion)
+		if !ok {
+			continue
+		}
+
+		family, _ := d.getIPFamily(ipVersion)
+		parts := append([]string{}, args...)
+
+		if len(sources) > 0 {
+			parts = append(parts, fmt.Sprintf("%s saddr {%s}", family, strings.Join(sources, ", ")))
+		}
+
+		if len(destinations) > 0 {
+			parts = append(parts, fmt.Sprintf("%s daddr {%s}", family, strings.Join(destinations, ", ")))
+		}
+
+		parts = append(append(parts, protoArgs...), verdict)
+		nftRules = append(nftRules, strings.Join(parts, " "))
+	}
+
+	return nftRules
+}
+
+// aclRuleProtocolArgs returns the nftables protocol, port and ICMP matches for an ACL rule.
+func (d Nftables) aclRuleProtocolArgs(rule ACLRule) []string {
+	args := []string{}
+
+	switch rule.Protocol {
+	case "tcp", "udp":
+		if rule.SourcePort != "" {
+			args = append(args, fmt.Sprintf("%s sport {%s}", rule.Protocol, strings.Join(aclPorts(rule.SourcePort), ", ")))
+		}
+
+		if rule.DestinationPort != "" {
+			args = append(args, fmt.Sprintf("%s dport {%s}", rule.Protocol, strings.Join(aclPorts(rule.DestinationPort), ", ")))
+		}
+
+		if len(args) == 0 {
+			args = append(args, fmt.Sprintf("meta l4proto %s", rule.Protocol))
+		}
+	case "icmp4", "icmp6":
+		icmpProto := "icmp"
+		l4Proto := "icmp"
+		if rule.Protocol == "icmp6" {
+			icmpProto = "icmpv6"
+			l4Proto = "ipv6-icmp"
+		}
+
+		if rule.ICMPType != "" {
+			args = append(args, fmt.Sprintf("%s type %s", icmpProto, rule.ICMPType))
+		}
+
+		if rule.ICMPCode != "" {
+			args = append(args, fmt.Sprintf("%s code %s", icmpProto, rule.ICMPCode))
+		}
+
+		if len(args) == 0 {
+			args = append(args, fmt.Sprintf("meta l4proto %s", l4Proto))
+		}
+	}
+
+	return args
+}
+
 //instanceDeviceLabel returns the unique label used for instance device chains.
 func (d Nftables) instanceDeviceLabel(projectName, instanceName, deviceName string) string {
 	return fmt.Sprintf("%s%s%s", project.Instance(projectName, instanceName), nftablesChainSeparator, deviceName)
@@ -342,6 +465,63 @@ func (d Nftables) InstanceClearBridgeFilter(projectName string, instanceName str
 	return nil
 }
 
+// InstanceApplyACLRules applies the ACL rules to the traffic going through the bridge port of an instance device.
+// Rules are evaluated in order and an empty set of rules removes any existing ACL rules.
+func (d Nftables) InstanceApplyACLRules(projectName string, instanceName string, deviceName string, hostName string, rules []ACLRule) error {
+	deviceLabel := d.instanceDeviceLabel(projectName, instanceName, deviceName)
+
+	err := d.removeChains([]string{"bridge"}, deviceLabel, "aclin", "aclout")
+	if err != nil {
+		return errors.Wrapf(err, "Failed clearing ACL rules for instance device %q", deviceLabel)
+	}
+
+	if len(rules) == 0 {
+		return nil
+	}
+
+	ingressRules, egressRules := d.instanceACLRulesToNftRules(hostName, rules)
+
+	tplFields := map[string]interface{}{
+		"namespace":      nftablesNamespace,
+		"chainSeparator": nftablesChainSeparator,
+		"family":         "bridge",
+		"deviceLabel":    deviceLabel,
+		"hostName":       hostName,
+		"ingressRules":   ingressRules,
+		"egressRules":    egressRules,
+	}
+
+	err = d.applyNftConfig(nftablesInstanceACLSetup, tplFields)
+	if err != nil {
+		return errors.Wrapf(err, "Failed adding ACL rules for instance device %q", deviceLabel)
+	}
+
+	return nil
+}
+
+// instanceACLRulesToNftRules converts the ACL rules of an instance device into the nftables rules for the
+// traffic going to (ingress) and coming from (egress) its bridge port.
+func (d Nftables) instanceACLRulesToNftRules(hostName string, rules []ACLRule) ([]string, []string) {
+	ingressRules := []string{}
+	egressRules := []string{}
+
+	for _, rule := range rules {
+		if rule.Direction == "ingress" {
+			// The bridge family can only reject frames before they are forwarded, so ingress
+			// traffic that should be rejected is dropped instead.
+			if rule.Action == "reject" {
+				rule.Action = "drop"
+			}
+
+			ingressRules = append(ingressRules, d.aclRuleToNftRules(hostName, rule)...)
+		} else {
+			egressRules = append(egressRules, d.aclRuleToNftRules(hostName, rule)...)
+		}
+	}
+
+	return ingressRules, egressRules
+}
+
 // InstanceSetupProxyNAT creates DNAT rules for proxy devices.
 func (d Nftables) InstanceSetupProxyNAT(projectName string, instanceName string, deviceName string, listen, connect *deviceConfig.ProxyAddress) error {
 	connectAddrCount := len(connect.Addr)
@@ -18,6 +18,20 @@ chain fwd{{.chainSeparator}}{{.networkName}} {
 }
 `))
 
+// nftablesNetACLSetup defines the chain used to apply ACL rules to traffic forwarded to and from a network.
+// Replies to established connections are always allowed so that only the connection initiator's direction
+// needs to be allowed.
+var nftablesNetACLSetup = template.Must(template.New("nftablesNetACLSetup").Parse(`
+chain aclfwd{{.chainSeparator}}{{.networkName}} {
+	type filter hook forward priority 0; policy accept;
+	iifname "{{.networkName}}" ct state established,related accept
+	oifname "{{.networkName}}" ct state established,related accept
+	{{- range .rules}}
+	{{.}}
+	{{- end}}
+}
+`))
+
 var nftablesNetOutboundNAT = template.Must(template.New("nftablesNetOutboundNAT").Parse(`
 chain pstrt{{.chainSeparator}}{{.networkName}} {
 	type nat hook postrouting priority 100; policy accept;
@@ -141,6 +155,34 @@ chain fwd{{.chainSeparator}}{{.deviceLabel}} {
 }
 `))
 
+// nftablesInstanceACLSetup defines the chains used to apply ACL rules to the traffic going through the bridge port
+// of an instance device, whether it's bridged to another port or routed by the host. Replies to established
+// connections are always allowed, as well as the ARP, NDP and DHCP traffic needed for the instance to get on the
+// network.
+var nftablesInstanceACLSetup = template.Must(template.New("nftablesInstanceACLSetup").Parse(`
+chain aclin{{.chainSeparator}}{{.deviceLabel}} {
+	type filter hook prerouting priority 0; policy accept;
+	iifname "{{.hostName}}" ct state established,related accept
+	iifname "{{.hostName}}" ether type arp accept
+	iifname "{{.hostName}}" icmpv6 type {nd-router-solicit, nd-neighbor-solicit, nd-neighbor-advert} accept
+	iifname "{{.hostName}}" udp dport {67, 547} accept
+	{{- range .egressRules}}
+	{{.}}
+	{{- end}}
+}
+
+chain aclout{{.chainSeparator}}{{.deviceLabel}} {
+	type filter hook postrouting priority 0; policy accept;
+	oifname "{{.hostName}}" ct state established,related accept
+	oifname "{{.hostName}}" ether type arp accept
+	oifname "{{.hostName}}" icmpv6 type {nd-router-advert, nd-neighbor-solicit, nd-neighbor-advert} accept
+	oifname "{{.hostName}}" udp dport {68, 546} accept
+	{{- range .ingressRules}}
+	{{.}}
+	{{- end}}
+}
+`))
+
 // nftablesInstanceRPFilter defines the rules to perform reverse path filtering.
 var nftablesInstanceRPFilter = template.Must(template.New("nftablesInstanceRPFilter").Parse(`
 chain prert{{.chainSeparator}}{{.deviceLabel}} {
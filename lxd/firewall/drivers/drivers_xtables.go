@@ -258,6 +258,123 @@ func (d Xtables) NetworkClear(networkName string, ipVersion uint) error {
 	return nil
 }
 
+// NetworkApplyACLRules applies the ACL rules to traffic forwarded to and from the network.
+// Rules are evaluated in order and an empty set of rules removes any existing ACL rules.
+func (d Xtables) NetworkApplyACLRules(networkName string, rules []ACLRule) error {
+	comment := fmt.Sprintf("%s acl", d.networkIPTablesComment(networkName))
+
+	for _, ipVersion := range []uint{4, 6} {
+		err := d.iptablesClear(ipVersion, comment, "filter")
+		if err != nil {
+			return err
+		}
+
+		if len(rules) == 0 {
+			continue
+		}
+
+		// Always allow replies to established connections.
+		iptRules := [][]string{
+			{"-i", networkName, "-m", "conntrack", "--ctstate", "RELATED,ESTABLISHED", "-j", "ACCEPT"},
+			{"-o", networkName, "-m", "conntrack", "--ctstate", "RELATED,ESTABLISHED", "-j", "ACCEPT"},
+		}
+
+		seen := map[string]bool{}
+		for _, rule := range rules {
+			args, ok := d.aclRuleToIptablesArgs(ipVersion, rule, []string{"-o", networkName}, []string{"-i", networkName}, "ACCEPT")
+			if !ok {
+				continue
+			}
+
+			// Identical rules are only added once by iptablesAdd, so skip later duplicates here to
+			// keep the evaluation order intact.
+			key := strings.Join(args, " ")
+			if seen[key] {
+				continue
+			}
+
+			seen[key] = true
+			iptRules = append(iptRules, args)
+		}
+
+		// Prepend the rules in reverse order so that they end up in order before any other forwarding rules.
+		for i := len(iptRules) - 1; i >= 0; i-- {
+			err = d.iptablesPrepend(ipVersion, comment, "filter", "FORWARD", iptRules[i]...)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// aclRuleToIptablesArgs converts an ACL rule into iptables arguments for the specified IP version, using the
+// ingress or egress arguments to match the direction of the traffic and the allow target for allowed traffic.
+// The returned boolean is false if the rule cannot match any traffic of that IP version.
+func (d Xtables) aclRuleToIptablesArgs(ipVersion uint, rule ACLRule, ingressArgs []string, egressArgs []string, allowTarget string) ([]string, bool) {
+	sources, destinations, ok := rule.subjectsForIPVersion(ipVersion)
+	if !ok {
+		return nil, false
+	}
+
+	args := []string{}
+	if rule.Direction == "ingress" {
+		args = append(args, ingressArgs...)
+	} else {
+		args = append(args, egressArgs...)
+	}
+
+	if len(sources) > 0 {
+		args = append(args, "-s", strings.Join(sources, ","))
+	}
+
+	if len(destinations) > 0 {
+		args = append(args, "-d", strings.Join(destinations, ","))
+	}
+
+	switch rule.Protocol {
+	case "tcp", "udp":
+		args = append(args, "-p", rule.Protocol)
+
+		// The multiport module uses ":" as range separator.
+		if rule.SourcePort != "" {
+			args = append(args, "-m", "multiport", "--sports", strings.Replace(rule.SourcePort, "-", ":", -1))
+		}
+
+		if rule.DestinationPort != "" {
+			args = append(args, "-m", "multiport", "--dports", strings.Replace(rule.DestinationPort, "-", ":", -1))
+		}
+	case "icmp4", "icmp6":
+		proto := "icmp"
+		if rule.Protocol == "icmp6" {
+			proto = "icmpv6"
+		}
+
+		args = append(args, "-p", proto)
+
+		if rule.ICMPType != "" {
+			icmpType := rule.ICMPType
+			if rule.ICMPCode != "" {
+				icmpType = fmt.Sprintf("%s/%s", rule.ICMPType, rule.ICMPCode)
+			}
+
+			args = append(args, fmt.Sprintf("--%s-type", proto), icmpType)
+		}
+	}
+
+	switch rule.Action {
+	case "reject":
+		args = append(args, "-j", "REJECT")
+	case "drop":
+		args = append(args, "-j", "DROP")
+	default:
+		args = append(args, "-j", allowTarget)
+	}
+
+	return args, true
+}
+
 //instanceDeviceIPTablesComment returns the iptables comment that is added to each instance device related rule.
 func (d Xtables) instanceDeviceIPTablesComment(projectName string, instanceName string, deviceName string) string {
 	return fmt.Sprintf("LXD container %s (%s)", project.Instance(projectName, instanceName), deviceName)
@@ -348,6 +465,119 @@ func (d Xtables) InstanceClearBridgeFilter(projectName string, instanceName stri
 	return nil
 }
 
+// instanceDeviceACLIPTablesComment returns the iptables comment that is added to each instance device ACL rule.
+// It must not start with the comment of the other instance device rules, as those are cleared by comment prefix.
+func (d Xtables) instanceDeviceACLIPTablesComment(projectName string, instanceName string, deviceName string) string {
+	return fmt.Sprintf("LXD ACL for container %s (%s)", project.Instance(projectName, instanceName), deviceName)
+}
+
+// InstanceApplyACLRules applies the ACL rules to the traffic going through the bridge port of an instance device.
+// Rules are evaluated in order and an empty set of rules removes any existing ACL rules.
+// The rules are added to a chain of their own, in which allowed traffic returns to the calling chain so that it
+// still goes through the rules of the network. Bridged traffic only goes through iptables with br_netfilter, and
+// traffic routed by the host to the instance can't be matched on its bridge port, so ingress rules only apply to
+// the traffic bridged from the other ports.
+func (d Xtables) InstanceApplyACLRules(projectName string, instanceName string, deviceName string, hostName string, rules []ACLRule) error {
+	comment := d.instanceDeviceACLIPTablesComment(projectName, instanceName, deviceName)
+	chain := fmt.Sprintf("lxdacl-%s", hostName)
+
+	for _, ipVersion := range []uint{4, 6} {
+		// Remove the jumps to the chain before the chain itself.
+		err := d.iptablesClear(ipVersion, comment, "filter")
+		if err != nil {
+			return err
+		}
+
+		err = d.iptablesChainDelete(ipVersion, "filter", chain)
+		if err != nil {
+			return err
+		}
+
+		if len(rules) == 0 {
+			continue
+		}
+
+		err = d.iptablesChainCreate(ipVersion, "filter", chain)
+		if err != nil {
+			return err
+		}
+
+		for _, args := range d.instanceACLRulesToIptablesArgs(hostName, ipVersion, rules) {
+			err = d.iptablesAdd(ipVersion, comment, "filter", "-A", chain, args...)
+			if err != nil {
+				return err
+			}
+		}
+
+		jumps := [][]string{
+			{"INPUT", "-m", "physdev", "--physdev-in", hostName, "-j", chain},
+			{"FORWARD", "-m", "physdev", "--physdev-in", hostName, "-j", chain},
+			{"FORWARD", "-m", "physdev", "--physdev-out", hostName, "--physdev-is-bridged", "-j", chain},
+		}
+
+		for _, jump := range jumps {
+			err = d.iptablesPrepend(ipVersion, comment, "filter", jump[0], jump[1:]...)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// instanceACLRulesToIptablesArgs converts the ACL rules of an instance device into the iptables arguments of the
+// rules of its chain for the specified IP version. Replies to established connections are always allowed, as well
+// as the NDP and DHCP traffic needed for the instance to get on the network.
+func (d Xtables) instanceACLRulesToIptablesArgs(hostName string, ipVersion uint, rules []ACLRule) [][]string {
+	ingressArgs := []string{"-m", "physdev", "--physdev-out", hostName}
+	egressArgs := []string{"-m", "physdev", "--physdev-in", hostName}
+
+	iptRules := [][]string{
+		{"-m", "conntrack", "--ctstate", "RELATED,ESTABLISHED", "-j", "RETURN"},
+	}
+
+	if ipVersion == 4 {
+		iptRules = append(iptRules,
+			append(append([]string{}, egressArgs...), "-p", "udp", "--dport", "67", "-j", "RETURN"),
+			append(append([]string{}, ingressArgs...), "-p", "udp", "--dport", "68", "-j", "RETURN"),
+		)
+	} else {
+		for _, icmpType := range []string{"133", "135", "136"} {
+			iptRules = append(iptRules, append(append([]string{}, egressArgs...), "-p", "icmpv6", "--icmpv6-type", icmpType, "-j", "RETURN"))
+		}
+
+		for _, icmpType := range []string{"134", "135", "136"} {
+			iptRules = append(iptRules, append(append([]string{}, ingressArgs...), "-p", "icmpv6", "--icmpv6-type", icmpType, "-j", "RETURN"))
+		}
+
+		iptRules = append(iptRules,
+			append(append([]string{}, egressArgs...), "-p", "udp", "--dport", "547", "-j", "RETURN"),
+			append(append([]string{}, ingressArgs...), "-p", "udp", "--dport", "546", "-j", "RETURN"),
+		)
+	}
+
+	seen := map[string]bool{}
+	for _, rule := range rules {
+		args, ok := d.aclRuleToIptablesArgs(ipVersion, rule, ingressArgs, egressArgs, "RETURN")
+		if !ok {
+			continue
+		}
+
+		// Identical rules are only added once by iptablesAdd, so skip later duplicates here to keep the
+		// evaluation order intact.
+		key := strings.Join(args, " ")
+		if seen[key] {
+			continue
+		}
+
+		seen[key] = true
+		iptRules = append(iptRules, args)
+	}
+
+	return iptRules
+}
+
 // InstanceSetupProxyNAT creates DNAT rules for proxy devices.
 func (d Xtables) InstanceSetupProxyNAT(projectName string, instanceName string, deviceName string, listen *deviceConfig.ProxyAddress, connect *deviceConfig.ProxyAddress) error {
 	connectAddrCount := len(connect.Addr)
@@ -621,6 +851,47 @@ func (d Xtables) iptablesPrepend(ipVersion uint, comment string, table string, c
 	return d.iptablesAdd(ipVersion, comment, table, "-I", chain, rule...)
 }
 
+// iptablesChainCreate creates a chain in the specified table.
+func (d Xtables) iptablesChainCreate(ipVersion uint, table string, chain string) error {
+	cmd := "iptables"
+	if ipVersion == 6 {
+		cmd = "ip6tables"
+	}
+
+	_, err := shared.RunCommand(cmd, "-w", "-t", table, "-N", chain)
+	if err != nil {
+		return fmt.Errorf("Failed creating IPv%d chain %q (table %s): %v", ipVersion, chain, table, err)
+	}
+
+	return nil
+}
+
+// iptablesChainDelete flushes and deletes a chain from the specified table if it exists.
+func (d Xtables) iptablesChainDelete(ipVersion uint, table string, chain string) error {
+	cmd := "iptables"
+	if ipVersion == 6 {
+		cmd = "ip6tables"
+	}
+
+	// Check whether the chain exists.
+	_, err := shared.RunCommand(cmd, "-w", "-t", table, "-S", chain)
+	if err != nil {
+		return nil
+	}
+
+	_, err = shared.RunCommand(cmd, "-w", "-t", table, "-F", chain)
+	if err != nil {
+		return fmt.Errorf("Failed flushing IPv%d chain %q (table %s): %v", ipVersion, chain, table, err)
+	}
+
+	_, err = shared.RunCommand(cmd, "-w", "-t", table, "-X", chain)
+	if err != nil {
+		return fmt.Errorf("Failed deleting IPv%d chain %q (table %s): %v", ipVersion, chain, table, err)
+	}
+
+	return nil
+}
+
 // iptablesClear clears iptables rules matching the supplied comment in the specified tables.
 func (d Xtables) iptablesClear(ipVersion uint, comment string, fromTables ...string) error {
 	var cmd string
@@ -1231,11 +1231,19 @@ func autoUpdateImage(d *Daemon, op *operations.Operation, id int, info *api.Imag
 
 	// Update the image on each pool where it currently exists.
 	hash := fingerprint
+	downloadFailed := false
 
 	for _, poolName := range poolNames {
 		newInfo, err := d.ImageDownload(op, source.Server, source.Protocol, source.Certificate, "", source.Alias, info.Type, false, true, poolName, false, project, -1)
 		if err != nil {
 			logger.Error("Failed to update the image", log.Ctx{"err": err, "fp": fingerprint})
+			downloadFailed = true
+
+			err = d.cluster.UpsertWarningLocalNode(project, db.EntityTypeImage, id, db.WarningTypeImageAutoUpdateFailed, err.Error())
+			if err != nil {
+				logger.Warn("Failed to create warning", log.Ctx{"err": err})
+			}
+
 			continue
 		}
 
@@ -1285,6 +1293,13 @@ func autoUpdateImage(d *Daemon, op *operations.Operation, id int, info *api.Imag
 		}
 	}
 
+	if !downloadFailed {
+		err = d.cluster.ResolveWarningsByLocalNodeAndEntity(db.WarningTypeImageAutoUpdateFailed, db.EntityTypeImage, id)
+		if err != nil {
+			logger.Warn("Failed to resolve warning", log.Ctx{"err": err})
+		}
+	}
+
 	// Image didn't change, nothing to do.
 	if hash == fingerprint {
 		setRefreshResult(false)
@@ -1623,6 +1638,15 @@ func imageDeleteFromDisk(fingerprint string) {
 			logger.Errorf("Error deleting image file %s: %s", fname, err)
 		}
 	}
+
+	// Remove the deltas published for the image.
+	fnames, _ := filepath.Glob(shared.VarPath("images", fingerprint) + ".delta*")
+	for _, fname := range fnames {
+		err := os.Remove(fname)
+		if err != nil && !os.IsNotExist(err) {
+			logger.Errorf("Error deleting image file %s: %s", fname, err)
+		}
+	}
 }
 
 func doImageGet(db *db.Cluster, project, fingerprint string, public bool) (*api.Image, response.Response) {
@@ -0,0 +1,495 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/lxd/project"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/task"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/simplestreams"
+)
+
+// apiSimpleStreams lists the endpoints of the simplestreams feed, served under /streams.
+var apiSimpleStreams = []APIEndpoint{
+	simpleStreamsIndexCmd,
+	simpleStreamsImagesCmd,
+	simpleStreamsImageFileCmd,
+}
+
+var simpleStreamsIndexCmd = APIEndpoint{
+	Path: "v1/index.json",
+
+	Get: APIEndpointAction{Handler: simpleStreamsIndexGet, AllowUntrusted: true},
+}
+
+var simpleStreamsImagesCmd = APIEndpoint{
+	Path: "v1/images.json",
+
+	Get: APIEndpointAction{Handler: simpleStreamsImagesGet, AllowUntrusted: true},
+}
+
+var simpleStreamsImageFileCmd = APIEndpoint{
+	Path: "images/{fingerprint}/{file}",
+
+	Get: APIEndpointAction{Handler: simpleStreamsImageFileGet, AllowUntrusted: true},
+}
+
+// simpleStreamsHash is a cached SHA-256 of a published image file.
+type simpleStreamsHash struct {
+	size    int64
+	modTime time.Time
+	sha256  string
+}
+
+// simpleStreamsHashes caches the SHA-256 of the published image files (indexed by path) as hashing the root
+// file of every image on each request would be far too slow.
+var simpleStreamsHashes = map[string]simpleStreamsHash{}
+var simpleStreamsHashesLock sync.Mutex
+
+// simpleStreamsEnabled returns whether the public images are published as a simplestreams feed.
+func simpleStreamsEnabled(d *Daemon) (bool, error) {
+	var enabled bool
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		config, err := cluster.ConfigLoad(tx)
+		if err != nil {
+			return err
+		}
+
+		enabled = config.ImagesSimpleStreamsServer()
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return enabled, nil
+}
+
+// simpleStreamsFileHash returns the SHA-256 of the given file, from the cache if the file didn't change since
+// it was last hashed.
+func simpleStreamsFileHash(path string, info os.FileInfo) (string, error) {
+	simpleStreamsHashesLock.Lock()
+	cached, ok := simpleStreamsHashes[path]
+	simpleStreamsHashesLock.Unlock()
+
+	if ok && cached.size == info.Size() && cached.modTime.Equal(info.ModTime()) {
+		return cached.sha256, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	_, err = io.Copy(hash, f)
+	if err != nil {
+		return "", err
+	}
+
+	sum := fmt.Sprintf("%x", hash.Sum(nil))
+
+	simpleStreamsHashesLock.Lock()
+	simpleStreamsHashes[path] = simpleStreamsHash{size: info.Size(), modTime: info.ModTime(), sha256: sum}
+	simpleStreamsHashesLock.Unlock()
+
+	return sum, nil
+}
+
+// simpleStreamsRootType returns the simplestreams file type of the root file of a split image.
+func simpleStreamsRootType(image *api.Image, rootPath string) (string, error) {
+	if shared.StringInSlice(image.Properties["type"], []string{"disk1.img", "disk-kvm.img", "uefi1.img", "root.tar.xz", "squashfs"}) {
+		return image.Properties["type"], nil
+	}
+
+	f, err := os.Open(rootPath)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	magic := make([]byte, 4)
+	_, err = io.ReadFull(f, magic)
+	if err != nil {
+		return "", err
+	}
+
+	switch string(magic) {
+	case "hsqs":
+		return "squashfs", nil
+	case "QFI\xfb":
+		return "disk-kvm.img", nil
+	}
+
+	return "root.tar.xz", nil
+}
+
+// simpleStreamsImages returns the public images of the default project which are stored on this server along
+// with their published files, as expected by simplestreams.ProductsFromLXD.
+func simpleStreamsImages(d *Daemon) ([]api.Image, map[string]map[string]simplestreams.DownloadableFile, error) {
+	fingerprints, err := d.cluster.GetImagesFingerprints(project.Default, true)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "Failed loading public images")
+	}
+
+	images := []api.Image{}
+	files := map[string]map[string]simplestreams.DownloadableFile{}
+	seen := map[string]bool{}
+
+	for _, fingerprint := range fingerprints {
+		_, image, err := d.cluster.GetImage(project.Default, fingerprint, true)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "Failed loading image %q", fingerprint)
+		}
+
+		// Skip images whose files aren't stored on this server.
+		metaPath := shared.VarPath("images", fingerprint)
+		metaInfo, err := os.Stat(metaPath)
+		if err != nil {
+			continue
+		}
+
+		imgFiles := map[string]simplestreams.DownloadableFile{}
+		addFile := func(fileType string, path string, info os.FileInfo) error {
+			sum, err := simpleStreamsFileHash(path, info)
+			if err != nil {
+				return errors.Wrapf(err, "Failed hashing %q", path)
+			}
+
+			imgFiles[fileType] = simplestreams.DownloadableFile{
+				Path:   fmt.Sprintf("streams/images/%s/%s", fingerprint, fileType),
+				Sha256: sum,
+				Size:   info.Size(),
+			}
+
+			seen[path] = true
+			return nil
+		}
+
+		err = addFile("meta", metaPath, metaInfo)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		rootPath := metaPath + ".rootfs"
+		rootInfo, err := os.Stat(rootPath)
+		if err == nil {
+			err = addFile("root", rootPath, rootInfo)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			// Tell the feed what the root file actually is.
+			rootType, err := simpleStreamsRootType(image, rootPath)
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, "Failed detecting root file type of image %q", fingerprint)
+			}
+
+			if image.Properties == nil {
+				image.Properties = map[string]string{}
+			}
+
+			image.Properties["type"] = rootType
+
+			deltaPaths, err := filepath.Glob(metaPath + ".delta-*")
+			if err != nil {
+				return nil, nil, err
+			}
+
+			for _, deltaPath := range deltaPaths {
+				deltaInfo, err := os.Stat(deltaPath)
+				if err != nil {
+					continue
+				}
+
+				srcFingerprint := strings.TrimPrefix(filepath.Base(deltaPath), fingerprint+".delta-")
+				err = addFile(fmt.Sprintf("root.delta-%s", srcFingerprint), deltaPath, deltaInfo)
+				if err != nil {
+					return nil, nil, err
+				}
+			}
+		}
+
+		images = append(images, *image)
+		files[fingerprint] = imgFiles
+	}
+
+	// Drop the cached hashes of files which aren't published anymore.
+	simpleStreamsHashesLock.Lock()
+	for path := range simpleStreamsHashes {
+		if !seen[path] {
+			delete(simpleStreamsHashes, path)
+		}
+	}
+	simpleStreamsHashesLock.Unlock()
+
+	return images, files, nil
+}
+
+// simpleStreamsProducts returns the products data of the simplestreams feed.
+func simpleStreamsProducts(d *Daemon) (*simplestreams.Products, error) {
+	images, files, err := simpleStreamsImages(d)
+	if err != nil {
+		return nil, err
+	}
+
+	products := simplestreams.ProductsFromLXD(images, files)
+	products.Updated = time.Now().UTC().Format(time.RFC1123Z)
+
+	return products, nil
+}
+
+// /streams/v1/index.json
+// Get the simplestreams index of the published images.
+func simpleStreamsIndexGet(d *Daemon, r *http.Request) response.Response {
+	enabled, err := simpleStreamsEnabled(d)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if !enabled {
+		return response.NotFound(nil)
+	}
+
+	products, err := simpleStreamsProducts(d)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	productNames := make([]string, 0, len(products.Products))
+	for name := range products.Products {
+		productNames = append(productNames, name)
+	}
+
+	sort.Strings(productNames)
+
+	stream := simplestreams.Stream{
+		Index: map[string]simplestreams.StreamIndex{
+			"images": {
+				DataType: products.DataType,
+				Path:     "streams/v1/images.json",
+				Updated:  products.Updated,
+				Products: productNames,
+				Format:   products.Format,
+			},
+		},
+		Updated: products.Updated,
+		Format:  "index:1.0",
+	}
+
+	return response.ManualResponse(func(w http.ResponseWriter) error {
+		return json.NewEncoder(w).Encode(stream)
+	})
+}
+
+// /streams/v1/images.json
+// Get the simplestreams products of the published images.
+func simpleStreamsImagesGet(d *Daemon, r *http.Request) response.Response {
+	enabled, err := simpleStreamsEnabled(d)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if !enabled {
+		return response.NotFound(nil)
+	}
+
+	products, err := simpleStreamsProducts(d)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.ManualResponse(func(w http.ResponseWriter) error {
+		return json.NewEncoder(w).Encode(products)
+	})
+}
+
+// /streams/images/{fingerprint}/{file}
+// Download one of the files of a published image.
+func simpleStreamsImageFileGet(d *Daemon, r *http.Request) response.Response {
+	fingerprint := mux.Vars(r)["fingerprint"]
+	file := mux.Vars(r)["file"]
+
+	enabled, err := simpleStreamsEnabled(d)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if !enabled {
+		return response.NotFound(nil)
+	}
+
+	_, image, err := d.cluster.GetImage(project.Default, fingerprint, true)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Only serve full fingerprints so the paths of the feed are the only valid ones.
+	if image.Fingerprint != fingerprint {
+		return response.NotFound(nil)
+	}
+
+	var path string
+	switch {
+	case file == "meta":
+		path = shared.VarPath("images", fingerprint)
+	case file == "root":
+		path = shared.VarPath("images", fingerprint+".rootfs")
+	case strings.HasPrefix(file, "root.delta-"):
+		path = shared.VarPath("images", fmt.Sprintf("%s.delta-%s", fingerprint, strings.TrimPrefix(file, "root.delta-")))
+	default:
+		return response.NotFound(nil)
+	}
+
+	if !shared.PathExists(path) {
+		return response.NotFound(nil)
+	}
+
+	files := []response.FileResponseEntry{{Path: path, Filename: file}}
+	return response.FileResponse(r, files, nil, false)
+}
+
+// simpleStreamsDeltasTask periodically generates the deltas between consecutive versions of the published
+// squashfs images.
+func simpleStreamsDeltasTask(d *Daemon) (task.Func, task.Schedule) {
+	f := func(ctx context.Context) {
+		enabled, err := simpleStreamsEnabled(d)
+		if err != nil {
+			logger.Error("Failed to load the simplestreams server configuration", log.Ctx{"err": err})
+			return
+		}
+
+		// Deltas are only useful to the feed and require xdelta3.
+		if !enabled {
+			return
+		}
+
+		_, err = exec.LookPath("xdelta3")
+		if err != nil {
+			return
+		}
+
+		opRun := func(op *operations.Operation) error {
+			return simpleStreamsGenerateDeltas(ctx, d)
+		}
+
+		op, err := operations.OperationCreate(d.State(), "", operations.OperationClassTask, db.OperationImagesGenerateDeltas, nil, nil, opRun, nil, nil)
+		if err != nil {
+			logger.Error("Failed to start image deltas operation", log.Ctx{"err": err})
+			return
+		}
+
+		logger.Info("Generating image deltas")
+		_, err = op.Run()
+		if err != nil {
+			logger.Error("Failed to generate image deltas", log.Ctx{"err": err})
+		}
+		logger.Info("Done generating image deltas")
+	}
+
+	return f, task.Every(time.Hour)
+}
+
+// simpleStreamsGenerateDeltas generates the missing deltas from the previous version of each published squashfs
+// image and removes the deltas which don't match the current versions anymore.
+func simpleStreamsGenerateDeltas(ctx context.Context, d *Daemon) error {
+	images, files, err := simpleStreamsImages(d)
+	if err != nil {
+		return err
+	}
+
+	products := simplestreams.ProductsFromLXD(images, files)
+
+	// Figure out the source image of the delta of each image.
+	deltas := map[string]string{}
+	for _, product := range products.Products {
+		versionNames := make([]string, 0, len(product.Versions))
+		for name := range product.Versions {
+			versionNames = append(versionNames, name)
+		}
+
+		sort.Strings(versionNames)
+
+		for i := 1; i < len(versionNames); i++ {
+			srcFingerprint := product.Versions[versionNames[i-1]].Items["lxd.tar.xz"].LXDHashSha256SquashFs
+			fingerprint := product.Versions[versionNames[i]].Items["lxd.tar.xz"].LXDHashSha256SquashFs
+			if srcFingerprint == "" || fingerprint == "" {
+				continue
+			}
+
+			deltas[fingerprint] = srcFingerprint
+		}
+	}
+
+	// Remove the stale deltas.
+	deltaPaths, err := filepath.Glob(shared.VarPath("images", "*.delta-*"))
+	if err != nil {
+		return err
+	}
+
+	for _, deltaPath := range deltaPaths {
+		fields := strings.SplitN(filepath.Base(deltaPath), ".delta-", 2)
+		if deltas[fields[0]] == fields[1] {
+			continue
+		}
+
+		err = os.Remove(deltaPath)
+		if err != nil {
+			return errors.Wrapf(err, "Failed removing stale image delta %q", deltaPath)
+		}
+	}
+
+	// Generate the missing deltas.
+	for fingerprint, srcFingerprint := range deltas {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		deltaPath := shared.VarPath("images", fmt.Sprintf("%s.delta-%s", fingerprint, srcFingerprint))
+		if shared.PathExists(deltaPath) {
+			continue
+		}
+
+		srcPath := shared.VarPath("images", srcFingerprint+".rootfs")
+		path := shared.VarPath("images", fingerprint+".rootfs")
+
+		// Write to a temporary file so the feed never publishes a partial delta.
+		tmpPath := shared.VarPath("images", fingerprint+".delta.tmp")
+		_, err = shared.RunCommand("xdelta3", "-f", "-e", "-s", srcPath, path, tmpPath)
+		if err != nil {
+			os.Remove(tmpPath)
+			return errors.Wrapf(err, "Failed generating delta of image %q from image %q", fingerprint, srcFingerprint)
+		}
+
+		err = os.Rename(tmpPath, deltaPath)
+		if err != nil {
+			os.Remove(tmpPath)
+			return err
+		}
+	}
+
+	return nil
+}
@@ -344,13 +344,23 @@ func instanceCreateAsSnapshot(s *state.State, args db.InstanceArgs, sourceInstan
 			return nil, fmt.Errorf("Unable to create a stateful snapshot. The instance isn't running")
 		}
 
-		_, err := exec.LookPath("criu")
-		if err != nil {
-			return nil, fmt.Errorf("Unable to create a stateful snapshot. CRIU isn't installed")
+		if sourceInstance.Type() == instancetype.Container {
+			_, err := exec.LookPath("criu")
+			if err != nil {
+				return nil, fmt.Errorf("Unable to create a stateful snapshot. CRIU isn't installed")
+			}
+		} else {
+			// Keep the VM paused until the snapshot has been taken so
+			// that its disks match the saved memory state.
+			err := sourceInstance.Freeze()
+			if err != nil {
+				return nil, err
+			}
+			defer sourceInstance.Unfreeze()
 		}
 
 		stateDir := sourceInstance.StatePath()
-		err = os.MkdirAll(stateDir, 0700)
+		err := os.MkdirAll(stateDir, 0700)
 		if err != nil {
 			return nil, err
 		}
@@ -654,8 +664,8 @@ func instanceConfigureInternal(state *state.State, c instance.Instance) error {
 		return errors.Wrap(err, "Load instance storage pool")
 	}
 
-	if rootDiskDevice["size"] != "" {
-		err = pool.SetInstanceQuota(c, rootDiskDevice["size"], nil)
+	if rootDiskDevice["size"] != "" || rootDiskDevice["size.state"] != "" {
+		err = pool.SetInstanceQuota(c, rootDiskDevice["size"], rootDiskDevice["size.state"], nil)
 
 		// If the storage driver can't set the quota now, store in volatile.
 		if err == storagePools.ErrRunningQuotaResizeNotSupported {
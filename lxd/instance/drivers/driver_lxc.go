@@ -38,6 +38,7 @@ import (
 	"github.com/lxc/lxd/lxd/instance/instancetype"
 	"github.com/lxc/lxd/lxd/instance/operationlock"
 	"github.com/lxc/lxd/lxd/maas"
+	"github.com/lxc/lxd/lxd/metrics"
 	"github.com/lxc/lxd/lxd/network"
 	"github.com/lxc/lxd/lxd/operations"
 	"github.com/lxc/lxd/lxd/project"
@@ -1045,6 +1046,43 @@ func (c *lxc) initLXC(config bool) error {
 		}
 	}
 
+	// Setup the command, working directory and environment recorded by OCI images
+	if c.expandedConfig["image.oci.entrypoint"] != "" {
+		err = lxcSetConfigItem(cc, "lxc.init.cmd", c.expandedConfig["image.oci.entrypoint"])
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.expandedConfig["image.oci.cwd"] != "" {
+		if !util.RuntimeLiblxcVersionAtLeast(2, 1, 0) {
+			return fmt.Errorf("Setting the working directory of OCI images requires liblxc >= 2.1")
+		}
+
+		err = lxcSetConfigItem(cc, "lxc.init.cwd", c.expandedConfig["image.oci.cwd"])
+		if err != nil {
+			return err
+		}
+	}
+
+	for k, v := range c.expandedConfig {
+		if !strings.HasPrefix(k, "image.oci.env.") {
+			continue
+		}
+
+		// Environment variables set on the instance take precedence.
+		name := strings.TrimPrefix(k, "image.oci.env.")
+		_, ok := c.expandedConfig[fmt.Sprintf("environment.%s", name)]
+		if ok {
+			continue
+		}
+
+		err = lxcSetConfigItem(cc, "lxc.environment", fmt.Sprintf("%s=%s", name, v))
+		if err != nil {
+			return err
+		}
+	}
+
 	// Setup environment
 	for k, v := range c.expandedConfig {
 		if strings.HasPrefix(k, "environment.") {
@@ -1770,6 +1808,11 @@ func (c *lxc) deviceVolatileSetFunc(devName string) func(save map[string]string)
 			volatileSave[fmt.Sprintf("volatile.%s.%s", devName, k)] = v
 		}
 
+		// Don't overwrite the config of the copy running on another cluster member.
+		if instance.IsMovedAway(c.state, c) {
+			return nil
+		}
+
 		return c.VolatileSet(volatileSave)
 	}
 }
@@ -1780,12 +1823,12 @@ func (c *lxc) deviceResetVolatile(devName string, oldConfig, newConfig deviceCon
 	volatileClear := make(map[string]string)
 	devicePrefix := fmt.Sprintf("volatile.%s.", devName)
 
-	newNICType, err := nictype.NICType(c.state, newConfig)
+	newNICType, err := nictype.NICType(c.state, c.Project(), newConfig)
 	if err != nil {
 		return err
 	}
 
-	oldNICType, err := nictype.NICType(c.state, oldConfig)
+	oldNICType, err := nictype.NICType(c.state, c.Project(), oldConfig)
 	if err != nil {
 		return err
 	}
@@ -2861,10 +2904,15 @@ func (c *lxc) onStop(args map[string]string) error {
 		logger.Info(fmt.Sprintf("Container initiated %s", target), ctxMap)
 	}
 
+	// Leave the instance state alone if it is now running on another cluster member
+	movedAway := instance.IsMovedAway(c.state, c)
+
 	// Record power state
-	err = c.state.Cluster.UpdateInstancePowerState(c.id, "STOPPED")
-	if err != nil {
-		logger.Error("Failed to set container state", log.Ctx{"container": c.Name(), "err": err})
+	if !movedAway {
+		err = c.state.Cluster.UpdateInstancePowerState(c.id, "STOPPED")
+		if err != nil {
+			logger.Error("Failed to set container state", log.Ctx{"container": c.Name(), "err": err})
+		}
 	}
 
 	go func(c *lxc, target string, op *operationlock.InstanceOperation) {
@@ -2897,6 +2945,11 @@ func (c *lxc) onStop(args map[string]string) error {
 			logger.Error("Unable to remove disk devices", log.Ctx{"container": c.Name(), "err": err})
 		}
 
+		// Nothing else to do for the local copy of a container running on another cluster member
+		if movedAway {
+			return
+		}
+
 		// Log and emit lifecycle if not user triggered
 		if op == nil {
 			logger.Info("Shut down container", ctxMap)
@@ -3231,6 +3284,100 @@ func (c *lxc) RenderState() (*api.InstanceState, error) {
 	return &status, nil
 }
 
+// Metrics returns the metrics of the running container.
+func (c *lxc) Metrics() (*metrics.MetricSet, error) {
+	if !c.IsRunning() {
+		return nil, instance.ErrInstanceIsStopped
+	}
+
+	out := metrics.NewMetricSet(map[string]string{"project": c.project, "name": c.name, "type": instancetype.Container.String()})
+
+	cg, err := c.cgroup(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// CPU usage, reported in nanoseconds.
+	cpu := c.cpuState()
+	if cpu.Usage > 0 {
+		out.AddSamples(metrics.CPUSecondsTotal, metrics.Sample{Value: float64(cpu.Usage) / 1000000000})
+	}
+
+	// Memory usage.
+	memory := c.memoryState()
+	out.AddSamples(metrics.MemoryUsageBytes, metrics.Sample{Value: float64(memory.Usage)})
+
+	if memory.UsagePeak > 0 {
+		out.AddSamples(metrics.MemoryUsagePeakBytes, metrics.Sample{Value: float64(memory.UsagePeak)})
+	}
+
+	if memory.SwapUsage > 0 {
+		out.AddSamples(metrics.MemorySwapUsageBytes, metrics.Sample{Value: float64(memory.SwapUsage)})
+	}
+
+	if c.state.OS.CGInfo.Supports(cgroup.Memory, cg) {
+		memoryStats, err := cg.GetMemoryStats()
+		if err != nil {
+			logger.Warn("Failed to get memory stats", log.Ctx{"project": c.Project(), "instance": c.Name(), "err": err})
+		} else {
+			// The keys differ between cgroup V1 (cache/rss) and V2 (file/anon).
+			for _, key := range []string{"cache", "file"} {
+				value, ok := memoryStats[key]
+				if ok {
+					out.AddSamples(metrics.MemoryCachedBytes, metrics.Sample{Value: float64(value)})
+					break
+				}
+			}
+
+			for _, key := range []string{"rss", "anon"} {
+				value, ok := memoryStats[key]
+				if ok {
+					out.AddSamples(metrics.MemoryRSSBytes, metrics.Sample{Value: float64(value)})
+					break
+				}
+			}
+		}
+	}
+
+	// Disk I/O.
+	if c.state.OS.CGInfo.Supports(cgroup.Blkio, cg) {
+		ioStats, err := cg.GetIOStats()
+		if err != nil {
+			logger.Warn("Failed to get I/O stats", log.Ctx{"project": c.Project(), "instance": c.Name(), "err": err})
+		} else {
+			for device, stats := range ioStats {
+				labels := map[string]string{"device": device}
+				out.AddSamples(metrics.DiskReadBytesTotal, metrics.Sample{Value: float64(stats.ReadBytes), Labels: labels})
+				out.AddSamples(metrics.DiskReadsCompletedTotal, metrics.Sample{Value: float64(stats.ReadsCompleted), Labels: labels})
+				out.AddSamples(metrics.DiskWrittenBytesTotal, metrics.Sample{Value: float64(stats.WrittenBytes), Labels: labels})
+				out.AddSamples(metrics.DiskWritesCompletedTotal, metrics.Sample{Value: float64(stats.WritesCompleted), Labels: labels})
+			}
+		}
+	}
+
+	// Disk usage.
+	for name, disk := range c.diskState() {
+		out.AddSamples(metrics.FilesystemUsageBytes, metrics.Sample{Value: float64(disk.Usage), Labels: map[string]string{"device": name}})
+	}
+
+	// Network.
+	for name, network := range c.networkState() {
+		labels := map[string]string{"device": name}
+		out.AddSamples(metrics.NetworkReceiveBytesTotal, metrics.Sample{Value: float64(network.Counters.BytesReceived), Labels: labels})
+		out.AddSamples(metrics.NetworkReceivePacketsTotal, metrics.Sample{Value: float64(network.Counters.PacketsReceived), Labels: labels})
+		out.AddSamples(metrics.NetworkTransmitBytesTotal, metrics.Sample{Value: float64(network.Counters.BytesSent), Labels: labels})
+		out.AddSamples(metrics.NetworkTransmitPacketsTotal, metrics.Sample{Value: float64(network.Counters.PacketsSent), Labels: labels})
+	}
+
+	// Processes.
+	processes := c.processesState()
+	if processes >= 0 {
+		out.AddSamples(metrics.ProcsTotal, metrics.Sample{Value: float64(processes)})
+	}
+
+	return out, nil
+}
+
 // Snapshots returns the snapshots of the instance.
 func (c *lxc) Snapshots() ([]instance.Instance, error) {
 	var snaps []db.Instance
@@ -3991,12 +4138,12 @@ func (c *lxc) Update(args db.InstanceArgs, userRequested bool) error {
 		// devices are otherwise identical except for the fields returned here, then the
 		// device is considered to be being "updated" rather than "added & removed".
 
-		oldNICType, err := nictype.NICType(c.state, newDevice)
+		oldNICType, err := nictype.NICType(c.state, c.Project(), newDevice)
 		if err != nil {
 			return []string{} // Cannot hot-update due to config error.
 		}
 
-		newNICType, err := nictype.NICType(c.state, oldDevice)
+		newNICType, err := nictype.NICType(c.state, c.Project(), oldDevice)
 		if err != nil {
 			return []string{} // Cannot hot-update due to config error.
 		}
@@ -6402,7 +6549,7 @@ func (c *lxc) FillNetworkDevice(name string, m deviceConfig.Device) (deviceConfi
 		return nil
 	}
 
-	nicType, err := nictype.NICType(c.state, m)
+	nicType, err := nictype.NICType(c.state, c.Project(), m)
 	if err != nil {
 		return nil, err
 	}
@@ -17,6 +17,7 @@ import (
 	"sync"
 	"text/template"
 	"time"
+	"unsafe"
 
 	"github.com/flosch/pongo2"
 	"github.com/gorilla/websocket"
@@ -24,6 +25,7 @@ import (
 	"github.com/pborman/uuid"
 	"github.com/pkg/errors"
 	"golang.org/x/sys/unix"
+	liblxc "gopkg.in/lxc/go-lxc.v2"
 	"gopkg.in/yaml.v2"
 
 	lxdClient "github.com/lxc/lxd/client"
@@ -39,6 +41,7 @@ import (
 	"github.com/lxc/lxd/lxd/instance/instancetype"
 	"github.com/lxc/lxd/lxd/instance/operationlock"
 	"github.com/lxc/lxd/lxd/maas"
+	"github.com/lxc/lxd/lxd/metrics"
 	"github.com/lxc/lxd/lxd/network"
 	"github.com/lxc/lxd/lxd/operations"
 	"github.com/lxc/lxd/lxd/project"
@@ -67,6 +70,9 @@ const qemuUnsafeIO = "unsafeio"
 // qemuSerialChardevName is used to communicate state via qmp between Qemu and LXD.
 const qemuSerialChardevName = "qemu_serial-chardev"
 
+// qemuMigrationSocket is the name of the socket qemu sends its migration state to.
+const qemuMigrationSocket = "migration.sock"
+
 var errQemuAgentOffline = fmt.Errorf("LXD VM agent isn't currently running")
 
 var vmConsole = map[int]bool{}
@@ -510,12 +516,27 @@ func (vm *qemu) onStop(target string) error {
 		return fmt.Errorf("Instance is already running a %s operation", op.Action())
 	}
 
+	// If the instance is now running on another cluster member, only release the local resources.
+	// The devices are then cleaned up by Stop() using the local copy of their volatile config.
+	movedAway := instance.IsMovedAway(vm.state, vm)
+
 	// Cleanup.
-	vm.cleanupDevices()
+	if !movedAway {
+		vm.cleanupDevices()
+	}
+
 	os.Remove(vm.pidFilePath())
 	os.Remove(vm.monitorPath())
 	vm.unmount()
 
+	if movedAway {
+		if op != nil {
+			op.Done(nil)
+		}
+
+		return nil
+	}
+
 	// Record power state.
 	err := vm.state.Cluster.UpdateInstancePowerState(vm.id, "STOPPED")
 	if err != nil {
@@ -619,6 +640,19 @@ func (vm *qemu) ovmfPath() string {
 
 // Start starts the instance.
 func (vm *qemu) Start(stateful bool) error {
+	if !stateful {
+		return vm.start("")
+	}
+
+	if !vm.stateful {
+		return fmt.Errorf("Instance has no existing state to restore")
+	}
+
+	return vm.start(filepath.Join(vm.StatePath(), instance.VMStateFile))
+}
+
+// start starts the instance, restoring its runtime state from stateFile if not empty.
+func (vm *qemu) start(stateFile string) error {
 	// Ensure the correct vhost_vsock kernel module is loaded before establishing the vsock.
 	err := util.LoadModule("vhost_vsock")
 	if err != nil {
@@ -732,6 +766,12 @@ func (vm *qemu) Start(stateful bool) error {
 		return err
 	}
 
+	// Pass the migration state file to qemu if restoring.
+	stateFD := -1
+	if stateFile != "" {
+		stateFD = vm.addFileDescriptor(&fdFiles, stateFile)
+	}
+
 	// Check qemu is installed.
 	qemuPath, err := exec.LookPath(qemuBinary)
 	if err != nil {
@@ -759,6 +799,10 @@ func (vm *qemu) Start(stateful bool) error {
 		"-chroot", vm.Path(),
 	}
 
+	if stateFD >= 0 {
+		qemuCmd = append(qemuCmd, "-incoming", fmt.Sprintf("fd:%d", stateFD))
+	}
+
 	// SMBIOS only on x86_64 and aarch64.
 	if shared.IntInSlice(vm.architecture, []int{osarch.ARCH_64BIT_INTEL_X86, osarch.ARCH_64BIT_ARMV8_LITTLE_ENDIAN}) {
 		qemuCmd = append(qemuCmd, "-smbios", "type=2,manufacturer=Canonical Ltd.,product=LXD")
@@ -944,6 +988,15 @@ func (vm *qemu) Start(stateful bool) error {
 		}
 	}
 
+	// Wait for the incoming state to be loaded.
+	if stateFile != "" {
+		err = monitor.MigrateWait()
+		if err != nil {
+			op.Done(err)
+			return err
+		}
+	}
+
 	// Start the VM.
 	err = monitor.Start()
 	if err != nil {
@@ -976,6 +1029,23 @@ func (vm *qemu) Start(stateful bool) error {
 		return err
 	}
 
+	// Any stored state has either been restored or isn't needed anymore.
+	if vm.stateful {
+		err = os.RemoveAll(vm.StatePath())
+		if err != nil {
+			op.Done(err)
+			return err
+		}
+
+		vm.stateful = false
+		err = vm.state.Cluster.UpdateInstanceStatefulFlag(vm.id, false)
+		if err != nil {
+			err = errors.Wrap(err, "Persist stateful flag")
+			op.Done(err)
+			return err
+		}
+	}
+
 	revert.Success()
 	vm.state.Events.SendLifecycle(vm.project, "virtual-machine-started", fmt.Sprintf("/1.0/virtual-machines/%s", vm.name), nil)
 	return nil
@@ -1068,6 +1138,11 @@ func (vm *qemu) deviceVolatileSetFunc(devName string) func(save map[string]strin
 			volatileSave[fmt.Sprintf("volatile.%s.%s", devName, k)] = v
 		}
 
+		// Don't overwrite the config of the copy running on another cluster member.
+		if instance.IsMovedAway(vm.state, vm) {
+			return nil
+		}
+
 		return vm.VolatileSet(volatileSave)
 	}
 }
@@ -1118,7 +1193,7 @@ func (vm *qemu) deviceStart(deviceName string, rawConfig deviceConfig.Device, is
 		return nil, err
 	}
 
-	if canHotPlug, _ := d.CanHotPlug(); isRunning && !canHotPlug {
+	if canHotPlug, _ := d.CanHotPlug(); isRunning && (!canHotPlug || !vm.deviceHotplugSupported(rawConfig)) {
 		return nil, fmt.Errorf("Device cannot be started when instance is running")
 	}
 
@@ -1127,6 +1202,19 @@ func (vm *qemu) deviceStart(deviceName string, rawConfig deviceConfig.Device, is
 		return nil, err
 	}
 
+	// Plug the device into the running VM.
+	if isRunning && runConf != nil {
+		err = vm.deviceAttach(deviceName, runConf)
+		if err != nil {
+			stopRunConf, stopErr := d.Stop()
+			if stopErr == nil && stopRunConf != nil {
+				vm.runHooks(stopRunConf.PostHooks)
+			}
+
+			return nil, errors.Wrapf(err, "Failed to attach device %q", deviceName)
+		}
+	}
+
 	return runConf, nil
 }
 
@@ -1156,8 +1244,16 @@ func (vm *qemu) deviceStop(deviceName string, rawConfig deviceConfig.Device) err
 
 	canHotPlug, _ := d.CanHotPlug()
 
-	if vm.IsRunning() && !canHotPlug {
-		return fmt.Errorf("Device cannot be stopped when instance is running")
+	if vm.IsRunning() {
+		if !canHotPlug || !vm.deviceHotplugSupported(rawConfig) {
+			return fmt.Errorf("Device cannot be stopped when instance is running")
+		}
+
+		// Unplug the device from the running VM before removing it on the host.
+		err = vm.deviceDetach(deviceName, rawConfig)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to detach device %q", deviceName)
+		}
 	}
 
 	runConf, err := d.Stop()
@@ -1191,6 +1287,425 @@ func (vm *qemu) runHooks(hooks []func() error) error {
 	return nil
 }
 
+// deviceHotplugSupported returns whether a device can be added to or removed from the VM while it is running.
+// Devices that are only set up when qemu starts, such as directory shares, need the VM to be restarted.
+func (vm *qemu) deviceHotplugSupported(rawConfig deviceConfig.Device) bool {
+	switch rawConfig["type"] {
+	case "disk":
+		if shared.IsRootDiskDevice(rawConfig) {
+			return false
+		}
+
+		// Ceph RBD volumes are opened by qemu itself and CephFS is shared as a directory.
+		if strings.HasPrefix(rawConfig["source"], "ceph:") || strings.HasPrefix(rawConfig["source"], "cephfs:") {
+			return false
+		}
+
+		// Host directories are shared using 9p. Directory shares of storage volumes are only detected
+		// once the device has been started.
+		if rawConfig["pool"] == "" && shared.IsDir(shared.HostPath(rawConfig["source"])) {
+			return false
+		}
+
+		return true
+	case "nic":
+		nicType, err := nictype.NICType(vm.state, vm.Project(), rawConfig)
+		if err != nil {
+			return false
+		}
+
+		return shared.StringInSlice(nicType, []string{"bridged", "macvlan", "ovn"})
+	case "usb", "none":
+		return true
+	}
+
+	return false
+}
+
+// deviceAttach plugs the devices described in the run-time config of a started device into the running VM.
+func (vm *qemu) deviceAttach(deviceName string, runConf *deviceConfig.RunConfig) error {
+	if len(runConf.GPUDevice) > 0 {
+		return fmt.Errorf("GPU devices cannot be added to a running VM")
+	}
+
+	monitor, err := qmp.Connect(vm.monitorPath(), qemuSerialChardevName, vm.getMonitorEventHandler())
+	if err != nil {
+		return err
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	for _, drive := range runConf.Mounts {
+		if drive.TargetPath == "/" || drive.FSType == "9p" || strings.HasPrefix(drive.DevPath, "rbd:") {
+			return fmt.Errorf("Disk device %q cannot be added to a running VM", deviceName)
+		}
+
+		err = vm.deviceAttachDrive(monitor, drive)
+		if err != nil {
+			return err
+		}
+
+		revert.Add(func() { vm.deviceDetach(deviceName, deviceConfig.Device{"type": "disk"}) })
+	}
+
+	if len(runConf.NetworkInterface) > 0 {
+		err = vm.deviceAttachNIC(monitor, runConf.NetworkInterface)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, usbDev := range runConf.USBDevice {
+		err = vm.deviceAttachUSB(monitor, usbDev)
+		if err != nil {
+			return err
+		}
+
+		revert.Add(func() { vm.deviceDetach(deviceName, deviceConfig.Device{"type": "usb"}) })
+	}
+
+	revert.Success()
+	return nil
+}
+
+// deviceAttachDrive hot-plugs a drive into the running VM. As qemu runs chrooted and unprivileged, the drive
+// is opened on the host and its file descriptor is passed to qemu.
+func (vm *qemu) deviceAttachDrive(monitor *qmp.Monitor, driveConf deviceConfig.MountEntryItem) error {
+	aioMode, cacheMode, err := vm.driveIOModes(driveConf)
+	if err != nil {
+		return err
+	}
+
+	flags := os.O_RDWR
+	if cacheMode == "none" {
+		flags |= unix.O_DIRECT
+	}
+
+	f, err := os.OpenFile(driveConf.DevPath, flags, 0)
+	if err != nil {
+		return errors.Wrapf(err, "Failed opening drive %q", driveConf.DevPath)
+	}
+	defer f.Close()
+
+	nodeName := fmt.Sprintf("lxd_%s", driveConf.DevName)
+
+	fdSetID, err := monitor.SendFileWithFDSet(f, nodeName)
+	if err != nil {
+		return err
+	}
+
+	// Qemu keeps its own reference to the file for as long as the drive uses it.
+	defer monitor.RemoveFDSet(fdSetID)
+
+	driver := "file"
+	if shared.IsBlockdevPath(driveConf.DevPath) {
+		driver = "host_device"
+	}
+
+	cache := map[string]interface{}{
+		"direct":   cacheMode == "none",
+		"no-flush": cacheMode == "unsafe",
+	}
+
+	blockDev := map[string]interface{}{
+		"driver":    "raw",
+		"node-name": nodeName,
+		"discard":   "unmap",
+		"cache":     cache,
+		"file": map[string]interface{}{
+			"driver":   driver,
+			"filename": fmt.Sprintf("/dev/fdset/%d", fdSetID),
+			"aio":      aioMode,
+			"cache":    cache,
+			"locking":  "off",
+		},
+	}
+
+	// The SCSI target ID is left for qemu to pick as the ones used at boot time are taken from boot indexes.
+	device := map[string]interface{}{
+		"id":      fmt.Sprintf("dev-lxd_%s", driveConf.DevName),
+		"driver":  "scsi-hd",
+		"bus":     "qemu_scsi.0",
+		"channel": 0,
+		"lun":     1,
+		"drive":   nodeName,
+	}
+
+	return monitor.AddBlockDevice(blockDev, device)
+}
+
+// deviceAttachNIC hot-plugs a network interface into the running VM. The tap and vhost-net devices are
+// opened on the host and their file descriptors are passed to qemu.
+func (vm *qemu) deviceAttachNIC(monitor *qmp.Monitor, nicConfig []deviceConfig.RunConfigItem) error {
+	var devName, nicName, devHwaddr, pciSlotName string
+	for _, nicItem := range nicConfig {
+		if nicItem.Key == "devName" {
+			devName = nicItem.Value
+		} else if nicItem.Key == "link" {
+			nicName = nicItem.Value
+		} else if nicItem.Key == "hwaddr" {
+			devHwaddr = nicItem.Value
+		} else if nicItem.Key == "pciSlotName" {
+			pciSlotName = nicItem.Value
+		}
+	}
+
+	if pciSlotName != "" {
+		return fmt.Errorf("Physical network devices cannot be added to a running VM")
+	}
+
+	var tapFile *os.File
+	var err error
+	if shared.PathExists(fmt.Sprintf("/sys/class/net/%s/macvtap", nicName)) {
+		tapPath, err := macvtapDevicePath(nicName)
+		if err != nil {
+			return err
+		}
+
+		tapFile, err = os.OpenFile(tapPath, os.O_RDWR, 0)
+		if err != nil {
+			return errors.Wrapf(err, "Failed opening tap device %q", tapPath)
+		}
+	} else if shared.PathExists(fmt.Sprintf("/sys/class/net/%s/tun_flags", nicName)) {
+		tapFile, err = openTapDevice(nicName)
+		if err != nil {
+			return err
+		}
+	} else {
+		return fmt.Errorf("Unrecognised device type")
+	}
+	defer tapFile.Close()
+
+	vhostFile, err := os.OpenFile("/dev/vhost-net", os.O_RDWR, 0)
+	if err != nil {
+		return errors.Wrapf(err, "Failed opening vhost-net device")
+	}
+	defer vhostFile.Close()
+
+	tapFDName := fmt.Sprintf("lxd_%s", devName)
+	vhostFDName := fmt.Sprintf("lxd_%s.vhost", devName)
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	err = monitor.SendFile(tapFDName, tapFile)
+	if err != nil {
+		return err
+	}
+
+	revert.Add(func() { monitor.CloseFile(tapFDName) })
+
+	err = monitor.SendFile(vhostFDName, vhostFile)
+	if err != nil {
+		return err
+	}
+
+	revert.Add(func() { monitor.CloseFile(vhostFDName) })
+
+	netDev := map[string]interface{}{
+		"type":    "tap",
+		"id":      fmt.Sprintf("lxd_%s", devName),
+		"fd":      tapFDName,
+		"vhost":   true,
+		"vhostfd": vhostFDName,
+	}
+
+	device := map[string]interface{}{
+		"id":     fmt.Sprintf("dev-lxd_%s", devName),
+		"netdev": fmt.Sprintf("lxd_%s", devName),
+		"mac":    devHwaddr,
+	}
+
+	_, busName, err := vm.qemuArchConfig()
+	if err != nil {
+		return err
+	}
+
+	switch busName {
+	case "pcie":
+		portID, err := vm.pciHotplugPort(monitor)
+		if err != nil {
+			return err
+		}
+
+		device["driver"] = "virtio-net-pci"
+		device["bus"] = portID
+		device["addr"] = "00.0"
+	case "pci":
+		device["driver"] = "virtio-net-pci"
+		device["bus"] = "pci.0"
+	case "ccw":
+		device["driver"] = "virtio-net-ccw"
+	}
+
+	err = monitor.AddNIC(netDev, device)
+	if err != nil {
+		return err
+	}
+
+	revert.Success()
+	return nil
+}
+
+// deviceAttachUSB passes a host USB device through to the running VM.
+func (vm *qemu) deviceAttachUSB(monitor *qmp.Monitor, usbDev deviceConfig.USBDeviceItem) error {
+	_, busName, err := vm.qemuArchConfig()
+	if err != nil {
+		return err
+	}
+
+	if busName == "ccw" {
+		return fmt.Errorf("USB devices aren't supported on this architecture")
+	}
+
+	f, err := os.OpenFile(usbDev.HostDevicePath, os.O_RDWR, 0)
+	if err != nil {
+		return errors.Wrapf(err, "Failed opening USB device %q", usbDev.HostDevicePath)
+	}
+	defer f.Close()
+
+	fdSetID, err := monitor.SendFileWithFDSet(f, fmt.Sprintf("lxd_%s", usbDev.DeviceName))
+	if err != nil {
+		return err
+	}
+
+	// Qemu keeps its own reference to the file for as long as the device uses it.
+	defer monitor.RemoveFDSet(fdSetID)
+
+	return monitor.AddDevice(map[string]interface{}{
+		"id":         fmt.Sprintf("dev-lxd_%s", usbDev.DeviceName),
+		"driver":     "usb-host",
+		"bus":        "qemu_usb.0",
+		"hostdevice": fmt.Sprintf("/dev/fdset/%d", fdSetID),
+	})
+}
+
+// pciHotplugPort returns the ID of a reserved PCIe root port that doesn't have a device plugged into it.
+func (vm *qemu) pciHotplugPort(monitor *qmp.Monitor) (string, error) {
+	devices, err := monitor.QueryPCI()
+	if err != nil {
+		return "", err
+	}
+
+	for _, dev := range devices {
+		if !strings.HasPrefix(dev.DevID, "qemu_pcie") || dev.Bridge == nil || len(dev.Bridge.Devices) > 0 {
+			continue
+		}
+
+		return dev.DevID, nil
+	}
+
+	return "", fmt.Errorf("No free PCIe port left for hotplug, the VM needs to be restarted")
+}
+
+// deviceDetach unplugs a device from the running VM and removes any qemu backend it was using.
+func (vm *qemu) deviceDetach(deviceName string, rawConfig deviceConfig.Device) error {
+	monitor, err := qmp.Connect(vm.monitorPath(), qemuSerialChardevName, vm.getMonitorEventHandler())
+	if err != nil {
+		return err
+	}
+
+	// USB devices have a qemu device for each host device that was passed through.
+	isOurDevice := func(id string) bool {
+		if id == fmt.Sprintf("dev-lxd_%s", deviceName) {
+			return true
+		}
+
+		return rawConfig["type"] == "usb" && strings.HasPrefix(id, fmt.Sprintf("dev-lxd_%s-", deviceName))
+	}
+
+	devices, err := monitor.QueryDevices()
+	if err != nil {
+		return err
+	}
+
+	deviceIDs := []string{}
+	for _, id := range devices {
+		if isOurDevice(id) {
+			deviceIDs = append(deviceIDs, id)
+		}
+	}
+
+	// Nothing to unplug, the device may not have matched anything on the host.
+	if len(deviceIDs) == 0 {
+		return nil
+	}
+
+	for _, id := range deviceIDs {
+		err = monitor.RemoveDevice(id)
+		if err != nil {
+			return err
+		}
+	}
+
+	// PCI devices are only removed once the guest has released them.
+	waitUntil := time.Now().Add(30 * time.Second)
+	for {
+		devices, err := monitor.QueryDevices()
+		if err != nil {
+			return err
+		}
+
+		found := false
+		for _, id := range devices {
+			if isOurDevice(id) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			break
+		}
+
+		if time.Now().After(waitUntil) {
+			return fmt.Errorf("Timed out waiting for the guest to release the device")
+		}
+
+		time.Sleep(500 * time.Millisecond)
+	}
+
+	switch rawConfig["type"] {
+	case "disk":
+		// Drives set up at boot time are removed along with their device, so only hot-plugged drives
+		// have a block device node left to remove.
+		monitor.RemoveBlockDevice(fmt.Sprintf("lxd_%s", deviceName))
+	case "nic":
+		err = monitor.RemoveNIC(fmt.Sprintf("lxd_%s", deviceName))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// openTapDevice attaches to an existing persistent tap interface through the TUN driver.
+func openTapDevice(ifName string) (*os.File, error) {
+	f, err := os.OpenFile("/dev/net/tun", os.O_RDWR, 0)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed opening TUN device")
+	}
+
+	var ifr struct {
+		Name  [unix.IFNAMSIZ]byte
+		Flags uint16
+		_     [22]byte
+	}
+
+	copy(ifr.Name[:], ifName)
+	ifr.Flags = unix.IFF_TAP | unix.IFF_NO_PI | unix.IFF_VNET_HDR
+
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, f.Fd(), unix.TUNSETIFF, uintptr(unsafe.Pointer(&ifr)))
+	if errno != 0 {
+		f.Close()
+		return nil, errors.Wrapf(errno, "Failed attaching to tap device %q", ifName)
+	}
+
+	return f, nil
+}
+
 func (vm *qemu) monitorPath() string {
 	return filepath.Join(vm.LogPath(), "qemu.monitor")
 }
@@ -1712,6 +2227,19 @@ func (vm *qemu) generateQemuConfigFile(busName string, devConfs []*deviceConfig.
 		return "", err
 	}
 
+	// USB controller used for host USB device passthrough.
+	if bus.name != "ccw" {
+		devBus, devAddr, multi = bus.allocate(busFunctionGroupGeneric)
+		err = qemuUSB.Execute(sb, map[string]interface{}{
+			"devBus":        devBus,
+			"devAddr":       devAddr,
+			"multifunction": multi,
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+
 	devBus, devAddr, multi = bus.allocate(busFunctionGroupNone)
 	err = qemuSCSI.Execute(sb, map[string]interface{}{
 		"bus":           bus.name,
@@ -1794,8 +2322,20 @@ func (vm *qemu) generateQemuConfigFile(busName string, devConfs []*deviceConfig.
 				return "", err
 			}
 		}
+
+		// Add USB devices.
+		for _, usbDev := range runConf.USBDevice {
+			err = vm.addUSBDeviceConfig(sb, bus, usbDev)
+			if err != nil {
+				return "", err
+			}
+		}
 	}
 
+	// Reserve empty ports for devices added while the VM is running. These are allocated after all the
+	// boot time devices so that the addresses of the existing devices are unaffected.
+	bus.reserveHotplugPorts(busHotplugPorts)
+
 	// Write the agent mount config.
 	agentMountJSON, err := json.Marshal(agentMounts)
 	if err != nil {
@@ -2025,6 +2565,23 @@ func (vm *qemu) addDriveDirConfig(sb *strings.Builder, bus *qemuBus, fdFiles *[]
 
 // addDriveConfig adds the qemu config required for adding a supplementary drive.
 func (vm *qemu) addDriveConfig(sb *strings.Builder, bootIndexes map[string]int, driveConf deviceConfig.MountEntryItem) error {
+	aioMode, cacheMode, err := vm.driveIOModes(driveConf)
+	if err != nil {
+		return err
+	}
+
+	return qemuDrive.Execute(sb, map[string]interface{}{
+		"devName":   driveConf.DevName,
+		"devPath":   driveConf.DevPath,
+		"bootIndex": bootIndexes[driveConf.DevName],
+		"cacheMode": cacheMode,
+		"aioMode":   aioMode,
+		"shared":    driveConf.TargetPath != "/" && !strings.HasPrefix(driveConf.DevPath, "rbd:"),
+	})
+}
+
+// driveIOModes returns the qemu async I/O and cache modes to use for a drive.
+func (vm *qemu) driveIOModes(driveConf deviceConfig.MountEntryItem) (string, string, error) {
 	// Use native kernel async IO and O_DIRECT by default.
 	aioMode := "native"
 	cacheMode := "none" // Bypass host cache, use O_DIRECT semantics.
@@ -2038,7 +2595,7 @@ func (vm *qemu) addDriveConfig(sb *strings.Builder, bootIndexes map[string]int,
 		// Disk dev path is a file, check whether it is located on a ZFS filesystem.
 		fsType, err := util.FilesystemDetect(driveConf.DevPath)
 		if err != nil {
-			return errors.Wrapf(err, "Failed detecting filesystem type of %q", driveConf.DevPath)
+			return "", "", errors.Wrapf(err, "Failed detecting filesystem type of %q", driveConf.DevPath)
 		}
 
 		// If FS is ZFS, avoid using direct I/O and use host page cache only.
@@ -2051,14 +2608,7 @@ func (vm *qemu) addDriveConfig(sb *strings.Builder, bootIndexes map[string]int,
 		}
 	}
 
-	return qemuDrive.Execute(sb, map[string]interface{}{
-		"devName":   driveConf.DevName,
-		"devPath":   driveConf.DevPath,
-		"bootIndex": bootIndexes[driveConf.DevName],
-		"cacheMode": cacheMode,
-		"aioMode":   aioMode,
-		"shared":    driveConf.TargetPath != "/" && !strings.HasPrefix(driveConf.DevPath, "rbd:"),
-	})
+	return aioMode, cacheMode, nil
 }
 
 // addNetDevConfig adds the qemu config required for adding a network device.
@@ -2087,18 +2637,13 @@ func (vm *qemu) addNetDevConfig(sb *strings.Builder, bus *qemuBus, bootIndexes m
 	// Detect MACVTAP interface types and figure out which tap device is being used.
 	// This is so we can open a file handle to the tap device and pass it to the qemu process.
 	if shared.PathExists(fmt.Sprintf("/sys/class/net/%s/macvtap", nicName)) {
-		content, err := ioutil.ReadFile(fmt.Sprintf("/sys/class/net/%s/ifindex", nicName))
-		if err != nil {
-			return errors.Wrapf(err, "Error getting tap device ifindex")
-		}
-
-		ifindex, err := strconv.Atoi(strings.TrimSpace(string(content)))
+		tapPath, err := macvtapDevicePath(nicName)
 		if err != nil {
-			return errors.Wrapf(err, "Error parsing tap device ifindex")
+			return err
 		}
 
 		// Append the tap device file path to the list of files to be opened and passed to qemu.
-		tplFields["tapFD"] = vm.addFileDescriptor(fdFiles, fmt.Sprintf("/dev/tap%d", ifindex))
+		tplFields["tapFD"] = vm.addFileDescriptor(fdFiles, tapPath)
 		tpl = qemuNetDevTapFD
 	} else if shared.PathExists(fmt.Sprintf("/sys/class/net/%s/tun_flags", nicName)) {
 		// Detect TAP (via TUN driver) device.
@@ -2121,6 +2666,33 @@ func (vm *qemu) addNetDevConfig(sb *strings.Builder, bus *qemuBus, bootIndexes m
 	return fmt.Errorf("Unrecognised device type")
 }
 
+// macvtapDevicePath returns the path of the tap character device backing a MACVTAP interface.
+func macvtapDevicePath(nicName string) (string, error) {
+	content, err := ioutil.ReadFile(fmt.Sprintf("/sys/class/net/%s/ifindex", nicName))
+	if err != nil {
+		return "", errors.Wrapf(err, "Error getting tap device ifindex")
+	}
+
+	ifindex, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil {
+		return "", errors.Wrapf(err, "Error parsing tap device ifindex")
+	}
+
+	return fmt.Sprintf("/dev/tap%d", ifindex), nil
+}
+
+// addUSBDeviceConfig adds the qemu config required for passing through a host USB device.
+func (vm *qemu) addUSBDeviceConfig(sb *strings.Builder, bus *qemuBus, usbDev deviceConfig.USBDeviceItem) error {
+	if bus.name == "ccw" {
+		return fmt.Errorf("USB devices aren't supported on this architecture")
+	}
+
+	return qemuUSBDev.Execute(sb, map[string]interface{}{
+		"devName":    usbDev.DeviceName,
+		"hostDevice": usbDev.HostDevicePath,
+	})
+}
+
 // addGPUDevConfig adds the qemu config required for adding a GPU device.
 func (vm *qemu) addGPUDevConfig(sb *strings.Builder, bus *qemuBus, gpuConfig []deviceConfig.RunConfigItem) error {
 	var devName, pciSlotName string
@@ -2232,9 +2804,19 @@ func (vm *qemu) Stop(stateful bool) error {
 		return fmt.Errorf("The instance is already stopped")
 	}
 
-	// Check that no stateful stop was requested.
+	// Save the VM state before stopping it, the VM is left paused once done.
 	if stateful {
-		return fmt.Errorf("Stateful stop isn't supported for VMs at this time")
+		err := vm.saveState(vm.StatePath())
+		if err != nil {
+			return err
+		}
+
+		vm.stateful = true
+		err = vm.state.Cluster.UpdateInstanceStatefulFlag(vm.id, true)
+		if err != nil {
+			vm.Unfreeze()
+			return errors.Wrap(err, "Persist stateful flag")
+		}
 	}
 
 	// Setup a new operation.
@@ -2284,6 +2866,12 @@ func (vm *qemu) Stop(stateful bool) error {
 		return err
 	}
 
+	// The instance is now running on another cluster member, release the local devices only.
+	if instance.IsMovedAway(vm.state, vm) {
+		vm.cleanupDevices()
+		return nil
+	}
+
 	vm.state.Events.SendLifecycle(vm.project, "virtual-machine-stopped", fmt.Sprintf("/1.0/virtual-machines/%s", vm.name), nil)
 	return nil
 }
@@ -2312,10 +2900,6 @@ func (vm *qemu) IsPrivileged() bool {
 
 // Restore restores an instance snapshot.
 func (vm *qemu) Restore(source instance.Instance, stateful bool) error {
-	if stateful {
-		return fmt.Errorf("Stateful snapshots of VMs aren't supported yet")
-	}
-
 	var ctxMap log.Ctx
 
 	// Load the storage driver.
@@ -2415,8 +2999,28 @@ func (vm *qemu) Restore(source instance.Instance, stateful bool) error {
 		return err
 	}
 
-	vm.state.Events.SendLifecycle(vm.project, "virtual-machine-snapshot-restored", fmt.Sprintf("/1.0/virtual-machines/%s", vm.name), map[string]interface{}{"snapshot_name": vm.name})
-
+	vm.state.Events.SendLifecycle(vm.project, "virtual-machine-snapshot-restored", fmt.Sprintf("/1.0/virtual-machines/%s", vm.name), map[string]interface{}{"snapshot_name": vm.name})
+
+	// Restore the VM from the state saved in the snapshot.
+	if stateful {
+		if !shared.PathExists(filepath.Join(vm.StatePath(), instance.VMStateFile)) {
+			return fmt.Errorf("Stateful restore requested but the snapshot has no saved VM state")
+		}
+
+		logger.Debug("Performing stateful restore", ctxMap)
+		vm.stateful = true
+
+		err = vm.Start(true)
+		if err != nil {
+			logger.Info("Failed restoring instance", ctxMap)
+			return err
+		}
+
+		logger.Debug("Performed stateful restore", ctxMap)
+		logger.Info("Restored instance", ctxMap)
+		return nil
+	}
+
 	// Restart the insance.
 	if wasRunning {
 		logger.Info("Restored instance", ctxMap)
@@ -2618,131 +3222,7 @@ func (vm *qemu) Rename(newName string) error {
 
 // Update the instance config.
 func (vm *qemu) Update(args db.InstanceArgs, userRequested bool) error {
-	// Only user.* keys can be changed on a running VM
-	if vm.IsRunning() {
-		if args.Config == nil {
-			args.Config = map[string]string{}
-		}
-
-		if userRequested {
-			// Validate the new config.
-			err := instance.ValidConfig(vm.state.OS, args.Config, false, false)
-			if err != nil {
-				return errors.Wrap(err, "Invalid config")
-			}
-		}
-
-		oldExpandedConfig := map[string]string{}
-		err := shared.DeepCopy(&vm.expandedConfig, &oldExpandedConfig)
-		if err != nil {
-			return err
-		}
-
-		oldLocalConfig := map[string]string{}
-		err = shared.DeepCopy(&vm.localConfig, &oldLocalConfig)
-		if err != nil {
-			return err
-		}
-
-		undoChanges := true
-		defer func() {
-			if undoChanges {
-				vm.expandedConfig = oldExpandedConfig
-				vm.localConfig = oldLocalConfig
-			}
-		}()
-
-		vm.localConfig = args.Config
-
-		// Expand the config and refresh the LXC config.
-		err = vm.expandConfig(nil)
-		if err != nil {
-			return errors.Wrap(err, "Expand config")
-		}
-
-		// Diff the configurations.
-		changedConfig := []string{}
-		for key := range oldExpandedConfig {
-			if oldExpandedConfig[key] != vm.expandedConfig[key] {
-				if !shared.StringInSlice(key, changedConfig) {
-					changedConfig = append(changedConfig, key)
-				}
-			}
-		}
-
-		for key := range vm.expandedConfig {
-			if oldExpandedConfig[key] != vm.expandedConfig[key] {
-				if !shared.StringInSlice(key, changedConfig) {
-					changedConfig = append(changedConfig, key)
-				}
-			}
-		}
-
-		for _, key := range changedConfig {
-			if !strings.HasPrefix(key, "user.") {
-				return fmt.Errorf("Only user.* keys can be updated on running VMs")
-			}
-		}
-
-		if userRequested {
-			// Do some validation of the config diff.
-			err = instance.ValidConfig(vm.state.OS, vm.expandedConfig, false, true)
-			if err != nil {
-				return errors.Wrap(err, "Invalid expanded config")
-			}
-		}
-
-		err = vm.state.Cluster.Transaction(func(tx *db.ClusterTx) error {
-			object, err := tx.GetInstance(vm.project, vm.name)
-			if err != nil {
-				return err
-			}
-
-			object.Config = vm.localConfig
-
-			return tx.UpdateInstance(vm.project, vm.name, *object)
-		})
-		if err != nil {
-			return errors.Wrap(err, "Failed to update database")
-		}
-
-		err = vm.UpdateBackupFile()
-		if err != nil && !os.IsNotExist(err) {
-			return errors.Wrap(err, "Failed to write backup file")
-		}
-
-		// Success, update the closure to mark that the changes should be kept.
-		undoChanges = false
-
-		err = vm.writeInstanceData()
-		if err != nil {
-			return errors.Wrap(err, "Failed to write instance-data file")
-		}
-
-		// Send devlxd notifications only for user.* key changes
-		for _, key := range changedConfig {
-			if !strings.HasPrefix(key, "user.") {
-				continue
-			}
-
-			msg := map[string]string{
-				"key":       key,
-				"old_value": oldExpandedConfig[key],
-				"value":     vm.expandedConfig[key],
-			}
-
-			err = vm.devlxdEventSend("config", msg)
-			if err != nil {
-				return err
-			}
-		}
-
-		endpoint := fmt.Sprintf("/1.0/virtual-machines/%s", vm.name)
-
-		vm.state.Events.SendLifecycle(vm.project, "virtual-machine-updated", endpoint, nil)
-
-		return nil
-	}
+	isRunning := vm.IsRunning()
 
 	// Set sane defaults for unset keys.
 	if args.Project == "" {
@@ -2915,12 +3395,12 @@ func (vm *qemu) Update(args db.InstanceArgs, userRequested bool) error {
 		// between oldDevice and newDevice. The result of this is that as long as the
 		// devices are otherwise identical except for the fields returned here, then the
 		// device is considered to be being "updated" rather than "added & removed".
-		oldNICType, err := nictype.NICType(vm.state, newDevice)
+		oldNICType, err := nictype.NICType(vm.state, vm.Project(), newDevice)
 		if err != nil {
 			return []string{} // Cannot hot-update due to config error.
 		}
 
-		newNICType, err := nictype.NICType(vm.state, oldDevice)
+		newNICType, err := nictype.NICType(vm.state, vm.Project(), oldDevice)
 		if err != nil {
 			return []string{} // Cannot hot-update due to config error.
 		}
@@ -2952,6 +3432,30 @@ func (vm *qemu) Update(args db.InstanceArgs, userRequested bool) error {
 		}
 	}
 
+	if isRunning {
+		// Only user.* keys can be changed on a running VM.
+		for _, key := range changedConfig {
+			if !strings.HasPrefix(key, "user.") {
+				return fmt.Errorf("Only user.* keys can be updated on running VMs")
+			}
+		}
+
+		// Check that all device changes can be applied without restarting the VM before changing anything.
+		restartDevices := []string{}
+		for _, devices := range []deviceConfig.Devices{removeDevices, addDevices} {
+			for devName, devConfig := range devices {
+				if !vm.deviceHotplugSupported(devConfig) && !shared.StringInSlice(devName, restartDevices) {
+					restartDevices = append(restartDevices, devName)
+				}
+			}
+		}
+
+		if len(restartDevices) > 0 {
+			sort.Strings(restartDevices)
+			return fmt.Errorf("Changes to devices %q require the VM to be restarted", restartDevices)
+		}
+	}
+
 	// Use the device interface to apply update changes.
 	err = vm.updateDevices(removeDevices, addDevices, updateDevices, oldExpandedDevices)
 	if err != nil {
@@ -3016,6 +3520,31 @@ func (vm *qemu) Update(args db.InstanceArgs, userRequested bool) error {
 	// Success, update the closure to mark that the changes should be kept.
 	undoChanges = false
 
+	if isRunning {
+		err = vm.writeInstanceData()
+		if err != nil {
+			return errors.Wrap(err, "Failed to write instance-data file")
+		}
+
+		// Send devlxd notifications only for user.* key changes
+		for _, key := range changedConfig {
+			if !strings.HasPrefix(key, "user.") {
+				continue
+			}
+
+			msg := map[string]string{
+				"key":       key,
+				"old_value": oldExpandedConfig[key],
+				"value":     vm.expandedConfig[key],
+			}
+
+			err = vm.devlxdEventSend("config", msg)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
 	var endpoint string
 
 	if vm.IsSnapshot() {
@@ -3105,12 +3634,12 @@ func (vm *qemu) deviceResetVolatile(devName string, oldConfig, newConfig deviceC
 	volatileClear := make(map[string]string)
 	devicePrefix := fmt.Sprintf("volatile.%s.", devName)
 
-	newNICType, err := nictype.NICType(vm.state, newConfig)
+	newNICType, err := nictype.NICType(vm.state, vm.Project(), newConfig)
 	if err != nil {
 		return err
 	}
 
-	oldNICType, err := nictype.NICType(vm.state, oldConfig)
+	oldNICType, err := nictype.NICType(vm.state, vm.Project(), oldConfig)
 	if err != nil {
 		return err
 	}
@@ -3624,11 +4153,184 @@ func (vm *qemu) Export(w io.Writer, properties map[string]string) (api.ImageMeta
 	return meta, nil
 }
 
-// Migrate migrates the instance to another node.
+// Migrate dumps or restores the runtime state of the VM.
 func (vm *qemu) Migrate(args *instance.CriuMigrationArgs) error {
+	stateFile := filepath.Join(args.StateDir, args.DumpDir, instance.VMStateFile)
+
+	switch args.Cmd {
+	case liblxc.MIGRATE_DUMP:
+		if !vm.IsRunning() {
+			return fmt.Errorf("The instance isn't running")
+		}
+
+		// Connect to the monitor.
+		monitor, err := qmp.Connect(vm.monitorPath(), qemuSerialChardevName, vm.getMonitorEventHandler())
+		if err != nil {
+			return err
+		}
+
+		// Record whether the VM is paused so it is left as it was found.
+		status, err := monitor.Status()
+		if err != nil {
+			return err
+		}
+
+		err = vm.saveState(filepath.Dir(stateFile))
+		if err != nil {
+			return err
+		}
+
+		if args.Stop {
+			return vm.Stop(false)
+		}
+
+		if status == "running" {
+			return vm.Unfreeze()
+		}
+
+		return nil
+	case liblxc.MIGRATE_RESTORE:
+		if vm.IsRunning() {
+			return fmt.Errorf("The instance is already running")
+		}
+
+		if !shared.PathExists(stateFile) {
+			return fmt.Errorf("Missing VM state file %q", stateFile)
+		}
+
+		return vm.start(stateFile)
+	}
+
 	return instance.ErrNotImplemented
 }
 
+// saveState writes the runtime state of the running VM into stateDir.
+// On success the VM is left paused, on failure it gets resumed.
+func (vm *qemu) saveState(stateDir string) error {
+	err := vm.checkStateStorage()
+	if err != nil {
+		return err
+	}
+
+	os.RemoveAll(stateDir)
+
+	err = os.MkdirAll(stateDir, 0700)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(filepath.Join(stateDir, instance.VMStateFile), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	err = vm.MigrateState(f)
+	if err == nil {
+		err = f.Close()
+	}
+
+	if err != nil {
+		vm.Unfreeze()
+		os.RemoveAll(stateDir)
+		return errors.Wrap(err, "Failed saving VM state")
+	}
+
+	return nil
+}
+
+// checkStateStorage checks that the root disk reserves enough space to store the VM's memory.
+func (vm *qemu) checkStateStorage() error {
+	memSize := vm.expandedConfig["limits.memory"]
+	if memSize == "" {
+		memSize = "1GiB" // Default to 1GiB if no memory limit specified.
+	}
+
+	memSizeBytes, err := units.ParseByteSizeString(memSize)
+	if err != nil {
+		return fmt.Errorf("limits.memory invalid: %v", err)
+	}
+
+	_, rootDiskDevice, err := shared.GetRootDiskDevice(vm.expandedDevices.CloneNative())
+	if err != nil {
+		return err
+	}
+
+	if rootDiskDevice["size.state"] == "" {
+		return fmt.Errorf(`Saving the VM state requires the root disk device "size.state" property to be set`)
+	}
+
+	stateSizeBytes, err := units.ParseByteSizeString(rootDiskDevice["size.state"])
+	if err != nil {
+		return err
+	}
+
+	if stateSizeBytes < memSizeBytes {
+		return fmt.Errorf(`The root disk device "size.state" property must be at least as large as the VM memory (%s)`, memSize)
+	}
+
+	return nil
+}
+
+// MigrateState streams the runtime state of the running VM to the provided writer.
+// Once complete, the VM is left paused so that it can either be stopped or resumed.
+func (vm *qemu) MigrateState(w io.Writer) error {
+	// Connect to the monitor.
+	monitor, err := qmp.Connect(vm.monitorPath(), qemuSerialChardevName, vm.getMonitorEventHandler())
+	if err != nil {
+		return err
+	}
+
+	// Setup a socket for qemu to send the state to. As qemu runs chrooted into the instance
+	// path, the socket is passed to it relative to that path.
+	sockPath := filepath.Join(vm.Path(), qemuMigrationSocket)
+	os.Remove(sockPath)
+
+	listener, err := net.Listen("unix", sockPath)
+	if err != nil {
+		return errors.Wrap(err, "Failed to create migration socket")
+	}
+	defer os.Remove(sockPath)
+	defer listener.Close()
+
+	if vm.state.OS.UnprivUser != "" {
+		err = os.Chown(sockPath, int(vm.state.OS.UnprivUID), -1)
+		if err != nil {
+			return err
+		}
+	}
+
+	chCopy := make(chan error, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			chCopy <- err
+			return
+		}
+		defer conn.Close()
+
+		_, err = io.Copy(w, conn)
+		chCopy <- err
+	}()
+
+	err = monitor.Migrate(fmt.Sprintf("unix:/%s", qemuMigrationSocket))
+	if err != nil {
+		return err
+	}
+
+	err = monitor.MigrateWait()
+	if err != nil {
+		return err
+	}
+
+	err = <-chCopy
+	if err != nil {
+		return errors.Wrap(err, "Failed to transfer VM state")
+	}
+
+	return nil
+}
+
 // CGroupSet is not implemented for VMs.
 func (vm *qemu) CGroupSet(key string, value string) error {
 	return instance.ErrNotImplemented
@@ -4075,7 +4777,7 @@ func (vm *qemu) RenderState() (*api.InstanceState, error) {
 			status.Processes = -1
 			networks := map[string]api.InstanceStateNetwork{}
 			for k, m := range vm.ExpandedDevices() {
-				nicType, err := nictype.NICType(vm.state, m)
+				nicType, err := nictype.NICType(vm.state, vm.Project(), m)
 				if err != nil {
 					return nil, err
 				}
@@ -4236,6 +4938,65 @@ func (vm *qemu) agentGetState() (*api.InstanceState, error) {
 	return status, nil
 }
 
+// Metrics returns the metrics of the running VM, as reported by the agent and the host.
+func (vm *qemu) Metrics() (*metrics.MetricSet, error) {
+	if !vm.IsRunning() {
+		return nil, instance.ErrInstanceIsStopped
+	}
+
+	out := metrics.NewMetricSet(map[string]string{"project": vm.project, "name": vm.name, "type": instancetype.VM.String()})
+
+	// Disk usage is tracked on the host.
+	disks, err := vm.diskState()
+	if err != nil && err != storageDrivers.ErrNotSupported {
+		logger.Warn("Error getting disk usage", log.Ctx{"project": vm.Project(), "instance": vm.Name(), "err": err})
+	}
+
+	for name, disk := range disks {
+		out.AddSamples(metrics.FilesystemUsageBytes, metrics.Sample{Value: float64(disk.Usage), Labels: map[string]string{"device": name}})
+	}
+
+	// Everything else comes from the agent.
+	status, err := vm.agentGetState()
+	if err != nil {
+		if err != errQemuAgentOffline {
+			logger.Warn("Could not get VM state from agent", log.Ctx{"project": vm.Project(), "instance": vm.Name(), "err": err})
+		}
+
+		return out, nil
+	}
+
+	// CPU usage, reported in nanoseconds.
+	if status.CPU.Usage > 0 {
+		out.AddSamples(metrics.CPUSecondsTotal, metrics.Sample{Value: float64(status.CPU.Usage) / 1000000000})
+	}
+
+	out.AddSamples(metrics.MemoryUsageBytes, metrics.Sample{Value: float64(status.Memory.Usage)})
+
+	if status.Memory.UsagePeak > 0 {
+		out.AddSamples(metrics.MemoryUsagePeakBytes, metrics.Sample{Value: float64(status.Memory.UsagePeak)})
+	}
+
+	for name, network := range status.Network {
+		// Skip the loopback device of the guest.
+		if network.Type == "loopback" {
+			continue
+		}
+
+		labels := map[string]string{"device": name}
+		out.AddSamples(metrics.NetworkReceiveBytesTotal, metrics.Sample{Value: float64(network.Counters.BytesReceived), Labels: labels})
+		out.AddSamples(metrics.NetworkReceivePacketsTotal, metrics.Sample{Value: float64(network.Counters.PacketsReceived), Labels: labels})
+		out.AddSamples(metrics.NetworkTransmitBytesTotal, metrics.Sample{Value: float64(network.Counters.BytesSent), Labels: labels})
+		out.AddSamples(metrics.NetworkTransmitPacketsTotal, metrics.Sample{Value: float64(network.Counters.PacketsSent), Labels: labels})
+	}
+
+	if status.Processes >= 0 {
+		out.AddSamples(metrics.ProcsTotal, metrics.Sample{Value: float64(status.Processes)})
+	}
+
+	return out, nil
+}
+
 // IsRunning returns whether or not the instance is running.
 func (vm *qemu) IsRunning() bool {
 	state := vm.State()
@@ -4468,7 +5229,7 @@ func (vm *qemu) FillNetworkDevice(name string, m deviceConfig.Device) (deviceCon
 		return nil
 	}
 
-	nicType, err := nictype.NICType(vm.state, m)
+	nicType, err := nictype.NICType(vm.state, vm.Project(), m)
 	if err != nil {
 		return nil, err
 	}
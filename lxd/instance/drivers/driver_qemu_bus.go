@@ -9,6 +9,8 @@ const busFunctionGroupNone = ""           // Add a non multi-function port.
 const busFunctionGroupGeneric = "generic" // Add multi-function port to generic group (used for internal devices).
 const busFunctionGroup9p = "9p"           // Add multi-function port to 9p group (used for 9p shares).
 
+const busHotplugPorts = 8 // Number of empty PCIe root ports reserved for hot-plugged devices.
+
 type qemuBusEntry struct {
 	bridgeDev int // Device number on the root bridge.
 	bridgeFn  int // Function number on the root bridge.
@@ -133,6 +135,18 @@ func (a *qemuBus) allocate(multiFunctionGroup string) (string, string, bool) {
 	return "", "", false
 }
 
+// reserveHotplugPorts adds empty root ports to a PCIe bus so that devices can be hot-plugged into them while
+// the VM is running. Other bus types don't need reserved ports to support hotplug.
+func (a *qemuBus) reserveHotplugPorts(count int) {
+	if a.name != "pcie" {
+		return
+	}
+
+	for i := 0; i < count; i++ {
+		a.allocate(busFunctionGroupNone)
+	}
+}
+
 // qemuNewBus instantiates a new qemu bus allocator. Accepts the type name of the bus and the qemu config builder
 // which it will use to write root port config entries too as ports are allocated.
 func qemuNewBus(name string, sb *strings.Builder) *qemuBus {
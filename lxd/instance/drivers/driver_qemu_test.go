@@ -0,0 +1,39 @@
+package drivers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	deviceConfig "github.com/lxc/lxd/lxd/device/config"
+)
+
+func TestQemuCheckStateStorage(t *testing.T) {
+	tests := []struct {
+		memory    string
+		stateSize string
+		err       string
+	}{
+		{"", "", `Saving the VM state requires the root disk device "size.state" property to be set`},
+		{"2GiB", "1GiB", `The root disk device "size.state" property must be at least as large as the VM memory (2GiB)`},
+		{"", "512MiB", `The root disk device "size.state" property must be at least as large as the VM memory (1GiB)`},
+		{"2GiB", "2GiB", ""},
+		{"", "4GiB", ""},
+	}
+
+	for _, test := range tests {
+		vm := &qemu{common: common{
+			expandedConfig: map[string]string{"limits.memory": test.memory},
+			expandedDevices: deviceConfig.Devices{
+				"root": {"type": "disk", "path": "/", "pool": "default", "size.state": test.stateSize},
+			},
+		}}
+
+		err := vm.checkStateStorage()
+		if test.err == "" {
+			assert.NoError(t, err)
+		} else {
+			assert.EqualError(t, err, test.err)
+		}
+	}
+}
@@ -322,3 +322,321 @@ func (m *Monitor) GetCPUs() ([]int, error) {
 
 	return pids, nil
 }
+
+// Migrate starts a migration stream to the provided URI.
+func (m *Monitor) Migrate(uri string) error {
+	// Check if disconnected
+	if m.disconnected {
+		return ErrMonitorDisconnect
+	}
+
+	req, err := json.Marshal(map[string]interface{}{
+		"execute":   "migrate",
+		"arguments": map[string]string{"uri": uri},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Start the migration.
+	_, err = m.qmp.Run(req)
+	if err != nil {
+		return fmt.Errorf("Failed to start migration: %v", err)
+	}
+
+	return nil
+}
+
+// MigrateStatus returns the status of the current or last migration.
+func (m *Monitor) MigrateStatus() (string, error) {
+	// Check if disconnected
+	if m.disconnected {
+		return "", ErrMonitorDisconnect
+	}
+
+	// Query the migration.
+	respRaw, err := m.qmp.Run([]byte("{'execute': 'query-migrate'}"))
+	if err != nil {
+		m.Disconnect()
+		return "", ErrMonitorDisconnect
+	}
+
+	// Process the response.
+	var respDecoded struct {
+		Return struct {
+			Status    string `json:"status"`
+			ErrorDesc string `json:"error-desc"`
+		} `json:"return"`
+	}
+
+	err = json.Unmarshal(respRaw, &respDecoded)
+	if err != nil {
+		return "", ErrMonitorBadReturn
+	}
+
+	if respDecoded.Return.Status == "failed" {
+		return respDecoded.Return.Status, fmt.Errorf("Migration failed: %s", respDecoded.Return.ErrorDesc)
+	}
+
+	return respDecoded.Return.Status, nil
+}
+
+// MigrateWait waits until the current migration reaches the "completed" state.
+func (m *Monitor) MigrateWait() error {
+	for {
+		status, err := m.MigrateStatus()
+		if err != nil {
+			return err
+		}
+
+		switch status {
+		case "completed":
+			return nil
+		case "cancelled":
+			return fmt.Errorf("Migration was cancelled")
+		}
+
+		time.Sleep(250 * time.Millisecond)
+	}
+}
+
+// execute runs a command with the provided arguments and decodes its return value into resp (if not nil).
+// Unlike the status queries, errors reported by QEMU for the command are returned to the caller.
+func (m *Monitor) execute(cmd string, args interface{}, resp interface{}) error {
+	// Check if disconnected
+	if m.disconnected {
+		return ErrMonitorDisconnect
+	}
+
+	req := map[string]interface{}{
+		"execute": cmd,
+	}
+
+	if args != nil {
+		req["arguments"] = args
+	}
+
+	reqJSON, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	respRaw, err := m.qmp.Run(reqJSON)
+	if err != nil {
+		return err
+	}
+
+	if resp == nil {
+		return nil
+	}
+
+	err = json.Unmarshal(respRaw, resp)
+	if err != nil {
+		return ErrMonitorBadReturn
+	}
+
+	return nil
+}
+
+// SendFileWithFDSet passes a file descriptor to QEMU in a new file descriptor set and returns the set ID.
+// The file can then be referred to by QEMU as "/dev/fdset/<ID>" which is needed to access host files once
+// QEMU has been chrooted and dropped its privileges.
+func (m *Monitor) SendFileWithFDSet(file *os.File, opaque string) (int, error) {
+	// Check if disconnected
+	if m.disconnected {
+		return -1, ErrMonitorDisconnect
+	}
+
+	reqJSON, err := json.Marshal(map[string]interface{}{
+		"execute":   "add-fd",
+		"arguments": map[string]string{"opaque": opaque},
+	})
+	if err != nil {
+		return -1, err
+	}
+
+	respRaw, err := m.qmp.RunWithFile(reqJSON, file)
+	if err != nil {
+		return -1, fmt.Errorf("Failed to send file descriptor: %v", err)
+	}
+
+	// Process the response.
+	var respDecoded struct {
+		Return struct {
+			FDSetID int `json:"fdset-id"`
+		} `json:"return"`
+	}
+
+	err = json.Unmarshal(respRaw, &respDecoded)
+	if err != nil {
+		return -1, ErrMonitorBadReturn
+	}
+
+	return respDecoded.Return.FDSetID, nil
+}
+
+// RemoveFDSet removes a file descriptor set. QEMU keeps the file open for as long as it is in use.
+func (m *Monitor) RemoveFDSet(fdSetID int) error {
+	return m.execute("remove-fd", map[string]int{"fdset-id": fdSetID}, nil)
+}
+
+// SendFile passes a file descriptor to QEMU under the provided name.
+func (m *Monitor) SendFile(name string, file *os.File) error {
+	// Check if disconnected
+	if m.disconnected {
+		return ErrMonitorDisconnect
+	}
+
+	reqJSON, err := json.Marshal(map[string]interface{}{
+		"execute":   "getfd",
+		"arguments": map[string]string{"fdname": name},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = m.qmp.RunWithFile(reqJSON, file)
+	if err != nil {
+		return fmt.Errorf("Failed to send file descriptor: %v", err)
+	}
+
+	return nil
+}
+
+// CloseFile closes a named file descriptor previously passed to QEMU with SendFile.
+func (m *Monitor) CloseFile(name string) error {
+	return m.execute("closefd", map[string]string{"fdname": name}, nil)
+}
+
+// AddDevice hot-plugs a device into the VM.
+func (m *Monitor) AddDevice(device map[string]interface{}) error {
+	err := m.execute("device_add", device, nil)
+	if err != nil {
+		return fmt.Errorf("Failed adding device: %v", err)
+	}
+
+	return nil
+}
+
+// RemoveDevice requests the removal of a device from the VM.
+// Removal of some devices needs the cooperation of the guest and so completes asynchronously.
+func (m *Monitor) RemoveDevice(deviceID string) error {
+	err := m.execute("device_del", map[string]string{"id": deviceID}, nil)
+	if err != nil {
+		return fmt.Errorf("Failed removing device: %v", err)
+	}
+
+	return nil
+}
+
+// AddBlockDevice adds a block device node and then hot-plugs the guest device using it.
+func (m *Monitor) AddBlockDevice(blockDev map[string]interface{}, device map[string]interface{}) error {
+	err := m.execute("blockdev-add", blockDev, nil)
+	if err != nil {
+		return fmt.Errorf("Failed adding block device: %v", err)
+	}
+
+	err = m.AddDevice(device)
+	if err != nil {
+		m.RemoveBlockDevice(fmt.Sprintf("%v", blockDev["node-name"]))
+		return err
+	}
+
+	return nil
+}
+
+// RemoveBlockDevice removes a block device node which is no longer in use by a guest device.
+func (m *Monitor) RemoveBlockDevice(nodeName string) error {
+	err := m.execute("blockdev-del", map[string]string{"node-name": nodeName}, nil)
+	if err != nil {
+		return fmt.Errorf("Failed removing block device: %v", err)
+	}
+
+	return nil
+}
+
+// AddNIC adds a network backend and then hot-plugs the guest network device using it.
+func (m *Monitor) AddNIC(netDev map[string]interface{}, device map[string]interface{}) error {
+	err := m.execute("netdev_add", netDev, nil)
+	if err != nil {
+		return fmt.Errorf("Failed adding network backend: %v", err)
+	}
+
+	err = m.AddDevice(device)
+	if err != nil {
+		m.RemoveNIC(fmt.Sprintf("%v", netDev["id"]))
+		return err
+	}
+
+	return nil
+}
+
+// RemoveNIC removes a network backend which is no longer in use by a guest device.
+func (m *Monitor) RemoveNIC(netDevID string) error {
+	err := m.execute("netdev_del", map[string]string{"id": netDevID}, nil)
+	if err != nil {
+		return fmt.Errorf("Failed removing network backend: %v", err)
+	}
+
+	return nil
+}
+
+// QueryDevices returns the IDs of all the named devices of the VM.
+func (m *Monitor) QueryDevices() ([]string, error) {
+	var resp struct {
+		Return []struct {
+			Name string `json:"name"`
+			Type string `json:"type"`
+		} `json:"return"`
+	}
+
+	err := m.execute("qom-list", map[string]string{"path": "/machine/peripheral"}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	devices := []string{}
+	for _, entry := range resp.Return {
+		if !strings.HasPrefix(entry.Type, "child<") {
+			continue
+		}
+
+		devices = append(devices, entry.Name)
+	}
+
+	return devices, nil
+}
+
+// PCIDevice represents a device on a PCI bus of the VM.
+type PCIDevice struct {
+	DevID  string     `json:"qdev_id"`
+	Bridge *PCIBridge `json:"pci_bridge"`
+}
+
+// PCIBridge represents the secondary bus of a PCI bridge or PCIe root port.
+type PCIBridge struct {
+	Devices []PCIDevice `json:"devices"`
+}
+
+// QueryPCI returns the devices on the root PCI bus of the VM.
+func (m *Monitor) QueryPCI() ([]PCIDevice, error) {
+	var resp struct {
+		Return []struct {
+			Bus     int         `json:"bus"`
+			Devices []PCIDevice `json:"devices"`
+		} `json:"return"`
+	}
+
+	err := m.execute("query-pci", nil, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, bus := range resp.Return {
+		if bus.Bus == 0 {
+			return bus.Devices, nil
+		}
+	}
+
+	return nil, ErrMonitorBadReturn
+}
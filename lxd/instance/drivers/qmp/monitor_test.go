@@ -0,0 +1,390 @@
+package qmp
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCommand is a command received by the test QMP server.
+type testCommand struct {
+	Execute   string                 `json:"execute"`
+	Arguments map[string]interface{} `json:"arguments"`
+
+	// Number of file descriptors passed along with the command.
+	files int
+}
+
+// testServer is a minimal QMP server recording the commands it receives.
+type testServer struct {
+	dir       string
+	path      string
+	listener  *net.UnixListener
+	monitor   *Monitor
+	responses map[string]string
+
+	mu       sync.Mutex
+	commands []testCommand
+}
+
+// newTestServer starts a QMP server answering the commands using responses, indexed by command name.
+// Commands without a response get an empty return value.
+func newTestServer(t *testing.T, responses map[string]string) *testServer {
+	dir, err := ioutil.TempDir("", "lxd-qmp-")
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, "qmp.monitor")
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: path, Net: "unix"})
+	require.NoError(t, err)
+
+	s := &testServer{dir: dir, path: path, listener: listener, responses: responses}
+	go s.serve()
+
+	return s
+}
+
+// close disconnects the monitor connected to the server and stops it.
+func (s *testServer) close() {
+	if s.monitor != nil {
+		s.monitor.Disconnect()
+	}
+
+	s.listener.Close()
+	os.RemoveAll(s.dir)
+}
+
+func (s *testServer) serve() {
+	conn, err := s.listener.AcceptUnix()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(`{"QMP": {"version": {"qemu": {"micro": 0, "minor": 2, "major": 5}, "package": ""}, "capabilities": []}}` + "\n"))
+	if err != nil {
+		return
+	}
+
+	buf := make([]byte, 4096)
+	oob := make([]byte, syscall.CmsgSpace(4))
+	for {
+		n, oobn, _, _, err := conn.ReadMsgUnix(buf, oob)
+		if err != nil {
+			return
+		}
+
+		// Close the received file descriptors, only their number is recorded.
+		files := 0
+		msgs, err := syscall.ParseSocketControlMessage(oob[:oobn])
+		if err == nil {
+			for _, msg := range msgs {
+				fds, err := syscall.ParseUnixRights(&msg)
+				if err != nil {
+					continue
+				}
+
+				for _, fd := range fds {
+					syscall.Close(fd)
+				}
+
+				files += len(fds)
+			}
+		}
+
+		dec := json.NewDecoder(bytes.NewReader(buf[:n]))
+		for {
+			cmd := testCommand{}
+			err = dec.Decode(&cmd)
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return
+			}
+
+			cmd.files = files
+			files = 0
+
+			resp, ok := s.responses[cmd.Execute]
+			if !ok {
+				resp = `{"return": {}}`
+			}
+
+			if cmd.Execute == "ringbuf-read" {
+				resp = `{"return": ""}`
+			} else if cmd.Execute != "qmp_capabilities" {
+				s.mu.Lock()
+				s.commands = append(s.commands, cmd)
+				s.mu.Unlock()
+			}
+
+			_, err = conn.Write([]byte(resp + "\n"))
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+// received returns the commands received so far, excluding the ones sent by the monitor itself.
+func (s *testServer) received() []testCommand {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]testCommand{}, s.commands...)
+}
+
+// connect connects a monitor to the test server.
+func (s *testServer) connect(t *testing.T) *Monitor {
+	monitor, err := Connect(s.path, "console", nil)
+	require.NoError(t, err)
+
+	s.monitor = monitor
+
+	return monitor
+}
+
+const testErrorResponse = `{"error": {"class": "GenericError", "desc": "failure"}}`
+
+func TestMonitor_AddRemoveDevice(t *testing.T) {
+	s := newTestServer(t, nil)
+	defer s.close()
+
+	monitor := s.connect(t)
+
+	err := monitor.AddDevice(map[string]interface{}{"driver": "virtio-net-pci", "id": "dev-lxd_eth0", "bus": "qemu_pcie1"})
+	require.NoError(t, err)
+
+	err = monitor.RemoveDevice("dev-lxd_eth0")
+	require.NoError(t, err)
+
+	assert.Equal(t, []testCommand{
+		{Execute: "device_add", Arguments: map[string]interface{}{"driver": "virtio-net-pci", "id": "dev-lxd_eth0", "bus": "qemu_pcie1"}},
+		{Execute: "device_del", Arguments: map[string]interface{}{"id": "dev-lxd_eth0"}},
+	}, s.received())
+}
+
+func TestMonitor_AddDeviceError(t *testing.T) {
+	s := newTestServer(t, map[string]string{"device_add": testErrorResponse, "device_del": testErrorResponse})
+	defer s.close()
+
+	monitor := s.connect(t)
+
+	err := monitor.AddDevice(map[string]interface{}{"driver": "virtio-net-pci", "id": "dev-lxd_eth0"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Failed adding device")
+
+	err = monitor.RemoveDevice("dev-lxd_eth0")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Failed removing device")
+}
+
+func TestMonitor_AddRemoveBlockDevice(t *testing.T) {
+	s := newTestServer(t, nil)
+	defer s.close()
+
+	monitor := s.connect(t)
+
+	blockDev := map[string]interface{}{"driver": "raw", "node-name": "lxd_data"}
+	device := map[string]interface{}{"driver": "scsi-hd", "id": "dev-lxd_data", "drive": "lxd_data"}
+
+	err := monitor.AddBlockDevice(blockDev, device)
+	require.NoError(t, err)
+
+	err = monitor.RemoveBlockDevice("lxd_data")
+	require.NoError(t, err)
+
+	assert.Equal(t, []testCommand{
+		{Execute: "blockdev-add", Arguments: blockDev},
+		{Execute: "device_add", Arguments: device},
+		{Execute: "blockdev-del", Arguments: map[string]interface{}{"node-name": "lxd_data"}},
+	}, s.received())
+}
+
+func TestMonitor_AddBlockDeviceRevert(t *testing.T) {
+	s := newTestServer(t, map[string]string{"device_add": testErrorResponse})
+	defer s.close()
+
+	monitor := s.connect(t)
+
+	blockDev := map[string]interface{}{"driver": "raw", "node-name": "lxd_data"}
+	device := map[string]interface{}{"driver": "scsi-hd", "id": "dev-lxd_data", "drive": "lxd_data"}
+
+	// The block device node is removed again when the guest device can't be added.
+	err := monitor.AddBlockDevice(blockDev, device)
+	assert.Error(t, err)
+
+	assert.Equal(t, []testCommand{
+		{Execute: "blockdev-add", Arguments: blockDev},
+		{Execute: "device_add", Arguments: device},
+		{Execute: "blockdev-del", Arguments: map[string]interface{}{"node-name": "lxd_data"}},
+	}, s.received())
+}
+
+func TestMonitor_AddBlockDeviceError(t *testing.T) {
+	s := newTestServer(t, map[string]string{"blockdev-add": testErrorResponse})
+	defer s.close()
+
+	monitor := s.connect(t)
+
+	// The guest device isn't added when the block device node can't be.
+	err := monitor.AddBlockDevice(map[string]interface{}{"driver": "raw", "node-name": "lxd_data"}, map[string]interface{}{"driver": "scsi-hd"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Failed adding block device")
+	assert.Len(t, s.received(), 1)
+}
+
+func TestMonitor_AddRemoveNIC(t *testing.T) {
+	s := newTestServer(t, nil)
+	defer s.close()
+
+	monitor := s.connect(t)
+
+	netDev := map[string]interface{}{"type": "tap", "id": "lxd_eth0", "ifname": "tap1234"}
+	device := map[string]interface{}{"driver": "virtio-net-pci", "id": "dev-lxd_eth0", "netdev": "lxd_eth0"}
+
+	err := monitor.AddNIC(netDev, device)
+	require.NoError(t, err)
+
+	err = monitor.RemoveNIC("lxd_eth0")
+	require.NoError(t, err)
+
+	assert.Equal(t, []testCommand{
+		{Execute: "netdev_add", Arguments: netDev},
+		{Execute: "device_add", Arguments: device},
+		{Execute: "netdev_del", Arguments: map[string]interface{}{"id": "lxd_eth0"}},
+	}, s.received())
+}
+
+func TestMonitor_AddNICRevert(t *testing.T) {
+	s := newTestServer(t, map[string]string{"device_add": testErrorResponse})
+	defer s.close()
+
+	monitor := s.connect(t)
+
+	netDev := map[string]interface{}{"type": "tap", "id": "lxd_eth0", "ifname": "tap1234"}
+	device := map[string]interface{}{"driver": "virtio-net-pci", "id": "dev-lxd_eth0", "netdev": "lxd_eth0"}
+
+	// The network backend is removed again when the guest device can't be added.
+	err := monitor.AddNIC(netDev, device)
+	assert.Error(t, err)
+
+	assert.Equal(t, []testCommand{
+		{Execute: "netdev_add", Arguments: netDev},
+		{Execute: "device_add", Arguments: device},
+		{Execute: "netdev_del", Arguments: map[string]interface{}{"id": "lxd_eth0"}},
+	}, s.received())
+}
+
+func TestMonitor_QueryDevices(t *testing.T) {
+	s := newTestServer(t, map[string]string{
+		"qom-list": `{"return": [{"name": "type", "type": "string"}, {"name": "dev-lxd_root", "type": "child<virtio-blk-pci>"}, {"name": "dev-lxd_eth0", "type": "child<virtio-net-pci>"}]}`,
+	})
+	defer s.close()
+
+	monitor := s.connect(t)
+
+	devices, err := monitor.QueryDevices()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dev-lxd_root", "dev-lxd_eth0"}, devices)
+
+	assert.Equal(t, []testCommand{
+		{Execute: "qom-list", Arguments: map[string]interface{}{"path": "/machine/peripheral"}},
+	}, s.received())
+}
+
+func TestMonitor_QueryPCI(t *testing.T) {
+	s := newTestServer(t, map[string]string{
+		"query-pci": `{"return": [{"bus": 1, "devices": []}, {"bus": 0, "devices": [{"qdev_id": "qemu_pcie1", "pci_bridge": {"devices": [{"qdev_id": "dev-lxd_eth0"}]}}, {"qdev_id": ""}]}]}`,
+	})
+	defer s.close()
+
+	monitor := s.connect(t)
+
+	devices, err := monitor.QueryPCI()
+	require.NoError(t, err)
+	assert.Equal(t, []PCIDevice{
+		{DevID: "qemu_pcie1", Bridge: &PCIBridge{Devices: []PCIDevice{{DevID: "dev-lxd_eth0"}}}},
+		{DevID: ""},
+	}, devices)
+}
+
+func TestMonitor_QueryPCINoRootBus(t *testing.T) {
+	s := newTestServer(t, map[string]string{"query-pci": `{"return": [{"bus": 1, "devices": []}]}`})
+	defer s.close()
+
+	monitor := s.connect(t)
+
+	_, err := monitor.QueryPCI()
+	assert.Equal(t, ErrMonitorBadReturn, err)
+}
+
+func TestMonitor_SendCloseFile(t *testing.T) {
+	s := newTestServer(t, nil)
+	defer s.close()
+
+	monitor := s.connect(t)
+
+	f, err := ioutil.TempFile("", "lxd-qmp-file-")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	err = monitor.SendFile("lxd_eth0.0", f)
+	require.NoError(t, err)
+
+	err = monitor.CloseFile("lxd_eth0.0")
+	require.NoError(t, err)
+
+	assert.Equal(t, []testCommand{
+		{Execute: "getfd", Arguments: map[string]interface{}{"fdname": "lxd_eth0.0"}, files: 1},
+		{Execute: "closefd", Arguments: map[string]interface{}{"fdname": "lxd_eth0.0"}},
+	}, s.received())
+}
+
+func TestMonitor_SendFileWithFDSet(t *testing.T) {
+	s := newTestServer(t, map[string]string{"add-fd": `{"return": {"fdset-id": 3, "fd": 10}}`})
+	defer s.close()
+
+	monitor := s.connect(t)
+
+	f, err := ioutil.TempFile("", "lxd-qmp-file-")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	fdSetID, err := monitor.SendFileWithFDSet(f, "lxd_data")
+	require.NoError(t, err)
+	assert.Equal(t, 3, fdSetID)
+
+	err = monitor.RemoveFDSet(fdSetID)
+	require.NoError(t, err)
+
+	assert.Equal(t, []testCommand{
+		{Execute: "add-fd", Arguments: map[string]interface{}{"opaque": "lxd_data"}, files: 1},
+		{Execute: "remove-fd", Arguments: map[string]interface{}{"fdset-id": float64(3)}},
+	}, s.received())
+}
+
+func TestMonitor_Disconnected(t *testing.T) {
+	s := newTestServer(t, nil)
+	defer s.close()
+
+	monitor := s.connect(t)
+	monitor.Disconnect()
+
+	assert.Error(t, monitor.AddDevice(map[string]interface{}{"driver": "virtio-net-pci"}))
+	assert.Equal(t, ErrMonitorDisconnect, monitor.RemoveFDSet(3))
+
+	_, err := monitor.QueryDevices()
+	assert.Equal(t, ErrMonitorDisconnect, err)
+}
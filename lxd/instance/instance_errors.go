@@ -6,3 +6,6 @@ import (
 
 // ErrNotImplemented is the "Not implemented" error
 var ErrNotImplemented = fmt.Errorf("Not implemented")
+
+// ErrInstanceIsStopped indicates that the instance is stopped.
+var ErrInstanceIsStopped = fmt.Errorf("The instance is already stopped")
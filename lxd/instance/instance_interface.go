@@ -11,6 +11,7 @@ import (
 	"github.com/lxc/lxd/lxd/db"
 	deviceConfig "github.com/lxc/lxd/lxd/device/config"
 	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/metrics"
 	"github.com/lxc/lxd/lxd/operations"
 	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/idmap"
@@ -87,6 +88,7 @@ type Instance interface {
 	Render(options ...func(response interface{}) error) (interface{}, interface{}, error)
 	RenderFull() (*api.InstanceFull, interface{}, error)
 	RenderState() (*api.InstanceState, error)
+	Metrics() (*metrics.MetricSet, error)
 	IsRunning() bool
 	IsFrozen() bool
 	IsEphemeral() bool
@@ -150,6 +152,16 @@ type Container interface {
 	DevptsFd() (*os.File, error)
 }
 
+// VM interface is for VM specific functions.
+type VM interface {
+	Instance
+
+	MigrateState(w io.Writer) error
+}
+
+// VMStateFile is the name of the file holding a VM's runtime state within a migration state directory.
+const VMStateFile = "qemu.state"
+
 // CriuMigrationArgs arguments for CRIU migration.
 type CriuMigrationArgs struct {
 	Cmd          uint
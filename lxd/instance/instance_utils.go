@@ -209,6 +209,10 @@ func validConfigKey(os *sys.OS, key string, value string) error {
 	if key == "raw.lxc" {
 		return lxcValidConfig(value)
 	}
+	if key == "backups.target" && value != "" {
+		_, err := backup.ParseTarget(value)
+		return err
+	}
 	if key == "security.syscalls.deny_compat" || key == "security.syscalls.blacklist_compat" {
 		for _, arch := range os.Architectures {
 			if arch == osarch.ARCH_64BIT_INTEL_X86 ||
@@ -499,6 +503,24 @@ func LoadByProjectAndName(s *state.State, project, name string) (Instance, error
 	return inst, nil
 }
 
+// IsMovedAway returns whether the database record of the instance points to another cluster member.
+// This is the case of the local copy of an instance which was live migrated or healed away, in which
+// case stopping it must only release the local host resources and leave the instance state alone.
+func IsMovedAway(s *state.State, inst Instance) bool {
+	var address string
+	err := s.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		address, err = tx.GetNodeAddressOfInstance(inst.Project(), inst.Name(), inst.Type())
+		return err
+	})
+	if err != nil {
+		logger.Warn("Failed to get instance location", log.Ctx{"project": inst.Project(), "instance": inst.Name(), "err": err})
+		return false
+	}
+
+	return address != ""
+}
+
 // LoadAllInternal loads a list of db instances into a list of instances.
 func LoadAllInternal(s *state.State, dbInstances []db.Instance) ([]Instance, error) {
 	// Figure out what profiles are in use
@@ -674,7 +696,7 @@ func BackupLoadByName(s *state.State, project, name string) (*backup.Backup, err
 		return nil, errors.Wrap(err, "Load instance from database")
 	}
 
-	return backup.New(s, instance, args.ID, name, args.CreationDate, args.ExpiryDate, args.InstanceOnly, args.OptimizedStorage), nil
+	return backup.New(s, instance, args.ID, name, args.CreationDate, args.ExpiryDate, args.InstanceOnly, args.OptimizedStorage, args.Parent), nil
 }
 
 // ResolveImage takes an instance source and returns a hash suitable for instance creation or download.
@@ -843,6 +865,16 @@ func SuitableArchitectures(s *state.State, project string, req api.InstancesPost
 				if err != nil {
 					return nil, err
 				}
+			} else if req.Source.Protocol == "oci" {
+				// Remote OCI registry.
+				remote, err = lxd.ConnectOCI(req.Source.Server, &lxd.ConnectionArgs{
+					TLSServerCert: req.Source.Certificate,
+					UserAgent:     version.UserAgent,
+					Proxy:         s.Proxy,
+				})
+				if err != nil {
+					return nil, err
+				}
 			} else {
 				return nil, fmt.Errorf("Unsupported remote image server protocol: %s", req.Source.Protocol)
 			}
@@ -2,12 +2,16 @@ package main
 
 import (
 	"bytes"
+	"crypto/x509"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strings"
 
 	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
 	"github.com/pborman/uuid"
 	"github.com/pkg/errors"
 
@@ -20,6 +24,7 @@ import (
 	"github.com/lxc/lxd/lxd/operations"
 	"github.com/lxc/lxd/lxd/project"
 	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/revert"
 	driver "github.com/lxc/lxd/lxd/storage"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
@@ -32,6 +37,19 @@ var internalClusterContainerMovedCmd = APIEndpoint{
 	Post: APIEndpointAction{Handler: internalClusterContainerMovedPost},
 }
 
+var internalClusterInstanceMoveCmd = APIEndpoint{
+	Path: "cluster/instance-move/{name}",
+
+	Post: APIEndpointAction{Handler: internalClusterInstanceMovePost},
+}
+
+// internalClusterInstanceMovePostRequest is used to ask a cluster node to receive a live migrated instance.
+type internalClusterInstanceMovePostRequest struct {
+	Operation     string            `json:"operation" yaml:"operation"`
+	Websockets    map[string]string `json:"websockets" yaml:"websockets"`
+	StorageShared bool              `json:"storage_shared" yaml:"storage_shared"`
+}
+
 func containerPost(d *Daemon, r *http.Request) response.Response {
 	instanceType, err := urlInstanceTypeDetect(r)
 	if err != nil {
@@ -50,9 +68,24 @@ func containerPost(d *Daemon, r *http.Request) response.Response {
 	// online (only relevant if "?target=<node>" was given).
 	targetNodeOffline := false
 
+	// Flag indicating whether the node the container should be moved to is
+	// evacuated (only relevant if "?target=<node>" was given).
+	targetNodeEvacuated := false
+
 	// A POST to /containers/<name>?target=<node> is meant to be used to
 	// move a container from one node to another within a cluster.
 	if targetNode != "" {
+		var resp response.Response
+		targetNode, resp = instancePostClusterTarget(d, project, targetNode)
+		if resp != nil {
+			return resp
+		}
+
+		// Have the member running the instance use the resolved target when the request gets forwarded.
+		query := r.URL.Query()
+		query.Set("target", targetNode)
+		r.URL.RawQuery = query.Encode()
+
 		// Determine if either the source node (the one currently
 		// running the container) or the target node are offline.
 		//
@@ -80,6 +113,8 @@ func containerPost(d *Daemon, r *http.Request) response.Response {
 			}
 			targetNodeOffline = node.IsOffline(config.OfflineThreshold())
 
+			targetNodeEvacuated = node.State == db.ClusterMemberStateEvacuated
+
 			// Load source node.
 			address, err := tx.GetNodeAddressOfInstance(project, name, instanceType)
 			if err != nil {
@@ -107,6 +142,10 @@ func containerPost(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(fmt.Errorf("Target node is offline"))
 	}
 
+	if targetNode != "" && targetNodeEvacuated {
+		return response.BadRequest(fmt.Errorf("Target node is evacuated"))
+	}
+
 	// Check whether to forward the request to the node that is running the
 	// container. Here are the possible cases:
 	//
@@ -173,9 +212,14 @@ func containerPost(d *Daemon, r *http.Request) response.Response {
 
 	if req.Migration {
 		if targetNode != "" {
-			// Check whether the container is running.
+			// Check whether the container is running. Running VMs can be live migrated.
+			liveMove := false
 			if !sourceNodeOffline && inst.IsRunning() {
-				return response.BadRequest(fmt.Errorf("Container is running"))
+				if inst.Type() != instancetype.VM || !stateful {
+					return response.BadRequest(fmt.Errorf("Container is running"))
+				}
+
+				liveMove = true
 			}
 
 			// Check if we are migrating a ceph-based container.
@@ -189,6 +233,10 @@ func containerPost(d *Daemon, r *http.Request) response.Response {
 				err = errors.Wrap(err, "Failed to fetch instance's pool info")
 				return response.SmartError(err)
 			}
+			if liveMove {
+				return containerPostClusteringMigrateLive(d, inst, req.Name, targetNode, pool.Driver == "ceph")
+			}
+
 			if pool.Driver == "ceph" {
 				return containerPostClusteringMigrateWithCeph(d, inst, project, name, req.Name, targetNode, instanceType)
 			}
@@ -270,8 +318,93 @@ func containerPost(d *Daemon, r *http.Request) response.Response {
 	return operations.OperationResponse(op)
 }
 
+// instancePostClusterTarget checks that the project's restrictions allow moving its instances to the given
+// target and returns the name of the target member. A target prefixed with "@" refers to a cluster group, in
+// which case the member of the group with the least instances is picked.
+func instancePostClusterTarget(d *Daemon, projectName string, target string) (string, response.Response) {
+	var projectConfig map[string]string
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		p, err := tx.GetProject(projectName)
+		if err != nil {
+			return err
+		}
+
+		projectConfig = p.Config
+		return nil
+	})
+	if err != nil {
+		return "", response.SmartError(err)
+	}
+
+	if !strings.HasPrefix(target, "@") {
+		var groups []string
+		err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			node, err := tx.GetNodeByName(target)
+			if err != nil {
+				return errors.Wrap(err, "Failed to get target node")
+			}
+
+			groups, err = tx.GetNodeClusterGroups(node.ID)
+			return err
+		})
+		if err != nil {
+			return "", response.SmartError(err)
+		}
+
+		err = project.ClusterMemberAllowed(projectConfig, target, groups)
+		if err != nil {
+			return "", response.Forbidden(err)
+		}
+
+		return target, nil
+	}
+
+	group := strings.TrimPrefix(target, "@")
+	err = project.ClusterGroupAllowed(projectConfig, group)
+	if err != nil {
+		return "", response.Forbidden(err)
+	}
+
+	var targetNode string
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		_, err := tx.GetClusterGroup(group)
+		if err != nil {
+			return errors.Wrapf(err, "Failed loading cluster group %q", group)
+		}
+
+		targetNode, err = tx.GetNodeWithLeastInstances(nil, group, project.GetRestrictedClusterGroups(projectConfig), projectName, "")
+		return err
+	})
+	if err != nil {
+		return "", response.SmartError(err)
+	}
+
+	if targetNode == "" {
+		return "", response.BadRequest(fmt.Errorf("No suitable cluster member could be found in group %q", group))
+	}
+
+	return targetNode, nil
+}
+
 // Move a non-ceph container to another cluster node.
 func containerPostClusteringMigrate(d *Daemon, c instance.Instance, oldName, newName, newNode string) response.Response {
+	run := func(*operations.Operation) error {
+		return migrateClusterInstance(d, c, oldName, newName, newNode)
+	}
+
+	resources := map[string][]string{}
+	resources["containers"] = []string{oldName}
+	op, err := operations.OperationCreate(d.State(), c.Project(), operations.OperationClassTask, db.OperationContainerMigrate, resources, nil, run, nil, nil)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return operations.OperationResponse(op)
+}
+
+// migrateClusterInstance moves a non-ceph instance from the local node to another cluster node, by copying it
+// to the new node and then deleting the local copy.
+func migrateClusterInstance(d *Daemon, c instance.Instance, oldName, newName, newNode string) error {
 	cert := d.endpoints.NetworkCert()
 
 	var sourceAddress string
@@ -298,119 +431,313 @@ func containerPostClusteringMigrate(d *Daemon, c instance.Instance, oldName, new
 		return nil
 	})
 	if err != nil {
-		return response.SmartError(err)
+		return err
 	}
 
-	run := func(*operations.Operation) error {
-		// Connect to the source host, i.e. ourselves (the node the container is running on).
-		source, err := cluster.Connect(sourceAddress, cert, true)
-		if err != nil {
-			return errors.Wrap(err, "Failed to connect to source server")
+	// Connect to the source host, i.e. ourselves (the node the container is running on).
+	source, err := cluster.Connect(sourceAddress, cert, true)
+	if err != nil {
+		return errors.Wrap(err, "Failed to connect to source server")
+	}
+	source = source.UseProject(c.Project())
+
+	// Connect to the destination host, i.e. the node to migrate the container to.
+	dest, err := cluster.Connect(targetAddress, cert, false)
+	if err != nil {
+		return errors.Wrap(err, "Failed to connect to destination server")
+	}
+	dest = dest.UseTarget(newNode).UseProject(c.Project())
+
+	destName := newName
+	isSameName := false
+
+	// If no new name was provided, the user wants to keep the same
+	// container name. In that case we need to generate a temporary
+	// name.
+	if destName == "" || destName == oldName {
+		isSameName = true
+		destName = fmt.Sprintf("move-%s", uuid.NewRandom().String())
+	}
+
+	// First make a copy on the new node of the container to be moved.
+	entry, _, err := source.GetInstance(oldName)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get instance info")
+	}
+
+	args := lxd.InstanceCopyArgs{
+		Name: destName,
+		Mode: "pull",
+	}
+
+	copyOp, err := dest.CopyInstance(source, *entry, &args)
+	if err != nil {
+		return errors.Wrap(err, "Failed to issue copy instance API request")
+	}
+
+	err = copyOp.Wait()
+	if err != nil {
+		return errors.Wrap(err, "Copy instance operation failed")
+	}
+
+	// Delete the container on the original node.
+	deleteOp, err := source.DeleteInstance(oldName)
+	if err != nil {
+		return errors.Wrap(err, "Failed to issue delete instance API request")
+	}
+
+	err = deleteOp.Wait()
+	if err != nil {
+		return errors.Wrap(err, "Delete instance operation failed")
+	}
+
+	// If the destination name is not set, we have generated a random name for
+	// the new container, so we need to rename it.
+	if isSameName {
+		instancePost := api.InstancePost{
+			Name: oldName,
 		}
 
-		// Connect to the destination host, i.e. the node to migrate the container to.
-		dest, err := cluster.Connect(targetAddress, cert, false)
+		op, err := dest.RenameInstance(destName, instancePost)
 		if err != nil {
-			return errors.Wrap(err, "Failed to connect to destination server")
+			return errors.Wrap(err, "Failed to issue rename instance API request")
 		}
-		dest = dest.UseTarget(newNode)
 
-		destName := newName
-		isSameName := false
-
-		// If no new name was provided, the user wants to keep the same
-		// container name. In that case we need to generate a temporary
-		// name.
-		if destName == "" || destName == oldName {
-			isSameName = true
-			destName = fmt.Sprintf("move-%s", uuid.NewRandom().String())
+		err = op.Wait()
+		if err != nil {
+			return errors.Wrap(err, "Rename instance operation failed")
 		}
+		destName = oldName
+	}
 
-		// First make a copy on the new node of the container to be moved.
-		entry, _, err := source.GetInstance(oldName)
+	// Restore the original value of "volatile.apply_template"
+	project := c.Project()
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		id, err := tx.GetInstanceID(project, destName)
+		if err != nil {
+			return errors.Wrap(err, "Failed to get ID of moved instance")
+		}
+		err = tx.DeleteInstanceConfigKey(id, "volatile.apply_template")
 		if err != nil {
-			return errors.Wrap(err, "Failed to get instance info")
+			return errors.Wrap(err, "Failed to remove volatile.apply_template config key")
 		}
 
-		args := lxd.InstanceCopyArgs{
-			Name: destName,
-			Mode: "pull",
+		if origVolatileApplyTemplate != "" {
+			config := map[string]string{
+				"volatile.apply_template": origVolatileApplyTemplate,
+			}
+			err = tx.CreateInstanceConfig(int(id), config)
+			if err != nil {
+				return errors.Wrap(err, "Failed to set volatile.apply_template config key")
+			}
 		}
 
-		copyOp, err := dest.CopyInstance(source, *entry, &args)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Live migrate a running VM to another cluster node.
+func containerPostClusteringMigrateLive(d *Daemon, inst instance.Instance, newName, newNode string, storageShared bool) response.Response {
+	if newName != "" && newName != inst.Name() {
+		return response.BadRequest(fmt.Errorf("Running instances can't be renamed while being moved"))
+	}
+
+	if inst.IsEphemeral() {
+		return response.BadRequest(fmt.Errorf("Ephemeral instances can't be live migrated between cluster members"))
+	}
+
+	// Snapshots are only moved along with the instance when the storage is shared.
+	if !storageShared {
+		snapshots, err := inst.Snapshots()
 		if err != nil {
-			return errors.Wrap(err, "Failed to issue copy instance API request")
+			return response.SmartError(err)
 		}
 
-		err = copyOp.Wait()
-		if err != nil {
-			return errors.Wrap(err, "Copy instance operation failed")
+		if len(snapshots) > 0 {
+			return response.BadRequest(fmt.Errorf("Instances with snapshots can only be live migrated between cluster members on shared storage"))
 		}
+	}
+
+	run := func(op *operations.Operation) error {
+		return migrateClusterInstanceLive(d, inst, newNode, storageShared, op)
+	}
+
+	resources := map[string][]string{}
+	resources["instances"] = []string{inst.Name()}
+	op, err := operations.OperationCreate(d.State(), inst.Project(), operations.OperationClassTask, db.OperationContainerMigrate, resources, nil, run, nil, nil)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return operations.OperationResponse(op)
+}
 
-		// Delete the container on the original node.
-		deleteOp, err := source.DeleteInstance(oldName)
+// migrateClusterInstanceLive moves a running VM to another cluster node using the migration protocol. The
+// target node receives the state and restores the VM using the existing instance record. When the storage
+// is shared, only the VM state is transferred, otherwise the VM is paused while its volume is transferred
+// too and the local copy is deleted once the VM is running on the target node. The source VM stays paused
+// until the target node confirms the restore and is resumed if it fails.
+func migrateClusterInstanceLive(d *Daemon, inst instance.Instance, newNode string, storageShared bool, op *operations.Operation) error {
+	var sourceAddress string
+	var targetAddress string
+
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+
+		sourceAddress, err = tx.GetLocalNodeAddress()
 		if err != nil {
-			return errors.Wrap(err, "Failed to issue delete instance API request")
+			return errors.Wrap(err, "Failed to get local node address")
 		}
 
-		err = deleteOp.Wait()
+		node, err := tx.GetNodeByName(newNode)
 		if err != nil {
-			return errors.Wrap(err, "Delete instance operation failed")
+			return errors.Wrap(err, "Failed to get new node address")
 		}
+		targetAddress = node.Address
 
-		// If the destination name is not set, we have generated a random name for
-		// the new container, so we need to rename it.
-		if isSameName {
-			instancePost := api.InstancePost{
-				Name: oldName,
-			}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
 
-			op, err := dest.RenameInstance(destName, instancePost)
-			if err != nil {
-				return errors.Wrap(err, "Failed to issue rename instance API request")
-			}
+	// Keep the volatile keys of the source VM to restore them if the migration fails, as the target node
+	// updates the same instance record when restoring the VM.
+	sourceConfig := inst.LocalConfig()
 
-			err = op.Wait()
+	ws, err := newMigrationSource(inst, true, true)
+	if err != nil {
+		return errors.Wrap(err, "Failed to setup migration source")
+	}
+
+	ws.clusterMove = true
+	ws.storageShared = storageShared
+
+	run := func(op *operations.Operation) error {
+		return ws.Do(d.State(), op)
+	}
+
+	cancel := func(op *operations.Operation) error {
+		ws.disconnect()
+		return nil
+	}
+
+	resources := map[string][]string{}
+	resources["instances"] = []string{inst.Name()}
+	sourceOp, err := operations.OperationCreate(d.State(), inst.Project(), operations.OperationClassWebsocket, db.OperationContainerMigrate, resources, ws.Metadata(), run, cancel, ws.Connect)
+	if err != nil {
+		return err
+	}
+
+	chSource, err := sourceOp.Run()
+	if err != nil {
+		return err
+	}
+
+	// Ask the target node to receive the instance.
+	client, err := cluster.Connect(targetAddress, d.endpoints.NetworkCert(), false)
+	if err != nil {
+		sourceOp.Cancel()
+		return errors.Wrap(err, "Failed to connect to target node")
+	}
+
+	req := internalClusterInstanceMovePostRequest{
+		Operation:     fmt.Sprintf("https://%s%s", sourceAddress, sourceOp.URL()),
+		Websockets:    map[string]string{"control": ws.controlSecret, "fs": ws.fsSecret, "criu": ws.criuSecret},
+		StorageShared: storageShared,
+	}
+
+	path := fmt.Sprintf("/internal/cluster/instance-move/%s?project=%s", inst.Name(), inst.Project())
+	targetOp, _, err := client.RawOperation("POST", path, req, "")
+	if err != nil {
+		sourceOp.Cancel()
+		return errors.Wrap(err, "Failed to migrate instance to target node")
+	}
+
+	sourceErr := <-chSource
+	targetErr := targetOp.Wait()
+	if sourceErr != nil || targetErr != nil {
+		// Resume the source VM if it was left paused and restore its own volatile keys.
+		if inst.IsFrozen() {
+			err = inst.Unfreeze()
 			if err != nil {
-				return errors.Wrap(err, "Rename instance operation failed")
+				logger.Errorf("Failed resuming VM after failed migration: %v", err)
 			}
-			destName = oldName
 		}
 
-		// Restore the original value of "volatile.apply_template"
-		project := c.Project()
-		err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
-			id, err := tx.GetInstanceID(project, destName)
-			if err != nil {
-				return errors.Wrap(err, "Failed to get ID of moved instance")
-			}
-			err = tx.DeleteInstanceConfigKey(id, "volatile.apply_template")
-			if err != nil {
-				return errors.Wrap(err, "Failed to remove volatile.apply_template config key")
-			}
+		current, err := instance.LoadByProjectAndName(d.State(), inst.Project(), inst.Name())
+		if err == nil {
+			err = inst.VolatileSet(migrationVolatileChanges(current.LocalConfig(), sourceConfig))
+		}
 
-			if origVolatileApplyTemplate != "" {
-				config := map[string]string{
-					"volatile.apply_template": origVolatileApplyTemplate,
-				}
-				err = tx.CreateInstanceConfig(int(id), config)
-				if err != nil {
-					return errors.Wrap(err, "Failed to set volatile.apply_template config key")
-				}
-			}
+		if err != nil {
+			logger.Errorf("Failed restoring volatile keys after failed migration: %v", err)
+		}
 
-			return nil
-		})
+		if sourceErr != nil {
+			return errors.Wrap(sourceErr, "Failed to migrate instance to target node")
+		}
+
+		return errors.Wrap(targetErr, "Failed to migrate instance to target node")
+	}
+
+	// The VM now runs on the target node which owns the instance record, so stopping the paused source VM
+	// only releases its local resources.
+	err = inst.Stop(false)
+	if err != nil {
+		return errors.Wrap(err, "Failed to stop instance on source node")
+	}
+
+	// Remove the local copy of the volume now that the VM runs from the target node.
+	if !storageShared {
+		pool, err := driver.GetPoolByInstance(d.State(), inst)
 		if err != nil {
 			return err
 		}
 
-		return nil
+		err = pool.DeleteInstance(inst, op)
+		if err != nil {
+			return errors.Wrap(err, "Failed to delete instance volume on source node")
+		}
+	}
+
+	return nil
+}
+
+// migrationVolatileChanges returns the changes to apply to the volatile keys of current to match wanted.
+// Volatile keys missing from wanted are reset.
+func migrationVolatileChanges(current map[string]string, wanted map[string]string) map[string]string {
+	changes := map[string]string{}
+	for key, value := range wanted {
+		if strings.HasPrefix(key, "volatile.") && current[key] != value {
+			changes[key] = value
+		}
+	}
+
+	for key := range current {
+		_, ok := wanted[key]
+		if strings.HasPrefix(key, "volatile.") && !ok {
+			changes[key] = ""
+		}
+	}
+
+	return changes
+}
+
+// Special case migrating a container backed by ceph across two cluster nodes.
+func containerPostClusteringMigrateWithCeph(d *Daemon, c instance.Instance, projectName, oldName, newName, newNode string, instanceType instancetype.Type) response.Response {
+	run := func(*operations.Operation) error {
+		return migrateClusterInstanceWithCeph(d, c, projectName, oldName, newName, newNode, instanceType, false)
 	}
 
 	resources := map[string][]string{}
 	resources["containers"] = []string{oldName}
-	op, err := operations.OperationCreate(d.State(), c.Project(), operations.OperationClassTask, db.OperationContainerMigrate, resources, nil, run, nil, nil)
+	op, err := operations.OperationCreate(d.State(), projectName, operations.OperationClassTask, db.OperationContainerMigrate, resources, nil, run, nil, nil)
 	if err != nil {
 		return response.InternalError(err)
 	}
@@ -418,77 +745,179 @@ func containerPostClusteringMigrate(d *Daemon, c instance.Instance, oldName, new
 	return operations.OperationResponse(op)
 }
 
-// Special case migrating a container backed by ceph across two cluster nodes.
-func containerPostClusteringMigrateWithCeph(d *Daemon, c instance.Instance, projectName, oldName, newName, newNode string, instanceType instancetype.Type) response.Response {
-	run := func(*operations.Operation) error {
-		// If source node is online (i.e. we're serving the request on
-		// it, and c != nil), let's unmap the RBD volume locally
-		logger.Debugf(`Renaming RBD storage volume for source container "%s" from "%s" to "%s"`, c.Name(), c.Name(), newName)
-		poolName, err := c.StoragePool()
-		if err != nil {
-			return errors.Wrap(err, "Failed to get source instance's storage pool name")
-		}
+// migrateClusterInstanceWithCeph moves a ceph-based instance to another cluster node. As the storage is shared,
+// only the RBD volume mapping and the database records need to be updated. If fence is true, the ceph clients
+// still using the volume are blocklisted first, which is needed when the source node is offline.
+func migrateClusterInstanceWithCeph(d *Daemon, c instance.Instance, projectName, oldName, newName, newNode string, instanceType instancetype.Type, fence bool) error {
+	// If source node is online (i.e. we're serving the request on
+	// it, and c != nil), let's unmap the RBD volume locally
+	logger.Debugf(`Renaming RBD storage volume for source container "%s" from "%s" to "%s"`, c.Name(), c.Name(), newName)
+	poolName, err := c.StoragePool()
+	if err != nil {
+		return errors.Wrap(err, "Failed to get source instance's storage pool name")
+	}
 
-		pool, err := driver.GetPoolByName(d.State(), poolName)
-		if err != nil {
-			return errors.Wrap(err, "Failed to get source instance's storage pool")
-		}
+	pool, err := driver.GetPoolByName(d.State(), poolName)
+	if err != nil {
+		return errors.Wrap(err, "Failed to get source instance's storage pool")
+	}
 
-		if pool.Driver().Info().Name != "ceph" {
-			return fmt.Errorf("Source instance's storage pool is not of type ceph")
-		}
+	if pool.Driver().Info().Name != "ceph" {
+		return fmt.Errorf("Source instance's storage pool is not of type ceph")
+	}
 
-		args := migration.VolumeSourceArgs{
-			Data: project.Instance(projectName, newName),
-		}
+	args := migration.VolumeSourceArgs{
+		Data:  project.Instance(projectName, newName),
+		Fence: fence,
+	}
 
-		// Trigger a rename in the Ceph driver.
-		err = pool.MigrateInstance(c, nil, &args, nil)
+	// Trigger a rename in the Ceph driver.
+	err = pool.MigrateInstance(c, nil, &args, nil)
+	if err != nil {
+		return errors.Wrap(err, "Failed to rename ceph RBD volume")
+	}
+
+	// Re-link the database entries against the new node name.
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		err := tx.UpdateInstanceNode(projectName, oldName, newName, newNode)
 		if err != nil {
-			return errors.Wrap(err, "Failed to rename ceph RBD volume")
+			return errors.Wrapf(
+				err, "Move container %s to %s with new name %s", oldName, newNode, newName)
 		}
 
-		// Re-link the database entries against the new node name.
-		err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
-			err := tx.UpdateInstanceNode(projectName, oldName, newName, newNode)
-			if err != nil {
-				return errors.Wrapf(
-					err, "Move container %s to %s with new name %s", oldName, newNode, newName)
-			}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "Failed to relink instance database data")
+	}
 
-			return nil
-		})
+	// Create the container mount point on the target node
+	cert := d.endpoints.NetworkCert()
+	client, err := cluster.ConnectIfInstanceIsRemote(d.cluster, projectName, newName, cert, instanceType)
+	if err != nil {
+		return errors.Wrap(err, "Failed to connect to target node")
+	}
+	if client == nil {
+		err := containerPostCreateContainerMountPoint(d, projectName, newName)
 		if err != nil {
-			return errors.Wrap(err, "Failed to relink instance database data")
+			return errors.Wrap(err, "Failed to create mount point on target node")
 		}
-
-		// Create the container mount point on the target node
-		cert := d.endpoints.NetworkCert()
-		client, err := cluster.ConnectIfInstanceIsRemote(d.cluster, projectName, newName, cert, instanceType)
+	} else {
+		path := fmt.Sprintf("/internal/cluster/container-moved/%s", newName)
+		resp, _, err := client.RawQuery("POST", path, nil, "")
 		if err != nil {
-			return errors.Wrap(err, "Failed to connect to target node")
+			return errors.Wrap(err, "Failed to create mount point on target node")
 		}
-		if client == nil {
-			err := containerPostCreateContainerMountPoint(d, projectName, newName)
+		if resp.StatusCode != 200 {
+			return fmt.Errorf("Failed to create mount point on target node: %s", resp.Error)
+		}
+	}
+
+	return nil
+}
+
+// Receive a live migrated instance from another cluster node.
+func internalClusterInstanceMovePost(d *Daemon, r *http.Request) response.Response {
+	projectName := projectParam(r)
+	name := mux.Vars(r)["name"]
+
+	req := internalClusterInstanceMovePostRequest{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	inst, err := instance.LoadByProjectAndName(d.State(), projectName, name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if inst.Type() != instancetype.VM {
+		return response.BadRequest(fmt.Errorf("Only virtual machines can be live migrated between cluster members"))
+	}
+
+	// All cluster nodes share the same certificate.
+	certBlock, _ := pem.Decode(d.endpoints.NetworkCert().PublicKey())
+	if certBlock == nil {
+		return response.InternalError(fmt.Errorf("Invalid cluster certificate"))
+	}
+
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	config, err := shared.GetTLSConfig("", "", "", cert)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	sink, err := newMigrationSink(&MigrationSinkArgs{
+		Url: req.Operation,
+		Dialer: websocket.Dialer{
+			TLSClientConfig: config,
+			NetDial:         shared.RFC3493Dialer},
+		Instance:      inst,
+		Secrets:       req.Websockets,
+		Live:          true,
+		InstanceOnly:  true,
+		StorageShared: req.StorageShared,
+	})
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	run := func(op *operations.Operation) error {
+		revert := revert.New()
+		defer revert.Fail()
+
+		// Create the volume record for this node, the volume itself is created by the migration.
+		if !req.StorageShared {
+			poolName, err := inst.StoragePool()
 			if err != nil {
-				return errors.Wrap(err, "Failed to create mount point on target node")
+				return err
 			}
-		} else {
-			path := fmt.Sprintf("/internal/cluster/container-moved/%s", newName)
-			resp, _, err := client.RawQuery("POST", path, nil, "")
+
+			poolID, pool, err := d.cluster.GetStoragePool(poolName)
 			if err != nil {
-				return errors.Wrap(err, "Failed to create mount point on target node")
+				return err
 			}
-			if resp.StatusCode != 200 {
-				return fmt.Errorf("Failed to create mount point on target node: %s", resp.Error)
+
+			volumeConfig := map[string]string{}
+			err = driver.VolumeFillDefault(volumeConfig, pool)
+			if err != nil {
+				return err
+			}
+
+			_, err = d.cluster.CreateStoragePoolVolume(projectName, name, "", db.StoragePoolVolumeTypeVM, poolID, volumeConfig, db.StoragePoolVolumeContentTypeBlock)
+			if err != nil {
+				return err
 			}
+
+			revert.Add(func() { d.cluster.RemoveStoragePoolVolume(projectName, name, db.StoragePoolVolumeTypeVM, poolID) })
+		}
+
+		err := sink.Do(d.State(), op)
+		if err != nil {
+			return errors.Wrap(err, "Error transferring instance data")
 		}
 
+		// The VM now runs on this node, stop it if the instance can't be moved here.
+		revert.Add(func() { inst.Stop(false) })
+
+		err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			return tx.UpdateInstanceNodeID(projectName, name, d.cluster.GetNodeID())
+		})
+		if err != nil {
+			return err
+		}
+
+		revert.Success()
 		return nil
 	}
 
 	resources := map[string][]string{}
-	resources["containers"] = []string{oldName}
+	resources["instances"] = []string{name}
 	op, err := operations.OperationCreate(d.State(), projectName, operations.OperationClassTask, db.OperationContainerMigrate, resources, nil, run, nil, nil)
 	if err != nil {
 		return response.InternalError(err)
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/lxd/instance"
+)
+
+func TestMigrationVolatileChanges(t *testing.T) {
+	current := map[string]string{
+		"limits.cpu":                "2",
+		"volatile.eth0.host_name":   "tap1234",
+		"volatile.vsock_id":         "10",
+		"volatile.last_state.idmap": "[]",
+	}
+
+	wanted := map[string]string{
+		"limits.cpu":              "4",
+		"volatile.eth0.host_name": "tap5678",
+		"volatile.vsock_id":       "10",
+		"volatile.eth0.hwaddr":    "00:16:3e:00:00:01",
+	}
+
+	// Only volatile keys are changed and the ones missing from wanted are reset.
+	assert.Equal(t, map[string]string{
+		"volatile.eth0.host_name":   "tap5678",
+		"volatile.eth0.hwaddr":      "00:16:3e:00:00:01",
+		"volatile.last_state.idmap": "",
+	}, migrationVolatileChanges(current, wanted))
+
+	assert.Empty(t, migrationVolatileChanges(wanted, wanted))
+}
+
+func TestMigrationRecvVMState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lxd-vm-state-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	stateDir := filepath.Join(dir, "final")
+	err = migrationRecvVMState(stateDir, strings.NewReader("state"))
+	require.NoError(t, err)
+
+	content, err := ioutil.ReadFile(filepath.Join(stateDir, instance.VMStateFile))
+	require.NoError(t, err)
+	assert.Equal(t, "state", string(content))
+
+	// A new state replaces the previous one.
+	err = migrationRecvVMState(stateDir, strings.NewReader("new"))
+	require.NoError(t, err)
+
+	content, err = ioutil.ReadFile(filepath.Join(stateDir, instance.VMStateFile))
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(content))
+}
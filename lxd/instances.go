@@ -205,6 +205,31 @@ func (slice containerAutostartList) Swap(i, j int) {
 }
 
 func containersRestart(s *state.State) error {
+	// Don't start any instance while the local member is evacuated.
+	evacuated := false
+	err := s.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		name, err := tx.GetLocalNodeName()
+		if err != nil {
+			return err
+		}
+
+		node, err := tx.GetNodeByName(name)
+		if err != nil {
+			return err
+		}
+
+		evacuated = node.State == db.ClusterMemberStateEvacuated
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if evacuated {
+		logger.Infof("Skipping instances autostart as the cluster member is evacuated")
+		return nil
+	}
+
 	// Get all the instances
 	result, err := instance.LoadNodeAll(s, instancetype.Any)
 	if err != nil {
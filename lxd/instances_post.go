@@ -604,6 +604,10 @@ func createFromBackup(d *Daemon, project string, data io.Reader, pool string) re
 	}
 	bInfo.Project = project
 
+	if bInfo.Type == backup.TypeCustom {
+		return response.BadRequest(fmt.Errorf("Custom volume backups must be imported through the storage volumes API"))
+	}
+
 	// Override pool.
 	if pool != "" {
 		bInfo.Pool = pool
@@ -617,8 +621,56 @@ func createFromBackup(d *Daemon, project string, data io.Reader, pool string) re
 		"pool":      bInfo.Pool,
 		"optimized": *bInfo.OptimizedStorage,
 		"snapshots": bInfo.Snapshots,
+		"parent":    bInfo.Parent,
 	})
 
+	// Incremental backups are applied on top of the existing instance restored from the parent backup chain.
+	if bInfo.Parent != "" {
+		if !*bInfo.OptimizedStorage {
+			return response.BadRequest(fmt.Errorf("Incremental backups must use optimized storage"))
+		}
+
+		inst, err := instance.LoadByProjectAndName(d.State(), project, bInfo.Name)
+		if err != nil {
+			if errors.Cause(err) == db.ErrNoSuchObject {
+				return response.BadRequest(fmt.Errorf("Instance %q must exist to restore incremental backup, restore parent backup %q first", bInfo.Name, bInfo.Parent))
+			}
+
+			return response.SmartError(err)
+		}
+
+		if inst.IsRunning() {
+			return response.BadRequest(fmt.Errorf("Instance %q must be stopped to restore incremental backup", bInfo.Name))
+		}
+
+		instPool, err := storagePools.GetPoolByInstance(d.State(), inst)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		if pool != "" && pool != instPool.Name() {
+			return response.BadRequest(fmt.Errorf("Incremental backup must be restored to the pool of instance %q", bInfo.Name))
+		}
+
+		bInfo.Pool = instPool.Name()
+
+		snaps, err := inst.Snapshots()
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		snapNames := make([]string, 0, len(snaps))
+		for _, snap := range snaps {
+			_, snapName, _ := shared.InstanceGetParentAndSnapshotName(snap.Name())
+			snapNames = append(snapNames, snapName)
+		}
+
+		err = bInfo.ValidateParent(snapNames)
+		if err != nil {
+			return response.BadRequest(err)
+		}
+	}
+
 	// Check storage pool exists.
 	_, _, err = d.State().Cluster.GetStoragePoolInAnyState(bInfo.Pool)
 	if errors.Cause(err) == db.ErrNoSuchObject {
@@ -663,6 +715,25 @@ func createFromBackup(d *Daemon, project string, data io.Reader, pool string) re
 			return fmt.Errorf("Optimized backup storage driver %q differs from the target storage pool driver %q", bInfo.Backend, pool.Driver().Info().Name)
 		}
 
+		// Importing an incremental backup re-creates the instance record, which removes the records of the
+		// instance's backups along with it. Keep them to re-create them once imported.
+		instBackups := []db.InstanceBackup{}
+		if bInfo.Parent != "" {
+			backupNames, err := d.cluster.GetInstanceBackups(project, bInfo.Name)
+			if err != nil {
+				return errors.Wrap(err, "Load instance backups")
+			}
+
+			for _, backupName := range backupNames {
+				instBackup, err := d.cluster.GetInstanceBackup(project, backupName)
+				if err != nil {
+					return errors.Wrap(err, "Load instance backup")
+				}
+
+				instBackups = append(instBackups, instBackup)
+			}
+		}
+
 		// Dump tarball to storage. Because the backup file is unpacked and restored onto the storage
 		// device before the instance is created in the database it is necessary to return two functions;
 		// a post hook that can be run once the instance has been created in the database to run any
@@ -701,8 +772,18 @@ func createFromBackup(d *Daemon, project string, data io.Reader, pool string) re
 			return errors.Wrap(err, "Load instance")
 		}
 
-		// Clean up created instance if the post hook fails below.
-		runRevert.Add(func() { inst.Delete() })
+		// Clean up created instance if the post hook fails below (unless it existed before).
+		if bInfo.Parent == "" {
+			runRevert.Add(func() { inst.Delete() })
+		}
+
+		for _, instBackup := range instBackups {
+			instBackup.InstanceID = inst.ID()
+			err = d.cluster.CreateInstanceBackup(instBackup)
+			if err != nil {
+				return errors.Wrapf(err, "Re-create instance backup %q", instBackup.Name)
+			}
+		}
 
 		// Run the storage post hook to perform any final actions now that the instance has been created
 		// in the database (this normally includes unmounting volumes that were mounted).
@@ -757,24 +838,107 @@ func containersPost(d *Daemon, r *http.Request) response.Response {
 	}
 
 	targetNode := queryParam(r, "target")
+
+	// Targets prefixed with "@" refer to a cluster group rather than a member.
+	targetGroup := ""
+	if strings.HasPrefix(targetNode, "@") {
+		targetGroup = strings.TrimPrefix(targetNode, "@")
+		targetNode = ""
+	}
+
+	clustered, err := cluster.Enabled(d.db)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if targetGroup != "" && !clustered {
+		return response.BadRequest(fmt.Errorf("Cluster groups can only be targeted on clustered servers"))
+	}
+
+	// Cluster groups the project is allowed to place instances on, nil if unrestricted.
+	var allowedGroups []string
+	var projectConfig map[string]string
+	if clustered {
+		err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			p, err := tx.GetProject(project)
+			if err != nil {
+				return err
+			}
+
+			projectConfig = p.Config
+			return nil
+		})
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		allowedGroups = projecthelpers.GetRestrictedClusterGroups(projectConfig)
+
+		if targetGroup != "" {
+			err = projecthelpers.ClusterGroupAllowed(projectConfig, targetGroup)
+			if err != nil {
+				return response.Forbidden(err)
+			}
+		}
+	}
+
 	if targetNode == "" {
 		// If no target node was specified, pick the node with the
-		// least number of containers. If there's just one node, or if
-		// the selected node is the local one, this is effectively a
-		// no-op, since GetNodeWithLeastInstances() will return an empty
-		// string.
+		// least number of containers among the members of the target
+		// group and of the groups allowed by the project, preferring
+		// failure domains with fewer replicas of the instance's
+		// application. If there's just one node, or if the selected
+		// node is the local one, this is effectively a no-op, since
+		// GetNodeWithLeastInstances() will return an empty string.
 		architectures, err := instance.SuitableArchitectures(d.State(), project, req)
 		if err != nil {
 			return response.BadRequest(err)
 		}
 		err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			if targetGroup != "" {
+				_, err := tx.GetClusterGroup(targetGroup)
+				if err != nil {
+					return errors.Wrapf(err, "Failed loading cluster group %q", targetGroup)
+				}
+			}
+
 			var err error
-			targetNode, err = tx.GetNodeWithLeastInstances(architectures)
+			targetNode, err = tx.GetNodeWithLeastInstances(architectures, targetGroup, allowedGroups, project, req.Config["cluster.application"])
 			return err
 		})
 		if err != nil {
 			return response.SmartError(err)
 		}
+
+		if targetNode == "" && (targetGroup != "" || allowedGroups != nil) {
+			return response.BadRequest(fmt.Errorf("No suitable cluster member could be found"))
+		}
+	} else {
+		// Evacuated members can't be used to host new instances.
+		var node db.NodeInfo
+		var nodeGroups []string
+		err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			var err error
+			node, err = tx.GetNodeByName(targetNode)
+			if err != nil {
+				return err
+			}
+
+			nodeGroups, err = tx.GetNodeClusterGroups(node.ID)
+			return err
+		})
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		if node.State == db.ClusterMemberStateEvacuated {
+			return response.BadRequest(fmt.Errorf("Target node is evacuated"))
+		}
+
+		err = projecthelpers.ClusterMemberAllowed(projectConfig, targetNode, nodeGroups)
+		if err != nil {
+			return response.Forbidden(err)
+		}
 	}
 
 	if targetNode != "" {
@@ -148,7 +148,34 @@ func (c *cmdInit) askClustering(config *cmdInitData, d lxd.InstanceServer) error
 		if cli.AskBool("Are you joining an existing cluster? (yes/no) [default=no]: ", "no") {
 			// Existing cluster
 			config.Cluster.ServerAddress = serverAddress
-			for {
+
+			joinToken := cli.AskBool("Do you have a join token? (yes/no) [default=no]: ", "no")
+			for joinToken {
+				// Cluster join token
+				token, err := shared.JoinTokenDecode(cli.AskString("Please provide join token: ", "", nil))
+				if err != nil {
+					fmt.Printf("Invalid join token: %v\n", err)
+					continue
+				}
+
+				clusterAddress, cert, err := clusterJoinTokenTarget(token)
+				if err != nil {
+					fmt.Printf("Error connecting to existing cluster node: %v\n", err)
+					continue
+				}
+
+				if config.Cluster.ServerName != token.ServerName {
+					fmt.Printf("Using the member name %q of the join token\n", token.ServerName)
+					config.Cluster.ServerName = token.ServerName
+				}
+
+				config.Cluster.ClusterAddress = clusterAddress
+				config.Cluster.ClusterCertificate = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))
+				config.Cluster.ClusterPassword = token.Secret
+				break
+			}
+
+			for !joinToken {
 				// Cluster URL
 				clusterAddress := cli.AskString("IP address or FQDN of an existing cluster node: ", "", nil)
 				_, _, err := net.SplitHostPort(clusterAddress)
@@ -193,13 +220,18 @@ func (c *cmdInit) askClustering(config *cmdInitData, d lxd.InstanceServer) error
 				return err
 			}
 
-			err = cluster.SetupTrust(string(cert.PublicKey()),
+			err = cluster.SetupTrust(string(cert.PublicKey()), config.Cluster.ServerName,
 				config.Cluster.ClusterAddress,
 				string(config.Cluster.ClusterCertificate), config.Cluster.ClusterPassword)
 			if err != nil {
 				return errors.Wrap(err, "Failed to setup trust relationship with cluster")
 			}
 
+			// Join tokens are single-use and the trust relationship is now in place.
+			if joinToken {
+				config.Cluster.ClusterPassword = ""
+			}
+
 			// Client parameters to connect to the target cluster node.
 			args := &lxd.ConnectionArgs{
 				TLSClientCert: string(cert.PublicKey()),
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/metrics"
+	"github.com/lxc/lxd/lxd/resources"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/shared/logger"
+
+	log "github.com/lxc/lxd/shared/log15"
+)
+
+// The kernel reports CPU times in USER_HZ, which is 100 on all supported architectures.
+const userHZ = 100
+
+// Columns of the CPU lines in /proc/stat.
+var procStatCPUModes = []string{"user", "nice", "system", "idle", "iowait", "irq", "softirq", "steal"}
+
+var metricsCmd = APIEndpoint{
+	Path: "metrics",
+
+	Get: APIEndpointAction{Handler: metricsGet},
+}
+
+// /1.0/metrics
+// Get the metrics of the local node and its instances in the OpenMetrics format
+func metricsGet(d *Daemon, r *http.Request) response.Response {
+	// If a target was specified, forward the request to the relevant node.
+	resp := forwardedResponseIfTargetIsRemote(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	projectName := queryParam(r, "project")
+
+	// Load all local instances.
+	instances, err := instance.LoadNodeAll(d.State(), instancetype.Any)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	out := metrics.NewMetricSet(nil)
+
+	// Count the instances per project and type.
+	counts := map[string]map[instancetype.Type]int{}
+
+	for _, inst := range instances {
+		if projectName != "" && inst.Project() != projectName {
+			continue
+		}
+
+		if counts[inst.Project()] == nil {
+			counts[inst.Project()] = map[instancetype.Type]int{}
+		}
+
+		counts[inst.Project()][inst.Type()]++
+
+		instanceMetrics, err := inst.Metrics()
+		if err != nil {
+			if err != instance.ErrInstanceIsStopped {
+				logger.Warn("Failed to get instance metrics", log.Ctx{"project": inst.Project(), "instance": inst.Name(), "err": err})
+			}
+
+			continue
+		}
+
+		out.Merge(instanceMetrics)
+	}
+
+	for project, types := range counts {
+		for instanceType, count := range types {
+			out.AddSamples(metrics.Instances, metrics.Sample{
+				Value:  float64(count),
+				Labels: map[string]string{"project": project, "type": instanceType.String()},
+			})
+		}
+	}
+
+	// Host metrics are only included when not filtering on a project.
+	if projectName == "" {
+		hostMetrics, err := getHostMetrics()
+		if err != nil {
+			logger.Warn("Failed to get host metrics", log.Ctx{"err": err})
+
+			err = d.cluster.UpsertWarningLocalNode("", db.EntityTypeNone, 0, db.WarningTypeHostResourcesUnavailable, err.Error())
+			if err != nil {
+				logger.Warn("Failed to create warning", log.Ctx{"err": err})
+			}
+		} else {
+			out.Merge(hostMetrics)
+		}
+	}
+
+	return response.ManualResponse(func(w http.ResponseWriter) error {
+		w.Header().Set("Content-Type", "application/openmetrics-text; version=1.0.0; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+
+		_, err := w.Write([]byte(out.String()))
+		return err
+	})
+}
+
+// getHostMetrics returns the CPU and memory metrics of the host.
+func getHostMetrics() (*metrics.MetricSet, error) {
+	out := metrics.NewMetricSet(nil)
+
+	memory, err := resources.GetMemory()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to get memory information")
+	}
+
+	out.AddSamples(metrics.HostMemoryTotalBytes, metrics.Sample{Value: float64(memory.Total)})
+	out.AddSamples(metrics.HostMemoryUsedBytes, metrics.Sample{Value: float64(memory.Used)})
+
+	f, err := os.Open("/proc/stat")
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to open /proc/stat")
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+
+		// Only consider the per-CPU lines (cpu0, cpu1, ...).
+		if len(fields) < 2 || !strings.HasPrefix(fields[0], "cpu") || fields[0] == "cpu" {
+			continue
+		}
+
+		cpu := strings.TrimPrefix(fields[0], "cpu")
+
+		for i, mode := range procStatCPUModes {
+			if i+1 >= len(fields) {
+				break
+			}
+
+			value, err := strconv.ParseUint(fields[i+1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to parse %q for CPU %s: %v", fields[i+1], cpu, err)
+			}
+
+			out.AddSamples(metrics.HostCPUSecondsTotal, metrics.Sample{
+				Value:  float64(value) / userHZ,
+				Labels: map[string]string{"cpu": cpu, "mode": mode},
+			})
+		}
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to read /proc/stat")
+	}
+
+	return out, nil
+}
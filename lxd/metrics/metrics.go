@@ -0,0 +1,121 @@
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// NewMetricSet returns a new MetricSet. The provided labels are added to every sample of the set.
+func NewMetricSet(labels map[string]string) *MetricSet {
+	out := MetricSet{set: make(map[MetricType][]Sample)}
+
+	if labels != nil {
+		out.labels = labels
+	} else {
+		out.labels = make(map[string]string)
+	}
+
+	return &out
+}
+
+// AddSamples adds samples of the given metric type to the MetricSet.
+func (m *MetricSet) AddSamples(metricType MetricType, samples ...Sample) {
+	for i := range samples {
+		labels := make(map[string]string, len(m.labels)+len(samples[i].Labels))
+
+		// Add the set's labels first so that sample specific labels can override them.
+		for k, v := range m.labels {
+			labels[k] = v
+		}
+
+		for k, v := range samples[i].Labels {
+			labels[k] = v
+		}
+
+		samples[i].Labels = labels
+	}
+
+	m.set[metricType] = append(m.set[metricType], samples...)
+}
+
+// Merge adds the samples of the provided MetricSet to this one.
+func (m *MetricSet) Merge(metricSet *MetricSet) {
+	if metricSet == nil {
+		return
+	}
+
+	for metricType, samples := range metricSet.set {
+		m.set[metricType] = append(m.set[metricType], samples...)
+	}
+}
+
+// String returns the MetricSet in the OpenMetrics text format.
+func (m *MetricSet) String() string {
+	var out strings.Builder
+
+	metricTypes := make([]MetricType, 0, len(m.set))
+	for metricType := range m.set {
+		metricTypes = append(metricTypes, metricType)
+	}
+
+	sort.Slice(metricTypes, func(i, j int) bool { return metricTypes[i] < metricTypes[j] })
+
+	for _, metricType := range metricTypes {
+		family, ok := metricFamilies[metricType]
+		if !ok || len(m.set[metricType]) == 0 {
+			continue
+		}
+
+		sampleName := family.name
+		if family.kind == counter {
+			sampleName = fmt.Sprintf("%s_total", family.name)
+		}
+
+		out.WriteString(fmt.Sprintf("# HELP %s %s\n", family.name, family.help))
+		out.WriteString(fmt.Sprintf("# TYPE %s %s\n", family.name, family.kind))
+
+		for _, sample := range m.set[metricType] {
+			out.WriteString(sampleName)
+			out.WriteString(formatLabels(sample.Labels))
+			out.WriteString(" ")
+			out.WriteString(strconv.FormatFloat(sample.Value, 'g', -1, 64))
+			out.WriteString("\n")
+		}
+	}
+
+	out.WriteString("# EOF\n")
+
+	return out.String()
+}
+
+// formatLabels renders a set of labels, sorted by name.
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	fields := make([]string, 0, len(keys))
+	for _, k := range keys {
+		fields = append(fields, fmt.Sprintf("%s=\"%s\"", k, escapeLabelValue(labels[k])))
+	}
+
+	return fmt.Sprintf("{%s}", strings.Join(fields, ","))
+}
+
+// escapeLabelValue escapes backslashes, double quotes and line feeds in label values.
+func escapeLabelValue(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `"`, `\"`)
+	value = strings.ReplaceAll(value, "\n", `\n`)
+
+	return value
+}
@@ -0,0 +1,73 @@
+package metrics_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lxc/lxd/lxd/metrics"
+)
+
+func TestMetricSet_String(t *testing.T) {
+	set := metrics.NewMetricSet(map[string]string{"project": "default", "name": "c1"})
+	set.AddSamples(metrics.ProcsTotal, metrics.Sample{Value: 12})
+	set.AddSamples(metrics.CPUSecondsTotal, metrics.Sample{Value: 1.5})
+	set.AddSamples(metrics.NetworkReceiveBytesTotal,
+		metrics.Sample{Value: 100, Labels: map[string]string{"device": "eth0"}},
+		metrics.Sample{Value: 200, Labels: map[string]string{"device": "eth1"}})
+
+	expected := `# HELP lxd_cpu_seconds The total number of CPU time used in seconds.
+# TYPE lxd_cpu_seconds counter
+lxd_cpu_seconds_total{name="c1",project="default"} 1.5
+# HELP lxd_network_receive_bytes The amount of received bytes on a given interface.
+# TYPE lxd_network_receive_bytes counter
+lxd_network_receive_bytes_total{device="eth0",name="c1",project="default"} 100
+lxd_network_receive_bytes_total{device="eth1",name="c1",project="default"} 200
+# HELP lxd_procs The number of running processes.
+# TYPE lxd_procs gauge
+lxd_procs{name="c1",project="default"} 12
+# EOF
+`
+
+	assert.Equal(t, expected, set.String())
+}
+
+func TestMetricSet_Merge(t *testing.T) {
+	set1 := metrics.NewMetricSet(map[string]string{"name": "c1"})
+	set1.AddSamples(metrics.MemoryUsageBytes, metrics.Sample{Value: 1024})
+
+	set2 := metrics.NewMetricSet(map[string]string{"name": "c2"})
+	set2.AddSamples(metrics.MemoryUsageBytes, metrics.Sample{Value: 2048})
+
+	set := metrics.NewMetricSet(nil)
+	set.Merge(set1)
+	set.Merge(set2)
+	set.Merge(nil)
+
+	expected := `# HELP lxd_memory_usage_bytes The amount of used memory.
+# TYPE lxd_memory_usage_bytes gauge
+lxd_memory_usage_bytes{name="c1"} 1024
+lxd_memory_usage_bytes{name="c2"} 2048
+# EOF
+`
+
+	assert.Equal(t, expected, set.String())
+}
+
+func TestMetricSet_EscapeLabels(t *testing.T) {
+	set := metrics.NewMetricSet(nil)
+	set.AddSamples(metrics.Instances, metrics.Sample{Value: 1, Labels: map[string]string{"name": "a\"b\\c\nd"}})
+
+	expected := `# HELP lxd_instances The number of instances on the host.
+# TYPE lxd_instances gauge
+lxd_instances{name="a\"b\\c\nd"} 1
+# EOF
+`
+
+	assert.Equal(t, expected, set.String())
+}
+
+func TestMetricSet_Empty(t *testing.T) {
+	set := metrics.NewMetricSet(nil)
+	assert.Equal(t, "# EOF\n", set.String())
+}
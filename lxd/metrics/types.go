@@ -0,0 +1,98 @@
+package metrics
+
+// A Sample represents a single sample of a metric, along with its labels.
+type Sample struct {
+	Value  float64
+	Labels map[string]string
+}
+
+// MetricSet represents a set of metrics, along with the labels shared by all of its samples.
+type MetricSet struct {
+	set    map[MetricType][]Sample
+	labels map[string]string
+}
+
+// MetricType is a numeric code identifying the metric.
+type MetricType int
+
+const (
+	// CPUSecondsTotal represents the total CPU seconds used.
+	CPUSecondsTotal MetricType = iota
+	// DiskReadBytesTotal represents the read bytes for a disk.
+	DiskReadBytesTotal
+	// DiskReadsCompletedTotal represents the completed reads for a disk.
+	DiskReadsCompletedTotal
+	// DiskWrittenBytesTotal represents the written bytes for a disk.
+	DiskWrittenBytesTotal
+	// DiskWritesCompletedTotal represents the completed writes for a disk.
+	DiskWritesCompletedTotal
+	// FilesystemUsageBytes represents the used bytes of a disk device.
+	FilesystemUsageBytes
+	// MemoryUsageBytes represents the used memory.
+	MemoryUsageBytes
+	// MemoryUsagePeakBytes represents the peak of used memory.
+	MemoryUsagePeakBytes
+	// MemorySwapUsageBytes represents the used swap.
+	MemorySwapUsageBytes
+	// MemoryCachedBytes represents the page cache memory.
+	MemoryCachedBytes
+	// MemoryRSSBytes represents the anonymous and swap cache memory.
+	MemoryRSSBytes
+	// NetworkReceiveBytesTotal represents the received bytes on a network interface.
+	NetworkReceiveBytesTotal
+	// NetworkReceivePacketsTotal represents the received packets on a network interface.
+	NetworkReceivePacketsTotal
+	// NetworkTransmitBytesTotal represents the transmitted bytes on a network interface.
+	NetworkTransmitBytesTotal
+	// NetworkTransmitPacketsTotal represents the transmitted packets on a network interface.
+	NetworkTransmitPacketsTotal
+	// ProcsTotal represents the number of processes.
+	ProcsTotal
+	// HostCPUSecondsTotal represents the CPU seconds spent by the host in each mode.
+	HostCPUSecondsTotal
+	// HostMemoryTotalBytes represents the total memory of the host.
+	HostMemoryTotalBytes
+	// HostMemoryUsedBytes represents the used memory of the host.
+	HostMemoryUsedBytes
+	// Instances represents the number of instances on the host.
+	Instances
+)
+
+// metricKind is the OpenMetrics type of a metric family.
+type metricKind string
+
+const (
+	counter metricKind = "counter"
+	gauge   metricKind = "gauge"
+)
+
+// metricFamily describes how a MetricType is exposed.
+type metricFamily struct {
+	name string
+	kind metricKind
+	help string
+}
+
+// metricFamilies associates a metric type to its family description.
+var metricFamilies = map[MetricType]metricFamily{
+	CPUSecondsTotal:             {"lxd_cpu_seconds", counter, "The total number of CPU time used in seconds."},
+	DiskReadBytesTotal:          {"lxd_disk_read_bytes", counter, "The total number of bytes read."},
+	DiskReadsCompletedTotal:     {"lxd_disk_reads_completed", counter, "The total number of completed reads."},
+	DiskWrittenBytesTotal:       {"lxd_disk_written_bytes", counter, "The total number of bytes written."},
+	DiskWritesCompletedTotal:    {"lxd_disk_writes_completed", counter, "The total number of completed writes."},
+	FilesystemUsageBytes:        {"lxd_filesystem_usage_bytes", gauge, "The number of bytes used on the disk device."},
+	MemoryUsageBytes:            {"lxd_memory_usage_bytes", gauge, "The amount of used memory."},
+	MemoryUsagePeakBytes:        {"lxd_memory_usage_peak_bytes", gauge, "The peak amount of used memory."},
+	MemorySwapUsageBytes:        {"lxd_memory_swap_usage_bytes", gauge, "The amount of used swap."},
+	MemoryCachedBytes:           {"lxd_memory_cached_bytes", gauge, "The amount of memory used by the page cache."},
+	MemoryRSSBytes:              {"lxd_memory_rss_bytes", gauge, "The amount of anonymous and swap cache memory."},
+	NetworkReceiveBytesTotal:    {"lxd_network_receive_bytes", counter, "The amount of received bytes on a given interface."},
+	NetworkReceivePacketsTotal:  {"lxd_network_receive_packets", counter, "The amount of received packets on a given interface."},
+	NetworkTransmitBytesTotal:   {"lxd_network_transmit_bytes", counter, "The amount of transmitted bytes on a given interface."},
+	NetworkTransmitPacketsTotal: {"lxd_network_transmit_packets", counter, "The amount of transmitted packets on a given interface."},
+	ProcsTotal:                  {"lxd_procs", gauge, "The number of running processes."},
+	HostCPUSecondsTotal:         {"lxd_host_cpu_seconds", counter, "The number of CPU seconds spent by the host in each mode."},
+	HostMemoryTotalBytes:        {"lxd_host_memory_total_bytes", gauge, "The total amount of memory on the host."},
+	HostMemoryUsedBytes:         {"lxd_host_memory_used_bytes", gauge, "The amount of used memory on the host."},
+	Instances:                   {"lxd_instances", gauge, "The number of instances on the host."},
+}
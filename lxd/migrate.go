@@ -42,6 +42,10 @@ type migrationFields struct {
 	instanceOnly bool
 	instance     instance.Instance
 
+	// cluster move specific fields
+	clusterMove   bool
+	storageShared bool
+
 	// storage specific fields
 	volumeOnly bool
 }
@@ -273,6 +277,9 @@ type MigrationSinkArgs struct {
 	Refresh      bool
 	Snapshots    []*migration.Snapshot
 
+	// Cluster move specific fields
+	StorageShared bool
+
 	// Storage specific fields
 	VolumeOnly bool
 	VolumeSize int64
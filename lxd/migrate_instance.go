@@ -3,6 +3,7 @@ package main
 import (
 	"encoding/binary"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
@@ -47,13 +48,11 @@ func newMigrationSource(inst instance.Instance, stateful bool, instanceOnly bool
 	}
 
 	if stateful && inst.IsRunning() {
-		if inst.Type() == instancetype.VM {
-			return nil, errors.Wrap(storagePools.ErrNotImplemented, "Unable to perform VM live migration")
-		}
-
-		_, err := exec.LookPath("criu")
-		if err != nil {
-			return nil, fmt.Errorf("Unable to perform container live migration. CRIU isn't installed on the source server")
+		if inst.Type() == instancetype.Container {
+			_, err := exec.LookPath("criu")
+			if err != nil {
+				return nil, fmt.Errorf("Unable to perform container live migration. CRIU isn't installed on the source server")
+			}
 		}
 
 		ret.live = true
@@ -364,6 +363,8 @@ func (s *migrationSourceWs) Do(state *state.State, migrateOp *operations.Operati
 		if s.instance.IsRunning() {
 			criuType = migration.CRIUType_NONE.Enum()
 		}
+	} else if s.instance.Type() == instancetype.VM {
+		criuType = migration.CRIUType_VM_QEMU.Enum()
 	}
 	offerHeader.Criu = criuType
 
@@ -422,7 +423,7 @@ func (s *migrationSourceWs) Do(state *state.State, migrateOp *operations.Operati
 	// Add predump info to source header.
 	offerUsePreDumps := false
 	maxDumpIterations := 0
-	if s.live {
+	if s.live && s.instance.Type() == instancetype.Container {
 		offerUsePreDumps, maxDumpIterations = s.checkForPreDumpSupport()
 	}
 
@@ -492,15 +493,66 @@ func (s *migrationSourceWs) Do(state *state.State, migrateOp *operations.Operati
 	volSourceArgs.MigrationType = migrationTypes[0]
 	volSourceArgs.Snapshots = sendSnapshotNames
 	volSourceArgs.TrackProgress = true
-	err = pool.MigrateInstance(s.instance, &shared.WebsocketIO{Conn: s.fsConn}, volSourceArgs, migrateOp)
-	if err != nil {
-		return abort(err)
+
+	// Indicates that the VM was paused for its migration and must either be stopped or resumed depending on
+	// the outcome of the migration.
+	vmPaused := false
+
+	// When moving within a cluster on shared storage, the target already has access to the volume.
+	if !s.storageShared {
+		// Pause the VM while its volume is transferred, so that the copy matches the state sent afterwards.
+		if s.live && s.instance.Type() == instancetype.VM {
+			err = s.instance.Freeze()
+			if err != nil {
+				return abort(err)
+			}
+
+			vmPaused = true
+		}
+
+		err = pool.MigrateInstance(s.instance, &shared.WebsocketIO{Conn: s.fsConn}, volSourceArgs, migrateOp)
+		if err != nil {
+			if vmPaused {
+				s.instance.Unfreeze()
+			}
+
+			return abort(err)
+		}
 	}
 
 	restoreSuccess := make(chan bool, 1)
 	dumpSuccess := make(chan error, 1)
 
-	if s.live {
+	if s.live && s.instance.Type() == instancetype.VM {
+		if respHeader.Criu == nil || *respHeader.Criu != migration.CRIUType_VM_QEMU {
+			if vmPaused {
+				s.instance.Unfreeze()
+			}
+
+			return abort(fmt.Errorf("Target doesn't support VM live migration"))
+		}
+
+		vm := s.instance.(instance.VM)
+
+		// Stream the VM state, QEMU pauses the VM once the remaining dirty memory has been sent.
+		stateConn := &shared.WebsocketIO{Conn: s.criuConn}
+		err = vm.MigrateState(stateConn)
+		if err != nil {
+			s.instance.Unfreeze()
+			return abort(err)
+		}
+
+		vmPaused = true
+
+		// Indicate to the target that the state stream is finished.
+		err = stateConn.Close()
+		if err != nil {
+			s.instance.Unfreeze()
+			return abort(err)
+		}
+	}
+
+	if s.live && s.instance.Type() == instancetype.Container {
 		if respHeader.Criu == nil {
 			return abort(fmt.Errorf("Got no CRIU socket type for live migration"))
 		} else if *respHeader.Criu != migration.CRIUType_CRIU_RSYNC {
@@ -676,7 +728,7 @@ func (s *migrationSourceWs) Do(state *state.State, migrateOp *operations.Operati
 	}
 
 	// Perform final sync if in multi sync mode.
-	if volSourceArgs.MultiSync {
+	if volSourceArgs.MultiSync && !s.storageShared {
 		// Indicate to the storage driver we are doing final sync and because of this don't send
 		// snapshots as they don't need to have a final sync as not being modified.
 		volSourceArgs.FinalSync = true
@@ -684,6 +736,10 @@ func (s *migrationSourceWs) Do(state *state.State, migrateOp *operations.Operati
 
 		err = pool.MigrateInstance(s.instance, &shared.WebsocketIO{Conn: s.fsConn}, volSourceArgs, migrateOp)
 		if err != nil {
+			if vmPaused {
+				s.instance.Unfreeze()
+			}
+
 			return abort(err)
 		}
 	}
@@ -691,11 +747,32 @@ func (s *migrationSourceWs) Do(state *state.State, migrateOp *operations.Operati
 	msg := migration.MigrationControl{}
 	err = s.recv(&msg)
 	if err != nil {
+		if vmPaused {
+			s.instance.Unfreeze()
+		}
+
 		s.disconnect()
 		return err
 	}
 
-	if s.live {
+	// The source VM is kept paused until the target confirms the restore. When moving within a cluster, the
+	// paused VM is left for the caller to stop once the target took over the instance record.
+	if vmPaused {
+		if !*msg.Success {
+			err = s.instance.Unfreeze()
+			if err != nil {
+				logger.Errorf("Failed resuming VM after failed restore: %v", err)
+			}
+		} else if !s.clusterMove {
+			// The VM is now running on the target.
+			err = s.instance.Stop(false)
+			if err != nil {
+				logger.Errorf("Failed stopping VM after successful restore: %v", err)
+			}
+		}
+	}
+
+	if s.live && s.instance.Type() == instancetype.Container {
 		restoreSuccess <- *msg.Success
 		err := <-dumpSuccess
 		if err != nil {
@@ -712,7 +789,7 @@ func (s *migrationSourceWs) Do(state *state.State, migrateOp *operations.Operati
 
 func newMigrationSink(args *MigrationSinkArgs) (*migrationSink, error) {
 	sink := migrationSink{
-		src:     migrationFields{instance: args.Instance, instanceOnly: args.InstanceOnly},
+		src:     migrationFields{instance: args.Instance, instanceOnly: args.InstanceOnly, storageShared: args.StorageShared},
 		dest:    migrationFields{instanceOnly: args.InstanceOnly},
 		url:     args.Url,
 		dialer:  args.Dialer,
@@ -759,11 +836,14 @@ func newMigrationSink(args *MigrationSinkArgs) (*migrationSink, error) {
 		sink.src.live = ok
 	}
 
-	_, err = exec.LookPath("criu")
-	if sink.push && sink.dest.live && err != nil {
-		return nil, fmt.Errorf("Unable to perform container live migration. CRIU isn't installed on the destination server")
-	} else if sink.src.live && err != nil {
-		return nil, fmt.Errorf("Unable to perform container live migration. CRIU isn't installed on the destination server")
+	// VM live migration relies on QEMU rather than CRIU.
+	if args.Instance.Type() == instancetype.Container {
+		_, err = exec.LookPath("criu")
+		if sink.push && sink.dest.live && err != nil {
+			return nil, fmt.Errorf("Unable to perform container live migration. CRIU isn't installed on the destination server")
+		} else if sink.src.live && err != nil {
+			return nil, fmt.Errorf("Unable to perform container live migration. CRIU isn't installed on the destination server")
+		}
 	}
 
 	return &sink, nil
@@ -837,6 +917,8 @@ func (c *migrationSink) Do(state *state.State, migrateOp *operations.Operation)
 	} else {
 		if !live {
 			criuType = nil
+		} else if c.src.instance.Type() == instancetype.VM {
+			criuType = migration.CRIUType_VM_QEMU.Enum()
 		}
 	}
 
@@ -869,6 +951,11 @@ func (c *migrationSink) Do(state *state.State, migrateOp *operations.Operation)
 	// Translate the legacy MigrationSinkArgs to a VolumeTargetArgs suitable for use
 	// with the new storage layer.
 	myTarget = func(conn *websocket.Conn, op *operations.Operation, args MigrationSinkArgs) error {
+		// When moving within a cluster on shared storage, the volume is already accessible.
+		if c.src.storageShared {
+			return nil
+		}
+
 		volTargetArgs := migration.VolumeTargetArgs{
 			Name:          args.Instance.Name(),
 			MigrationType: respTypes[0],
@@ -1086,11 +1173,20 @@ func (c *migrationSink) Do(state *state.State, migrateOp *operations.Operation)
 				criuConn = c.src.criuConn
 			}
 
+			// Receive the VM state while the storage transfer is ongoing.
+			if c.src.instance.Type() == instancetype.VM {
+				err = migrationRecvVMState(filepath.Join(imagesDir, "final"), &shared.WebsocketIO{Conn: criuConn})
+				if err != nil {
+					restore <- err
+					return
+				}
+			}
+
 			sync := &migration.MigrationSync{
 				FinalPreDump: proto.Bool(false),
 			}
 
-			if respHeader.GetPredump() {
+			if respHeader.GetPredump() && c.src.instance.Type() == instancetype.Container {
 				for !sync.GetFinalPreDump() {
 					logger.Debugf("About to receive rsync")
 					// Transfer a CRIU pre-dump.
@@ -1122,10 +1218,12 @@ func (c *migrationSink) Do(state *state.State, migrateOp *operations.Operation)
 			}
 
 			// Final CRIU dump.
-			err = rsync.Recv(shared.AddSlash(imagesDir), &shared.WebsocketIO{Conn: criuConn}, nil, rsyncFeatures)
-			if err != nil {
-				restore <- err
-				return
+			if c.src.instance.Type() == instancetype.Container {
+				err = rsync.Recv(shared.AddSlash(imagesDir), &shared.WebsocketIO{Conn: criuConn}, nil, rsyncFeatures)
+				if err != nil {
+					restore <- err
+					return
+				}
 			}
 		}
 
@@ -1148,12 +1246,11 @@ func (c *migrationSink) Do(state *state.State, migrateOp *operations.Operation)
 
 			// Currently we only do a single CRIU pre-dump so we can hardcode "final"
 			// here since we know that "final" is the folder for CRIU's final dump.
-			if c.src.instance.Type() == instancetype.Container {
-				err = c.src.instance.Migrate(&criuMigrationArgs)
-				if err != nil {
-					restore <- err
-					return
-				}
+			// For VMs, this is where the QEMU state was received.
+			err = c.src.instance.Migrate(&criuMigrationArgs)
+			if err != nil {
+				restore <- err
+				return
 			}
 		}
 
@@ -1234,3 +1331,24 @@ func migrationCompareSnapshots(sourceSnapshots []*migration.Snapshot, targetSnap
 
 	return toSync, toDelete
 }
+
+// migrationRecvVMState receives a VM's runtime state from conn and writes it into stateDir.
+func migrationRecvVMState(stateDir string, conn io.Reader) error {
+	err := os.MkdirAll(stateDir, 0700)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(filepath.Join(stateDir, instance.VMStateFile), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(f, conn)
+	if err != nil {
+		return errors.Wrap(err, "Failed receiving VM state")
+	}
+
+	return f.Close()
+}
@@ -28,6 +28,7 @@ const (
 	MigrationFSType_ZFS             MigrationFSType = 2
 	MigrationFSType_RBD             MigrationFSType = 3
 	MigrationFSType_BLOCK_AND_RSYNC MigrationFSType = 4
+	MigrationFSType_DMTHIN          MigrationFSType = 5
 )
 
 var MigrationFSType_name = map[int32]string{
@@ -36,6 +37,7 @@ var MigrationFSType_name = map[int32]string{
 	2: "ZFS",
 	3: "RBD",
 	4: "BLOCK_AND_RSYNC",
+	5: "DMTHIN",
 }
 
 var MigrationFSType_value = map[string]int32{
@@ -44,6 +46,7 @@ var MigrationFSType_value = map[string]int32{
 	"ZFS":             2,
 	"RBD":             3,
 	"BLOCK_AND_RSYNC": 4,
+	"DMTHIN":          5,
 }
 
 func (x MigrationFSType) Enum() *MigrationFSType {
@@ -75,18 +78,21 @@ const (
 	CRIUType_CRIU_RSYNC CRIUType = 0
 	CRIUType_PHAUL      CRIUType = 1
 	CRIUType_NONE       CRIUType = 2
+	CRIUType_VM_QEMU    CRIUType = 3
 )
 
 var CRIUType_name = map[int32]string{
 	0: "CRIU_RSYNC",
 	1: "PHAUL",
 	2: "NONE",
+	3: "VM_QEMU",
 }
 
 var CRIUType_value = map[string]int32{
 	"CRIU_RSYNC": 0,
 	"PHAUL":      1,
 	"NONE":       2,
+	"VM_QEMU":    3,
 }
 
 func (x CRIUType) Enum() *CRIUType {
@@ -994,76 +1000,77 @@ func init() {
 func init() { proto.RegisterFile("lxd/migration/migrate.proto", fileDescriptor_fe8772548dc4b615) }
 
 var fileDescriptor_fe8772548dc4b615 = []byte{
-	// 1128 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x55, 0xdd, 0x8e, 0xd3, 0x46,
-	0x14, 0x6e, 0x12, 0x67, 0x37, 0x3e, 0x49, 0x76, 0xc3, 0x80, 0x90, 0x05, 0x2d, 0x4d, 0x0d, 0x55,
-	0xc3, 0x56, 0x02, 0x1a, 0x54, 0x89, 0xab, 0x4a, 0x6c, 0xd2, 0x2d, 0xa8, 0x10, 0x56, 0x13, 0x50,
-	0xd5, 0xde, 0x58, 0xb3, 0xf6, 0x71, 0x32, 0xc2, 0x7f, 0x9a, 0xb1, 0x17, 0x92, 0x9b, 0xaa, 0x0f,
-	0xd3, 0xe7, 0xe9, 0x55, 0x1f, 0xa6, 0x77, 0xd5, 0xcc, 0xd8, 0xc6, 0xde, 0xad, 0xd4, 0xbb, 0x39,
-	0xdf, 0xf9, 0xfc, 0x9d, 0x33, 0xe7, 0x67, 0x0c, 0x77, 0xa3, 0x8f, 0xc1, 0xe3, 0x98, 0x6f, 0x04,
-	0xcb, 0x79, 0x9a, 0x94, 0x27, 0x7c, 0x94, 0x89, 0x34, 0x4f, 0x89, 0x5d, 0x3b, 0xdc, 0xdf, 0xc1,
-	0x7e, 0xb9, 0x7c, 0xcd, 0xb2, 0xb7, 0xbb, 0x0c, 0xc9, 0x2d, 0xe8, 0x73, 0x59, 0xf0, 0xc0, 0xe9,
-	0x4c, 0xbb, 0xb3, 0x01, 0x35, 0x86, 0x41, 0x37, 0x3c, 0x70, 0xba, 0x15, 0xba, 0xe1, 0x01, 0xb9,
-	0x0d, 0x07, 0xdb, 0x54, 0xe6, 0x3c, 0x70, 0x7a, 0xd3, 0xee, 0xac, 0x4f, 0x4b, 0x8b, 0x10, 0xb0,
-	0x12, 0xc9, 0x03, 0xc7, 0xd2, 0xa8, 0x3e, 0x93, 0x3b, 0x30, 0x88, 0x59, 0x26, 0x58, 0xb2, 0x41,
-	0xa7, 0xaf, 0xf1, 0xda, 0x76, 0x9f, 0xc0, 0xc1, 0x22, 0x4d, 0x42, 0xbe, 0x21, 0x13, 0xe8, 0xbd,
-	0xc7, 0x9d, 0x8e, 0x6d, 0x53, 0x75, 0x54, 0x91, 0x2f, 0x59, 0x54, 0xa0, 0x8e, 0x6c, 0x53, 0x63,
-	0xb8, 0x3f, 0xc1, 0xc1, 0x12, 0x2f, 0xb9, 0x8f, 0x3a, 0x16, 0x8b, 0xb1, 0xfc, 0x44, 0x9f, 0xc9,
-	0x43, 0x38, 0xf0, 0xb5, 0x9e, 0xd3, 0x9d, 0xf6, 0x66, 0xc3, 0xf9, 0x8d, 0x47, 0xf5, 0x65, 0x1f,
-	0x99, 0x40, 0xb4, 0x24, 0xb8, 0x7f, 0x75, 0x61, 0xb0, 0x4e, 0x58, 0x26, 0xb7, 0x69, 0xfe, 0x9f,
-	0x5a, 0x4f, 0x61, 0x18, 0xa5, 0x3e, 0x8b, 0x16, 0xff, 0x23, 0xd8, 0x64, 0xa9, 0xcb, 0x66, 0x22,
-	0x0d, 0x79, 0x84, 0xd2, 0xe9, 0x4d, 0x7b, 0x33, 0x9b, 0xd6, 0x36, 0xf9, 0x1c, 0x6c, 0xcc, 0xb6,
-	0x18, 0xa3, 0x60, 0x91, 0xae, 0xd0, 0x80, 0x7e, 0x02, 0xc8, 0xf7, 0x30, 0xd2, 0x42, 0xe6, 0x76,
-	0xd2, 0xe9, 0x5f, 0x8b, 0x67, 0x3c, 0xb4, 0x45, 0x23, 0x2e, 0x8c, 0x98, 0xf0, 0xb7, 0x3c, 0x47,
-	0x3f, 0x2f, 0x04, 0x3a, 0x07, 0xba, 0xc2, 0x2d, 0x4c, 0x25, 0x25, 0x73, 0x96, 0x63, 0x58, 0x44,
-	0xce, 0xa1, 0x8e, 0x5b, 0xdb, 0xe4, 0x3e, 0x8c, 0x7d, 0x81, 0x3a, 0x80, 0x17, 0xb0, 0x1c, 0x9d,
-	0xc1, 0xb4, 0x33, 0xeb, 0xd1, 0x51, 0x05, 0x2e, 0x59, 0x8e, 0xe4, 0x01, 0x1c, 0x45, 0x4c, 0xe6,
-	0x5e, 0x21, 0x31, 0x30, 0x2c, 0xdb, 0xb0, 0x14, 0xfa, 0x4e, 0x62, 0xa0, 0x58, 0xee, 0x1f, 0x1d,
-	0x18, 0x0b, 0xb9, 0x4b, 0xfc, 0x33, 0x64, 0x2a, 0xae, 0x54, 0x63, 0xf2, 0x91, 0xe5, 0xb9, 0x90,
-	0x4e, 0x67, 0xda, 0x99, 0x0d, 0x68, 0x69, 0x29, 0x3c, 0xc0, 0x08, 0x73, 0xd5, 0x5b, 0x8d, 0x1b,
-	0x4b, 0x25, 0xea, 0xa7, 0x71, 0x26, 0x50, 0xaa, 0xea, 0x29, 0x4f, 0x6d, 0x93, 0x07, 0x30, 0xbe,
-	0xe0, 0x01, 0x17, 0xe8, 0xab, 0xb4, 0x74, 0x05, 0x15, 0xa1, 0x0d, 0xba, 0x0f, 0x61, 0xb8, 0x0f,
-	0x65, 0x9d, 0x40, 0x53, 0xb0, 0xd3, 0x16, 0x74, 0x37, 0x30, 0xbe, 0xc8, 0x45, 0x83, 0xfc, 0x10,
-	0x26, 0x75, 0xb1, 0xbd, 0x2d, 0xb2, 0x00, 0x45, 0xf9, 0xd1, 0x71, 0x8d, 0xbf, 0xd0, 0x30, 0xf9,
-	0x16, 0x6e, 0x18, 0x82, 0x27, 0x8b, 0x8b, 0xcb, 0x34, 0x2a, 0x62, 0x94, 0xe5, 0x5d, 0x26, 0xc6,
-	0xb1, 0xae, 0x71, 0xf7, 0x9f, 0x1e, 0x1c, 0xbf, 0xbe, 0x22, 0x70, 0x02, 0xdd, 0x50, 0xea, 0x71,
-	0x3b, 0x9a, 0xdf, 0x69, 0xf4, 0xb8, 0xe6, 0x9d, 0xad, 0xd5, 0x52, 0xd2, 0x6e, 0x28, 0xc9, 0x37,
-	0x60, 0xf9, 0x82, 0x17, 0x5a, 0xff, 0x68, 0x7e, 0xb3, 0x39, 0x81, 0xf4, 0xe5, 0x3b, 0x4d, 0xd3,
-	0x04, 0x72, 0x02, 0x7d, 0x1e, 0xc4, 0x2c, 0xd3, 0x93, 0x37, 0x9c, 0xdf, 0x6a, 0x30, 0xeb, 0x35,
-	0xa7, 0x86, 0xa2, 0xca, 0x29, 0xcb, 0xe9, 0x5f, 0x31, 0x95, 0xbd, 0xa5, 0xa7, 0xb5, 0x0d, 0x92,
-	0xef, 0xc0, 0xae, 0x80, 0x6a, 0x22, 0x9b, 0xf1, 0xab, 0xfd, 0xa1, 0x9f, 0x58, 0xc4, 0x81, 0xc3,
-	0x4c, 0x60, 0x50, 0xc4, 0x99, 0x73, 0xa8, 0x0b, 0x52, 0x99, 0xe4, 0x87, 0x2b, 0xe3, 0xa1, 0x47,
-	0x6d, 0x38, 0x77, 0x1a, 0x82, 0x2d, 0x3f, 0xbd, 0x32, 0x4d, 0x0e, 0x1c, 0x0a, 0x0c, 0x05, 0xca,
-	0xad, 0x1e, 0xbf, 0x01, 0xad, 0x4c, 0xf2, 0xac, 0xd5, 0x75, 0x07, 0xb4, 0xee, 0xed, 0x86, 0x6e,
-	0xc3, 0x4b, 0x5b, 0x03, 0x72, 0x0f, 0xc0, 0xb4, 0x69, 0xcd, 0xf7, 0xe8, 0x0c, 0xf5, 0x54, 0x37,
-	0x10, 0x95, 0x73, 0x6b, 0x48, 0x9c, 0xd1, 0xb5, 0x9c, 0x5b, 0x7e, 0xda, 0xa6, 0xbb, 0x67, 0x30,
-	0xa9, 0x5b, 0xba, 0x48, 0x93, 0x5c, 0xa4, 0x91, 0xba, 0x87, 0x2c, 0x7c, 0xdf, 0xcc, 0xa4, 0xda,
-	0xc6, 0xca, 0x54, 0x9e, 0x18, 0xa5, 0x64, 0x1b, 0xb3, 0x18, 0x36, 0xad, 0x4c, 0xf7, 0x29, 0x8c,
-	0x6b, 0x9d, 0xf5, 0x2e, 0xf1, 0xd5, 0xde, 0x87, 0x3c, 0x61, 0xd1, 0xb9, 0xc0, 0xa5, 0xaa, 0xb5,
-	0x51, 0x6a, 0x61, 0xee, 0x9f, 0x3d, 0x98, 0xa8, 0xca, 0x7b, 0x6a, 0xdb, 0xa5, 0x87, 0x49, 0x2e,
-	0x76, 0x6a, 0xe1, 0x43, 0x81, 0xb8, 0xe7, 0xc9, 0xc6, 0xcb, 0x79, 0xf9, 0xe6, 0x8d, 0xe9, 0xa8,
-	0x02, 0xdf, 0xf2, 0x18, 0xc9, 0x97, 0x30, 0x0c, 0x45, 0xba, 0xc7, 0xc4, 0x50, 0xba, 0x9a, 0x02,
-	0x06, 0xd2, 0x84, 0xaf, 0x60, 0x14, 0x63, 0xac, 0xc5, 0x35, 0xa3, 0xa7, 0x19, 0xc3, 0x12, 0xd3,
-	0x94, 0xfb, 0x30, 0x8e, 0x31, 0xfe, 0x20, 0x78, 0x8e, 0x86, 0x63, 0x99, 0x40, 0x15, 0x58, 0x91,
-	0x32, 0xb6, 0x41, 0xe9, 0x49, 0x9f, 0x25, 0x09, 0x06, 0xfa, 0x0f, 0x61, 0xd1, 0x91, 0x06, 0xd7,
-	0x06, 0x23, 0x4f, 0xe0, 0x56, 0x49, 0x7a, 0xcf, 0xb3, 0x0c, 0x03, 0x2f, 0x63, 0x02, 0x93, 0x5c,
-	0xbf, 0x75, 0x16, 0x25, 0x86, 0x6b, 0x5c, 0xe7, 0xda, 0xf3, 0x49, 0x56, 0x45, 0xca, 0x31, 0xd1,
-	0xcf, 0x5e, 0x25, 0xfb, 0x8b, 0xc1, 0x14, 0x89, 0x8b, 0x98, 0x65, 0x9e, 0x40, 0x99, 0x46, 0x97,
-	0xe6, 0xe9, 0x1b, 0xd3, 0x91, 0x06, 0xa9, 0xc1, 0xc8, 0x17, 0x00, 0x46, 0x29, 0x62, 0xfb, 0x9d,
-	0x63, 0x6b, 0x19, 0x5b, 0x23, 0xaf, 0xd8, 0x7e, 0x57, 0xb9, 0xbd, 0x8c, 0x67, 0xe5, 0xe0, 0x95,
-	0xee, 0x73, 0x05, 0xa8, 0x87, 0xb3, 0x76, 0x7b, 0x17, 0x45, 0x28, 0xf5, 0x88, 0x95, 0x89, 0x28,
-	0xca, 0x69, 0x11, 0x4a, 0xf7, 0xef, 0x0e, 0xdc, 0x14, 0x28, 0xf3, 0x54, 0x60, 0xab, 0x55, 0x5f,
-	0x9b, 0xaf, 0xa5, 0xa7, 0xde, 0x2c, 0x26, 0xd0, 0xfc, 0x9a, 0x2d, 0x6a, 0xee, 0xb6, 0x28, 0x41,
-	0x72, 0x02, 0x37, 0xda, 0xe5, 0xf1, 0xd3, 0x0f, 0xba, 0x65, 0x16, 0x3d, 0x6e, 0xd6, 0x66, 0x91,
-	0x7e, 0x50, 0x7d, 0x0b, 0x53, 0xf1, 0xbe, 0x6e, 0x7e, 0xd9, 0xb7, 0x12, 0xab, 0x5a, 0x5b, 0x25,
-	0xd3, 0x68, 0xdb, 0xb0, 0xc4, 0x34, 0xa5, 0x4e, 0xac, 0x04, 0x55, 0xdb, 0x3a, 0x75, 0x62, 0xb4,
-	0x04, 0xdd, 0x8f, 0x30, 0x6c, 0x5e, 0xe7, 0x31, 0x58, 0x81, 0x19, 0x55, 0xb5, 0x42, 0x77, 0x1b,
-	0x2b, 0x74, 0x75, 0x48, 0xa9, 0x26, 0x92, 0x67, 0x6a, 0xe1, 0xb5, 0x96, 0x5e, 0x87, 0xe1, 0xfc,
-	0x5e, 0xf3, 0xa9, 0xb8, 0x5e, 0x30, 0x5a, 0xd1, 0x4f, 0x56, 0x8d, 0x17, 0xd7, 0xbc, 0xa4, 0xc4,
-	0x86, 0x3e, 0x5d, 0xff, 0xba, 0x5a, 0x4c, 0x3e, 0x53, 0xc7, 0xd3, 0xb7, 0xf4, 0x6c, 0x3d, 0xe9,
-	0x90, 0x43, 0xe8, 0xfd, 0x76, 0xb6, 0x9e, 0x74, 0xd5, 0x81, 0x9e, 0x2e, 0x27, 0x3d, 0x72, 0x13,
-	0x8e, 0x4f, 0x5f, 0xbd, 0x59, 0xfc, 0xec, 0x3d, 0x5f, 0x2d, 0x3d, 0xf3, 0x85, 0x75, 0xf2, 0x18,
-	0x06, 0xd5, 0x5b, 0x4b, 0x8e, 0x00, 0xd4, 0xd9, 0x6b, 0xa8, 0x9d, 0xbf, 0x78, 0xfe, 0xee, 0xd5,
-	0xa4, 0x43, 0x06, 0x60, 0xad, 0xde, 0xac, 0x7e, 0x9c, 0x74, 0xff, 0x0d, 0x00, 0x00, 0xff, 0xff,
-	0x51, 0xb3, 0x06, 0x24, 0x82, 0x09, 0x00, 0x00,
+	// 1148 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x55, 0x5d, 0x6f, 0xd4, 0x46,
+	0x17, 0x7e, 0xf7, 0x2b, 0xbb, 0x3e, 0xde, 0x4d, 0xcc, 0x80, 0x90, 0x05, 0x6f, 0x69, 0x6a, 0xa8,
+	0x1a, 0x52, 0x09, 0x68, 0x50, 0x25, 0x2e, 0x2a, 0x24, 0xb2, 0x4b, 0x0a, 0x2a, 0x09, 0xe9, 0x6c,
+	0xd2, 0xaa, 0xdc, 0x58, 0x13, 0xfb, 0x78, 0x33, 0xc2, 0x5f, 0x9a, 0xb1, 0x03, 0x9b, 0x9b, 0xaa,
+	0x3f, 0xa6, 0xbf, 0xa7, 0x57, 0xfd, 0x31, 0xbd, 0xab, 0x66, 0xc6, 0x76, 0xec, 0x80, 0xd4, 0xbb,
+	0x39, 0xcf, 0x79, 0xfc, 0x9c, 0x33, 0xe7, 0x63, 0x0c, 0x77, 0xe3, 0x8f, 0xe1, 0xe3, 0x84, 0xaf,
+	0x04, 0x2b, 0x78, 0x96, 0x56, 0x27, 0x7c, 0x94, 0x8b, 0xac, 0xc8, 0x88, 0xd5, 0x38, 0xbc, 0xdf,
+	0xc1, 0x7a, 0xbd, 0x38, 0x64, 0xf9, 0xc9, 0x3a, 0x47, 0x72, 0x0b, 0x46, 0x5c, 0x96, 0x3c, 0x74,
+	0x7b, 0xdb, 0xfd, 0x9d, 0x09, 0x35, 0x86, 0x41, 0x57, 0x3c, 0x74, 0xfb, 0x35, 0xba, 0xe2, 0x21,
+	0xb9, 0x0d, 0x1b, 0xe7, 0x99, 0x2c, 0x78, 0xe8, 0x0e, 0xb6, 0xfb, 0x3b, 0x23, 0x5a, 0x59, 0x84,
+	0xc0, 0x30, 0x95, 0x3c, 0x74, 0x87, 0x1a, 0xd5, 0x67, 0x72, 0x07, 0x26, 0x09, 0xcb, 0x05, 0x4b,
+	0x57, 0xe8, 0x8e, 0x34, 0xde, 0xd8, 0xde, 0x13, 0xd8, 0x98, 0x67, 0x69, 0xc4, 0x57, 0xc4, 0x81,
+	0xc1, 0x7b, 0x5c, 0xeb, 0xd8, 0x16, 0x55, 0x47, 0x15, 0xf9, 0x82, 0xc5, 0x25, 0xea, 0xc8, 0x16,
+	0x35, 0x86, 0xf7, 0x23, 0x6c, 0x2c, 0xf0, 0x82, 0x07, 0xa8, 0x63, 0xb1, 0x04, 0xab, 0x4f, 0xf4,
+	0x99, 0x3c, 0x84, 0x8d, 0x40, 0xeb, 0xb9, 0xfd, 0xed, 0xc1, 0x8e, 0xbd, 0x77, 0xe3, 0x51, 0x73,
+	0xd9, 0x47, 0x26, 0x10, 0xad, 0x08, 0xde, 0x5f, 0x7d, 0x98, 0x2c, 0x53, 0x96, 0xcb, 0xf3, 0xac,
+	0xf8, 0xac, 0xd6, 0x53, 0xb0, 0xe3, 0x2c, 0x60, 0xf1, 0xfc, 0x3f, 0x04, 0xdb, 0x2c, 0x75, 0xd9,
+	0x5c, 0x64, 0x11, 0x8f, 0x51, 0xba, 0x83, 0xed, 0xc1, 0x8e, 0x45, 0x1b, 0x9b, 0xfc, 0x1f, 0x2c,
+	0xcc, 0xcf, 0x31, 0x41, 0xc1, 0x62, 0x5d, 0xa1, 0x09, 0xbd, 0x02, 0xc8, 0xf7, 0x30, 0xd5, 0x42,
+	0xe6, 0x76, 0xd2, 0x1d, 0x7d, 0x12, 0xcf, 0x78, 0x68, 0x87, 0x46, 0x3c, 0x98, 0x32, 0x11, 0x9c,
+	0xf3, 0x02, 0x83, 0xa2, 0x14, 0xe8, 0x6e, 0xe8, 0x0a, 0x77, 0x30, 0x95, 0x94, 0x2c, 0x58, 0x81,
+	0x51, 0x19, 0xbb, 0x63, 0x1d, 0xb7, 0xb1, 0xc9, 0x7d, 0x98, 0x05, 0x02, 0x75, 0x00, 0x3f, 0x64,
+	0x05, 0xba, 0x93, 0xed, 0xde, 0xce, 0x80, 0x4e, 0x6b, 0x70, 0xc1, 0x0a, 0x24, 0x0f, 0x60, 0x33,
+	0x66, 0xb2, 0xf0, 0x4b, 0x89, 0xa1, 0x61, 0x59, 0x86, 0xa5, 0xd0, 0x53, 0x89, 0xa1, 0x62, 0x79,
+	0x7f, 0xf4, 0x60, 0x26, 0xe4, 0x3a, 0x0d, 0x0e, 0x90, 0xa9, 0xb8, 0x52, 0x8d, 0xc9, 0x47, 0x56,
+	0x14, 0x42, 0xba, 0xbd, 0xed, 0xde, 0xce, 0x84, 0x56, 0x96, 0xc2, 0x43, 0x8c, 0xb1, 0x50, 0xbd,
+	0xd5, 0xb8, 0xb1, 0x54, 0xa2, 0x41, 0x96, 0xe4, 0x02, 0xa5, 0xaa, 0x9e, 0xf2, 0x34, 0x36, 0x79,
+	0x00, 0xb3, 0x33, 0x1e, 0x72, 0x81, 0x81, 0x4a, 0x4b, 0x57, 0x50, 0x11, 0xba, 0xa0, 0xf7, 0x10,
+	0xec, 0xcb, 0x48, 0x36, 0x09, 0xb4, 0x05, 0x7b, 0x5d, 0x41, 0x6f, 0x05, 0xb3, 0xb3, 0x42, 0xb4,
+	0xc8, 0x0f, 0xc1, 0x69, 0x8a, 0xed, 0x9f, 0x23, 0x0b, 0x51, 0x54, 0x1f, 0x6d, 0x35, 0xf8, 0x2b,
+	0x0d, 0x93, 0x6f, 0xe1, 0x86, 0x21, 0xf8, 0xb2, 0x3c, 0xbb, 0xc8, 0xe2, 0x32, 0x41, 0x59, 0xdd,
+	0xc5, 0x31, 0x8e, 0x65, 0x83, 0x7b, 0xff, 0x0c, 0x60, 0xeb, 0xf0, 0x9a, 0xc0, 0x2e, 0xf4, 0x23,
+	0xa9, 0xc7, 0x6d, 0x73, 0xef, 0x4e, 0xab, 0xc7, 0x0d, 0xef, 0x60, 0xa9, 0x96, 0x92, 0xf6, 0x23,
+	0x49, 0xbe, 0x81, 0x61, 0x20, 0x78, 0xa9, 0xf5, 0x37, 0xf7, 0x6e, 0xb6, 0x27, 0x90, 0xbe, 0x3e,
+	0xd5, 0x34, 0x4d, 0x20, 0xbb, 0x30, 0xe2, 0x61, 0xc2, 0x72, 0x3d, 0x79, 0xf6, 0xde, 0xad, 0x16,
+	0xb3, 0x59, 0x73, 0x6a, 0x28, 0xaa, 0x9c, 0xb2, 0x9a, 0xfe, 0x23, 0xa6, 0xb2, 0x1f, 0xea, 0x69,
+	0xed, 0x82, 0xe4, 0x3b, 0xb0, 0x6a, 0xa0, 0x9e, 0xc8, 0x76, 0xfc, 0x7a, 0x7f, 0xe8, 0x15, 0x8b,
+	0xb8, 0x30, 0xce, 0x05, 0x86, 0x65, 0x92, 0xbb, 0x63, 0x5d, 0x90, 0xda, 0x24, 0xcf, 0xaf, 0x8d,
+	0x87, 0x1e, 0x35, 0x7b, 0xcf, 0x6d, 0x09, 0x76, 0xfc, 0xf4, 0xda, 0x34, 0xb9, 0x30, 0x16, 0x18,
+	0x09, 0x94, 0xe7, 0x7a, 0xfc, 0x26, 0xb4, 0x36, 0xc9, 0xb3, 0x4e, 0xd7, 0x5d, 0xd0, 0xba, 0xb7,
+	0x5b, 0xba, 0x2d, 0x2f, 0xed, 0x0c, 0xc8, 0x3d, 0x00, 0xd3, 0xa6, 0x25, 0xbf, 0x44, 0xd7, 0xd6,
+	0x53, 0xdd, 0x42, 0xc8, 0xf3, 0x6b, 0x43, 0xe2, 0x4e, 0x3f, 0xc9, 0xb9, 0xe3, 0xa7, 0x5d, 0xba,
+	0x77, 0x00, 0x4e, 0xd3, 0xd2, 0x79, 0x96, 0x16, 0x22, 0x8b, 0xd5, 0x3d, 0x64, 0x19, 0x04, 0x66,
+	0x26, 0xd5, 0x36, 0xd6, 0xa6, 0xf2, 0x24, 0x28, 0x25, 0x5b, 0x99, 0xc5, 0xb0, 0x68, 0x6d, 0x7a,
+	0x4f, 0x61, 0xd6, 0xe8, 0x2c, 0xd7, 0x69, 0xa0, 0xf6, 0x3e, 0xe2, 0x29, 0x8b, 0x8f, 0x05, 0x2e,
+	0x54, 0xad, 0x8d, 0x52, 0x07, 0xf3, 0xfe, 0x1c, 0x80, 0xa3, 0x2a, 0xef, 0xab, 0x6d, 0x97, 0x3e,
+	0xa6, 0x85, 0x58, 0xab, 0x85, 0x8f, 0x04, 0xe2, 0x25, 0x4f, 0x57, 0x7e, 0xc1, 0xab, 0x37, 0x6f,
+	0x46, 0xa7, 0x35, 0x78, 0xc2, 0x13, 0x24, 0x5f, 0x82, 0x1d, 0x89, 0xec, 0x12, 0x53, 0x43, 0xe9,
+	0x6b, 0x0a, 0x18, 0x48, 0x13, 0xbe, 0x82, 0x69, 0x82, 0x89, 0x16, 0xd7, 0x8c, 0x81, 0x66, 0xd8,
+	0x15, 0xa6, 0x29, 0xf7, 0x61, 0x96, 0x60, 0xf2, 0x41, 0xf0, 0x02, 0x0d, 0x67, 0x68, 0x02, 0xd5,
+	0x60, 0x4d, 0xca, 0xd9, 0x0a, 0xa5, 0x2f, 0x03, 0x96, 0xa6, 0x18, 0xea, 0x3f, 0xc4, 0x90, 0x4e,
+	0x35, 0xb8, 0x34, 0x18, 0x79, 0x02, 0xb7, 0x2a, 0xd2, 0x7b, 0x9e, 0xe7, 0x18, 0xfa, 0x39, 0x13,
+	0x98, 0x16, 0xfa, 0xad, 0x1b, 0x52, 0x62, 0xb8, 0xc6, 0x75, 0xac, 0x3d, 0x57, 0xb2, 0x2a, 0x52,
+	0x81, 0xa9, 0x3b, 0x6e, 0xc9, 0xfe, 0x6a, 0x30, 0x45, 0xe2, 0x22, 0x61, 0xb9, 0x2f, 0x50, 0x66,
+	0xf1, 0x85, 0x79, 0xfa, 0x66, 0x74, 0xaa, 0x41, 0x6a, 0x30, 0xf2, 0x05, 0x80, 0x51, 0x8a, 0xd9,
+	0xe5, 0xda, 0xb5, 0xb4, 0x8c, 0xa5, 0x91, 0x37, 0xec, 0x72, 0x5d, 0xbb, 0xfd, 0x9c, 0xe7, 0xd5,
+	0xe0, 0x55, 0xee, 0x63, 0x05, 0xa8, 0x87, 0xb3, 0x71, 0xfb, 0x67, 0x65, 0x24, 0xf5, 0x88, 0x55,
+	0x89, 0x28, 0xca, 0x7e, 0x19, 0x49, 0xef, 0xef, 0x1e, 0xdc, 0x14, 0x28, 0x8b, 0x4c, 0x60, 0xa7,
+	0x55, 0x5f, 0x9b, 0xaf, 0xa5, 0xaf, 0xde, 0x2c, 0x26, 0xd0, 0xfc, 0x9a, 0x87, 0xd4, 0xdc, 0x6d,
+	0x5e, 0x81, 0x64, 0x17, 0x6e, 0x74, 0xcb, 0x13, 0x64, 0x1f, 0x74, 0xcb, 0x86, 0x74, 0xab, 0x5d,
+	0x9b, 0x79, 0xf6, 0x41, 0xf5, 0x2d, 0xca, 0xc4, 0xfb, 0xa6, 0xf9, 0x55, 0xdf, 0x2a, 0xac, 0x6e,
+	0x6d, 0x9d, 0x4c, 0xab, 0x6d, 0x76, 0x85, 0x69, 0x4a, 0x93, 0x58, 0x05, 0xaa, 0xb6, 0xf5, 0x9a,
+	0xc4, 0x68, 0x05, 0x7a, 0x1f, 0xc1, 0x6e, 0x5f, 0xe7, 0x31, 0x0c, 0x43, 0x33, 0xaa, 0x6a, 0x85,
+	0xee, 0xb6, 0x56, 0xe8, 0xfa, 0x90, 0x52, 0x4d, 0x24, 0xcf, 0xd4, 0xc2, 0x6b, 0x2d, 0xbd, 0x0e,
+	0xf6, 0xde, 0xbd, 0xd6, 0x37, 0x9f, 0x29, 0x18, 0xad, 0xe9, 0xbb, 0xef, 0x60, 0xeb, 0xda, 0x4b,
+	0x4a, 0x2c, 0x18, 0xd1, 0xe5, 0x6f, 0x47, 0x73, 0xe7, 0x7f, 0xea, 0xb8, 0x7f, 0x42, 0x0f, 0x96,
+	0x4e, 0x8f, 0x8c, 0x61, 0xf0, 0xee, 0x60, 0xe9, 0xf4, 0xd5, 0x81, 0xee, 0x2f, 0x9c, 0x01, 0xb9,
+	0x09, 0x5b, 0xfb, 0x6f, 0xde, 0xce, 0x7f, 0xf2, 0x5f, 0x1c, 0x2d, 0x7c, 0xf3, 0xc5, 0x90, 0x00,
+	0x6c, 0x2c, 0x0e, 0x4f, 0x5e, 0xbd, 0x3e, 0x72, 0x46, 0xbb, 0x3f, 0xc0, 0xa4, 0x7e, 0x77, 0xc9,
+	0x26, 0x80, 0x3a, 0xfb, 0x2d, 0xe5, 0xe3, 0x57, 0x2f, 0x4e, 0xdf, 0x38, 0x3d, 0x32, 0x81, 0xe1,
+	0xd1, 0xdb, 0xa3, 0x97, 0x4e, 0x9f, 0xd8, 0x30, 0xfe, 0xe5, 0xd0, 0xff, 0xf9, 0xe5, 0xe1, 0xa9,
+	0x33, 0xf8, 0x77, 0x00, 0xa3, 0x5e, 0xfb, 0x21, 0x9b, 0x09, 0x00, 0x00,
 }
@@ -27,6 +27,7 @@ type VolumeSourceArgs struct {
 	FinalSync     bool
 	Data          interface{} // Optional store to persist storage driver state between MultiSync phases.
 	ContentType   string
+	Fence         bool // Cut off any other client still using the volume (cluster moves away from an offline member).
 }
 
 // VolumeTargetArgs represents the arguments needed to setup a volume migration sink.
@@ -0,0 +1,86 @@
+package acl
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/firewall/drivers"
+	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// loadRules loads the ingress and egress rules of the named ACLs, concatenated in the order of the names.
+func loadRules(s *state.State, projectName string, aclNames []string) ([]api.NetworkACLRule, []api.NetworkACLRule, error) {
+	ingress := []api.NetworkACLRule{}
+	egress := []api.NetworkACLRule{}
+
+	for _, aclName := range aclNames {
+		_, aclInfo, err := s.Cluster.GetNetworkACL(projectName, aclName)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "Failed loading network ACL %q", aclName)
+		}
+
+		ingress = append(ingress, aclInfo.Ingress...)
+		egress = append(egress, aclInfo.Egress...)
+	}
+
+	return ingress, egress, nil
+}
+
+// defaultAction returns the default action to use for unmatched traffic.
+func defaultAction(action string) string {
+	if action == "" {
+		return "reject"
+	}
+
+	return action
+}
+
+// FirewallRules returns the firewall rules for the named ACLs, followed by a rule per direction applying the
+// default action to unmatched traffic. Returns no rules if no ACLs are specified.
+func FirewallRules(s *state.State, projectName string, aclNames []string, defaultIngress string, defaultEgress string) ([]drivers.ACLRule, error) {
+	if len(aclNames) == 0 {
+		return nil, nil
+	}
+
+	ingress, egress, err := loadRules(s, projectName, aclNames)
+	if err != nil {
+		return nil, err
+	}
+
+	return firewallRules(ingress, egress, defaultIngress, defaultEgress), nil
+}
+
+// firewallRules converts the ingress and egress rules into firewall rules and adds the default rules.
+func firewallRules(ingress []api.NetworkACLRule, egress []api.NetworkACLRule, defaultIngress string, defaultEgress string) []drivers.ACLRule {
+	rules := make([]drivers.ACLRule, 0, len(ingress)+len(egress)+2)
+
+	for _, direction := range []struct {
+		name          string
+		rules         []api.NetworkACLRule
+		defaultAction string
+	}{
+		{name: "ingress", rules: ingress, defaultAction: defaultIngress},
+		{name: "egress", rules: egress, defaultAction: defaultEgress},
+	} {
+		for _, rule := range direction.rules {
+			rules = append(rules, drivers.ACLRule{
+				Direction:       direction.name,
+				Action:          rule.Action,
+				Source:          rule.Source,
+				Destination:     rule.Destination,
+				Protocol:        rule.Protocol,
+				SourcePort:      rule.SourcePort,
+				DestinationPort: rule.DestinationPort,
+				ICMPType:        rule.ICMPType,
+				ICMPCode:        rule.ICMPCode,
+			})
+		}
+
+		rules = append(rules, drivers.ACLRule{
+			Direction: direction.name,
+			Action:    defaultAction(direction.defaultAction),
+		})
+	}
+
+	return rules
+}
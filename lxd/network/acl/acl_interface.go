@@ -0,0 +1,23 @@
+package acl
+
+import (
+	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// NetworkACL represents a Network ACL.
+type NetworkACL interface {
+	// Initialise.
+	init(state *state.State, id int64, projectName string, aclInfo *api.NetworkACL)
+
+	// Info.
+	ID() int64
+	Project() string
+	Info() *api.NetworkACL
+	UsedBy() ([]string, error)
+
+	// Modifications.
+	Update(config *api.NetworkACLPut) error
+	Rename(newName string) error
+	Delete() error
+}
@@ -0,0 +1,109 @@
+package acl
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// LoadByName loads and initialises a Network ACL from the database by project and name.
+func LoadByName(s *state.State, projectName string, name string) (NetworkACL, error) {
+	id, aclInfo, err := s.Cluster.GetNetworkACL(projectName, name)
+	if err != nil {
+		return nil, err
+	}
+
+	var acl NetworkACL = &common{} // Only a single driver currently.
+	acl.init(s, id, projectName, aclInfo)
+
+	return acl, nil
+}
+
+// Create validates supplied record and creates new Network ACL record in the database.
+func Create(s *state.State, projectName string, aclInfo *api.NetworkACLsPost) error {
+	acl := &common{} // Only a single driver currently.
+	acl.init(s, -1, projectName, nil)
+
+	err := ValidName(aclInfo.Name)
+	if err != nil {
+		return err
+	}
+
+	acl.normaliseRules(&aclInfo.NetworkACLPut)
+
+	err = acl.validateConfig(&aclInfo.NetworkACLPut)
+	if err != nil {
+		return err
+	}
+
+	// Insert DB record.
+	_, err = s.Cluster.CreateNetworkACL(projectName, aclInfo)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ParseNames splits a comma separated list of ACL names, as used by the "security.acls" keys.
+func ParseNames(value string) []string {
+	names := []string{}
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		names = append(names, name)
+	}
+
+	return names
+}
+
+// ValidateNames checks that each ACL in the comma separated list exists in the project.
+// If s is nil then only the names themselves are validated.
+func ValidateNames(s *state.State, projectName string, value string) error {
+	names := ParseNames(value)
+	for _, name := range names {
+		err := ValidName(name)
+		if err != nil {
+			return err
+		}
+
+		if s == nil {
+			continue
+		}
+
+		_, _, err = s.Cluster.GetNetworkACL(projectName, name)
+		if err != nil {
+			return errors.Wrapf(err, "Failed loading network ACL %q", name)
+		}
+	}
+
+	if len(names) != len(uniqueNames(names)) {
+		return fmt.Errorf("Duplicate ACL names in %q", value)
+	}
+
+	return nil
+}
+
+// uniqueNames returns the names with duplicates removed.
+func uniqueNames(names []string) []string {
+	seen := make(map[string]struct{}, len(names))
+	out := make([]string, 0, len(names))
+	for _, name := range names {
+		_, found := seen[name]
+		if found {
+			continue
+		}
+
+		seen[name] = struct{}{}
+		out = append(out, name)
+	}
+
+	return out
+}
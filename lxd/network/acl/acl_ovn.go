@@ -0,0 +1,232 @@
+package acl
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/lxc/lxd/lxd/network/openvswitch"
+	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/shared/api"
+)
+
+// OVN ACL rule priorities. Higher values take precedence.
+const ovnACLPriorityBaseline = 32000       // Always allowed traffic (ARP, ND and DHCP).
+const ovnACLPriorityPortRuleMax = 31000    // First NIC level rule, decremented for each following rule.
+const ovnACLPriorityNetworkRuleMax = 30000 // First network level rule, decremented for each following rule.
+const ovnACLPriorityPortDefault = 2        // NIC level default action.
+const ovnACLPriorityNetworkDefault = 1     // Network level default action.
+
+// ovnACLMaxRules is the maximum number of rules per direction, so that priorities don't overlap.
+const ovnACLMaxRules = 1000
+
+// ovnACLBaselineEgress matches the traffic sent by instances that is always allowed.
+const ovnACLBaselineEgress = "arp || nd || nd_rs || udp.dst == 67 || udp.dst == 547"
+
+// ovnACLBaselineIngress matches the traffic received by instances that is always allowed.
+const ovnACLBaselineIngress = "arp || nd || nd_ra || udp.src == 67 || udp.src == 547"
+
+// OVNSwitchRules returns the ACL rules to apply to a network's internal logical switch for the named ACLs.
+// The rules apply to all ports except the router port. Returns no rules if no ACLs are specified.
+func OVNSwitchRules(s *state.State, projectName string, aclNames []string, defaultIngress string, defaultEgress string, routerPort openvswitch.OVNSwitchPort) ([]openvswitch.OVNACLRule, error) {
+	if len(aclNames) == 0 {
+		return nil, nil
+	}
+
+	ingress, egress, err := loadRules(s, projectName, aclNames)
+	if err != nil {
+		return nil, err
+	}
+
+	return ovnRules(ingress, egress, defaultIngress, defaultEgress, fmt.Sprintf("outport != %q", routerPort), fmt.Sprintf("inport != %q", routerPort), ovnACLPriorityNetworkRuleMax, ovnACLPriorityNetworkDefault)
+}
+
+// OVNPortRules returns the ACL rules to apply to an instance NIC's logical switch port for the named ACLs.
+// These take precedence over the network's rules. Returns no rules if no ACLs are specified.
+func OVNPortRules(s *state.State, projectName string, aclNames []string, defaultIngress string, defaultEgress string, port openvswitch.OVNSwitchPort) ([]openvswitch.OVNACLRule, error) {
+	if len(aclNames) == 0 {
+		return nil, nil
+	}
+
+	ingress, egress, err := loadRules(s, projectName, aclNames)
+	if err != nil {
+		return nil, err
+	}
+
+	return ovnRules(ingress, egress, defaultIngress, defaultEgress, fmt.Sprintf("outport == %q", port), fmt.Sprintf("inport == %q", port), ovnACLPriorityPortRuleMax, ovnACLPriorityPortDefault)
+}
+
+// ovnRules converts the ingress and egress rules into OVN ACL rules restricted by the port matches.
+// Ingress traffic is handled in the "to-lport" direction and egress traffic in the "from-lport" direction.
+func ovnRules(ingress []api.NetworkACLRule, egress []api.NetworkACLRule, defaultIngress string, defaultEgress string, ingressPortMatch string, egressPortMatch string, rulePriorityMax int, defaultPriority int) ([]openvswitch.OVNACLRule, error) {
+	rules := []openvswitch.OVNACLRule{}
+
+	for _, direction := range []struct {
+		name          string
+		ovnDirection  string
+		portMatch     string
+		baseline      string
+		rules         []api.NetworkACLRule
+		defaultAction string
+	}{
+		{name: "ingress", ovnDirection: "to-lport", portMatch: ingressPortMatch, baseline: ovnACLBaselineIngress, rules: ingress, defaultAction: defaultIngress},
+		{name: "egress", ovnDirection: "from-lport", portMatch: egressPortMatch, baseline: ovnACLBaselineEgress, rules: egress, defaultAction: defaultEgress},
+	} {
+		if len(direction.rules) > ovnACLMaxRules {
+			return nil, fmt.Errorf("Too many %s rules (maximum %d)", direction.name, ovnACLMaxRules)
+		}
+
+		rules = append(rules, openvswitch.OVNACLRule{
+			Direction: direction.ovnDirection,
+			Action:    "allow",
+			Match:     fmt.Sprintf("%s && (%s)", direction.portMatch, direction.baseline),
+			Priority:  ovnACLPriorityBaseline,
+		})
+
+		for i, rule := range direction.rules {
+			match := direction.portMatch
+			ruleMatch := ovnRuleMatch(rule)
+			if ruleMatch != "" {
+				match = fmt.Sprintf("%s && %s", match, ruleMatch)
+			}
+
+			rules = append(rules, openvswitch.OVNACLRule{
+				Direction: direction.ovnDirection,
+				Action:    ovnAction(rule.Action),
+				Match:     match,
+				Priority:  rulePriorityMax - i,
+			})
+		}
+
+		rules = append(rules, openvswitch.OVNACLRule{
+			Direction: direction.ovnDirection,
+			Action:    ovnAction(defaultAction(direction.defaultAction)),
+			Match:     fmt.Sprintf("%s && (ip4 || ip6)", direction.portMatch),
+			Priority:  defaultPriority,
+		})
+	}
+
+	return rules, nil
+}
+
+// ovnAction converts an ACL rule action into an OVN ACL verdict.
+func ovnAction(action string) string {
+	switch action {
+	case "reject":
+		return "reject"
+	case "drop":
+		return "drop"
+	}
+
+	// Allow replies to allowed traffic.
+	return "allow-related"
+}
+
+// ovnRuleMatch converts the match criteria of an ACL rule into an OVN match expression.
+func ovnRuleMatch(rule api.NetworkACLRule) string {
+	matches := []string{}
+
+	if rule.Source != "" {
+		matches = append(matches, ovnSubjectMatch(rule.Source, "src"))
+	}
+
+	if rule.Destination != "" {
+		matches = append(matches, ovnSubjectMatch(rule.Destination, "dst"))
+	}
+
+	switch rule.Protocol {
+	case "tcp", "udp":
+		portMatches := []string{}
+		if rule.SourcePort != "" {
+			portMatches = append(portMatches, ovnPortMatch(rule.Protocol, rule.SourcePort, "src"))
+		}
+
+		if rule.DestinationPort != "" {
+			portMatches = append(portMatches, ovnPortMatch(rule.Protocol, rule.DestinationPort, "dst"))
+		}
+
+		if len(portMatches) == 0 {
+			portMatches = append(portMatches, rule.Protocol)
+		}
+
+		matches = append(matches, portMatches...)
+	case "icmp4", "icmp6":
+		matches = append(matches, rule.Protocol)
+
+		if rule.ICMPType != "" {
+			matches = append(matches, fmt.Sprintf("%s.type == %s", rule.Protocol, rule.ICMPType))
+		}
+
+		if rule.ICMPCode != "" {
+			matches = append(matches, fmt.Sprintf("%s.code == %s", rule.Protocol, rule.ICMPCode))
+		}
+	}
+
+	return strings.Join(matches, " && ")
+}
+
+// ovnSubjectMatch returns an OVN match expression for a comma separated list of IPs and subnets.
+// The field argument is either "src" or "dst".
+func ovnSubjectMatch(subjects string, field string) string {
+	var ipv4, ipv6 []string
+	for _, subject := range strings.Split(subjects, ",") {
+		var ip net.IP
+		if strings.Contains(subject, "/") {
+			ip, _, _ = net.ParseCIDR(subject)
+		} else {
+			ip = net.ParseIP(subject)
+		}
+
+		if ip == nil {
+			continue
+		}
+
+		if ip.To4() != nil {
+			ipv4 = append(ipv4, subject)
+		} else {
+			ipv6 = append(ipv6, subject)
+		}
+	}
+
+	matches := []string{}
+	if len(ipv4) > 0 {
+		matches = append(matches, fmt.Sprintf("ip4.%s == {%s}", field, strings.Join(ipv4, ", ")))
+	}
+
+	if len(ipv6) > 0 {
+		matches = append(matches, fmt.Sprintf("ip6.%s == {%s}", field, strings.Join(ipv6, ", ")))
+	}
+
+	if len(matches) == 1 {
+		return matches[0]
+	}
+
+	return fmt.Sprintf("(%s)", strings.Join(matches, " || "))
+}
+
+// ovnPortMatch returns an OVN match expression for a comma separated list of ports and port ranges.
+// The field argument is either "src" or "dst".
+func ovnPortMatch(protocol string, ports string, field string) string {
+	fieldName := fmt.Sprintf("%s.%s", protocol, field)
+
+	var singles []string
+	matches := []string{}
+	for _, port := range strings.Split(ports, ",") {
+		fields := strings.SplitN(port, "-", 2)
+		if len(fields) == 2 {
+			matches = append(matches, fmt.Sprintf("%s <= %s <= %s", fields[0], fieldName, fields[1]))
+		} else {
+			singles = append(singles, port)
+		}
+	}
+
+	if len(singles) > 0 {
+		matches = append([]string{fmt.Sprintf("%s == {%s}", fieldName, strings.Join(singles, ", "))}, matches...)
+	}
+
+	if len(matches) == 1 {
+		return matches[0]
+	}
+
+	return fmt.Sprintf("(%s)", strings.Join(matches, " || "))
+}
@@ -0,0 +1,98 @@
+package acl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+func TestValidateRule(t *testing.T) {
+	d := &common{}
+
+	valid := []api.NetworkACLRule{
+		{Action: "allow"},
+		{Action: "allow", Source: "10.0.0.0/8", Protocol: "tcp", DestinationPort: "443"},
+		{Action: "reject", Source: "10.0.0.1,fd00::/8", Destination: "192.0.2.1"},
+		{Action: "drop", Protocol: "udp", SourcePort: "53", DestinationPort: "1000-2000,3000"},
+		{Action: "allow", Protocol: "icmp4", ICMPType: "8", ICMPCode: "0"},
+		{Action: "allow", Protocol: "icmp6", Destination: "fd00::1"},
+	}
+
+	for _, rule := range valid {
+		assert.NoError(t, d.validateRule(rule), "%+v", rule)
+	}
+
+	invalid := []api.NetworkACLRule{
+		{Action: "accept"},
+		{Action: "allow", Protocol: "sctp"},
+		{Action: "allow", Source: "10.0.0.0/33"},
+		{Action: "allow", Source: "10.0.0.1-10.0.0.5"},
+		{Action: "allow", Source: "10.0.0.1", Destination: "fd00::1"},
+		{Action: "allow", Protocol: "icmp4", Destination: "fd00::1"},
+		{Action: "allow", DestinationPort: "80"},
+		{Action: "allow", Protocol: "tcp", DestinationPort: "70000"},
+		{Action: "allow", Protocol: "tcp", DestinationPort: "2000-1000"},
+		{Action: "allow", Protocol: "tcp", ICMPType: "8"},
+		{Action: "allow", Protocol: "icmp4", ICMPCode: "0"},
+		{Action: "allow", Protocol: "icmp4", ICMPType: "256"},
+	}
+
+	for _, rule := range invalid {
+		assert.Error(t, d.validateRule(rule), "%+v", rule)
+	}
+}
+
+func TestValidName(t *testing.T) {
+	assert.NoError(t, ValidName("web-servers_1"))
+	assert.Error(t, ValidName(""))
+	assert.Error(t, ValidName("1web"))
+	assert.Error(t, ValidName("web,db"))
+	assert.Error(t, ValidName("web/db"))
+}
+
+func TestOVNRuleMatch(t *testing.T) {
+	tests := []struct {
+		rule  api.NetworkACLRule
+		match string
+	}{
+		{
+			api.NetworkACLRule{Action: "allow"},
+			"",
+		},
+		{
+			api.NetworkACLRule{Action: "allow", Source: "10.0.0.0/8", Protocol: "tcp", DestinationPort: "443"},
+			"ip4.src == {10.0.0.0/8} && tcp.dst == {443}",
+		},
+		{
+			api.NetworkACLRule{Action: "allow", Destination: "10.0.0.1,fd00::1", Protocol: "udp"},
+			"(ip4.dst == {10.0.0.1} || ip6.dst == {fd00::1}) && udp",
+		},
+		{
+			api.NetworkACLRule{Action: "allow", Protocol: "tcp", SourcePort: "1000-2000,80,443"},
+			"(tcp.src == {80, 443} || 1000 <= tcp.src <= 2000)",
+		},
+		{
+			api.NetworkACLRule{Action: "allow", Protocol: "icmp6", ICMPType: "128", ICMPCode: "0"},
+			"icmp6 && icmp6.type == 128 && icmp6.code == 0",
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.match, ovnRuleMatch(test.rule))
+	}
+}
+
+func TestFirewallRules(t *testing.T) {
+	ingress := []api.NetworkACLRule{{Action: "allow", Protocol: "tcp", DestinationPort: "22"}}
+
+	rules := firewallRules(ingress, nil, "", "allow")
+	assert.Len(t, rules, 3)
+	assert.Equal(t, "ingress", rules[0].Direction)
+	assert.Equal(t, "22", rules[0].DestinationPort)
+	assert.Equal(t, "ingress", rules[1].Direction)
+	assert.Equal(t, "reject", rules[1].Action)
+	assert.Equal(t, "egress", rules[2].Direction)
+	assert.Equal(t, "allow", rules[2].Action)
+}
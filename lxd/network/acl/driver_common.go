@@ -0,0 +1,509 @@
+package acl
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/project"
+	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/logging"
+	"github.com/lxc/lxd/shared/validate"
+	"github.com/lxc/lxd/shared/version"
+)
+
+// Rule actions.
+var validActions = []string{"allow", "reject", "drop"}
+
+// Rule protocols.
+var validProtocols = []string{"", "tcp", "udp", "icmp4", "icmp6"}
+
+// common represents a Network ACL.
+type common struct {
+	logger      logger.Logger
+	state       *state.State
+	id          int64
+	projectName string
+	info        *api.NetworkACL
+}
+
+// init initialise internal variables.
+func (d *common) init(state *state.State, id int64, projectName string, info *api.NetworkACL) {
+	if info == nil {
+		d.info = &api.NetworkACL{}
+	} else {
+		d.info = info
+	}
+
+	d.logger = logging.AddContext(logger.Log, log.Ctx{"project": projectName, "networkACL": d.info.Name})
+	d.id = id
+	d.projectName = projectName
+	d.state = state
+
+	if d.info.Ingress == nil {
+		d.info.Ingress = []api.NetworkACLRule{}
+	}
+
+	if d.info.Egress == nil {
+		d.info.Egress = []api.NetworkACLRule{}
+	}
+
+	if d.info.Config == nil {
+		d.info.Config = make(map[string]string)
+	}
+}
+
+// ID returns the Network ACL ID.
+func (d *common) ID() int64 {
+	return d.id
+}
+
+// Project returns the project name.
+func (d *common) Project() string {
+	return d.projectName
+}
+
+// Info returns copy of internal info for the Network ACL.
+func (d *common) Info() *api.NetworkACL {
+	// Copy internal info to prevent modification externally.
+	info := api.NetworkACL{}
+	info.Name = d.info.Name
+	info.Description = d.info.Description
+	info.Ingress = append(make([]api.NetworkACLRule, 0, len(d.info.Ingress)), d.info.Ingress...)
+	info.Egress = append(make([]api.NetworkACLRule, 0, len(d.info.Egress)), d.info.Egress...)
+	info.Config = make(map[string]string, len(d.info.Config))
+	for k, v := range d.info.Config {
+		info.Config[k] = v
+	}
+
+	info.UsedBy = nil // To indicate its not populated (use UsedBy() function to populate).
+
+	return &info
+}
+
+// UsedBy returns a list of API endpoints referencing this ACL.
+func (d *common) UsedBy() ([]string, error) {
+	return d.usedBy(false)
+}
+
+// usedBy returns a list of API endpoints referencing this ACL.
+// If firstOnly is true then search stops at first result.
+func (d *common) usedBy(firstOnly bool) ([]string, error) {
+	usedBy := []string{}
+
+	// Find networks using the ACL.
+	networkNames, err := UsedByNetworks(d.state, d.projectName, d.info.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, networkName := range networkNames {
+		uri := fmt.Sprintf("/%s/networks/%s", version.APIVersion, networkName)
+		if d.projectName != project.Default {
+			uri += fmt.Sprintf("?project=%s", d.projectName)
+		}
+
+		usedBy = append(usedBy, uri)
+		if firstOnly {
+			return usedBy, nil
+		}
+	}
+
+	// Instances and profiles can only reference ACLs in their effective network project.
+	networkProjects := map[string]string{}
+	inACLProject := func(projectName string) (bool, error) {
+		networkProject, found := networkProjects[projectName]
+		if !found {
+			networkProject, err = project.NetworkProject(d.state.Cluster, projectName)
+			if err != nil {
+				return false, err
+			}
+
+			networkProjects[projectName] = networkProject
+		}
+
+		return networkProject == d.projectName, nil
+	}
+
+	// Find instances using the ACL.
+	insts, err := instance.LoadFromAllProjects(d.state)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, inst := range insts {
+		inProject, err := inACLProject(inst.Project())
+		if err != nil {
+			return nil, err
+		}
+
+		if !inProject || !devicesUseACL(inst.ExpandedDevices().CloneNative(), d.info.Name) {
+			continue
+		}
+
+		uri := fmt.Sprintf("/%s/instances/%s", version.APIVersion, inst.Name())
+		if inst.Project() != project.Default {
+			uri += fmt.Sprintf("?project=%s", inst.Project())
+		}
+
+		usedBy = append(usedBy, uri)
+		if firstOnly {
+			return usedBy, nil
+		}
+	}
+
+	// Find profiles using the ACL.
+	var profiles []db.Profile
+	err = d.state.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		profiles, err = tx.GetProfiles(db.ProfileFilter{})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, profile := range profiles {
+		inProject, err := inACLProject(profile.Project)
+		if err != nil {
+			return nil, err
+		}
+
+		if !inProject || !devicesUseACL(profile.Devices, d.info.Name) {
+			continue
+		}
+
+		uri := fmt.Sprintf("/%s/profiles/%s", version.APIVersion, profile.Name)
+		if profile.Project != project.Default {
+			uri += fmt.Sprintf("?project=%s", profile.Project)
+		}
+
+		usedBy = append(usedBy, uri)
+		if firstOnly {
+			return usedBy, nil
+		}
+	}
+
+	return usedBy, nil
+}
+
+// isUsed returns whether or not the ACL is in use.
+func (d *common) isUsed() (bool, error) {
+	usedBy, err := d.usedBy(true)
+	if err != nil {
+		return false, err
+	}
+
+	return len(usedBy) > 0, nil
+}
+
+// ValidName checks the ACL name is valid.
+func ValidName(name string) error {
+	if name == "" {
+		return fmt.Errorf("Name is required")
+	}
+
+	if len(name) > 63 {
+		return fmt.Errorf("Name is too long (maximum 63 characters)")
+	}
+
+	// The name is used in comma separated lists, so restrict it to a safe character set.
+	match, _ := regexp.MatchString("^[a-zA-Z][-_a-zA-Z0-9]*$", name)
+	if !match {
+		return fmt.Errorf("Name must start with a letter and only contain letters, numbers, dashes and underscores")
+	}
+
+	return nil
+}
+
+// validateConfig checks the config and rules are valid.
+func (d *common) validateConfig(info *api.NetworkACLPut) error {
+	for k := range info.Config {
+		if !strings.HasPrefix(k, "user.") {
+			return fmt.Errorf("Invalid option %q (only user.* keys are supported)", k)
+		}
+	}
+
+	for i, rule := range info.Ingress {
+		err := d.validateRule(rule)
+		if err != nil {
+			return errors.Wrapf(err, "Invalid ingress rule %d", i)
+		}
+	}
+
+	for i, rule := range info.Egress {
+		err := d.validateRule(rule)
+		if err != nil {
+			return errors.Wrapf(err, "Invalid egress rule %d", i)
+		}
+	}
+
+	return nil
+}
+
+// validateRule validates a single rule.
+func (d *common) validateRule(rule api.NetworkACLRule) error {
+	if !shared.StringInSlice(rule.Action, validActions) {
+		return fmt.Errorf("Action must be one of: %s", strings.Join(validActions, ", "))
+	}
+
+	if !shared.StringInSlice(rule.Protocol, validProtocols) {
+		return fmt.Errorf("Protocol must be one of: %s", strings.Join(validProtocols[1:], ", "))
+	}
+
+	sourceFamilies, err := d.validateSubjects(rule.Source)
+	if err != nil {
+		return errors.Wrapf(err, "Invalid source")
+	}
+
+	destinationFamilies, err := d.validateSubjects(rule.Destination)
+	if err != nil {
+		return errors.Wrapf(err, "Invalid destination")
+	}
+
+	// Check that the rule can match some traffic.
+	families := map[uint]bool{4: true, 6: true}
+	for _, subjectFamilies := range []map[uint]bool{sourceFamilies, destinationFamilies} {
+		if subjectFamilies == nil {
+			continue
+		}
+
+		for family := range families {
+			if !subjectFamilies[family] {
+				delete(families, family)
+			}
+		}
+	}
+
+	if rule.Protocol == "icmp4" {
+		delete(families, 6)
+	} else if rule.Protocol == "icmp6" {
+		delete(families, 4)
+	}
+
+	if len(families) == 0 {
+		return fmt.Errorf("Source, destination and protocol don't share a common IP family")
+	}
+
+	// Validate ports.
+	if rule.SourcePort != "" || rule.DestinationPort != "" {
+		if !shared.StringInSlice(rule.Protocol, []string{"tcp", "udp"}) {
+			return fmt.Errorf("Ports can only be specified with the tcp or udp protocols")
+		}
+
+		err = d.validatePorts(rule.SourcePort)
+		if err != nil {
+			return errors.Wrapf(err, "Invalid source port")
+		}
+
+		err = d.validatePorts(rule.DestinationPort)
+		if err != nil {
+			return errors.Wrapf(err, "Invalid destination port")
+		}
+	}
+
+	// Validate ICMP type and code.
+	if rule.ICMPType != "" || rule.ICMPCode != "" {
+		if !shared.StringInSlice(rule.Protocol, []string{"icmp4", "icmp6"}) {
+			return fmt.Errorf("ICMP type and code can only be specified with the icmp4 or icmp6 protocols")
+		}
+
+		if rule.ICMPType == "" {
+			return fmt.Errorf("ICMP code requires an ICMP type")
+		}
+
+		err = validate.IsUint8(rule.ICMPType)
+		if err != nil {
+			return errors.Wrapf(err, "Invalid ICMP type")
+		}
+
+		err = validate.Optional(validate.IsUint8)(rule.ICMPCode)
+		if err != nil {
+			return errors.Wrapf(err, "Invalid ICMP code")
+		}
+	}
+
+	return nil
+}
+
+// validateSubjects checks a comma separated list of IPs and CIDR subnets and returns the IP families used.
+// Returns nil if the list is empty (matches any family).
+func (d *common) validateSubjects(subjects string) (map[uint]bool, error) {
+	if subjects == "" {
+		return nil, nil
+	}
+
+	families := map[uint]bool{}
+	for _, subject := range strings.Split(subjects, ",") {
+		var ip net.IP
+		if strings.Contains(subject, "/") {
+			var err error
+			ip, _, err = net.ParseCIDR(subject)
+			if err != nil {
+				return nil, fmt.Errorf("Invalid subnet %q", subject)
+			}
+		} else {
+			ip = net.ParseIP(subject)
+			if ip == nil {
+				return nil, fmt.Errorf("Invalid IP address %q", subject)
+			}
+		}
+
+		if ip.To4() != nil {
+			families[4] = true
+		} else {
+			families[6] = true
+		}
+	}
+
+	return families, nil
+}
+
+// validatePorts checks a comma separated list of ports and port ranges.
+func (d *common) validatePorts(ports string) error {
+	if ports == "" {
+		return nil
+	}
+
+	for _, port := range strings.Split(ports, ",") {
+		fields := strings.SplitN(port, "-", 2)
+		values := make([]uint64, 0, len(fields))
+		for _, field := range fields {
+			value, err := strconv.ParseUint(field, 10, 16)
+			if err != nil {
+				return fmt.Errorf("Invalid port %q", port)
+			}
+
+			values = append(values, value)
+		}
+
+		if len(values) == 2 && values[0] >= values[1] {
+			return fmt.Errorf("Invalid port range %q", port)
+		}
+	}
+
+	return nil
+}
+
+// normaliseRules normalises the rules of the config so that they are stored consistently.
+func (d *common) normaliseRules(info *api.NetworkACLPut) {
+	for i := range info.Ingress {
+		info.Ingress[i].Normalise()
+	}
+
+	for i := range info.Egress {
+		info.Egress[i].Normalise()
+	}
+}
+
+// Update applies the supplied config to the ACL.
+func (d *common) Update(config *api.NetworkACLPut) error {
+	d.normaliseRules(config)
+
+	err := d.validateConfig(config)
+	if err != nil {
+		return err
+	}
+
+	err = d.state.Cluster.UpdateNetworkACL(d.id, config)
+	if err != nil {
+		return err
+	}
+
+	// Apply changes internally.
+	d.info.Description = config.Description
+	d.info.Ingress = config.Ingress
+	d.info.Egress = config.Egress
+	d.info.Config = config.Config
+	d.init(d.state, d.id, d.projectName, d.info)
+
+	return nil
+}
+
+// Rename renames the ACL if not in use.
+func (d *common) Rename(newName string) error {
+	err := ValidName(newName)
+	if err != nil {
+		return err
+	}
+
+	isUsed, err := d.isUsed()
+	if err != nil {
+		return err
+	}
+
+	if isUsed {
+		return fmt.Errorf("Cannot rename an ACL that is in use")
+	}
+
+	err = d.state.Cluster.RenameNetworkACL(d.id, newName)
+	if err != nil {
+		return err
+	}
+
+	// Apply changes internally.
+	d.info.Name = newName
+	d.init(d.state, d.id, d.projectName, d.info)
+
+	return nil
+}
+
+// Delete deletes the ACL if not in use.
+func (d *common) Delete() error {
+	isUsed, err := d.isUsed()
+	if err != nil {
+		return err
+	}
+
+	if isUsed {
+		return fmt.Errorf("Cannot delete an ACL that is in use")
+	}
+
+	return d.state.Cluster.DeleteNetworkACL(d.id)
+}
+
+// devicesUseACL returns whether any NIC device in the set references the ACL.
+func devicesUseACL(devices map[string]map[string]string, aclName string) bool {
+	for _, dev := range devices {
+		if dev["type"] != "nic" {
+			continue
+		}
+
+		if shared.StringInSlice(aclName, ParseNames(dev["security.acls"])) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// UsedByNetworks returns the names of the networks in the project that reference the ACL in their config.
+func UsedByNetworks(s *state.State, projectName string, aclName string) ([]string, error) {
+	networkNames, err := s.Cluster.GetNetworks(projectName)
+	if err != nil {
+		return nil, err
+	}
+
+	usedBy := []string{}
+	for _, networkName := range networkNames {
+		_, network, err := s.Cluster.GetNetworkInAnyState(projectName, networkName)
+		if err != nil {
+			return nil, err
+		}
+
+		if shared.StringInSlice(aclName, ParseNames(network.Config["security.acls"])) {
+			usedBy = append(usedBy, networkName)
+		}
+	}
+
+	return usedBy, nil
+}
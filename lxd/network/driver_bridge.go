@@ -23,6 +23,7 @@ import (
 	"github.com/lxc/lxd/lxd/daemon"
 	"github.com/lxc/lxd/lxd/dnsmasq"
 	"github.com/lxc/lxd/lxd/dnsmasq/dhcpalloc"
+	"github.com/lxc/lxd/lxd/network/acl"
 	"github.com/lxc/lxd/lxd/network/openvswitch"
 	"github.com/lxc/lxd/lxd/node"
 	"github.com/lxc/lxd/lxd/revert"
@@ -230,6 +231,11 @@ func (n *bridge) Validate(config map[string]string) error {
 		"maas.subnet.ipv6": validate.IsAny,
 	}
 
+	// Add the network ACL rules.
+	for k, validator := range n.aclValidationRules() {
+		rules[k] = validator
+	}
+
 	// Add dynamic validation rules.
 	for k := range config {
 		// Tunnel keys have the remote name in their name, so extract the real key
@@ -1275,6 +1281,12 @@ func (n *bridge) setup(oldConfig map[string]string) error {
 		}
 	}
 
+	// Apply the network ACLs.
+	err = n.applyACLs()
+	if err != nil {
+		return err
+	}
+
 	// Generate and load apparmor profiles.
 	err = apparmor.NetworkLoad(n.state, n)
 	if err != nil {
@@ -1457,6 +1469,13 @@ func (n *bridge) Stop() error {
 		}
 	}
 
+	if n.config["security.acls"] != "" {
+		err := n.state.Firewall.NetworkApplyACLRules(n.name, nil)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Kill any existing dnsmasq and forkdns daemon for this network
 	err := dnsmasq.Kill(n.name, false)
 	if err != nil {
@@ -1890,6 +1909,23 @@ func (n *bridge) updateForkdnsServersFile(addresses []string) error {
 	return nil
 }
 
+// applyACLs applies the rules of the network's ACLs to the traffic forwarded to and from the bridge.
+// If no ACLs are set, any previously applied rules are removed.
+func (n *bridge) applyACLs() error {
+	aclNames := acl.ParseNames(n.config["security.acls"])
+	rules, err := acl.FirewallRules(n.state, n.project, aclNames, n.config["security.acls.default.ingress.action"], n.config["security.acls.default.egress.action"])
+	if err != nil {
+		return err
+	}
+
+	err = n.state.Firewall.NetworkApplyACLRules(n.name, rules)
+	if err != nil {
+		return errors.Wrapf(err, "Failed applying network ACLs")
+	}
+
+	return nil
+}
+
 // hasIPv4Firewall indicates whether the network has IPv4 firewall enabled.
 func (n *bridge) hasIPv4Firewall() bool {
 	if n.config["ipv4.firewall"] == "" || shared.IsTrue(n.config["ipv4.firewall"]) {
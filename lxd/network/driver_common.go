@@ -12,6 +12,8 @@ import (
 	"github.com/lxc/lxd/lxd/cluster"
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/network/acl"
+	"github.com/lxc/lxd/lxd/project"
 	"github.com/lxc/lxd/lxd/state"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
@@ -26,6 +28,7 @@ type common struct {
 	logger      logger.Logger
 	state       *state.State
 	id          int64
+	project     string
 	name        string
 	netType     string
 	description string
@@ -34,9 +37,10 @@ type common struct {
 }
 
 // init initialise internal variables.
-func (n *common) init(state *state.State, id int64, name string, netType string, description string, config map[string]string, status string) {
-	n.logger = logging.AddContext(logger.Log, log.Ctx{"driver": netType, "network": name})
+func (n *common) init(state *state.State, id int64, projectName string, name string, netType string, description string, config map[string]string, status string) {
+	n.logger = logging.AddContext(logger.Log, log.Ctx{"project": projectName, "driver": netType, "network": name})
 	n.id = id
+	n.project = projectName
 	n.name = name
 	n.netType = netType
 	n.config = config
@@ -55,6 +59,21 @@ func (n *common) validationRules() map[string]func(string) error {
 	return map[string]func(string) error{}
 }
 
+// aclValidationRules returns the validation rules for the network ACL config keys.
+func (n *common) aclValidationRules() map[string]func(string) error {
+	return map[string]func(string) error{
+		"security.acls": func(value string) error {
+			return acl.ValidateNames(n.state, n.project, value)
+		},
+		"security.acls.default.ingress.action": validate.Optional(func(value string) error {
+			return validate.IsOneOf(value, []string{"allow", "reject", "drop"})
+		}),
+		"security.acls.default.egress.action": validate.Optional(func(value string) error {
+			return validate.IsOneOf(value, []string{"allow", "reject", "drop"})
+		}),
+	}
+}
+
 // validate a network config against common rules and optional driver specific rules.
 func (n *common) validate(config map[string]string, driverRules map[string]func(value string) error) error {
 	checkedFields := map[string]struct{}{}
@@ -109,6 +128,11 @@ func (n *common) Name() string {
 	return n.name
 }
 
+// Project returns the network project.
+func (n *common) Project() string {
+	return n.project
+}
+
 // Status returns the network status.
 func (n *common) Status() string {
 	return n.status
@@ -133,7 +157,7 @@ func (n *common) IsUsed() (bool, error) {
 	}
 
 	for _, inst := range insts {
-		inUse, err := IsInUseByInstance(n.state, inst, n.name)
+		inUse, err := IsInUseByInstance(n.state, inst, n.project, n.name)
 		if err != nil {
 			return false, err
 		}
@@ -158,7 +182,7 @@ func (n *common) IsUsed() (bool, error) {
 	}
 
 	for _, profile := range profiles {
-		inUse, err := IsInUseByProfile(n.state, *db.ProfileToAPI(&profile), n.name)
+		inUse, err := IsInUseByProfile(n.state, profile.Project, *db.ProfileToAPI(&profile), n.project, n.name)
 		if err != nil {
 			return false, err
 		}
@@ -225,7 +249,7 @@ func (n *common) DHCPv6Ranges() []shared.IPRange {
 func (n *common) update(applyNetwork api.NetworkPut, targetNode string, clientType cluster.ClientType) error {
 	// Update internal config before database has been updated (so that if update is a notification we apply
 	// the config being supplied and not that in the database).
-	n.init(n.state, n.id, n.name, n.netType, applyNetwork.Description, applyNetwork.Config, n.status)
+	n.init(n.state, n.id, n.project, n.name, n.netType, applyNetwork.Description, applyNetwork.Config, n.status)
 
 	// If this update isn't coming via a cluster notification itself, then notify all nodes of change and then
 	// update the database.
@@ -249,7 +273,7 @@ func (n *common) update(applyNetwork api.NetworkPut, targetNode string, clientTy
 			}
 
 			err = notifier(func(client lxd.InstanceServer) error {
-				return client.UpdateNetwork(n.name, sendNetwork, "")
+				return client.UseProject(n.project).UpdateNetwork(n.name, sendNetwork, "")
 			})
 			if err != nil {
 				return err
@@ -257,7 +281,7 @@ func (n *common) update(applyNetwork api.NetworkPut, targetNode string, clientTy
 		}
 
 		// Update the database.
-		err := n.state.Cluster.UpdateNetwork(n.name, applyNetwork.Description, applyNetwork.Config)
+		err := n.state.Cluster.UpdateNetwork(n.project, n.name, applyNetwork.Description, applyNetwork.Config)
 		if err != nil {
 			return err
 		}
@@ -316,27 +340,30 @@ func (n *common) configChanged(newNetwork api.NetworkPut) (bool, []string, api.N
 
 // rename the network directory, update database record and update internal variables.
 func (n *common) rename(newName string) error {
-	// Clear new directory if exists.
-	if shared.PathExists(shared.VarPath("networks", newName)) {
-		os.RemoveAll(shared.VarPath("networks", newName))
-	}
+	// Only networks in the default project have a directory (they share the host namespace).
+	if n.project == project.Default {
+		// Clear new directory if exists.
+		if shared.PathExists(shared.VarPath("networks", newName)) {
+			os.RemoveAll(shared.VarPath("networks", newName))
+		}
 
-	// Rename directory to new name.
-	if shared.PathExists(shared.VarPath("networks", n.name)) {
-		err := os.Rename(shared.VarPath("networks", n.name), shared.VarPath("networks", newName))
-		if err != nil {
-			return err
+		// Rename directory to new name.
+		if shared.PathExists(shared.VarPath("networks", n.name)) {
+			err := os.Rename(shared.VarPath("networks", n.name), shared.VarPath("networks", newName))
+			if err != nil {
+				return err
+			}
 		}
 	}
 
 	// Rename the database entry.
-	err := n.state.Cluster.RenameNetwork(n.name, newName)
+	err := n.state.Cluster.RenameNetwork(n.project, n.name, newName)
 	if err != nil {
 		return err
 	}
 
 	// Reinitialise internal name variable and logger context with new name.
-	n.init(n.state, n.id, newName, n.netType, n.description, n.config, n.status)
+	n.init(n.state, n.id, n.project, newName, n.netType, n.description, n.config, n.status)
 
 	return nil
 }
@@ -351,21 +378,21 @@ func (n *common) delete(clientType cluster.ClientType) error {
 			return err
 		}
 		err = notifier(func(client lxd.InstanceServer) error {
-			return client.DeleteNetwork(n.name)
+			return client.UseProject(n.project).DeleteNetwork(n.name)
 		})
 		if err != nil {
 			return err
 		}
 
 		// Remove the network from the database.
-		err = n.state.Cluster.DeleteNetwork(n.name)
+		err = n.state.Cluster.DeleteNetwork(n.project, n.name)
 		if err != nil {
 			return err
 		}
 	}
 
-	// Cleanup storage.
-	if shared.PathExists(shared.VarPath("networks", n.name)) {
+	// Cleanup storage (only networks in the default project have a directory).
+	if n.project == project.Default && shared.PathExists(shared.VarPath("networks", n.name)) {
 		os.RemoveAll(shared.VarPath("networks", n.name))
 	}
 
@@ -383,3 +410,33 @@ func (n *common) Create(clientType cluster.ClientType) error {
 func (n *common) HandleHeartbeat(heartbeatData *cluster.APIHeartbeat) error {
 	return nil
 }
+
+// ForwardCreate returns ErrNotImplemented for drivers that do not support forwards.
+func (n *common) ForwardCreate(forward api.NetworkForwardsPost) error {
+	return ErrNotImplemented
+}
+
+// ForwardUpdate returns ErrNotImplemented for drivers that do not support forwards.
+func (n *common) ForwardUpdate(listenAddress string, newForward api.NetworkForwardPut) error {
+	return ErrNotImplemented
+}
+
+// ForwardDelete returns ErrNotImplemented for drivers that do not support forwards.
+func (n *common) ForwardDelete(listenAddress string) error {
+	return ErrNotImplemented
+}
+
+// LoadBalancerCreate returns ErrNotImplemented for drivers that do not support load balancers.
+func (n *common) LoadBalancerCreate(loadBalancer api.NetworkLoadBalancersPost) error {
+	return ErrNotImplemented
+}
+
+// LoadBalancerUpdate returns ErrNotImplemented for drivers that do not support load balancers.
+func (n *common) LoadBalancerUpdate(listenAddress string, newLoadBalancer api.NetworkLoadBalancerPut) error {
+	return ErrNotImplemented
+}
+
+// LoadBalancerDelete returns ErrNotImplemented for drivers that do not support load balancers.
+func (n *common) LoadBalancerDelete(listenAddress string) error {
+	return ErrNotImplemented
+}
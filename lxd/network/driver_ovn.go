@@ -19,6 +19,7 @@ import (
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/dnsmasq"
 	"github.com/lxc/lxd/lxd/locking"
+	"github.com/lxc/lxd/lxd/network/acl"
 	"github.com/lxc/lxd/lxd/network/openvswitch"
 	"github.com/lxc/lxd/lxd/project"
 	"github.com/lxc/lxd/lxd/revert"
@@ -98,6 +99,11 @@ func (n *ovn) Validate(config map[string]string) error {
 		ovnVolatileParentIPv6: validate.Optional(validate.IsNetworkAddressV6),
 	}
 
+	// Add the network ACL rules.
+	for k, validator := range n.aclValidationRules() {
+		rules[k] = validator
+	}
+
 	err := n.validate(config, rules)
 	if err != nil {
 		return err
@@ -245,6 +251,11 @@ func (n *ovn) getExtSwitchProviderPortName() openvswitch.OVNSwitchPort {
 	return openvswitch.OVNSwitchPort(fmt.Sprintf("%s-lsp-provider", n.getExtSwitchName()))
 }
 
+// getLoadBalancerName returns OVN load balancer name to use for a listen address.
+func (n *ovn) getLoadBalancerName(listenAddress string) openvswitch.OVNLoadBalancer {
+	return openvswitch.OVNLoadBalancer(fmt.Sprintf("%s-lb-%s", n.getNetworkPrefix(), listenAddress))
+}
+
 // getIntSwitchName returns OVN logical internal switch name.
 func (n *ovn) getIntSwitchName() openvswitch.OVNSwitch {
 	return openvswitch.OVNSwitch(fmt.Sprintf("%s-ls-int", n.getNetworkPrefix()))
@@ -263,7 +274,7 @@ func (n *ovn) getIntSwitchInstancePortPrefix() string {
 // setupParentPort initialises the parent uplink connection. Returns the derived ovnParentVars settings used
 // during the initial creation of the logical network.
 func (n *ovn) setupParentPort(routerMAC net.HardwareAddr) (*ovnParentVars, error) {
-	parentNet, err := LoadByName(n.state, n.config["network"])
+	parentNet, err := LoadByName(n.state, project.Default, n.config["network"])
 	if err != nil {
 		return nil, errors.Wrapf(err, "Failed loading parent network")
 	}
@@ -363,7 +374,7 @@ func (n *ovn) setupParentPortBridge(parentNet Network, routerMAC net.HardwareAdd
 				n.config[ovnVolatileParentIPv6] = routerExtPortIPv6.String()
 			}
 
-			networkID, err := tx.GetNetworkID(n.name)
+			networkID, err := tx.GetNetworkID(n.project, n.name)
 			if err != nil {
 				return errors.Wrapf(err, "Failed to get network ID for network %q", n.name)
 			}
@@ -400,31 +411,57 @@ func (n *ovn) setupParentPortBridge(parentNet Network, routerMAC net.HardwareAdd
 	return v, nil
 }
 
-// parentAllAllocatedIPs gets a list of all IPv4 and IPv6 addresses allocated to OVN networks connected to parent.
+// parentAllAllocatedIPs gets a list of all IPv4 and IPv6 addresses allocated to OVN networks connected to parent,
+// including the listen addresses of their forwards and load balancers. Networks in all projects are considered.
 func (n *ovn) parentAllAllocatedIPs(tx *db.ClusterTx, parentNetName string) ([]net.IP, []net.IP, error) {
-	// Get all managed networks.
-	networks, err := tx.GetNonPendingNetworks()
+	projectNames, err := tx.GetProjectNames()
 	if err != nil {
 		return nil, nil, err
 	}
 
+	// Get all managed networks across all projects.
+	networks := make(map[int64]api.Network)
+	for _, projectName := range projectNames {
+		projectNetworks, err := tx.GetNonPendingNetworks(projectName)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for netID, netInfo := range projectNetworks {
+			networks[netID] = netInfo
+		}
+	}
+
 	v4IPs := make([]net.IP, 0)
 	v6IPs := make([]net.IP, 0)
 
-	for _, netInfo := range networks {
+	for netID, netInfo := range networks {
 		if netInfo.Type != "ovn" || netInfo.Config["network"] != parentNetName {
 			continue
 		}
 
+		addresses := []string{}
 		for _, k := range []string{ovnVolatileParentIPv4, ovnVolatileParentIPv6} {
 			if netInfo.Config[k] != "" {
-				ip := net.ParseIP(netInfo.Config[k])
-				if ip != nil {
-					if ip.To4() != nil {
-						v4IPs = append(v4IPs, ip)
-					} else {
-						v6IPs = append(v6IPs, ip)
-					}
+				addresses = append(addresses, netInfo.Config[k])
+			}
+		}
+
+		// Include the listen addresses of the network's forwards and load balancers.
+		listenAddresses, err := tx.GetNetworkListenAddresses(netID)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "Failed getting listen addresses for network %q", netInfo.Name)
+		}
+
+		addresses = append(addresses, listenAddresses...)
+
+		for _, address := range addresses {
+			ip := net.ParseIP(address)
+			if ip != nil {
+				if ip.To4() != nil {
+					v4IPs = append(v4IPs, ip)
+				} else {
+					v6IPs = append(v6IPs, ip)
 				}
 			}
 		}
@@ -486,7 +523,7 @@ func (n *ovn) parentAllocateIP(ipRanges []*shared.IPRange, allAllocated []net.IP
 
 // startParentPort performs any network start up logic needed to connect the parent uplink connection to OVN.
 func (n *ovn) startParentPort() error {
-	parentNet, err := LoadByName(n.state, n.config["network"])
+	parentNet, err := LoadByName(n.state, project.Default, n.config["network"])
 	if err != nil {
 		return errors.Wrapf(err, "Failed loading parent network")
 	}
@@ -614,7 +651,7 @@ func (n *ovn) startParentPortBridge(parentNet Network) error {
 
 // deleteParentPort deletes the parent uplink connection.
 func (n *ovn) deleteParentPort() error {
-	parentNet, err := LoadByName(n.state, n.config["network"])
+	parentNet, err := LoadByName(n.state, project.Default, n.config["network"])
 	if err != nil {
 		return errors.Wrapf(err, "Failed loading parent network")
 	}
@@ -1049,10 +1086,55 @@ func (n *ovn) setup(update bool) error {
 		return errors.Wrapf(err, "Failed linking internal router port to internal switch port")
 	}
 
+	// Apply the network ACLs to the internal switch.
+	err = n.applyACLs(client)
+	if err != nil {
+		return err
+	}
+
+	// Apply the forwards and load balancers to the logical router (which may have been recreated).
+	err = n.applyListenAddresses(client)
+	if err != nil {
+		return err
+	}
+
 	revert.Success()
 	return nil
 }
 
+// applyACLs applies the rules of the network's ACLs to the internal switch.
+// If no ACLs are set, any previously applied rules are removed.
+func (n *ovn) applyACLs(client *openvswitch.OVN) error {
+	aclNames := acl.ParseNames(n.config["security.acls"])
+	rules, err := acl.OVNSwitchRules(n.state, n.project, aclNames, n.config["security.acls.default.ingress.action"], n.config["security.acls.default.egress.action"], n.getIntSwitchRouterPortName())
+	if err != nil {
+		return err
+	}
+
+	err = client.LogicalSwitchSetACLRules(n.getIntSwitchName(), rules...)
+	if err != nil {
+		return errors.Wrapf(err, "Failed applying network ACLs")
+	}
+
+	return nil
+}
+
+// instanceDevicePortApplyACLs applies the rules of the instance device's ACLs to its switch port.
+// If no ACLs are set, any previously applied rules are removed.
+func (n *ovn) instanceDevicePortApplyACLs(client *openvswitch.OVN, instancePortName openvswitch.OVNSwitchPort, aclNames []string) error {
+	rules, err := acl.OVNPortRules(n.state, n.project, aclNames, n.config["security.acls.default.ingress.action"], n.config["security.acls.default.egress.action"], instancePortName)
+	if err != nil {
+		return err
+	}
+
+	err = client.LogicalSwitchPortSetACLRules(instancePortName, rules...)
+	if err != nil {
+		return errors.Wrapf(err, "Failed applying instance device ACLs")
+	}
+
+	return nil
+}
+
 // Delete deletes a network.
 func (n *ovn) Delete(clientType cluster.ClientType) error {
 	n.logger.Debug("Delete", log.Ctx{"clientType": clientType})
@@ -1063,6 +1145,26 @@ func (n *ovn) Delete(clientType cluster.ClientType) error {
 			return err
 		}
 
+		// Load balancers are not removed along with the logical router.
+		var listenAddresses []string
+		err = n.state.Cluster.Transaction(func(tx *db.ClusterTx) error {
+			listenAddresses, err = tx.GetNetworkListenAddresses(n.id)
+			return err
+		})
+		if err != nil {
+			return errors.Wrapf(err, "Failed getting listen addresses")
+		}
+
+		loadBalancerNames := make([]openvswitch.OVNLoadBalancer, 0, len(listenAddresses))
+		for _, listenAddress := range listenAddresses {
+			loadBalancerNames = append(loadBalancerNames, n.getLoadBalancerName(listenAddress))
+		}
+
+		err = client.LoadBalancerDelete(loadBalancerNames...)
+		if err != nil {
+			return err
+		}
+
 		err = client.LogicalRouterDelete(n.getRouterName())
 		if err != nil {
 			return err
@@ -1223,7 +1325,8 @@ func (n *ovn) getInstanceDevicePortName(instanceID int, deviceName string) openv
 }
 
 // instanceDevicePortAdd adds an instance device port to the internal logical switch and returns the port name.
-func (n *ovn) instanceDevicePortAdd(instanceID int, instanceName string, deviceName string, mac net.HardwareAddr, ips []net.IP) (openvswitch.OVNSwitchPort, error) {
+// The rules of the specified ACLs are applied to the port.
+func (n *ovn) instanceDevicePortAdd(instanceID int, instanceName string, deviceName string, mac net.HardwareAddr, ips []net.IP, aclNames []string) (openvswitch.OVNSwitchPort, error) {
 	var dhcpV4ID, dhcpv6ID string
 
 	revert := revert.New()
@@ -1287,6 +1390,11 @@ func (n *ovn) instanceDevicePortAdd(instanceID int, instanceName string, deviceN
 		return "", err
 	}
 
+	err = n.instanceDevicePortApplyACLs(client, instancePortName, aclNames)
+	if err != nil {
+		return "", err
+	}
+
 	revert.Success()
 	return instancePortName, nil
 }
@@ -1310,5 +1418,642 @@ func (n *ovn) instanceDevicePortDelete(instanceID int, deviceName string) error
 		return err
 	}
 
+	// Remove any ACL rules of the port.
+	err = client.LogicalSwitchPortSetACLRules(instancePortName)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// listenAddressValidate checks that a listen address is within the parent network's OVN ranges and that it isn't
+// already allocated to an OVN network, forward or load balancer using the same parent.
+func (n *ovn) listenAddressValidate(listenAddress string) (net.IP, error) {
+	listenIP := net.ParseIP(listenAddress)
+	if listenIP == nil {
+		return nil, fmt.Errorf("Invalid listen address %q", listenAddress)
+	}
+
+	parentNet, err := LoadByName(n.state, project.Default, n.config["network"])
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed loading parent network")
+	}
+
+	rangesKey := "ipv4.ovn.ranges"
+	parentSubnet := parentNet.DHCPv4Subnet()
+	if listenIP.To4() == nil {
+		rangesKey = "ipv6.ovn.ranges"
+		parentSubnet = parentNet.DHCPv6Subnet()
+	}
+
+	parentNetConf := parentNet.Config()
+	if parentNetConf[rangesKey] == "" {
+		return nil, fmt.Errorf("Missing required %q config key on parent network", rangesKey)
+	}
+
+	ipRanges, err := parseIPRanges(parentNetConf[rangesKey], parentSubnet)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed parsing parent %q", rangesKey)
+	}
+
+	inRange := false
+	for _, ipRange := range ipRanges {
+		if ipRange.ContainsIP(listenIP) {
+			inRange = true
+			break
+		}
+	}
+
+	if !inRange {
+		return nil, fmt.Errorf("Listen address %q is not within the parent network's %q", listenAddress, rangesKey)
+	}
+
+	err = n.state.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		// Check the listen address is within the subnets the network's project may use on the uplink.
+		p, err := tx.GetProject(n.project)
+		if err != nil {
+			return errors.Wrapf(err, "Failed loading project %q", n.project)
+		}
+
+		err = project.NetworkAddressAllowed(p.Config, parentNet.Name(), listenIP)
+		if err != nil {
+			return err
+		}
+
+		allAllocatedIPv4, allAllocatedIPv6, err := n.parentAllAllocatedIPs(tx, parentNet.Name())
+		if err != nil {
+			return errors.Wrapf(err, "Failed to get all allocated IPs for parent")
+		}
+
+		for _, allocatedIP := range append(allAllocatedIPv4, allAllocatedIPv6...) {
+			if listenIP.Equal(allocatedIP) {
+				return fmt.Errorf("Listen address %q is already in use", listenAddress)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return listenIP, nil
+}
+
+// targetAddressValidate checks that a target address is within the network's subnet of the same IP family as the
+// listen address.
+func (n *ovn) targetAddressValidate(listenIP net.IP, targetAddress string) (net.IP, error) {
+	targetIP := net.ParseIP(targetAddress)
+	if targetIP == nil {
+		return nil, fmt.Errorf("Invalid target address %q", targetAddress)
+	}
+
+	if (listenIP.To4() == nil) != (targetIP.To4() == nil) {
+		return nil, fmt.Errorf("Target address %q is not the same IP family as the listen address", targetAddress)
+	}
+
+	routerIntPortIPNet := n.getRouterIntPortIPv4Net()
+	if targetIP.To4() == nil {
+		routerIntPortIPNet = n.getRouterIntPortIPv6Net()
+	}
+
+	routerIntPortIP, subnet, err := net.ParseCIDR(routerIntPortIPNet)
+	if err != nil || !subnet.Contains(targetIP) {
+		return nil, fmt.Errorf("Target address %q is not within the network's subnet", targetAddress)
+	}
+
+	if targetIP.Equal(routerIntPortIP) {
+		return nil, fmt.Errorf("Target address %q is the network's router address", targetAddress)
+	}
+
+	return targetIP, nil
+}
+
+// targetPortsMap parses the target ports and returns the target port to use for each of the listen ports.
+// If no target ports are specified, the listen ports are used. A single target port is used for all listen ports.
+func (n *ovn) targetPortsMap(listenPorts []uint64, targetPorts string) ([]uint64, error) {
+	if targetPorts == "" {
+		return listenPorts, nil
+	}
+
+	ports, err := parsePortRanges(targetPorts)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ports) == 1 {
+		mappedPorts := make([]uint64, 0, len(listenPorts))
+		for range listenPorts {
+			mappedPorts = append(mappedPorts, ports[0])
+		}
+
+		return mappedPorts, nil
+	}
+
+	if len(ports) != len(listenPorts) {
+		return nil, fmt.Errorf("Mismatch between listen port(s) and target port(s) count")
+	}
+
+	return ports, nil
+}
+
+// listenPortsValidate checks the protocol and listen ports of a port specification and returns the listen ports.
+// The usedPorts map is used to detect the same port being used by multiple port specifications.
+func (n *ovn) listenPortsValidate(protocol string, listenPort string, usedPorts map[string]struct{}) ([]uint64, error) {
+	if !shared.StringInSlice(protocol, []string{"tcp", "udp"}) {
+		return nil, fmt.Errorf("Invalid protocol %q for listen port %q", protocol, listenPort)
+	}
+
+	listenPorts, err := parsePortRanges(listenPort)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Invalid listen port %q", listenPort)
+	}
+
+	for _, port := range listenPorts {
+		key := fmt.Sprintf("%s/%d", protocol, port)
+		_, found := usedPorts[key]
+		if found {
+			return nil, fmt.Errorf("Duplicate listen port %d for protocol %q", port, protocol)
+		}
+
+		usedPorts[key] = struct{}{}
+	}
+
+	return listenPorts, nil
+}
+
+// forwardValidate validates a forward and returns its default target address (if set) and the load balancer VIPs
+// needed for its ports.
+func (n *ovn) forwardValidate(listenIP net.IP, forward *api.NetworkForwardPut) (net.IP, []openvswitch.OVNLoadBalancerVIP, error) {
+	var defaultTarget net.IP
+	var err error
+
+	for k, v := range forward.Config {
+		if k == "target_address" {
+			if v == "" {
+				continue
+			}
+
+			defaultTarget, err = n.targetAddressValidate(listenIP, v)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			continue
+		}
+
+		if !strings.HasPrefix(k, "user.") {
+			return nil, nil, fmt.Errorf("Invalid config key %q", k)
+		}
+	}
+
+	vips := []openvswitch.OVNLoadBalancerVIP{}
+	usedPorts := map[string]struct{}{}
+	for _, port := range forward.Ports {
+		listenPorts, err := n.listenPortsValidate(port.Protocol, port.ListenPort, usedPorts)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		targetIP, err := n.targetAddressValidate(listenIP, port.TargetAddress)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		targetPorts, err := n.targetPortsMap(listenPorts, port.TargetPort)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "Invalid target port %q", port.TargetPort)
+		}
+
+		for i, listenPort := range listenPorts {
+			vips = append(vips, openvswitch.OVNLoadBalancerVIP{
+				Protocol:      port.Protocol,
+				ListenAddress: listenIP,
+				ListenPort:    listenPort,
+				Targets:       []openvswitch.OVNLoadBalancerTarget{{Address: targetIP, Port: targetPorts[i]}},
+			})
+		}
+	}
+
+	return defaultTarget, vips, nil
+}
+
+// loadBalancerValidate validates a load balancer and returns the load balancer VIPs needed for its ports.
+func (n *ovn) loadBalancerValidate(listenIP net.IP, loadBalancer *api.NetworkLoadBalancerPut) ([]openvswitch.OVNLoadBalancerVIP, error) {
+	for k := range loadBalancer.Config {
+		if !strings.HasPrefix(k, "user.") {
+			return nil, fmt.Errorf("Invalid config key %q", k)
+		}
+	}
+
+	backends := make(map[string]api.NetworkLoadBalancerBackend, len(loadBalancer.Backends))
+	backendIPs := make(map[string]net.IP, len(loadBalancer.Backends))
+	for _, backend := range loadBalancer.Backends {
+		if backend.Name == "" {
+			return nil, fmt.Errorf("Backend name is required")
+		}
+
+		_, found := backends[backend.Name]
+		if found {
+			return nil, fmt.Errorf("Duplicate backend name %q", backend.Name)
+		}
+
+		targetIP, err := n.targetAddressValidate(listenIP, backend.TargetAddress)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid backend %q", backend.Name)
+		}
+
+		if backend.TargetPort != "" {
+			_, err = parsePortRanges(backend.TargetPort)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Invalid target port for backend %q", backend.Name)
+			}
+		}
+
+		backends[backend.Name] = backend
+		backendIPs[backend.Name] = targetIP
+	}
+
+	vips := []openvswitch.OVNLoadBalancerVIP{}
+	usedPorts := map[string]struct{}{}
+	for _, port := range loadBalancer.Ports {
+		listenPorts, err := n.listenPortsValidate(port.Protocol, port.ListenPort, usedPorts)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(port.TargetBackend) == 0 {
+			return nil, fmt.Errorf("No target backends for listen port %q", port.ListenPort)
+		}
+
+		// Build the targets for each of the listen ports.
+		targets := make([][]openvswitch.OVNLoadBalancerTarget, len(listenPorts))
+		for _, backendName := range port.TargetBackend {
+			backend, found := backends[backendName]
+			if !found {
+				return nil, fmt.Errorf("Target backend %q not found for listen port %q", backendName, port.ListenPort)
+			}
+
+			targetPorts, err := n.targetPortsMap(listenPorts, backend.TargetPort)
+			if err != nil {
+				return nil, errors.Wrapf(err, "Invalid target port for backend %q", backendName)
+			}
+
+			for i := range listenPorts {
+				targets[i] = append(targets[i], openvswitch.OVNLoadBalancerTarget{
+					Address: backendIPs[backendName],
+					Port:    targetPorts[i],
+				})
+			}
+		}
+
+		for i, listenPort := range listenPorts {
+			vips = append(vips, openvswitch.OVNLoadBalancerVIP{
+				Protocol:      port.Protocol,
+				ListenAddress: listenIP,
+				ListenPort:    listenPort,
+				Targets:       targets[i],
+			})
+		}
+	}
+
+	return vips, nil
+}
+
+// listenAddressApply applies the default target and load balancer VIPs of a listen address to the logical router.
+// Any previously applied default target and VIPs for the listen address are replaced.
+func (n *ovn) listenAddressApply(client *openvswitch.OVN, listenIP net.IP, defaultTarget net.IP, vips []openvswitch.OVNLoadBalancerVIP) error {
+	err := client.LogicalRouterDNATSNATDelete(n.getRouterName(), listenIP)
+	if err != nil {
+		return errors.Wrapf(err, "Failed removing default target")
+	}
+
+	if defaultTarget != nil {
+		err = client.LogicalRouterDNATSNATAdd(n.getRouterName(), listenIP, defaultTarget)
+		if err != nil {
+			return errors.Wrapf(err, "Failed adding default target")
+		}
+	}
+
+	loadBalancerName := n.getLoadBalancerName(listenIP.String())
+	if len(vips) == 0 {
+		err = client.LoadBalancerDelete(loadBalancerName)
+		if err != nil {
+			return errors.Wrapf(err, "Failed removing load balancer")
+		}
+
+		return nil
+	}
+
+	err = client.LoadBalancerApply(loadBalancerName, []openvswitch.OVNRouter{n.getRouterName()}, []openvswitch.OVNSwitch{n.getIntSwitchName()}, vips...)
+	if err != nil {
+		return errors.Wrapf(err, "Failed applying load balancer")
+	}
+
+	return nil
+}
+
+// listenAddressRemove removes the default target and load balancer VIPs of a listen address.
+func (n *ovn) listenAddressRemove(client *openvswitch.OVN, listenAddress string) error {
+	return n.listenAddressApply(client, net.ParseIP(listenAddress), nil, nil)
+}
+
+// applyListenAddresses applies the network's forwards and load balancers to the logical router.
+func (n *ovn) applyListenAddresses(client *openvswitch.OVN) error {
+	forwardListenAddresses, err := n.state.Cluster.GetNetworkForwardListenAddresses(n.id)
+	if err != nil {
+		return errors.Wrapf(err, "Failed loading forwards")
+	}
+
+	for _, listenAddress := range forwardListenAddresses {
+		_, forward, err := n.state.Cluster.GetNetworkForward(n.id, listenAddress)
+		if err != nil {
+			return errors.Wrapf(err, "Failed loading forward %q", listenAddress)
+		}
+
+		listenIP := net.ParseIP(listenAddress)
+		defaultTarget, vips, err := n.forwardValidate(listenIP, &forward.NetworkForwardPut)
+		if err != nil {
+			return errors.Wrapf(err, "Invalid forward %q", listenAddress)
+		}
+
+		err = n.listenAddressApply(client, listenIP, defaultTarget, vips)
+		if err != nil {
+			return errors.Wrapf(err, "Failed applying forward %q", listenAddress)
+		}
+	}
+
+	loadBalancerListenAddresses, err := n.state.Cluster.GetNetworkLoadBalancerListenAddresses(n.id)
+	if err != nil {
+		return errors.Wrapf(err, "Failed loading load balancers")
+	}
+
+	for _, listenAddress := range loadBalancerListenAddresses {
+		_, loadBalancer, err := n.state.Cluster.GetNetworkLoadBalancer(n.id, listenAddress)
+		if err != nil {
+			return errors.Wrapf(err, "Failed loading load balancer %q", listenAddress)
+		}
+
+		listenIP := net.ParseIP(listenAddress)
+		vips, err := n.loadBalancerValidate(listenIP, &loadBalancer.NetworkLoadBalancerPut)
+		if err != nil {
+			return errors.Wrapf(err, "Invalid load balancer %q", listenAddress)
+		}
+
+		err = n.listenAddressApply(client, listenIP, nil, vips)
+		if err != nil {
+			return errors.Wrapf(err, "Failed applying load balancer %q", listenAddress)
+		}
+	}
+
+	return nil
+}
+
+// ForwardCreate creates a network forward.
+func (n *ovn) ForwardCreate(forward api.NetworkForwardsPost) error {
+	forward.Normalise()
+
+	listenIP, err := n.listenAddressValidate(forward.ListenAddress)
+	if err != nil {
+		return err
+	}
+
+	// Store the listen address in its canonical form.
+	forward.ListenAddress = listenIP.String()
+
+	defaultTarget, vips, err := n.forwardValidate(listenIP, &forward.NetworkForwardPut)
+	if err != nil {
+		return err
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	id, err := n.state.Cluster.CreateNetworkForward(n.id, &forward)
+	if err != nil {
+		return errors.Wrapf(err, "Failed creating forward")
+	}
+
+	revert.Add(func() { n.state.Cluster.DeleteNetworkForward(id) })
+
+	// If we are in mock mode, just no-op.
+	if !n.state.OS.MockMode {
+		client, err := n.getClient()
+		if err != nil {
+			return err
+		}
+
+		revert.Add(func() { n.listenAddressRemove(client, forward.ListenAddress) })
+
+		err = n.listenAddressApply(client, listenIP, defaultTarget, vips)
+		if err != nil {
+			return err
+		}
+	}
+
+	revert.Success()
+	return nil
+}
+
+// ForwardUpdate updates a network forward.
+func (n *ovn) ForwardUpdate(listenAddress string, newForward api.NetworkForwardPut) error {
+	id, forward, err := n.state.Cluster.GetNetworkForward(n.id, listenAddress)
+	if err != nil {
+		return err
+	}
+
+	newForward.Normalise()
+
+	listenIP := net.ParseIP(forward.ListenAddress)
+	defaultTarget, vips, err := n.forwardValidate(listenIP, &newForward)
+	if err != nil {
+		return err
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	err = n.state.Cluster.UpdateNetworkForward(id, &newForward)
+	if err != nil {
+		return errors.Wrapf(err, "Failed updating forward")
+	}
+
+	oldForward := forward.Writable()
+	revert.Add(func() { n.state.Cluster.UpdateNetworkForward(id, &oldForward) })
+
+	// If we are in mock mode, just no-op.
+	if !n.state.OS.MockMode {
+		client, err := n.getClient()
+		if err != nil {
+			return err
+		}
+
+		revert.Add(func() {
+			oldDefaultTarget, oldVIPs, err := n.forwardValidate(listenIP, &oldForward)
+			if err == nil {
+				n.listenAddressApply(client, listenIP, oldDefaultTarget, oldVIPs)
+			}
+		})
+
+		err = n.listenAddressApply(client, listenIP, defaultTarget, vips)
+		if err != nil {
+			return err
+		}
+	}
+
+	revert.Success()
+	return nil
+}
+
+// ForwardDelete deletes a network forward.
+func (n *ovn) ForwardDelete(listenAddress string) error {
+	id, forward, err := n.state.Cluster.GetNetworkForward(n.id, listenAddress)
+	if err != nil {
+		return err
+	}
+
+	// If we are in mock mode, just no-op.
+	if !n.state.OS.MockMode {
+		client, err := n.getClient()
+		if err != nil {
+			return err
+		}
+
+		err = n.listenAddressRemove(client, forward.ListenAddress)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = n.state.Cluster.DeleteNetworkForward(id)
+	if err != nil {
+		return errors.Wrapf(err, "Failed deleting forward")
+	}
+
+	return nil
+}
+
+// LoadBalancerCreate creates a network load balancer.
+func (n *ovn) LoadBalancerCreate(loadBalancer api.NetworkLoadBalancersPost) error {
+	loadBalancer.Normalise()
+
+	listenIP, err := n.listenAddressValidate(loadBalancer.ListenAddress)
+	if err != nil {
+		return err
+	}
+
+	// Store the listen address in its canonical form.
+	loadBalancer.ListenAddress = listenIP.String()
+
+	vips, err := n.loadBalancerValidate(listenIP, &loadBalancer.NetworkLoadBalancerPut)
+	if err != nil {
+		return err
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	id, err := n.state.Cluster.CreateNetworkLoadBalancer(n.id, &loadBalancer)
+	if err != nil {
+		return errors.Wrapf(err, "Failed creating load balancer")
+	}
+
+	revert.Add(func() { n.state.Cluster.DeleteNetworkLoadBalancer(id) })
+
+	// If we are in mock mode, just no-op.
+	if !n.state.OS.MockMode {
+		client, err := n.getClient()
+		if err != nil {
+			return err
+		}
+
+		revert.Add(func() { n.listenAddressRemove(client, loadBalancer.ListenAddress) })
+
+		err = n.listenAddressApply(client, listenIP, nil, vips)
+		if err != nil {
+			return err
+		}
+	}
+
+	revert.Success()
+	return nil
+}
+
+// LoadBalancerUpdate updates a network load balancer.
+func (n *ovn) LoadBalancerUpdate(listenAddress string, newLoadBalancer api.NetworkLoadBalancerPut) error {
+	id, loadBalancer, err := n.state.Cluster.GetNetworkLoadBalancer(n.id, listenAddress)
+	if err != nil {
+		return err
+	}
+
+	newLoadBalancer.Normalise()
+
+	listenIP := net.ParseIP(loadBalancer.ListenAddress)
+	vips, err := n.loadBalancerValidate(listenIP, &newLoadBalancer)
+	if err != nil {
+		return err
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	err = n.state.Cluster.UpdateNetworkLoadBalancer(id, &newLoadBalancer)
+	if err != nil {
+		return errors.Wrapf(err, "Failed updating load balancer")
+	}
+
+	oldLoadBalancer := loadBalancer.Writable()
+	revert.Add(func() { n.state.Cluster.UpdateNetworkLoadBalancer(id, &oldLoadBalancer) })
+
+	// If we are in mock mode, just no-op.
+	if !n.state.OS.MockMode {
+		client, err := n.getClient()
+		if err != nil {
+			return err
+		}
+
+		revert.Add(func() {
+			oldVIPs, err := n.loadBalancerValidate(listenIP, &oldLoadBalancer)
+			if err == nil {
+				n.listenAddressApply(client, listenIP, nil, oldVIPs)
+			}
+		})
+
+		err = n.listenAddressApply(client, listenIP, nil, vips)
+		if err != nil {
+			return err
+		}
+	}
+
+	revert.Success()
+	return nil
+}
+
+// LoadBalancerDelete deletes a network load balancer.
+func (n *ovn) LoadBalancerDelete(listenAddress string) error {
+	id, loadBalancer, err := n.state.Cluster.GetNetworkLoadBalancer(n.id, listenAddress)
+	if err != nil {
+		return err
+	}
+
+	// If we are in mock mode, just no-op.
+	if !n.state.OS.MockMode {
+		client, err := n.getClient()
+		if err != nil {
+			return err
+		}
+
+		err = n.listenAddressRemove(client, loadBalancer.ListenAddress)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = n.state.Cluster.DeleteNetworkLoadBalancer(id)
+	if err != nil {
+		return errors.Wrapf(err, "Failed deleting load balancer")
+	}
+
 	return nil
 }
@@ -12,7 +12,7 @@ import (
 // Network represents a LXD network.
 type Network interface {
 	// Load.
-	init(state *state.State, id int64, name string, netType string, description string, config map[string]string, status string)
+	init(state *state.State, id int64, projectName string, name string, netType string, description string, config map[string]string, status string)
 	fillConfig(config map[string]string) error
 
 	// Config.
@@ -20,6 +20,7 @@ type Network interface {
 	Validate(config map[string]string) error
 	ID() int64
 	Name() string
+	Project() string
 	Type() string
 	Status() string
 	Config() map[string]string
@@ -37,4 +38,14 @@ type Network interface {
 	Update(newNetwork api.NetworkPut, targetNode string, clientType cluster.ClientType) error
 	HandleHeartbeat(heartbeatData *cluster.APIHeartbeat) error
 	Delete(clientType cluster.ClientType) error
+
+	// Address forwards.
+	ForwardCreate(forward api.NetworkForwardsPost) error
+	ForwardUpdate(listenAddress string, newForward api.NetworkForwardPut) error
+	ForwardDelete(listenAddress string) error
+
+	// Load balancers.
+	LoadBalancerCreate(loadBalancer api.NetworkLoadBalancersPost) error
+	LoadBalancerUpdate(listenAddress string, newLoadBalancer api.NetworkLoadBalancerPut) error
+	LoadBalancerDelete(listenAddress string) error
 }
@@ -70,23 +70,30 @@ func networkValidPort(value string) error {
 
 // IsInUseByInstance indicates if network is referenced by an instance's NIC devices.
 // Checks if the device's parent or network properties match the network name.
-func IsInUseByInstance(s *state.State, c instance.Instance, networkName string) (bool, error) {
-	return isInUseByDevices(s, c.ExpandedDevices(), networkName)
+func IsInUseByInstance(s *state.State, c instance.Instance, networkProjectName string, networkName string) (bool, error) {
+	return isInUseByDevices(s, c.Project(), c.ExpandedDevices(), networkProjectName, networkName)
 }
 
 // IsInUseByProfile indicates if network is referenced by a profile's NIC devices.
 // Checks if the device's parent or network properties match the network name.
-func IsInUseByProfile(s *state.State, profile api.Profile, networkName string) (bool, error) {
-	return isInUseByDevices(s, deviceConfig.NewDevices(profile.Devices), networkName)
+func IsInUseByProfile(s *state.State, profileProjectName string, profile api.Profile, networkProjectName string, networkName string) (bool, error) {
+	return isInUseByDevices(s, profileProjectName, deviceConfig.NewDevices(profile.Devices), networkProjectName, networkName)
 }
 
-func isInUseByDevices(s *state.State, devices deviceConfig.Devices, networkName string) (bool, error) {
+func isInUseByDevices(s *state.State, devicesProjectName string, devices deviceConfig.Devices, networkProjectName string, networkName string) (bool, error) {
+	// Managed networks referenced by the "network" property are looked up in the effective network project of
+	// the devices' project.
+	devicesNetworkProjectName, err := project.NetworkProject(s.Cluster, devicesProjectName)
+	if err != nil {
+		return false, err
+	}
+
 	for _, d := range devices {
 		if d["type"] != "nic" {
 			continue
 		}
 
-		nicType, err := nictype.NICType(s, d)
+		nicType, err := nictype.NICType(s, devicesProjectName, d)
 		if err != nil {
 			return false, err
 		}
@@ -95,11 +102,12 @@ func isInUseByDevices(s *state.State, devices deviceConfig.Devices, networkName
 			continue
 		}
 
-		if d["network"] != "" && d["network"] == networkName {
+		if d["network"] != "" && d["network"] == networkName && devicesNetworkProjectName == networkProjectName {
 			return true, nil
 		}
 
-		if d["parent"] == "" {
+		// Host interfaces referenced by the "parent" property can only be networks in the default project.
+		if d["parent"] == "" || networkProjectName != project.Default {
 			continue
 		}
 
@@ -243,7 +251,7 @@ func UpdateDNSMasqStatic(s *state.State, networkName string) error {
 	var networks []string
 	if networkName == "" {
 		var err error
-		networks, err = s.Cluster.GetNetworks()
+		networks, err = s.Cluster.GetNetworks(project.Default)
 		if err != nil {
 			return err
 		}
@@ -267,7 +275,7 @@ func UpdateDNSMasqStatic(s *state.State, networkName string) error {
 				continue
 			}
 
-			nicType, err := nictype.NICType(s, d)
+			nicType, err := nictype.NICType(s, inst.Project(), d)
 			if err != nil || nicType != "bridged" {
 				continue
 			}
@@ -322,9 +330,9 @@ func UpdateDNSMasqStatic(s *state.State, networkName string) error {
 			continue
 		}
 
-		n, err := LoadByName(s, network)
+		n, err := LoadByName(s, project.Default, network)
 		if err != nil {
-			return errors.Wrapf(err, "Failed to load network %q in project %q for dnsmasq update", project.Default, network)
+			return errors.Wrapf(err, "Failed to load network %q in project %q for dnsmasq update", network, project.Default)
 		}
 
 		config := n.Config()
@@ -702,7 +710,7 @@ func GetLeaseAddresses(s *state.State, networkName string, hwaddr string) ([]api
 		return addresses, nil
 	}
 
-	dbInfo, err := LoadByName(s, networkName)
+	dbInfo, err := LoadByName(s, project.Default, networkName)
 	if err != nil {
 		return nil, err
 	}
@@ -986,3 +994,36 @@ func parseIPRanges(ipRangesList string, allowedNets ...*net.IPNet) ([]*shared.IP
 
 	return netIPRanges, nil
 }
+
+// parsePortRanges parses a comma separated list of ports and port ranges (e.g. "80,8000-8010") and returns the
+// individual ports in the order they were specified.
+func parsePortRanges(portsList string) ([]uint64, error) {
+	ports := []uint64{}
+	for _, portRange := range strings.Split(portsList, ",") {
+		fields := strings.SplitN(strings.TrimSpace(portRange), "-", 2)
+		values := make([]uint64, 0, len(fields))
+		for _, field := range fields {
+			value, err := strconv.ParseUint(field, 10, 16)
+			if err != nil || value == 0 {
+				return nil, fmt.Errorf("Invalid port %q", portRange)
+			}
+
+			values = append(values, value)
+		}
+
+		if len(values) == 1 {
+			ports = append(ports, values[0])
+			continue
+		}
+
+		if values[0] >= values[1] {
+			return nil, fmt.Errorf("Invalid port range %q", portRange)
+		}
+
+		for port := values[0]; port <= values[1]; port++ {
+			ports = append(ports, port)
+		}
+	}
+
+	return ports, nil
+}
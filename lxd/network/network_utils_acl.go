@@ -0,0 +1,134 @@
+package network
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/network/acl"
+	"github.com/lxc/lxd/lxd/project"
+	"github.com/lxc/lxd/lxd/state"
+	"github.com/lxc/lxd/shared"
+)
+
+// ACLUpdated re-applies the rules of the networks and local instance devices that use the ACL after it changed.
+// The OVN network rules are stored centrally in the OVN northbound database, so they are only re-applied when
+// the request isn't a cluster notification.
+func ACLUpdated(s *state.State, projectName string, aclName string, clientType cluster.ClientType) error {
+	networkNames, err := acl.UsedByNetworks(s, projectName, aclName)
+	if err != nil {
+		return err
+	}
+
+	for _, networkName := range networkNames {
+		n, err := LoadByName(s, projectName, networkName)
+		if err != nil {
+			return errors.Wrapf(err, "Failed loading network %q", networkName)
+		}
+
+		switch n := n.(type) {
+		case *bridge:
+			if !n.isRunning() {
+				continue
+			}
+
+			err = n.applyACLs()
+			if err != nil {
+				return errors.Wrapf(err, "Failed applying ACLs to network %q", networkName)
+			}
+		case *ovn:
+			if clientType != cluster.ClientTypeNormal {
+				continue
+			}
+
+			client, err := n.getClient()
+			if err != nil {
+				return err
+			}
+
+			err = n.applyACLs(client)
+			if err != nil {
+				return errors.Wrapf(err, "Failed applying ACLs to network %q", networkName)
+			}
+		}
+	}
+
+	// Re-apply the rules of the local running instances' bridge and OVN devices.
+	insts, err := instance.LoadNodeAll(s, instancetype.Any)
+	if err != nil {
+		return err
+	}
+
+	for _, inst := range insts {
+		if !inst.IsRunning() {
+			continue
+		}
+
+		// Only devices using networks from the ACL's project can reference it.
+		networkProject, err := project.NetworkProject(s.Cluster, inst.Project())
+		if err != nil {
+			return err
+		}
+
+		if networkProject != projectName {
+			continue
+		}
+
+		for devName, dev := range inst.ExpandedDevices() {
+			if dev["type"] != "nic" || dev["network"] == "" {
+				continue
+			}
+
+			aclNames := acl.ParseNames(dev["security.acls"])
+			if !shared.StringInSlice(aclName, aclNames) {
+				continue
+			}
+
+			n, err := LoadByName(s, projectName, dev["network"])
+			if err != nil {
+				return errors.Wrapf(err, "Failed loading network %q", dev["network"])
+			}
+
+			switch n.Type() {
+			case "bridge":
+				hostName := inst.LocalConfig()[fmt.Sprintf("volatile.%s.host_name", devName)]
+				if hostName == "" {
+					continue
+				}
+
+				err = BridgeInstanceDevicePortApplyACLs(s, n, inst.Project(), inst.Name(), devName, hostName, aclNames)
+			case "ovn":
+				err = OVNInstanceDevicePortApplyACLs(n, inst.ID(), devName, aclNames)
+			default:
+				continue
+			}
+
+			if err != nil {
+				return errors.Wrapf(err, "Failed applying ACLs to instance %q device %q", inst.Name(), devName)
+			}
+		}
+	}
+
+	return nil
+}
+
+// BridgeInstanceDevicePortApplyACLs applies the rules of the specified ACLs to the traffic going through the bridge
+// port of an instance device, followed by the default actions of the network. An empty list of ACLs removes any
+// rules previously applied.
+func BridgeInstanceDevicePortApplyACLs(s *state.State, n Network, instProjectName string, instName string, deviceName string, hostName string, aclNames []string) error {
+	netConfig := n.Config()
+	rules, err := acl.FirewallRules(s, n.Project(), aclNames, netConfig["security.acls.default.ingress.action"], netConfig["security.acls.default.egress.action"])
+	if err != nil {
+		return err
+	}
+
+	err = s.Firewall.InstanceApplyACLRules(instProjectName, instName, deviceName, hostName, rules)
+	if err != nil {
+		return errors.Wrapf(err, "Failed applying network ACLs")
+	}
+
+	return nil
+}
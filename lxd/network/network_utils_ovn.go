@@ -9,14 +9,15 @@ import (
 
 // OVNInstanceDevicePortAdd adds a logical port to the OVN network's internal switch and returns the logical
 // port name for use linking an OVS port on the integration bridge to the logical switch port.
-func OVNInstanceDevicePortAdd(network Network, instanceID int, instanceName string, deviceName string, mac net.HardwareAddr, ips []net.IP) (openvswitch.OVNSwitchPort, error) {
+// The rules of the specified ACLs are applied to the logical port.
+func OVNInstanceDevicePortAdd(network Network, instanceID int, instanceName string, deviceName string, mac net.HardwareAddr, ips []net.IP, aclNames []string) (openvswitch.OVNSwitchPort, error) {
 	// Check network is of type OVN.
 	n, ok := network.(*ovn)
 	if !ok {
 		return "", fmt.Errorf("Network is not OVN type")
 	}
 
-	return n.instanceDevicePortAdd(instanceID, instanceName, deviceName, mac, ips)
+	return n.instanceDevicePortAdd(instanceID, instanceName, deviceName, mac, ips, aclNames)
 }
 
 // OVNInstanceDevicePortDelete deletes a logical port from the OVN network's internal switch.
@@ -30,6 +31,22 @@ func OVNInstanceDevicePortDelete(network Network, instanceID int, deviceName str
 	return n.instanceDevicePortDelete(instanceID, deviceName)
 }
 
+// OVNInstanceDevicePortApplyACLs applies the rules of the specified ACLs to an instance device's logical port.
+func OVNInstanceDevicePortApplyACLs(network Network, instanceID int, deviceName string, aclNames []string) error {
+	// Check network is of type OVN.
+	n, ok := network.(*ovn)
+	if !ok {
+		return fmt.Errorf("Network is not OVN type")
+	}
+
+	client, err := n.getClient()
+	if err != nil {
+		return err
+	}
+
+	return n.instanceDevicePortApplyACLs(client, n.getInstanceDevicePortName(instanceID, deviceName), aclNames)
+}
+
 // OVNInstanceDeviceMTU returns the MTU that should be used for an OVN instance device.
 func OVNInstanceDeviceMTU(network Network) (uint32, error) {
 	// Check network is of type OVN.
@@ -86,3 +86,40 @@ func Example_parseIPRange() {
 	// Start: fd22:c952:653e:ffff::1, End: fd22:c952:653e:ffff::ffff
 	// Start: ::aaaa:ffff:ffff:ffff:1, End: ::aaaa:ffff:ffff:ffff:ffff
 }
+
+func Example_parsePortRanges() {
+	portRanges := []string{
+		"80",
+		"80,443",
+		"8000-8003",
+		"22, 8000-8001",
+		// Invalid port ranges.
+		"",
+		"0",
+		"65536",
+		"80-80",
+		"8010-8000",
+		"http",
+	}
+
+	for _, portRange := range portRanges {
+		ports, err := parsePortRanges(portRange)
+		if err != nil {
+			fmt.Printf("Err: %v\n", err)
+			continue
+		}
+
+		fmt.Println(ports)
+	}
+
+	// Output: [80]
+	// [80 443]
+	// [8000 8001 8002 8003]
+	// [22 8000 8001]
+	// Err: Invalid port ""
+	// Err: Invalid port "0"
+	// Err: Invalid port "65536"
+	// Err: Invalid port range "80-80"
+	// Err: Invalid port range "8010-8000"
+	// Err: Invalid port "http"
+}
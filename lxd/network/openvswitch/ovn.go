@@ -25,6 +25,9 @@ type OVNSwitchPort string
 // OVNChassisGroup OVN HA chassis group name.
 type OVNChassisGroup string
 
+// OVNLoadBalancer OVN load balancer name.
+type OVNLoadBalancer string
+
 // OVNIPAllocationOpts defines IP allocation settings that can be applied to a logical switch.
 type OVNIPAllocationOpts struct {
 	PrefixIPv4  *net.IPNet
@@ -87,6 +90,28 @@ type OVNSwitchPortOpts struct {
 	DHCPv6OptsID string           // Optional, if empty, no DHCPv6 enabled on port.
 }
 
+// OVNACLRule represents an ACL rule that can be added to a logical switch or port group.
+type OVNACLRule struct {
+	Direction string // Either "from-lport" or "to-lport".
+	Action    string // Either "allow-related", "allow", "drop", or "reject".
+	Match     string // Match criteria. See OVN Southbound database's Logical_Flow table match column usage.
+	Priority  int    // Priority (between 0 and 32767, inclusive). Higher values take precedence.
+}
+
+// OVNLoadBalancerTarget represents an OVN load balancer Virtual IP target.
+type OVNLoadBalancerTarget struct {
+	Address net.IP
+	Port    uint64
+}
+
+// OVNLoadBalancerVIP represents an OVN load balancer Virtual IP entry.
+type OVNLoadBalancerVIP struct {
+	Protocol      string // Either "tcp" or "udp".
+	ListenAddress net.IP
+	ListenPort    uint64
+	Targets       []OVNLoadBalancerTarget
+}
+
 // NewOVN initialises new OVN wrapper.
 func NewOVN() *OVN {
 	return &OVN{}
@@ -151,6 +176,39 @@ func (o *OVN) LogicalRouterSNATAdd(routerName OVNRouter, intNet *net.IPNet, extI
 	return nil
 }
 
+// LogicalRouterDNATSNATAdd adds a DNAT and SNAT rule to a logical router to translate packets from extIP to intIP.
+func (o *OVN) LogicalRouterDNATSNATAdd(routerName OVNRouter, extIP net.IP, intIP net.IP) error {
+	_, err := o.nbctl("--may-exist", "lr-nat-add", string(routerName), "dnat_and_snat", extIP.String(), intIP.String())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// LogicalRouterDNATSNATDelete deletes the DNAT and SNAT rules of a logical router for the specified extIPs.
+func (o *OVN) LogicalRouterDNATSNATDelete(routerName OVNRouter, extIPs ...net.IP) error {
+	args := []string{}
+	for _, extIP := range extIPs {
+		if len(args) > 0 {
+			args = append(args, "--")
+		}
+
+		args = append(args, "--if-exists", "lr-nat-del", string(routerName), "dnat_and_snat", extIP.String())
+	}
+
+	if len(args) == 0 {
+		return nil
+	}
+
+	_, err := o.nbctl(args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // LogicalRouterRouteAdd adds a static route to the logical router.
 func (o *OVN) LogicalRouterRouteAdd(routerName OVNRouter, destination *net.IPNet, nextHop net.IP) error {
 	_, err := o.nbctl("lr-route-add", string(routerName), destination.String(), nextHop.String())
@@ -721,6 +779,50 @@ func (o *OVN) LogicalSwitchPortDeleteDNS(switchName OVNSwitch, portName OVNSwitc
 	return nil
 }
 
+// LogicalSwitchSetACLRules replaces the ACL rules of a logical switch with the supplied rules.
+func (o *OVN) LogicalSwitchSetACLRules(switchName OVNSwitch, aclRules ...OVNACLRule) error {
+	// Remove the existing rules and add the new ones in a single transaction.
+	args := []string{"acl-del", string(switchName)}
+	for _, rule := range aclRules {
+		args = append(args, "--", "acl-add", string(switchName), rule.Direction, strconv.Itoa(rule.Priority), rule.Match, rule.Action)
+	}
+
+	_, err := o.nbctl(args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// logicalSwitchPortACLGroupName returns the port group name used to hold the ACL rules of a switch port.
+// Port group names can only contain letters, numbers and underscores.
+func (o *OVN) logicalSwitchPortACLGroupName(portName OVNSwitchPort) string {
+	return fmt.Sprintf("lxd_acl_%s", strings.Replace(string(portName), "-", "_", -1))
+}
+
+// LogicalSwitchPortSetACLRules replaces the ACL rules of a logical switch port with the supplied rules.
+// The rules are held in a port group containing only the switch port, which is removed if no rules are supplied.
+func (o *OVN) LogicalSwitchPortSetACLRules(portName OVNSwitchPort, aclRules ...OVNACLRule) error {
+	groupName := o.logicalSwitchPortACLGroupName(portName)
+
+	// Removing the port group also removes its rules.
+	args := []string{"--if-exists", "destroy", "port_group", groupName}
+	if len(aclRules) > 0 {
+		args = append(args, "--", "pg-add", groupName, string(portName))
+		for _, rule := range aclRules {
+			args = append(args, "--", "--type=port-group", "acl-add", groupName, rule.Direction, strconv.Itoa(rule.Priority), rule.Match, rule.Action)
+		}
+	}
+
+	_, err := o.nbctl(args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // LogicalSwitchPortDelete deletes a named logical switch port.
 func (o *OVN) LogicalSwitchPortDelete(portName OVNSwitchPort) error {
 	_, err := o.nbctl("--if-exists", "lsp-del", string(portName))
@@ -823,3 +925,89 @@ func (o *OVN) ChassisGroupChassisAdd(haChassisGroupName OVNChassisGroup, chassis
 
 	return nil
 }
+
+// loadBalancerAddress returns the address and port in the format expected by a load balancer.
+func (o *OVN) loadBalancerAddress(ip net.IP, port uint64) string {
+	if ip.To4() == nil {
+		return fmt.Sprintf("[%s]:%d", ip.String(), port)
+	}
+
+	return fmt.Sprintf("%s:%d", ip.String(), port)
+}
+
+// LoadBalancerApply creates a new load balancer (or replaces an existing one) and attaches it to the specified
+// routers and switches. As load balancers only handle a single protocol, one load balancer is created for each
+// protocol in use, using the suffix "-tcp" or "-udp" after the load balancer name.
+func (o *OVN) LoadBalancerApply(loadBalancerName OVNLoadBalancer, routers []OVNRouter, switches []OVNSwitch, vips ...OVNLoadBalancerVIP) error {
+	lbTCPName := fmt.Sprintf("%s-tcp", loadBalancerName)
+	lbUDPName := fmt.Sprintf("%s-udp", loadBalancerName)
+
+	// Remove existing load balancers (which also removes them from any routers and switches) and add the new
+	// VIPs in a single transaction.
+	args := []string{"--if-exists", "lb-del", lbTCPName, "--", "--if-exists", "lb-del", lbUDPName}
+
+	lbNames := []string{}
+	for _, vip := range vips {
+		if len(vip.Targets) == 0 {
+			continue
+		}
+
+		lbName := lbTCPName
+		if vip.Protocol == "udp" {
+			lbName = lbUDPName
+		}
+
+		targetAddresses := make([]string, 0, len(vip.Targets))
+		for _, target := range vip.Targets {
+			targetAddresses = append(targetAddresses, o.loadBalancerAddress(target.Address, target.Port))
+		}
+
+		args = append(args, "--", "lb-add", lbName, o.loadBalancerAddress(vip.ListenAddress, vip.ListenPort), strings.Join(targetAddresses, ","), vip.Protocol)
+
+		if !shared.StringInSlice(lbName, lbNames) {
+			lbNames = append(lbNames, lbName)
+		}
+	}
+
+	for _, lbName := range lbNames {
+		for _, router := range routers {
+			args = append(args, "--", "lr-lb-add", string(router), lbName)
+		}
+
+		for _, switchName := range switches {
+			args = append(args, "--", "ls-lb-add", string(switchName), lbName)
+		}
+	}
+
+	_, err := o.nbctl(args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// LoadBalancerDelete deletes the specified load balancers (including the per-protocol ones).
+func (o *OVN) LoadBalancerDelete(loadBalancerNames ...OVNLoadBalancer) error {
+	args := []string{}
+	for _, loadBalancerName := range loadBalancerNames {
+		for _, protocol := range []string{"tcp", "udp"} {
+			if len(args) > 0 {
+				args = append(args, "--")
+			}
+
+			args = append(args, "--if-exists", "lb-del", fmt.Sprintf("%s-%s", loadBalancerName, protocol))
+		}
+	}
+
+	if len(args) == 0 {
+		return nil
+	}
+
+	_, err := o.nbctl(args...)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	lxd "github.com/lxc/lxd/client"
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/network"
+	"github.com/lxc/lxd/lxd/network/acl"
+	"github.com/lxc/lxd/lxd/project"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/version"
+)
+
+var networkACLsCmd = APIEndpoint{
+	Path: "network-acls",
+
+	Get:  APIEndpointAction{Handler: networkACLsGet, AccessHandler: allowAuthenticatedProject},
+	Post: APIEndpointAction{Handler: networkACLsPost},
+}
+
+var networkACLCmd = APIEndpoint{
+	Path: "network-acls/{name}",
+
+	Delete: APIEndpointAction{Handler: networkACLDelete},
+	Get:    APIEndpointAction{Handler: networkACLGet, AccessHandler: allowAuthenticatedProject},
+	Patch:  APIEndpointAction{Handler: networkACLPatch},
+	Post:   APIEndpointAction{Handler: networkACLPost},
+	Put:    APIEndpointAction{Handler: networkACLPut},
+}
+
+// API endpoints.
+
+// List Network ACLs.
+func networkACLsGet(d *Daemon, r *http.Request) response.Response {
+	projectName, err := project.NetworkProject(d.State().Cluster, projectParam(r))
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	recursion := util.IsRecursionRequest(r)
+
+	// Network ACLs live in the same project as the networks they apply to.
+	aclNames, err := d.cluster.GetNetworkACLs(projectName)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	resultString := []string{}
+	resultMap := []api.NetworkACL{}
+	for _, aclName := range aclNames {
+		if !recursion {
+			resultString = append(resultString, fmt.Sprintf("/%s/network-acls/%s", version.APIVersion, aclName))
+		} else {
+			netACL, err := acl.LoadByName(d.State(), projectName, aclName)
+			if err != nil {
+				continue
+			}
+
+			netACLInfo := netACL.Info()
+			netACLInfo.UsedBy, err = netACL.UsedBy()
+			if err != nil {
+				return response.SmartError(err)
+			}
+
+			resultMap = append(resultMap, *netACLInfo)
+		}
+	}
+
+	if !recursion {
+		return response.SyncResponse(true, resultString)
+	}
+
+	return response.SyncResponse(true, resultMap)
+}
+
+// Create Network ACL.
+func networkACLsPost(d *Daemon, r *http.Request) response.Response {
+	projectName, err := project.NetworkProject(d.State().Cluster, projectParam(r))
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	req := api.NetworkACLsPost{}
+
+	// Parse the request into a record.
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	_, err = acl.LoadByName(d.State(), projectName, req.Name)
+	if err == nil {
+		return response.BadRequest(fmt.Errorf("The network ACL already exists"))
+	}
+
+	err = acl.Create(d.State(), projectName, &req)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	url := fmt.Sprintf("/%s/network-acls/%s", version.APIVersion, req.Name)
+	return response.SyncResponseLocation(true, nil, url)
+}
+
+// Delete Network ACL.
+func networkACLDelete(d *Daemon, r *http.Request) response.Response {
+	projectName, err := project.NetworkProject(d.State().Cluster, projectParam(r))
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	netACL, err := acl.LoadByName(d.State(), projectName, mux.Vars(r)["name"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = netACL.Delete()
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// Show Network ACL.
+func networkACLGet(d *Daemon, r *http.Request) response.Response {
+	projectName, err := project.NetworkProject(d.State().Cluster, projectParam(r))
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	netACL, err := acl.LoadByName(d.State(), projectName, mux.Vars(r)["name"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	info := netACL.Info()
+	info.UsedBy, err = netACL.UsedBy()
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponseETag(true, info, netACL.Info())
+}
+
+// Update Network ACL.
+func networkACLPut(d *Daemon, r *http.Request) response.Response {
+	projectName, err := project.NetworkProject(d.State().Cluster, projectParam(r))
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	aclName := mux.Vars(r)["name"]
+	clientType := cluster.UserAgentClientType(r.Header.Get("User-Agent"))
+
+	// Cluster notifications only need to apply the already stored rules locally.
+	if clientType == cluster.ClientTypeNotifier {
+		err := network.ACLUpdated(d.State(), projectName, aclName, clientType)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		return response.EmptySyncResponse
+	}
+
+	// Get the existing Network ACL.
+	netACL, err := acl.LoadByName(d.State(), projectName, aclName)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Validate the ETag.
+	err = util.EtagCheck(r, netACL.Info())
+	if err != nil {
+		return response.PreconditionFailed(err)
+	}
+
+	req := api.NetworkACLPut{}
+
+	// Decode the request.
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if r.Method == http.MethodPatch {
+		// If config being updated via "patch" method, then merge all existing config with the keys that
+		// are present in the request config.
+		for k, v := range netACL.Info().Config {
+			_, ok := req.Config[k]
+			if !ok {
+				if req.Config == nil {
+					req.Config = map[string]string{}
+				}
+
+				req.Config[k] = v
+			}
+		}
+
+		// Rules not present in the request are kept unchanged.
+		if req.Ingress == nil {
+			req.Ingress = netACL.Info().Ingress
+		}
+
+		if req.Egress == nil {
+			req.Egress = netACL.Info().Egress
+		}
+	}
+
+	err = netACL.Update(&req)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Apply the changed rules to the local networks and instances.
+	err = network.ACLUpdated(d.State(), projectName, aclName, clientType)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Notify all other nodes to apply the changed rules.
+	notifier, err := cluster.NewNotifier(d.State(), d.endpoints.NetworkCert(), cluster.NotifyAll)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = notifier(func(client lxd.InstanceServer) error {
+		return client.UseProject(projectName).UpdateNetworkACL(aclName, req, "")
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// Patch Network ACL.
+func networkACLPatch(d *Daemon, r *http.Request) response.Response {
+	return networkACLPut(d, r)
+}
+
+// Rename Network ACL.
+func networkACLPost(d *Daemon, r *http.Request) response.Response {
+	projectName, err := project.NetworkProject(d.State().Cluster, projectParam(r))
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	req := api.NetworkACLPost{}
+
+	// Parse the request.
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	// Get the existing Network ACL.
+	netACL, err := acl.LoadByName(d.State(), projectName, mux.Vars(r)["name"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Check that the name isn't already in use.
+	aclNames, err := d.cluster.GetNetworkACLs(projectName)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if shared.StringInSlice(req.Name, aclNames) {
+		return response.Conflict(fmt.Errorf("Network ACL %q already exists", req.Name))
+	}
+
+	err = netACL.Rename(req.Name)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	url := fmt.Sprintf("/%s/network-acls/%s", version.APIVersion, req.Name)
+	return response.SyncResponseLocation(true, nil, url)
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lxc/lxd/lxd/network"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/version"
+)
+
+var networkForwardsCmd = APIEndpoint{
+	Path: "networks/{networkName}/forwards",
+
+	Get:  APIEndpointAction{Handler: networkForwardsGet, AccessHandler: allowAuthenticatedProject},
+	Post: APIEndpointAction{Handler: networkForwardsPost},
+}
+
+var networkForwardCmd = APIEndpoint{
+	Path: "networks/{networkName}/forwards/{listenAddress}",
+
+	Delete: APIEndpointAction{Handler: networkForwardDelete},
+	Get:    APIEndpointAction{Handler: networkForwardGet, AccessHandler: allowAuthenticatedProject},
+	Patch:  APIEndpointAction{Handler: networkForwardPatch},
+	Put:    APIEndpointAction{Handler: networkForwardPut},
+}
+
+// API endpoints.
+
+// List Network forwards.
+func networkForwardsGet(d *Daemon, r *http.Request) response.Response {
+	recursion := util.IsRecursionRequest(r)
+
+	n, err := networkLoadFromRequest(d, r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	listenAddresses, err := d.cluster.GetNetworkForwardListenAddresses(n.ID())
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	resultString := []string{}
+	resultMap := []api.NetworkForward{}
+	for _, listenAddress := range listenAddresses {
+		if !recursion {
+			resultString = append(resultString, fmt.Sprintf("/%s/networks/%s/forwards/%s", version.APIVersion, n.Name(), listenAddress))
+		} else {
+			_, forward, err := d.cluster.GetNetworkForward(n.ID(), listenAddress)
+			if err != nil {
+				continue
+			}
+
+			resultMap = append(resultMap, *forward)
+		}
+	}
+
+	if !recursion {
+		return response.SyncResponse(true, resultString)
+	}
+
+	return response.SyncResponse(true, resultMap)
+}
+
+// Create Network forward.
+func networkForwardsPost(d *Daemon, r *http.Request) response.Response {
+	n, err := networkLoadFromRequest(d, r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	req := api.NetworkForwardsPost{}
+
+	// Parse the request into a record.
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	err = n.ForwardCreate(req)
+	if err != nil {
+		return networkForwardErrorResponse(n, err)
+	}
+
+	// The listen address is stored in its canonical form.
+	listenIP := net.ParseIP(req.ListenAddress)
+
+	url := fmt.Sprintf("/%s/networks/%s/forwards/%s", version.APIVersion, n.Name(), listenIP.String())
+	return response.SyncResponseLocation(true, nil, url)
+}
+
+// Delete Network forward.
+func networkForwardDelete(d *Daemon, r *http.Request) response.Response {
+	n, err := networkLoadFromRequest(d, r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = n.ForwardDelete(mux.Vars(r)["listenAddress"])
+	if err != nil {
+		return networkForwardErrorResponse(n, err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// Show Network forward.
+func networkForwardGet(d *Daemon, r *http.Request) response.Response {
+	n, err := networkLoadFromRequest(d, r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	_, forward, err := d.cluster.GetNetworkForward(n.ID(), mux.Vars(r)["listenAddress"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponseETag(true, forward, forward.Writable())
+}
+
+// Update Network forward.
+func networkForwardPut(d *Daemon, r *http.Request) response.Response {
+	listenAddress := mux.Vars(r)["listenAddress"]
+
+	n, err := networkLoadFromRequest(d, r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Get the existing Network forward.
+	_, forward, err := d.cluster.GetNetworkForward(n.ID(), listenAddress)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Validate the ETag.
+	err = util.EtagCheck(r, forward.Writable())
+	if err != nil {
+		return response.PreconditionFailed(err)
+	}
+
+	req := api.NetworkForwardPut{}
+
+	// Decode the request.
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if r.Method == http.MethodPatch {
+		// If config being updated via "patch" method, then merge all existing config with the keys that
+		// are present in the request config.
+		for k, v := range forward.Config {
+			_, ok := req.Config[k]
+			if !ok {
+				if req.Config == nil {
+					req.Config = map[string]string{}
+				}
+
+				req.Config[k] = v
+			}
+		}
+
+		// Ports not present in the request are kept unchanged.
+		if req.Ports == nil {
+			req.Ports = forward.Ports
+		}
+	}
+
+	err = n.ForwardUpdate(listenAddress, req)
+	if err != nil {
+		return networkForwardErrorResponse(n, err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// Patch Network forward.
+func networkForwardPatch(d *Daemon, r *http.Request) response.Response {
+	return networkForwardPut(d, r)
+}
+
+// networkForwardErrorResponse returns the response to use for an error returned by a network forward or load
+// balancer action, reporting drivers that don't support them as a bad request.
+func networkForwardErrorResponse(n network.Network, err error) response.Response {
+	if err == network.ErrNotImplemented {
+		return response.BadRequest(fmt.Errorf("Network type %q does not support forwards or load balancers", n.Type()))
+	}
+
+	return response.SmartError(err)
+}
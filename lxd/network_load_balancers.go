@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/version"
+)
+
+var networkLoadBalancersCmd = APIEndpoint{
+	Path: "networks/{networkName}/load-balancers",
+
+	Get:  APIEndpointAction{Handler: networkLoadBalancersGet, AccessHandler: allowAuthenticatedProject},
+	Post: APIEndpointAction{Handler: networkLoadBalancersPost},
+}
+
+var networkLoadBalancerCmd = APIEndpoint{
+	Path: "networks/{networkName}/load-balancers/{listenAddress}",
+
+	Delete: APIEndpointAction{Handler: networkLoadBalancerDelete},
+	Get:    APIEndpointAction{Handler: networkLoadBalancerGet, AccessHandler: allowAuthenticatedProject},
+	Patch:  APIEndpointAction{Handler: networkLoadBalancerPatch},
+	Put:    APIEndpointAction{Handler: networkLoadBalancerPut},
+}
+
+// API endpoints.
+
+// List Network load balancers.
+func networkLoadBalancersGet(d *Daemon, r *http.Request) response.Response {
+	recursion := util.IsRecursionRequest(r)
+
+	n, err := networkLoadFromRequest(d, r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	listenAddresses, err := d.cluster.GetNetworkLoadBalancerListenAddresses(n.ID())
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	resultString := []string{}
+	resultMap := []api.NetworkLoadBalancer{}
+	for _, listenAddress := range listenAddresses {
+		if !recursion {
+			resultString = append(resultString, fmt.Sprintf("/%s/networks/%s/load-balancers/%s", version.APIVersion, n.Name(), listenAddress))
+		} else {
+			_, loadBalancer, err := d.cluster.GetNetworkLoadBalancer(n.ID(), listenAddress)
+			if err != nil {
+				continue
+			}
+
+			resultMap = append(resultMap, *loadBalancer)
+		}
+	}
+
+	if !recursion {
+		return response.SyncResponse(true, resultString)
+	}
+
+	return response.SyncResponse(true, resultMap)
+}
+
+// Create Network load balancer.
+func networkLoadBalancersPost(d *Daemon, r *http.Request) response.Response {
+	n, err := networkLoadFromRequest(d, r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	req := api.NetworkLoadBalancersPost{}
+
+	// Parse the request into a record.
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	err = n.LoadBalancerCreate(req)
+	if err != nil {
+		return networkForwardErrorResponse(n, err)
+	}
+
+	// The listen address is stored in its canonical form.
+	listenIP := net.ParseIP(req.ListenAddress)
+
+	url := fmt.Sprintf("/%s/networks/%s/load-balancers/%s", version.APIVersion, n.Name(), listenIP.String())
+	return response.SyncResponseLocation(true, nil, url)
+}
+
+// Delete Network load balancer.
+func networkLoadBalancerDelete(d *Daemon, r *http.Request) response.Response {
+	n, err := networkLoadFromRequest(d, r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = n.LoadBalancerDelete(mux.Vars(r)["listenAddress"])
+	if err != nil {
+		return networkForwardErrorResponse(n, err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// Show Network load balancer.
+func networkLoadBalancerGet(d *Daemon, r *http.Request) response.Response {
+	n, err := networkLoadFromRequest(d, r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	_, loadBalancer, err := d.cluster.GetNetworkLoadBalancer(n.ID(), mux.Vars(r)["listenAddress"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponseETag(true, loadBalancer, loadBalancer.Writable())
+}
+
+// Update Network load balancer.
+func networkLoadBalancerPut(d *Daemon, r *http.Request) response.Response {
+	listenAddress := mux.Vars(r)["listenAddress"]
+
+	n, err := networkLoadFromRequest(d, r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Get the existing Network load balancer.
+	_, loadBalancer, err := d.cluster.GetNetworkLoadBalancer(n.ID(), listenAddress)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Validate the ETag.
+	err = util.EtagCheck(r, loadBalancer.Writable())
+	if err != nil {
+		return response.PreconditionFailed(err)
+	}
+
+	req := api.NetworkLoadBalancerPut{}
+
+	// Decode the request.
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if r.Method == http.MethodPatch {
+		// If config being updated via "patch" method, then merge all existing config with the keys that
+		// are present in the request config.
+		for k, v := range loadBalancer.Config {
+			_, ok := req.Config[k]
+			if !ok {
+				if req.Config == nil {
+					req.Config = map[string]string{}
+				}
+
+				req.Config[k] = v
+			}
+		}
+
+		// Backends and ports not present in the request are kept unchanged.
+		if req.Backends == nil {
+			req.Backends = loadBalancer.Backends
+		}
+
+		if req.Ports == nil {
+			req.Ports = loadBalancer.Ports
+		}
+	}
+
+	err = n.LoadBalancerUpdate(listenAddress, req)
+	if err != nil {
+		return networkForwardErrorResponse(n, err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// Patch Network load balancer.
+func networkLoadBalancerPatch(d *Daemon, r *http.Request) response.Response {
+	return networkLoadBalancerPut(d, r)
+}
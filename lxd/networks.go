@@ -38,7 +38,7 @@ var networkCreateLock sync.Mutex
 var networksCmd = APIEndpoint{
 	Path: "networks",
 
-	Get:  APIEndpointAction{Handler: networksGet, AccessHandler: allowAuthenticated},
+	Get:  APIEndpointAction{Handler: networksGet, AccessHandler: allowAuthenticatedProject},
 	Post: APIEndpointAction{Handler: networksPost},
 }
 
@@ -46,7 +46,7 @@ var networkCmd = APIEndpoint{
 	Path: "networks/{name}",
 
 	Delete: APIEndpointAction{Handler: networkDelete},
-	Get:    APIEndpointAction{Handler: networkGet, AccessHandler: allowAuthenticated},
+	Get:    APIEndpointAction{Handler: networkGet, AccessHandler: allowAuthenticatedProject},
 	Patch:  APIEndpointAction{Handler: networkPatch},
 	Post:   APIEndpointAction{Handler: networkPost},
 	Put:    APIEndpointAction{Handler: networkPut},
@@ -55,20 +55,25 @@ var networkCmd = APIEndpoint{
 var networkLeasesCmd = APIEndpoint{
 	Path: "networks/{name}/leases",
 
-	Get: APIEndpointAction{Handler: networkLeasesGet, AccessHandler: allowAuthenticated},
+	Get: APIEndpointAction{Handler: networkLeasesGet, AccessHandler: allowAuthenticatedProject},
 }
 
 var networkStateCmd = APIEndpoint{
 	Path: "networks/{name}/state",
 
-	Get: APIEndpointAction{Handler: networkStateGet, AccessHandler: allowAuthenticated},
+	Get: APIEndpointAction{Handler: networkStateGet, AccessHandler: allowAuthenticatedProject},
 }
 
 // API endpoints
 func networksGet(d *Daemon, r *http.Request) response.Response {
+	projectName, err := project.NetworkProject(d.State().Cluster, projectParam(r))
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	recursion := util.IsRecursionRequest(r)
 
-	ifs, err := networkGetInterfaces(d.cluster)
+	ifs, err := networkGetNames(d, projectName)
 	if err != nil {
 		return response.InternalError(err)
 	}
@@ -79,7 +84,7 @@ func networksGet(d *Daemon, r *http.Request) response.Response {
 		if !recursion {
 			resultString = append(resultString, fmt.Sprintf("/%s/networks/%s", version.APIVersion, iface))
 		} else {
-			net, err := doNetworkGet(d, iface)
+			net, err := doNetworkGet(d, projectName, iface)
 			if err != nil {
 				continue
 			}
@@ -111,8 +116,23 @@ func networksPost(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(fmt.Errorf("No name provided"))
 	}
 
+	projectName, err := project.NetworkProject(d.State().Cluster, projectParam(r))
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	if req.Type == "" {
-		req.Type = "bridge"
+		if projectName != project.Default {
+			req.Type = "ovn"
+		} else {
+			req.Type = "bridge"
+		}
+	}
+
+	// Only OVN networks may be created in projects other than the default one, as the other network
+	// types use host interfaces directly.
+	if projectName != project.Default && req.Type != "ovn" {
+		return response.BadRequest(fmt.Errorf("Network type %q is not supported in non-default projects", req.Type))
 	}
 
 	if req.Config == nil {
@@ -147,15 +167,27 @@ func networksPost(d *Daemon, r *http.Request) response.Response {
 	if isClusterNotification(r) {
 		// This is an internal request which triggers the actual creation of the network across all nodes
 		// after they have been previously defined.
-		err = doNetworksCreate(d, req, clientType)
+		err = doNetworksCreate(d, projectName, req, clientType)
 		if err != nil {
 			return response.SmartError(err)
 		}
 		return resp
 	}
 
+	// Check the uplink network is allowed by the project's restrictions.
+	if projectName != project.Default {
+		err = networkCheckUplinkAllowed(d, projectName, req.Config)
+		if err != nil {
+			return response.BadRequest(err)
+		}
+	}
+
 	targetNode := queryParam(r, "target")
 	if targetNode != "" {
+		if projectName != project.Default {
+			return response.BadRequest(fmt.Errorf("Node-specific network config is not supported in non-default projects"))
+		}
+
 		// A targetNode was specified, let's just define the node's network without actually creating it.
 		// Check that only NodeSpecificNetworkConfig keys are specified.
 		for key := range req.Config {
@@ -165,7 +197,7 @@ func networksPost(d *Daemon, r *http.Request) response.Response {
 		}
 
 		err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
-			return tx.CreatePendingNetwork(targetNode, project.Default, req.Name, dbNetType, req.Config)
+			return tx.CreatePendingNetwork(targetNode, projectName, req.Name, dbNetType, req.Config)
 		})
 		if err != nil {
 			if err == db.ErrAlreadyDefined {
@@ -183,7 +215,30 @@ func networksPost(d *Daemon, r *http.Request) response.Response {
 	}
 
 	if count > 1 {
-		err = networksPostCluster(d, req, clientType)
+		// Networks in non-default projects have no node-specific config, so define them as pending on
+		// all nodes rather than requiring a per-node creation step.
+		if projectName != project.Default {
+			err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+				nodes, err := tx.GetNodes()
+				if err != nil {
+					return err
+				}
+
+				for _, node := range nodes {
+					err = tx.CreatePendingNetwork(node.Name, projectName, req.Name, dbNetType, nil)
+					if err != nil && err != db.ErrAlreadyDefined {
+						return err
+					}
+				}
+
+				return nil
+			})
+			if err != nil {
+				return response.SmartError(err)
+			}
+		}
+
+		err = networksPostCluster(d, projectName, req, clientType)
 		if err != nil {
 			return response.SmartError(err)
 		}
@@ -197,7 +252,7 @@ func networksPost(d *Daemon, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
-	networks, err := networkGetInterfaces(d.cluster)
+	networks, err := networkGetNames(d, projectName)
 	if err != nil {
 		return response.InternalError(err)
 	}
@@ -210,16 +265,16 @@ func networksPost(d *Daemon, r *http.Request) response.Response {
 	defer revert.Fail()
 
 	// Create the database entry.
-	_, err = d.cluster.CreateNetwork(project.Default, req.Name, req.Description, dbNetType, req.Config)
+	_, err = d.cluster.CreateNetwork(projectName, req.Name, req.Description, dbNetType, req.Config)
 	if err != nil {
 		return response.SmartError(errors.Wrapf(err, "Error inserting %q into database", req.Name))
 	}
 
 	revert.Add(func() {
-		d.cluster.DeleteNetwork(req.Name)
+		d.cluster.DeleteNetwork(projectName, req.Name)
 	})
 
-	err = doNetworksCreate(d, req, clientType)
+	err = doNetworksCreate(d, projectName, req, clientType)
 	if err != nil {
 		return response.SmartError(err)
 	}
@@ -228,7 +283,7 @@ func networksPost(d *Daemon, r *http.Request) response.Response {
 	return resp
 }
 
-func networksPostCluster(d *Daemon, req api.NetworksPost, clientType cluster.ClientType) error {
+func networksPostCluster(d *Daemon, projectName string, req api.NetworksPost, clientType cluster.ClientType) error {
 	// Check that no node-specific config key has been defined.
 	for key := range req.Config {
 		if shared.StringInSlice(key, db.NodeSpecificNetworkConfig) {
@@ -238,7 +293,7 @@ func networksPostCluster(d *Daemon, req api.NetworksPost, clientType cluster.Cli
 
 	// Check that the requested network type matches the type created when adding the local node config.
 	// If network doesn't exist yet, ignore not found error, as this will be checked by NetworkNodeConfigs().
-	_, netInfo, err := d.cluster.GetNetworkInAnyState(req.Name)
+	_, netInfo, err := d.cluster.GetNetworkInAnyState(projectName, req.Name)
 	if err != nil && err != db.ErrNoSuchObject {
 		return err
 	}
@@ -259,7 +314,7 @@ func networksPostCluster(d *Daemon, req api.NetworksPost, clientType cluster.Cli
 	var networkID int64
 	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
 		// Fetch the network ID.
-		networkID, err = tx.GetNetworkID(req.Name)
+		networkID, err = tx.GetNetworkID(projectName, req.Name)
 		if err != nil {
 			return err
 		}
@@ -304,20 +359,20 @@ func networksPostCluster(d *Daemon, req api.NetworksPost, clientType cluster.Cli
 
 	revert.Add(func() {
 		d.cluster.Transaction(func(tx *db.ClusterTx) error {
-			return tx.NetworkErrored(req.Name)
+			return tx.NetworkErrored(projectName, req.Name)
 		})
 	})
 
 	// We need to mark the network as created now, because the network.LoadByName call invoked by
 	// doNetworksCreate would fail with not-found otherwise.
 	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
-		return tx.NetworkCreated(req.Name)
+		return tx.NetworkCreated(projectName, req.Name)
 	})
 	if err != nil {
 		return err
 	}
 
-	err = doNetworksCreate(d, nodeReq, clientType)
+	err = doNetworksCreate(d, projectName, nodeReq, clientType)
 	if err != nil {
 		return err
 	}
@@ -333,7 +388,7 @@ func networksPostCluster(d *Daemon, req api.NetworksPost, clientType cluster.Cli
 			nodeReq.Config[key] = value
 		}
 
-		return client.CreateNetwork(nodeReq)
+		return client.UseProject(projectName).CreateNetwork(nodeReq)
 	})
 	if err != nil {
 		return err
@@ -345,9 +400,9 @@ func networksPostCluster(d *Daemon, req api.NetworksPost, clientType cluster.Cli
 
 // Create the network on the system. The clusterNotification flag is used to indicate whether creation request
 // is coming from a cluster notification (and if so we should not delete the database record on error).
-func doNetworksCreate(d *Daemon, req api.NetworksPost, clientType cluster.ClientType) error {
+func doNetworksCreate(d *Daemon, projectName string, req api.NetworksPost, clientType cluster.ClientType) error {
 	// Start the network.
-	n, err := network.LoadByName(d.State(), req.Name)
+	n, err := network.LoadByName(d.State(), projectName, req.Name)
 	if err != nil {
 		return err
 	}
@@ -380,9 +435,14 @@ func networkGet(d *Daemon, r *http.Request) response.Response {
 		return resp
 	}
 
+	projectName, err := project.NetworkProject(d.State().Cluster, projectParam(r))
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	name := mux.Vars(r)["name"]
 
-	n, err := doNetworkGet(d, name)
+	n, err := doNetworkGet(d, projectName, name)
 	if err != nil {
 		return response.SmartError(err)
 	}
@@ -406,15 +466,19 @@ func networkGet(d *Daemon, r *http.Request) response.Response {
 	return response.SyncResponseETag(true, &n, etag)
 }
 
-func doNetworkGet(d *Daemon, name string) (api.Network, error) {
+func doNetworkGet(d *Daemon, projectName string, name string) (api.Network, error) {
 	// Ignore veth pairs (for performance reasons)
 	if strings.HasPrefix(name, "veth") {
 		return api.Network{}, os.ErrNotExist
 	}
 
-	// Get some information
-	osInfo, _ := net.InterfaceByName(name)
-	_, dbInfo, _ := d.cluster.GetNetworkInAnyState(name)
+	// Get some information. Host interfaces are only visible from the default project.
+	var osInfo *net.Interface
+	if projectName == project.Default {
+		osInfo, _ = net.InterfaceByName(name)
+	}
+
+	_, dbInfo, _ := d.cluster.GetNetworkInAnyState(projectName, name)
 
 	// Sanity check
 	if osInfo == nil && dbInfo == nil {
@@ -461,7 +525,7 @@ func doNetworkGet(d *Daemon, name string) (api.Network, error) {
 		}
 
 		for _, inst := range insts {
-			inUse, err := network.IsInUseByInstance(d.State(), inst, n.Name)
+			inUse, err := network.IsInUseByInstance(d.State(), inst, projectName, n.Name)
 			if err != nil {
 				return api.Network{}, err
 			}
@@ -490,7 +554,7 @@ func doNetworkGet(d *Daemon, name string) (api.Network, error) {
 		}
 
 		for _, profile := range profiles {
-			inUse, err := network.IsInUseByProfile(d.State(), *db.ProfileToAPI(&profile), n.Name)
+			inUse, err := network.IsInUseByProfile(d.State(), profile.Project, *db.ProfileToAPI(&profile), projectName, n.Name)
 			if err != nil {
 				return api.Network{}, err
 			}
@@ -514,16 +578,21 @@ func doNetworkGet(d *Daemon, name string) (api.Network, error) {
 }
 
 func networkDelete(d *Daemon, r *http.Request) response.Response {
+	projectName, err := project.NetworkProject(d.State().Cluster, projectParam(r))
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	name := mux.Vars(r)["name"]
 	state := d.State()
 
 	// Check if the network is pending, if so we just need to delete it from the database.
-	_, dbNetwork, err := d.cluster.GetNetworkInAnyState(name)
+	_, dbNetwork, err := d.cluster.GetNetworkInAnyState(projectName, name)
 	if err != nil {
 		return response.SmartError(err)
 	}
 	if dbNetwork.Status == api.NetworkStatusPending {
-		err := d.cluster.DeleteNetwork(name)
+		err := d.cluster.DeleteNetwork(projectName, name)
 		if err != nil {
 			return response.SmartError(err)
 		}
@@ -531,7 +600,7 @@ func networkDelete(d *Daemon, r *http.Request) response.Response {
 	}
 
 	// Get the existing network.
-	n, err := network.LoadByName(state, name)
+	n, err := network.LoadByName(state, projectName, name)
 	if err != nil {
 		return response.NotFound(err)
 	}
@@ -576,6 +645,11 @@ func networkPost(d *Daemon, r *http.Request) response.Response {
 		return response.BadRequest(fmt.Errorf("Renaming a network not supported in LXD clusters"))
 	}
 
+	projectName, err := project.NetworkProject(d.State().Cluster, projectParam(r))
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	name := mux.Vars(r)["name"]
 	req := api.NetworkPost{}
 	state := d.State()
@@ -587,7 +661,7 @@ func networkPost(d *Daemon, r *http.Request) response.Response {
 	}
 
 	// Get the existing network
-	n, err := network.LoadByName(state, name)
+	n, err := network.LoadByName(state, projectName, name)
 	if err != nil {
 		return response.NotFound(err)
 	}
@@ -603,7 +677,7 @@ func networkPost(d *Daemon, r *http.Request) response.Response {
 	}
 
 	// Check that the name isn't already in use
-	networks, err := networkGetInterfaces(d.cluster)
+	networks, err := networkGetNames(d, projectName)
 	if err != nil {
 		return response.InternalError(err)
 	}
@@ -628,10 +702,15 @@ func networkPut(d *Daemon, r *http.Request) response.Response {
 		return resp
 	}
 
+	projectName, err := project.NetworkProject(d.State().Cluster, projectParam(r))
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	name := mux.Vars(r)["name"]
 
 	// Get the existing network.
-	_, dbInfo, err := d.cluster.GetNetworkInAnyState(name)
+	_, dbInfo, err := d.cluster.GetNetworkInAnyState(projectName, name)
 	if err != nil {
 		return response.SmartError(err)
 	}
@@ -686,7 +765,7 @@ func networkPut(d *Daemon, r *http.Request) response.Response {
 
 	clientType := cluster.UserAgentClientType(r.Header.Get("User-Agent"))
 
-	return doNetworkUpdate(d, name, req, targetNode, clientType, r.Method, clustered)
+	return doNetworkUpdate(d, projectName, name, req, targetNode, clientType, r.Method, clustered)
 }
 
 func networkPatch(d *Daemon, r *http.Request) response.Response {
@@ -695,9 +774,9 @@ func networkPatch(d *Daemon, r *http.Request) response.Response {
 
 // doNetworkUpdate loads the current local network config, merges with the requested network config, validates
 // and applies the changes. Will also notify other cluster nodes of non-node specific config if needed.
-func doNetworkUpdate(d *Daemon, name string, req api.NetworkPut, targetNode string, clientType cluster.ClientType, httpMethod string, clustered bool) response.Response {
+func doNetworkUpdate(d *Daemon, projectName string, name string, req api.NetworkPut, targetNode string, clientType cluster.ClientType, httpMethod string, clustered bool) response.Response {
 	// Load the local node-specific network.
-	n, err := network.LoadByName(d.State(), name)
+	n, err := network.LoadByName(d.State(), projectName, name)
 	if err != nil {
 		return response.NotFound(err)
 	}
@@ -729,11 +808,19 @@ func doNetworkUpdate(d *Daemon, name string, req api.NetworkPut, targetNode stri
 	}
 
 	// Validate the merged configuration.
-	err = network.Validate(name, n.Type(), req.Config)
+	err = n.Validate(req.Config)
 	if err != nil {
 		return response.BadRequest(err)
 	}
 
+	// Check the uplink network is allowed by the project's restrictions.
+	if projectName != project.Default {
+		err = networkCheckUplinkAllowed(d, projectName, req.Config)
+		if err != nil {
+			return response.BadRequest(err)
+		}
+	}
+
 	// Apply the new configuration (will also notify other cluster nodes if needed).
 	err = n.Update(req, targetNode, clientType)
 	if err != nil {
@@ -745,10 +832,15 @@ func doNetworkUpdate(d *Daemon, name string, req api.NetworkPut, targetNode stri
 
 func networkLeasesGet(d *Daemon, r *http.Request) response.Response {
 	name := mux.Vars(r)["name"]
-	project := projectParam(r)
+	projectName := projectParam(r)
+
+	networkProjectName, err := project.NetworkProject(d.State().Cluster, projectName)
+	if err != nil {
+		return response.SmartError(err)
+	}
 
 	// Try to get the network
-	n, err := doNetworkGet(d, name)
+	n, err := doNetworkGet(d, networkProjectName, name)
 	if err != nil {
 		return response.SmartError(err)
 	}
@@ -764,7 +856,7 @@ func networkLeasesGet(d *Daemon, r *http.Request) response.Response {
 	// Get all static leases
 	if !isClusterNotification(r) {
 		// Get all the instances
-		instances, err := instance.LoadByProject(d.State(), project)
+		instances, err := instance.LoadByProject(d.State(), projectName)
 		if err != nil {
 			return response.SmartError(err)
 		}
@@ -777,7 +869,7 @@ func networkLeasesGet(d *Daemon, r *http.Request) response.Response {
 					continue
 				}
 
-				nicType, err := nictype.NICType(d.State(), dev)
+				nicType, err := nictype.NICType(d.State(), inst.Project(), dev)
 				if err != nil || nicType != "bridged" {
 					continue
 				}
@@ -919,30 +1011,40 @@ func networkLeasesGet(d *Daemon, r *http.Request) response.Response {
 
 func networkStartup(s *state.State) error {
 	// Get a list of managed networks.
-	networks, err := s.Cluster.GetNonPendingNetworks()
+	projectNetworks, err := s.Cluster.GetNonPendingNetworksAllProjects()
 	if err != nil {
 		return errors.Wrapf(err, "Failed to load networks")
 	}
 
-	// Bring them all up.
-	for _, name := range networks {
-		n, err := network.LoadByName(s, name)
-		if err != nil {
-			return errors.Wrapf(err, "Failed to load network %q", name)
+	// Start the default project first, as it contains the uplink networks used by other projects.
+	projectNames := []string{project.Default}
+	for projectName := range projectNetworks {
+		if projectName != project.Default {
+			projectNames = append(projectNames, projectName)
 		}
+	}
 
-		err = n.Validate(n.Config())
-		if err != nil {
-			// Don't cause LXD to fail to start entirely on network start up failure.
-			logger.Error("Failed to validate network", log.Ctx{"err": err, "name": name})
-			continue
-		}
+	// Bring them all up.
+	for _, projectName := range projectNames {
+		for _, name := range projectNetworks[projectName] {
+			n, err := network.LoadByName(s, projectName, name)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to load network %q in project %q", name, projectName)
+			}
 
-		err = n.Start()
-		if err != nil {
-			// Don't cause LXD to fail to start entirely on network start up failure.
-			logger.Error("Failed to bring up network", log.Ctx{"err": err, "name": name})
-			continue
+			err = n.Validate(n.Config())
+			if err != nil {
+				// Don't cause LXD to fail to start entirely on network start up failure.
+				logger.Error("Failed to validate network", log.Ctx{"err": err, "project": projectName, "name": name})
+				continue
+			}
+
+			err = n.Start()
+			if err != nil {
+				// Don't cause LXD to fail to start entirely on network start up failure.
+				logger.Error("Failed to bring up network", log.Ctx{"err": err, "project": projectName, "name": name})
+				continue
+			}
 		}
 	}
 
@@ -951,21 +1053,23 @@ func networkStartup(s *state.State) error {
 
 func networkShutdown(s *state.State) error {
 	// Get a list of managed networks
-	networks, err := s.Cluster.GetNetworks()
+	projectNetworks, err := s.Cluster.GetNetworksAllProjects()
 	if err != nil {
 		return err
 	}
 
-	// Bring them all up
-	for _, name := range networks {
-		n, err := network.LoadByName(s, name)
-		if err != nil {
-			return err
-		}
+	// Bring them all down
+	for projectName, networks := range projectNetworks {
+		for _, name := range networks {
+			n, err := network.LoadByName(s, projectName, name)
+			if err != nil {
+				return err
+			}
 
-		err = n.Stop()
-		if err != nil {
-			logger.Error("Failed to bring down network", log.Ctx{"err": err, "name": name})
+			err = n.Stop()
+			if err != nil {
+				logger.Error("Failed to bring down network", log.Ctx{"err": err, "project": projectName, "name": name})
+			}
 		}
 	}
 
@@ -979,6 +1083,16 @@ func networkStateGet(d *Daemon, r *http.Request) response.Response {
 		return resp
 	}
 
+	projectName, err := project.NetworkProject(d.State().Cluster, projectParam(r))
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Networks in non-default projects have no host interface whose state could be reported.
+	if projectName != project.Default {
+		return response.NotFound(fmt.Errorf("Network state is not available in project %q", projectName))
+	}
+
 	name := mux.Vars(r)["name"]
 
 	// Get some information
@@ -991,3 +1105,40 @@ func networkStateGet(d *Daemon, r *http.Request) response.Response {
 
 	return response.SyncResponse(true, networkGetState(*osInfo))
 }
+
+// networkGetNames returns the names of the networks in use in the specified project. For the default project
+// this includes unmanaged host interfaces.
+func networkGetNames(d *Daemon, projectName string) ([]string, error) {
+	if projectName == project.Default {
+		return networkGetInterfaces(d.cluster)
+	}
+
+	return d.cluster.GetNetworks(projectName)
+}
+
+// networkCheckUplinkAllowed checks that the uplink network referenced by the config is allowed by the
+// restrictions of the specified project.
+func networkCheckUplinkAllowed(d *Daemon, projectName string, config map[string]string) error {
+	var p *api.Project
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+		p, err = tx.GetProject(projectName)
+		return err
+	})
+	if err != nil {
+		return errors.Wrapf(err, "Failed loading project %q", projectName)
+	}
+
+	return project.NetworkUplinkAllowed(p.Config, config["network"])
+}
+
+// networkLoadFromRequest loads the network named by the networkName request path variable from the effective
+// network project of the request's project.
+func networkLoadFromRequest(d *Daemon, r *http.Request) (network.Network, error) {
+	projectName, err := project.NetworkProject(d.State().Cluster, projectParam(r))
+	if err != nil {
+		return nil, err
+	}
+
+	return network.LoadByName(d.State(), projectName, mux.Vars(r)["networkName"])
+}
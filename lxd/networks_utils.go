@@ -11,6 +11,7 @@ import (
 	"github.com/lxc/lxd/lxd/cluster"
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/network"
+	"github.com/lxc/lxd/lxd/project"
 	"github.com/lxc/lxd/lxd/state"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
@@ -42,7 +43,7 @@ func networkAutoAttach(cluster *db.Cluster, devName string) error {
 }
 
 func networkGetInterfaces(cluster *db.Cluster) ([]string, error) {
-	networks, err := cluster.GetNetworks()
+	networks, err := cluster.GetNetworks(project.Default)
 	if err != nil {
 		return nil, err
 	}
@@ -69,14 +70,14 @@ func networkGetInterfaces(cluster *db.Cluster) ([]string, error) {
 
 // networkUpdateForkdnsServersTask runs every 30s and refreshes the forkdns servers list.
 func networkUpdateForkdnsServersTask(s *state.State, heartbeatData *cluster.APIHeartbeat) error {
-	// Get a list of managed networks
-	networks, err := s.Cluster.GetNonPendingNetworks()
+	// Get a list of managed networks. Fan bridges only exist in the default project.
+	networks, err := s.Cluster.GetNonPendingNetworks(project.Default)
 	if err != nil {
 		return err
 	}
 
 	for _, name := range networks {
-		n, err := network.LoadByName(s, name)
+		n, err := network.LoadByName(s, project.Default, name)
 		if err != nil {
 			logger.Errorf("Failed to load network %q for heartbeat", name)
 			continue
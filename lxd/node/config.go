@@ -51,6 +51,11 @@ func (c *Config) DebugAddress() string {
 	return c.m.GetString("core.debug_address")
 }
 
+// StorageBucketsAddress returns the address and port to setup the storage buckets listener on
+func (c *Config) StorageBucketsAddress() string {
+	return c.m.GetString("core.storage_buckets_address")
+}
+
 // MAASMachine returns the MAAS machine this instance is associated with, if
 // any.
 func (c *Config) MAASMachine() string {
@@ -136,6 +141,22 @@ func DebugAddress(node *db.Node) (string, error) {
 	return config.DebugAddress(), nil
 }
 
+// StorageBucketsAddress is a convenience for loading the node configuration and
+// returning the value of core.storage_buckets_address.
+func StorageBucketsAddress(node *db.Node) (string, error) {
+	var config *Config
+	err := node.Transaction(func(tx *db.NodeTx) error {
+		var err error
+		config, err = ConfigLoad(tx)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return config.StorageBucketsAddress(), nil
+}
+
 func (c *Config) update(values map[string]interface{}) (map[string]string, error) {
 	changed, err := c.m.Change(values)
 	if err != nil {
@@ -161,6 +182,9 @@ var ConfigSchema = config.Schema{
 	// Network address for the debug server
 	"core.debug_address": {},
 
+	// Network address for the storage buckets server
+	"core.storage_buckets_address": {},
+
 	// MAAS machine this LXD instance is associated with
 	"maas.machine": {},
 
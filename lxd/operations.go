@@ -1,6 +1,7 @@
 package main
 
 import (
+	"crypto/subtle"
 	"fmt"
 	"net/http"
 	"strings"
@@ -19,6 +20,7 @@ import (
 	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
 	"github.com/lxc/lxd/shared/logger"
 )
 
@@ -32,7 +34,7 @@ var operationCmd = APIEndpoint{
 var operationsCmd = APIEndpoint{
 	Path: "operations",
 
-	Get: APIEndpointAction{Handler: operationsGet, AccessHandler: allowAuthenticated},
+	Get: APIEndpointAction{Handler: operationsGet, AccessHandler: allowAuthenticatedProject},
 }
 
 var operationWait = APIEndpoint{
@@ -120,7 +122,18 @@ func operationGet(d *Daemon, r *http.Request) response.Response {
 	// First check if the query is for a local operation from this node
 	op, err := operations.OperationGetInternal(id)
 	if err == nil {
-		_, body, err = op.Render()
+		if !operationAllowed(d, r, op) {
+			return response.Forbidden(nil)
+		}
+
+		// Other cluster members need the secret of token operations to check tokens. Requests they forward on
+		// behalf of clients aren't notifications.
+		if r.Context().Value("protocol") == "cluster" && isClusterNotification(r) {
+			_, body, err = op.RenderWithSecret()
+		} else {
+			_, body, err = op.Render()
+		}
+
 		if err != nil {
 			return response.SmartError(err)
 		}
@@ -158,6 +171,10 @@ func operationDelete(d *Daemon, r *http.Request) response.Response {
 	// First check if the query is for a local operation from this node
 	op, err := operations.OperationGetInternal(id)
 	if err == nil {
+		if !operationAllowed(d, r, op) {
+			return response.Forbidden(nil)
+		}
+
 		if op.Permission() != "" {
 			projectName := op.Project()
 			if projectName == "" {
@@ -201,9 +218,31 @@ func operationDelete(d *Daemon, r *http.Request) response.Response {
 	return response.ForwardedResponse(client, r)
 }
 
+// operationAllowed returns whether the operation is visible to the client. Restricted client certificates only
+// see the operations of their projects.
+func operationAllowed(d *Daemon, r *http.Request, op *operations.Operation) bool {
+	// Join and certificate add tokens grant access to the server, so only administrators can see them.
+	if op.Type() == db.OperationClusterJoinToken || op.Type() == db.OperationCertificateAddToken {
+		return d.userIsAdmin(r)
+	}
+
+	_, restricted := d.userRestrictedProjects(r)
+	if !restricted {
+		return true
+	}
+
+	projectName := op.Project()
+	if projectName == "" {
+		projectName = project.Default
+	}
+
+	return d.userHasPermission(r, projectName, "view")
+}
+
 func operationsGet(d *Daemon, r *http.Request) response.Response {
 	project := projectParam(r)
 	recursion := util.IsRecursionRequest(r)
+	_, restricted := d.userRestrictedProjects(r)
 
 	localOperationURLs := func() (shared.Jmap, error) {
 		// Get all the operations
@@ -216,6 +255,15 @@ func operationsGet(d *Daemon, r *http.Request) response.Response {
 			if v.Project() != "" && v.Project() != project {
 				continue
 			}
+
+			// Operations not tied to a project are hidden from restricted clients.
+			if v.Project() == "" && restricted {
+				continue
+			}
+
+			if !operationAllowed(d, r, v) {
+				continue
+			}
 			status := strings.ToLower(v.Status().String())
 			_, ok := body[status]
 			if !ok {
@@ -239,6 +287,15 @@ func operationsGet(d *Daemon, r *http.Request) response.Response {
 			if v.Project() != "" && v.Project() != project {
 				continue
 			}
+
+			// Operations not tied to a project are hidden from restricted clients.
+			if v.Project() == "" && restricted {
+				continue
+			}
+
+			if !operationAllowed(d, r, v) {
+				continue
+			}
 			status := strings.ToLower(v.Status().String())
 			_, ok := body[status]
 			if !ok {
@@ -340,13 +397,18 @@ func operationsGet(d *Daemon, r *http.Request) response.Response {
 		}
 
 		// Get operation data
-		ops, err := client.GetOperations()
+		ops, err := client.UseProject(project).GetOperations()
 		if err != nil {
 			return response.SmartError(err)
 		}
 
 		// Merge with existing data
 		for _, op := range ops {
+			// Join and certificate add tokens are only visible to administrators.
+			if op.Class == operations.OperationClassToken.String() && !d.userIsAdmin(r) && shared.StringInSlice(op.Description, []string{db.OperationClusterJoinToken.Description(), db.OperationCertificateAddToken.Description()}) {
+				continue
+			}
+
 			status := strings.ToLower(op.Status)
 
 			_, ok := md[status]
@@ -386,7 +448,11 @@ func operationWaitGet(d *Daemon, r *http.Request) response.Response {
 	// First check if the query is for a local operation from this node
 	op, err := operations.OperationGetInternal(id)
 	if err == nil {
-		if secret != "" && op.Metadata()["secret"] != secret {
+		if secret != "" {
+			if op.Metadata()["secret"] != secret {
+				return response.Forbidden(nil)
+			}
+		} else if !operationAllowed(d, r, op) {
 			return response.Forbidden(nil)
 		}
 
@@ -512,3 +578,156 @@ func operationWebsocketGet(d *Daemon, r *http.Request) response.Response {
 
 	return &forwardedOperationWebSocket{r, id, source}
 }
+
+// operationsGetByType returns the operations of the given type across the cluster. The secret of token operations
+// is included, so they must not be returned to clients.
+func operationsGetByType(d *Daemon, opType db.OperationType) ([]*api.Operation, error) {
+	var dbOps []db.Operation
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		var err error
+
+		dbOps, err = tx.GetOperationsOfType(opType)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cert := d.endpoints.NetworkCert()
+	ops := []*api.Operation{}
+	for _, dbOp := range dbOps {
+		// Check for a local operation first.
+		localOp, err := operations.OperationGetInternal(dbOp.UUID)
+		if err == nil {
+			_, op, err := localOp.RenderWithSecret()
+			if err != nil {
+				return nil, err
+			}
+
+			ops = append(ops, op)
+			continue
+		}
+
+		// Then get the operation from the member running it.
+		client, err := cluster.Connect(dbOp.NodeAddress, cert, true)
+		if err != nil {
+			logger.Warn("Failed to connect to cluster member", log.Ctx{"address": dbOp.NodeAddress, "err": err})
+			continue
+		}
+
+		op, _, err := client.GetOperation(dbOp.UUID)
+		if err != nil {
+			logger.Warn("Failed to get operation from cluster member", log.Ctx{"address": dbOp.NodeAddress, "operation": dbOp.UUID, "err": err})
+			continue
+		}
+
+		ops = append(ops, op)
+	}
+
+	return ops, nil
+}
+
+// operationCancel cancels the operation with the given ID, wherever it runs in the cluster.
+func operationCancel(d *Daemon, id string) error {
+	localOp, err := operations.OperationGetInternal(id)
+	if err == nil {
+		_, err = localOp.Cancel()
+		return err
+	}
+
+	var address string
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		operation, err := tx.GetOperationByUUID(id)
+		if err != nil {
+			return err
+		}
+
+		address = operation.NodeAddress
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	client, err := cluster.Connect(address, d.endpoints.NetworkCert(), true)
+	if err != nil {
+		return err
+	}
+
+	return client.DeleteOperation(id)
+}
+
+// tokenOperationCreate creates a token operation of the given type with a random secret added to the metadata.
+// The token is cancelled once the expiry expression has elapsed, an empty expression means it doesn't expire.
+func tokenOperationCreate(d *Daemon, opType db.OperationType, metadata shared.Jmap, expiry string) (*operations.Operation, error) {
+	secret, err := shared.RandomCryptoString()
+	if err != nil {
+		return nil, err
+	}
+
+	metadata["secret"] = secret
+
+	expiresAt, err := shared.GetSnapshotExpiry(time.Now(), expiry)
+	if err != nil {
+		return nil, err
+	}
+
+	if !expiresAt.IsZero() {
+		metadata["expires_at"] = expiresAt
+	}
+
+	op, err := operations.OperationCreate(d.State(), "", operations.OperationClassToken, opType, nil, metadata, nil, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if !expiresAt.IsZero() {
+		time.AfterFunc(time.Until(expiresAt), func() {
+			if op.Status() == api.Running {
+				op.Cancel()
+			}
+		})
+	}
+
+	return op, nil
+}
+
+// tokenOperationValid looks for a running token operation of the given type with the given secret across the
+// cluster. The token isn't consumed, see tokenOperationConsume. Nil is returned if no valid token matches.
+func tokenOperationValid(d *Daemon, opType db.OperationType, secret string) (*api.Operation, error) {
+	if secret == "" {
+		return nil, nil
+	}
+
+	ops, err := operationsGetByType(d, opType)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, op := range ops {
+		if op.Class != operations.OperationClassToken.String() || op.StatusCode != api.Running {
+			continue
+		}
+
+		opSecret, ok := op.Metadata["secret"].(string)
+		if !ok || subtle.ConstantTimeCompare([]byte(opSecret), []byte(secret)) != 1 {
+			continue
+		}
+
+		return op, nil
+	}
+
+	return nil, nil
+}
+
+// tokenOperationConsume cancels a token operation as tokens are single-use. Only one of concurrent attempts to
+// use the same token succeeds, the others getting an error.
+func tokenOperationConsume(d *Daemon, op *api.Operation) error {
+	err := operationCancel(d, op.ID)
+	if err != nil {
+		logger.Warn("Failed to cancel token operation", log.Ctx{"operation": op.ID, "err": err})
+		return fmt.Errorf("Token was already used")
+	}
+
+	return nil
+}
@@ -297,17 +297,20 @@ func (op *Operation) Run() (chan error, error) {
 // Cancel cancels a running operation. If the operation cannot be cancelled, it
 // returns an error.
 func (op *Operation) Cancel() (chan error, error) {
+	// Check and change the status at once so that only one of concurrent cancellations succeeds.
+	op.lock.Lock()
 	if op.status != api.Running {
+		op.lock.Unlock()
 		return nil, fmt.Errorf("Only running operations can be cancelled")
 	}
 
 	if !op.mayCancel() {
+		op.lock.Unlock()
 		return nil, fmt.Errorf("This operation can't be cancelled")
 	}
 
 	chanCancel := make(chan error, 1)
 
-	op.lock.Lock()
 	oldStatus := op.status
 	op.status = api.Cancelling
 	op.lock.Unlock()
@@ -430,7 +433,18 @@ func (op *Operation) mayCancel() bool {
 }
 
 // Render renders the operation structure.
+// The secret of token operations is left out as the rendered operation is also sent in events.
 func (op *Operation) Render() (string, *api.Operation, error) {
+	return op.render(false)
+}
+
+// RenderWithSecret renders the operation structure including the secret of token operations.
+// It must only be sent to the creator of the token and to other cluster members checking it.
+func (op *Operation) RenderWithSecret() (string, *api.Operation, error) {
+	return op.render(true)
+}
+
+func (op *Operation) render(withSecret bool) (string, *api.Operation, error) {
 	// Setup the resource URLs
 	resources := op.resources
 	if resources != nil {
@@ -453,6 +467,16 @@ func (op *Operation) Render() (string, *api.Operation, error) {
 	}
 
 	op.lock.Lock()
+	metadata := op.metadata
+	if op.class == OperationClassToken && !withSecret {
+		metadata = make(map[string]interface{}, len(op.metadata))
+		for key, value := range op.metadata {
+			if key != "secret" {
+				metadata[key] = value
+			}
+		}
+	}
+
 	retOp := &api.Operation{
 		ID:          op.id,
 		Class:       op.class.String(),
@@ -462,7 +486,7 @@ func (op *Operation) Render() (string, *api.Operation, error) {
 		Status:      op.status.String(),
 		StatusCode:  op.status,
 		Resources:   resources,
-		Metadata:    op.metadata,
+		Metadata:    metadata,
 		MayCancel:   op.mayCancel(),
 		Err:         op.err,
 		Location:    serverName,
@@ -0,0 +1,37 @@
+package operations
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/lxd/db"
+)
+
+// The secret of token operations is only rendered on request, as rendered operations are also sent in events.
+func TestOperation_RenderTokenSecret(t *testing.T) {
+	metadata := map[string]interface{}{"client_name": "c1", "secret": "abc"}
+
+	op, err := OperationCreate(nil, "", OperationClassToken, db.OperationCertificateAddToken, nil, metadata, nil, nil, nil)
+	require.NoError(t, err)
+
+	_, opAPI, err := op.Render()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"client_name": "c1"}, opAPI.Metadata)
+
+	_, opAPI, err = op.RenderWithSecret()
+	require.NoError(t, err)
+	assert.Equal(t, metadata, opAPI.Metadata)
+
+	// The secret is kept for the checks done by the server itself.
+	assert.Equal(t, "abc", op.Metadata()["secret"])
+
+	// Other operations are rendered unchanged.
+	op, err = OperationCreate(nil, "p1", OperationClassTask, db.OperationSnapshotCreate, nil, map[string]interface{}{"secret": "abc"}, nil, nil, nil)
+	require.NoError(t, err)
+
+	_, opAPI, err = op.Render()
+	require.NoError(t, err)
+	assert.Equal(t, "abc", opAPI.Metadata["secret"])
+}
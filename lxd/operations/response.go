@@ -26,7 +26,8 @@ func (r *operationResponse) Render(w http.ResponseWriter) error {
 		return err
 	}
 
-	url, md, err := r.op.Render()
+	// This is the response to the creation of the operation, so the secret of tokens is included.
+	url, md, err := r.op.RenderWithSecret()
 	if err != nil {
 		return err
 	}
@@ -2,6 +2,7 @@ package project
 
 import (
 	"fmt"
+	"net"
 	"strconv"
 	"strings"
 
@@ -151,8 +152,8 @@ func AllowVolumeCreation(tx *db.ClusterTx, projectName string, req api.StorageVo
 		return nil
 	}
 
-	// If "limits.disk" is not set, there's nothing to do.
-	if info.Project.Config["limits.disk"] == "" {
+	// If "limits.disk" is not set and the project isn't restricted, there's nothing to do.
+	if info.Project.Config["limits.disk"] == "" && !shared.IsTrue(info.Project.Config["restricted"]) {
 		return nil
 	}
 
@@ -249,6 +250,23 @@ func checkRestrictionsAndAggregateLimits(tx *db.ClusterTx, info *projectInfo) er
 		if err != nil {
 			return err
 		}
+
+		err = checkVolumeRestrictions(info.Project, info.Volumes)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Check that the custom volumes of a restricted project don't use forbidden config.
+func checkVolumeRestrictions(project *api.Project, volumes []db.StorageVolumeArgs) error {
+	for _, volume := range volumes {
+		// Backups can only be exported to other volumes by the server administrator.
+		if volume.Config["backups.target"] != "" {
+			return fmt.Errorf("Use of config %q on volume %q of project %q is forbidden", "backups.target", volume.Name, project.Name)
+		}
 	}
 
 	return nil
@@ -541,6 +559,7 @@ func isContainerLowLevelOptionForbidden(key string) bool {
 	}
 
 	if shared.StringInSlice(key, []string{
+		"backups.target",
 		"boot.host_shutdown_timeout",
 		"linux.kernel_modules",
 		"raw.apparmor",
@@ -560,6 +579,7 @@ func isContainerLowLevelOptionForbidden(key string) bool {
 // Return true if a low-level VM option is forbidden.
 func isVMLowLevelOptionForbidden(key string) bool {
 	if shared.StringInSlice(key, []string{
+		"backups.target",
 		"boot.host_shutdown_timeout",
 		"limits.memory.hugepages",
 		"raw.qemu",
@@ -621,8 +641,8 @@ func AllowVolumeUpdate(tx *db.ClusterTx, projectName, volumeName string, req api
 		return nil
 	}
 
-	// If "limits.disk" is not set, there's nothing to do.
-	if info.Project.Config["limits.disk"] == "" {
+	// If "limits.disk" is not set and the project isn't restricted, there's nothing to do.
+	if info.Project.Config["limits.disk"] == "" && !shared.IsTrue(info.Project.Config["restricted"]) {
 		return nil
 	}
 
@@ -683,7 +703,25 @@ func AllowProjectUpdate(tx *db.ClusterTx, projectName string, config map[string]
 	// instances.
 	aggregateKeys := []string{}
 
+	// Check the network restrictions once if any of the related keys have changed.
+	for _, key := range []string{"restricted", "restricted.networks.uplinks", "restricted.networks.subnets"} {
+		if !shared.StringInSlice(key, changed) {
+			continue
+		}
+
+		err := CheckNetworkRestrictions(tx, projectName, config)
+		if err != nil {
+			return errors.Wrapf(err, "Conflict detected when changing %q in project %q", key, projectName)
+		}
+
+		break
+	}
+
 	for _, key := range changed {
+		if strings.HasPrefix(key, "restricted.networks.") || key == "restricted.cluster.groups" {
+			continue
+		}
+
 		if strings.HasPrefix(key, "restricted.") {
 			project := &api.Project{
 				Name: projectName,
@@ -1035,3 +1073,212 @@ var aggregateLimitConfigValuePrinters = map[string]func(int64) string{
 		return units.GetByteSizeString(limit, 1)
 	},
 }
+
+// networkSubnet is a subnet of an uplink network allocated to a project via "restricted.networks.subnets".
+type networkSubnet struct {
+	uplink string
+	subnet *net.IPNet
+}
+
+// parseNetworkSubnets parses a comma delimited list of "<uplink>:<subnet>" entries.
+func parseNetworkSubnets(value string) ([]networkSubnet, error) {
+	subnets := []networkSubnet{}
+
+	if value == "" {
+		return subnets, nil
+	}
+
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+
+		// IPv6 subnets contain colons, so only split on the first one.
+		parts := strings.SplitN(entry, ":", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("Invalid subnet %q, must be in the form <uplink>:<subnet>", entry)
+		}
+
+		_, subnet, err := net.ParseCIDR(parts[1])
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid subnet %q", entry)
+		}
+
+		subnets = append(subnets, networkSubnet{uplink: parts[0], subnet: subnet})
+	}
+
+	return subnets, nil
+}
+
+// parseNameList parses a comma delimited list of names, such as uplink networks or cluster groups.
+func parseNameList(value string) []string {
+	names := []string{}
+
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		names = append(names, name)
+	}
+
+	return names
+}
+
+// ValidateNetworkSubnets checks the syntax of a "restricted.networks.subnets" value.
+func ValidateNetworkSubnets(value string) error {
+	_, err := parseNetworkSubnets(value)
+	return err
+}
+
+// NetworkUplinkAllowed returns an error if the project's restrictions don't allow its networks to use the
+// specified uplink network. When the project is restricted, only the uplinks listed in
+// "restricted.networks.uplinks" can be used.
+func NetworkUplinkAllowed(projectConfig map[string]string, uplinkNetworkName string) error {
+	if !shared.IsTrue(projectConfig["restricted"]) {
+		return nil
+	}
+
+	if !shared.StringInSlice(uplinkNetworkName, parseNameList(projectConfig["restricted.networks.uplinks"])) {
+		return fmt.Errorf("Uplink network %q is not allowed by the project's %q setting", uplinkNetworkName, "restricted.networks.uplinks")
+	}
+
+	return nil
+}
+
+// NetworkAddressAllowed returns an error if the project's restrictions don't allow its networks to use the
+// specified address from the uplink network (for example as a network forward listen address). When the project
+// is restricted, only addresses within the project's "restricted.networks.subnets" for that uplink can be used.
+func NetworkAddressAllowed(projectConfig map[string]string, uplinkNetworkName string, address net.IP) error {
+	if !shared.IsTrue(projectConfig["restricted"]) {
+		return nil
+	}
+
+	subnets, err := parseNetworkSubnets(projectConfig["restricted.networks.subnets"])
+	if err != nil {
+		return err
+	}
+
+	for _, subnet := range subnets {
+		if subnet.uplink == uplinkNetworkName && subnet.subnet.Contains(address) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("Address %q is not within the project's %q for uplink network %q", address.String(), "restricted.networks.subnets", uplinkNetworkName)
+}
+
+// GetRestrictedClusterGroups returns the cluster groups the instances of the project may be placed on, or nil if
+// the project doesn't restrict them. When the project is restricted and "restricted.cluster.groups" is set, only
+// the members of the listed groups can be used.
+func GetRestrictedClusterGroups(projectConfig map[string]string) []string {
+	if !shared.IsTrue(projectConfig["restricted"]) || projectConfig["restricted.cluster.groups"] == "" {
+		return nil
+	}
+
+	return parseNameList(projectConfig["restricted.cluster.groups"])
+}
+
+// ClusterGroupAllowed returns an error if the project's restrictions don't allow targeting the given cluster group.
+func ClusterGroupAllowed(projectConfig map[string]string, groupName string) error {
+	allowedGroups := GetRestrictedClusterGroups(projectConfig)
+	if allowedGroups != nil && !shared.StringInSlice(groupName, allowedGroups) {
+		return fmt.Errorf("Cluster group %q is not allowed by the project's %q setting", groupName, "restricted.cluster.groups")
+	}
+
+	return nil
+}
+
+// ClusterMemberAllowed returns an error if the project's restrictions don't allow targeting the cluster member
+// with the given name, which belongs to the given cluster groups.
+func ClusterMemberAllowed(projectConfig map[string]string, memberName string, memberGroups []string) error {
+	allowedGroups := GetRestrictedClusterGroups(projectConfig)
+	if allowedGroups == nil {
+		return nil
+	}
+
+	for _, group := range memberGroups {
+		if shared.StringInSlice(group, allowedGroups) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("Cluster member %q is not in any of the groups allowed by the project's %q setting", memberName, "restricted.cluster.groups")
+}
+
+// CheckNetworkRestrictions checks that the network restrictions in the supplied project config are consistent
+// and are not violated by the project's existing networks. Uplink subnets allocated to the project must use an
+// allowed uplink and must not overlap with the subnets allocated to any other project.
+func CheckNetworkRestrictions(tx *db.ClusterTx, projectName string, config map[string]string) error {
+	subnets, err := parseNetworkSubnets(config["restricted.networks.subnets"])
+	if err != nil {
+		return err
+	}
+
+	uplinks := parseNameList(config["restricted.networks.uplinks"])
+	for _, subnet := range subnets {
+		if !shared.StringInSlice(subnet.uplink, uplinks) {
+			return fmt.Errorf("Uplink network %q of subnet %q is not in %q", subnet.uplink, subnet.subnet.String(), "restricted.networks.uplinks")
+		}
+	}
+
+	// Check the subnets don't overlap with those allocated to other projects.
+	projects, err := tx.GetProjects(db.ProjectFilter{})
+	if err != nil {
+		return errors.Wrap(err, "Fetch projects from database")
+	}
+
+	for _, otherProject := range projects {
+		if otherProject.Name == projectName {
+			continue
+		}
+
+		otherSubnets, err := parseNetworkSubnets(otherProject.Config["restricted.networks.subnets"])
+		if err != nil {
+			return errors.Wrapf(err, "Invalid subnets in project %q", otherProject.Name)
+		}
+
+		for _, subnet := range subnets {
+			for _, otherSubnet := range otherSubnets {
+				if subnet.uplink != otherSubnet.uplink {
+					continue
+				}
+
+				if subnet.subnet.Contains(otherSubnet.subnet.IP) || otherSubnet.subnet.Contains(subnet.subnet.IP) {
+					return fmt.Errorf("Subnet %q overlaps with subnet %q allocated to project %q", subnet.subnet.String(), otherSubnet.subnet.String(), otherProject.Name)
+				}
+			}
+		}
+	}
+
+	// Check the project's existing networks still comply with the restrictions.
+	networks, err := tx.GetNonPendingNetworks(projectName)
+	if err != nil {
+		return errors.Wrap(err, "Fetch project networks from database")
+	}
+
+	for networkID, network := range networks {
+		uplink := network.Config["network"]
+		if uplink == "" {
+			continue
+		}
+
+		err = NetworkUplinkAllowed(config, uplink)
+		if err != nil {
+			return errors.Wrapf(err, "Network %q", network.Name)
+		}
+
+		listenAddresses, err := tx.GetNetworkListenAddresses(networkID)
+		if err != nil {
+			return errors.Wrapf(err, "Fetch listen addresses of network %q", network.Name)
+		}
+
+		for _, listenAddress := range listenAddresses {
+			err = NetworkAddressAllowed(config, uplink, net.ParseIP(listenAddress))
+			if err != nil {
+				return errors.Wrapf(err, "Network %q", network.Name)
+			}
+		}
+	}
+
+	return nil
+}
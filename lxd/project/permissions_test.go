@@ -1,6 +1,7 @@
 package project_test
 
 import (
+	"net"
 	"testing"
 
 	"github.com/lxc/lxd/lxd/db"
@@ -124,3 +125,128 @@ func TestAllowInstanceCreation_DifferentType(t *testing.T) {
 	err = project.AllowInstanceCreation(tx, "p1", req)
 	assert.NoError(t, err)
 }
+
+// Uplink networks are only restricted when the project is restricted.
+func TestNetworkUplinkAllowed(t *testing.T) {
+	config := map[string]string{
+		"restricted.networks.uplinks": "lxdbr0, lxdbr1",
+	}
+
+	assert.NoError(t, project.NetworkUplinkAllowed(config, "lxdbr2"))
+
+	config["restricted"] = "true"
+	assert.NoError(t, project.NetworkUplinkAllowed(config, "lxdbr1"))
+	assert.Error(t, project.NetworkUplinkAllowed(config, "lxdbr2"))
+
+	delete(config, "restricted.networks.uplinks")
+	assert.Error(t, project.NetworkUplinkAllowed(config, "lxdbr0"))
+}
+
+// Addresses of a restricted project must be within the subnets allocated for the uplink.
+func TestNetworkAddressAllowed(t *testing.T) {
+	config := map[string]string{
+		"restricted":                  "true",
+		"restricted.networks.subnets": "lxdbr0:192.0.2.0/28,lxdbr0:2001:db8::/64",
+	}
+
+	assert.NoError(t, project.NetworkAddressAllowed(config, "lxdbr0", net.ParseIP("192.0.2.1")))
+	assert.NoError(t, project.NetworkAddressAllowed(config, "lxdbr0", net.ParseIP("2001:db8::1")))
+	assert.Error(t, project.NetworkAddressAllowed(config, "lxdbr0", net.ParseIP("192.0.2.16")))
+	assert.Error(t, project.NetworkAddressAllowed(config, "lxdbr1", net.ParseIP("192.0.2.1")))
+}
+
+// Subnets allocated to a project can't overlap with those of other projects.
+func TestCheckNetworkRestrictions(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	_, err := tx.CreateProject(api.ProjectsPost{
+		Name: "p1",
+		ProjectPut: api.ProjectPut{
+			Config: map[string]string{
+				"restricted":                  "true",
+				"restricted.networks.uplinks": "lxdbr0",
+				"restricted.networks.subnets": "lxdbr0:192.0.2.0/28",
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	config := map[string]string{
+		"restricted":                  "true",
+		"restricted.networks.uplinks": "lxdbr0",
+		"restricted.networks.subnets": "lxdbr0:192.0.2.16/28",
+	}
+
+	assert.NoError(t, project.CheckNetworkRestrictions(tx, "p2", config))
+
+	config["restricted.networks.subnets"] = "lxdbr0:192.0.2.0/24"
+	assert.EqualError(t, project.CheckNetworkRestrictions(tx, "p2", config), `Subnet "192.0.2.0/24" overlaps with subnet "192.0.2.0/28" allocated to project "p1"`)
+
+	config["restricted.networks.subnets"] = "lxdbr1:192.0.2.0/24"
+	assert.Error(t, project.CheckNetworkRestrictions(tx, "p2", config))
+
+	config["restricted.networks.subnets"] = "invalid"
+	assert.Error(t, project.CheckNetworkRestrictions(tx, "p2", config))
+}
+
+// Cluster groups are only restricted when the project is restricted and lists allowed groups.
+func TestClusterTargetAllowed(t *testing.T) {
+	config := map[string]string{
+		"restricted.cluster.groups": "foo, bar",
+	}
+
+	assert.Nil(t, project.GetRestrictedClusterGroups(config))
+	assert.NoError(t, project.ClusterGroupAllowed(config, "default"))
+
+	config["restricted"] = "true"
+	assert.Equal(t, []string{"foo", "bar"}, project.GetRestrictedClusterGroups(config))
+	assert.NoError(t, project.ClusterGroupAllowed(config, "bar"))
+	assert.Error(t, project.ClusterGroupAllowed(config, "default"))
+	assert.NoError(t, project.ClusterMemberAllowed(config, "node1", []string{"default", "foo"}))
+	assert.Error(t, project.ClusterMemberAllowed(config, "node1", []string{"default"}))
+
+	delete(config, "restricted.cluster.groups")
+	assert.NoError(t, project.ClusterMemberAllowed(config, "node1", []string{"default"}))
+}
+
+// Exporting backups to a storage volume is forbidden in restricted projects.
+func TestAllowBackupsTarget_Restricted(t *testing.T) {
+	tx, cleanup := db.NewTestClusterTx(t)
+	defer cleanup()
+
+	_, err := tx.CreateProject(api.ProjectsPost{
+		Name: "p1",
+		ProjectPut: api.ProjectPut{
+			Config: map[string]string{
+				"restricted": "true",
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	instReq := api.InstancesPost{
+		Name: "c1",
+		Type: api.InstanceTypeContainer,
+		InstancePut: api.InstancePut{
+			Config: map[string]string{"backups.target": "default/backups"},
+		},
+	}
+
+	err = project.AllowInstanceCreation(tx, "p1", instReq)
+	assert.EqualError(t, err, `Use of low-level config "backups.target" on container "c1" of project "p1" is forbidden`)
+
+	volReq := api.StorageVolumesPost{
+		Name: "vol1",
+		StorageVolumePut: api.StorageVolumePut{
+			Config: map[string]string{"backups.target": "default/backups"},
+		},
+	}
+
+	err = project.AllowVolumeCreation(tx, "p1", volReq)
+	assert.EqualError(t, err, `Use of config "backups.target" on volume "vol1" of project "p1" is forbidden`)
+
+	// Unrestricted projects can use it.
+	assert.NoError(t, project.AllowInstanceCreation(tx, "default", instReq))
+	assert.NoError(t, project.AllowVolumeCreation(tx, "default", volReq))
+}
@@ -1,26 +1,32 @@
 package main
 
 import (
+	"context"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 
+	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/resources"
 	"github.com/lxc/lxd/lxd/response"
 	storagePools "github.com/lxc/lxd/lxd/storage"
+	"github.com/lxc/lxd/lxd/task"
 	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
 )
 
 var api10ResourcesCmd = APIEndpoint{
 	Path: "resources",
 
-	Get: APIEndpointAction{Handler: api10ResourcesGet, AccessHandler: allowAuthenticated},
+	Get: APIEndpointAction{Handler: api10ResourcesGet, AccessHandler: allowUnrestricted},
 }
 
 var storagePoolResourcesCmd = APIEndpoint{
 	Path: "storage-pools/{name}/resources",
 
-	Get: APIEndpointAction{Handler: storagePoolResourcesGet, AccessHandler: allowAuthenticated},
+	Get: APIEndpointAction{Handler: storagePoolResourcesGet, AccessHandler: allowUnrestricted},
 }
 
 // /1.0/resources
@@ -41,6 +47,31 @@ func api10ResourcesGet(d *Daemon, r *http.Request) response.Response {
 	return response.SyncResponse(true, res)
 }
 
+// hostResourcesCheckTask records a warning while the host resources can't be retrieved and resolves it once they
+// can be again.
+func hostResourcesCheckTask(d *Daemon) (task.Func, task.Schedule) {
+	f := func(ctx context.Context) {
+		_, err := resources.GetResources()
+		if err != nil {
+			logger.Warn("Failed to get host resources", log.Ctx{"err": err})
+
+			err = d.cluster.UpsertWarningLocalNode("", db.EntityTypeNone, 0, db.WarningTypeHostResourcesUnavailable, err.Error())
+			if err != nil {
+				logger.Warn("Failed to create warning", log.Ctx{"err": err})
+			}
+
+			return
+		}
+
+		err = d.cluster.ResolveWarningsByLocalNodeAndType(db.WarningTypeHostResourcesUnavailable)
+		if err != nil {
+			logger.Warn("Failed to resolve warning", log.Ctx{"err": err})
+		}
+	}
+
+	return f, task.Every(time.Hour)
+}
+
 // /1.0/storage-pools/{name}/resources
 // Get resources for a specific storage pool
 func storagePoolResourcesGet(d *Daemon, r *http.Request) response.Response {
@@ -17,9 +17,15 @@ func forwardedResponseIfTargetIsRemote(d *Daemon, request *http.Request) respons
 		return nil
 	}
 
+	return forwardedResponseToNode(d, request, targetNode)
+}
+
+// forwardedResponseToNode redirects a request to the given node. If the node
+// is the local one, nothing gets done and nil is returned.
+func forwardedResponseToNode(d *Daemon, request *http.Request, node string) response.Response {
 	// Figure out the address of the target node (which is possibly
 	// this very same node).
-	address, err := cluster.ResolveTarget(d.cluster, targetNode)
+	address, err := cluster.ResolveTarget(d.cluster, node)
 	if err != nil {
 		return response.SmartError(err)
 	}
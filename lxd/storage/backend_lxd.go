@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
 	yaml "gopkg.in/yaml.v2"
 
 	"github.com/lxc/lxd/lxd/backup"
@@ -31,6 +32,8 @@ import (
 	log "github.com/lxc/lxd/shared/log15"
 	"github.com/lxc/lxd/shared/logger"
 	"github.com/lxc/lxd/shared/logging"
+	"github.com/lxc/lxd/shared/units"
+	"github.com/lxc/lxd/shared/validate"
 )
 
 type lxdBackend struct {
@@ -394,6 +397,39 @@ func (b *lxdBackend) removeInstanceSnapshotSymlinkIfUnused(instanceType instance
 	return nil
 }
 
+// purgeVolume removes a volume and all of its snapshots from the pool. The database record is removed first so
+// that an interrupted purge never leaves a record pointing at partially deleted data. Instance symlinks are left
+// untouched.
+func (b *lxdBackend) purgeVolume(projectName string, volName string, volType drivers.VolumeType, volDBType int, contentType drivers.ContentType, volStorageName string, op *operations.Operation) error {
+	// Removing the volume record also removes the records of its snapshots.
+	err := b.state.Cluster.RemoveStoragePoolVolume(projectName, volName, volDBType, b.ID())
+	if err != nil && err != db.ErrNoSuchObject {
+		return errors.Wrapf(err, "Error deleting storage volume from database")
+	}
+
+	// There's no need to pass config as it's not needed when deleting a volume.
+	vol := b.newVolume(volType, contentType, volStorageName, nil)
+
+	if !b.driver.HasVolume(vol) {
+		return nil
+	}
+
+	snapNames, err := b.driver.VolumeSnapshots(vol, op)
+	if err != nil {
+		return err
+	}
+
+	for _, snapName := range snapNames {
+		snapVol := b.newVolume(volType, contentType, drivers.GetSnapshotVolumeName(volStorageName, snapName), nil)
+		err = b.driver.DeleteVolumeSnapshot(snapVol, op)
+		if err != nil {
+			return err
+		}
+	}
+
+	return b.driver.DeleteVolume(vol, op)
+}
+
 // instanceRootVolumeConfig returns the instance's root volume config.
 func (b *lxdBackend) instanceRootVolumeConfig(inst instance.Instance) (map[string]string, error) {
 	volType, err := InstanceTypeToVolumeType(inst.Type())
@@ -427,6 +463,11 @@ func (b *lxdBackend) instanceRootVolumeConfig(inst instance.Instance) (map[strin
 		vol.Config["size"] = rootDiskConf["size"]
 	}
 
+	// Pass the space reserved for the VM state through to the storage driver.
+	if rootDiskConf["size.state"] != "" {
+		vol.Config["size.state"] = rootDiskConf["size.state"]
+	}
+
 	return vol.Config, nil
 }
 
@@ -532,9 +573,12 @@ func (b *lxdBackend) CreateInstanceFromBackup(srcBackup backup.Info, srcData io.
 		return nil, nil, err
 	}
 
-	revert.Add(func() {
-		b.removeInstanceSymlink(instanceType, srcBackup.Project, srcBackup.Name)
-	})
+	// Incremental backups are applied on top of an existing instance, so leave its symlinks in place.
+	if srcBackup.Parent == "" {
+		revert.Add(func() {
+			b.removeInstanceSymlink(instanceType, srcBackup.Project, srcBackup.Name)
+		})
+	}
 
 	if len(srcBackup.Snapshots) > 0 {
 		err = b.ensureInstanceSnapshotSymlink(instanceType, srcBackup.Project, srcBackup.Name)
@@ -542,9 +586,11 @@ func (b *lxdBackend) CreateInstanceFromBackup(srcBackup backup.Info, srcData io.
 			return nil, nil, err
 		}
 
-		revert.Add(func() {
-			b.removeInstanceSnapshotSymlinkIfUnused(instanceType, srcBackup.Project, srcBackup.Name)
-		})
+		if srcBackup.Parent == "" {
+			revert.Add(func() {
+				b.removeInstanceSnapshotSymlinkIfUnused(instanceType, srcBackup.Project, srcBackup.Name)
+			})
+		}
 	}
 
 	// Update pool information in the backup.yaml file.
@@ -786,6 +832,193 @@ func (b *lxdBackend) CreateInstanceFromCopy(inst instance.Instance, src instance
 	return nil
 }
 
+// MoveInstanceFromPool moves a stopped instance's volume and its snapshots from another pool to this one.
+// The volume is only removed from the source pool once it has been fully transferred, so an interrupted move can
+// simply be retried.
+func (b *lxdBackend) MoveInstanceFromPool(inst instance.Instance, srcPool Pool, op *operations.Operation) error {
+	logger := logging.AddContext(b.logger, log.Ctx{"project": inst.Project(), "instance": inst.Name(), "srcPool": srcPool.Name()})
+	logger.Debug("MoveInstanceFromPool started")
+	defer logger.Debug("MoveInstanceFromPool finished")
+
+	if inst.IsSnapshot() {
+		return fmt.Errorf("Instance cannot be a snapshot")
+	}
+
+	if inst.IsRunning() {
+		return fmt.Errorf("Instance must be stopped")
+	}
+
+	// Convert to lxdBackend so we can access driver.
+	srcBackend, ok := srcPool.(*lxdBackend)
+	if !ok {
+		return fmt.Errorf("Pool is not an lxdBackend")
+	}
+
+	if srcBackend.name == b.name {
+		return fmt.Errorf("Source and target pools must be different")
+	}
+
+	volType, err := InstanceTypeToVolumeType(inst.Type())
+	if err != nil {
+		return err
+	}
+
+	volDBType, err := VolumeTypeToDBType(volType)
+	if err != nil {
+		return err
+	}
+
+	volTypeName := db.StoragePoolVolumeTypeNameContainer
+	if inst.Type() == instancetype.VM {
+		volTypeName = db.StoragePoolVolumeTypeNameVM
+	}
+
+	contentType := InstanceContentType(inst)
+
+	// Get the volume name on storage.
+	volStorageName := project.Instance(inst.Project(), inst.Name())
+
+	_, srcVolRow, err := b.state.Cluster.GetLocalStoragePoolVolume(inst.Project(), inst.Name(), volDBType, srcBackend.ID())
+	if err != nil {
+		if err == db.ErrNoSuchObject {
+			return fmt.Errorf("Source volume doesn't exist")
+		}
+
+		return err
+	}
+
+	snapshots, err := VolumeSnapshotsGet(b.state, inst.Project(), srcBackend.name, inst.Name(), volDBType)
+	if err != nil {
+		return err
+	}
+
+	// Remove anything left behind on this pool by a previously interrupted move.
+	err = b.purgeVolume(inst.Project(), inst.Name(), volType, volDBType, contentType, volStorageName, op)
+	if err != nil {
+		return errors.Wrapf(err, "Failed removing leftover volume")
+	}
+
+	// Check the source volume's config and remove any fields not relevant for this pool's type.
+	vol := b.newVolume(volType, contentType, volStorageName, srcVolRow.Config)
+	err = b.driver.ValidateVolume(vol, true)
+	if err != nil {
+		return err
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	revert.Add(func() {
+		b.purgeVolume(inst.Project(), inst.Name(), volType, volDBType, contentType, volStorageName, op)
+
+		// Point the instance's symlinks back at the source pool.
+		srcBackend.ensureInstanceSymlink(inst.Type(), inst.Project(), inst.Name(), drivers.GetVolumeMountPath(srcBackend.name, volType, volStorageName))
+		if len(snapshots) > 0 {
+			srcBackend.ensureInstanceSnapshotSymlink(inst.Type(), inst.Project(), inst.Name())
+		}
+	})
+
+	// Create the database entries for the volume and its snapshots on this pool.
+	err = VolumeDBCreate(b.state, inst.Project(), b.name, inst.Name(), srcVolRow.Description, volTypeName, false, vol.Config(), time.Time{}, srcVolRow.ContentType)
+	if err != nil {
+		return err
+	}
+
+	snapshotNames := make([]string, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		err = VolumeDBCreate(b.state, inst.Project(), b.name, snapshot.Name, snapshot.Description, volTypeName, true, vol.Config(), snapshot.ExpiryDate, srcVolRow.ContentType)
+		if err != nil {
+			return err
+		}
+
+		_, snapName, _ := shared.InstanceGetParentAndSnapshotName(snapshot.Name)
+		snapshotNames = append(snapshotNames, snapName)
+	}
+
+	// Negotiate the migration type to use.
+	offeredTypes := srcBackend.MigrationTypes(contentType, false)
+	offerHeader := migration.TypesToHeader(offeredTypes...)
+	migrationTypes, err := migration.MatchTypes(offerHeader, FallbackMigrationType(contentType), b.MigrationTypes(contentType, false))
+	if err != nil {
+		return fmt.Errorf("Failed to negotiate move migration type: %v", err)
+	}
+
+	var srcVolumeSize int64
+
+	// For VMs, get source volume size so that target can create the volume the same size.
+	if inst.Type() == instancetype.VM {
+		srcVolumeSize, err = InstanceDiskBlockSize(srcBackend, inst, op)
+		if err != nil {
+			return errors.Wrapf(err, "Failed getting source disk size")
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Use in-memory pipe pair to simulate a connection between the sender and receiver.
+	aEnd, bEnd := memorypipe.NewPipePair(ctx)
+
+	// Run sender and receiver in separate go routines to prevent deadlocks.
+	aEndErrCh := make(chan error, 1)
+	bEndErrCh := make(chan error, 1)
+	go func() {
+		err := srcBackend.MigrateInstance(inst, aEnd, &migration.VolumeSourceArgs{
+			Name:          inst.Name(),
+			Snapshots:     snapshotNames,
+			MigrationType: migrationTypes[0],
+			TrackProgress: true, // Do use a progress tracker on sender.
+		}, op)
+
+		if err != nil {
+			cancel()
+		}
+		aEndErrCh <- err
+	}()
+
+	go func() {
+		err := b.CreateInstanceFromMigration(inst, bEnd, migration.VolumeTargetArgs{
+			Name:          inst.Name(),
+			Snapshots:     snapshotNames,
+			MigrationType: migrationTypes[0],
+			VolumeSize:    srcVolumeSize,
+			TrackProgress: false, // Do not use a progress tracker on receiver.
+		}, op)
+
+		if err != nil {
+			cancel()
+		}
+		bEndErrCh <- err
+	}()
+
+	// Capture errors from the sender and receiver from their result channels.
+	errs := []error{}
+	aEndErr := <-aEndErrCh
+	if aEndErr != nil {
+		errs = append(errs, aEndErr)
+	}
+
+	bEndErr := <-bEndErrCh
+	if bEndErr != nil {
+		errs = append(errs, bEndErr)
+	}
+
+	cancel()
+
+	if len(errs) > 0 {
+		return fmt.Errorf("Move instance volume failed: %v", errs)
+	}
+
+	revert.Success()
+
+	// The volume is now fully on this pool, so remove it from the source pool.
+	err = srcBackend.purgeVolume(inst.Project(), inst.Name(), volType, volDBType, contentType, volStorageName, op)
+	if err != nil {
+		return errors.Wrapf(err, "Failed removing volume from source pool")
+	}
+
+	return nil
+}
+
 // RefreshInstance synchronises one instance's volume (and optionally snapshots) over another.
 // Snapshots that are not present in the source but are in the destination are removed from the
 // destination if snapshots are included in the synchronisation.
@@ -1512,8 +1745,8 @@ func (b *lxdBackend) MigrateInstance(inst instance.Instance, conn io.ReadWriteCl
 }
 
 // BackupInstance creates an instance backup.
-func (b *lxdBackend) BackupInstance(inst instance.Instance, tarWriter *instancewriter.InstanceTarWriter, optimized bool, snapshots bool, op *operations.Operation) error {
-	logger := logging.AddContext(b.logger, log.Ctx{"project": inst.Project(), "instance": inst.Name(), "optimized": optimized, "snapshots": snapshots})
+func (b *lxdBackend) BackupInstance(inst instance.Instance, tarWriter *instancewriter.InstanceTarWriter, optimized bool, snapshots bool, parent string, op *operations.Operation) error {
+	logger := logging.AddContext(b.logger, log.Ctx{"project": inst.Project(), "instance": inst.Name(), "optimized": optimized, "snapshots": snapshots, "parent": parent})
 	logger.Debug("BackupInstance started")
 	defer logger.Debug("BackupInstance finished")
 
@@ -1539,8 +1772,13 @@ func (b *lxdBackend) BackupInstance(inst instance.Instance, tarWriter *instancew
 		return err
 	}
 
+	// Incremental backups rely on the driver sending the snapshots as optimized deltas.
+	if parent != "" && (!optimized || !snapshots) {
+		return fmt.Errorf("Incremental backups require optimized storage and snapshots")
+	}
+
 	vol := b.newVolume(volType, contentType, volStorageName, rootDiskConf)
-	err = b.driver.BackupVolume(vol, tarWriter, optimized, snapshots, op)
+	err = b.driver.BackupVolume(vol, tarWriter, optimized, snapshots, parent, op)
 	if err != nil {
 		return err
 	}
@@ -1568,10 +1806,35 @@ func (b *lxdBackend) GetInstanceUsage(inst instance.Instance) (int64, error) {
 	return b.driver.GetVolumeUsage(vol)
 }
 
-// SetInstanceQuota sets the quota on the instance's root volume.
+// GetInstanceVolumeState returns the usage and I/O statistics of the instance's root volume.
+func (b *lxdBackend) GetInstanceVolumeState(inst instance.Instance) (*api.StorageVolumeState, error) {
+	logger := logging.AddContext(b.logger, log.Ctx{"project": inst.Project(), "instance": inst.Name()})
+	logger.Debug("GetInstanceVolumeState started")
+	defer logger.Debug("GetInstanceVolumeState finished")
+
+	volType, err := InstanceTypeToVolumeType(inst.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	contentType := InstanceContentType(inst)
+
+	rootDiskConf, err := b.instanceRootVolumeConfig(inst)
+	if err != nil {
+		return nil, err
+	}
+
+	volStorageName := project.Instance(inst.Project(), inst.Name())
+	vol := b.newVolume(volType, contentType, volStorageName, rootDiskConf)
+
+	return b.volumeState(vol)
+}
+
+// SetInstanceQuota sets the quota on the instance's root volume and, for VMs, the space reserved for
+// the VM state on the associated filesystem volume.
 // Returns ErrRunningQuotaResizeNotSupported if the instance is running and the storage driver
 // doesn't support resizing whilst the instance is running.
-func (b *lxdBackend) SetInstanceQuota(inst instance.Instance, size string, op *operations.Operation) error {
+func (b *lxdBackend) SetInstanceQuota(inst instance.Instance, size string, vmStateSize string, op *operations.Operation) error {
 	logger := logging.AddContext(b.logger, log.Ctx{"project": inst.Project(), "instance": inst.Name()})
 	logger.Debug("SetInstanceQuota started")
 	defer logger.Debug("SetInstanceQuota finished")
@@ -1593,7 +1856,23 @@ func (b *lxdBackend) SetInstanceQuota(inst instance.Instance, size string, op *o
 	// There's no need to pass config as it's not needed when setting quotas.
 	vol := b.newVolume(volType, contentVolume, volStorageName, nil)
 
-	return b.driver.SetVolumeQuota(vol, size, op)
+	err = b.driver.SetVolumeQuota(vol, size, op)
+	if err != nil {
+		return err
+	}
+
+	// Resize the VM filesystem volume to fit the VM state.
+	if vol.IsVMBlock() && vmStateSize != "" {
+		vol = b.newVolume(volType, contentVolume, volStorageName, map[string]string{"size.state": vmStateSize})
+		fsVol := vol.NewVMBlockFilesystemVolume()
+
+		err = b.driver.SetVolumeQuota(fsVol, fsVol.ConfigSize(), op)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 // MountInstance mounts the instance's root volume.
@@ -2257,8 +2536,9 @@ func (b *lxdBackend) CreateCustomVolume(projectName string, volName string, desc
 	return nil
 }
 
-// CreateCustomVolumeFromCopy creates a custom volume from an existing custom volume.
-// It copies the snapshots from the source volume by default, but can be disabled if requested.
+// CreateCustomVolumeFromCopy creates a custom volume from an existing custom volume or custom volume snapshot.
+// It copies the snapshots from the source volume by default, but can be disabled if requested. When the source
+// is a snapshot, only that snapshot's contents are copied into the new volume.
 func (b *lxdBackend) CreateCustomVolumeFromCopy(projectName string, volName string, desc string, config map[string]string, srcPoolName, srcVolName string, srcVolOnly bool, op *operations.Operation) error {
 	logger := logging.AddContext(b.logger, log.Ctx{"project": projectName, "volName": volName, "desc": desc, "config": config, "srcPoolName": srcPoolName, "srcVolName": srcVolName, "srcVolOnly": srcVolOnly})
 	logger.Debug("CreateCustomVolumeFromCopy started")
@@ -2294,6 +2574,11 @@ func (b *lxdBackend) CreateCustomVolumeFromCopy(projectName string, volName stri
 		return err
 	}
 
+	// A snapshot has no snapshots of its own, so only its contents can be copied.
+	if shared.IsSnapshot(srcVolName) {
+		srcVolOnly = true
+	}
+
 	// Use the source volume's config if not supplied.
 	if config == nil {
 		config = srcVolRow.Config
@@ -2489,6 +2774,82 @@ func (b *lxdBackend) CreateCustomVolumeFromCopy(projectName string, volName stri
 	return nil
 }
 
+// MoveCustomVolumeFromPool moves a custom volume and its snapshots from another pool to this one.
+// The volume is only removed from the source pool once it has been fully copied, so an interrupted move can
+// simply be retried.
+func (b *lxdBackend) MoveCustomVolumeFromPool(projectName string, volName string, srcPool Pool, op *operations.Operation) error {
+	logger := logging.AddContext(b.logger, log.Ctx{"project": projectName, "volName": volName, "srcPool": srcPool.Name()})
+	logger.Debug("MoveCustomVolumeFromPool started")
+	defer logger.Debug("MoveCustomVolumeFromPool finished")
+
+	if shared.IsSnapshot(volName) {
+		return fmt.Errorf("Volume name cannot be a snapshot")
+	}
+
+	// Convert to lxdBackend so we can access driver.
+	srcBackend, ok := srcPool.(*lxdBackend)
+	if !ok {
+		return fmt.Errorf("Pool is not an lxdBackend")
+	}
+
+	if srcBackend.name == b.name {
+		return fmt.Errorf("Source and target pools must be different")
+	}
+
+	_, srcVolRow, err := b.state.Cluster.GetLocalStoragePoolVolume(projectName, volName, db.StoragePoolVolumeTypeCustom, srcBackend.ID())
+	if err != nil {
+		if err == db.ErrNoSuchObject {
+			return fmt.Errorf("Source volume doesn't exist")
+		}
+
+		return err
+	}
+
+	// Backups belong to the volume on its pool and would be lost.
+	backups, err := b.state.Cluster.GetStoragePoolVolumeBackups(projectName, volName, srcBackend.ID())
+	if err != nil {
+		return err
+	}
+
+	if len(backups) > 0 {
+		return fmt.Errorf("Volume has backups which must be removed first")
+	}
+
+	contentDBType, err := VolumeContentTypeNameToContentType(srcVolRow.ContentType)
+	if err != nil {
+		return err
+	}
+
+	contentType, err := VolumeDBContentTypeToContentType(contentDBType)
+	if err != nil {
+		return err
+	}
+
+	// Get the volume name on storage.
+	volStorageName := project.StorageVolume(projectName, volName)
+
+	// Remove anything left behind on this pool by a previously interrupted move.
+	err = b.purgeVolume(projectName, volName, drivers.VolumeTypeCustom, db.StoragePoolVolumeTypeCustom, contentType, volStorageName, op)
+	if err != nil {
+		return errors.Wrapf(err, "Failed removing leftover volume")
+	}
+
+	// Provide empty description and nil config to copy them from the source volume.
+	err = b.CreateCustomVolumeFromCopy(projectName, volName, "", nil, srcBackend.name, volName, false, op)
+	if err != nil {
+		b.purgeVolume(projectName, volName, drivers.VolumeTypeCustom, db.StoragePoolVolumeTypeCustom, contentType, volStorageName, op)
+		return err
+	}
+
+	// The volume is now fully on this pool, so remove it from the source pool.
+	err = srcBackend.purgeVolume(projectName, volName, drivers.VolumeTypeCustom, db.StoragePoolVolumeTypeCustom, contentType, volStorageName, op)
+	if err != nil {
+		return errors.Wrapf(err, "Failed removing volume from source pool")
+	}
+
+	return nil
+}
+
 // MigrateCustomVolume sends a volume for migration.
 func (b *lxdBackend) MigrateCustomVolume(projectName string, conn io.ReadWriteCloser, args *migration.VolumeSourceArgs, op *operations.Operation) error {
 	logger := logging.AddContext(b.logger, log.Ctx{"project": projectName, "volName": args.Name, "args": args})
@@ -2642,16 +3003,36 @@ func (b *lxdBackend) RenameCustomVolume(projectName string, volName string, newV
 	}
 
 	revert.Success()
-	return nil
-}
 
-// detectChangedConfig returns the config that has changed between current and new config maps.
-// Also returns a boolean indicating whether all of the changed keys start with "user.".
-// Deleted keys will be returned as having an empty string value.
-func (b *lxdBackend) detectChangedConfig(curConfig, newConfig map[string]string) (map[string]string, bool) {
-	// Diff the configurations.
-	changedConfig := make(map[string]string)
-	userOnly := true
+	// Move the backups of the volume over to the new name. The volume itself has been renamed already, so
+	// failures here are not fatal.
+	backups, err := b.state.Cluster.GetStoragePoolVolumeBackups(projectName, newVolName, b.ID())
+	if err != nil {
+		logger.Warn("Failed to load volume backups", log.Ctx{"err": err})
+		return nil
+	}
+
+	for _, dbBackup := range backups {
+		_, backupName, _ := shared.InstanceGetParentAndSnapshotName(dbBackup.Name)
+		volBackup := backup.NewVolumeBackup(b.state, projectName, b.name, newVolName, dbBackup.ID, dbBackup.Name, dbBackup.CreationDate, dbBackup.ExpiryDate, dbBackup.VolumeOnly, dbBackup.OptimizedStorage)
+		err = volBackup.Rename(drivers.GetSnapshotVolumeName(newVolName, backupName))
+		if err != nil {
+			logger.Warn("Failed to rename volume backup", log.Ctx{"backup": dbBackup.Name, "err": err})
+		}
+	}
+
+	os.Remove(backup.VolumeBackupsPath(projectName, b.name, volName))
+
+	return nil
+}
+
+// detectChangedConfig returns the config that has changed between current and new config maps.
+// Also returns a boolean indicating whether all of the changed keys start with "user.".
+// Deleted keys will be returned as having an empty string value.
+func (b *lxdBackend) detectChangedConfig(curConfig, newConfig map[string]string) (map[string]string, bool) {
+	// Diff the configurations.
+	changedConfig := make(map[string]string)
+	userOnly := true
 	for key := range curConfig {
 		if curConfig[key] != newConfig[key] {
 			if !strings.HasPrefix(key, "user.") {
@@ -2867,6 +3248,20 @@ func (b *lxdBackend) DeleteCustomVolume(projectName string, volName string, op *
 		}
 	}
 
+	// Remove the backups of the volume.
+	backups, err := b.state.Cluster.GetStoragePoolVolumeBackups(projectName, volName, b.ID())
+	if err != nil {
+		return err
+	}
+
+	for _, dbBackup := range backups {
+		volBackup := backup.NewVolumeBackup(b.state, projectName, b.name, volName, dbBackup.ID, dbBackup.Name, dbBackup.CreationDate, dbBackup.ExpiryDate, dbBackup.VolumeOnly, dbBackup.OptimizedStorage)
+		err = volBackup.Delete()
+		if err != nil {
+			return errors.Wrapf(err, "Failed to delete volume backup %q", dbBackup.Name)
+		}
+	}
+
 	// Finally, remove the volume record from the database.
 	err = b.state.Cluster.RemoveStoragePoolVolume(projectName, volName, db.StoragePoolVolumeTypeCustom, b.ID())
 	if err != nil {
@@ -2908,6 +3303,153 @@ func (b *lxdBackend) GetCustomVolumeUsage(projectName, volName string) (int64, e
 	return b.driver.GetVolumeUsage(vol)
 }
 
+// GetCustomVolumeState returns the usage and I/O statistics of the custom volume.
+func (b *lxdBackend) GetCustomVolumeState(projectName, volName string) (*api.StorageVolumeState, error) {
+	_, volume, err := b.state.Cluster.GetLocalStoragePoolVolume(projectName, volName, db.StoragePoolVolumeTypeCustom, b.id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the volume name on storage.
+	volStorageName := project.StorageVolume(projectName, volName)
+	vol := b.newVolume(drivers.VolumeTypeCustom, drivers.ContentType(volume.ContentType), volStorageName, volume.Config)
+
+	return b.volumeState(vol)
+}
+
+// volumeState returns the usage and I/O statistics of a volume. The volume is mounted for the duration of the
+// check so that the values don't depend on whether it is in use or not. Volumes on remote storage are never
+// mounted for the check, as they may be in use by another cluster member.
+func (b *lxdBackend) volumeState(vol drivers.Volume) (*api.StorageVolumeState, error) {
+	if b.driver.Info().Remote {
+		return b.remoteVolumeState(vol)
+	}
+
+	volState := &api.StorageVolumeState{}
+	err := vol.MountTask(func(mountPath string, op *operations.Operation) error {
+		return b.fillVolumeState(vol, mountPath, volState)
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return volState, nil
+}
+
+// remoteVolumeState returns the usage and I/O statistics of a volume on remote storage. Filesystem volumes are
+// only checked when already mounted on this member, while block volumes only report their usage and size.
+func (b *lxdBackend) remoteVolumeState(vol drivers.Volume) (*api.StorageVolumeState, error) {
+	volState := &api.StorageVolumeState{}
+
+	if vol.ContentType() == drivers.ContentTypeFS {
+		if !shared.IsMountPoint(vol.MountPath()) {
+			return volState, nil
+		}
+
+		err := b.fillVolumeState(vol, vol.MountPath(), volState)
+		if err != nil {
+			return nil, err
+		}
+
+		return volState, nil
+	}
+
+	// Block volumes aren't mapped here, so their size is taken from their config.
+	used, err := b.driver.GetVolumeUsage(vol)
+	if err != nil {
+		if err == drivers.ErrNotSupported {
+			return volState, nil
+		}
+
+		return nil, err
+	}
+
+	total, err := units.ParseByteSizeString(vol.ConfigSize())
+	if err != nil {
+		return nil, err
+	}
+
+	volState.Usage = &api.StorageVolumeStateUsage{
+		Used:  uint64(used),
+		Total: total,
+	}
+
+	return volState, nil
+}
+
+// fillVolumeState fills in the usage and I/O statistics of a volume mounted at mountPath.
+func (b *lxdBackend) fillVolumeState(vol drivers.Volume, mountPath string, volState *api.StorageVolumeState) error {
+	// Leave the usage unset if the driver can't report it.
+	used, err := b.driver.GetVolumeUsage(vol)
+	if err != nil && err != drivers.ErrNotSupported {
+		return err
+	}
+
+	usageSupported := err == nil
+	usage := &api.StorageVolumeStateUsage{
+		Used:  uint64(used),
+		Total: -1,
+	}
+
+	// Path to get the I/O counters from, only set when backed by a block device.
+	ioPath := ""
+
+	if vol.ContentType() == drivers.ContentTypeBlock {
+		diskPath, err := b.driver.GetVolumeDiskPath(vol)
+		if err != nil {
+			return err
+		}
+
+		usage.Total, err = drivers.BlockDiskSizeBytes(diskPath)
+		if err != nil {
+			return err
+		}
+
+		if shared.IsBlockdevPath(diskPath) {
+			ioPath = diskPath
+		}
+	} else {
+		var stat unix.Statfs_t
+		err := unix.Statfs(mountPath, &stat)
+		if err != nil {
+			return err
+		}
+
+		// Use the volume's quota if set, otherwise the space available to its filesystem.
+		usage.Total = int64(stat.Blocks) * int64(stat.Bsize)
+		size := vol.ExpandedConfig("size")
+		if size != "" && size != "0" {
+			usage.Total, err = units.ParseByteSizeString(size)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Some filesystems don't report inodes since they allocate them dynamically e.g. btrfs.
+		if stat.Files > 0 {
+			usage.InodesTotal = stat.Files
+			usage.InodesUsed = stat.Files - stat.Ffree
+		}
+
+		if vol.IsBlockBacked() {
+			ioPath = mountPath
+		}
+	}
+
+	if usageSupported {
+		volState.Usage = usage
+	}
+
+	if ioPath != "" {
+		volState.IO, err = drivers.BlockDiskIOStats(ioPath)
+		if err != nil && err != drivers.ErrNotSupported {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // MountCustomVolume mounts a custom volume.
 func (b *lxdBackend) MountCustomVolume(projectName, volName string, op *operations.Operation) (bool, error) {
 	logger := logging.AddContext(b.logger, log.Ctx{"project": projectName, "volName": volName})
@@ -2944,6 +3486,113 @@ func (b *lxdBackend) UnmountCustomVolume(projectName, volName string, op *operat
 	return b.driver.UnmountVolume(vol, op)
 }
 
+// BackupCustomVolume creates a custom volume backup.
+func (b *lxdBackend) BackupCustomVolume(projectName string, volName string, tarWriter *instancewriter.InstanceTarWriter, optimized bool, snapshots bool, op *operations.Operation) error {
+	logger := logging.AddContext(b.logger, log.Ctx{"project": projectName, "volume": volName, "optimized": optimized, "snapshots": snapshots})
+	logger.Debug("BackupCustomVolume started")
+	defer logger.Debug("BackupCustomVolume finished")
+
+	_, volume, err := b.state.Cluster.GetLocalStoragePoolVolume(projectName, volName, db.StoragePoolVolumeTypeCustom, b.id)
+	if err != nil {
+		return err
+	}
+
+	// Get the volume name on storage.
+	volStorageName := project.StorageVolume(projectName, volName)
+	vol := b.newVolume(drivers.VolumeTypeCustom, drivers.ContentType(volume.ContentType), volStorageName, volume.Config)
+
+	err = b.driver.BackupVolume(vol, tarWriter, optimized, snapshots, "", op)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// CreateCustomVolumeFromBackup restores a custom volume backup onto the storage device and creates the
+// database records for the volume and its snapshots.
+func (b *lxdBackend) CreateCustomVolumeFromBackup(srcBackup backup.Info, srcData io.ReadSeeker, op *operations.Operation) error {
+	logger := logging.AddContext(b.logger, log.Ctx{"project": srcBackup.Project, "volume": srcBackup.Name, "snapshots": srcBackup.Snapshots, "optimizedStorage": *srcBackup.OptimizedStorage})
+	logger.Debug("CreateCustomVolumeFromBackup started")
+	defer logger.Debug("CreateCustomVolumeFromBackup finished")
+
+	if srcBackup.Volume == nil {
+		return fmt.Errorf("Valid volume config not found in index")
+	}
+
+	// Check the volume doesn't exist already.
+	_, _, err := b.state.Cluster.GetLocalStoragePoolVolume(srcBackup.Project, srcBackup.Name, db.StoragePoolVolumeTypeCustom, b.id)
+	if err != db.ErrNoSuchObject {
+		if err != nil {
+			return err
+		}
+
+		return fmt.Errorf("Volume by that name already exists")
+	}
+
+	contentType := drivers.ContentTypeFS
+	if srcBackup.ContentType != "" {
+		contentType = drivers.ContentType(srcBackup.ContentType)
+	}
+
+	// Get the volume name on storage.
+	volStorageName := project.StorageVolume(srcBackup.Project, srcBackup.Name)
+	vol := b.newVolume(drivers.VolumeTypeCustom, contentType, volStorageName, srcBackup.Volume.Config)
+
+	// Validate config.
+	err = b.driver.ValidateVolume(vol, false)
+	if err != nil {
+		return err
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	// Create database entry for new storage volume.
+	err = VolumeDBCreate(b.state, srcBackup.Project, b.name, srcBackup.Name, srcBackup.Volume.Description, db.StoragePoolVolumeTypeNameCustom, false, vol.Config(), time.Time{}, string(contentType))
+	if err != nil {
+		return err
+	}
+
+	revert.Add(func() {
+		b.state.Cluster.RemoveStoragePoolVolume(srcBackup.Project, srcBackup.Name, db.StoragePoolVolumeTypeCustom, b.ID())
+	})
+
+	// Create database entries for the snapshots, they take the config of the volume.
+	for _, snapName := range srcBackup.Snapshots {
+		fullSnapshotName := drivers.GetSnapshotVolumeName(srcBackup.Name, snapName)
+		err = VolumeDBCreate(b.state, srcBackup.Project, b.name, fullSnapshotName, srcBackup.Volume.Description, db.StoragePoolVolumeTypeNameCustom, true, vol.Config(), time.Time{}, string(contentType))
+		if err != nil {
+			return err
+		}
+
+		revert.Add(func() {
+			b.state.Cluster.RemoveStoragePoolVolume(srcBackup.Project, fullSnapshotName, db.StoragePoolVolumeTypeCustom, b.ID())
+		})
+	}
+
+	// Unpack the backup into the new storage volume(s).
+	volPostHook, revertHook, err := b.driver.CreateVolumeFromBackup(vol, srcBackup, srcData, op)
+	if err != nil {
+		return err
+	}
+
+	if revertHook != nil {
+		revert.Add(revertHook)
+	}
+
+	// There is no instance to wait for, so run the driver's post hook straight away.
+	if volPostHook != nil {
+		err = volPostHook(vol)
+		if err != nil {
+			return err
+		}
+	}
+
+	revert.Success()
+	return nil
+}
+
 // CreateCustomVolumeSnapshot creates a snapshot of a custom volume.
 func (b *lxdBackend) CreateCustomVolumeSnapshot(projectName, volName string, newSnapshotName string, newExpiryDate time.Time, op *operations.Operation) error {
 	logger := logging.AddContext(b.logger, log.Ctx{"project": projectName, "volName": volName, "newSnapshotName": newSnapshotName, "newExpiryDate": newExpiryDate})
@@ -3185,6 +3834,172 @@ func (b *lxdBackend) RestoreCustomVolume(projectName, volName string, snapshotNa
 	return nil
 }
 
+// bucketVolume returns the storage volume backing a bucket.
+func (b *lxdBackend) bucketVolume(projectName string, bucketName string, config map[string]string) drivers.Volume {
+	volConfig := map[string]string{}
+	if config["size"] != "" {
+		volConfig["size"] = config["size"]
+	}
+
+	return b.newVolume(drivers.VolumeTypeBucket, drivers.ContentTypeFS, project.StorageVolume(projectName, bucketName), volConfig)
+}
+
+// validateBucket validates the bucket name and config.
+func (b *lxdBackend) validateBucket(bucketName string, config map[string]string) error {
+	supported := false
+	for _, volType := range b.driver.Info().VolumeTypes {
+		if volType == drivers.VolumeTypeBucket {
+			supported = true
+			break
+		}
+	}
+
+	if !supported {
+		return fmt.Errorf("Storage pool driver %q doesn't support buckets", b.driver.Info().Name)
+	}
+
+	err := ValidBucketName(bucketName)
+	if err != nil {
+		return err
+	}
+
+	for k, v := range config {
+		if strings.HasPrefix(k, "user.") {
+			continue
+		}
+
+		if k != "size" {
+			return fmt.Errorf("Invalid bucket option %q", k)
+		}
+
+		err := validate.Optional(validate.IsSize)(v)
+		if err != nil {
+			return errors.Wrapf(err, "Invalid value for bucket option %q", k)
+		}
+	}
+
+	return nil
+}
+
+// CreateBucket creates a bucket backed by a new storage volume.
+func (b *lxdBackend) CreateBucket(projectName string, bucket api.StorageBucketsPost, op *operations.Operation) error {
+	logger := logging.AddContext(b.logger, log.Ctx{"project": projectName, "bucketName": bucket.Name, "desc": bucket.Description, "config": bucket.Config})
+	logger.Debug("CreateBucket started")
+	defer logger.Debug("CreateBucket finished")
+
+	err := b.validateBucket(bucket.Name, bucket.Config)
+	if err != nil {
+		return err
+	}
+
+	vol := b.bucketVolume(projectName, bucket.Name, bucket.Config)
+	err = b.driver.ValidateVolume(vol, false)
+	if err != nil {
+		return err
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	// Create database entry for new bucket.
+	bucketID, err := b.state.Cluster.CreateStoragePoolBucket(projectName, b.ID(), &bucket)
+	if err != nil {
+		return err
+	}
+
+	revert.Add(func() { b.state.Cluster.DeleteStoragePoolBucket(bucketID) })
+
+	// Create the empty bucket volume on the storage device.
+	err = b.driver.CreateVolume(vol, nil, op)
+	if err != nil {
+		return err
+	}
+
+	revert.Success()
+	return nil
+}
+
+// UpdateBucket updates the description and config of a bucket, resizing the backing volume if needed.
+func (b *lxdBackend) UpdateBucket(projectName string, bucketName string, bucket api.StorageBucketPut, op *operations.Operation) error {
+	logger := logging.AddContext(b.logger, log.Ctx{"project": projectName, "bucketName": bucketName, "newDesc": bucket.Description, "newConfig": bucket.Config})
+	logger.Debug("UpdateBucket started")
+	defer logger.Debug("UpdateBucket finished")
+
+	err := b.validateBucket(bucketName, bucket.Config)
+	if err != nil {
+		return err
+	}
+
+	bucketID, curBucket, err := b.state.Cluster.GetStoragePoolBucket(projectName, b.ID(), bucketName)
+	if err != nil {
+		return err
+	}
+
+	// Apply the quota change to the backing volume.
+	if curBucket.Config["size"] != bucket.Config["size"] {
+		curVol := b.bucketVolume(projectName, bucketName, curBucket.Config)
+		err = b.driver.UpdateVolume(curVol, map[string]string{"size": bucket.Config["size"]})
+		if err != nil {
+			return err
+		}
+	}
+
+	return b.state.Cluster.UpdateStoragePoolBucket(bucketID, &bucket)
+}
+
+// DeleteBucket deletes a bucket, its keys and the backing storage volume.
+func (b *lxdBackend) DeleteBucket(projectName string, bucketName string, op *operations.Operation) error {
+	logger := logging.AddContext(b.logger, log.Ctx{"project": projectName, "bucketName": bucketName})
+	logger.Debug("DeleteBucket started")
+	defer logger.Debug("DeleteBucket finished")
+
+	bucketID, _, err := b.state.Cluster.GetStoragePoolBucket(projectName, b.ID(), bucketName)
+	if err != nil {
+		return err
+	}
+
+	// There's no need to pass config as it's not needed when deleting a volume.
+	vol := b.bucketVolume(projectName, bucketName, nil)
+
+	if b.driver.HasVolume(vol) {
+		_, err = b.driver.UnmountVolume(vol, op)
+		if err != nil {
+			return err
+		}
+
+		err = b.driver.DeleteVolume(vol, op)
+		if err != nil {
+			return err
+		}
+	}
+
+	return b.state.Cluster.DeleteStoragePoolBucket(bucketID)
+}
+
+// MountBucket mounts the storage volume backing a bucket.
+func (b *lxdBackend) MountBucket(projectName string, bucketName string, op *operations.Operation) (bool, error) {
+	logger := logging.AddContext(b.logger, log.Ctx{"project": projectName, "bucketName": bucketName})
+	logger.Debug("MountBucket started")
+	defer logger.Debug("MountBucket finished")
+
+	_, bucket, err := b.state.Cluster.GetStoragePoolBucket(projectName, b.ID(), bucketName)
+	if err != nil {
+		return false, err
+	}
+
+	vol := b.bucketVolume(projectName, bucketName, bucket.Config)
+
+	return b.driver.MountVolume(vol, op)
+}
+
+// GetBucketUsage returns the disk space used by the bucket.
+func (b *lxdBackend) GetBucketUsage(projectName string, bucketName string) (int64, error) {
+	// There's no need to pass config as it's not needed when getting the volume usage.
+	vol := b.bucketVolume(projectName, bucketName, nil)
+
+	return b.driver.GetVolumeUsage(vol)
+}
+
 func (b *lxdBackend) createStorageStructure(path string) error {
 	for _, volType := range b.driver.Info().VolumeTypes {
 		for _, name := range drivers.BaseDirectories[volType] {
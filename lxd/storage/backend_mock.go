@@ -79,6 +79,10 @@ func (b *mockBackend) CreateInstanceFromCopy(inst instance.Instance, src instanc
 	return nil
 }
 
+func (b *mockBackend) MoveInstanceFromPool(inst instance.Instance, srcPool Pool, op *operations.Operation) error {
+	return nil
+}
+
 func (b *mockBackend) CreateInstanceFromImage(inst instance.Instance, fingerprint string, op *operations.Operation) error {
 	return nil
 }
@@ -115,7 +119,7 @@ func (b *mockBackend) RefreshInstance(i instance.Instance, src instance.Instance
 	return nil
 }
 
-func (b *mockBackend) BackupInstance(inst instance.Instance, tarWriter *instancewriter.InstanceTarWriter, optimized bool, snapshots bool, op *operations.Operation) error {
+func (b *mockBackend) BackupInstance(inst instance.Instance, tarWriter *instancewriter.InstanceTarWriter, optimized bool, snapshots bool, parent string, op *operations.Operation) error {
 	return nil
 }
 
@@ -123,7 +127,11 @@ func (b *mockBackend) GetInstanceUsage(inst instance.Instance) (int64, error) {
 	return 0, nil
 }
 
-func (b *mockBackend) SetInstanceQuota(inst instance.Instance, size string, op *operations.Operation) error {
+func (b *mockBackend) GetInstanceVolumeState(inst instance.Instance) (*api.StorageVolumeState, error) {
+	return nil, nil
+}
+
+func (b *mockBackend) SetInstanceQuota(inst instance.Instance, size string, vmStateSize string, op *operations.Operation) error {
 	return nil
 }
 
@@ -187,6 +195,10 @@ func (b *mockBackend) CreateCustomVolumeFromCopy(projectName string, volName str
 	return nil
 }
 
+func (b *mockBackend) MoveCustomVolumeFromPool(projectName string, volName string, srcPool Pool, op *operations.Operation) error {
+	return nil
+}
+
 func (b *mockBackend) RenameCustomVolume(projectName string, volName string, newName string, op *operations.Operation) error {
 	return nil
 }
@@ -215,6 +227,10 @@ func (b *mockBackend) GetCustomVolumeUsage(projectName string, volName string) (
 	return 0, nil
 }
 
+func (b *mockBackend) GetCustomVolumeState(projectName string, volName string) (*api.StorageVolumeState, error) {
+	return nil, nil
+}
+
 func (b *mockBackend) MountCustomVolume(projectName string, volName string, op *operations.Operation) (bool, error) {
 	return true, nil
 }
@@ -223,6 +239,14 @@ func (b *mockBackend) UnmountCustomVolume(projectName string, volName string, op
 	return true, nil
 }
 
+func (b *mockBackend) BackupCustomVolume(projectName string, volName string, tarWriter *instancewriter.InstanceTarWriter, optimized bool, snapshots bool, op *operations.Operation) error {
+	return nil
+}
+
+func (b *mockBackend) CreateCustomVolumeFromBackup(srcBackup backup.Info, srcData io.ReadSeeker, op *operations.Operation) error {
+	return nil
+}
+
 func (b *mockBackend) CreateCustomVolumeSnapshot(projectName string, volName string, newSnapshotName string, expiryDate time.Time, op *operations.Operation) error {
 	return nil
 }
@@ -242,3 +266,23 @@ func (b *mockBackend) UpdateCustomVolumeSnapshot(projectName string, volName str
 func (b *mockBackend) RestoreCustomVolume(projectName string, volName string, snapshotName string, op *operations.Operation) error {
 	return nil
 }
+
+func (b *mockBackend) CreateBucket(projectName string, bucket api.StorageBucketsPost, op *operations.Operation) error {
+	return nil
+}
+
+func (b *mockBackend) UpdateBucket(projectName string, bucketName string, bucket api.StorageBucketPut, op *operations.Operation) error {
+	return nil
+}
+
+func (b *mockBackend) DeleteBucket(projectName string, bucketName string, op *operations.Operation) error {
+	return nil
+}
+
+func (b *mockBackend) MountBucket(projectName string, bucketName string, op *operations.Operation) (bool, error) {
+	return true, nil
+}
+
+func (b *mockBackend) GetBucketUsage(projectName string, bucketName string) (int64, error) {
+	return 0, nil
+}
@@ -36,6 +36,7 @@ func (d *btrfs) load() error {
 		"storage_zfs_volmode":                      nil,
 		"storage_rename_custom_volume_add_project": nil,
 		"storage_lvm_skipactivation":               nil,
+		"storage_create_buckets":                   nil,
 	}
 
 	// Done if previously loaded.
@@ -78,7 +79,7 @@ func (d *btrfs) Info() Info {
 		OptimizedBackupHeader: true,
 		PreservesInodes:       !d.state.OS.RunningInUserNS,
 		Remote:                d.isRemote(),
-		VolumeTypes:           []VolumeType{VolumeTypeCustom, VolumeTypeImage, VolumeTypeContainer, VolumeTypeVM},
+		VolumeTypes:           []VolumeType{VolumeTypeCustom, VolumeTypeImage, VolumeTypeContainer, VolumeTypeVM, VolumeTypeBucket},
 		BlockBacking:          false,
 		RunningQuotaResize:    true,
 		RunningSnapshotFreeze: false,
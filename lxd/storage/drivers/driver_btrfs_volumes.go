@@ -115,7 +115,17 @@ func (d *btrfs) CreateVolumeFromBackup(vol Volume, srcBackup backup.Info, srcDat
 		return genericVFSBackupUnpack(d, vol, srcBackup.Snapshots, srcData, op)
 	}
 
-	if d.HasVolume(vol) {
+	if srcBackup.Parent != "" {
+		// Incremental backups are received on top of the volume restored from the parent backup.
+		parentSnapshot, err := vol.NewSnapshot(srcBackup.ParentSnapshot)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if !d.HasVolume(vol) || !d.HasVolume(parentSnapshot) {
+			return nil, nil, fmt.Errorf("Cannot restore incremental backup, parent snapshot %q doesn't exist on target", srcBackup.ParentSnapshot)
+		}
+	} else if d.HasVolume(vol) {
 		return nil, nil, fmt.Errorf("Cannot restore volume, already exists on target")
 	}
 
@@ -131,8 +141,10 @@ func (d *btrfs) CreateVolumeFromBackup(vol Volume, srcBackup backup.Info, srcDat
 			d.DeleteVolumeSnapshot(snapVol, op)
 		}
 
-		// And lastly the main volume.
-		d.DeleteVolume(vol, op)
+		// And lastly the main volume (unless it existed before an incremental restore).
+		if srcBackup.Parent == "" {
+			d.DeleteVolume(vol, op)
+		}
 	}
 	// Only execute the revert function if we have had an error internally.
 	revert.Add(revertHook)
@@ -273,6 +285,8 @@ func (d *btrfs) CreateVolumeFromBackup(vol Volume, srcBackup backup.Info, srcDat
 				if vol.contentType == ContentTypeFS {
 					srcFilePrefix = fmt.Sprintf("%s-config", snapName)
 				}
+			} else if vol.volType == VolumeTypeCustom {
+				snapDir = "volume-snapshots"
 			}
 
 			srcFilePrefix = filepath.Join(snapDir, srcFilePrefix)
@@ -291,6 +305,24 @@ func (d *btrfs) CreateVolumeFromBackup(vol Volume, srcBackup backup.Info, srcDat
 		} else {
 			srcFilePrefix = "virtual-machine"
 		}
+	} else if vol.volType == VolumeTypeCustom {
+		srcFilePrefix = "volume"
+	}
+
+	// For incremental backups, move the existing main volume out of the way so it can be replaced.
+	oldSubvolume := ""
+	if srcBackup.Parent != "" {
+		target := vol.MountPath()
+		oldSubvolume = fmt.Sprintf("%s%s", target, tmpVolSuffix)
+		err = os.Rename(target, oldSubvolume)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "Failed to rename %q to %q", target, oldSubvolume)
+		}
+
+		revert.Add(func() {
+			d.deleteSubvolume(target, true)
+			os.Rename(oldSubvolume, target)
+		})
 	}
 
 	err = unpackVolume(vol, srcFilePrefix)
@@ -317,6 +349,14 @@ func (d *btrfs) CreateVolumeFromBackup(vol Volume, srcBackup backup.Info, srcDat
 		}
 	}
 
+	// Remove the replaced main volume. Should always be the last step to allow revert.
+	if oldSubvolume != "" {
+		err = d.deleteSubvolume(oldSubvolume, true)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
 	revert.Success()
 	return nil, revertHook, nil
 }
@@ -599,7 +639,7 @@ func (d *btrfs) ValidateVolume(vol Volume, removeUnknownKeys bool) error {
 
 // UpdateVolume applies config changes to the volume.
 func (d *btrfs) UpdateVolume(vol Volume, changedConfig map[string]string) error {
-	if vol.volType != VolumeTypeCustom {
+	if vol.volType != VolumeTypeCustom && vol.volType != VolumeTypeBucket {
 		return ErrNotSupported
 	}
 
@@ -637,6 +677,11 @@ func (d *btrfs) SetVolumeQuota(vol Volume, size string, op *operations.Operation
 		return err
 	}
 
+	// VM filesystem volumes share their directory with the VM's block file, so no quota can be applied.
+	if vol.volType == VolumeTypeVM && vol.contentType == ContentTypeFS {
+		return nil
+	}
+
 	// For VM block files, resize the file if needed.
 	if vol.contentType == ContentTypeBlock {
 		// Do nothing if size isn't specified.
@@ -937,7 +982,7 @@ func (d *btrfs) MigrateVolume(vol Volume, conn io.ReadWriteCloser, volSrcArgs *m
 
 // BackupVolume copies a volume (and optionally its snapshots) to a specified target path.
 // This driver does not support optimized backups.
-func (d *btrfs) BackupVolume(vol Volume, tarWriter *instancewriter.InstanceTarWriter, optimized bool, snapshots bool, op *operations.Operation) error {
+func (d *btrfs) BackupVolume(vol Volume, tarWriter *instancewriter.InstanceTarWriter, optimized bool, snapshots bool, parent string, op *operations.Operation) error {
 	// Handle the non-optimized tarballs through the generic packer.
 	if !optimized {
 		// Because the generic backup method will not take a consistent backup if files are being modified
@@ -990,6 +1035,14 @@ func (d *btrfs) BackupVolume(vol Volume, tarWriter *instancewriter.InstanceTarWr
 		if err != nil {
 			return err
 		}
+
+		// For incremental backups, only include the snapshots taken after the parent snapshot.
+		if parent != "" {
+			volSnapshots, err = backupSnapshotsAfter(volSnapshots, parent)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
 	// Generate driver restoration header.
@@ -1129,6 +1182,11 @@ func (d *btrfs) BackupVolume(vol Volume, tarWriter *instancewriter.InstanceTarWr
 
 	// Backup snapshots if populated.
 	lastVolPath := "" // Used as parent for differential exports.
+	if parent != "" {
+		parentVol, _ := vol.NewSnapshot(parent)
+		lastVolPath = parentVol.MountPath()
+	}
+
 	for _, snapName := range volSnapshots {
 		snapVol, _ := vol.NewSnapshot(snapName)
 
@@ -1140,6 +1198,8 @@ func (d *btrfs) BackupVolume(vol Volume, tarWriter *instancewriter.InstanceTarWr
 			if vol.contentType == ContentTypeFS {
 				fileName = fmt.Sprintf("%s-config", snapName)
 			}
+		} else if vol.volType == VolumeTypeCustom {
+			snapDir = "volume-snapshots"
 		}
 
 		fileNamePrefix := filepath.Join(snapDir, fileName)
@@ -1187,6 +1247,8 @@ func (d *btrfs) BackupVolume(vol Volume, tarWriter *instancewriter.InstanceTarWr
 		} else {
 			fileNamePrefix = "virtual-machine"
 		}
+	} else if vol.volType == VolumeTypeCustom {
+		fileNamePrefix = "volume"
 	}
 
 	err = addVolume(vol, targetVolume, lastVolPath, fileNamePrefix)
@@ -35,6 +35,7 @@ func (d *ceph) load() error {
 		"storage_zfs_volmode":                      nil,
 		"storage_rename_custom_volume_add_project": nil,
 		"storage_lvm_skipactivation":               nil,
+		"storage_create_buckets":                   nil,
 	}
 
 	// Done if previously loaded.
@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/pborman/uuid"
+	"github.com/pkg/errors"
 
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/shared"
@@ -138,6 +139,82 @@ func (d *ceph) rbdMapVolume(vol Volume) (string, error) {
 	return strings.TrimSpace(devPath), nil
 }
 
+// rbdFenceVolume blocklists the clients still watching a given RBD storage volume, such as the one of a cluster
+// member which went away with the volume mapped, so that they can't write to it anymore.
+func (d *ceph) rbdFenceVolume(vol Volume) error {
+	out, err := shared.RunCommand(
+		"rbd",
+		"--id", d.config["ceph.user.name"],
+		"--cluster", d.config["ceph.cluster_name"],
+		"--pool", d.config["ceph.osd.pool_name"],
+		"status",
+		"--format", "json",
+		d.getRBDVolumeName(vol, "", false, false))
+	if err != nil {
+		return err
+	}
+
+	addresses, err := rbdParseWatchers(out)
+	if err != nil {
+		return err
+	}
+
+	for _, address := range addresses {
+		d.logger.Warn("Blocklisting RBD client", log.Ctx{"volume": vol.name, "address": address})
+
+		err = d.osdBlocklistClient(address)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to blocklist RBD client %q", address)
+		}
+	}
+
+	return nil
+}
+
+// rbdParseWatchers returns the addresses of the clients watching an RBD storage volume from the JSON output of
+// "rbd status".
+func rbdParseWatchers(status string) ([]string, error) {
+	var res struct {
+		Watchers []struct {
+			Address string `json:"address"`
+		} `json:"watchers"`
+	}
+
+	err := json.Unmarshal([]byte(status), &res)
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to parse RBD volume status")
+	}
+
+	addresses := []string{}
+	for _, watcher := range res.Watchers {
+		if !shared.StringInSlice(watcher.Address, addresses) {
+			addresses = append(addresses, watcher.Address)
+		}
+	}
+
+	return addresses, nil
+}
+
+// osdBlocklistClient blocklists a given client address.
+// Ceph releases older than Pacific call the blocklist a blacklist.
+func (d *ceph) osdBlocklistClient(address string) error {
+	var err error
+	for _, cmd := range []string{"blocklist", "blacklist"} {
+		_, err = shared.RunCommand("ceph",
+			"--name", fmt.Sprintf("client.%s", d.config["ceph.user.name"]),
+			"--cluster", d.config["ceph.cluster_name"],
+			"osd",
+			cmd,
+			"add",
+			address)
+		if err == nil {
+			return nil
+		}
+	}
+
+	return err
+}
+
 // rbdUnmapVolume unmaps a given RBD storage volume.
 // This is a precondition in order to delete an RBD storage volume can.
 func (d *ceph) rbdUnmapVolume(vol Volume, unmapUntilEINVAL bool) error {
@@ -145,3 +145,29 @@ func Example_ceph_parseParent() {
 	// pool container test-project_c4  block  <nil>
 	// pool zombie_container test-project_c1_28e7a7ab-740a-490c-8118-7caf7810f83b  filesystem zombie_snapshot_1027f4ab-de11-4cee-8015-bd532a1fed76 <nil>
 }
+
+func Test_rbdParseWatchers(t *testing.T) {
+	addresses, err := rbdParseWatchers(`{"watchers":[{"address":"10.0.0.1:0/1234","client":4567,"cookie":18446462598732840961},{"address":"10.0.0.1:0/1234","client":4567,"cookie":18446462598732840962},{"address":"10.0.0.2:0/5678","client":8910,"cookie":18446462598732840963}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fmt.Sprintf("%v", addresses) != "[10.0.0.1:0/1234 10.0.0.2:0/5678]" {
+		t.Errorf("Unexpected watchers %v", addresses)
+	}
+
+	// Volumes which aren't mapped have no watchers.
+	addresses, err = rbdParseWatchers(`{"watchers":[]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(addresses) != 0 {
+		t.Errorf("Unexpected watchers %v", addresses)
+	}
+
+	_, err = rbdParseWatchers("rbd: error opening image")
+	if err == nil {
+		t.Error("Expected an error for an invalid status")
+	}
+}
@@ -1038,6 +1038,15 @@ func (d *ceph) MigrateVolume(vol Volume, conn io.ReadWriteCloser, volSrcArgs *mi
 				return err
 			}
 
+			// Any client still using the volume is on a member which the instance is moved away from without
+			// its help, fence it off before the volume gets used elsewhere.
+			if volSrcArgs.Fence {
+				err = d.rbdFenceVolume(vol)
+				if err != nil {
+					return errors.Wrap(err, "Failed to fence volume")
+				}
+			}
+
 			// Rename volume.
 			if vol.name != data {
 				err = d.rbdRenameVolume(vol, data)
@@ -1146,7 +1155,7 @@ func (d *ceph) MigrateVolume(vol Volume, conn io.ReadWriteCloser, volSrcArgs *mi
 }
 
 // BackupVolume creates an exported version of a volume.
-func (d *ceph) BackupVolume(vol Volume, tarWriter *instancewriter.InstanceTarWriter, optimized bool, snapshots bool, op *operations.Operation) error {
+func (d *ceph) BackupVolume(vol Volume, tarWriter *instancewriter.InstanceTarWriter, optimized bool, snapshots bool, parent string, op *operations.Operation) error {
 	return genericVFSBackupVolume(d, vol, tarWriter, snapshots, op)
 }
 
@@ -120,9 +120,9 @@ func (d *common) validateVolume(vol Volume, driverRules map[string]func(value st
 		}
 	}
 
-	// If volume type is not custom, don't allow "size" property.
-	if vol.volType != VolumeTypeCustom && vol.config["size"] != "" {
-		return fmt.Errorf("Volume %q property is only valid for custom volume types", "size")
+	// If volume type is not custom or bucket, don't allow "size" property.
+	if vol.volType != VolumeTypeCustom && vol.volType != VolumeTypeBucket && vol.config["size"] != "" {
+		return fmt.Errorf("Volume %q property is only valid for custom and bucket volume types", "size")
 	}
 
 	return nil
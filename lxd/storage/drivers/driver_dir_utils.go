@@ -29,7 +29,7 @@ func (d *dir) setupInitialQuota(vol Volume) (func(), error) {
 	volPath := vol.MountPath()
 
 	// Get the volume ID for the new volume, which is used to set project quota.
-	volID, err := d.getVolID(vol.volType, vol.name)
+	volID, err := d.quotaVolID(vol)
 	if err != nil {
 		return nil, err
 	}
@@ -57,6 +57,16 @@ func (d *dir) setupInitialQuota(vol Volume) (func(), error) {
 	return revertFunc, nil
 }
 
+// quotaVolID returns the volume ID used to generate the project quota ID of a volume.
+// Buckets aren't tracked as storage volumes in the database, so their quota is enforced by the S3 gateway instead.
+func (d *dir) quotaVolID(vol Volume) (int64, error) {
+	if vol.volType == VolumeTypeBucket {
+		return volIDQuotaSkip, nil
+	}
+
+	return d.getVolID(vol.volType, vol.name)
+}
+
 // deleteQuota removes the project quota for a volID from a path.
 func (d *dir) deleteQuota(path string, volID int64) error {
 	if volID == volIDQuotaSkip {
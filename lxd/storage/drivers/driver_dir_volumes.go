@@ -169,7 +169,7 @@ func (d *dir) DeleteVolume(vol Volume, op *operations.Operation) error {
 	}
 
 	// Get the volume ID for the volume, which is used to remove project quota.
-	volID, err := d.getVolID(vol.volType, vol.name)
+	volID, err := d.quotaVolID(vol)
 	if err != nil {
 		return err
 	}
@@ -232,11 +232,16 @@ func (d *dir) GetVolumeUsage(vol Volume) (int64, error) {
 	}
 
 	// Get the volume ID for the volume to access quota.
-	volID, err := d.getVolID(vol.volType, vol.name)
+	volID, err := d.quotaVolID(vol)
 	if err != nil {
 		return -1, err
 	}
 
+	// Buckets don't have a project quota.
+	if volID == volIDQuotaSkip {
+		return -1, ErrNotSupported
+	}
+
 	projectID := d.quotaProjectID(volID)
 
 	// Get project quota used.
@@ -257,6 +262,11 @@ func (d *dir) SetVolumeQuota(vol Volume, size string, op *operations.Operation)
 		return err
 	}
 
+	// VM filesystem volumes share their directory with the VM's block file, so no quota can be applied.
+	if vol.volType == VolumeTypeVM && vol.contentType == ContentTypeFS {
+		return nil
+	}
+
 	// For VM block files, resize the file if needed.
 	if vol.contentType == ContentTypeBlock {
 		// Do nothing if size isn't specified.
@@ -288,7 +298,7 @@ func (d *dir) SetVolumeQuota(vol Volume, size string, op *operations.Operation)
 	}
 
 	// For non-VM block volumes, set filesystem quota.
-	volID, err := d.getVolID(vol.volType, vol.name)
+	volID, err := d.quotaVolID(vol)
 	if err != nil {
 		return err
 	}
@@ -334,7 +344,7 @@ func (d *dir) MigrateVolume(vol Volume, conn io.ReadWriteCloser, volSrcArgs *mig
 
 // BackupVolume copies a volume (and optionally its snapshots) to a specified target path.
 // This driver does not support optimized backups.
-func (d *dir) BackupVolume(vol Volume, tarWriter *instancewriter.InstanceTarWriter, optimized bool, snapshots bool, op *operations.Operation) error {
+func (d *dir) BackupVolume(vol Volume, tarWriter *instancewriter.InstanceTarWriter, optimized bool, snapshots bool, parent string, op *operations.Operation) error {
 	return genericVFSBackupVolume(d, vol, tarWriter, snapshots, op)
 }
 
@@ -0,0 +1,416 @@
+package drivers
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/migration"
+	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/lxd/revert"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/units"
+	"github.com/lxc/lxd/shared/validate"
+)
+
+var dmthinLoaded bool
+var dmthinVersion string
+
+var dmthinAllowedFilesystems = []string{"btrfs", "ext4", "xfs"}
+
+type dmthin struct {
+	common
+}
+
+func (d *dmthin) load() error {
+	// Register the patches.
+	d.patches = map[string]func() error{
+		"storage_create_vm":                        nil,
+		"storage_zfs_mount":                        nil,
+		"storage_create_vm_again":                  nil,
+		"storage_zfs_volmode":                      nil,
+		"storage_rename_custom_volume_add_project": nil,
+		"storage_lvm_skipactivation":               nil,
+		"storage_create_buckets":                   nil,
+	}
+
+	// Done if previously loaded.
+	if dmthinLoaded {
+		return nil
+	}
+
+	// Load the kernel module.
+	err := util.LoadModule("dm_thin_pool")
+	if err != nil {
+		return errors.Wrapf(err, "Error loading %q module", "dm_thin_pool")
+	}
+
+	// Validate the required binaries.
+	for _, tool := range []string{"dmsetup"} {
+		_, err := exec.LookPath(tool)
+		if err != nil {
+			return fmt.Errorf("Required tool %q is missing", tool)
+		}
+	}
+
+	// Detect and record the version.
+	if dmthinVersion == "" {
+		output, err := shared.RunCommand("dmsetup", "version")
+		if err != nil {
+			return errors.Wrapf(err, "Error getting device-mapper version")
+		}
+
+		for _, line := range strings.Split(output, "\n") {
+			fields := strings.SplitN(line, ":", 2)
+			if len(fields) < 2 {
+				continue
+			}
+
+			if dmthinVersion != "" {
+				dmthinVersion += " / "
+			}
+
+			// Strip the release date from the library version.
+			dmthinVersion += strings.Fields(fields[1])[0]
+		}
+	}
+
+	dmthinLoaded = true
+	return nil
+}
+
+// Info returns info about the driver and its environment.
+func (d *dmthin) Info() Info {
+	return Info{
+		Name:                  "dmthin",
+		Version:               dmthinVersion,
+		OptimizedImages:       true,
+		OptimizedBackups:      true,
+		PreservesInodes:       false,
+		Remote:                d.isRemote(),
+		VolumeTypes:           []VolumeType{VolumeTypeCustom, VolumeTypeImage, VolumeTypeContainer, VolumeTypeVM, VolumeTypeBucket},
+		BlockBacking:          true,
+		RunningQuotaResize:    false,
+		RunningSnapshotFreeze: false,
+		DirectIO:              true,
+		MountedRoot:           false,
+	}
+}
+
+// Create creates the storage pool on the storage device.
+func (d *dmthin) Create() error {
+	d.config["volatile.initial_source"] = d.config["source"]
+
+	defaultSource := loopFilePath(d.name)
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	if d.config["source"] == "" || d.config["source"] == defaultSource {
+		// We are using an LXD internal loopback file.
+		d.config["source"] = defaultSource
+
+		size, err := units.ParseByteSizeString(d.config["size"])
+		if err != nil {
+			return err
+		}
+
+		if shared.PathExists(d.config["source"]) {
+			return fmt.Errorf("Source file location already exists")
+		}
+
+		err = ensureSparseFile(d.config["source"], size)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to create sparse file %q", d.config["source"])
+		}
+
+		revert.Add(func() { os.Remove(d.config["source"]) })
+	} else if filepath.IsAbs(d.config["source"]) {
+		// We are using an existing block device.
+		d.config["source"] = shared.HostPath(d.config["source"])
+
+		// Size is ignored as the block device is a fixed size.
+		d.config["size"] = ""
+
+		if !shared.IsBlockdevPath(d.config["source"]) {
+			return fmt.Errorf("Custom loop file locations are not supported")
+		}
+	} else {
+		return fmt.Errorf("Invalid source property")
+	}
+
+	f, err := os.OpenFile(d.config["source"], os.O_RDWR, 0)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to open %q", d.config["source"])
+	}
+	defer f.Close()
+
+	// Refuse to overwrite an existing pool.
+	_, err = dmthinReadHeader(f)
+	if err == nil {
+		return fmt.Errorf("Source %q is already used by a dmthin storage pool", d.config["source"])
+	}
+
+	devSize, err := BlockDiskSizeBytes(d.config["source"])
+	if err != nil {
+		return err
+	}
+
+	blockSize := int64(dmthinDefaultBlockSize)
+	if d.config["dmthin.block_size"] != "" {
+		blockSize, err = units.ParseByteSizeString(d.config["dmthin.block_size"])
+		if err != nil {
+			return err
+		}
+	}
+
+	metaSize := dmthinMetadataSize(devSize-dmthinHeaderSize, blockSize)
+	if d.config["dmthin.metadata_size"] != "" {
+		metaSize, err = units.ParseByteSizeString(d.config["dmthin.metadata_size"])
+		if err != nil {
+			return err
+		}
+
+		metaSize = dmthinRoundUp(metaSize, 1024*1024)
+	}
+
+	dataSize := (devSize - dmthinHeaderSize - metaSize) / blockSize * blockSize
+	if dataSize < dmthinMinDataBlocks*blockSize {
+		return fmt.Errorf("Source %q is too small for a dmthin storage pool", d.config["source"])
+	}
+
+	// Wipe the start of the metadata region so that the kernel formats fresh metadata on first activation.
+	_, err = f.WriteAt(make([]byte, 4096), dmthinHeaderSize)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to wipe metadata region of %q", d.config["source"])
+	}
+
+	header := &dmthinHeader{
+		BlockSize:    blockSize,
+		MetadataSize: metaSize,
+		DataSize:     dataSize,
+		Volumes:      map[string]dmthinVolume{},
+	}
+
+	err = dmthinWriteHeader(f, header)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to write dmthin header to %q", d.config["source"])
+	}
+
+	d.logger.Debug("Thin pool created", log.Ctx{"source": d.config["source"], "block_size": blockSize, "metadata_size": metaSize, "data_size": dataSize})
+
+	revert.Success()
+	return nil
+}
+
+// Delete removes the storage pool from the storage device.
+func (d *dmthin) Delete(op *operations.Operation) error {
+	// Deactivate the pool and release the loop device if needed.
+	_, err := d.Unmount()
+	if err != nil {
+		return err
+	}
+
+	if shared.IsBlockdevPath(d.config["source"]) {
+		// Wipe the header so that the device isn't detected as being in use anymore.
+		f, err := os.OpenFile(d.config["source"], os.O_RDWR, 0)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to open %q", d.config["source"])
+		}
+		defer f.Close()
+
+		_, err = f.WriteAt(make([]byte, dmthinHeaderSize), 0)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to wipe dmthin header of %q", d.config["source"])
+		}
+
+		err = f.Sync()
+		if err != nil {
+			return err
+		}
+
+		d.logger.Debug("Thin pool header wiped", log.Ctx{"source": d.config["source"]})
+	} else if filepath.IsAbs(d.config["source"]) {
+		// This is a loop file so remove it.
+		err = os.Remove(d.config["source"])
+		if err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "Error removing dmthin pool loop file %q", d.config["source"])
+		}
+
+		d.logger.Debug("Thin pool loop file removed", log.Ctx{"file_name": d.config["source"]})
+	}
+
+	// Wipe everything in the storage pool directory.
+	err = wipeDirectory(GetPoolMountPath(d.name))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Validate checks that all provide keys are supported and that no conflicting or missing configuration is present.
+func (d *dmthin) Validate(config map[string]string) error {
+	rules := map[string]func(value string) error{
+		"dmthin.block_size":          validate.Optional(dmthinValidateBlockSize),
+		"dmthin.metadata_size":       validate.Optional(validate.IsSize),
+		"volume.block.mount_options": validate.IsAny,
+		"volume.block.filesystem": func(value string) error {
+			if value == "" {
+				return nil
+			}
+			return validate.IsOneOf(value, dmthinAllowedFilesystems)
+		},
+	}
+
+	return d.validatePool(config, rules)
+}
+
+// Update applies any driver changes required from a configuration change.
+func (d *dmthin) Update(changedConfig map[string]string) error {
+	for _, key := range []string{"dmthin.block_size", "dmthin.metadata_size"} {
+		_, changed := changedConfig[key]
+		if changed {
+			return fmt.Errorf("%s cannot be changed", key)
+		}
+	}
+
+	return nil
+}
+
+// Mount activates the thin pool (opening the loop device first for loop file backed pools).
+func (d *dmthin) Mount() (bool, error) {
+	if d.config["source"] == "" {
+		return false, fmt.Errorf("Cannot mount pool as %q is not specified", "source")
+	}
+
+	// Nothing to do if the thin pool is already active.
+	if shared.PathExists(dmthinDevPath(d.dmName("pool"))) {
+		return false, nil
+	}
+
+	devPath := d.config["source"]
+	if !shared.IsBlockdevPath(devPath) {
+		loopFile, err := d.openLoopFile(devPath)
+		if err != nil {
+			return false, err
+		}
+		defer loopFile.Close()
+
+		devPath = loopFile.Name()
+	}
+
+	header, err := d.readHeader()
+	if err != nil {
+		return false, err
+	}
+
+	err = d.activatePool(devPath, header)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Unmount deactivates the thin pool and its volumes (releasing the loop device for loop file backed pools).
+func (d *dmthin) Unmount() (bool, error) {
+	active := shared.PathExists(dmthinDevPath(d.dmName("pool")))
+	loopBacked := filepath.IsAbs(d.config["source"]) && !shared.IsBlockdevPath(d.config["source"])
+
+	if active {
+		header, err := d.readHeader()
+		if err != nil {
+			return false, err
+		}
+
+		// Deactivate any remaining thin volumes.
+		for _, volume := range header.Volumes {
+			name := d.volDevName(volume.ID)
+			if !shared.PathExists(dmthinDevPath(name)) {
+				continue
+			}
+
+			err = d.dmsetupRemove(name)
+			if err != nil {
+				return false, err
+			}
+		}
+
+		err = d.deactivatePool()
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if loopBacked && shared.PathExists(d.config["source"]) {
+		err := releaseLoopDev(d.config["source"])
+		if err != nil {
+			return false, errors.Wrapf(err, "Failed releasing loop file device %q", d.config["source"])
+		}
+
+		return true, nil
+	}
+
+	return active, nil
+}
+
+// GetResources returns utilisation and space info about the pool.
+func (d *dmthin) GetResources() (*api.ResourcesStoragePool, error) {
+	header, err := d.readHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	usedBlocks, totalBlocks, err := d.poolUsage()
+	if err != nil {
+		return nil, err
+	}
+
+	res := api.ResourcesStoragePool{}
+	res.Space.Total = totalBlocks * uint64(header.BlockSize)
+	res.Space.Used = usedBlocks * uint64(header.BlockSize)
+
+	return &res, nil
+}
+
+// MigrationTypes returns the type of transfer methods to be used when doing migrations between pools in
+// preference order.
+func (d *dmthin) MigrationTypes(contentType ContentType, refresh bool) []migration.Type {
+	rsyncFeatures := []string{"xattrs", "delete", "compress", "bidirectional"}
+
+	var rsyncType migration.MigrationFSType
+	if contentType == ContentTypeBlock {
+		rsyncType = migration.MigrationFSType_BLOCK_AND_RSYNC
+	} else {
+		rsyncType = migration.MigrationFSType_RSYNC
+	}
+
+	// When performing a refresh, always use rsync. Sending thin deltas here doesn't make sense since the
+	// target volume's snapshots aren't related to ours.
+	if refresh {
+		return []migration.Type{
+			{
+				FSType:   rsyncType,
+				Features: rsyncFeatures,
+			},
+		}
+	}
+
+	return []migration.Type{
+		{
+			FSType: migration.MigrationFSType_DMTHIN,
+		},
+		{
+			FSType:   rsyncType,
+			Features: rsyncFeatures,
+		},
+	}
+}
@@ -0,0 +1,1424 @@
+package drivers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unsafe"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+
+	"github.com/lxc/lxd/lxd/locking"
+	"github.com/lxc/lxd/lxd/revert"
+	"github.com/lxc/lxd/shared"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/units"
+)
+
+// dmthinHeaderMagic identifies a header slot written by the dmthin driver.
+const dmthinHeaderMagic = "LXDTHIN1"
+
+// dmthinHeaderPrefixSize is the size of the fixed part of a header slot (magic, generation, length and checksum).
+const dmthinHeaderPrefixSize = 24
+
+// dmthinHeaderSlotSize is the size of each of the two header slots. The header is written alternately to each
+// slot so that a valid copy always remains if writing is interrupted.
+const dmthinHeaderSlotSize = 2 * 1024 * 1024
+
+// dmthinHeaderSize is the space reserved at the start of the source device for the header slots.
+const dmthinHeaderSize = 2 * dmthinHeaderSlotSize
+
+// dmthinDefaultBlockSize is the default data block size of the thin pool.
+const dmthinDefaultBlockSize = 64 * 1024
+
+// dmthinMinDataBlocks is the minimum number of data blocks a new pool must have.
+const dmthinMinDataBlocks = 128
+
+// dmthinMaxID is the highest thin device ID supported by the kernel (24 bits).
+const dmthinMaxID = 1<<24 - 1
+
+// dmthinScanSize is the granularity used when looking for zero regions while sending a full volume.
+const dmthinScanSize = 64 * 1024
+
+// dmthinStreamMagic identifies a dmthin volume stream.
+const dmthinStreamMagic = "LXDTHINS"
+
+// dmthinStreamBufferSize is the size of the buffers used when reading and writing volume streams.
+const dmthinStreamBufferSize = 1024 * 1024
+
+// dmthinBLKDISCARD is the BLKDISCARD ioctl request number (_IO(0x12, 119)).
+const dmthinBLKDISCARD = 0x1277
+
+// Record types used in dmthin volume streams.
+const (
+	dmthinRecordEnd byte = iota
+	dmthinRecordData
+	dmthinRecordDiscard
+)
+
+var errDmthinNoHeader = fmt.Errorf("No dmthin header found")
+
+// dmthinHeader is the pool header stored at the start of the source device. It records the layout of the pool
+// and maps volume names to thin device IDs.
+type dmthinHeader struct {
+	Generation   uint64                  `json:"-"`
+	BlockSize    int64                   `json:"block_size"`
+	MetadataSize int64                   `json:"metadata_size"`
+	DataSize     int64                   `json:"data_size"`
+	NextID       uint32                  `json:"next_id"`
+	Volumes      map[string]dmthinVolume `json:"volumes"`
+}
+
+// dmthinVolume is a thin volume entry of the pool header.
+type dmthinVolume struct {
+	ID   uint32 `json:"id"`
+	Size int64  `json:"size"`
+}
+
+// dmthinExtent is a byte range of a volume that is either sent as data or discarded.
+type dmthinExtent struct {
+	offset  int64
+	length  int64
+	discard bool
+}
+
+// dmthinEncodeHeader encodes the header into a header slot.
+func dmthinEncodeHeader(header *dmthinHeader) ([]byte, error) {
+	body, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(body) > dmthinHeaderSlotSize-dmthinHeaderPrefixSize {
+		return nil, fmt.Errorf("Pool header is too large (%d bytes)", len(body))
+	}
+
+	buf := make([]byte, dmthinHeaderPrefixSize+len(body))
+	copy(buf, dmthinHeaderMagic)
+	binary.LittleEndian.PutUint64(buf[8:], header.Generation)
+	binary.LittleEndian.PutUint32(buf[16:], uint32(len(body)))
+	binary.LittleEndian.PutUint32(buf[20:], crc32.ChecksumIEEE(body))
+	copy(buf[dmthinHeaderPrefixSize:], body)
+
+	return buf, nil
+}
+
+// dmthinReadHeaderSlot reads and validates the header slot at the given offset.
+func dmthinReadHeaderSlot(r io.ReaderAt, offset int64) (*dmthinHeader, error) {
+	prefix := make([]byte, dmthinHeaderPrefixSize)
+	_, err := r.ReadAt(prefix, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	if string(prefix[:8]) != dmthinHeaderMagic {
+		return nil, errDmthinNoHeader
+	}
+
+	length := binary.LittleEndian.Uint32(prefix[16:])
+	if length > dmthinHeaderSlotSize-dmthinHeaderPrefixSize {
+		return nil, fmt.Errorf("Invalid header length %d", length)
+	}
+
+	body := make([]byte, length)
+	_, err = r.ReadAt(body, offset+dmthinHeaderPrefixSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if crc32.ChecksumIEEE(body) != binary.LittleEndian.Uint32(prefix[20:]) {
+		return nil, fmt.Errorf("Header checksum mismatch")
+	}
+
+	header := &dmthinHeader{}
+	err = json.Unmarshal(body, header)
+	if err != nil {
+		return nil, err
+	}
+
+	header.Generation = binary.LittleEndian.Uint64(prefix[8:])
+	if header.Volumes == nil {
+		header.Volumes = map[string]dmthinVolume{}
+	}
+
+	return header, nil
+}
+
+// dmthinReadHeader returns the most recent valid header from the header slots.
+func dmthinReadHeader(r io.ReaderAt) (*dmthinHeader, error) {
+	var header *dmthinHeader
+
+	for slot := int64(0); slot < 2; slot++ {
+		slotHeader, err := dmthinReadHeaderSlot(r, slot*dmthinHeaderSlotSize)
+		if err != nil {
+			continue
+		}
+
+		if header == nil || slotHeader.Generation > header.Generation {
+			header = slotHeader
+		}
+	}
+
+	if header == nil {
+		return nil, errDmthinNoHeader
+	}
+
+	return header, nil
+}
+
+// dmthinWriteHeader writes the header into the slot not holding the current generation.
+func dmthinWriteHeader(f *os.File, header *dmthinHeader) error {
+	header.Generation++
+
+	buf, err := dmthinEncodeHeader(header)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.WriteAt(buf, int64(header.Generation%2)*dmthinHeaderSlotSize)
+	if err != nil {
+		return err
+	}
+
+	return f.Sync()
+}
+
+// dmthinRoundUp rounds value up to the next multiple of unit.
+func dmthinRoundUp(value int64, unit int64) int64 {
+	return (value + unit - 1) / unit * unit
+}
+
+// dmthinMetadataSize returns the default metadata size for a pool of the given size and block size.
+func dmthinMetadataSize(size int64, blockSize int64) int64 {
+	// The kernel documentation recommends 48 bytes per data block, double it to leave room for snapshots.
+	metaSize := dmthinRoundUp(2*48*(size/blockSize), 1024*1024)
+
+	// The kernel doesn't use more than ~16GiB of metadata.
+	if metaSize > 15*1024*1024*1024 {
+		metaSize = 15 * 1024 * 1024 * 1024
+	}
+
+	if metaSize < 4*1024*1024 {
+		metaSize = 4 * 1024 * 1024
+	}
+
+	return metaSize
+}
+
+// dmthinValidateBlockSize validates the thin pool data block size.
+func dmthinValidateBlockSize(value string) error {
+	blockSize, err := units.ParseByteSizeString(value)
+	if err != nil {
+		return err
+	}
+
+	if blockSize < 64*1024 || blockSize > 1024*1024*1024 || blockSize%(64*1024) != 0 {
+		return fmt.Errorf("Block size must be a multiple of 64KiB between 64KiB and 1GiB")
+	}
+
+	return nil
+}
+
+// dmthinRoundedSizeBytes rounds the size down to a multiple of 512 bytes as device-mapper requires this.
+func dmthinRoundedSizeBytes(size string) (int64, error) {
+	sizeBytes, err := units.ParseByteSizeString(size)
+	if err != nil {
+		return 0, err
+	}
+
+	const minSizeBytes = 512
+	if sizeBytes < minSizeBytes {
+		sizeBytes = minSizeBytes
+	}
+
+	return sizeBytes / minSizeBytes * minSizeBytes, nil
+}
+
+// dmthinDevPath returns the path to a device-mapper device.
+func dmthinDevPath(name string) string {
+	return filepath.Join("/dev/mapper", name)
+}
+
+// dmName returns the name of one of the device-mapper devices of the pool.
+func (d *dmthin) dmName(suffix string) string {
+	return fmt.Sprintf("lxd-dmthin-%s-%s", d.name, suffix)
+}
+
+// volDevName returns the name of the device-mapper device of a thin volume.
+func (d *dmthin) volDevName(id uint32) string {
+	return d.dmName(fmt.Sprintf("thin%d", id))
+}
+
+// volumeKey returns the name under which the volume is recorded in the pool header.
+func (d *dmthin) volumeKey(vol Volume) string {
+	return fmt.Sprintf("%s/%s/%s", vol.volType, vol.contentType, vol.name)
+}
+
+// openLoopFile opens a loopback file and disable auto detach.
+func (d *dmthin) openLoopFile(source string) (*os.File, error) {
+	if source == "" {
+		return nil, fmt.Errorf("No source property found for the storage pool")
+	}
+
+	if filepath.IsAbs(source) && !shared.IsBlockdevPath(source) {
+		unlock := locking.Lock(OperationLockName(d.name, "", ""))
+		defer unlock()
+
+		// Try to prepare new loop device.
+		loopF, err := PrepareLoopDev(source, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		// Make sure that LO_FLAGS_AUTOCLEAR is unset, so that the loopback device will not
+		// autodestruct on last close.
+		err = UnsetAutoclearOnLoopDev(int(loopF.Fd()))
+		if err != nil {
+			return nil, err
+		}
+
+		return loopF, nil
+	}
+
+	return nil, fmt.Errorf("Source is not loop file")
+}
+
+// readHeader reads the pool header from the source device.
+func (d *dmthin) readHeader() (*dmthinHeader, error) {
+	f, err := os.Open(d.config["source"])
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to open %q", d.config["source"])
+	}
+	defer f.Close()
+
+	header, err := dmthinReadHeader(f)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to read dmthin header from %q", d.config["source"])
+	}
+
+	return header, nil
+}
+
+// updateHeader runs the update function against the current pool header and writes the result back if the
+// function succeeded. The header is locked for the duration of the update.
+func (d *dmthin) updateHeader(update func(header *dmthinHeader) error) error {
+	unlock := locking.Lock(OperationLockName(d.name, "dmthin", "header"))
+	defer unlock()
+
+	f, err := os.OpenFile(d.config["source"], os.O_RDWR, 0)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to open %q", d.config["source"])
+	}
+	defer f.Close()
+
+	header, err := dmthinReadHeader(f)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to read dmthin header from %q", d.config["source"])
+	}
+
+	err = update(header)
+	if err != nil {
+		return err
+	}
+
+	err = dmthinWriteHeader(f, header)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to write dmthin header to %q", d.config["source"])
+	}
+
+	return nil
+}
+
+// getVolume returns the header entry of the volume.
+func (d *dmthin) getVolume(vol Volume) (dmthinVolume, error) {
+	header, err := d.readHeader()
+	if err != nil {
+		return dmthinVolume{}, err
+	}
+
+	volume, found := header.Volumes[d.volumeKey(vol)]
+	if !found {
+		return dmthinVolume{}, fmt.Errorf("Thin volume %q not found", vol.name)
+	}
+
+	return volume, nil
+}
+
+// dmsetupCreate creates a device-mapper device with the given table.
+func (d *dmthin) dmsetupCreate(name string, table string, readonly bool) error {
+	args := []string{"create", name, "--table", table}
+	if readonly {
+		args = append(args, "--readonly")
+	}
+
+	_, err := shared.TryRunCommand("dmsetup", args...)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to create device-mapper device %q", name)
+	}
+
+	// Create the device node ourselves if udev didn't.
+	if !shared.PathExists(dmthinDevPath(name)) {
+		_, err = shared.RunCommand("dmsetup", "mknodes", name)
+		if err != nil {
+			return errors.Wrapf(err, "Failed to create device node for %q", name)
+		}
+	}
+
+	return nil
+}
+
+// dmsetupRemove removes a device-mapper device.
+func (d *dmthin) dmsetupRemove(name string) error {
+	_, err := shared.TryRunCommand("dmsetup", "remove", "--retry", name)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to remove device-mapper device %q", name)
+	}
+
+	return nil
+}
+
+// dmsetupStatus returns the fields of the status line of a device-mapper device.
+func (d *dmthin) dmsetupStatus(name string) ([]string, error) {
+	output, err := shared.RunCommand("dmsetup", "status", name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to get status of device-mapper device %q", name)
+	}
+
+	return strings.Fields(output), nil
+}
+
+// poolMessage sends a message to the thin pool target.
+func (d *dmthin) poolMessage(message ...string) error {
+	args := append([]string{"message", d.dmName("pool"), "0"}, message...)
+	_, err := shared.RunCommand("dmsetup", args...)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to send %q to thin pool", strings.Join(message, " "))
+	}
+
+	return nil
+}
+
+// activatePool creates the metadata, data and thin pool devices on top of the source device.
+func (d *dmthin) activatePool(devPath string, header *dmthinHeader) error {
+	revert := revert.New()
+	defer revert.Fail()
+
+	headerSectors := int64(dmthinHeaderSize / 512)
+	metaSectors := header.MetadataSize / 512
+	dataSectors := header.DataSize / 512
+
+	metaName := d.dmName("meta")
+	if !shared.PathExists(dmthinDevPath(metaName)) {
+		err := d.dmsetupCreate(metaName, fmt.Sprintf("0 %d linear %s %d", metaSectors, devPath, headerSectors), false)
+		if err != nil {
+			return err
+		}
+
+		revert.Add(func() { d.dmsetupRemove(metaName) })
+	}
+
+	dataName := d.dmName("data")
+	if !shared.PathExists(dmthinDevPath(dataName)) {
+		err := d.dmsetupCreate(dataName, fmt.Sprintf("0 %d linear %s %d", dataSectors, devPath, headerSectors+metaSectors), false)
+		if err != nil {
+			return err
+		}
+
+		revert.Add(func() { d.dmsetupRemove(dataName) })
+	}
+
+	table := fmt.Sprintf("0 %d thin-pool %s %s %d 0", dataSectors, dmthinDevPath(metaName), dmthinDevPath(dataName), header.BlockSize/512)
+	err := d.dmsetupCreate(d.dmName("pool"), table, false)
+	if err != nil {
+		return err
+	}
+
+	d.logger.Debug("Activated thin pool", log.Ctx{"dev": devPath})
+
+	revert.Success()
+	return nil
+}
+
+// deactivatePool removes the thin pool, data and metadata devices.
+func (d *dmthin) deactivatePool() error {
+	for _, suffix := range []string{"pool", "data", "meta"} {
+		name := d.dmName(suffix)
+		if !shared.PathExists(dmthinDevPath(name)) {
+			continue
+		}
+
+		err := d.dmsetupRemove(name)
+		if err != nil {
+			return err
+		}
+	}
+
+	d.logger.Debug("Deactivated thin pool")
+	return nil
+}
+
+// poolUsage returns the number of used and total data blocks of the thin pool.
+func (d *dmthin) poolUsage() (uint64, uint64, error) {
+	// Format: <start> <length> thin-pool <transaction id> <used meta>/<total meta> <used data>/<total data> ...
+	fields, err := d.dmsetupStatus(d.dmName("pool"))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if len(fields) < 6 || fields[2] != "thin-pool" {
+		return 0, 0, fmt.Errorf("Unexpected thin pool status %q", strings.Join(fields, " "))
+	}
+
+	parts := strings.SplitN(fields[5], "/", 2)
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("Unexpected thin pool data usage %q", fields[5])
+	}
+
+	used, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	total, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return used, total, nil
+}
+
+// createThinVolume creates a new empty thin volume.
+func (d *dmthin) createThinVolume(vol Volume, sizeBytes int64) error {
+	err := d.updateHeader(func(header *dmthinHeader) error {
+		key := d.volumeKey(vol)
+		_, found := header.Volumes[key]
+		if found {
+			return fmt.Errorf("Thin volume %q already exists", vol.name)
+		}
+
+		if header.NextID > dmthinMaxID {
+			return fmt.Errorf("No thin device IDs left in pool")
+		}
+
+		err := d.poolMessage("create_thin", fmt.Sprintf("%d", header.NextID))
+		if err != nil {
+			return err
+		}
+
+		header.Volumes[key] = dmthinVolume{ID: header.NextID, Size: sizeBytes}
+		header.NextID++
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	d.logger.Debug("Thin volume created", log.Ctx{"vol": vol.name, "size": fmt.Sprintf("%db", sizeBytes)})
+	return nil
+}
+
+// createThinSnapshot creates a thin snapshot of srcVol as snapVol.
+func (d *dmthin) createThinSnapshot(srcVol Volume, snapVol Volume) error {
+	err := d.updateHeader(func(header *dmthinHeader) error {
+		src, found := header.Volumes[d.volumeKey(srcVol)]
+		if !found {
+			return fmt.Errorf("Thin volume %q not found", srcVol.name)
+		}
+
+		key := d.volumeKey(snapVol)
+		_, found = header.Volumes[key]
+		if found {
+			return fmt.Errorf("Thin volume %q already exists", snapVol.name)
+		}
+
+		if header.NextID > dmthinMaxID {
+			return fmt.Errorf("No thin device IDs left in pool")
+		}
+
+		// The origin must be suspended while the snapshot is taken if it is active.
+		srcDevName := d.volDevName(src.ID)
+		if shared.PathExists(dmthinDevPath(srcDevName)) {
+			_, err := shared.RunCommand("dmsetup", "suspend", srcDevName)
+			if err != nil {
+				return errors.Wrapf(err, "Failed to suspend thin volume %q", srcVol.name)
+			}
+
+			defer shared.RunCommand("dmsetup", "resume", srcDevName)
+		}
+
+		err := d.poolMessage("create_snap", fmt.Sprintf("%d", header.NextID), fmt.Sprintf("%d", src.ID))
+		if err != nil {
+			return err
+		}
+
+		header.Volumes[key] = dmthinVolume{ID: header.NextID, Size: src.Size}
+		header.NextID++
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	d.logger.Debug("Thin volume snapshot created", log.Ctx{"src": srcVol.name, "vol": snapVol.name})
+	return nil
+}
+
+// deleteThinVolume deactivates and deletes a thin volume. Does nothing if the volume doesn't exist.
+func (d *dmthin) deleteThinVolume(vol Volume) error {
+	_, err := d.deactivateVolume(vol)
+	if err != nil {
+		return err
+	}
+
+	err = d.updateHeader(func(header *dmthinHeader) error {
+		key := d.volumeKey(vol)
+		volume, found := header.Volumes[key]
+		if !found {
+			return nil
+		}
+
+		err := d.poolMessage("delete", fmt.Sprintf("%d", volume.ID))
+		if err != nil {
+			return err
+		}
+
+		delete(header.Volumes, key)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	d.logger.Debug("Thin volume deleted", log.Ctx{"vol": vol.name})
+	return nil
+}
+
+// renameThinVolume renames the header entry of a thin volume. The device-mapper devices are named after the
+// thin device IDs so they don't need renaming.
+func (d *dmthin) renameThinVolume(vol Volume, newVolName string) error {
+	err := d.updateHeader(func(header *dmthinHeader) error {
+		key := d.volumeKey(vol)
+		volume, found := header.Volumes[key]
+		if !found {
+			return fmt.Errorf("Thin volume %q not found", vol.name)
+		}
+
+		newVol := NewVolume(d, d.name, vol.volType, vol.contentType, newVolName, vol.config, vol.poolConfig)
+		newKey := d.volumeKey(newVol)
+		_, found = header.Volumes[newKey]
+		if found {
+			return fmt.Errorf("Thin volume %q already exists", newVolName)
+		}
+
+		delete(header.Volumes, key)
+		header.Volumes[newKey] = volume
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	d.logger.Debug("Thin volume renamed", log.Ctx{"vol": vol.name, "new_name": newVolName})
+	return nil
+}
+
+// swapThinVolumes exchanges the thin devices of two volumes.
+func (d *dmthin) swapThinVolumes(vol Volume, otherVol Volume) error {
+	return d.updateHeader(func(header *dmthinHeader) error {
+		key := d.volumeKey(vol)
+		otherKey := d.volumeKey(otherVol)
+
+		volume, found := header.Volumes[key]
+		if !found {
+			return fmt.Errorf("Thin volume %q not found", vol.name)
+		}
+
+		otherVolume, found := header.Volumes[otherKey]
+		if !found {
+			return fmt.Errorf("Thin volume %q not found", otherVol.name)
+		}
+
+		header.Volumes[key] = otherVolume
+		header.Volumes[otherKey] = volume
+
+		return nil
+	})
+}
+
+// thinVolumeSnapshots returns the snapshot names of the volume recorded in the pool header, oldest first.
+func (d *dmthin) thinVolumeSnapshots(vol Volume) ([]string, error) {
+	header, err := d.readHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := d.volumeKey(vol) + shared.SnapshotDelimiter
+	snapshots := []string{}
+	snapshotIDs := map[string]uint32{}
+	for key, volume := range header.Volumes {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+
+		// Skip the temporary writable snapshots used for mounting.
+		snapName := strings.TrimPrefix(key, prefix)
+		if strings.HasSuffix(snapName, tmpVolSuffix) {
+			continue
+		}
+
+		snapshots = append(snapshots, snapName)
+		snapshotIDs[snapName] = volume.ID
+	}
+
+	// Thin device IDs are allocated incrementally so they reflect the order the snapshots were taken in.
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshotIDs[snapshots[i]] < snapshotIDs[snapshots[j]]
+	})
+
+	return snapshots, nil
+}
+
+// thinVolumeUsage returns the space allocated to the volume from the thin pool.
+func (d *dmthin) thinVolumeUsage(vol Volume) (int64, error) {
+	activated, err := d.activateVolume(vol)
+	if err != nil {
+		return -1, err
+	}
+
+	if activated {
+		defer d.deactivateVolume(vol)
+	}
+
+	volume, err := d.getVolume(vol)
+	if err != nil {
+		return -1, err
+	}
+
+	// Format: <start> <length> thin <mapped sectors> <highest mapped sector>
+	fields, err := d.dmsetupStatus(d.volDevName(volume.ID))
+	if err != nil {
+		return -1, err
+	}
+
+	if len(fields) < 4 || fields[2] != "thin" {
+		return -1, fmt.Errorf("Unexpected thin volume status %q", strings.Join(fields, " "))
+	}
+
+	mappedSectors, err := strconv.ParseInt(fields[3], 10, 64)
+	if err != nil {
+		return -1, err
+	}
+
+	return mappedSectors * 512, nil
+}
+
+// activateVolume creates the device-mapper device of a thin volume if not already present. Snapshots are
+// activated read-only, except for the temporary writable snapshots used when mounting them.
+// Returns true if activated, false if not.
+func (d *dmthin) activateVolume(vol Volume) (bool, error) {
+	volume, err := d.getVolume(vol)
+	if err != nil {
+		return false, err
+	}
+
+	name := d.volDevName(volume.ID)
+	if shared.PathExists(dmthinDevPath(name)) {
+		return false, nil
+	}
+
+	readonly := vol.IsSnapshot() && !strings.HasSuffix(vol.name, tmpVolSuffix)
+	table := fmt.Sprintf("0 %d thin %s %d", volume.Size/512, dmthinDevPath(d.dmName("pool")), volume.ID)
+	err = d.dmsetupCreate(name, table, readonly)
+	if err != nil {
+		return false, err
+	}
+
+	d.logger.Debug("Activated thin volume", log.Ctx{"vol": vol.name, "dev": dmthinDevPath(name)})
+	return true, nil
+}
+
+// deactivateVolume removes the device-mapper device of a thin volume if present.
+// Returns true if deactivated, false if not.
+func (d *dmthin) deactivateVolume(vol Volume) (bool, error) {
+	header, err := d.readHeader()
+	if err != nil {
+		return false, err
+	}
+
+	volume, found := header.Volumes[d.volumeKey(vol)]
+	if !found {
+		return false, nil
+	}
+
+	name := d.volDevName(volume.ID)
+	if !shared.PathExists(dmthinDevPath(name)) {
+		return false, nil
+	}
+
+	err = d.dmsetupRemove(name)
+	if err != nil {
+		return false, err
+	}
+
+	d.logger.Debug("Deactivated thin volume", log.Ctx{"vol": vol.name})
+	return true, nil
+}
+
+// volumeDevPath returns the path to the device-mapper device of a thin volume.
+func (d *dmthin) volumeDevPath(vol Volume) (string, error) {
+	volume, err := d.getVolume(vol)
+	if err != nil {
+		return "", err
+	}
+
+	return dmthinDevPath(d.volDevName(volume.ID)), nil
+}
+
+// resizeThinVolume changes the size of a thin volume. This function does not resize any filesystem inside the
+// volume. When shrinking, the blocks beyond the new size are discarded to return them to the pool.
+func (d *dmthin) resizeThinVolume(vol Volume, sizeBytes int64) error {
+	volume, err := d.getVolume(vol)
+	if err != nil {
+		return err
+	}
+
+	name := d.volDevName(volume.ID)
+	activated, err := d.activateVolume(vol)
+	if err != nil {
+		return err
+	}
+
+	if activated {
+		defer d.deactivateVolume(vol)
+	}
+
+	if sizeBytes < volume.Size {
+		f, err := os.OpenFile(dmthinDevPath(name), os.O_RDWR, 0)
+		if err != nil {
+			return err
+		}
+
+		err = dmthinDiscard(f, sizeBytes, volume.Size-sizeBytes)
+		f.Close()
+		if err != nil {
+			return errors.Wrapf(err, "Failed to discard end of thin volume %q", vol.name)
+		}
+	}
+
+	err = d.updateHeader(func(header *dmthinHeader) error {
+		key := d.volumeKey(vol)
+		volume := header.Volumes[key]
+		volume.Size = sizeBytes
+		header.Volumes[key] = volume
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Load the new table and make it live.
+	table := fmt.Sprintf("0 %d thin %s %d", sizeBytes/512, dmthinDevPath(d.dmName("pool")), volume.ID)
+	_, err = shared.RunCommand("dmsetup", "reload", name, "--table", table)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to reload thin volume %q", vol.name)
+	}
+
+	_, err = shared.RunCommand("dmsetup", "resume", name)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to resume thin volume %q", vol.name)
+	}
+
+	d.logger.Debug("Thin volume resized", log.Ctx{"vol": vol.name, "size": fmt.Sprintf("%db", sizeBytes)})
+	return nil
+}
+
+// thinDelta returns the extents that differ between two thin volumes using the thin_delta tool.
+func (d *dmthin) thinDelta(oldID uint32, newID uint32) ([]dmthinExtent, error) {
+	// Only one metadata snapshot can exist at a time.
+	unlock := locking.Lock(OperationLockName(d.name, "dmthin", "metadata_snap"))
+	defer unlock()
+
+	err := d.poolMessage("reserve_metadata_snap")
+	if err != nil {
+		return nil, err
+	}
+
+	defer d.poolMessage("release_metadata_snap")
+
+	output, err := shared.RunCommand("thin_delta", "--metadata-snap", "--snap1", fmt.Sprintf("%d", oldID), "--snap2", fmt.Sprintf("%d", newID), dmthinDevPath(d.dmName("meta")))
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to compare thin volumes %d and %d", oldID, newID)
+	}
+
+	return dmthinParseThinDelta([]byte(output))
+}
+
+// dmthinParseThinDelta parses the output of thin_delta into the extents that need sending. Blocks only present
+// in the new volume or that differ are sent as data, blocks only present in the old volume are discarded.
+func dmthinParseThinDelta(output []byte) ([]dmthinExtent, error) {
+	delta := struct {
+		DataBlockSize int64 `xml:"data_block_size,attr"`
+		Diff          struct {
+			Entries []struct {
+				XMLName xml.Name
+				Begin   int64 `xml:"begin,attr"`
+				Length  int64 `xml:"length,attr"`
+			} `xml:",any"`
+		} `xml:"diff"`
+	}{}
+
+	err := xml.Unmarshal(output, &delta)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to parse thin_delta output")
+	}
+
+	if delta.DataBlockSize <= 0 {
+		return nil, fmt.Errorf("Invalid data block size in thin_delta output")
+	}
+
+	blockSize := delta.DataBlockSize * 512
+	extents := []dmthinExtent{}
+	for _, entry := range delta.Diff.Entries {
+		extent := dmthinExtent{offset: entry.Begin * blockSize, length: entry.Length * blockSize}
+
+		switch entry.XMLName.Local {
+		case "same":
+			continue
+		case "different", "right_only":
+		case "left_only":
+			extent.discard = true
+		default:
+			return nil, fmt.Errorf("Unknown thin_delta entry %q", entry.XMLName.Local)
+		}
+
+		extents = append(extents, extent)
+	}
+
+	return extents, nil
+}
+
+// writeVolumeStream writes a stream of the volume to w. If a parent is supplied only the differences to the
+// parent are included in the stream, otherwise the whole volume is included (skipping zero regions).
+func (d *dmthin) writeVolumeStream(w io.Writer, vol Volume, parent *Volume) error {
+	volume, err := d.getVolume(vol)
+	if err != nil {
+		return err
+	}
+
+	activated, err := d.activateVolume(vol)
+	if err != nil {
+		return err
+	}
+
+	if activated {
+		defer d.deactivateVolume(vol)
+	}
+
+	src, err := os.Open(dmthinDevPath(d.volDevName(volume.ID)))
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	sw, err := newDmthinStreamWriter(w, volume.Size)
+	if err != nil {
+		return err
+	}
+
+	if parent == nil {
+		d.logger.Debug("Sending full thin volume", log.Ctx{"vol": vol.name})
+		err = dmthinSendFull(sw, src, volume.Size)
+		if err != nil {
+			return err
+		}
+
+		return sw.close()
+	}
+
+	parentVolume, err := d.getVolume(*parent)
+	if err != nil {
+		return err
+	}
+
+	_, err = exec.LookPath("thin_delta")
+	if err == nil {
+		d.logger.Debug("Sending thin volume delta", log.Ctx{"vol": vol.name, "parent": parent.name})
+		extents, err := d.thinDelta(parentVolume.ID, volume.ID)
+		if err != nil {
+			return err
+		}
+
+		err = dmthinSendExtents(sw, src, volume.Size, extents)
+		if err != nil {
+			return err
+		}
+
+		return sw.close()
+	}
+
+	// Without thin_delta, find the differences by comparing the content of both volumes.
+	d.logger.Debug("Sending thin volume differences", log.Ctx{"vol": vol.name, "parent": parent.name})
+	parentActivated, err := d.activateVolume(*parent)
+	if err != nil {
+		return err
+	}
+
+	if parentActivated {
+		defer d.deactivateVolume(*parent)
+	}
+
+	parentSrc, err := os.Open(dmthinDevPath(d.volDevName(parentVolume.ID)))
+	if err != nil {
+		return err
+	}
+	defer parentSrc.Close()
+
+	err = dmthinSendCompare(sw, parentSrc, parentVolume.Size, src, volume.Size)
+	if err != nil {
+		return err
+	}
+
+	return sw.close()
+}
+
+// receiveVolumeStream applies a volume stream to the volume, creating or resizing it as needed.
+func (d *dmthin) receiveVolumeStream(vol Volume, r io.Reader) error {
+	br := bufio.NewReaderSize(r, dmthinStreamBufferSize)
+
+	size, err := dmthinReadStreamHeader(br)
+	if err != nil {
+		return err
+	}
+
+	if !d.HasVolume(vol) {
+		err = d.createThinVolume(vol, size)
+	} else {
+		err = d.resizeThinVolume(vol, size)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	header, err := d.readHeader()
+	if err != nil {
+		return err
+	}
+
+	activated, err := d.activateVolume(vol)
+	if err != nil {
+		return err
+	}
+
+	if activated {
+		defer d.deactivateVolume(vol)
+	}
+
+	devPath, err := d.volumeDevPath(vol)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(devPath, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	err = dmthinApplyStream(br, size, &dmthinDevice{File: f, blockSize: header.BlockSize})
+	if err != nil {
+		return errors.Wrapf(err, "Failed to receive thin volume %q", vol.name)
+	}
+
+	err = f.Sync()
+	if err != nil {
+		return err
+	}
+
+	// Consume anything left until the end of the stream.
+	_, err = io.Copy(ioutil.Discard, br)
+	if err != nil {
+		return err
+	}
+
+	return f.Close()
+}
+
+// dmthinStreamWriter writes dmthin volume streams. A stream starts with a magic and the volume size, followed
+// by data and discard records and terminated by an end record. Adjacent discard records are merged.
+type dmthinStreamWriter struct {
+	w       *bufio.Writer
+	discard dmthinExtent
+}
+
+// newDmthinStreamWriter writes the stream header and returns a writer for the records.
+func newDmthinStreamWriter(w io.Writer, size int64) (*dmthinStreamWriter, error) {
+	sw := &dmthinStreamWriter{w: bufio.NewWriterSize(w, dmthinStreamBufferSize)}
+
+	_, err := sw.w.WriteString(dmthinStreamMagic)
+	if err != nil {
+		return nil, err
+	}
+
+	err = binary.Write(sw.w, binary.LittleEndian, size)
+	if err != nil {
+		return nil, err
+	}
+
+	return sw, nil
+}
+
+func (sw *dmthinStreamWriter) record(recordType byte, offset int64, length int64) error {
+	buf := make([]byte, 17)
+	buf[0] = recordType
+	binary.LittleEndian.PutUint64(buf[1:], uint64(offset))
+	binary.LittleEndian.PutUint64(buf[9:], uint64(length))
+
+	_, err := sw.w.Write(buf)
+	return err
+}
+
+func (sw *dmthinStreamWriter) flushDiscard() error {
+	if sw.discard.length == 0 {
+		return nil
+	}
+
+	err := sw.record(dmthinRecordDiscard, sw.discard.offset, sw.discard.length)
+	if err != nil {
+		return err
+	}
+
+	sw.discard = dmthinExtent{}
+	return nil
+}
+
+// Data writes a data record.
+func (sw *dmthinStreamWriter) Data(offset int64, data []byte) error {
+	err := sw.flushDiscard()
+	if err != nil {
+		return err
+	}
+
+	err = sw.record(dmthinRecordData, offset, int64(len(data)))
+	if err != nil {
+		return err
+	}
+
+	_, err = sw.w.Write(data)
+	return err
+}
+
+// Discard writes a discard record, merging it with the previous one if adjacent.
+func (sw *dmthinStreamWriter) Discard(offset int64, length int64) error {
+	if sw.discard.length > 0 && sw.discard.offset+sw.discard.length == offset {
+		sw.discard.length += length
+		return nil
+	}
+
+	err := sw.flushDiscard()
+	if err != nil {
+		return err
+	}
+
+	sw.discard = dmthinExtent{offset: offset, length: length, discard: true}
+	return nil
+}
+
+// close writes the end record and flushes the stream. It doesn't close the underlying writer.
+func (sw *dmthinStreamWriter) close() error {
+	err := sw.flushDiscard()
+	if err != nil {
+		return err
+	}
+
+	err = sw.record(dmthinRecordEnd, 0, 0)
+	if err != nil {
+		return err
+	}
+
+	return sw.w.Flush()
+}
+
+// dmthinIsZero returns whether the buffer only contains zeroes.
+func dmthinIsZero(buf []byte) bool {
+	for _, b := range buf {
+		if b != 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// dmthinSendFull sends the whole content of src, discarding the regions only containing zeroes.
+func dmthinSendFull(sw *dmthinStreamWriter, src io.ReaderAt, size int64) error {
+	return dmthinSendCompare(sw, nil, 0, src, size)
+}
+
+// dmthinSendExtents sends the given extents of src.
+func dmthinSendExtents(sw *dmthinStreamWriter, src io.ReaderAt, size int64, extents []dmthinExtent) error {
+	buf := make([]byte, dmthinStreamBufferSize)
+
+	for _, extent := range extents {
+		if extent.offset >= size {
+			continue
+		}
+
+		end := extent.offset + extent.length
+		if end > size {
+			end = size
+		}
+
+		if extent.discard {
+			err := sw.Discard(extent.offset, end-extent.offset)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		for offset := extent.offset; offset < end; offset += dmthinStreamBufferSize {
+			n := end - offset
+			if n > dmthinStreamBufferSize {
+				n = dmthinStreamBufferSize
+			}
+
+			_, err := src.ReadAt(buf[:n], offset)
+			if err != nil {
+				return err
+			}
+
+			err = sw.Data(offset, buf[:n])
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// dmthinSendCompare sends the regions of newSrc that differ from oldSrc. Regions beyond the end of oldSrc are
+// compared against zeroes. If oldSrc is nil, all of newSrc is sent.
+func dmthinSendCompare(sw *dmthinStreamWriter, oldSrc io.ReaderAt, oldSize int64, newSrc io.ReaderAt, newSize int64) error {
+	newBuf := make([]byte, dmthinStreamBufferSize)
+	oldBuf := make([]byte, dmthinStreamBufferSize)
+
+	for offset := int64(0); offset < newSize; offset += dmthinStreamBufferSize {
+		n := newSize - offset
+		if n > dmthinStreamBufferSize {
+			n = dmthinStreamBufferSize
+		}
+
+		_, err := newSrc.ReadAt(newBuf[:n], offset)
+		if err != nil {
+			return err
+		}
+
+		// Read the old content, zero filling anything beyond its end.
+		oldN := int64(0)
+		if oldSrc != nil && offset < oldSize {
+			oldN = oldSize - offset
+			if oldN > n {
+				oldN = n
+			}
+
+			_, err = oldSrc.ReadAt(oldBuf[:oldN], offset)
+			if err != nil {
+				return err
+			}
+		}
+
+		for i := oldN; i < n; i++ {
+			oldBuf[i] = 0
+		}
+
+		for chunk := int64(0); chunk < n; chunk += dmthinScanSize {
+			chunkEnd := chunk + dmthinScanSize
+			if chunkEnd > n {
+				chunkEnd = n
+			}
+
+			// Full sends include the zero regions as discards so that they can be applied over
+			// existing content.
+			if oldSrc != nil && bytes.Equal(newBuf[chunk:chunkEnd], oldBuf[chunk:chunkEnd]) {
+				continue
+			}
+
+			if dmthinIsZero(newBuf[chunk:chunkEnd]) {
+				err = sw.Discard(offset+chunk, chunkEnd-chunk)
+			} else {
+				err = sw.Data(offset+chunk, newBuf[chunk:chunkEnd])
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// dmthinReadStreamHeader reads the stream header and returns the size of the volume.
+func dmthinReadStreamHeader(r io.Reader) (int64, error) {
+	buf := make([]byte, len(dmthinStreamMagic)+8)
+	_, err := io.ReadFull(r, buf)
+	if err != nil {
+		return -1, errors.Wrapf(err, "Failed to read thin volume stream header")
+	}
+
+	if string(buf[:len(dmthinStreamMagic)]) != dmthinStreamMagic {
+		return -1, fmt.Errorf("Invalid thin volume stream header")
+	}
+
+	size := int64(binary.LittleEndian.Uint64(buf[len(dmthinStreamMagic):]))
+	if size <= 0 || size%512 != 0 {
+		return -1, fmt.Errorf("Invalid thin volume size %d", size)
+	}
+
+	return size, nil
+}
+
+// dmthinStreamTarget is where the records of a volume stream are applied.
+type dmthinStreamTarget interface {
+	WriteAt(p []byte, offset int64) (int, error)
+	Discard(offset int64, length int64) error
+}
+
+// dmthinApplyStream applies the records of a volume stream (following its header) to the target.
+func dmthinApplyStream(r io.Reader, size int64, target dmthinStreamTarget) error {
+	record := make([]byte, 17)
+	buf := make([]byte, dmthinStreamBufferSize)
+
+	for {
+		_, err := io.ReadFull(r, record)
+		if err != nil {
+			return err
+		}
+
+		offset := int64(binary.LittleEndian.Uint64(record[1:]))
+		length := int64(binary.LittleEndian.Uint64(record[9:]))
+		if offset < 0 || length < 0 || offset+length > size || offset+length < offset {
+			return fmt.Errorf("Invalid record for offset %d and length %d", offset, length)
+		}
+
+		switch record[0] {
+		case dmthinRecordEnd:
+			return nil
+		case dmthinRecordData:
+			for length > 0 {
+				n := length
+				if n > dmthinStreamBufferSize {
+					n = dmthinStreamBufferSize
+				}
+
+				_, err = io.ReadFull(r, buf[:n])
+				if err != nil {
+					return err
+				}
+
+				_, err = target.WriteAt(buf[:n], offset)
+				if err != nil {
+					return err
+				}
+
+				offset += n
+				length -= n
+			}
+		case dmthinRecordDiscard:
+			err = target.Discard(offset, length)
+			if err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("Unknown record type %d", record[0])
+		}
+	}
+}
+
+// dmthinDevice is a thin volume device being written by a volume stream.
+type dmthinDevice struct {
+	*os.File
+	blockSize int64
+}
+
+// Discard discards the whole blocks of the range (so they are unmapped from the thin volume) and zeroes the
+// partial blocks at either end.
+func (dev *dmthinDevice) Discard(offset int64, length int64) error {
+	start := dmthinRoundUp(offset, dev.blockSize)
+	end := (offset + length) / dev.blockSize * dev.blockSize
+	if end <= start {
+		return dev.zero(offset, length)
+	}
+
+	err := dmthinDiscard(dev.File, start, end-start)
+	if err != nil {
+		return err
+	}
+
+	err = dev.zero(offset, start-offset)
+	if err != nil {
+		return err
+	}
+
+	return dev.zero(end, offset+length-end)
+}
+
+// zero fills the range with zeroes, skipping the parts already zeroed to avoid allocating blocks.
+func (dev *dmthinDevice) zero(offset int64, length int64) error {
+	buf := make([]byte, dmthinStreamBufferSize)
+
+	for length > 0 {
+		n := length
+		if n > dmthinStreamBufferSize {
+			n = dmthinStreamBufferSize
+		}
+
+		_, err := dev.ReadAt(buf[:n], offset)
+		if err != nil {
+			return err
+		}
+
+		if !dmthinIsZero(buf[:n]) {
+			_, err = dev.WriteAt(make([]byte, n), offset)
+			if err != nil {
+				return err
+			}
+		}
+
+		offset += n
+		length -= n
+	}
+
+	return nil
+}
+
+// dmthinDiscard discards a byte range of a block device.
+func dmthinDiscard(f *os.File, offset int64, length int64) error {
+	r := [2]uint64{uint64(offset), uint64(length)}
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, f.Fd(), dmthinBLKDISCARD, uintptr(unsafe.Pointer(&r[0])))
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
@@ -0,0 +1,197 @@
+package drivers
+
+import (
+	"bytes"
+	"testing"
+)
+
+// dmthinMemTarget is an in-memory dmthinStreamTarget.
+type dmthinMemTarget struct {
+	data      []byte
+	discarded int64
+}
+
+func (t *dmthinMemTarget) WriteAt(p []byte, offset int64) (int, error) {
+	return copy(t.data[offset:], p), nil
+}
+
+func (t *dmthinMemTarget) Discard(offset int64, length int64) error {
+	for i := offset; i < offset+length; i++ {
+		t.data[i] = 0
+	}
+
+	t.discarded += length
+	return nil
+}
+
+func Test_dmthin_header(t *testing.T) {
+	buf := make([]byte, dmthinHeaderSize)
+
+	_, err := dmthinReadHeader(bytes.NewReader(buf))
+	if err != errDmthinNoHeader {
+		t.Fatalf("Expected no header, got %v", err)
+	}
+
+	header := &dmthinHeader{BlockSize: dmthinDefaultBlockSize, Volumes: map[string]dmthinVolume{}}
+	for i := uint32(0); i < 3; i++ {
+		header.Generation++
+		header.Volumes["custom/filesystem/vol"] = dmthinVolume{ID: i, Size: 1024}
+		slot, err := dmthinEncodeHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		copy(buf[int64(header.Generation%2)*dmthinHeaderSlotSize:], slot)
+	}
+
+	got, err := dmthinReadHeader(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Generation != 3 || got.Volumes["custom/filesystem/vol"].ID != 2 {
+		t.Fatalf("Expected generation 3 with ID 2, got generation %d with ID %d", got.Generation, got.Volumes["custom/filesystem/vol"].ID)
+	}
+
+	// Corrupt the latest slot, the previous generation should be used.
+	buf[dmthinHeaderSlotSize+dmthinHeaderPrefixSize] ^= 0xff
+
+	got, err = dmthinReadHeader(bytes.NewReader(buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Generation != 2 || got.Volumes["custom/filesystem/vol"].ID != 1 {
+		t.Fatalf("Expected generation 2 with ID 1, got generation %d with ID %d", got.Generation, got.Volumes["custom/filesystem/vol"].ID)
+	}
+}
+
+func Test_dmthin_stream(t *testing.T) {
+	size := int64(4 * dmthinStreamBufferSize)
+
+	oldData := make([]byte, size)
+	for i := int64(0); i < size/2; i++ {
+		oldData[i] = byte(i % 251)
+	}
+
+	newData := make([]byte, size)
+	copy(newData, oldData)
+
+	// Change some data, zero a region and add data past the old content.
+	copy(newData[100:], []byte("changed"))
+	for i := int64(dmthinScanSize); i < 3*dmthinScanSize; i++ {
+		newData[i] = 0
+	}
+
+	copy(newData[size-10:], []byte("appended"))
+
+	tests := []struct {
+		name    string
+		oldSrc  []byte
+		initial []byte
+	}{
+		{"Full", nil, make([]byte, size)},
+		{"Full over existing", nil, append([]byte{}, oldData...)},
+		{"Delta", oldData, append([]byte{}, oldData...)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var stream bytes.Buffer
+			sw, err := newDmthinStreamWriter(&stream, size)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if test.oldSrc == nil {
+				err = dmthinSendFull(sw, bytes.NewReader(newData), size)
+			} else {
+				err = dmthinSendCompare(sw, bytes.NewReader(test.oldSrc), size, bytes.NewReader(newData), size)
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = sw.close()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			gotSize, err := dmthinReadStreamHeader(&stream)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if gotSize != size {
+				t.Fatalf("Expected size %d, got %d", size, gotSize)
+			}
+
+			target := &dmthinMemTarget{data: test.initial}
+			err = dmthinApplyStream(&stream, size, target)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(target.data, newData) {
+				t.Fatal("Received data doesn't match sent data")
+			}
+
+			if target.discarded == 0 {
+				t.Fatal("Expected zero regions to be discarded")
+			}
+		})
+	}
+}
+
+func Test_dmthin_parseThinDelta(t *testing.T) {
+	output := []byte(`<superblock uuid="" time="1" transaction="2" data_block_size="128" nr_data_blocks="1024">
+  <diff left="1" right="2">
+    <same begin="0" length="4"/>
+    <different begin="4" length="2"/>
+    <right_only begin="6" length="1"/>
+    <left_only begin="10" length="3"/>
+  </diff>
+</superblock>`)
+
+	extents, err := dmthinParseThinDelta(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blockSize := int64(128 * 512)
+	expected := []dmthinExtent{
+		{offset: 4 * blockSize, length: 2 * blockSize},
+		{offset: 6 * blockSize, length: 1 * blockSize},
+		{offset: 10 * blockSize, length: 3 * blockSize, discard: true},
+	}
+
+	if len(extents) != len(expected) {
+		t.Fatalf("Expected %d extents, got %d", len(expected), len(extents))
+	}
+
+	for i := range expected {
+		if extents[i] != expected[i] {
+			t.Errorf("Extent %d: expected %+v, got %+v", i, expected[i], extents[i])
+		}
+	}
+}
+
+func Test_dmthin_metadataSize(t *testing.T) {
+	tests := []struct {
+		size      int64
+		blockSize int64
+		want      int64
+	}{
+		{1024 * 1024 * 1024, 64 * 1024, 4 * 1024 * 1024},
+		{1024 * 1024 * 1024 * 1024, 64 * 1024, 1536 * 1024 * 1024},
+		{1024 * 1024 * 1024 * 1024 * 1024, 64 * 1024, 15 * 1024 * 1024 * 1024},
+	}
+
+	for _, test := range tests {
+		got := dmthinMetadataSize(test.size, test.blockSize)
+		if got != test.want {
+			t.Errorf("dmthinMetadataSize(%d, %d): expected %d, got %d", test.size, test.blockSize, test.want, got)
+		}
+	}
+}
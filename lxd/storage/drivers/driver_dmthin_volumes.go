@@ -0,0 +1,1545 @@
+package drivers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/pborman/uuid"
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+
+	"github.com/lxc/lxd/lxd/backup"
+	"github.com/lxc/lxd/lxd/migration"
+	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/lxd/revert"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/instancewriter"
+	"github.com/lxc/lxd/shared/ioprogress"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/validate"
+)
+
+// CreateVolume creates an empty volume and can optionally fill it by executing the supplied filler function.
+func (d *dmthin) CreateVolume(vol Volume, filler *VolumeFiller, op *operations.Operation) error {
+	revert := revert.New()
+	defer revert.Fail()
+
+	volPath := vol.MountPath()
+	err := vol.EnsureMountPath()
+	if err != nil {
+		return err
+	}
+	revert.Add(func() { os.RemoveAll(volPath) })
+
+	sizeBytes, err := dmthinRoundedSizeBytes(vol.ConfigSize())
+	if err != nil {
+		return err
+	}
+
+	err = d.createThinVolume(vol, sizeBytes)
+	if err != nil {
+		return errors.Wrapf(err, "Error creating thin volume")
+	}
+	revert.Add(func() { d.DeleteVolume(vol, op) })
+
+	if vol.contentType == ContentTypeFS {
+		err = d.makeVolumeFS(vol)
+		if err != nil {
+			return err
+		}
+	}
+
+	// For VMs, also create the filesystem volume.
+	if vol.IsVMBlock() {
+		fsVol := vol.NewVMBlockFilesystemVolume()
+		err := d.CreateVolume(fsVol, nil, op)
+		if err != nil {
+			return err
+		}
+
+		revert.Add(func() { d.DeleteVolume(fsVol, op) })
+	}
+
+	err = vol.MountTask(func(mountPath string, op *operations.Operation) error {
+		// Run the volume filler function if supplied.
+		if filler != nil && filler.Fill != nil {
+			var err error
+			var devPath string
+
+			if vol.contentType == ContentTypeBlock {
+				// Get the device path.
+				devPath, err = d.GetVolumeDiskPath(vol)
+				if err != nil {
+					return err
+				}
+			}
+
+			// Run the filler.
+			err = d.runFiller(vol, devPath, filler)
+			if err != nil {
+				return err
+			}
+
+			// Move the GPT alt header to end of disk if needed.
+			if vol.IsVMBlock() {
+				err = d.moveGPTAltHeader(devPath)
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		if vol.contentType == ContentTypeFS {
+			// Run EnsureMountPath again after mounting and filling to ensure the mount directory has
+			// the correct permissions set.
+			err = vol.EnsureMountPath()
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}, op)
+	if err != nil {
+		return err
+	}
+
+	revert.Success()
+	return nil
+}
+
+// makeVolumeFS formats a newly created thin volume with the volume's filesystem.
+func (d *dmthin) makeVolumeFS(vol Volume) error {
+	activated, err := d.activateVolume(vol)
+	if err != nil {
+		return err
+	}
+
+	if activated {
+		defer d.deactivateVolume(vol)
+	}
+
+	devPath, err := d.volumeDevPath(vol)
+	if err != nil {
+		return err
+	}
+
+	_, err = makeFSType(devPath, vol.ConfigBlockFilesystem(), nil)
+	if err != nil {
+		return errors.Wrapf(err, "Error making filesystem on thin volume")
+	}
+
+	return nil
+}
+
+// CreateVolumeFromBackup restores a backup tarball onto the storage device.
+func (d *dmthin) CreateVolumeFromBackup(vol Volume, srcBackup backup.Info, srcData io.ReadSeeker, op *operations.Operation) (func(vol Volume) error, func(), error) {
+	// Handle the non-optimized tarballs through the generic unpacker.
+	if !*srcBackup.OptimizedStorage {
+		return genericVFSBackupUnpack(d, vol, srcBackup.Snapshots, srcData, op)
+	}
+
+	var parentSnapshot Volume
+	if srcBackup.Parent != "" {
+		// Incremental backups are applied on top of the volume restored from the parent backup.
+		var err error
+		parentSnapshot, err = vol.NewSnapshot(srcBackup.ParentSnapshot)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if !d.HasVolume(vol) || !d.HasVolume(parentSnapshot) {
+			return nil, nil, fmt.Errorf("Cannot restore incremental backup, parent snapshot %q doesn't exist on target", srcBackup.ParentSnapshot)
+		}
+	} else if d.HasVolume(vol) {
+		return nil, nil, fmt.Errorf("Cannot restore volume, already exists on target")
+	}
+
+	// Restore VM config volumes first.
+	if vol.IsVMBlock() {
+		fsVol := vol.NewVMBlockFilesystemVolume()
+
+		// The revert and post hooks define below will also apply to what is done here.
+		_, _, err := d.CreateVolumeFromBackup(fsVol, srcBackup, srcData, op)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	// Define a revert function that will be used both to revert if an error occurs inside this
+	// function but also return it for use from the calling functions if no error internally.
+	revertHook := func() {
+		for _, snapName := range srcBackup.Snapshots {
+			fullSnapshotName := GetSnapshotVolumeName(vol.name, snapName)
+			snapVol := NewVolume(d, d.name, vol.volType, vol.contentType, fullSnapshotName, vol.config, vol.poolConfig)
+			d.DeleteVolumeSnapshot(snapVol, op)
+		}
+
+		// And lastly the main volume (unless it existed before an incremental restore).
+		if srcBackup.Parent == "" {
+			d.DeleteVolume(vol, op)
+		}
+	}
+
+	// Only execute the revert function if we have had an error internally.
+	revert.Add(revertHook)
+
+	// Define function to unpack a volume stream from a backup tarball file. Each stream in the backup is
+	// relative to the previous one, so they are all applied to the main volume in order.
+	unpackVolume := func(r io.ReadSeeker, unpacker []string, srcFile string) error {
+		d.Logger().Debug("Unpacking optimized volume", log.Ctx{"source": srcFile, "target": vol.name})
+		tr, cancelFunc, err := shared.CompressedTarReader(context.Background(), r, unpacker)
+		if err != nil {
+			return err
+		}
+		defer cancelFunc()
+
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break // End of archive
+			}
+			if err != nil {
+				return err
+			}
+
+			if hdr.Name == srcFile {
+				// Extract the backup.
+				err = d.receiveVolumeStream(vol, tr)
+				if err != nil {
+					return err
+				}
+
+				cancelFunc()
+				return nil
+			}
+		}
+
+		return fmt.Errorf("Could not find %q", srcFile)
+	}
+
+	// Find the compression algorithm used for backup source data.
+	srcData.Seek(0, 0)
+	_, _, unpacker, err := shared.DetectCompressionFile(srcData)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = vol.EnsureMountPath()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The streams of an incremental backup are relative to the parent snapshot, so swap the volume's
+	// thin device with a fresh snapshot of it. The original device is removed once everything is applied.
+	var oldVol *Volume
+	if srcBackup.Parent != "" {
+		_, err = d.UnmountVolume(vol, op)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		_, err = d.deactivateVolume(vol)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		tmpVol := NewVolume(d, d.name, vol.volType, vol.contentType, fmt.Sprintf("%s%s", vol.name, tmpVolSuffix), vol.config, vol.poolConfig)
+		err = d.createThinSnapshot(parentSnapshot, tmpVol)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		revert.Add(func() { d.deleteThinVolume(tmpVol) })
+
+		err = d.swapThinVolumes(vol, tmpVol)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		revert.Add(func() { d.swapThinVolumes(vol, tmpVol) })
+		oldVol = &tmpVol
+	}
+
+	if len(srcBackup.Snapshots) > 0 {
+		// Create new snapshots directory.
+		err := createParentSnapshotDirIfMissing(d.name, vol.volType, vol.name)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Restore backups from oldest to newest.
+	for _, snapName := range srcBackup.Snapshots {
+		prefix := "snapshots"
+		fileName := fmt.Sprintf("%s.bin", snapName)
+		if vol.volType == VolumeTypeVM {
+			prefix = "virtual-machine-snapshots"
+			if vol.contentType == ContentTypeFS {
+				fileName = fmt.Sprintf("%s-config.bin", snapName)
+			}
+		} else if vol.volType == VolumeTypeCustom {
+			prefix = "volume-snapshots"
+		}
+
+		err = unpackVolume(srcData, unpacker, fmt.Sprintf("backup/%s/%s", prefix, fileName))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		snapVol, err := vol.NewSnapshot(snapName)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		err = snapVol.EnsureMountPath()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		err = d.createThinSnapshot(vol, snapVol)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Extract main volume.
+	fileName := "container.bin"
+	if vol.volType == VolumeTypeVM {
+		if vol.contentType == ContentTypeFS {
+			fileName = "virtual-machine-config.bin"
+		} else {
+			fileName = "virtual-machine.bin"
+		}
+	} else if vol.volType == VolumeTypeCustom {
+		fileName = "volume.bin"
+	}
+
+	err = unpackVolume(srcData, unpacker, fmt.Sprintf("backup/%s", fileName))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Remove the replaced thin device. Should always be the last step to allow revert.
+	if oldVol != nil {
+		err = d.deleteThinVolume(*oldVol)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "Error removing original thin volume")
+		}
+	}
+
+	// The import requires a mounted volume, so mount it and have it unmounted as a post hook.
+	_, err = d.MountVolume(vol, op)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	postHook := func(vol Volume) error {
+		_, err := d.UnmountVolume(vol, op)
+		return err
+	}
+
+	revert.Success()
+	return postHook, revertHook, nil
+}
+
+// CreateVolumeFromCopy provides same-pool volume copying functionality.
+func (d *dmthin) CreateVolumeFromCopy(vol, srcVol Volume, copySnapshots bool, op *operations.Operation) error {
+	var err error
+	var srcSnapshots []Volume
+
+	if copySnapshots && !srcVol.IsSnapshot() {
+		// Get the list of snapshots from the source.
+		srcSnapshots, err = srcVol.Snapshots(op)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = d.copyThinVolume(vol, srcVol, srcSnapshots, false)
+	if err != nil {
+		return err
+	}
+
+	// For VMs, also copy the filesystem volume.
+	if vol.IsVMBlock() {
+		srcFSVol := srcVol.NewVMBlockFilesystemVolume()
+		fsVol := vol.NewVMBlockFilesystemVolume()
+		return d.copyThinVolume(fsVol, srcFSVol, srcSnapshots, false)
+	}
+
+	return nil
+}
+
+// copyThinVolume makes an optimised copy of a thin volume by using thin snapshots.
+func (d *dmthin) copyThinVolume(vol, srcVol Volume, srcSnapshots []Volume, refresh bool) error {
+	revert := revert.New()
+	defer revert.Fail()
+
+	removeVols := []Volume{}
+
+	// If copying snapshots is indicated, check the source isn't itself a snapshot.
+	if len(srcSnapshots) > 0 && !srcVol.IsSnapshot() {
+		// Create the parent snapshot directory.
+		err := createParentSnapshotDirIfMissing(d.name, vol.volType, vol.name)
+		if err != nil {
+			return err
+		}
+
+		for _, srcSnapshot := range srcSnapshots {
+			_, snapName, _ := shared.InstanceGetParentAndSnapshotName(srcSnapshot.name)
+			newFullSnapName := GetSnapshotVolumeName(vol.name, snapName)
+			newSnapVol := NewVolume(d, d.Name(), vol.volType, vol.contentType, newFullSnapName, vol.config, vol.poolConfig)
+
+			// The filesystem volume of a VM snapshot has a different content type than the snapshot list.
+			srcSnapVol := NewVolume(d, d.Name(), srcVol.volType, srcVol.contentType, srcSnapshot.name, srcSnapshot.config, srcSnapshot.poolConfig)
+
+			if d.HasVolume(newSnapVol) {
+				return fmt.Errorf("Thin snapshot volume already exists %q", newSnapVol.name)
+			}
+
+			newSnapVolPath := newSnapVol.MountPath()
+			err := newSnapVol.EnsureMountPath()
+			if err != nil {
+				return err
+			}
+
+			revert.Add(func() { os.RemoveAll(newSnapVolPath) })
+
+			// We do not modify the original snapshot so as to avoid damaging if it is corrupted for
+			// some reason. If the filesystem needs to have a unique UUID generated in order to mount
+			// this will be done at restore time to be safe.
+			err = d.createThinSnapshot(srcSnapVol, newSnapVol)
+			if err != nil {
+				return errors.Wrapf(err, "Error creating thin snapshot volume")
+			}
+
+			revert.Add(func() { d.deleteThinVolume(newSnapVol) })
+		}
+	}
+
+	// Handle copying the main volume.
+	if d.HasVolume(vol) {
+		if refresh {
+			tmpVolName := fmt.Sprintf("%s%s", vol.name, tmpVolSuffix)
+			tmpVol := NewVolume(d, d.name, vol.volType, vol.contentType, tmpVolName, vol.config, vol.poolConfig)
+
+			_, err := d.UnmountVolume(vol, nil)
+			if err != nil {
+				return err
+			}
+
+			_, err = d.deactivateVolume(vol)
+			if err != nil {
+				return err
+			}
+
+			// Rename existing volume to temporary new name so we can revert if needed.
+			err = d.renameThinVolume(vol, tmpVolName)
+			if err != nil {
+				return errors.Wrapf(err, "Error temporarily renaming original thin volume")
+			}
+
+			// Record this volume to be removed at the very end.
+			removeVols = append(removeVols, tmpVol)
+
+			revert.Add(func() {
+				// Rename the original volume back to the original name.
+				d.renameThinVolume(tmpVol, vol.name)
+			})
+		} else {
+			return fmt.Errorf("Thin volume already exists %q", vol.name)
+		}
+	} else {
+		volPath := vol.MountPath()
+		err := vol.EnsureMountPath()
+		if err != nil {
+			return err
+		}
+
+		revert.Add(func() { os.RemoveAll(volPath) })
+	}
+
+	// Create snapshot of source volume as new volume.
+	err := d.createThinSnapshot(srcVol, vol)
+	if err != nil {
+		return errors.Wrapf(err, "Error creating thin snapshot volume")
+	}
+
+	revert.Add(func() { d.deleteThinVolume(vol) })
+
+	if vol.contentType == ContentTypeFS {
+		// Generate a new filesystem UUID if needed (this is required because some filesystems won't allow
+		// volumes with the same UUID to be mounted at the same time). This should be done before volume
+		// resize as some filesystems will need to mount the filesystem to resize.
+		if renegerateFilesystemUUIDNeeded(vol.ConfigBlockFilesystem()) {
+			err = d.regenerateVolumeFSUUID(vol)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Mount the volume and ensure the permissions are set correctly inside the mounted volume.
+		err = vol.MountTask(func(_ string, _ *operations.Operation) error {
+			return vol.EnsureMountPath()
+		}, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Resize volume to the size specified. Only uses volume "size" property and does not use pool/defaults
+	// to give the caller more control over the size being used.
+	err = d.SetVolumeQuota(vol, vol.config["size"], nil)
+	if err != nil {
+		return err
+	}
+
+	// Finally clean up original volumes left that were renamed with a tmpVolSuffix suffix.
+	for _, removeVol := range removeVols {
+		err := d.deleteThinVolume(removeVol)
+		if err != nil {
+			return errors.Wrapf(err, "Error removing thin volume %q", vol.name)
+		}
+	}
+
+	revert.Success()
+	return nil
+}
+
+// regenerateVolumeFSUUID generates a new filesystem UUID for the volume.
+func (d *dmthin) regenerateVolumeFSUUID(vol Volume) error {
+	activated, err := d.activateVolume(vol)
+	if err != nil {
+		return err
+	}
+
+	if activated {
+		defer d.deactivateVolume(vol)
+	}
+
+	devPath, err := d.volumeDevPath(vol)
+	if err != nil {
+		return err
+	}
+
+	d.logger.Debug("Regenerating filesystem UUID", log.Ctx{"dev": devPath, "fs": vol.ConfigBlockFilesystem()})
+	return regenerateFilesystemUUID(vol.ConfigBlockFilesystem(), devPath)
+}
+
+// CreateVolumeFromMigration creates a volume being sent via a migration.
+func (d *dmthin) CreateVolumeFromMigration(vol Volume, conn io.ReadWriteCloser, volTargetArgs migration.VolumeTargetArgs, preFiller *VolumeFiller, op *operations.Operation) error {
+	// Handle simple rsync and block_and_rsync through generic.
+	if volTargetArgs.MigrationType.FSType == migration.MigrationFSType_RSYNC || volTargetArgs.MigrationType.FSType == migration.MigrationFSType_BLOCK_AND_RSYNC {
+		return genericVFSCreateVolumeFromMigration(d, nil, vol, conn, volTargetArgs, preFiller, op)
+	} else if volTargetArgs.MigrationType.FSType != migration.MigrationFSType_DMTHIN {
+		return ErrNotSupported
+	}
+
+	if vol.IsVMBlock() {
+		fsVol := vol.NewVMBlockFilesystemVolume()
+		err := d.CreateVolumeFromMigration(fsVol, conn, volTargetArgs, preFiller, op)
+		if err != nil {
+			return err
+		}
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	err := vol.EnsureMountPath()
+	if err != nil {
+		return err
+	}
+
+	revert.Add(func() { d.DeleteVolume(vol, op) })
+
+	// Each stream is relative to the previous one, so they are all received into the main volume and the
+	// snapshots are taken from it as they arrive.
+	if len(volTargetArgs.Snapshots) > 0 {
+		// Create the parent directory.
+		err := createParentSnapshotDirIfMissing(d.name, vol.volType, vol.name)
+		if err != nil {
+			return err
+		}
+
+		// Transfer the snapshots.
+		for _, snapName := range volTargetArgs.Snapshots {
+			snapVol, err := vol.NewSnapshot(snapName)
+			if err != nil {
+				return err
+			}
+
+			wrapper := migration.ProgressReader(op, "fs_progress", snapVol.name)
+			err = d.receiveVolumeStream(vol, wrapper(conn))
+			if err != nil {
+				return err
+			}
+
+			err = snapVol.EnsureMountPath()
+			if err != nil {
+				return err
+			}
+
+			err = d.createThinSnapshot(vol, snapVol)
+			if err != nil {
+				return err
+			}
+
+			revert.Add(func() { d.DeleteVolumeSnapshot(snapVol, op) })
+		}
+	}
+
+	// Transfer the main volume.
+	wrapper := migration.ProgressReader(op, "fs_progress", vol.name)
+	err = d.receiveVolumeStream(vol, wrapper(conn))
+	if err != nil {
+		return err
+	}
+
+	// A live migration sends a final sync of the main volume.
+	if volTargetArgs.Live {
+		err = d.receiveVolumeStream(vol, wrapper(conn))
+		if err != nil {
+			return err
+		}
+	}
+
+	// Re-generate the filesystem UUID if needed.
+	if vol.contentType == ContentTypeFS && renegerateFilesystemUUIDNeeded(vol.ConfigBlockFilesystem()) {
+		err = d.regenerateVolumeFSUUID(vol)
+		if err != nil {
+			return err
+		}
+	}
+
+	revert.Success()
+	return nil
+}
+
+// RefreshVolume provides same-pool volume and specific snapshots syncing functionality.
+func (d *dmthin) RefreshVolume(vol, srcVol Volume, srcSnapshots []Volume, op *operations.Operation) error {
+	return d.copyThinVolume(vol, srcVol, srcSnapshots, true)
+}
+
+// DeleteVolume deletes a volume of the storage device. If any snapshots of the volume remain then this function
+// will return an error.
+func (d *dmthin) DeleteVolume(vol Volume, op *operations.Operation) error {
+	snapshots, err := d.VolumeSnapshots(vol, op)
+	if err != nil {
+		return err
+	}
+
+	if len(snapshots) > 0 {
+		return fmt.Errorf("Cannot remove a volume that has snapshots")
+	}
+
+	if d.HasVolume(vol) {
+		if vol.contentType == ContentTypeFS {
+			_, err = d.UnmountVolume(vol, op)
+			if err != nil {
+				return errors.Wrapf(err, "Error unmounting thin volume")
+			}
+		}
+
+		err = d.deleteThinVolume(vol)
+		if err != nil {
+			return errors.Wrapf(err, "Error removing thin volume")
+		}
+	}
+
+	if vol.contentType == ContentTypeFS {
+		// Remove the volume from the storage device.
+		mountPath := vol.MountPath()
+		err = os.RemoveAll(mountPath)
+		if err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "Error removing thin volume mount path %q", mountPath)
+		}
+
+		// Although the volume snapshot directory should already be removed, lets remove it here to just in
+		// case the top-level directory is left.
+		err = deleteParentSnapshotDirIfEmpty(d.name, vol.volType, vol.name)
+		if err != nil {
+			return err
+		}
+	}
+
+	// For VMs, also delete the filesystem volume.
+	if vol.IsVMBlock() {
+		fsVol := vol.NewVMBlockFilesystemVolume()
+		err := d.DeleteVolume(fsVol, op)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// HasVolume indicates whether a specific volume exists on the storage pool.
+func (d *dmthin) HasVolume(vol Volume) bool {
+	_, err := d.getVolume(vol)
+	return err == nil
+}
+
+// ValidateVolume validates the supplied volume config.
+func (d *dmthin) ValidateVolume(vol Volume, removeUnknownKeys bool) error {
+	rules := map[string]func(value string) error{
+		"block.filesystem": func(value string) error {
+			if value == "" {
+				return nil
+			}
+			return validate.IsOneOf(value, dmthinAllowedFilesystems)
+		},
+	}
+
+	return d.validateVolume(vol, rules, removeUnknownKeys)
+}
+
+// UpdateVolume applies config changes to the volume.
+func (d *dmthin) UpdateVolume(vol Volume, changedConfig map[string]string) error {
+	if _, changed := changedConfig["size"]; changed {
+		err := d.SetVolumeQuota(vol, changedConfig["size"], nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetVolumeUsage returns the disk space used by the volume.
+func (d *dmthin) GetVolumeUsage(vol Volume) (int64, error) {
+	// Snapshot usage not supported for dmthin.
+	if vol.IsSnapshot() {
+		return -1, ErrNotSupported
+	}
+
+	// For filesystem volumes, we only return usage when the volume is mounted, as the space allocated from
+	// the thin pool isn't released when files are deleted in the volume.
+	if vol.contentType == ContentTypeFS && shared.IsMountPoint(vol.MountPath()) {
+		var stat unix.Statfs_t
+		err := unix.Statfs(vol.MountPath(), &stat)
+		if err != nil {
+			return -1, err
+		}
+
+		return int64(stat.Blocks-stat.Bfree) * int64(stat.Bsize), nil
+	} else if vol.contentType == ContentTypeBlock {
+		// For block volumes we can calculate an approximate usage using the space allocated to the
+		// volume from the thin pool.
+		return d.thinVolumeUsage(vol)
+	}
+
+	return -1, ErrNotSupported
+}
+
+// SetVolumeQuota sets the quota on the volume.
+// Does nothing if supplied with an empty/zero size.
+func (d *dmthin) SetVolumeQuota(vol Volume, size string, op *operations.Operation) error {
+	// Do nothing if size isn't specified.
+	if size == "" || size == "0" {
+		return nil
+	}
+
+	sizeBytes, err := dmthinRoundedSizeBytes(size)
+	if err != nil {
+		return err
+	}
+
+	// Read actual size of current volume.
+	volume, err := d.getVolume(vol)
+	if err != nil {
+		return err
+	}
+
+	oldSizeBytes := volume.Size
+	if sizeBytes == oldSizeBytes {
+		return nil
+	}
+
+	logCtx := log.Ctx{"vol": vol.name, "size": fmt.Sprintf("%db", sizeBytes)}
+
+	// Activate volume if needed.
+	activated, err := d.activateVolume(vol)
+	if err != nil {
+		return err
+	}
+
+	if activated {
+		defer d.deactivateVolume(vol)
+	}
+
+	volDevPath, err := d.volumeDevPath(vol)
+	if err != nil {
+		return err
+	}
+
+	// Resize filesystem if needed.
+	if vol.contentType == ContentTypeFS {
+		if sizeBytes < oldSizeBytes {
+			// Shrink filesystem to new size first, then shrink thin volume.
+			err = shrinkFileSystem(vol.ConfigBlockFilesystem(), volDevPath, vol, sizeBytes)
+			if err != nil {
+				return err
+			}
+			d.logger.Debug("Thin volume filesystem shrunk", logCtx)
+
+			err = d.resizeThinVolume(vol, sizeBytes)
+			if err != nil {
+				return err
+			}
+		} else if sizeBytes > oldSizeBytes {
+			// Grow thin volume to new size first, then grow filesystem to fill it.
+			err = d.resizeThinVolume(vol, sizeBytes)
+			if err != nil {
+				return err
+			}
+
+			err = growFileSystem(vol.ConfigBlockFilesystem(), volDevPath, vol)
+			if err != nil {
+				return err
+			}
+			d.logger.Debug("Thin volume filesystem grown", logCtx)
+		}
+	} else {
+		if sizeBytes < oldSizeBytes && !vol.allowUnsafeResize {
+			return errors.Wrap(ErrCannotBeShrunk, "You cannot shrink block volumes")
+		}
+
+		err = d.resizeThinVolume(vol, sizeBytes)
+		if err != nil {
+			return err
+		}
+
+		// Move the VM GPT alt header to end of disk if needed (not needed in unsafe resize mode as it is
+		// expected the caller will do all necessary post resize actions themselves).
+		if vol.IsVMBlock() && !vol.allowUnsafeResize {
+			err = d.moveGPTAltHeader(volDevPath)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// GetVolumeDiskPath returns the location of a disk volume.
+func (d *dmthin) GetVolumeDiskPath(vol Volume) (string, error) {
+	if vol.IsVMBlock() || vol.volType == VolumeTypeCustom && vol.contentType == ContentTypeBlock {
+		return d.volumeDevPath(vol)
+	}
+
+	return "", ErrNotSupported
+}
+
+// MountVolume mounts a volume. Returns true if this volume was our mount.
+func (d *dmthin) MountVolume(vol Volume, op *operations.Operation) (bool, error) {
+	// Activate thin volume if needed.
+	activated, err := d.activateVolume(vol)
+	if err != nil {
+		return false, err
+	}
+
+	volDevPath, err := d.volumeDevPath(vol)
+	if err != nil {
+		return false, err
+	}
+
+	// Check if already mounted.
+	mountPath := vol.MountPath()
+	if vol.contentType == ContentTypeFS && !shared.IsMountPoint(mountPath) {
+		err = vol.EnsureMountPath()
+		if err != nil {
+			return false, err
+		}
+
+		mountFlags, mountOptions := resolveMountOptions(vol.ConfigBlockMountOptions())
+		err = TryMount(volDevPath, mountPath, vol.ConfigBlockFilesystem(), mountFlags, mountOptions)
+		if err != nil {
+			return false, errors.Wrapf(err, "Failed to mount thin volume")
+		}
+		d.logger.Debug("Mounted thin volume", log.Ctx{"dev": volDevPath, "path": mountPath, "options": mountOptions})
+
+		return true, nil
+	}
+
+	// For VMs, mount the filesystem volume.
+	if vol.IsVMBlock() {
+		fsVol := vol.NewVMBlockFilesystemVolume()
+		return d.MountVolume(fsVol, op)
+	}
+
+	return activated, nil
+}
+
+// UnmountVolume unmounts a volume. Returns true if we unmounted.
+func (d *dmthin) UnmountVolume(vol Volume, op *operations.Operation) (bool, error) {
+	var err error
+	mountPath := vol.MountPath()
+
+	// Check if already mounted.
+	if vol.contentType == ContentTypeFS && shared.IsMountPoint(mountPath) {
+		err = TryUnmount(mountPath, 0)
+		if err != nil {
+			return false, errors.Wrapf(err, "Failed to unmount thin volume")
+		}
+		d.logger.Debug("Unmounted thin volume", log.Ctx{"path": mountPath})
+
+		// We only deactivate filesystem volumes if an unmount was needed to better align with our
+		// unmount return value indicator.
+		_, err = d.deactivateVolume(vol)
+		if err != nil {
+			return false, err
+		}
+
+		return true, nil
+	}
+
+	deactivated := false
+	if vol.contentType == ContentTypeBlock {
+		deactivated, err = d.deactivateVolume(vol)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	// For VMs, unmount the filesystem volume.
+	if vol.IsVMBlock() {
+		fsVol := vol.NewVMBlockFilesystemVolume()
+		return d.UnmountVolume(fsVol, op)
+	}
+
+	return deactivated, nil
+}
+
+// RenameVolume renames a volume and its snapshots.
+func (d *dmthin) RenameVolume(vol Volume, newVolName string, op *operations.Operation) error {
+	return vol.UnmountTask(func(op *operations.Operation) error {
+		snapNames, err := d.VolumeSnapshots(vol, op)
+		if err != nil {
+			return err
+		}
+
+		revert := revert.New()
+		defer revert.Fail()
+
+		// Rename snapshots (change volume prefix to use new parent volume name).
+		for _, snapName := range snapNames {
+			snapVol, err := vol.NewSnapshot(snapName)
+			if err != nil {
+				return err
+			}
+
+			newSnapVolName := GetSnapshotVolumeName(newVolName, snapName)
+			err = d.renameThinVolume(snapVol, newSnapVolName)
+			if err != nil {
+				return err
+			}
+
+			newSnapVol := NewVolume(d, d.name, vol.volType, vol.contentType, newSnapVolName, vol.config, vol.poolConfig)
+			revert.Add(func() { d.renameThinVolume(newSnapVol, snapVol.name) })
+		}
+
+		// Rename snapshots dir if present.
+		if vol.contentType == ContentTypeFS {
+			srcSnapshotDir := GetVolumeSnapshotDir(d.name, vol.volType, vol.name)
+			dstSnapshotDir := GetVolumeSnapshotDir(d.name, vol.volType, newVolName)
+			if shared.PathExists(srcSnapshotDir) {
+				err = os.Rename(srcSnapshotDir, dstSnapshotDir)
+				if err != nil {
+					return errors.Wrapf(err, "Error renaming thin volume snapshot directory from %q to %q", srcSnapshotDir, dstSnapshotDir)
+				}
+				revert.Add(func() { os.Rename(dstSnapshotDir, srcSnapshotDir) })
+			}
+		}
+
+		// Rename actual volume.
+		err = d.renameThinVolume(vol, newVolName)
+		if err != nil {
+			return err
+		}
+
+		newVol := NewVolume(d, d.name, vol.volType, vol.contentType, newVolName, vol.config, vol.poolConfig)
+		revert.Add(func() { d.renameThinVolume(newVol, vol.name) })
+
+		// Rename volume dir.
+		if vol.contentType == ContentTypeFS {
+			srcVolumePath := GetVolumeMountPath(d.name, vol.volType, vol.name)
+			dstVolumePath := GetVolumeMountPath(d.name, vol.volType, newVolName)
+			err = os.Rename(srcVolumePath, dstVolumePath)
+			if err != nil {
+				return errors.Wrapf(err, "Error renaming thin volume mount path from %q to %q", srcVolumePath, dstVolumePath)
+			}
+			revert.Add(func() { os.Rename(dstVolumePath, srcVolumePath) })
+		}
+
+		// For VMs, also rename the filesystem volume.
+		if vol.IsVMBlock() {
+			fsVol := vol.NewVMBlockFilesystemVolume()
+			err = d.RenameVolume(fsVol, newVolName, op)
+			if err != nil {
+				return err
+			}
+		}
+
+		revert.Success()
+		return nil
+	}, op)
+}
+
+// MigrateVolume sends a volume for migration.
+func (d *dmthin) MigrateVolume(vol Volume, conn io.ReadWriteCloser, volSrcArgs *migration.VolumeSourceArgs, op *operations.Operation) error {
+	// Handle simple rsync and block_and_rsync through generic.
+	if volSrcArgs.MigrationType.FSType == migration.MigrationFSType_RSYNC || volSrcArgs.MigrationType.FSType == migration.MigrationFSType_BLOCK_AND_RSYNC {
+		return genericVFSMigrateVolume(d, d.state, vol, conn, volSrcArgs, op)
+	} else if volSrcArgs.MigrationType.FSType != migration.MigrationFSType_DMTHIN {
+		return ErrNotSupported
+	}
+
+	if vol.IsVMBlock() {
+		fsVol := vol.NewVMBlockFilesystemVolume()
+		err := d.MigrateVolume(fsVol, conn, volSrcArgs, op)
+		if err != nil {
+			return err
+		}
+	}
+
+	// sendVolume sends a single volume stream (relative to parent if not nil) and terminates it.
+	sendVolume := func(sendVol Volume, parent *Volume, name string) error {
+		var w io.WriteCloser = conn
+		if volSrcArgs.TrackProgress {
+			w = &ioprogress.ProgressWriter{
+				WriteCloser: conn,
+				Tracker:     migration.ProgressTracker(op, "fs_progress", name),
+			}
+		}
+
+		err := d.writeVolumeStream(w, sendVol, parent)
+		if err != nil {
+			return err
+		}
+
+		return conn.Close()
+	}
+
+	var finalParent *Volume
+	if !volSrcArgs.FinalSync {
+		// Transfer the snapshots first.
+		for _, snapName := range volSrcArgs.Snapshots {
+			snapshot, err := vol.NewSnapshot(snapName)
+			if err != nil {
+				return err
+			}
+
+			err = sendVolume(snapshot, finalParent, snapshot.name)
+			if err != nil {
+				return err
+			}
+
+			finalParent = &snapshot
+		}
+	}
+
+	srcVol := vol
+	if !vol.IsSnapshot() {
+		// Create a temporary read-only snapshot.
+		srcVolName := fmt.Sprintf("%s.migration-%s", vol.name, uuid.NewRandom().String())
+		srcVol = NewVolume(d, d.name, vol.volType, vol.contentType, srcVolName, vol.config, vol.poolConfig)
+		err := d.createThinSnapshot(vol, srcVol)
+		if err != nil {
+			return err
+		}
+
+		if volSrcArgs.MultiSync {
+			if volSrcArgs.FinalSync {
+				if volSrcArgs.Data != nil {
+					parentVolName := volSrcArgs.Data.(map[ContentType]string)[vol.ContentType()]
+					parentVol := NewVolume(d, d.name, vol.volType, vol.contentType, parentVolName, vol.config, vol.poolConfig)
+					finalParent = &parentVol
+
+					defer d.deleteThinVolume(parentVol)
+				}
+
+				defer d.deleteThinVolume(srcVol)
+			} else {
+				if volSrcArgs.Data == nil {
+					volSrcArgs.Data = map[ContentType]string{}
+				}
+				volSrcArgs.Data.(map[ContentType]string)[vol.ContentType()] = srcVol.name // Persist parent state for final sync.
+			}
+		} else {
+			defer d.deleteThinVolume(srcVol)
+		}
+	}
+
+	// Send the volume itself.
+	return sendVolume(srcVol, finalParent, vol.name)
+}
+
+// BackupVolume copies a volume (and optionally its snapshots) to a specified target path.
+func (d *dmthin) BackupVolume(vol Volume, tarWriter *instancewriter.InstanceTarWriter, optimized bool, snapshots bool, parent string, op *operations.Operation) error {
+	// Handle the non-optimized tarballs through the generic packer.
+	if !optimized {
+		return genericVFSBackupVolume(d, vol, tarWriter, snapshots, op)
+	}
+
+	// Backup VM config volumes first.
+	if vol.IsVMBlock() {
+		fsVol := vol.NewVMBlockFilesystemVolume()
+		err := d.BackupVolume(fsVol, tarWriter, optimized, snapshots, parent, op)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Handle the optimized tarballs.
+	sendToFile := func(sendVol Volume, parent *Volume, fileName string) error {
+		// Create temporary file to store the volume stream.
+		backupsPath := shared.VarPath("backups")
+		tmpFile, err := ioutil.TempFile(backupsPath, "lxd_backup_dmthin")
+		if err != nil {
+			return errors.Wrapf(err, "Failed to open temporary file for dmthin backup")
+		}
+		defer tmpFile.Close()
+		defer os.Remove(tmpFile.Name())
+
+		// Write the volume to the file.
+		d.logger.Debug("Generating optimized volume file", log.Ctx{"vol": sendVol.name, "file": tmpFile.Name(), "name": fileName})
+
+		err = d.writeVolumeStream(tmpFile, sendVol, parent)
+		if err != nil {
+			return err
+		}
+
+		// Get info (importantly size) of the generated file for tarball header.
+		tmpFileInfo, err := os.Lstat(tmpFile.Name())
+		if err != nil {
+			return err
+		}
+
+		err = tarWriter.WriteFile(fileName, tmpFile.Name(), tmpFileInfo, false)
+		if err != nil {
+			return err
+		}
+
+		return tmpFile.Close()
+	}
+
+	// Handle snapshots.
+	var finalParent *Volume
+	if snapshots {
+		// Retrieve the snapshots.
+		volSnapshots, err := d.VolumeSnapshots(vol, op)
+		if err != nil {
+			return err
+		}
+
+		// For incremental backups, only include the snapshots taken after the parent snapshot and send
+		// them relative to it.
+		if parent != "" {
+			volSnapshots, err = backupSnapshotsAfter(volSnapshots, parent)
+			if err != nil {
+				return err
+			}
+
+			parentSnapshot, err := vol.NewSnapshot(parent)
+			if err != nil {
+				return err
+			}
+
+			finalParent = &parentSnapshot
+		}
+
+		for _, snapName := range volSnapshots {
+			snapshot, err := vol.NewSnapshot(snapName)
+			if err != nil {
+				return err
+			}
+
+			// Make a binary dmthin backup.
+			prefix := "snapshots"
+			fileName := fmt.Sprintf("%s.bin", snapName)
+			if vol.volType == VolumeTypeVM {
+				prefix = "virtual-machine-snapshots"
+				if vol.contentType == ContentTypeFS {
+					fileName = fmt.Sprintf("%s-config.bin", snapName)
+				}
+			} else if vol.volType == VolumeTypeCustom {
+				prefix = "volume-snapshots"
+			}
+
+			target := fmt.Sprintf("backup/%s/%s", prefix, fileName)
+			err = sendToFile(snapshot, finalParent, target)
+			if err != nil {
+				return err
+			}
+
+			finalParent = &snapshot
+		}
+	}
+
+	// Create a temporary read-only snapshot.
+	srcVolName := fmt.Sprintf("%s.backup-%s", vol.name, uuid.NewRandom().String())
+	srcVol := NewVolume(d, d.name, vol.volType, vol.contentType, srcVolName, vol.config, vol.poolConfig)
+	err := d.createThinSnapshot(vol, srcVol)
+	if err != nil {
+		return err
+	}
+	defer d.deleteThinVolume(srcVol)
+
+	// Dump the container to a file.
+	fileName := "container.bin"
+	if vol.volType == VolumeTypeVM {
+		if vol.contentType == ContentTypeFS {
+			fileName = "virtual-machine-config.bin"
+		} else {
+			fileName = "virtual-machine.bin"
+		}
+	} else if vol.volType == VolumeTypeCustom {
+		fileName = "volume.bin"
+	}
+
+	err = sendToFile(srcVol, finalParent, fmt.Sprintf("backup/%s", fileName))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// CreateVolumeSnapshot creates a snapshot of a volume.
+func (d *dmthin) CreateVolumeSnapshot(snapVol Volume, op *operations.Operation) error {
+	parentName, _, _ := shared.InstanceGetParentAndSnapshotName(snapVol.name)
+	parentVol := NewVolume(d, d.name, snapVol.volType, snapVol.contentType, parentName, snapVol.config, snapVol.poolConfig)
+	snapPath := snapVol.MountPath()
+
+	// Create the parent directory.
+	err := createParentSnapshotDirIfMissing(d.name, snapVol.volType, parentName)
+	if err != nil {
+		return err
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	// Create snapshot directory.
+	err = snapVol.EnsureMountPath()
+	if err != nil {
+		return err
+	}
+	revert.Add(func() { os.RemoveAll(snapPath) })
+
+	err = d.createThinSnapshot(parentVol, snapVol)
+	if err != nil {
+		return errors.Wrapf(err, "Error creating thin snapshot volume")
+	}
+	revert.Add(func() { d.deleteThinVolume(snapVol) })
+
+	// For VMs, also snapshot the filesystem.
+	if snapVol.IsVMBlock() {
+		parentFSVol := parentVol.NewVMBlockFilesystemVolume()
+		fsVol := snapVol.NewVMBlockFilesystemVolume()
+		err = d.createThinSnapshot(parentFSVol, fsVol)
+		if err != nil {
+			return errors.Wrapf(err, "Error creating thin snapshot volume")
+		}
+	}
+
+	revert.Success()
+	return nil
+}
+
+// DeleteVolumeSnapshot removes a snapshot from the storage device. The volName and snapshotName
+// must be bare names and should not be in the format "volume/snapshot".
+func (d *dmthin) DeleteVolumeSnapshot(snapVol Volume, op *operations.Operation) error {
+	// Remove the snapshot from the storage device.
+	if d.HasVolume(snapVol) {
+		_, err := d.UnmountVolumeSnapshot(snapVol, op)
+		if err != nil {
+			return errors.Wrapf(err, "Error unmounting thin snapshot volume")
+		}
+
+		err = d.deleteThinVolume(snapVol)
+		if err != nil {
+			return errors.Wrapf(err, "Error removing thin snapshot volume")
+		}
+	}
+
+	// For VMs, also remove the snapshot filesystem volume.
+	if snapVol.IsVMBlock() {
+		fsVol := snapVol.NewVMBlockFilesystemVolume()
+		err := d.DeleteVolumeSnapshot(fsVol, op)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Remove the snapshot mount path from the storage device.
+	snapPath := snapVol.MountPath()
+	err := os.RemoveAll(snapPath)
+	if err != nil && !os.IsNotExist(err) {
+		return errors.Wrapf(err, "Error removing thin snapshot mount path %q", snapPath)
+	}
+
+	// Remove the parent snapshot directory if this is the last snapshot being removed.
+	parentName, _, _ := shared.InstanceGetParentAndSnapshotName(snapVol.name)
+	err = deleteParentSnapshotDirIfEmpty(d.name, snapVol.volType, parentName)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// MountVolumeSnapshot sets up a read-only mount on top of the snapshot to avoid accidental modifications.
+func (d *dmthin) MountVolumeSnapshot(snapVol Volume, op *operations.Operation) (bool, error) {
+	var err error
+	mountPath := snapVol.MountPath()
+
+	// Check if already mounted.
+	if snapVol.contentType == ContentTypeFS && !shared.IsMountPoint(mountPath) {
+		revert := revert.New()
+		defer revert.Fail()
+
+		err = snapVol.EnsureMountPath()
+		if err != nil {
+			return false, err
+		}
+
+		// Default to mounting the original snapshot directly. This may be changed below if a temporary
+		// snapshot needs to be taken.
+		mountVol := snapVol
+		mountFlags, mountOptions := resolveMountOptions(mountVol.ConfigBlockMountOptions())
+
+		// Regenerate filesystem UUID if needed. As snapshotting a volume copies its UUID, at mount time
+		// we take another writable snapshot of the snapshot, regenerate the temporary snapshot's UUID and
+		// then mount that. See the LVM driver for the full rationale.
+		regenerateFSUUID := renegerateFilesystemUUIDNeeded(snapVol.ConfigBlockFilesystem())
+		if regenerateFSUUID {
+			// Instantiate a new volume to be the temporary writable snapshot.
+			tmpVolName := fmt.Sprintf("%s%s", snapVol.name, tmpVolSuffix)
+			tmpVol := NewVolume(d, d.name, snapVol.volType, snapVol.contentType, tmpVolName, snapVol.config, snapVol.poolConfig)
+
+			// Create writable snapshot from source snapshot named with a tmpVolSuffix suffix.
+			err = d.createThinSnapshot(snapVol, tmpVol)
+			if err != nil {
+				return false, errors.Wrapf(err, "Error creating temporary thin snapshot volume")
+			}
+
+			revert.Add(func() { d.deleteThinVolume(tmpVol) })
+
+			// We are going to mount the temporary volume instead.
+			mountVol = tmpVol
+		}
+
+		// Activate volume if needed.
+		_, err = d.activateVolume(mountVol)
+		if err != nil {
+			return false, err
+		}
+
+		volDevPath, err := d.volumeDevPath(mountVol)
+		if err != nil {
+			return false, err
+		}
+
+		if regenerateFSUUID {
+			tmpVolFsType := mountVol.ConfigBlockFilesystem()
+
+			// When mounting XFS filesystems temporarily we can use the nouuid option rather than fully
+			// regenerating the filesystem UUID.
+			if tmpVolFsType == "xfs" {
+				idx := strings.Index(mountOptions, "nouuid")
+				if idx < 0 {
+					mountOptions += ",nouuid"
+				}
+			} else {
+				d.logger.Debug("Regenerating filesystem UUID", log.Ctx{"dev": volDevPath, "fs": tmpVolFsType})
+				err = regenerateFilesystemUUID(mountVol.ConfigBlockFilesystem(), volDevPath)
+				if err != nil {
+					return false, err
+				}
+			}
+		}
+
+		// Finally attempt to mount the volume that needs mounting.
+		err = TryMount(volDevPath, mountPath, mountVol.ConfigBlockFilesystem(), mountFlags|unix.MS_RDONLY, mountOptions)
+		if err != nil {
+			return false, errors.Wrapf(err, "Failed to mount thin snapshot volume")
+		}
+		d.logger.Debug("Mounted thin snapshot volume", log.Ctx{"dev": volDevPath, "path": mountPath, "options": mountOptions})
+
+		revert.Success()
+		return true, nil
+	}
+
+	activated := false
+	if snapVol.contentType == ContentTypeBlock {
+		// Activate volume if needed.
+		activated, err = d.activateVolume(snapVol)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	// For VMs, mount the filesystem volume.
+	if snapVol.IsVMBlock() {
+		fsVol := snapVol.NewVMBlockFilesystemVolume()
+		return d.MountVolumeSnapshot(fsVol, op)
+	}
+
+	return activated, nil
+}
+
+// UnmountVolumeSnapshot removes the read-only mount placed on top of a snapshot.
+// If a temporary snapshot volume exists then it will attempt to remove it.
+func (d *dmthin) UnmountVolumeSnapshot(snapVol Volume, op *operations.Operation) (bool, error) {
+	var err error
+	mountPath := snapVol.MountPath()
+
+	// Check if already mounted.
+	if snapVol.contentType == ContentTypeFS && shared.IsMountPoint(mountPath) {
+		err = TryUnmount(mountPath, 0)
+		if err != nil {
+			return false, errors.Wrapf(err, "Failed to unmount thin snapshot volume")
+		}
+		d.logger.Debug("Unmounted thin snapshot volume", log.Ctx{"path": mountPath})
+
+		// Check if a temporary snapshot exists, and if so remove it.
+		tmpVolName := fmt.Sprintf("%s%s", snapVol.name, tmpVolSuffix)
+		tmpVol := NewVolume(d, d.name, snapVol.volType, snapVol.contentType, tmpVolName, snapVol.config, snapVol.poolConfig)
+		if d.HasVolume(tmpVol) {
+			err = d.deleteThinVolume(tmpVol)
+			if err != nil {
+				return true, errors.Wrapf(err, "Failed to remove temporary thin snapshot volume %q", tmpVolName)
+			}
+		}
+
+		// We only deactivate filesystem volumes if an unmount was needed to better align with our
+		// unmount return value indicator.
+		_, err = d.deactivateVolume(snapVol)
+		if err != nil {
+			return false, err
+		}
+
+		return true, nil
+	}
+
+	deactivated := false
+	if snapVol.contentType == ContentTypeBlock {
+		deactivated, err = d.deactivateVolume(snapVol)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	// For VMs, unmount the filesystem volume.
+	if snapVol.IsVMBlock() {
+		fsVol := snapVol.NewVMBlockFilesystemVolume()
+		return d.UnmountVolumeSnapshot(fsVol, op)
+	}
+
+	return deactivated, nil
+}
+
+// VolumeSnapshots returns a list of snapshots for the volume.
+func (d *dmthin) VolumeSnapshots(vol Volume, op *operations.Operation) ([]string, error) {
+	return d.thinVolumeSnapshots(vol)
+}
+
+// RestoreVolume restores a volume from a snapshot.
+func (d *dmthin) RestoreVolume(vol Volume, snapshotName string, op *operations.Operation) error {
+	// Instantiate snapshot volume from snapshot name.
+	snapVol, err := vol.NewSnapshot(snapshotName)
+	if err != nil {
+		return err
+	}
+
+	revert := revert.New()
+	defer revert.Fail()
+
+	// The process for restoring a snapshot is as follows:
+	// 1. Create a writable snapshot with a temporary name from the snapshot being restored.
+	// 2. Swap the thin devices of the original volume and the temporary volume.
+	// 3. Delete the temporary volume (now holding the original volume's device).
+	_, err = d.UnmountVolume(vol, op)
+	if err != nil {
+		return errors.Wrapf(err, "Error unmounting thin volume")
+	}
+
+	_, err = d.deactivateVolume(vol)
+	if err != nil {
+		return err
+	}
+
+	tmpVolName := fmt.Sprintf("%s%s", vol.name, tmpVolSuffix)
+	tmpVol := NewVolume(d, d.name, vol.volType, vol.contentType, tmpVolName, vol.config, vol.poolConfig)
+
+	err = d.createThinSnapshot(snapVol, tmpVol)
+	if err != nil {
+		return errors.Wrapf(err, "Error restoring thin snapshot volume")
+	}
+
+	revert.Add(func() { d.deleteThinVolume(tmpVol) })
+
+	err = d.swapThinVolumes(vol, tmpVol)
+	if err != nil {
+		return err
+	}
+
+	revert.Add(func() { d.swapThinVolumes(vol, tmpVol) })
+
+	// If the volume's filesystem needs to have its UUID regenerated to allow mount then do so now.
+	if vol.contentType == ContentTypeFS && renegerateFilesystemUUIDNeeded(vol.ConfigBlockFilesystem()) {
+		err = d.regenerateVolumeFSUUID(vol)
+		if err != nil {
+			return err
+		}
+	}
+
+	// For VMs, also restore the filesystem volume.
+	if vol.IsVMBlock() {
+		fsVol := vol.NewVMBlockFilesystemVolume()
+		err = d.RestoreVolume(fsVol, snapshotName, op)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Finally remove the original thin volume. Should always be the last step to allow revert.
+	err = d.deleteThinVolume(tmpVol)
+	if err != nil {
+		return errors.Wrapf(err, "Error removing original thin volume")
+	}
+
+	revert.Success()
+	return nil
+}
+
+// RenameVolumeSnapshot renames a volume snapshot.
+func (d *dmthin) RenameVolumeSnapshot(snapVol Volume, newSnapshotName string, op *operations.Operation) error {
+	parentName, _, _ := shared.InstanceGetParentAndSnapshotName(snapVol.name)
+	newSnapVolName := GetSnapshotVolumeName(parentName, newSnapshotName)
+	err := d.renameThinVolume(snapVol, newSnapVolName)
+	if err != nil {
+		return errors.Wrapf(err, "Error renaming thin snapshot volume")
+	}
+
+	oldPath := snapVol.MountPath()
+	newPath := GetVolumeMountPath(d.name, snapVol.volType, newSnapVolName)
+	err = os.Rename(oldPath, newPath)
+	if err != nil {
+		return errors.Wrapf(err, "Error renaming snapshot mount path from %q to %q", oldPath, newPath)
+	}
+
+	return nil
+}
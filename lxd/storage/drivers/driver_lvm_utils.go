@@ -474,6 +474,8 @@ func (d *lvm) lvmFullVolumeName(volType VolumeType, contentType ContentType, vol
 		volTypePrefix = "images"
 	case VolumeTypeCustom:
 		volTypePrefix = "custom"
+	case VolumeTypeBucket:
+		volTypePrefix = "buckets"
 	}
 
 	// Invalid volume type supplied.
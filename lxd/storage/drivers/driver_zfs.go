@@ -48,6 +48,7 @@ func (d *zfs) load() error {
 		"storage_zfs_volmode":                      d.patchStorageZFSVolMode,
 		"storage_rename_custom_volume_add_project": nil,
 		"storage_lvm_skipactivation":               nil,
+		"storage_create_buckets":                   d.patchStorageCreateVM,
 	}
 
 	// Done if previously loaded.
@@ -109,7 +110,7 @@ func (d *zfs) Info() Info {
 		OptimizedBackups:      true,
 		PreservesInodes:       true,
 		Remote:                d.isRemote(),
-		VolumeTypes:           []VolumeType{VolumeTypeCustom, VolumeTypeImage, VolumeTypeContainer, VolumeTypeVM},
+		VolumeTypes:           []VolumeType{VolumeTypeCustom, VolumeTypeImage, VolumeTypeContainer, VolumeTypeVM, VolumeTypeBucket},
 		BlockBacking:          false,
 		RunningQuotaResize:    true,
 		RunningSnapshotFreeze: false,
@@ -253,7 +253,17 @@ func (d *zfs) CreateVolumeFromBackup(vol Volume, srcBackup backup.Info, srcData
 		return genericVFSBackupUnpack(d, vol, srcBackup.Snapshots, srcData, op)
 	}
 
-	if d.HasVolume(vol) {
+	if srcBackup.Parent != "" {
+		// Incremental backups are received on top of the volume restored from the parent backup.
+		parentSnapshot, err := vol.NewSnapshot(srcBackup.ParentSnapshot)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if !d.HasVolume(vol) || !d.HasVolume(parentSnapshot) {
+			return nil, nil, fmt.Errorf("Cannot restore incremental backup, parent snapshot %q doesn't exist on target", srcBackup.ParentSnapshot)
+		}
+	} else if d.HasVolume(vol) {
 		return nil, nil, fmt.Errorf("Cannot restore volume, already exists on target")
 	}
 
@@ -280,8 +290,17 @@ func (d *zfs) CreateVolumeFromBackup(vol Volume, srcBackup backup.Info, srcData
 			d.DeleteVolumeSnapshot(snapVol, op)
 		}
 
-		// And lastly the main volume.
-		d.DeleteVolume(vol, op)
+		// And lastly the main volume. When it existed before an incremental restore, roll it back to the
+		// parent snapshot instead, as the receive may have left it partially updated.
+		if srcBackup.Parent == "" {
+			d.DeleteVolume(vol, op)
+		} else {
+			parentSnapshot := fmt.Sprintf("%s@snapshot-%s", d.dataset(vol, false), srcBackup.ParentSnapshot)
+			_, err := shared.RunCommand("zfs", "rollback", "-r", parentSnapshot)
+			if err != nil {
+				d.Logger().Warn("Failed rolling back volume after failed incremental restore", log.Ctx{"snapshot": parentSnapshot, "err": err})
+			}
+		}
 	}
 
 	// Only execute the revert function if we have had an error internally.
@@ -349,6 +368,8 @@ func (d *zfs) CreateVolumeFromBackup(vol Volume, srcBackup backup.Info, srcData
 			if vol.contentType == ContentTypeFS {
 				fileName = fmt.Sprintf("%s-config.bin", snapName)
 			}
+		} else if vol.volType == VolumeTypeCustom {
+			prefix = "volume-snapshots"
 		}
 
 		srcFile := fmt.Sprintf("backup/%s/%s", prefix, fileName)
@@ -367,6 +388,8 @@ func (d *zfs) CreateVolumeFromBackup(vol Volume, srcBackup backup.Info, srcData
 		} else {
 			fileName = "virtual-machine.bin"
 		}
+	} else if vol.volType == VolumeTypeCustom {
+		fileName = "volume.bin"
 	}
 
 	err = unpackVolume(srcData, unpacker, fmt.Sprintf("backup/%s", fileName), d.dataset(vol, false))
@@ -1300,7 +1323,7 @@ func (d *zfs) MigrateVolume(vol Volume, conn io.ReadWriteCloser, volSrcArgs *mig
 }
 
 // BackupVolume creates an exported version of a volume.
-func (d *zfs) BackupVolume(vol Volume, tarWriter *instancewriter.InstanceTarWriter, optimized bool, snapshots bool, op *operations.Operation) error {
+func (d *zfs) BackupVolume(vol Volume, tarWriter *instancewriter.InstanceTarWriter, optimized bool, snapshots bool, parent string, op *operations.Operation) error {
 	// Handle the non-optimized tarballs through the generic packer.
 	if !optimized {
 		// For block volumes that are exporting snapshots, we need to activate parent volume first so that
@@ -1373,7 +1396,7 @@ func (d *zfs) BackupVolume(vol Volume, tarWriter *instancewriter.InstanceTarWrit
 	// Backup VM config volumes first.
 	if vol.IsVMBlock() {
 		fsVol := vol.NewVMBlockFilesystemVolume()
-		err := d.BackupVolume(fsVol, tarWriter, optimized, snapshots, op)
+		err := d.BackupVolume(fsVol, tarWriter, optimized, snapshots, parent, op)
 		if err != nil {
 			return err
 		}
@@ -1429,16 +1452,21 @@ func (d *zfs) BackupVolume(vol Volume, tarWriter *instancewriter.InstanceTarWrit
 			return err
 		}
 
-		for i, snapName := range volSnapshots {
-			snapshot, _ := vol.NewSnapshot(snapName)
-
-			// Figure out parent and current subvolumes.
-			parent := ""
-			if i > 0 {
-				oldSnapshot, _ := vol.NewSnapshot(volSnapshots[i-1])
-				parent = d.dataset(oldSnapshot, false)
+		// For incremental backups, only include the snapshots taken after the parent snapshot and send
+		// them relative to it.
+		if parent != "" {
+			volSnapshots, err = backupSnapshotsAfter(volSnapshots, parent)
+			if err != nil {
+				return err
 			}
 
+			parentSnapshot, _ := vol.NewSnapshot(parent)
+			finalParent = d.dataset(parentSnapshot, false)
+		}
+
+		for _, snapName := range volSnapshots {
+			snapshot, _ := vol.NewSnapshot(snapName)
+
 			// Make a binary zfs backup.
 			prefix := "snapshots"
 			fileName := fmt.Sprintf("%s.bin", snapName)
@@ -1447,10 +1475,12 @@ func (d *zfs) BackupVolume(vol Volume, tarWriter *instancewriter.InstanceTarWrit
 				if vol.contentType == ContentTypeFS {
 					fileName = fmt.Sprintf("%s-config.bin", snapName)
 				}
+			} else if vol.volType == VolumeTypeCustom {
+				prefix = "volume-snapshots"
 			}
 
 			target := fmt.Sprintf("backup/%s/%s", prefix, fileName)
-			err := sendToFile(d.dataset(snapshot, false), parent, target)
+			err := sendToFile(d.dataset(snapshot, false), finalParent, target)
 			if err != nil {
 				return err
 			}
@@ -1475,6 +1505,8 @@ func (d *zfs) BackupVolume(vol Volume, tarWriter *instancewriter.InstanceTarWrit
 		} else {
 			fileName = "virtual-machine.bin"
 		}
+	} else if vol.volType == VolumeTypeCustom {
+		fileName = "volume.bin"
 	}
 
 	err = sendToFile(srcSnapshot, finalParent, fmt.Sprintf("backup/%s", fileName))
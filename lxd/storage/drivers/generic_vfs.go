@@ -456,7 +456,7 @@ func genericVFSBackupVolume(d Driver, vol Volume, tarWriter *instancewriter.Inst
 			// Reset hard link cache as we are copying a new volume (instance or snapshot).
 			tarWriter.ResetHardLinkMap()
 
-			if v.IsVMBlock() {
+			if v.IsVMBlock() || v.IsCustomBlock() {
 				blockPath, err := d.GetVolumeDiskPath(v)
 				if err != nil {
 					return errors.Wrapf(err, "Error getting VM block volume disk path")
@@ -477,31 +477,34 @@ func genericVFSBackupVolume(d Driver, vol Volume, tarWriter *instancewriter.Inst
 					exclude = append(exclude, blockPath)
 				}
 
-				d.Logger().Debug("Copying virtual machine config volume", log.Ctx{"sourcePath": mountPath, "prefix": prefix})
-				err = filepath.Walk(mountPath, func(srcPath string, fi os.FileInfo, err error) error {
-					if err != nil {
-						return err
-					}
+				// Custom block volumes don't have a config volume.
+				if v.IsVMBlock() {
+					d.Logger().Debug("Copying virtual machine config volume", log.Ctx{"sourcePath": mountPath, "prefix": prefix})
+					err = filepath.Walk(mountPath, func(srcPath string, fi os.FileInfo, err error) error {
+						if err != nil {
+							return err
+						}
 
-					// Skip any exluded files.
-					if shared.StringInSlice(srcPath, exclude) {
-						return nil
-					}
+						// Skip any exluded files.
+						if shared.StringInSlice(srcPath, exclude) {
+							return nil
+						}
 
-					name := filepath.Join(prefix, strings.TrimPrefix(srcPath, mountPath))
-					err = tarWriter.WriteFile(name, srcPath, fi, false)
+						name := filepath.Join(prefix, strings.TrimPrefix(srcPath, mountPath))
+						err = tarWriter.WriteFile(name, srcPath, fi, false)
+						if err != nil {
+							return errors.Wrapf(err, "Error adding %q as %q to tarball", srcPath, name)
+						}
+
+						return nil
+					})
 					if err != nil {
-						return errors.Wrapf(err, "Error adding %q as %q to tarball", srcPath, name)
+						return err
 					}
-
-					return nil
-				})
-				if err != nil {
-					return err
 				}
 
 				name := fmt.Sprintf("%s.img", prefix)
-				d.Logger().Debug("Copying virtual machine block volume", log.Ctx{"sourcePath": blockPath, "file": name, "size": blockDiskSize})
+				d.Logger().Debug("Copying block volume", log.Ctx{"sourcePath": blockPath, "file": name, "size": blockDiskSize})
 				from, err := os.Open(blockPath)
 				if err != nil {
 					return errors.Wrapf(err, "Error opening file for reading %q", blockPath)
@@ -520,7 +523,7 @@ func genericVFSBackupVolume(d Driver, vol Volume, tarWriter *instancewriter.Inst
 					return errors.Wrapf(err, "Error copying %q as %q to tarball", blockPath, name)
 				}
 			} else {
-				d.Logger().Debug("Copying container filesystem volume", log.Ctx{"sourcePath": mountPath, "prefix": prefix})
+				d.Logger().Debug("Copying filesystem volume", log.Ctx{"sourcePath": mountPath, "prefix": prefix})
 				return filepath.Walk(mountPath, func(srcPath string, fi os.FileInfo, err error) error {
 					if err != nil {
 						if os.IsNotExist(err) {
@@ -554,6 +557,8 @@ func genericVFSBackupVolume(d Driver, vol Volume, tarWriter *instancewriter.Inst
 		snapshotsPrefix := "backup/snapshots"
 		if vol.IsVMBlock() {
 			snapshotsPrefix = "backup/virtual-machine-snapshots"
+		} else if vol.volType == VolumeTypeCustom {
+			snapshotsPrefix = "backup/volume-snapshots"
 		}
 
 		// List the snapshots.
@@ -576,6 +581,8 @@ func genericVFSBackupVolume(d Driver, vol Volume, tarWriter *instancewriter.Inst
 	prefix := "backup/container"
 	if vol.IsVMBlock() {
 		prefix = "backup/virtual-machine"
+	} else if vol.volType == VolumeTypeCustom {
+		prefix = "backup/volume"
 	}
 
 	err := backupVolume(vol, prefix)
@@ -596,40 +603,45 @@ func genericVFSBackupUnpack(d Driver, vol Volume, snapshots []string, srcData io
 		volTypeName := "container"
 		if vol.IsVMBlock() {
 			volTypeName = "virtual machine"
+		} else if vol.volType == VolumeTypeCustom {
+			volTypeName = "custom"
 		}
 
-		// Clear the volume ready for unpack.
-		err := wipeDirectory(mountPath)
-		if err != nil {
-			return errors.Wrapf(err, "Error clearing volume before unpack")
-		}
-
-		// Prepare tar arguments.
-		srcParts := strings.Split(srcPrefix, string(os.PathSeparator))
-		args := append(tarArgs, []string{
-			"-",
-			"--xattrs-include=*",
-			fmt.Sprintf("--strip-components=%d", len(srcParts)),
-			"-C", mountPath, srcPrefix,
-		}...)
-
-		// Extract filesystem volume.
-		d.Logger().Debug(fmt.Sprintf("Unpacking %s filesystem volume", volTypeName), log.Ctx{"source": srcPrefix, "target": mountPath})
-		srcData.Seek(0, 0)
-		err = shared.RunCommandWithFds(r, nil, "tar", args...)
-		if err != nil {
-			return errors.Wrapf(err, "Error starting unpack")
+		// Custom block volumes don't have a filesystem part.
+		if !vol.IsCustomBlock() {
+			// Clear the volume ready for unpack.
+			err := wipeDirectory(mountPath)
+			if err != nil {
+				return errors.Wrapf(err, "Error clearing volume before unpack")
+			}
+
+			// Prepare tar arguments.
+			srcParts := strings.Split(srcPrefix, string(os.PathSeparator))
+			args := append(tarArgs, []string{
+				"-",
+				"--xattrs-include=*",
+				fmt.Sprintf("--strip-components=%d", len(srcParts)),
+				"-C", mountPath, srcPrefix,
+			}...)
+
+			// Extract filesystem volume.
+			d.Logger().Debug(fmt.Sprintf("Unpacking %s filesystem volume", volTypeName), log.Ctx{"source": srcPrefix, "target": mountPath})
+			srcData.Seek(0, 0)
+			err = shared.RunCommandWithFds(r, nil, "tar", args...)
+			if err != nil {
+				return errors.Wrapf(err, "Error starting unpack")
+			}
 		}
 
-		// Extract block file to block volume if VM.
-		if vol.IsVMBlock() {
+		// Extract block file to block volume if VM or custom block volume.
+		if vol.IsVMBlock() || vol.IsCustomBlock() {
 			targetPath, err := d.GetVolumeDiskPath(vol)
 			if err != nil {
 				return err
 			}
 
 			srcFile := fmt.Sprintf("%s.img", srcPrefix)
-			d.Logger().Debug("Unpacking virtual machine block volume", log.Ctx{"source": srcFile, "target": targetPath})
+			d.Logger().Debug(fmt.Sprintf("Unpacking %s block volume", volTypeName), log.Ctx{"source": srcFile, "target": targetPath})
 
 			tr, cancelFunc, err := shared.CompressedTarReader(context.Background(), r, unpacker)
 			if err != nil {
@@ -713,6 +725,8 @@ func genericVFSBackupUnpack(d Driver, vol Volume, snapshots []string, srcData io
 	backupSnapshotsPrefix := "backup/snapshots"
 	if vol.IsVMBlock() {
 		backupSnapshotsPrefix = "backup/virtual-machine-snapshots"
+	} else if vol.volType == VolumeTypeCustom {
+		backupSnapshotsPrefix = "backup/volume-snapshots"
 	}
 
 	for _, snapName := range snapshots {
@@ -757,6 +771,8 @@ func genericVFSBackupUnpack(d Driver, vol Volume, snapshots []string, srcData io
 	backupPrefix := "backup/container"
 	if vol.IsVMBlock() {
 		backupPrefix = "backup/virtual-machine"
+	} else if vol.volType == VolumeTypeCustom {
+		backupPrefix = "backup/volume"
 	}
 
 	mountPath := vol.MountPath()
@@ -9,6 +9,7 @@ var drivers = map[string]func() driver{
 	"btrfs":  func() driver { return &btrfs{} },
 	"cephfs": func() driver { return &cephfs{} },
 	"dir":    func() driver { return &dir{} },
+	"dmthin": func() driver { return &dmthin{} },
 	"lvm":    func() driver { return &lvm{} },
 	"zfs":    func() driver { return &zfs{} },
 	"ceph":   func() driver { return &ceph{} },
@@ -8,6 +8,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -16,6 +17,7 @@ import (
 
 	"github.com/lxc/lxd/lxd/operations"
 	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/idmap"
 )
 
@@ -794,6 +796,58 @@ func BlockDiskSizeBytes(blockDiskPath string) (int64, error) {
 	return fi.Size(), nil
 }
 
+// BlockDiskIOStats returns the I/O counters of the block device backing a path. The path is either a block device
+// or a path on a filesystem mounted from a block device. Returns ErrNotSupported if no block device backs the path.
+func BlockDiskIOStats(path string) (*api.StorageVolumeStateIO, error) {
+	var stat unix.Stat_t
+	err := unix.Stat(path, &stat)
+	if err != nil {
+		return nil, err
+	}
+
+	dev := uint64(stat.Dev)
+	if stat.Mode&unix.S_IFMT == unix.S_IFBLK {
+		dev = uint64(stat.Rdev)
+	}
+
+	content, err := ioutil.ReadFile(fmt.Sprintf("/sys/dev/block/%d:%d/stat", unix.Major(dev), unix.Minor(dev)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNotSupported
+		}
+
+		return nil, err
+	}
+
+	return parseBlockDiskStat(string(content))
+}
+
+// parseBlockDiskStat parses the content of the sysfs stat file of a block device.
+func parseBlockDiskStat(content string) (*api.StorageVolumeStateIO, error) {
+	fields := strings.Fields(content)
+	if len(fields) < 7 {
+		return nil, fmt.Errorf("Invalid block device stat %q", content)
+	}
+
+	values := make([]uint64, 7)
+	for i := range values {
+		value, err := strconv.ParseUint(fields[i], 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Invalid block device stat %q", content)
+		}
+
+		values[i] = value
+	}
+
+	// The block layer always counts in 512 bytes sectors, regardless of the device's sector size.
+	return &api.StorageVolumeStateIO{
+		ReadsCompleted:  values[0],
+		BytesRead:       values[2] * 512,
+		WritesCompleted: values[4],
+		BytesWritten:    values[6] * 512,
+	}, nil
+}
+
 // PathNameEncode encodes a path string to be used as part of a file name.
 // The encoding scheme replaces "-" with "--" and then "/" with "-".
 func PathNameEncode(text string) string {
@@ -812,3 +866,15 @@ func PathNameDecode(text string) string {
 func OperationLockName(poolName string, volType string, volName string) string {
 	return fmt.Sprintf("%s/%s/%s", poolName, volType, volName)
 }
+
+// backupSnapshotsAfter returns the snapshots (oldest first) taken after the parent snapshot that an
+// incremental backup is based on.
+func backupSnapshotsAfter(snapshots []string, parent string) ([]string, error) {
+	for i, snapName := range snapshots {
+		if snapName == parent {
+			return snapshots[i+1:], nil
+		}
+	}
+
+	return nil, fmt.Errorf("Parent snapshot %q of incremental backup not found", parent)
+}
@@ -35,3 +35,42 @@ func TestGetVolumeMountPath(t *testing.T) {
 	expected = GetPoolMountPath(poolName) + "/virtual-machines/testvol"
 	assert.Equal(t, expected, path)
 }
+
+// Test backupSnapshotsAfter
+func TestBackupSnapshotsAfter(t *testing.T) {
+	snapshots := []string{"snap0", "snap1", "snap2"}
+
+	// Test snapshots after a middle snapshot.
+	after, err := backupSnapshotsAfter(snapshots, "snap1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"snap2"}, after)
+
+	// Test parent being the most recent snapshot.
+	after, err = backupSnapshotsAfter(snapshots, "snap2")
+	assert.NoError(t, err)
+	assert.Empty(t, after)
+
+	// Test missing parent snapshot.
+	_, err = backupSnapshotsAfter(snapshots, "snap3")
+	assert.Error(t, err)
+}
+
+// Test parseBlockDiskStat
+func TestParseBlockDiskStat(t *testing.T) {
+	content := "    1234       56    78900     4321     2345       67    98700     5432        0     3456     9753     10     0     20     0     0     0\n"
+
+	stats, err := parseBlockDiskStat(content)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1234), stats.ReadsCompleted)
+	assert.Equal(t, uint64(78900*512), stats.BytesRead)
+	assert.Equal(t, uint64(2345), stats.WritesCompleted)
+	assert.Equal(t, uint64(98700*512), stats.BytesWritten)
+
+	// Test truncated content.
+	_, err = parseBlockDiskStat("1234 56 78900")
+	assert.Error(t, err)
+
+	// Test invalid counters.
+	_, err = parseBlockDiskStat("1234 56 foo 4321 2345 67 98700")
+	assert.Error(t, err)
+}
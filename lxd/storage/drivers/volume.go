@@ -46,6 +46,9 @@ const VolumeTypeContainer = VolumeType("containers")
 // VolumeTypeVM represents a virtual-machine storage volume.
 const VolumeTypeVM = VolumeType("virtual-machines")
 
+// VolumeTypeBucket represents a storage bucket volume.
+const VolumeTypeBucket = VolumeType("buckets")
+
 // ContentType indicates the format of the volume.
 type ContentType string
 
@@ -62,6 +65,7 @@ var BaseDirectories = map[VolumeType][]string{
 	VolumeTypeCustom:    {"custom", "custom-snapshots"},
 	VolumeTypeImage:     {"images"},
 	VolumeTypeVM:        {"virtual-machines", "virtual-machines-snapshots"},
+	VolumeTypeBucket:    {"buckets"},
 }
 
 // Volume represents a storage volume, and provides functions to mount and unmount it.
@@ -316,8 +320,13 @@ func (v Volume) IsVMBlock() bool {
 	return (v.volType == VolumeTypeVM || v.volType == VolumeTypeImage) && v.contentType == ContentTypeBlock
 }
 
+// IsCustomBlock returns true if volume is a custom block volume.
+func (v Volume) IsCustomBlock() bool {
+	return v.volType == VolumeTypeCustom && v.contentType == ContentTypeBlock
+}
+
 // NewVMBlockFilesystemVolume returns a copy of the volume with the content type set to ContentTypeFS and the
-// config "size" property set to vmBlockFilesystemSize.
+// config "size" property set to vmBlockFilesystemSize plus any space reserved for the VM state by "size.state".
 func (v Volume) NewVMBlockFilesystemVolume() Volume {
 	// Copy volume config so modifications don't affect original volume.
 	newConf := make(map[string]string, len(v.config))
@@ -325,8 +334,15 @@ func (v Volume) NewVMBlockFilesystemVolume() Volume {
 		newConf[k] = v
 	}
 
-	// VM Block filesystems are a fixed size.
+	// VM Block filesystems are a fixed size, grown by the space needed to store the VM state.
 	newConf["size"] = vmBlockFilesystemSize
+	if v.config["size.state"] != "" {
+		baseSizeBytes, _ := units.ParseByteSizeString(vmBlockFilesystemSize)
+		stateSizeBytes, err := units.ParseByteSizeString(v.config["size.state"])
+		if err == nil && stateSizeBytes > 0 {
+			newConf["size"] = fmt.Sprintf("%dB", baseSizeBytes+stateSizeBytes)
+		}
+	}
 
 	return NewVolume(v.driver, v.pool, v.volType, ContentTypeFS, v.name, newConf, v.poolConfig)
 }
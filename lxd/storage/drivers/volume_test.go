@@ -110,3 +110,25 @@ func Test_Volume_ConfigSizeFromSource(t *testing.T) {
 		assert.Equal(t, test.err, err)
 	}
 }
+
+// Test Volume_NewVMBlockFilesystemVolume
+func Test_Volume_NewVMBlockFilesystemVolume(t *testing.T) {
+	driver := dir{}
+
+	// The filesystem volume has a fixed size when no space is reserved for the VM state.
+	vol := Volume{driver: &driver, volType: VolumeTypeVM, contentType: ContentTypeBlock, config: map[string]string{"size": "10GB"}}
+	fsVol := vol.NewVMBlockFilesystemVolume()
+	assert.Equal(t, ContentTypeFS, fsVol.ContentType())
+	assert.Equal(t, vmBlockFilesystemSize, fsVol.ExpandedConfig("size"))
+	assert.Equal(t, "10GB", vol.ExpandedConfig("size"))
+
+	// The space reserved for the VM state is added to the filesystem volume.
+	vol.config["size.state"] = "1GB"
+	fsVol = vol.NewVMBlockFilesystemVolume()
+	assert.Equal(t, "1100000000B", fsVol.ExpandedConfig("size"))
+
+	// An invalid state size is ignored.
+	vol.config["size.state"] = "invalid"
+	fsVol = vol.NewVMBlockFilesystemVolume()
+	assert.Equal(t, vmBlockFilesystemSize, fsVol.ExpandedConfig("size"))
+}
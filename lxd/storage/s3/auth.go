@@ -0,0 +1,458 @@
+package s3
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	signatureAlgorithm = "AWS4-HMAC-SHA256"
+	chunkAlgorithm     = "AWS4-HMAC-SHA256-PAYLOAD"
+	amzDateFormat      = "20060102T150405Z"
+	unsignedPayload    = "UNSIGNED-PAYLOAD"
+	streamingPayload   = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD"
+
+	// Maximum allowed difference between the request time and the server time.
+	maxClockSkew = 15 * time.Minute
+
+	// Maximum validity of presigned URLs.
+	maxPresignedExpiry = 7 * 24 * time.Hour
+)
+
+// request represents an authenticated S3 request.
+type request struct {
+	bucket *Bucket
+	body   io.Reader // Decoded request body, checked against the signed payload hash.
+	size   int64     // Decoded content length, -1 if unknown.
+}
+
+// signature holds the AWS signature version 4 parameters of a request.
+type signature struct {
+	accessKey     string
+	date          string
+	region        string
+	service       string
+	amzDate       time.Time
+	signedHeaders []string
+	signature     string
+	payloadHash   string
+	presigned     bool
+	expires       time.Duration
+}
+
+// authenticate verifies the signature of the request and returns the bucket the access key grants access to.
+func (s *Server) authenticate(r *http.Request) (*request, error) {
+	var sig *signature
+	var err error
+
+	if strings.HasPrefix(r.Header.Get("Authorization"), signatureAlgorithm+" ") {
+		sig, err = parseAuthorizationHeader(r)
+	} else if r.URL.Query().Get("X-Amz-Algorithm") == signatureAlgorithm {
+		sig, err = parsePresignedQuery(r)
+	} else {
+		return nil, errAccessDenied
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Check the request time.
+	now := s.now()
+	if sig.presigned {
+		if now.Before(sig.amzDate.Add(-maxClockSkew)) || now.After(sig.amzDate.Add(sig.expires)) {
+			return nil, errExpiredRequest
+		}
+	} else if now.Sub(sig.amzDate) > maxClockSkew || sig.amzDate.Sub(now) > maxClockSkew {
+		return nil, errRequestTimeTooSkewed
+	}
+
+	bucket, err := s.lookup(sig.accessKey)
+	if err != nil {
+		if err == ErrUnknownAccessKey {
+			return nil, errInvalidAccessKeyID
+		}
+
+		return nil, err
+	}
+
+	expected := computeSignature(r, sig, bucket.SecretKey)
+	if !hmac.Equal([]byte(expected), []byte(sig.signature)) {
+		return nil, errSignatureDoesNotMatch
+	}
+
+	req := &request{
+		bucket: bucket,
+		body:   r.Body,
+		size:   r.ContentLength,
+	}
+
+	switch sig.payloadHash {
+	case unsignedPayload:
+	case streamingPayload:
+		// Each chunk is signed using the signature of the previous one, starting with the request signature.
+		req.body = &chunkedReader{
+			r:         bufio.NewReader(r.Body),
+			key:       signingKey(sig, bucket.SecretKey),
+			amzDate:   sig.amzDate.Format(amzDateFormat),
+			scope:     sig.scope(),
+			signature: sig.signature,
+		}
+
+		req.size, err = strconv.ParseInt(r.Header.Get("X-Amz-Decoded-Content-Length"), 10, 64)
+		if err != nil {
+			return nil, errInvalidRequest("Missing or invalid x-amz-decoded-content-length header")
+		}
+
+	default:
+		req.body = &hashReader{r: r.Body, h: sha256.New(), expected: sig.payloadHash}
+	}
+
+	return req, nil
+}
+
+// parseAuthorizationHeader parses the signature parameters from the Authorization header.
+func parseAuthorizationHeader(r *http.Request) (*signature, error) {
+	sig := &signature{}
+
+	fields := strings.Split(strings.TrimPrefix(r.Header.Get("Authorization"), signatureAlgorithm+" "), ",")
+	for _, field := range fields {
+		parts := strings.SplitN(strings.TrimSpace(field), "=", 2)
+		if len(parts) != 2 {
+			return nil, errInvalidArgument("Invalid Authorization header")
+		}
+
+		switch parts[0] {
+		case "Credential":
+			err := sig.parseCredential(parts[1])
+			if err != nil {
+				return nil, err
+			}
+
+		case "SignedHeaders":
+			sig.signedHeaders = strings.Split(parts[1], ";")
+		case "Signature":
+			sig.signature = parts[1]
+		}
+	}
+
+	if sig.accessKey == "" || len(sig.signedHeaders) == 0 || sig.signature == "" {
+		return nil, errInvalidArgument("Invalid Authorization header")
+	}
+
+	err := sig.parseDate(r.Header.Get("X-Amz-Date"))
+	if err != nil {
+		return nil, err
+	}
+
+	sig.payloadHash = r.Header.Get("X-Amz-Content-Sha256")
+	if sig.payloadHash == "" {
+		return nil, errInvalidRequest("Missing required header for this request: x-amz-content-sha256")
+	}
+
+	return sig, nil
+}
+
+// parsePresignedQuery parses the signature parameters from the query string of a presigned URL.
+func parsePresignedQuery(r *http.Request) (*signature, error) {
+	query := r.URL.Query()
+	sig := &signature{
+		presigned:   true,
+		payloadHash: unsignedPayload,
+	}
+
+	err := sig.parseCredential(query.Get("X-Amz-Credential"))
+	if err != nil {
+		return nil, err
+	}
+
+	err = sig.parseDate(query.Get("X-Amz-Date"))
+	if err != nil {
+		return nil, err
+	}
+
+	expires, err := strconv.ParseInt(query.Get("X-Amz-Expires"), 10, 64)
+	if err != nil || expires < 0 {
+		return nil, errInvalidArgument("X-Amz-Expires should be a number")
+	}
+
+	sig.expires = time.Duration(expires) * time.Second
+	if sig.expires > maxPresignedExpiry {
+		return nil, errInvalidArgument("X-Amz-Expires must be less than a week (in seconds)")
+	}
+
+	sig.signedHeaders = strings.Split(query.Get("X-Amz-SignedHeaders"), ";")
+	sig.signature = query.Get("X-Amz-Signature")
+	if sig.signature == "" {
+		return nil, errInvalidArgument("Missing X-Amz-Signature")
+	}
+
+	return sig, nil
+}
+
+// parseCredential parses a credential in the "<access key>/<date>/<region>/<service>/aws4_request" format.
+func (sig *signature) parseCredential(credential string) error {
+	parts := strings.Split(credential, "/")
+	if len(parts) != 5 || parts[0] == "" || parts[4] != "aws4_request" {
+		return errInvalidArgument("Invalid credential")
+	}
+
+	sig.accessKey = parts[0]
+	sig.date = parts[1]
+	sig.region = parts[2]
+	sig.service = parts[3]
+
+	return nil
+}
+
+// parseDate parses the request date and checks it matches the credential scope.
+func (sig *signature) parseDate(value string) error {
+	amzDate, err := time.Parse(amzDateFormat, value)
+	if err != nil {
+		return errInvalidArgument("Missing or invalid X-Amz-Date")
+	}
+
+	if amzDate.Format("20060102") != sig.date {
+		return errInvalidArgument("Credential date doesn't match X-Amz-Date")
+	}
+
+	sig.amzDate = amzDate
+
+	return nil
+}
+
+// scope returns the credential scope of the signature.
+func (sig *signature) scope() string {
+	return strings.Join([]string{sig.date, sig.region, sig.service, "aws4_request"}, "/")
+}
+
+// signingKey derives the key used to sign requests from the secret key.
+func signingKey(sig *signature, secretKey string) []byte {
+	key := hmacSHA256([]byte("AWS4"+secretKey), sig.date)
+	key = hmacSHA256(key, sig.region)
+	key = hmacSHA256(key, sig.service)
+	return hmacSHA256(key, "aws4_request")
+}
+
+// computeSignature computes the expected signature of the request for the given secret key.
+func computeSignature(r *http.Request, sig *signature, secretKey string) string {
+	stringToSign := strings.Join([]string{
+		signatureAlgorithm,
+		sig.amzDate.Format(amzDateFormat),
+		sig.scope(),
+		hashHex(canonicalRequest(r, sig)),
+	}, "\n")
+
+	return hex.EncodeToString(hmacSHA256(signingKey(sig, secretKey), stringToSign))
+}
+
+// canonicalRequest builds the canonical form of the request as defined by AWS signature version 4.
+func canonicalRequest(r *http.Request, sig *signature) string {
+	// Canonical URI.
+	uri := uriEncode(r.URL.Path, false)
+	if uri == "" {
+		uri = "/"
+	}
+
+	// Canonical query string.
+	params := []string{}
+	for key, values := range r.URL.Query() {
+		if sig.presigned && key == "X-Amz-Signature" {
+			continue
+		}
+
+		for _, value := range values {
+			params = append(params, uriEncode(key, true)+"="+uriEncode(value, true))
+		}
+	}
+
+	sort.Strings(params)
+
+	// Canonical headers.
+	headers := []string{}
+	for _, name := range sig.signedHeaders {
+		var value string
+		switch name {
+		case "host":
+			value = r.Host
+		case "content-length":
+			value = r.Header.Get("Content-Length")
+			if value == "" {
+				value = strconv.FormatInt(r.ContentLength, 10)
+			}
+
+		default:
+			value = strings.Join(r.Header[http.CanonicalHeaderKey(name)], ",")
+		}
+
+		headers = append(headers, name+":"+strings.Join(strings.Fields(value), " ")+"\n")
+	}
+
+	return strings.Join([]string{
+		r.Method,
+		uri,
+		strings.Join(params, "&"),
+		strings.Join(headers, ""),
+		strings.Join(sig.signedHeaders, ";"),
+		sig.payloadHash,
+	}, "\n")
+}
+
+// uriEncode encodes a string as defined by AWS signature version 4, keeping slashes unless encodeSlash is set.
+func uriEncode(value string, encodeSlash bool) string {
+	var b strings.Builder
+
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !encodeSlash) {
+			b.WriteByte(c)
+		} else {
+			b.WriteString("%" + strings.ToUpper(hex.EncodeToString([]byte{c})))
+		}
+	}
+
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func hashHex(data string) string {
+	h := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(h[:])
+}
+
+// hashReader checks the data read against the expected SHA256 hash once the end of the data is reached.
+type hashReader struct {
+	r        io.Reader
+	h        hash.Hash
+	expected string
+}
+
+func (h *hashReader) Read(p []byte) (int, error) {
+	n, err := h.r.Read(p)
+	h.h.Write(p[:n])
+
+	if err == io.EOF && hex.EncodeToString(h.h.Sum(nil)) != h.expected {
+		return n, errContentSHA256Mismatch
+	}
+
+	return n, err
+}
+
+// chunkedReader decodes a request body sent with the aws-chunked content encoding, checking the signature of
+// each chunk once all of its data was read.
+type chunkedReader struct {
+	r         *bufio.Reader
+	key       []byte // Signing key of the request.
+	amzDate   string
+	scope     string
+	signature string    // Signature of the previous chunk, or of the request for the first one.
+	expected  string    // Signature sent for the current chunk.
+	h         hash.Hash // Hash of the data of the current chunk.
+	remaining int64     // Bytes left in the current chunk.
+	done      bool
+}
+
+func (c *chunkedReader) Read(p []byte) (int, error) {
+	if c.done {
+		return 0, io.EOF
+	}
+
+	// Read the next chunk header, in the "<hex size>;chunk-signature=<signature>" format.
+	if c.remaining == 0 {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			return 0, errIncompleteBody
+		}
+
+		fields := strings.SplitN(strings.TrimSpace(line), ";", 2)
+		size, err := strconv.ParseInt(fields[0], 16, 64)
+		if err != nil || size < 0 || len(fields) != 2 || !strings.HasPrefix(fields[1], "chunk-signature=") {
+			return 0, errInvalidRequest("Invalid chunk header")
+		}
+
+		c.expected = strings.TrimPrefix(fields[1], "chunk-signature=")
+		c.h = sha256.New()
+
+		if size == 0 {
+			// The final chunk is empty and terminated by an empty line.
+			crlf := make([]byte, 2)
+			_, err := io.ReadFull(c.r, crlf)
+			if err != nil || string(crlf) != "\r\n" {
+				return 0, errInvalidRequest("Invalid chunk terminator")
+			}
+
+			err = c.verify()
+			if err != nil {
+				return 0, err
+			}
+
+			c.done = true
+			return 0, io.EOF
+		}
+
+		c.remaining = size
+	}
+
+	if int64(len(p)) > c.remaining {
+		p = p[:c.remaining]
+	}
+
+	n, err := c.r.Read(p)
+	c.remaining -= int64(n)
+	c.h.Write(p[:n])
+
+	if err == io.EOF {
+		return n, errIncompleteBody
+	}
+
+	// Skip the CRLF terminating the chunk data and check the chunk signature.
+	if c.remaining == 0 {
+		crlf := make([]byte, 2)
+		_, err := io.ReadFull(c.r, crlf)
+		if err != nil || string(crlf) != "\r\n" {
+			return n, errInvalidRequest("Invalid chunk terminator")
+		}
+
+		err = c.verify()
+		if err != nil {
+			return n, err
+		}
+	}
+
+	return n, err
+}
+
+// verify checks the signature of the current chunk, which the next chunk's signature is then based on.
+func (c *chunkedReader) verify() error {
+	stringToSign := strings.Join([]string{
+		chunkAlgorithm,
+		c.amzDate,
+		c.scope,
+		c.signature,
+		hashHex(""),
+		hex.EncodeToString(c.h.Sum(nil)),
+	}, "\n")
+
+	signature := hex.EncodeToString(hmacSHA256(c.key, stringToSign))
+	if !hmac.Equal([]byte(signature), []byte(c.expected)) {
+		return errSignatureDoesNotMatch
+	}
+
+	c.signature = signature
+
+	return nil
+}
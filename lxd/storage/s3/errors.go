@@ -0,0 +1,87 @@
+package s3
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+)
+
+// Error represents an S3 error response.
+type Error struct {
+	Code       string
+	Message    string
+	StatusCode int
+}
+
+// Error returns the error message.
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// errorResponse is the XML body of an S3 error response.
+type errorResponse struct {
+	XMLName  xml.Name `xml:"Error"`
+	Code     string   `xml:"Code"`
+	Message  string   `xml:"Message"`
+	Resource string   `xml:"Resource"`
+}
+
+var (
+	errAccessDenied          = &Error{Code: "AccessDenied", Message: "Access Denied", StatusCode: http.StatusForbidden}
+	errInvalidAccessKeyID    = &Error{Code: "InvalidAccessKeyId", Message: "The access key ID you provided does not exist", StatusCode: http.StatusForbidden}
+	errSignatureDoesNotMatch = &Error{Code: "SignatureDoesNotMatch", Message: "The request signature doesn't match the signature computed by the server", StatusCode: http.StatusForbidden}
+	errRequestTimeTooSkewed  = &Error{Code: "RequestTimeTooSkewed", Message: "The difference between the request time and the server's time is too large", StatusCode: http.StatusForbidden}
+	errExpiredRequest        = &Error{Code: "AccessDenied", Message: "Request has expired", StatusCode: http.StatusForbidden}
+	errQuotaExceeded         = &Error{Code: "QuotaExceeded", Message: "The bucket quota has been exceeded", StatusCode: http.StatusForbidden}
+	errNoSuchBucket          = &Error{Code: "NoSuchBucket", Message: "The specified bucket does not exist", StatusCode: http.StatusNotFound}
+	errNoSuchKey             = &Error{Code: "NoSuchKey", Message: "The specified key does not exist", StatusCode: http.StatusNotFound}
+	errNoSuchUpload          = &Error{Code: "NoSuchUpload", Message: "The specified multipart upload does not exist", StatusCode: http.StatusNotFound}
+	errInvalidPart           = &Error{Code: "InvalidPart", Message: "One or more of the specified parts could not be found", StatusCode: http.StatusBadRequest}
+	errInvalidPartOrder      = &Error{Code: "InvalidPartOrder", Message: "The list of parts was not in ascending order", StatusCode: http.StatusBadRequest}
+	errMalformedXML          = &Error{Code: "MalformedXML", Message: "The XML you provided was not well-formed", StatusCode: http.StatusBadRequest}
+	errIncompleteBody        = &Error{Code: "IncompleteBody", Message: "The request body is shorter than the specified content length", StatusCode: http.StatusBadRequest}
+	errBadDigest             = &Error{Code: "BadDigest", Message: "The Content-MD5 you specified did not match what was received", StatusCode: http.StatusBadRequest}
+	errContentSHA256Mismatch = &Error{Code: "XAmzContentSHA256Mismatch", Message: "The provided 'x-amz-content-sha256' header does not match what was computed", StatusCode: http.StatusBadRequest}
+	errMethodNotAllowed      = &Error{Code: "MethodNotAllowed", Message: "The specified method is not allowed against this resource", StatusCode: http.StatusMethodNotAllowed}
+	errNotImplemented        = &Error{Code: "NotImplemented", Message: "A header or query you provided implies functionality that is not implemented", StatusCode: http.StatusNotImplemented}
+)
+
+// errInvalidArgument returns an InvalidArgument error with the given message.
+func errInvalidArgument(message string) *Error {
+	return &Error{Code: "InvalidArgument", Message: message, StatusCode: http.StatusBadRequest}
+}
+
+// errInvalidRequest returns an InvalidRequest error with the given message.
+func errInvalidRequest(message string) *Error {
+	return &Error{Code: "InvalidRequest", Message: message, StatusCode: http.StatusBadRequest}
+}
+
+// writeError writes an S3 error response. Errors which aren't S3 errors are reported as internal errors.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	s3Err, ok := err.(*Error)
+	if !ok {
+		s3Err = &Error{Code: "InternalError", Message: err.Error(), StatusCode: http.StatusInternalServerError}
+	}
+
+	resp := errorResponse{
+		Code:     s3Err.Code,
+		Message:  s3Err.Message,
+		Resource: r.URL.Path,
+	}
+
+	// HEAD responses don't have a body.
+	if r.Method == http.MethodHead {
+		w.WriteHeader(s3Err.StatusCode)
+		return
+	}
+
+	writeXML(w, s3Err.StatusCode, resp)
+}
+
+// writeXML writes an XML response.
+func writeXML(w http.ResponseWriter, statusCode int, v interface{}) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(statusCode)
+	w.Write([]byte(xml.Header))
+	xml.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,189 @@
+package s3
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// listObjects lists the objects of a bucket, supporting both ListObjects and ListObjectsV2.
+func (s *Server) listObjects(w http.ResponseWriter, r *http.Request, req *request) error {
+	query := r.URL.Query()
+	prefix := query.Get("prefix")
+	delimiter := query.Get("delimiter")
+	encodingType := query.Get("encoding-type")
+	v2 := query.Get("list-type") == "2"
+
+	if encodingType != "" && encodingType != "url" {
+		return errInvalidArgument("Invalid Encoding Method specified in Request")
+	}
+
+	maxKeys := 1000
+	if query.Get("max-keys") != "" {
+		value, err := strconv.Atoi(query.Get("max-keys"))
+		if err != nil || value < 0 {
+			return errInvalidArgument("Provided max-keys not an integer or within integer range")
+		}
+
+		if value < maxKeys {
+			maxKeys = value
+		}
+	}
+
+	// Objects are returned after the marker (version 1) or the continuation token or start key (version 2).
+	after := query.Get("marker")
+	if v2 {
+		after = query.Get("start-after")
+
+		token := query.Get("continuation-token")
+		if token != "" {
+			decoded, err := base64.StdEncoding.DecodeString(token)
+			if err != nil {
+				return errInvalidArgument("The continuation token provided is incorrect")
+			}
+
+			after = string(decoded)
+		}
+	}
+
+	keys, err := listKeys(req.bucket)
+	if err != nil {
+		return err
+	}
+
+	resp := listObjectsResponse{
+		Xmlns:        xmlNamespace,
+		Name:         req.bucket.Name,
+		Prefix:       prefix,
+		Delimiter:    delimiter,
+		MaxKeys:      maxKeys,
+		EncodingType: encodingType,
+	}
+
+	count := 0
+	last := ""
+	seenPrefixes := map[string]bool{}
+	for _, key := range keys {
+		if !strings.HasPrefix(key, prefix) || key <= after {
+			continue
+		}
+
+		// Group keys sharing a prefix up to the delimiter.
+		entryPrefix := ""
+		if delimiter != "" {
+			i := strings.Index(key[len(prefix):], delimiter)
+			if i >= 0 {
+				entryPrefix = key[:len(prefix)+i+len(delimiter)]
+			}
+		}
+
+		if entryPrefix != "" && (seenPrefixes[entryPrefix] || entryPrefix <= after) {
+			continue
+		}
+
+		if count >= maxKeys {
+			resp.IsTruncated = true
+			break
+		}
+
+		count++
+
+		if entryPrefix != "" {
+			seenPrefixes[entryPrefix] = true
+			resp.CommonPrefixes = append(resp.CommonPrefixes, commonPrefix{Prefix: encodeKey(entryPrefix, encodingType)})
+			last = entryPrefix
+			continue
+		}
+
+		path := objectPath(req.bucket, key)
+		info, err := os.Stat(path)
+		if err != nil {
+			// The object may have been removed since the listing.
+			count--
+			continue
+		}
+
+		resp.Contents = append(resp.Contents, objectEntry{
+			Key:          encodeKey(key, encodingType),
+			LastModified: formatTime(info.ModTime()),
+			ETag:         fmt.Sprintf("%q", objectETag(path, info)),
+			Size:         info.Size(),
+			StorageClass: "STANDARD",
+		})
+
+		last = key
+	}
+
+	if v2 {
+		resp.KeyCount = &count
+		resp.StartAfter = encodeKey(query.Get("start-after"), encodingType)
+		resp.ContinuationToken = query.Get("continuation-token")
+		if resp.IsTruncated {
+			resp.NextContinuationToken = base64.StdEncoding.EncodeToString([]byte(last))
+		}
+	} else {
+		marker := encodeKey(query.Get("marker"), encodingType)
+		resp.Marker = &marker
+		if resp.IsTruncated {
+			resp.NextMarker = encodeKey(last, encodingType)
+		}
+	}
+
+	resp.Prefix = encodeKey(prefix, encodingType)
+	resp.Delimiter = encodeKey(delimiter, encodingType)
+	writeXML(w, http.StatusOK, resp)
+
+	return nil
+}
+
+// listKeys returns the sorted keys of all objects of a bucket.
+func listKeys(bucket *Bucket) ([]string, error) {
+	root := objectsPath(bucket)
+	keys := []string{}
+
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+
+		keys = append(keys, filepath.ToSlash(rel))
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Keys are sorted by their binary representation, which differs from the directory walk order.
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
+// encodeKey encodes a key when the client requested URL encoded keys.
+func encodeKey(key string, encodingType string) string {
+	if encodingType != "url" {
+		return key
+	}
+
+	return strings.Replace(url.QueryEscape(key), "%2F", "/", -1)
+}
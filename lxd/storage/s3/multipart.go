@@ -0,0 +1,247 @@
+package s3
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Maximum number of parts of a multipart upload.
+const maxParts = 10000
+
+// uploadPath returns the directory holding the parts of a multipart upload.
+func uploadPath(bucket *Bucket, uploadID string) string {
+	return filepath.Join(bucket.Path, "uploads", uploadID)
+}
+
+// checkUpload checks that the multipart upload exists and is for the given key.
+func checkUpload(bucket *Bucket, key string, uploadID string) error {
+	_, err := hex.DecodeString(uploadID)
+	if err != nil {
+		return errNoSuchUpload
+	}
+
+	uploadKey, err := ioutil.ReadFile(filepath.Join(uploadPath(bucket, uploadID), "key"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return errNoSuchUpload
+		}
+
+		return err
+	}
+
+	if string(uploadKey) != key {
+		return errNoSuchUpload
+	}
+
+	return nil
+}
+
+// createMultipartUpload starts a new multipart upload.
+func (s *Server) createMultipartUpload(w http.ResponseWriter, req *request, key string) error {
+	if req.bucket.ReadOnly {
+		return errAccessDenied
+	}
+
+	buf := make([]byte, 16)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return err
+	}
+
+	uploadID := hex.EncodeToString(buf)
+	path := uploadPath(req.bucket, uploadID)
+
+	err = os.MkdirAll(path, 0700)
+	if err != nil {
+		return err
+	}
+
+	err = ioutil.WriteFile(filepath.Join(path, "key"), []byte(key), 0600)
+	if err != nil {
+		os.RemoveAll(path)
+		return err
+	}
+
+	writeXML(w, http.StatusOK, initiateMultipartUploadResponse{
+		Xmlns:    xmlNamespace,
+		Bucket:   req.bucket.Name,
+		Key:      key,
+		UploadID: uploadID,
+	})
+
+	return nil
+}
+
+// uploadPart stores a part of a multipart upload.
+func (s *Server) uploadPart(w http.ResponseWriter, r *http.Request, req *request, key string, uploadID string) error {
+	if req.bucket.ReadOnly {
+		return errAccessDenied
+	}
+
+	partNumber, err := strconv.Atoi(r.URL.Query().Get("partNumber"))
+	if err != nil || partNumber < 1 || partNumber > maxParts {
+		return errInvalidArgument(fmt.Sprintf("Part number must be an integer between 1 and %d, inclusive", maxParts))
+	}
+
+	err = checkUpload(req.bucket, key, uploadID)
+	if err != nil {
+		return err
+	}
+
+	partPath := filepath.Join(uploadPath(req.bucket, uploadID), strconv.Itoa(partNumber))
+
+	tmpPath, digest, err := s.writeFile(req.bucket, req, r, fileSize(partPath))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpPath)
+
+	etag := hex.EncodeToString(digest)
+	err = ioutil.WriteFile(partPath+".etag", []byte(etag), 0600)
+	if err != nil {
+		return err
+	}
+
+	err = os.Rename(tmpPath, partPath)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("ETag", fmt.Sprintf("%q", etag))
+	w.WriteHeader(http.StatusOK)
+
+	return nil
+}
+
+// completeMultipartUpload assembles the listed parts into the final object.
+func (s *Server) completeMultipartUpload(w http.ResponseWriter, r *http.Request, req *request, key string, uploadID string) error {
+	if req.bucket.ReadOnly {
+		return errAccessDenied
+	}
+
+	err := checkUpload(req.bucket, key, uploadID)
+	if err != nil {
+		return err
+	}
+
+	completeReq := completeMultipartUploadRequest{}
+	err = readXML(req, &completeReq)
+	if err != nil {
+		return err
+	}
+
+	if len(completeReq.Parts) == 0 {
+		return errMalformedXML
+	}
+
+	path := uploadPath(req.bucket, uploadID)
+
+	tmpDir := filepath.Join(req.bucket.Path, "tmp")
+	err = os.MkdirAll(tmpDir, 0700)
+	if err != nil {
+		return err
+	}
+
+	f, err := ioutil.TempFile(tmpDir, "upload_")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	// The ETag of a multipart object is the MD5 hash of the part MD5 hashes, suffixed with the number of parts.
+	h := md5.New()
+	previous := 0
+	for _, part := range completeReq.Parts {
+		if part.PartNumber <= previous {
+			return errInvalidPartOrder
+		}
+
+		previous = part.PartNumber
+		partPath := filepath.Join(path, strconv.Itoa(part.PartNumber))
+
+		etag, err := ioutil.ReadFile(partPath + ".etag")
+		if err != nil || string(etag) != strings.Trim(part.ETag, `"`) {
+			return errInvalidPart
+		}
+
+		digest, err := hex.DecodeString(string(etag))
+		if err != nil {
+			return errInvalidPart
+		}
+
+		h.Write(digest)
+
+		err = appendFile(f, partPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = f.Close()
+	if err != nil {
+		return err
+	}
+
+	etag := fmt.Sprintf("%s-%d", hex.EncodeToString(h.Sum(nil)), len(completeReq.Parts))
+	err = storeObject(req.bucket, key, f.Name(), etag)
+	if err != nil {
+		return err
+	}
+
+	os.RemoveAll(path)
+	s.forgetUsage(req.bucket)
+
+	writeXML(w, http.StatusOK, completeMultipartUploadResponse{
+		Xmlns:    xmlNamespace,
+		Location: r.URL.Path,
+		Bucket:   req.bucket.Name,
+		Key:      key,
+		ETag:     fmt.Sprintf("%q", etag),
+	})
+
+	return nil
+}
+
+// appendFile appends the content of the file at the given path.
+func appendFile(w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return errInvalidPart
+	}
+	defer f.Close()
+
+	_, err = io.Copy(w, f)
+	return err
+}
+
+// abortMultipartUpload removes a multipart upload and its parts.
+func (s *Server) abortMultipartUpload(w http.ResponseWriter, req *request, key string, uploadID string) error {
+	if req.bucket.ReadOnly {
+		return errAccessDenied
+	}
+
+	err := checkUpload(req.bucket, key, uploadID)
+	if err != nil {
+		return err
+	}
+
+	err = os.RemoveAll(uploadPath(req.bucket, uploadID))
+	s.forgetUsage(req.bucket)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
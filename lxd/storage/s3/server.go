@@ -0,0 +1,607 @@
+// Package s3 implements a minimal S3-compatible gateway serving buckets stored in local directories.
+package s3
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// ErrUnknownAccessKey is returned by the lookup function when the access key doesn't exist.
+var ErrUnknownAccessKey = fmt.Errorf("Unknown access key")
+
+// Bucket represents a bucket as seen through an access key.
+type Bucket struct {
+	Name      string // Name of the bucket in S3 requests.
+	Path      string // Directory holding the bucket data.
+	Quota     int64  // Maximum size of the bucket data in bytes (0 for unlimited).
+	ReadOnly  bool   // Whether the access key only grants read access.
+	SecretKey string // Secret key used to verify request signatures.
+
+	// Usage returns the disk space used by the bucket as reported by its storage, or -1 if it can't tell.
+	// If not set or unknown, the usage is computed from the bucket directory and cached.
+	Usage func() (int64, error)
+}
+
+// Server serves S3 requests for the buckets returned by its lookup function.
+//
+// Only path-style requests are supported. Objects are stored as regular files in the "objects"
+// directory of the bucket and in-progress multipart uploads in its "uploads" directory.
+type Server struct {
+	lookup func(accessKey string) (*Bucket, error)
+	now    func() time.Time
+
+	usageMu sync.Mutex
+	usage   map[string]*cachedUsage // Computed usage of the buckets, keyed by path.
+}
+
+// cachedUsage is the usage of a bucket computed from its directory, kept up to date by the server writes.
+type cachedUsage struct {
+	usage   int64
+	expires time.Time
+}
+
+// NewServer returns a new S3 server using the given function to look up buckets by access key.
+func NewServer(lookup func(accessKey string) (*Bucket, error)) *Server {
+	return &Server{
+		lookup: lookup,
+		now:    time.Now,
+		usage:  map[string]*cachedUsage{},
+	}
+}
+
+const (
+	xmlNamespace = "http://s3.amazonaws.com/doc/2006-03-01/"
+	etagXattr    = "user.lxd.s3.etag"
+
+	// Maximum size of XML request bodies.
+	maxXMLBodySize = 1024 * 1024
+
+	// How long the computed usage of a bucket is trusted before walking its directory again.
+	usageCacheExpiry = time.Minute
+)
+
+// ServeHTTP handles an S3 request.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	req, err := s.authenticate(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+
+	err = s.handle(w, r, req)
+	if err != nil {
+		writeError(w, r, err)
+	}
+}
+
+// handle routes an authenticated request to the relevant handler.
+func (s *Server) handle(w http.ResponseWriter, r *http.Request, req *request) error {
+	bucketName, key := splitPath(r.URL.Path)
+	query := r.URL.Query()
+
+	if bucketName == "" {
+		if r.Method != http.MethodGet {
+			return errMethodNotAllowed
+		}
+
+		return s.listBuckets(w, req)
+	}
+
+	// Access keys only grant access to a single bucket.
+	if bucketName != req.bucket.Name {
+		return errNoSuchBucket
+	}
+
+	// Bucket level operations.
+	if key == "" {
+		switch r.Method {
+		case http.MethodHead:
+			w.WriteHeader(http.StatusOK)
+			return nil
+		case http.MethodGet:
+			_, ok := query["location"]
+			if ok {
+				writeXML(w, http.StatusOK, locationResponse{Xmlns: xmlNamespace})
+				return nil
+			}
+
+			return s.listObjects(w, r, req)
+		case http.MethodPost:
+			_, ok := query["delete"]
+			if ok {
+				return s.deleteObjects(w, r, req)
+			}
+		}
+
+		return errNotImplemented
+	}
+
+	err := validateKey(key)
+	if err != nil {
+		return err
+	}
+
+	// Object level operations.
+	uploadID := query.Get("uploadId")
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		return s.getObject(w, r, req, key)
+	case http.MethodPut:
+		if r.Header.Get("X-Amz-Copy-Source") != "" {
+			return errNotImplemented
+		}
+
+		if uploadID != "" {
+			return s.uploadPart(w, r, req, key, uploadID)
+		}
+
+		return s.putObject(w, r, req, key)
+	case http.MethodPost:
+		_, ok := query["uploads"]
+		if ok {
+			return s.createMultipartUpload(w, req, key)
+		}
+
+		if uploadID != "" {
+			return s.completeMultipartUpload(w, r, req, key, uploadID)
+		}
+	case http.MethodDelete:
+		if uploadID != "" {
+			return s.abortMultipartUpload(w, req, key, uploadID)
+		}
+
+		return s.deleteObject(w, req, key)
+	}
+
+	return errNotImplemented
+}
+
+// splitPath splits a path-style request path into bucket name and object key.
+func splitPath(urlPath string) (string, string) {
+	parts := strings.SplitN(strings.TrimPrefix(urlPath, "/"), "/", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+
+	return parts[0], parts[1]
+}
+
+// validateKey checks that an object key can be mapped to a file inside the bucket.
+func validateKey(key string) error {
+	if len(key) > 1024 {
+		return &Error{Code: "KeyTooLongError", Message: "Your key is too long", StatusCode: http.StatusBadRequest}
+	}
+
+	if path.Clean("/"+key) != "/"+key || strings.Contains(key, "\x00") {
+		return errInvalidArgument("Object keys must be relative paths without empty, '.' or '..' elements")
+	}
+
+	return nil
+}
+
+func objectsPath(bucket *Bucket) string {
+	return filepath.Join(bucket.Path, "objects")
+}
+
+func objectPath(bucket *Bucket, key string) string {
+	return filepath.Join(objectsPath(bucket), filepath.FromSlash(key))
+}
+
+// bucketUsage returns the disk space used by the objects and pending uploads of a bucket.
+func bucketUsage(bucket *Bucket) (int64, error) {
+	var usage int64
+
+	err := filepath.Walk(bucket.Path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+
+			return err
+		}
+
+		if info.Mode().IsRegular() {
+			usage += info.Size()
+		}
+
+		return nil
+	})
+	if err != nil {
+		return -1, err
+	}
+
+	return usage, nil
+}
+
+// bucketUsage returns the disk space used by a bucket, preferring the usage reported by its storage.
+// Otherwise the usage is computed from the bucket directory and cached until it expires.
+func (s *Server) bucketUsage(bucket *Bucket) (int64, error) {
+	if bucket.Usage != nil {
+		usage, err := bucket.Usage()
+		if err != nil {
+			return -1, err
+		}
+
+		if usage >= 0 {
+			return usage, nil
+		}
+	}
+
+	s.usageMu.Lock()
+	defer s.usageMu.Unlock()
+
+	cached, ok := s.usage[bucket.Path]
+	if ok && s.now().Before(cached.expires) {
+		return cached.usage, nil
+	}
+
+	usage, err := bucketUsage(bucket)
+	if err != nil {
+		return -1, err
+	}
+
+	s.usage[bucket.Path] = &cachedUsage{usage: usage, expires: s.now().Add(usageCacheExpiry)}
+
+	return usage, nil
+}
+
+// updateUsage adds the given number of bytes to the cached usage of a bucket.
+func (s *Server) updateUsage(bucket *Bucket, delta int64) {
+	s.usageMu.Lock()
+	defer s.usageMu.Unlock()
+
+	cached, ok := s.usage[bucket.Path]
+	if ok {
+		cached.usage += delta
+	}
+}
+
+// forgetUsage drops the cached usage of a bucket, for changes whose size isn't easily known.
+func (s *Server) forgetUsage(bucket *Bucket) {
+	s.usageMu.Lock()
+	defer s.usageMu.Unlock()
+
+	delete(s.usage, bucket.Path)
+}
+
+// quotaRemaining returns how many bytes can be added to a bucket when replacing a file of the given size,
+// or -1 if the bucket has no quota.
+func (s *Server) quotaRemaining(bucket *Bucket, replacedSize int64) (int64, error) {
+	if bucket.Quota <= 0 {
+		return -1, nil
+	}
+
+	usage, err := s.bucketUsage(bucket)
+	if err != nil {
+		return -1, err
+	}
+
+	remaining := bucket.Quota - usage + replacedSize
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return remaining, nil
+}
+
+// fileSize returns the size of a regular file or 0 if it doesn't exist.
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return 0
+	}
+
+	return info.Size()
+}
+
+// writeFile writes the request body to a temporary file in the bucket, enforcing the quota.
+// It returns the path of the temporary file along with the MD5 hash of its content.
+func (s *Server) writeFile(bucket *Bucket, req *request, r *http.Request, replacedSize int64) (string, []byte, error) {
+	remaining, err := s.quotaRemaining(bucket, replacedSize)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if remaining >= 0 && req.size > remaining {
+		return "", nil, errQuotaExceeded
+	}
+
+	tmpDir := filepath.Join(bucket.Path, "tmp")
+	err = os.MkdirAll(tmpDir, 0700)
+	if err != nil {
+		return "", nil, err
+	}
+
+	f, err := ioutil.TempFile(tmpDir, "upload_")
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+
+	success := false
+	defer func() {
+		if !success {
+			os.Remove(f.Name())
+		}
+	}()
+
+	body := req.body
+	if remaining >= 0 {
+		body = io.LimitReader(body, remaining+1)
+	}
+
+	h := md5.New()
+	written, err := io.Copy(io.MultiWriter(f, h), body)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if remaining >= 0 && written > remaining {
+		return "", nil, errQuotaExceeded
+	}
+
+	if req.size >= 0 && written != req.size {
+		return "", nil, errIncompleteBody
+	}
+
+	digest := h.Sum(nil)
+
+	contentMD5 := r.Header.Get("Content-MD5")
+	if contentMD5 != "" && contentMD5 != base64.StdEncoding.EncodeToString(digest) {
+		return "", nil, errBadDigest
+	}
+
+	err = f.Close()
+	if err != nil {
+		return "", nil, err
+	}
+
+	// The file then replaces the existing one.
+	s.updateUsage(bucket, written-replacedSize)
+
+	success = true
+	return f.Name(), digest, nil
+}
+
+// storeObject moves a file into place as the object with the given key.
+func storeObject(bucket *Bucket, key string, srcPath string, etag string) error {
+	dstPath := objectPath(bucket, key)
+
+	err := os.MkdirAll(filepath.Dir(dstPath), 0700)
+	if err != nil {
+		return errInvalidArgument("Object key conflicts with an existing object")
+	}
+
+	info, err := os.Stat(dstPath)
+	if err == nil && info.IsDir() {
+		return errInvalidArgument("Object key conflicts with existing objects")
+	}
+
+	err = os.Rename(srcPath, dstPath)
+	if err != nil {
+		return err
+	}
+
+	// The ETag is only cached, failing to record it isn't fatal.
+	unix.Setxattr(dstPath, etagXattr, []byte(etag), 0)
+
+	return nil
+}
+
+// objectETag returns the ETag of an object, falling back to one derived from its size and modification time.
+func objectETag(path string, info os.FileInfo) string {
+	buf := make([]byte, 128)
+	n, err := unix.Getxattr(path, etagXattr, buf)
+	if err == nil && n > 0 {
+		return string(buf[:n])
+	}
+
+	return fmt.Sprintf("%x-%x", info.ModTime().UnixNano(), info.Size())
+}
+
+// removeEmptyParents removes the empty parent directories of a removed object up to the objects directory.
+func removeEmptyParents(bucket *Bucket, path string) {
+	root := objectsPath(bucket)
+
+	for dir := filepath.Dir(path); strings.HasPrefix(dir, root+string(filepath.Separator)); dir = filepath.Dir(dir) {
+		err := os.Remove(dir)
+		if err != nil {
+			return
+		}
+	}
+}
+
+// readXML decodes an XML request body.
+func readXML(req *request, v interface{}) error {
+	data, err := ioutil.ReadAll(io.LimitReader(req.body, maxXMLBodySize))
+	if err != nil {
+		return err
+	}
+
+	err = xml.Unmarshal(data, v)
+	if err != nil {
+		return errMalformedXML
+	}
+
+	return nil
+}
+
+// listBuckets lists the bucket the access key grants access to.
+func (s *Server) listBuckets(w http.ResponseWriter, req *request) error {
+	resp := listBucketsResponse{Xmlns: xmlNamespace}
+	resp.Owner.ID = req.bucket.Name
+	resp.Owner.DisplayName = req.bucket.Name
+
+	var created time.Time
+	info, err := os.Stat(req.bucket.Path)
+	if err == nil {
+		created = info.ModTime()
+	}
+
+	resp.Buckets = []bucketEntry{{Name: req.bucket.Name, CreationDate: formatTime(created)}}
+	writeXML(w, http.StatusOK, resp)
+
+	return nil
+}
+
+// getObject sends the content of an object, supporting range and conditional requests.
+func (s *Server) getObject(w http.ResponseWriter, r *http.Request, req *request, key string) error {
+	path := objectPath(req.bucket, key)
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return errNoSuchKey
+		}
+
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	if !info.Mode().IsRegular() {
+		return errNoSuchKey
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("ETag", fmt.Sprintf("%q", objectETag(path, info)))
+	w.Header().Set("Accept-Ranges", "bytes")
+	http.ServeContent(w, r, "", info.ModTime(), f)
+
+	return nil
+}
+
+// putObject stores the request body as an object.
+func (s *Server) putObject(w http.ResponseWriter, r *http.Request, req *request, key string) error {
+	if req.bucket.ReadOnly {
+		return errAccessDenied
+	}
+
+	tmpPath, digest, err := s.writeFile(req.bucket, req, r, fileSize(objectPath(req.bucket, key)))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpPath)
+
+	etag := hex.EncodeToString(digest)
+	err = storeObject(req.bucket, key, tmpPath, etag)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("ETag", fmt.Sprintf("%q", etag))
+	w.WriteHeader(http.StatusOK)
+
+	return nil
+}
+
+// deleteObject removes an object, deleting a missing object isn't an error.
+func (s *Server) deleteObject(w http.ResponseWriter, req *request, key string) error {
+	if req.bucket.ReadOnly {
+		return errAccessDenied
+	}
+
+	err := s.removeObject(req.bucket, key)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func (s *Server) removeObject(bucket *Bucket, key string) error {
+	path := objectPath(bucket, key)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	if info.IsDir() {
+		return nil
+	}
+
+	err = os.Remove(path)
+	if err != nil {
+		return err
+	}
+
+	s.updateUsage(bucket, -info.Size())
+	removeEmptyParents(bucket, path)
+
+	return nil
+}
+
+// deleteObjects removes multiple objects.
+func (s *Server) deleteObjects(w http.ResponseWriter, r *http.Request, req *request) error {
+	if req.bucket.ReadOnly {
+		return errAccessDenied
+	}
+
+	deleteReq := deleteRequest{}
+	err := readXML(req, &deleteReq)
+	if err != nil {
+		return err
+	}
+
+	if len(deleteReq.Objects) > 1000 {
+		return errMalformedXML
+	}
+
+	resp := deleteResponse{Xmlns: xmlNamespace}
+	for _, object := range deleteReq.Objects {
+		err := validateKey(object.Key)
+		if err == nil {
+			err = s.removeObject(req.bucket, object.Key)
+		}
+
+		if err != nil {
+			entry := deleteError{Key: object.Key, Code: "InternalError", Message: err.Error()}
+			s3Err, ok := err.(*Error)
+			if ok {
+				entry.Code = s3Err.Code
+				entry.Message = s3Err.Message
+			}
+
+			resp.Errors = append(resp.Errors, entry)
+			continue
+		}
+
+		if !deleteReq.Quiet {
+			resp.Deleted = append(resp.Deleted, deletedObject{Key: object.Key})
+		}
+	}
+
+	writeXML(w, http.StatusOK, resp)
+
+	return nil
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000Z")
+}
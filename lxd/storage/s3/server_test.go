@@ -0,0 +1,348 @@
+package s3
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testTime = time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// testServer returns a server with an admin key ("admin"/"secret") and a read-only key ("reader"/"secret")
+// for the "b1" bucket stored in a temporary directory.
+func testServer(t *testing.T, quota int64) (*httptest.Server, func()) {
+	dir, err := ioutil.TempDir("", "lxd_s3_test_")
+	require.NoError(t, err)
+
+	s := NewServer(func(accessKey string) (*Bucket, error) {
+		switch accessKey {
+		case "admin":
+			return &Bucket{Name: "b1", Path: dir, Quota: quota, SecretKey: "secret"}, nil
+		case "reader":
+			return &Bucket{Name: "b1", Path: dir, Quota: quota, ReadOnly: true, SecretKey: "secret"}, nil
+		}
+
+		return nil, ErrUnknownAccessKey
+	})
+	s.now = func() time.Time { return testTime }
+
+	ts := httptest.NewServer(s)
+
+	return ts, func() {
+		ts.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+// signRequest signs a request using AWS signature version 4, as S3 clients do.
+func signRequest(t *testing.T, r *http.Request, accessKey string, secretKey string, body []byte) {
+	payloadHash := hashHex(string(body))
+	r.Header.Set("X-Amz-Date", testTime.Format(amzDateFormat))
+	r.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	sig := &signature{
+		accessKey:     accessKey,
+		date:          testTime.Format("20060102"),
+		region:        "us-east-1",
+		service:       "s3",
+		amzDate:       testTime,
+		signedHeaders: []string{"host", "x-amz-content-sha256", "x-amz-date"},
+		payloadHash:   payloadHash,
+	}
+
+	r.Header.Set("Authorization", signatureAlgorithm+" Credential="+accessKey+"/"+sig.date+"/us-east-1/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature="+computeSignature(r, sig, secretKey))
+}
+
+func doRequest(t *testing.T, ts *httptest.Server, method string, path string, accessKey string, body []byte) (*http.Response, []byte) {
+	r, err := http.NewRequest(method, ts.URL+path, bytes.NewReader(body))
+	require.NoError(t, err)
+
+	signRequest(t, r, accessKey, "secret", body)
+
+	resp, err := http.DefaultClient.Do(r)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	return resp, data
+}
+
+func errorCode(t *testing.T, data []byte) string {
+	resp := errorResponse{}
+	require.NoError(t, xml.Unmarshal(data, &resp))
+	return resp.Code
+}
+
+func TestServer_Auth(t *testing.T) {
+	ts, cleanup := testServer(t, 0)
+	defer cleanup()
+
+	// Unknown access key.
+	resp, data := doRequest(t, ts, "GET", "/b1", "unknown", nil)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, "InvalidAccessKeyId", errorCode(t, data))
+
+	// Wrong secret key.
+	r, err := http.NewRequest("GET", ts.URL+"/b1", nil)
+	require.NoError(t, err)
+	signRequest(t, r, "admin", "wrong", nil)
+	resp, err = http.DefaultClient.Do(r)
+	require.NoError(t, err)
+	data, _ = ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, "SignatureDoesNotMatch", errorCode(t, data))
+
+	// Anonymous request.
+	resp, err = http.Get(ts.URL + "/b1")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// Other bucket.
+	resp, data = doRequest(t, ts, "GET", "/b2", "admin", nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, "NoSuchBucket", errorCode(t, data))
+
+	// Tampered payload.
+	r, err = http.NewRequest("PUT", ts.URL+"/b1/foo", strings.NewReader("tampered"))
+	require.NoError(t, err)
+	signRequest(t, r, "admin", "secret", []byte("original"))
+	resp, err = http.DefaultClient.Do(r)
+	require.NoError(t, err)
+	data, _ = ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, "XAmzContentSHA256Mismatch", errorCode(t, data))
+}
+
+func TestServer_Objects(t *testing.T) {
+	ts, cleanup := testServer(t, 0)
+	defer cleanup()
+
+	resp, _ := doRequest(t, ts, "PUT", "/b1/dir/hello%20world.txt", "admin", []byte("hello"))
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, `"5d41402abc4b2a76b9719d911017c592"`, resp.Header.Get("ETag"))
+
+	resp, _ = doRequest(t, ts, "PUT", "/b1/top", "admin", []byte("top"))
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, data := doRequest(t, ts, "GET", "/b1/dir/hello%20world.txt", "reader", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "hello", string(data))
+
+	// Read-only keys can't write.
+	resp, data = doRequest(t, ts, "PUT", "/b1/other", "reader", []byte("data"))
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, "AccessDenied", errorCode(t, data))
+
+	// Keys escaping the bucket are rejected.
+	resp, _ = doRequest(t, ts, "PUT", "/b1/dir/%2E%2E/%2E%2E/escape", "admin", []byte("data"))
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// List with a delimiter.
+	resp, data = doRequest(t, ts, "GET", "/b1?list-type=2&delimiter=%2F", "reader", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	list := listObjectsResponse{}
+	require.NoError(t, xml.Unmarshal(data, &list))
+	require.Len(t, list.Contents, 1)
+	assert.Equal(t, "top", list.Contents[0].Key)
+	assert.Equal(t, []commonPrefix{{Prefix: "dir/"}}, list.CommonPrefixes)
+
+	// Paginated list.
+	resp, data = doRequest(t, ts, "GET", "/b1?list-type=2&max-keys=1", "reader", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	list = listObjectsResponse{}
+	require.NoError(t, xml.Unmarshal(data, &list))
+	require.Len(t, list.Contents, 1)
+	assert.Equal(t, "dir/hello world.txt", list.Contents[0].Key)
+	assert.True(t, list.IsTruncated)
+
+	resp, data = doRequest(t, ts, "GET", "/b1?list-type=2&max-keys=1&continuation-token="+list.NextContinuationToken, "reader", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	list = listObjectsResponse{}
+	require.NoError(t, xml.Unmarshal(data, &list))
+	require.Len(t, list.Contents, 1)
+	assert.Equal(t, "top", list.Contents[0].Key)
+	assert.False(t, list.IsTruncated)
+
+	// Delete.
+	resp, _ = doRequest(t, ts, "DELETE", "/b1/dir/hello%20world.txt", "admin", nil)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	resp, data = doRequest(t, ts, "GET", "/b1/dir/hello%20world.txt", "admin", nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, "NoSuchKey", errorCode(t, data))
+}
+
+func TestServer_Quota(t *testing.T) {
+	ts, cleanup := testServer(t, 10)
+	defer cleanup()
+
+	resp, _ := doRequest(t, ts, "PUT", "/b1/a", "admin", []byte("12345678"))
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, data := doRequest(t, ts, "PUT", "/b1/b", "admin", []byte("12345"))
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, "QuotaExceeded", errorCode(t, data))
+
+	// Replacing an object only counts the difference.
+	resp, _ = doRequest(t, ts, "PUT", "/b1/a", "admin", []byte("1234567890"))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, _ = doRequest(t, ts, "PUT", "/b1/b", "admin", []byte("1"))
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// Deleted objects free up space.
+	resp, _ = doRequest(t, ts, "DELETE", "/b1/a", "admin", nil)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	resp, _ = doRequest(t, ts, "PUT", "/b1/b", "admin", []byte("12345"))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestServer_Multipart(t *testing.T) {
+	ts, cleanup := testServer(t, 0)
+	defer cleanup()
+
+	resp, data := doRequest(t, ts, "POST", "/b1/big?uploads", "admin", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	initiate := initiateMultipartUploadResponse{}
+	require.NoError(t, xml.Unmarshal(data, &initiate))
+
+	complete := completeMultipartUploadRequest{}
+	digests := []byte{}
+	for i, part := range []string{"part1-", "part2"} {
+		resp, _ = doRequest(t, ts, "PUT", "/b1/big?partNumber="+string(rune('1'+i))+"&uploadId="+initiate.UploadID, "admin", []byte(part))
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+
+		complete.Parts = append(complete.Parts, completedPart{PartNumber: i + 1, ETag: resp.Header.Get("ETag")})
+
+		digest, err := hex.DecodeString(strings.Trim(resp.Header.Get("ETag"), `"`))
+		require.NoError(t, err)
+		digests = append(digests, digest...)
+	}
+
+	body, err := xml.Marshal(complete)
+	require.NoError(t, err)
+
+	resp, data = doRequest(t, ts, "POST", "/b1/big?uploadId="+initiate.UploadID, "admin", body)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	result := completeMultipartUploadResponse{}
+	require.NoError(t, xml.Unmarshal(data, &result))
+	assert.True(t, strings.HasSuffix(result.ETag, `-2"`))
+
+	resp, data = doRequest(t, ts, "GET", "/b1/big", "admin", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "part1-part2", string(data))
+
+	// The upload is gone once completed.
+	resp, _ = doRequest(t, ts, "DELETE", "/b1/big?uploadId="+initiate.UploadID, "admin", nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestChunkedReader(t *testing.T) {
+	// Example from the AWS signature version 4 documentation for chunked uploads.
+	sig := &signature{
+		date:      "20130524",
+		region:    "us-east-1",
+		service:   "s3",
+		amzDate:   time.Date(2013, 5, 24, 0, 0, 0, 0, time.UTC),
+		signature: "4f232c4386841ef735655705268965c44a0e4690baa4adea153f7db9fa80a0a9",
+	}
+
+	newReader := func(body string) *chunkedReader {
+		return &chunkedReader{
+			r:         bufio.NewReader(strings.NewReader(body)),
+			key:       signingKey(sig, "wJalrXUtnFEMI/K7MDENG/bPxRfiCYEXAMPLEKEY"),
+			amzDate:   sig.amzDate.Format(amzDateFormat),
+			scope:     sig.scope(),
+			signature: sig.signature,
+		}
+	}
+
+	chunk1 := strings.Repeat("a", 65536)
+	chunk2 := strings.Repeat("a", 1024)
+	body := "10000;chunk-signature=ad80c730a21e5b8d04586a2213dd63b9a0e99e0e2307b0ade35a65485a288648\r\n" + chunk1 + "\r\n" +
+		"400;chunk-signature=0055627c9e194cb4542bae2aa5492e3c1575bbb81b612b7d234b86a503ef5497\r\n" + chunk2 + "\r\n" +
+		"0;chunk-signature=b6c6ea8a5354eaf15b3cb7646744f4275b71ea724fed81ceb9323e279d449df9\r\n\r\n"
+
+	data, err := ioutil.ReadAll(newReader(body))
+	require.NoError(t, err)
+	assert.Equal(t, chunk1+chunk2, string(data))
+
+	// Tampered chunk data.
+	_, err = ioutil.ReadAll(newReader(strings.Replace(body, "a\r\n400", "b\r\n400", 1)))
+	assert.Equal(t, errSignatureDoesNotMatch, err)
+
+	// Chunks can't be reordered or dropped.
+	_, err = ioutil.ReadAll(newReader(body[strings.Index(body, "400;"):]))
+	assert.Equal(t, errSignatureDoesNotMatch, err)
+
+	// Missing chunk signature.
+	_, err = ioutil.ReadAll(newReader("5\r\nhello\r\n0\r\n\r\n"))
+	assert.Error(t, err)
+
+	_, err = ioutil.ReadAll(newReader("5;chunk-signature=abc\r\nhel"))
+	assert.Equal(t, errIncompleteBody, err)
+}
+
+func TestUriEncode(t *testing.T) {
+	assert.Equal(t, "/b1/dir/hello%20world%2B%21.txt", uriEncode("/b1/dir/hello world+!.txt", false))
+	assert.Equal(t, "a%2Fb", uriEncode("a/b", true))
+}
+
+func TestCanonicalRequest(t *testing.T) {
+	// Example from the AWS signature version 4 documentation for GET Object.
+	r, err := http.NewRequest("GET", "https://examplebucket.s3.amazonaws.com/test.txt", nil)
+	require.NoError(t, err)
+
+	r.Header.Set("Range", "bytes=0-9")
+	r.Header.Set("X-Amz-Content-Sha256", "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855")
+	r.Header.Set("X-Amz-Date", "20130524T000000Z")
+
+	sig := &signature{
+		date:          "20130524",
+		region:        "us-east-1",
+		service:       "s3",
+		amzDate:       time.Date(2013, 5, 24, 0, 0, 0, 0, time.UTC),
+		signedHeaders: []string{"host", "range", "x-amz-content-sha256", "x-amz-date"},
+		payloadHash:   "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+	}
+
+	signature := computeSignature(r, sig, "wJalrXUtnFEMI/K7MDENG/bPxRfiCYEXAMPLEKEY")
+	assert.Equal(t, "f0e8bdb87c964420e857bd35b5d6ed310bd44f0170aba48dd91039c6036bdb41", signature)
+
+	h := sha256.Sum256([]byte(canonicalRequest(r, sig)))
+	assert.Equal(t, "7344ae5b7ee6c3e7e6b0fe0640412a37625d1fbfff95c48bbb2dc43964946972", hex.EncodeToString(h[:]))
+}
+
+func TestValidateKey(t *testing.T) {
+	for _, key := range []string{"a", "a/b", "a b/c.txt"} {
+		assert.NoError(t, validateKey(key), key)
+	}
+
+	for _, key := range []string{"../a", "a/../../b", "a//b", "a/", "./a"} {
+		assert.Error(t, validateKey(key), key)
+	}
+
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/pkg/errors"
 	"gopkg.in/robfig/cron.v2"
 
+	"github.com/lxc/lxd/lxd/backup"
 	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/instance"
 	"github.com/lxc/lxd/lxd/instance/instancetype"
@@ -40,6 +41,28 @@ func ValidName(value string) error {
 	return nil
 }
 
+// ValidBucketName validates the provided name, and returns an error if it's not a valid bucket name.
+// Bucket names follow the S3 naming rules as they are used in the path of S3 requests.
+func ValidBucketName(value string) error {
+	if len(value) < 3 || len(value) > 63 {
+		return fmt.Errorf("Bucket name must be between 3 and 63 characters long")
+	}
+
+	for _, r := range value {
+		if !(r >= 'a' && r <= 'z') && !(r >= '0' && r <= '9') && r != '-' && r != '.' {
+			return fmt.Errorf("Bucket name can only contain lowercase letters, numbers, dots and hyphens")
+		}
+	}
+
+	first := value[0]
+	last := value[len(value)-1]
+	if first == '-' || first == '.' || last == '-' || last == '.' {
+		return fmt.Errorf("Bucket name must start and end with a letter or number")
+	}
+
+	return nil
+}
+
 // ConfigDiff returns a diff of the provided configs. Additionally, it returns whether or not
 // only user properties have been changed.
 func ConfigDiff(oldConfig map[string]string, newConfig map[string]string) ([]string, bool) {
@@ -234,9 +257,9 @@ func VolumeDBCreate(s *state.State, project, poolName, volumeName, volumeDescrip
 
 // SupportedPoolTypes the types of pools supported.
 // Deprecated: this is being replaced with drivers.SupportedDrivers()
-var SupportedPoolTypes = []string{"btrfs", "ceph", "cephfs", "dir", "lvm", "zfs"}
+var SupportedPoolTypes = []string{"btrfs", "ceph", "cephfs", "dir", "dmthin", "lvm", "zfs"}
 
-// StorageVolumeConfigKeys config validation for btrfs, ceph, cephfs, dir, lvm, zfs types.
+// StorageVolumeConfigKeys config validation for btrfs, ceph, cephfs, dir, dmthin, lvm, zfs types.
 // Deprecated: these are being moved to the per-storage-driver implementations.
 var StorageVolumeConfigKeys = map[string]func(value string) ([]string, error){
 	"block.filesystem": func(value string) ([]string, error) {
@@ -245,10 +268,10 @@ var StorageVolumeConfigKeys = map[string]func(value string) ([]string, error){
 			return nil, err
 		}
 
-		return []string{"ceph", "lvm"}, nil
+		return []string{"ceph", "dmthin", "lvm"}, nil
 	},
 	"block.mount_options": func(value string) ([]string, error) {
-		return []string{"ceph", "lvm"}, validate.IsAny(value)
+		return []string{"ceph", "dmthin", "lvm"}, validate.IsAny(value)
 	},
 	"security.shifted": func(value string) ([]string, error) {
 		return SupportedPoolTypes, validate.Optional(validate.IsBool)(value)
@@ -348,7 +371,7 @@ func VolumeValidateConfig(s *state.State, volName string, volType drivers.Volume
 
 // VolumeFillDefault fills default settings into a volume config.
 func VolumeFillDefault(config map[string]string, parentPool *api.StoragePool) error {
-	if parentPool.Driver == "lvm" || parentPool.Driver == "ceph" {
+	if parentPool.Driver == "lvm" || parentPool.Driver == "ceph" || parentPool.Driver == "dmthin" {
 		if config["block.filesystem"] == "" {
 			config["block.filesystem"] = parentPool.Config["volume.block.filesystem"]
 		}
@@ -419,11 +442,12 @@ func VolumePropertiesTranslate(targetConfig map[string]string, targetParentPoolD
 // validatePoolCommonRules returns a map of pool config rules common to all drivers.
 func validatePoolCommonRules() map[string]func(string) error {
 	return map[string]func(string) error{
-		"source":                  validate.IsAny,
-		"volatile.initial_source": validate.IsAny,
-		"volume.size":             validate.Optional(validate.IsSize),
-		"size":                    validate.Optional(validate.IsSize),
-		"rsync.bwlimit":           validate.IsAny,
+		"source":                    validate.IsAny,
+		"volatile.initial_source":   validate.IsAny,
+		"volatile.migration.target": validate.IsAny,
+		"volume.size":               validate.Optional(validate.IsSize),
+		"size":                      validate.Optional(validate.IsSize),
+		"rsync.bwlimit":             validate.IsAny,
 	}
 }
 
@@ -475,6 +499,23 @@ func validateVolumeCommonRules(vol drivers.Volume) map[string]func(string) error
 	if vol.Type() == drivers.VolumeTypeCustom {
 		rules["security.shifted"] = validate.Optional(validate.IsBool)
 		rules["security.unmapped"] = validate.Optional(validate.IsBool)
+
+		rules["backups.schedule"] = validate.Optional(validate.IsCronSchedule)
+
+		rules["backups.schedule.expiry"] = func(value string) error {
+			// Validate expression
+			_, err := shared.GetSnapshotExpiry(time.Time{}, value)
+			return err
+		}
+
+		rules["backups.target"] = func(value string) error {
+			if value == "" {
+				return nil
+			}
+
+			_, err := backup.ParseTarget(value)
+			return err
+		}
 	}
 
 	// volatile.rootfs.size is only used for image volumes.
@@ -0,0 +1,602 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/project"
+	"github.com/lxc/lxd/lxd/response"
+	storagePools "github.com/lxc/lxd/lxd/storage"
+	storageDrivers "github.com/lxc/lxd/lxd/storage/drivers"
+	"github.com/lxc/lxd/lxd/storage/s3"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/units"
+	"github.com/lxc/lxd/shared/version"
+)
+
+var storagePoolBucketsCmd = APIEndpoint{
+	Path: "storage-pools/{pool}/buckets",
+
+	Get:  APIEndpointAction{Handler: storagePoolBucketsGet, AccessHandler: allowProjectPermission("storage-volumes", "view")},
+	Post: APIEndpointAction{Handler: storagePoolBucketsPost, AccessHandler: allowProjectPermission("storage-volumes", "manage-storage-volumes")},
+}
+
+var storagePoolBucketCmd = APIEndpoint{
+	Path: "storage-pools/{pool}/buckets/{bucketName}",
+
+	Delete: APIEndpointAction{Handler: storagePoolBucketDelete, AccessHandler: allowProjectPermission("storage-volumes", "manage-storage-volumes")},
+	Get:    APIEndpointAction{Handler: storagePoolBucketGet, AccessHandler: allowProjectPermission("storage-volumes", "view")},
+	Patch:  APIEndpointAction{Handler: storagePoolBucketPatch, AccessHandler: allowProjectPermission("storage-volumes", "manage-storage-volumes")},
+	Put:    APIEndpointAction{Handler: storagePoolBucketPut, AccessHandler: allowProjectPermission("storage-volumes", "manage-storage-volumes")},
+}
+
+var storagePoolBucketKeysCmd = APIEndpoint{
+	Path: "storage-pools/{pool}/buckets/{bucketName}/keys",
+
+	Get:  APIEndpointAction{Handler: storagePoolBucketKeysGet, AccessHandler: allowProjectPermission("storage-volumes", "view")},
+	Post: APIEndpointAction{Handler: storagePoolBucketKeysPost, AccessHandler: allowProjectPermission("storage-volumes", "manage-storage-volumes")},
+}
+
+var storagePoolBucketKeyCmd = APIEndpoint{
+	Path: "storage-pools/{pool}/buckets/{bucketName}/keys/{keyName}",
+
+	Delete: APIEndpointAction{Handler: storagePoolBucketKeyDelete, AccessHandler: allowProjectPermission("storage-volumes", "manage-storage-volumes")},
+	Get:    APIEndpointAction{Handler: storagePoolBucketKeyGet, AccessHandler: allowProjectPermission("storage-volumes", "view")},
+	Patch:  APIEndpointAction{Handler: storagePoolBucketKeyPatch, AccessHandler: allowProjectPermission("storage-volumes", "manage-storage-volumes")},
+	Put:    APIEndpointAction{Handler: storagePoolBucketKeyPut, AccessHandler: allowProjectPermission("storage-volumes", "manage-storage-volumes")},
+}
+
+// Valid roles of storage bucket keys.
+var storageBucketKeyRoles = []string{"admin", "read-only"}
+
+// storageBucketProjectAndPool returns the effective project and the storage pool of a bucket request.
+func storageBucketProjectAndPool(d *Daemon, r *http.Request) (string, storagePools.Pool, error) {
+	projectName, err := project.StorageVolumeProject(d.State().Cluster, projectParam(r), db.StoragePoolVolumeTypeCustom)
+	if err != nil {
+		return "", nil, err
+	}
+
+	pool, err := storagePools.GetPoolByName(d.State(), mux.Vars(r)["pool"])
+	if err != nil {
+		return "", nil, err
+	}
+
+	return projectName, pool, nil
+}
+
+// storageBucketLoad returns the ID and the rendered bucket with the given name.
+func storageBucketLoad(d *Daemon, projectName string, pool storagePools.Pool, bucketName string) (int64, *api.StorageBucket, error) {
+	bucketID, bucket, err := d.cluster.GetStoragePoolBucket(projectName, pool.ID(), bucketName)
+	if err != nil {
+		return -1, nil, err
+	}
+
+	address := d.endpoints.StorageBucketsAddress()
+	if address != "" {
+		bucket.S3URL = fmt.Sprintf("https://%s/%s", address, url.PathEscape(bucket.Name))
+	}
+
+	clustered, err := cluster.Enabled(d.db)
+	if err != nil {
+		return -1, nil, err
+	}
+
+	if clustered {
+		err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+			bucket.Location, err = tx.GetLocalNodeName()
+			return err
+		})
+		if err != nil {
+			return -1, nil, err
+		}
+	}
+
+	return bucketID, bucket, nil
+}
+
+// API endpoints.
+
+// List storage buckets.
+func storagePoolBucketsGet(d *Daemon, r *http.Request) response.Response {
+	resp := forwardedResponseIfTargetIsRemote(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	projectName, pool, err := storageBucketProjectAndPool(d, r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	bucketNames, err := d.cluster.GetStoragePoolBucketNames(projectName, pool.ID())
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	recursion := util.IsRecursionRequest(r)
+
+	resultString := []string{}
+	resultMap := []api.StorageBucket{}
+	for _, bucketName := range bucketNames {
+		if !recursion {
+			resultString = append(resultString, fmt.Sprintf("/%s/storage-pools/%s/buckets/%s", version.APIVersion, pool.Name(), bucketName))
+		} else {
+			_, bucket, err := storageBucketLoad(d, projectName, pool, bucketName)
+			if err != nil {
+				continue
+			}
+
+			resultMap = append(resultMap, *bucket)
+		}
+	}
+
+	if !recursion {
+		return response.SyncResponse(true, resultString)
+	}
+
+	return response.SyncResponse(true, resultMap)
+}
+
+// Create storage bucket.
+func storagePoolBucketsPost(d *Daemon, r *http.Request) response.Response {
+	resp := forwardedResponseIfTargetIsRemote(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	projectName, pool, err := storageBucketProjectAndPool(d, r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	req := api.StorageBucketsPost{}
+
+	// Parse the request into a record.
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	// Check if a bucket by that name already exists.
+	_, _, err = d.cluster.GetStoragePoolBucket(projectName, pool.ID(), req.Name)
+	if err != db.ErrNoSuchObject {
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		return response.Conflict(fmt.Errorf("A bucket by that name already exists"))
+	}
+
+	err = pool.CreateBucket(projectName, req, nil)
+	if err != nil {
+		return response.SmartError(errors.Wrapf(err, "Failed creating bucket"))
+	}
+
+	url := fmt.Sprintf("/%s/storage-pools/%s/buckets/%s", version.APIVersion, pool.Name(), req.Name)
+	return response.SyncResponseLocation(true, nil, url)
+}
+
+// Show storage bucket.
+func storagePoolBucketGet(d *Daemon, r *http.Request) response.Response {
+	resp := forwardedResponseIfTargetIsRemote(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	projectName, pool, err := storageBucketProjectAndPool(d, r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	_, bucket, err := storageBucketLoad(d, projectName, pool, mux.Vars(r)["bucketName"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponseETag(true, bucket, bucket.Writable())
+}
+
+// Update storage bucket.
+func storagePoolBucketPut(d *Daemon, r *http.Request) response.Response {
+	resp := forwardedResponseIfTargetIsRemote(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	projectName, pool, err := storageBucketProjectAndPool(d, r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	bucketName := mux.Vars(r)["bucketName"]
+
+	// Get the existing bucket.
+	_, bucket, err := d.cluster.GetStoragePoolBucket(projectName, pool.ID(), bucketName)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Validate the ETag.
+	err = util.EtagCheck(r, bucket.Writable())
+	if err != nil {
+		return response.PreconditionFailed(err)
+	}
+
+	req := api.StorageBucketPut{}
+
+	// Decode the request.
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if r.Method == http.MethodPatch {
+		// If config being updated via "patch" method, then merge all existing config with the keys that
+		// are present in the request config.
+		for k, v := range bucket.Config {
+			_, ok := req.Config[k]
+			if !ok {
+				if req.Config == nil {
+					req.Config = map[string]string{}
+				}
+
+				req.Config[k] = v
+			}
+		}
+	}
+
+	err = pool.UpdateBucket(projectName, bucketName, req, nil)
+	if err != nil {
+		return response.SmartError(errors.Wrapf(err, "Failed updating bucket"))
+	}
+
+	return response.EmptySyncResponse
+}
+
+// Patch storage bucket.
+func storagePoolBucketPatch(d *Daemon, r *http.Request) response.Response {
+	return storagePoolBucketPut(d, r)
+}
+
+// Delete storage bucket.
+func storagePoolBucketDelete(d *Daemon, r *http.Request) response.Response {
+	resp := forwardedResponseIfTargetIsRemote(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	projectName, pool, err := storageBucketProjectAndPool(d, r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = pool.DeleteBucket(projectName, mux.Vars(r)["bucketName"], nil)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// storageBucketKeyValidate validates a storage bucket key, generating the access and secret keys if missing.
+func storageBucketKeyValidate(key *api.StorageBucketKeyPut) error {
+	if key.Role == "" {
+		key.Role = "read-only"
+	}
+
+	if !shared.StringInSlice(key.Role, storageBucketKeyRoles) {
+		return fmt.Errorf("Invalid key role %q", key.Role)
+	}
+
+	if key.AccessKey == "" {
+		accessKey, err := shared.RandomCryptoString()
+		if err != nil {
+			return errors.Wrapf(err, "Failed generating access key")
+		}
+
+		key.AccessKey = accessKey[:20]
+	}
+
+	if key.SecretKey == "" {
+		secretKey, err := shared.RandomCryptoString()
+		if err != nil {
+			return errors.Wrapf(err, "Failed generating secret key")
+		}
+
+		key.SecretKey = secretKey[:40]
+	}
+
+	return nil
+}
+
+// List storage bucket keys.
+func storagePoolBucketKeysGet(d *Daemon, r *http.Request) response.Response {
+	resp := forwardedResponseIfTargetIsRemote(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	projectName, pool, err := storageBucketProjectAndPool(d, r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	bucketName := mux.Vars(r)["bucketName"]
+	bucketID, _, err := d.cluster.GetStoragePoolBucket(projectName, pool.ID(), bucketName)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	keyNames, err := d.cluster.GetStoragePoolBucketKeyNames(bucketID)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	recursion := util.IsRecursionRequest(r)
+
+	resultString := []string{}
+	resultMap := []api.StorageBucketKey{}
+	for _, keyName := range keyNames {
+		if !recursion {
+			resultString = append(resultString, fmt.Sprintf("/%s/storage-pools/%s/buckets/%s/keys/%s", version.APIVersion, pool.Name(), bucketName, keyName))
+		} else {
+			_, key, err := d.cluster.GetStoragePoolBucketKey(bucketID, keyName)
+			if err != nil {
+				continue
+			}
+
+			resultMap = append(resultMap, *key)
+		}
+	}
+
+	if !recursion {
+		return response.SyncResponse(true, resultString)
+	}
+
+	return response.SyncResponse(true, resultMap)
+}
+
+// Create storage bucket key.
+func storagePoolBucketKeysPost(d *Daemon, r *http.Request) response.Response {
+	resp := forwardedResponseIfTargetIsRemote(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	projectName, pool, err := storageBucketProjectAndPool(d, r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	bucketName := mux.Vars(r)["bucketName"]
+	bucketID, _, err := d.cluster.GetStoragePoolBucket(projectName, pool.ID(), bucketName)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	req := api.StorageBucketKeysPost{}
+
+	// Parse the request into a record.
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if req.Name == "" {
+		return response.BadRequest(fmt.Errorf("No name provided"))
+	}
+
+	err = storageBucketKeyValidate(&req.StorageBucketKeyPut)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	// Check if a key by that name already exists.
+	_, _, err = d.cluster.GetStoragePoolBucketKey(bucketID, req.Name)
+	if err != db.ErrNoSuchObject {
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		return response.Conflict(fmt.Errorf("A key by that name already exists"))
+	}
+
+	_, err = d.cluster.CreateStoragePoolBucketKey(bucketID, &req)
+	if err != nil {
+		return response.SmartError(errors.Wrapf(err, "Failed creating bucket key"))
+	}
+
+	url := fmt.Sprintf("/%s/storage-pools/%s/buckets/%s/keys/%s", version.APIVersion, pool.Name(), bucketName, req.Name)
+	return response.SyncResponseLocation(true, nil, url)
+}
+
+// Show storage bucket key.
+func storagePoolBucketKeyGet(d *Daemon, r *http.Request) response.Response {
+	resp := forwardedResponseIfTargetIsRemote(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	projectName, pool, err := storageBucketProjectAndPool(d, r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	bucketID, _, err := d.cluster.GetStoragePoolBucket(projectName, pool.ID(), mux.Vars(r)["bucketName"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	_, key, err := d.cluster.GetStoragePoolBucketKey(bucketID, mux.Vars(r)["keyName"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponseETag(true, key, key.Writable())
+}
+
+// Update storage bucket key.
+func storagePoolBucketKeyPut(d *Daemon, r *http.Request) response.Response {
+	resp := forwardedResponseIfTargetIsRemote(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	projectName, pool, err := storageBucketProjectAndPool(d, r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	bucketID, _, err := d.cluster.GetStoragePoolBucket(projectName, pool.ID(), mux.Vars(r)["bucketName"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Get the existing key.
+	keyID, key, err := d.cluster.GetStoragePoolBucketKey(bucketID, mux.Vars(r)["keyName"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Validate the ETag.
+	err = util.EtagCheck(r, key.Writable())
+	if err != nil {
+		return response.PreconditionFailed(err)
+	}
+
+	req := api.StorageBucketKeyPut{}
+
+	// Decode the request.
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if r.Method == http.MethodPatch {
+		// Fields not present in the request are kept unchanged.
+		if req.Description == "" {
+			req.Description = key.Description
+		}
+
+		if req.Role == "" {
+			req.Role = key.Role
+		}
+
+		if req.AccessKey == "" {
+			req.AccessKey = key.AccessKey
+		}
+
+		if req.SecretKey == "" {
+			req.SecretKey = key.SecretKey
+		}
+	}
+
+	err = storageBucketKeyValidate(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	err = d.cluster.UpdateStoragePoolBucketKey(keyID, &req)
+	if err != nil {
+		return response.SmartError(errors.Wrapf(err, "Failed updating bucket key"))
+	}
+
+	return response.EmptySyncResponse
+}
+
+// Patch storage bucket key.
+func storagePoolBucketKeyPatch(d *Daemon, r *http.Request) response.Response {
+	return storagePoolBucketKeyPut(d, r)
+}
+
+// Delete storage bucket key.
+func storagePoolBucketKeyDelete(d *Daemon, r *http.Request) response.Response {
+	resp := forwardedResponseIfTargetIsRemote(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	projectName, pool, err := storageBucketProjectAndPool(d, r)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	bucketID, _, err := d.cluster.GetStoragePoolBucket(projectName, pool.ID(), mux.Vars(r)["bucketName"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	keyID, _, err := d.cluster.GetStoragePoolBucketKey(bucketID, mux.Vars(r)["keyName"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = d.cluster.DeleteStoragePoolBucketKey(keyID)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// storageBucketsServer returns the HTTP server of the S3 compatible storage buckets API.
+func storageBucketsServer(d *Daemon) *http.Server {
+	server := s3.NewServer(func(accessKey string) (*s3.Bucket, error) {
+		access, err := d.cluster.GetStoragePoolBucketAccess(accessKey)
+		if err != nil {
+			if err == db.ErrNoSuchObject {
+				return nil, s3.ErrUnknownAccessKey
+			}
+
+			return nil, err
+		}
+
+		pool, err := storagePools.GetPoolByName(d.State(), access.PoolName)
+		if err != nil {
+			return nil, err
+		}
+
+		// Bucket volumes are mounted on first use, e.g. after a restart.
+		_, err = pool.MountBucket(access.ProjectName, access.BucketName, nil)
+		if err != nil {
+			return nil, errors.Wrapf(err, "Failed mounting bucket")
+		}
+
+		var quota int64
+		if access.Config["size"] != "" {
+			quota, err = units.ParseByteSizeString(access.Config["size"])
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		volName := project.StorageVolume(access.ProjectName, access.BucketName)
+
+		return &s3.Bucket{
+			Name:      access.BucketName,
+			Path:      storageDrivers.GetVolumeMountPath(access.PoolName, storageDrivers.VolumeTypeBucket, volName),
+			Quota:     quota,
+			ReadOnly:  access.Role != "admin",
+			SecretKey: access.SecretKey,
+			Usage: func() (int64, error) {
+				usage, err := pool.GetBucketUsage(access.ProjectName, access.BucketName)
+				if err == storageDrivers.ErrNotSupported {
+					return -1, nil
+				}
+
+				return usage, err
+			},
+		}, nil
+	})
+
+	return &http.Server{Handler: server}
+}
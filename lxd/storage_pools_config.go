@@ -42,6 +42,10 @@ var storagePoolConfigKeys = map[string]func(value string) error{
 	"cephfs.path":         validate.IsAny,
 	"cephfs.user.name":    validate.IsAny,
 
+	// valid drivers: dmthin
+	"dmthin.block_size":    validate.Optional(validate.IsSize),
+	"dmthin.metadata_size": validate.Optional(validate.IsSize),
+
 	// valid drivers: lvm
 	"lvm.thinpool_name":       validate.IsAny,
 	"lvm.use_thinpool":        validate.Optional(validate.IsBool),
@@ -50,10 +54,10 @@ var storagePoolConfigKeys = map[string]func(value string) error{
 	"volume.lvm.stripes.size": validate.Optional(validate.IsSize),
 	"lvm.vg.force_reuse":      validate.Optional(validate.IsBool),
 
-	// valid drivers: btrfs, lvm, zfs
+	// valid drivers: btrfs, dmthin, lvm, zfs
 	"size": validate.Optional(validate.IsSize),
 
-	// valid drivers: btrfs, dir, lvm, zfs
+	// valid drivers: btrfs, dir, dmthin, lvm, zfs
 	"source": validate.IsAny,
 
 	// Using it as an indicator whether we created the pool or are just
@@ -64,7 +68,11 @@ var storagePoolConfigKeys = map[string]func(value string) error{
 	"volatile.pool.pristine":  validate.IsAny,
 	"volatile.initial_source": validate.IsAny,
 
-	// valid drivers: ceph, lvm
+	// Target pool of an unfinished storage pool migration.
+	// valid drivers: all
+	"volatile.migration.target": validate.IsAny,
+
+	// valid drivers: ceph, dmthin, lvm
 	"volume.block.filesystem": func(value string) error {
 		return validate.IsOneOf(value, []string{"btrfs", "ext4", "xfs"})
 	},
@@ -125,7 +133,13 @@ func storagePoolValidateConfig(name string, driver string, config map[string]str
 			}
 		}
 
-		if driver != "lvm" && driver != "ceph" {
+		if driver != "dmthin" {
+			if prfx(key, "dmthin.") {
+				return fmt.Errorf("the key %s cannot be used with %s storage pools", key, strings.ToUpper(driver))
+			}
+		}
+
+		if driver != "lvm" && driver != "ceph" && driver != "dmthin" {
 			if prfx(key, "volume.block.") {
 				return fmt.Errorf("the key %s cannot be used with %s storage pools", key, strings.ToUpper(driver))
 			}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/instance/operationlock"
+	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/state"
+	storagePools "github.com/lxc/lxd/lxd/storage"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+)
+
+var storagePoolMigrateCmd = APIEndpoint{
+	Path: "storage-pools/{name}/migrate",
+
+	Post: APIEndpointAction{Handler: storagePoolMigratePost},
+}
+
+// /1.0/storage-pools/{name}/migrate
+// Move all volumes of a storage pool to another storage pool of this server.
+func storagePoolMigratePost(d *Daemon, r *http.Request) response.Response {
+	poolName := mux.Vars(r)["name"]
+
+	req := api.StoragePoolMigratePost{}
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if req.Pool == "" {
+		return response.BadRequest(fmt.Errorf("No target storage pool provided"))
+	}
+
+	if req.Pool == poolName {
+		return response.BadRequest(fmt.Errorf("Target storage pool must be different from the source storage pool"))
+	}
+
+	// Profiles are shared by all cluster members, so their root disk devices can't follow a single member's pool.
+	clustered, err := cluster.Enabled(d.db)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if clustered {
+		return response.BadRequest(fmt.Errorf("Storage pool migration isn't supported on clustered servers"))
+	}
+
+	srcPool, err := storagePools.GetPoolByName(d.State(), poolName)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	pool, err := storagePools.GetPoolByName(d.State(), req.Pool)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	_, srcPoolInfo, err := d.cluster.GetStoragePool(poolName)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// An interrupted migration can only be resumed towards the same pool.
+	migrationTarget := srcPoolInfo.Config["volatile.migration.target"]
+	if migrationTarget != "" && migrationTarget != req.Pool {
+		return response.BadRequest(fmt.Errorf("Storage pool is already being migrated to %q", migrationTarget))
+	}
+
+	// Custom volumes already on the target pool can only be leftovers of the migration being resumed.
+	if migrationTarget == "" {
+		err = storagePoolMigrateCheckConflicts(d.State(), poolName, req.Pool)
+		if err != nil {
+			return response.BadRequest(err)
+		}
+	}
+
+	err = storagePoolMigrateCheckRunning(d.State(), poolName)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	run := func(op *operations.Operation) error {
+		return storagePoolMigrate(d.State(), srcPool, pool, op)
+	}
+
+	op, err := operations.OperationCreate(d.State(), "", operations.OperationClassTask, db.OperationStoragePoolMigrate, nil, nil, run, nil, nil)
+	if err != nil {
+		return response.InternalError(err)
+	}
+
+	return operations.OperationResponse(op)
+}
+
+// storagePoolMigrateCheckConflicts checks that none of the custom volumes of the source pool has a namesake in
+// the same project of the target pool.
+func storagePoolMigrateCheckConflicts(s *state.State, poolName string, targetPoolName string) error {
+	vols, err := s.Cluster.GetStoragePoolVolumesWithType(db.StoragePoolVolumeTypeCustom)
+	if err != nil {
+		return err
+	}
+
+	targetVols := map[string]bool{}
+	for _, vol := range vols {
+		if vol.PoolName == targetPoolName {
+			targetVols[fmt.Sprintf("%s/%s", vol.ProjectName, vol.Name)] = true
+		}
+	}
+
+	for _, vol := range vols {
+		if vol.PoolName == poolName && targetVols[fmt.Sprintf("%s/%s", vol.ProjectName, vol.Name)] {
+			return fmt.Errorf("Custom volume %q of project %q already exists on storage pool %q", vol.Name, vol.ProjectName, targetPoolName)
+		}
+	}
+
+	return nil
+}
+
+// storagePoolMigrateCheckRunning checks that no instance stored on or using volumes of the pool is running.
+func storagePoolMigrateCheckRunning(s *state.State, poolName string) error {
+	insts, err := instance.LoadNodeAll(s, instancetype.Any)
+	if err != nil {
+		return err
+	}
+
+	running := []string{}
+	for _, inst := range insts {
+		if !inst.IsRunning() {
+			continue
+		}
+
+		for _, dev := range inst.ExpandedDevices() {
+			if dev["type"] == "disk" && dev["pool"] == poolName {
+				running = append(running, fmt.Sprintf("%s (project %q)", inst.Name(), inst.Project()))
+				break
+			}
+		}
+	}
+
+	if len(running) > 0 {
+		return fmt.Errorf("Instances using the storage pool must be stopped: %v", running)
+	}
+
+	return nil
+}
+
+// storagePoolMigrateSetTarget records the target pool of an unfinished migration in the source pool's config.
+// An empty target clears it.
+func storagePoolMigrateSetTarget(s *state.State, poolName string, targetPoolName string) error {
+	_, pool, err := s.Cluster.GetStoragePool(poolName)
+	if err != nil {
+		return err
+	}
+
+	if targetPoolName == "" {
+		delete(pool.Config, "volatile.migration.target")
+	} else {
+		pool.Config["volatile.migration.target"] = targetPoolName
+	}
+
+	return s.Cluster.UpdateStoragePool(poolName, pool.Description, pool.Config)
+}
+
+// storagePoolMigrate moves all instance, custom and image volumes of srcPool to pool, then points all disk
+// devices using srcPool to pool. Volumes are moved one at a time and each move can be retried, so running it
+// again after an interruption carries on where it left off.
+func storagePoolMigrate(s *state.State, srcPool storagePools.Pool, pool storagePools.Pool, op *operations.Operation) error {
+	err := storagePoolMigrateSetTarget(s, srcPool.Name(), pool.Name())
+	if err != nil {
+		return errors.Wrapf(err, "Failed recording migration target")
+	}
+
+	// Move the instance volumes.
+	movedInsts := []instance.Instance{}
+	for _, volType := range []int{db.StoragePoolVolumeTypeContainer, db.StoragePoolVolumeTypeVM} {
+		vols, err := s.Cluster.GetStoragePoolVolumesWithType(volType)
+		if err != nil {
+			return err
+		}
+
+		for _, vol := range vols {
+			if vol.PoolName != srcPool.Name() {
+				continue
+			}
+
+			inst, err := instance.LoadByProjectAndName(s, vol.ProjectName, vol.Name)
+			if err != nil {
+				return errors.Wrapf(err, "Failed loading instance %q in project %q", vol.Name, vol.ProjectName)
+			}
+
+			err = storagePoolMigrateInstance(s, inst, srcPool, pool, op)
+			if err != nil {
+				return errors.Wrapf(err, "Failed moving instance %q in project %q", vol.Name, vol.ProjectName)
+			}
+
+			movedInsts = append(movedInsts, inst)
+		}
+	}
+
+	// Move the custom volumes.
+	vols, err := s.Cluster.GetStoragePoolVolumesWithType(db.StoragePoolVolumeTypeCustom)
+	if err != nil {
+		return err
+	}
+
+	for _, vol := range vols {
+		if vol.PoolName != srcPool.Name() {
+			continue
+		}
+
+		err = pool.MoveCustomVolumeFromPool(vol.ProjectName, vol.Name, srcPool, op)
+		if err != nil {
+			return errors.Wrapf(err, "Failed moving custom volume %q in project %q", vol.Name, vol.ProjectName)
+		}
+	}
+
+	// Image volumes are only caches of the image files, so recreate them on the target pool.
+	vols, err = s.Cluster.GetStoragePoolVolumesWithType(db.StoragePoolVolumeTypeImage)
+	if err != nil {
+		return err
+	}
+
+	for _, vol := range vols {
+		if vol.PoolName != srcPool.Name() {
+			continue
+		}
+
+		err = pool.EnsureImage(vol.Name, op)
+		if err != nil {
+			logger.Warn("Failed creating image volume on target pool", log.Ctx{"fingerprint": vol.Name, "pool": pool.Name(), "err": err})
+		}
+
+		err = srcPool.DeleteImage(vol.Name, op)
+		if err != nil {
+			return errors.Wrapf(err, "Failed deleting image volume %q", vol.Name)
+		}
+	}
+
+	// Point root disk devices and custom volume attachments at the target pool.
+	err = s.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		return tx.UpdateStoragePoolDevices(srcPool.Name(), pool.Name())
+	})
+	if err != nil {
+		return errors.Wrapf(err, "Failed updating disk devices")
+	}
+
+	// Refresh the backup files of the moved instances now that their profiles and custom volume attachments
+	// reference the target pool too.
+	for _, movedInst := range movedInsts {
+		inst, err := instance.LoadByProjectAndName(s, movedInst.Project(), movedInst.Name())
+		if err != nil {
+			return err
+		}
+
+		err = pool.UpdateInstanceBackupFile(inst, op)
+		if err != nil {
+			return errors.Wrapf(err, "Failed updating backup file of instance %q in project %q", inst.Name(), inst.Project())
+		}
+	}
+
+	return storagePoolMigrateSetTarget(s, srcPool.Name(), "")
+}
+
+// storagePoolMigrateInstance moves the volumes of an instance to pool and points its root disk device at it. The
+// instance is kept locked for the duration of the move so that it can't be started in the meantime.
+func storagePoolMigrateInstance(s *state.State, inst instance.Instance, srcPool storagePools.Pool, pool storagePools.Pool, op *operations.Operation) error {
+	unlock, err := storagePoolMigrateLockInstance(inst)
+	if err != nil {
+		return err
+	}
+
+	defer unlock()
+
+	if inst.IsRunning() {
+		return fmt.Errorf("Instance must be stopped")
+	}
+
+	rootDevName, rootDev, err := shared.GetRootDiskDevice(inst.ExpandedDevices().CloneNative())
+	if err != nil {
+		return err
+	}
+
+	err = pool.MoveInstanceFromPool(inst, srcPool, op)
+	if err != nil {
+		return err
+	}
+
+	err = s.Cluster.Transaction(func(tx *db.ClusterTx) error {
+		return tx.UpdateInstanceRootDiskPool(inst.Project(), inst.Name(), rootDevName, rootDev, pool.Name())
+	})
+	if err != nil {
+		return errors.Wrapf(err, "Failed updating root disk device")
+	}
+
+	// Reload the instance to get its updated devices.
+	inst, err = instance.LoadByProjectAndName(s, inst.Project(), inst.Name())
+	if err != nil {
+		return err
+	}
+
+	err = pool.UpdateInstanceBackupFile(inst, op)
+	if err != nil {
+		return errors.Wrapf(err, "Failed updating backup file")
+	}
+
+	return nil
+}
+
+// storagePoolMigrateLockInstance takes the operation lock of an instance and keeps it from timing out until the
+// returned function is called to release it.
+func storagePoolMigrateLockInstance(inst instance.Instance) (func(), error) {
+	opLock, err := operationlock.Create(inst.ID(), "migrate", true, false)
+	if err != nil {
+		return nil, err
+	}
+
+	chDone := make(chan struct{})
+	chStopped := make(chan struct{})
+	go func() {
+		defer close(chStopped)
+
+		for {
+			select {
+			case <-chDone:
+				return
+			case <-time.After(10 * time.Second):
+				opLock.Reset()
+			}
+		}
+	}()
+
+	return func() {
+		close(chDone)
+		<-chStopped
+		opLock.Done(nil)
+	}, nil
+}
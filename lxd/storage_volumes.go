@@ -407,6 +407,16 @@ func storagePoolVolumesPost(d *Daemon, r *http.Request) response.Response {
 		return resp
 	}
 
+	// If we're getting binary content, process separately.
+	if r.Header.Get("Content-Type") == "application/octet-stream" {
+		projectName, err := project.StorageVolumeProject(d.State().Cluster, projectParam(r), db.StoragePoolVolumeTypeCustom)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		return createStoragePoolVolumeFromBackup(d, projectName, mux.Vars(r)["name"], r.Body, r.Header.Get("X-LXD-name"))
+	}
+
 	req := api.StorageVolumesPost{}
 
 	// Parse the request.
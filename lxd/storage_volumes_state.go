@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lxc/lxd/lxd/cluster"
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/instance"
+	"github.com/lxc/lxd/lxd/instance/instancetype"
+	"github.com/lxc/lxd/lxd/project"
+	"github.com/lxc/lxd/lxd/response"
+	storagePools "github.com/lxc/lxd/lxd/storage"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+)
+
+var storagePoolVolumeTypeStateCmd = APIEndpoint{
+	Path: "storage-pools/{pool}/volumes/{type}/{name}/state",
+
+	Get: APIEndpointAction{Handler: storagePoolVolumeTypeStateGet, AccessHandler: allowProjectPermission("storage-volumes", "view")},
+}
+
+// /1.0/storage-pools/{pool}/volumes/{type}/{name}/state
+// Get the usage and I/O statistics of a storage volume.
+func storagePoolVolumeTypeStateGet(d *Daemon, r *http.Request) response.Response {
+	poolName := mux.Vars(r)["pool"]
+	volumeTypeName := mux.Vars(r)["type"]
+	volumeName := mux.Vars(r)["name"]
+
+	if shared.IsSnapshot(volumeName) {
+		return response.BadRequest(fmt.Errorf("Invalid volume name"))
+	}
+
+	// Convert the volume type name to our internal integer representation.
+	volumeType, err := storagePools.VolumeTypeNameToType(volumeTypeName)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	// Only volumes which can be in use have a state.
+	if !shared.IntInSlice(volumeType, []int{db.StoragePoolVolumeTypeCustom, db.StoragePoolVolumeTypeContainer, db.StoragePoolVolumeTypeVM}) {
+		return response.BadRequest(fmt.Errorf("Invalid storage volume type %q", volumeTypeName))
+	}
+
+	projectName, err := project.StorageVolumeProject(d.State().Cluster, projectParam(r), volumeType)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	pool, err := storagePools.GetPoolByName(d.State(), poolName)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	resp := forwardedResponseIfTargetIsRemote(d, r)
+	if resp != nil {
+		return resp
+	}
+
+	var volState *api.StorageVolumeState
+	if volumeType == db.StoragePoolVolumeTypeCustom {
+		if pool.Driver().Info().Remote {
+			resp = forwardedResponseIfVolumeIsInUseRemotely(d, r, projectName, poolName, volumeName)
+		} else {
+			resp = forwardedResponseIfVolumeIsRemote(d, r, pool.ID(), volumeName, volumeType)
+		}
+
+		if resp != nil {
+			return resp
+		}
+
+		volState, err = pool.GetCustomVolumeState(projectName, volumeName)
+		if err != nil {
+			return response.SmartError(err)
+		}
+	} else {
+		// Instance volumes are checked by the member the instance is on.
+		resp, err := forwardedResponseIfInstanceIsRemote(d, r, projectName, volumeName, instancetype.Any)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		if resp != nil {
+			return resp
+		}
+
+		inst, err := instance.LoadByProjectAndName(d.State(), projectName, volumeName)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		volState, err = pool.GetInstanceVolumeState(inst)
+		if err != nil {
+			return response.SmartError(err)
+		}
+	}
+
+	return response.SyncResponse(true, volState)
+}
+
+// forwardedResponseIfVolumeIsInUseRemotely redirects a request about a custom volume on remote storage to the
+// cluster member of an instance using it, as the volume can only be checked where it is mounted. If no instance
+// uses the volume or one of them is on this member, nothing gets done and nil is returned.
+func forwardedResponseIfVolumeIsInUseRemotely(d *Daemon, r *http.Request, projectName string, poolName string, volumeName string) response.Response {
+	if queryParam(r, "target") != "" {
+		return nil
+	}
+
+	instNames, err := storagePools.VolumeUsedByRunningInstancesWithProfilesGet(d.State(), projectName, poolName, volumeName, db.StoragePoolVolumeTypeNameCustom, false)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	addresses := []string{}
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		for _, instName := range instNames {
+			address, err := tx.GetNodeAddressOfInstance(projectName, instName, instancetype.Any)
+			if err != nil {
+				return err
+			}
+
+			addresses = append(addresses, address)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if len(addresses) == 0 || shared.StringInSlice("", addresses) {
+		return nil
+	}
+
+	client, err := cluster.Connect(addresses[0], d.endpoints.NetworkCert(), false)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.ForwardedResponse(client, r)
+}
@@ -9,6 +9,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/logger"
@@ -18,15 +19,25 @@ import (
 )
 
 // Initialize AppArmor-specific attributes.
-func (s *OS) initAppArmor() {
+func (s *OS) initAppArmor() []db.Warning {
+	var dbWarnings []db.Warning
+
 	/* Detect AppArmor availability */
 	_, err := exec.LookPath("apparmor_parser")
 	if os.Getenv("LXD_SECURITY_APPARMOR") == "false" {
 		logger.Warnf("AppArmor support has been manually disabled")
 	} else if !shared.IsDir("/sys/kernel/security/apparmor") {
 		logger.Warnf("AppArmor support has been disabled because of lack of kernel support")
+		dbWarnings = append(dbWarnings, db.Warning{
+			TypeCode:    db.WarningTypeAppArmorNotAvailable,
+			LastMessage: "Disabled because of lack of kernel support",
+		})
 	} else if err != nil {
 		logger.Warnf("AppArmor support has been disabled because 'apparmor_parser' couldn't be found")
+		dbWarnings = append(dbWarnings, db.Warning{
+			TypeCode:    db.WarningTypeAppArmorNotAvailable,
+			LastMessage: "Disabled because 'apparmor_parser' couldn't be found",
+		})
 	} else {
 		s.AppArmorAvailable = true
 	}
@@ -63,6 +74,8 @@ func (s *OS) initAppArmor() {
 		}
 		s.AppArmorConfined = true
 	}
+
+	return dbWarnings
 }
 
 func haveMacAdmin() bool {
@@ -9,6 +9,7 @@ import (
 	log "github.com/lxc/lxd/shared/log15"
 
 	"github.com/lxc/lxd/lxd/cgroup"
+	"github.com/lxc/lxd/lxd/db"
 	"github.com/lxc/lxd/lxd/util"
 	"github.com/lxc/lxd/shared"
 	"github.com/lxc/lxd/shared/idmap"
@@ -96,15 +97,16 @@ func DefaultOS() *OS {
 }
 
 // Init our internal data structures.
-func (s *OS) Init() error {
+// It returns the warnings about problems found while detecting the system's capabilities.
+func (s *OS) Init() ([]db.Warning, error) {
 	err := s.initDirs()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	s.Architectures, err = util.GetArchitectures()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	s.LxcPath = filepath.Join(s.VarDir, "containers")
@@ -141,8 +143,8 @@ func (s *OS) Init() error {
 	s.ExecPath = util.GetExecPath()
 	s.RunningInUserNS = shared.RunningInUserNS()
 
-	s.initAppArmor()
+	dbWarnings := s.initAppArmor()
 	s.CGInfo = cgroup.GetInfo()
 
-	return nil
+	return dbWarnings, nil
 }
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/lxc/lxd/lxd/db"
+	"github.com/lxc/lxd/lxd/operations"
+	"github.com/lxc/lxd/lxd/response"
+	"github.com/lxc/lxd/lxd/task"
+	"github.com/lxc/lxd/lxd/util"
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/api"
+	log "github.com/lxc/lxd/shared/log15"
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxd/shared/version"
+)
+
+var warningsCmd = APIEndpoint{
+	Path: "warnings",
+
+	Get: APIEndpointAction{Handler: warningsGet, AccessHandler: allowUnrestricted},
+}
+
+var warningCmd = APIEndpoint{
+	Path: "warnings/{uuid}",
+
+	Delete: APIEndpointAction{Handler: warningDelete},
+	Get:    APIEndpointAction{Handler: warningGet, AccessHandler: allowUnrestricted},
+	Patch:  APIEndpointAction{Handler: warningPatch},
+	Put:    APIEndpointAction{Handler: warningPut},
+}
+
+// API endpoints.
+
+// List warnings.
+func warningsGet(d *Daemon, r *http.Request) response.Response {
+	recursion := util.IsRecursionRequest(r)
+
+	// Only filter on the project if explicitly requested.
+	filter := db.WarningFilter{
+		Project: r.FormValue("project"),
+	}
+
+	resultString := []string{}
+	resultMap := []api.Warning{}
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		warnings, err := tx.GetWarnings(filter)
+		if err != nil {
+			return err
+		}
+
+		for _, w := range warnings {
+			if !recursion {
+				resultString = append(resultString, fmt.Sprintf("/%s/warnings/%s", version.APIVersion, w.UUID))
+				continue
+			}
+
+			entityURL, err := tx.GetWarningEntityURL(w.EntityTypeCode, w.EntityID)
+			if err != nil {
+				return err
+			}
+
+			resultMap = append(resultMap, w.ToAPI(entityURL))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	if !recursion {
+		return response.SyncResponse(true, resultString)
+	}
+
+	return response.SyncResponse(true, resultMap)
+}
+
+// Show warning.
+func warningGet(d *Daemon, r *http.Request) response.Response {
+	var info api.Warning
+
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		w, err := tx.GetWarning(mux.Vars(r)["uuid"])
+		if err != nil {
+			return err
+		}
+
+		entityURL, err := tx.GetWarningEntityURL(w.EntityTypeCode, w.EntityID)
+		if err != nil {
+			return err
+		}
+
+		info = w.ToAPI(entityURL)
+		return nil
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponseETag(true, info, info.Writable())
+}
+
+// Update warning status.
+func warningPut(d *Daemon, r *http.Request) response.Response {
+	UUID := mux.Vars(r)["uuid"]
+
+	req := api.WarningPut{}
+
+	// Decode the request.
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	// Resolving is done by LXD itself once the problem is gone.
+	if !shared.StringInSlice(req.Status, []string{api.WarningStatusNew, api.WarningStatusAcknowledged}) {
+		return response.BadRequest(fmt.Errorf("Status may only be set to %q or %q", api.WarningStatusNew, api.WarningStatusAcknowledged))
+	}
+
+	status, err := db.WarningStatusFromAPI(req.Status)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	var w *db.Warning
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		w, err = tx.GetWarning(UUID)
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Validate the ETag.
+	info := w.ToAPI("")
+	err = util.EtagCheck(r, info.Writable())
+	if err != nil {
+		return response.PreconditionFailed(err)
+	}
+
+	err = d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		return tx.UpdateWarningStatus(UUID, status)
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// Patch warning status.
+func warningPatch(d *Daemon, r *http.Request) response.Response {
+	return warningPut(d, r)
+}
+
+// Delete warning.
+func warningDelete(d *Daemon, r *http.Request) response.Response {
+	err := d.cluster.Transaction(func(tx *db.ClusterTx) error {
+		return tx.DeleteWarning(mux.Vars(r)["uuid"])
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+func pruneResolvedWarningsTask(d *Daemon) (task.Func, task.Schedule) {
+	f := func(ctx context.Context) {
+		opRun := func(op *operations.Operation) error {
+			// Keep resolved warnings around for a day, so that they can still be reviewed.
+			return d.cluster.Transaction(func(tx *db.ClusterTx) error {
+				return tx.DeleteResolvedWarningsOlderThan(time.Now().UTC().Add(-24 * time.Hour))
+			})
+		}
+
+		op, err := operations.OperationCreate(d.State(), "", operations.OperationClassTask, db.OperationWarningsPruneResolved, nil, nil, opRun, nil, nil)
+		if err != nil {
+			logger.Error("Failed to start prune resolved warnings operation", log.Ctx{"err": err})
+			return
+		}
+
+		logger.Info("Pruning resolved warnings")
+		_, err = op.Run()
+		if err != nil {
+			logger.Error("Failed to prune resolved warnings", log.Ctx{"err": err})
+		}
+		logger.Info("Done pruning resolved warnings")
+	}
+
+	return f, task.Daily()
+}
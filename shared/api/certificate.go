@@ -1,11 +1,33 @@
 package api
 
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+)
+
+// CertificateTypeClient indicates a client certificate type.
+const CertificateTypeClient = "client"
+
+// CertificateTypeMetrics indicates a metrics certificate type.
+//
+// API extension: metrics
+const CertificateTypeMetrics = "metrics"
+
+// CertificateTypeUnknown indicates an unknown certificate type.
+const CertificateTypeUnknown = "unknown"
+
 // CertificatesPost represents the fields of a new LXD certificate
 type CertificatesPost struct {
 	CertificatePut `yaml:",inline"`
 
 	Certificate string `json:"certificate" yaml:"certificate"`
 	Password    string `json:"password" yaml:"password"`
+
+	// Whether to create a certificate add token instead of adding a certificate
+	//
+	// API extension: certificate_token
+	Token bool `json:"token" yaml:"token"`
 }
 
 // CertificatePut represents the modifiable fields of a LXD certificate
@@ -14,6 +36,16 @@ type CertificatesPost struct {
 type CertificatePut struct {
 	Name string `json:"name" yaml:"name"`
 	Type string `json:"type" yaml:"type"`
+
+	// Whether the certificate is limited to the listed projects
+	//
+	// API extension: certificate_project
+	Restricted bool `json:"restricted" yaml:"restricted"`
+
+	// List of projects a restricted certificate has access to
+	//
+	// API extension: certificate_project
+	Projects []string `json:"projects" yaml:"projects"`
 }
 
 // Certificate represents a LXD certificate
@@ -28,3 +60,33 @@ type Certificate struct {
 func (cert *Certificate) Writable() CertificatePut {
 	return cert.CertificatePut
 }
+
+// CertificateAddToken represents the fields contained within an encoded certificate add token
+//
+// API extension: certificate_token
+type CertificateAddToken struct {
+	// The name of the new client
+	ClientName string `json:"client_name" yaml:"client_name"`
+
+	// The fingerprint of the network certificate
+	Fingerprint string `json:"fingerprint" yaml:"fingerprint"`
+
+	// The addresses of the server
+	Addresses []string `json:"addresses" yaml:"addresses"`
+
+	// The random join secret
+	Secret string `json:"secret" yaml:"secret"`
+
+	// The token's expiry date, zero if the token doesn't expire
+	ExpiresAt time.Time `json:"expires_at" yaml:"expires_at"`
+}
+
+// String encodes the certificate add token as JSON and then base64
+func (t *CertificateAddToken) String() string {
+	joinTokenJSON, err := json.Marshal(t)
+	if err != nil {
+		return ""
+	}
+
+	return base64.StdEncoding.EncodeToString(joinTokenJSON)
+}
@@ -1,5 +1,11 @@
 package api
 
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+)
+
 // Cluster represents high-level information about a LXD cluster.
 //
 // API extension: clustering
@@ -39,6 +45,48 @@ type ClusterPut struct {
 	// API extension: clustering_join
 	ServerAddress   string `json:"server_address" yaml:"server_address"`
 	ClusterPassword string `json:"cluster_password" yaml:"cluster_password"`
+
+	// Join token to use instead of the cluster address, certificate and password
+	//
+	// API extension: clustering_join_token
+	ClusterToken string `json:"cluster_token" yaml:"cluster_token"`
+}
+
+// ClusterMembersPost represents the fields required to request a join token to add a member to the cluster.
+//
+// API extension: clustering_join_token
+type ClusterMembersPost struct {
+	ServerName string `json:"server_name" yaml:"server_name"`
+}
+
+// ClusterMemberJoinToken represents the fields contained within an encoded cluster member join token.
+//
+// API extension: clustering_join_token
+type ClusterMemberJoinToken struct {
+	// The name of the new cluster member
+	ServerName string `json:"server_name" yaml:"server_name"`
+
+	// The fingerprint of the network certificate
+	Fingerprint string `json:"fingerprint" yaml:"fingerprint"`
+
+	// The addresses of the existing online cluster members
+	Addresses []string `json:"addresses" yaml:"addresses"`
+
+	// The random join secret
+	Secret string `json:"secret" yaml:"secret"`
+
+	// The token's expiry date, zero if the token doesn't expire
+	ExpiresAt time.Time `json:"expires_at" yaml:"expires_at"`
+}
+
+// String encodes the cluster member join token as JSON and then base64.
+func (t *ClusterMemberJoinToken) String() string {
+	joinTokenJSON, err := json.Marshal(t)
+	if err != nil {
+		return ""
+	}
+
+	return base64.StdEncoding.EncodeToString(joinTokenJSON)
 }
 
 // ClusterMemberPost represents the fields required to rename a LXD node.
@@ -61,6 +109,13 @@ type ClusterMember struct {
 	Message    string `json:"message" yaml:"message"`
 }
 
+// ClusterMemberStatePost represents the fields required to evacuate or restore a cluster member
+//
+// API extension: clustering_evacuation
+type ClusterMemberStatePost struct {
+	Action string `json:"action" yaml:"action"`
+}
+
 // Writable converts a full Profile struct into a ProfilePut struct (filters read-only fields)
 func (member *ClusterMember) Writable() ClusterMemberPut {
 	return member.ClusterMemberPut
@@ -78,4 +133,80 @@ type ClusterMemberPut struct {
 
 	// API extension: clustering_failure_domains
 	FailureDomain string `json:"failure_domain" yaml:"failure_domain"`
+
+	// Cluster groups the member belongs to
+	//
+	// API extension: clustering_groups
+	Groups []string `json:"groups" yaml:"groups"`
+}
+
+// ClusterGroupsPost represents the fields available for a new cluster group.
+//
+// API extension: clustering_groups
+type ClusterGroupsPost struct {
+	ClusterGroupPut `yaml:",inline"`
+
+	Name string `json:"name" yaml:"name"`
+}
+
+// ClusterGroupPost represents the fields required to rename a cluster group.
+//
+// API extension: clustering_groups
+type ClusterGroupPost struct {
+	Name string `json:"name" yaml:"name"`
+}
+
+// ClusterGroupPut represents the modifiable fields of a cluster group.
+//
+// API extension: clustering_groups
+type ClusterGroupPut struct {
+	Description string `json:"description" yaml:"description"`
+
+	// Names of the cluster members in the group
+	Members []string `json:"members" yaml:"members"`
+}
+
+// ClusterGroup represents a named group of cluster members.
+//
+// API extension: clustering_groups
+type ClusterGroup struct {
+	ClusterGroupPut `yaml:",inline"`
+
+	Name string `json:"name" yaml:"name"`
+}
+
+// Writable converts a full ClusterGroup struct into a ClusterGroupPut struct (filters read-only fields).
+func (group *ClusterGroup) Writable() ClusterGroupPut {
+	return group.ClusterGroupPut
+}
+
+// ClusterUpgrade represents the progress of a rolling upgrade of the cluster members.
+//
+// API extension: clustering_upgrade
+type ClusterUpgrade struct {
+	// Whether some members run a more recent version than others
+	InProgress bool `json:"in_progress" yaml:"in_progress"`
+
+	Members []ClusterUpgradeMember `json:"members" yaml:"members"`
+}
+
+// ClusterUpgradeMember represents the version of a cluster member during a rolling upgrade.
+//
+// API extension: clustering_upgrade
+type ClusterUpgradeMember struct {
+	ServerName    string `json:"server_name" yaml:"server_name"`
+	Schema        int    `json:"schema" yaml:"schema"`
+	APIExtensions int    `json:"api_extensions" yaml:"api_extensions"`
+	Status        string `json:"status" yaml:"status"`
+
+	// Whether the member already runs the most recent version of the cluster. Upgraded members are read-only
+	// until all members are upgraded.
+	Upgraded bool `json:"upgraded" yaml:"upgraded"`
+}
+
+// ClusterUpgradePost represents the fields required to drain a cluster member before upgrading it.
+//
+// API extension: clustering_upgrade
+type ClusterUpgradePost struct {
+	Member string `json:"member" yaml:"member"`
 }
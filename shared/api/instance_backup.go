@@ -14,6 +14,9 @@ type InstanceBackupsPost struct {
 
 	// API extension: backup_compression_algorithm
 	CompressionAlgorithm string `json:"compression_algorithm" yaml:"compression_algorithm"`
+
+	// API extension: backup_incremental
+	Parent string `json:"parent" yaml:"parent"`
 }
 
 // InstanceBackup represents a LXD instance backup.
@@ -26,6 +29,9 @@ type InstanceBackup struct {
 	InstanceOnly     bool      `json:"instance_only" yaml:"instance_only"`
 	ContainerOnly    bool      `json:"container_only" yaml:"container_only"` // Deprecated, use InstanceOnly.
 	OptimizedStorage bool      `json:"optimized_storage" yaml:"optimized_storage"`
+
+	// API extension: backup_incremental
+	Parent string `json:"parent" yaml:"parent"`
 }
 
 // InstanceBackupPost represents the fields available for the renaming of a instance backup.
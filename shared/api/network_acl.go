@@ -0,0 +1,92 @@
+package api
+
+import (
+	"strings"
+)
+
+// NetworkACLRule represents a single rule in an ACL ruleset.
+// Refer to doc/network-acls.md for details.
+//
+// API extension: network_acl
+type NetworkACLRule struct {
+	Action          string `json:"action" yaml:"action"`
+	Source          string `json:"source,omitempty" yaml:"source,omitempty"`
+	Destination     string `json:"destination,omitempty" yaml:"destination,omitempty"`
+	Protocol        string `json:"protocol,omitempty" yaml:"protocol,omitempty"`
+	SourcePort      string `json:"source_port,omitempty" yaml:"source_port,omitempty"`
+	DestinationPort string `json:"destination_port,omitempty" yaml:"destination_port,omitempty"`
+	ICMPType        string `json:"icmp_type,omitempty" yaml:"icmp_type,omitempty"`
+	ICMPCode        string `json:"icmp_code,omitempty" yaml:"icmp_code,omitempty"`
+	Description     string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+// Normalise normalises the fields of the rule so that they are comparable with stored ones.
+func (r *NetworkACLRule) Normalise() {
+	r.Action = strings.TrimSpace(r.Action)
+	r.Protocol = strings.TrimSpace(r.Protocol)
+	r.ICMPType = strings.TrimSpace(r.ICMPType)
+	r.ICMPCode = strings.TrimSpace(r.ICMPCode)
+	r.Description = strings.TrimSpace(r.Description)
+
+	// Remove spaces from the comma separated lists.
+	r.Source = normaliseList(r.Source)
+	r.Destination = normaliseList(r.Destination)
+	r.SourcePort = normaliseList(r.SourcePort)
+	r.DestinationPort = normaliseList(r.DestinationPort)
+}
+
+// normaliseList removes surrounding spaces from each entry of a comma separated list.
+func normaliseList(list string) string {
+	if strings.TrimSpace(list) == "" {
+		return ""
+	}
+
+	entries := strings.Split(list, ",")
+	for i, entry := range entries {
+		entries[i] = strings.TrimSpace(entry)
+	}
+
+	return strings.Join(entries, ",")
+}
+
+// NetworkACLPost used for renaming an ACL.
+//
+// API extension: network_acl
+type NetworkACLPost struct {
+	Name string `json:"name" yaml:"name"`
+}
+
+// NetworkACLPut used for updating an ACL.
+// Rules are evaluated in order and the first matching rule applies.
+// Config is restricted to user.* keys.
+//
+// API extension: network_acl
+type NetworkACLPut struct {
+	Description string            `json:"description" yaml:"description"`
+	Ingress     []NetworkACLRule  `json:"ingress" yaml:"ingress"`
+	Egress      []NetworkACLRule  `json:"egress" yaml:"egress"`
+	Config      map[string]string `json:"config" yaml:"config"`
+}
+
+// NetworkACL used for displaying an ACL.
+//
+// API extension: network_acl
+type NetworkACL struct {
+	NetworkACLPost `yaml:",inline"`
+	NetworkACLPut  `yaml:",inline"`
+
+	UsedBy []string `json:"used_by" yaml:"used_by"`
+}
+
+// Writable converts a full NetworkACL struct into a NetworkACLPut struct (filters read-only fields).
+func (acl *NetworkACL) Writable() NetworkACLPut {
+	return acl.NetworkACLPut
+}
+
+// NetworkACLsPost used for creating an ACL.
+//
+// API extension: network_acl
+type NetworkACLsPost struct {
+	NetworkACLPost `yaml:",inline"`
+	NetworkACLPut  `yaml:",inline"`
+}
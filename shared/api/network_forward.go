@@ -0,0 +1,71 @@
+package api
+
+import (
+	"strings"
+)
+
+// NetworkForwardPort represents a port specification in a network address forward.
+// Refer to doc/network-forwards.md for details.
+//
+// API extension: network_forward
+type NetworkForwardPort struct {
+	Description   string `json:"description" yaml:"description"`
+	Protocol      string `json:"protocol" yaml:"protocol"`
+	ListenPort    string `json:"listen_port" yaml:"listen_port"`
+	TargetPort    string `json:"target_port" yaml:"target_port"`
+	TargetAddress string `json:"target_address" yaml:"target_address"`
+}
+
+// Normalise normalises the fields of the port so that they are comparable with stored ones.
+func (p *NetworkForwardPort) Normalise() {
+	p.Description = strings.TrimSpace(p.Description)
+	p.Protocol = strings.TrimSpace(p.Protocol)
+	p.TargetAddress = strings.TrimSpace(p.TargetAddress)
+
+	// Remove spaces from the comma separated lists.
+	p.ListenPort = normaliseList(p.ListenPort)
+	p.TargetPort = normaliseList(p.TargetPort)
+}
+
+// NetworkForwardPut used for updating a network address forward.
+// The "target_address" config key sets the default target for traffic not matching any port.
+// Other config keys are restricted to user.* keys.
+//
+// API extension: network_forward
+type NetworkForwardPut struct {
+	Description string               `json:"description" yaml:"description"`
+	Config      map[string]string    `json:"config" yaml:"config"`
+	Ports       []NetworkForwardPort `json:"ports" yaml:"ports"`
+}
+
+// Normalise normalises the fields of the forward so that they are comparable with stored ones.
+func (f *NetworkForwardPut) Normalise() {
+	f.Description = strings.TrimSpace(f.Description)
+
+	for i := range f.Ports {
+		f.Ports[i].Normalise()
+	}
+}
+
+// NetworkForwardsPost used for creating a network address forward.
+//
+// API extension: network_forward
+type NetworkForwardsPost struct {
+	NetworkForwardPut `yaml:",inline"`
+
+	ListenAddress string `json:"listen_address" yaml:"listen_address"`
+}
+
+// NetworkForward used for displaying a network address forward.
+//
+// API extension: network_forward
+type NetworkForward struct {
+	NetworkForwardPut `yaml:",inline"`
+
+	ListenAddress string `json:"listen_address" yaml:"listen_address"`
+}
+
+// Writable converts a full NetworkForward struct into a NetworkForwardPut struct (filters read-only fields).
+func (f *NetworkForward) Writable() NetworkForwardPut {
+	return f.NetworkForwardPut
+}
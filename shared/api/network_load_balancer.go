@@ -0,0 +1,95 @@
+package api
+
+import (
+	"strings"
+)
+
+// NetworkLoadBalancerBackend represents a target backend specification in a network load balancer.
+// Refer to doc/network-load-balancers.md for details.
+//
+// API extension: network_load_balancer
+type NetworkLoadBalancerBackend struct {
+	Name          string `json:"name" yaml:"name"`
+	Description   string `json:"description" yaml:"description"`
+	TargetPort    string `json:"target_port" yaml:"target_port"`
+	TargetAddress string `json:"target_address" yaml:"target_address"`
+}
+
+// Normalise normalises the fields of the backend so that they are comparable with stored ones.
+func (b *NetworkLoadBalancerBackend) Normalise() {
+	b.Name = strings.TrimSpace(b.Name)
+	b.Description = strings.TrimSpace(b.Description)
+	b.TargetAddress = strings.TrimSpace(b.TargetAddress)
+
+	// Remove spaces from the comma separated list.
+	b.TargetPort = normaliseList(b.TargetPort)
+}
+
+// NetworkLoadBalancerPort represents a port specification in a network load balancer.
+// Traffic received on the listen port is spread across the named target backends.
+//
+// API extension: network_load_balancer
+type NetworkLoadBalancerPort struct {
+	Description   string   `json:"description" yaml:"description"`
+	Protocol      string   `json:"protocol" yaml:"protocol"`
+	ListenPort    string   `json:"listen_port" yaml:"listen_port"`
+	TargetBackend []string `json:"target_backend" yaml:"target_backend"`
+}
+
+// Normalise normalises the fields of the port so that they are comparable with stored ones.
+func (p *NetworkLoadBalancerPort) Normalise() {
+	p.Description = strings.TrimSpace(p.Description)
+	p.Protocol = strings.TrimSpace(p.Protocol)
+	p.ListenPort = normaliseList(p.ListenPort)
+
+	for i, backend := range p.TargetBackend {
+		p.TargetBackend[i] = strings.TrimSpace(backend)
+	}
+}
+
+// NetworkLoadBalancerPut used for updating a network load balancer.
+// Config is restricted to user.* keys.
+//
+// API extension: network_load_balancer
+type NetworkLoadBalancerPut struct {
+	Description string                       `json:"description" yaml:"description"`
+	Config      map[string]string            `json:"config" yaml:"config"`
+	Backends    []NetworkLoadBalancerBackend `json:"backends" yaml:"backends"`
+	Ports       []NetworkLoadBalancerPort    `json:"ports" yaml:"ports"`
+}
+
+// Normalise normalises the fields of the load balancer so that they are comparable with stored ones.
+func (lb *NetworkLoadBalancerPut) Normalise() {
+	lb.Description = strings.TrimSpace(lb.Description)
+
+	for i := range lb.Backends {
+		lb.Backends[i].Normalise()
+	}
+
+	for i := range lb.Ports {
+		lb.Ports[i].Normalise()
+	}
+}
+
+// NetworkLoadBalancersPost used for creating a network load balancer.
+//
+// API extension: network_load_balancer
+type NetworkLoadBalancersPost struct {
+	NetworkLoadBalancerPut `yaml:",inline"`
+
+	ListenAddress string `json:"listen_address" yaml:"listen_address"`
+}
+
+// NetworkLoadBalancer used for displaying a network load balancer.
+//
+// API extension: network_load_balancer
+type NetworkLoadBalancer struct {
+	NetworkLoadBalancerPut `yaml:",inline"`
+
+	ListenAddress string `json:"listen_address" yaml:"listen_address"`
+}
+
+// Writable converts a full NetworkLoadBalancer struct into a NetworkLoadBalancerPut struct (filters read-only fields).
+func (lb *NetworkLoadBalancer) Writable() NetworkLoadBalancerPut {
+	return lb.NetworkLoadBalancerPut
+}
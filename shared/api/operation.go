@@ -1,6 +1,8 @@
 package api
 
 import (
+	"encoding/json"
+	"fmt"
 	"time"
 )
 
@@ -21,3 +23,55 @@ type Operation struct {
 	// API extension: operation_location
 	Location string `json:"location" yaml:"location"`
 }
+
+// ToClusterJoinToken converts the metadata of a cluster member join token operation into a join token.
+// The secret is only set in the response to the creation of the token.
+//
+// API extension: clustering_join_token
+func (op *Operation) ToClusterJoinToken() (*ClusterMemberJoinToken, error) {
+	joinToken := ClusterMemberJoinToken{}
+
+	err := op.metadataToToken(&joinToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if joinToken.ServerName == "" {
+		return nil, fmt.Errorf("Operation isn't a cluster member join token")
+	}
+
+	return &joinToken, nil
+}
+
+// ToCertificateAddToken converts the metadata of a certificate add token operation into a certificate add token.
+// The secret is only set in the response to the creation of the token.
+//
+// API extension: certificate_token
+func (op *Operation) ToCertificateAddToken() (*CertificateAddToken, error) {
+	addToken := CertificateAddToken{}
+
+	err := op.metadataToToken(&addToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if addToken.ClientName == "" {
+		return nil, fmt.Errorf("Operation isn't a certificate add token")
+	}
+
+	return &addToken, nil
+}
+
+// metadataToToken fills the token from the operation metadata, which uses the same keys as the token.
+func (op *Operation) metadataToToken(token interface{}) error {
+	if op.Class != "token" {
+		return fmt.Errorf("Operation isn't a token")
+	}
+
+	data, err := json.Marshal(op.Metadata)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, token)
+}
@@ -10,6 +10,14 @@ type StoragePoolsPost struct {
 	Driver string `json:"driver" yaml:"driver"`
 }
 
+// StoragePoolMigratePost represents the fields required to move all volumes of a LXD storage pool to another
+// storage pool
+//
+// API extension: storage_pool_migrate
+type StoragePoolMigratePost struct {
+	Pool string `json:"pool" yaml:"pool"`
+}
+
 // StoragePool represents the fields of a LXD storage pool.
 //
 // API extension: storage
@@ -0,0 +1,70 @@
+package api
+
+// StorageBucketPut used for updating a storage bucket.
+// The "size" config key sets the bucket quota.
+//
+// API extension: storage_buckets
+type StorageBucketPut struct {
+	Description string            `json:"description" yaml:"description"`
+	Config      map[string]string `json:"config" yaml:"config"`
+}
+
+// StorageBucketsPost used for creating a storage bucket.
+//
+// API extension: storage_buckets
+type StorageBucketsPost struct {
+	StorageBucketPut `yaml:",inline"`
+
+	Name string `json:"name" yaml:"name"`
+}
+
+// StorageBucket used for displaying a storage bucket.
+//
+// API extension: storage_buckets
+type StorageBucket struct {
+	StorageBucketPut `yaml:",inline"`
+
+	Name     string `json:"name" yaml:"name"`
+	S3URL    string `json:"s3_url" yaml:"s3_url"`
+	Location string `json:"location" yaml:"location"`
+}
+
+// Writable converts a full StorageBucket struct into a StorageBucketPut struct (filters read-only fields).
+func (b *StorageBucket) Writable() StorageBucketPut {
+	return b.StorageBucketPut
+}
+
+// StorageBucketKeyPut used for updating a storage bucket key.
+// The role is either "admin" (read and write access) or "read-only".
+// Empty access and secret keys are generated by the server.
+//
+// API extension: storage_buckets
+type StorageBucketKeyPut struct {
+	Description string `json:"description" yaml:"description"`
+	Role        string `json:"role" yaml:"role"`
+	AccessKey   string `json:"access_key" yaml:"access_key"`
+	SecretKey   string `json:"secret_key" yaml:"secret_key"`
+}
+
+// StorageBucketKeysPost used for creating a storage bucket key.
+//
+// API extension: storage_buckets
+type StorageBucketKeysPost struct {
+	StorageBucketKeyPut `yaml:",inline"`
+
+	Name string `json:"name" yaml:"name"`
+}
+
+// StorageBucketKey used for displaying a storage bucket key.
+//
+// API extension: storage_buckets
+type StorageBucketKey struct {
+	StorageBucketKeyPut `yaml:",inline"`
+
+	Name string `json:"name" yaml:"name"`
+}
+
+// Writable converts a full StorageBucketKey struct into a StorageBucketKeyPut struct (filters read-only fields).
+func (k *StorageBucketKey) Writable() StorageBucketKeyPut {
+	return k.StorageBucketKeyPut
+}
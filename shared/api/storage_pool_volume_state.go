@@ -0,0 +1,34 @@
+package api
+
+// StorageVolumeState represents the live state of a LXD storage volume
+//
+// API extension: storage_volume_state
+type StorageVolumeState struct {
+	// Not set when the storage driver can't report the volume's usage
+	Usage *StorageVolumeStateUsage `json:"usage" yaml:"usage"`
+
+	// Only set for volumes backed by a block device
+	IO *StorageVolumeStateIO `json:"io" yaml:"io"`
+}
+
+// StorageVolumeStateUsage represents the disk usage of a LXD storage volume
+//
+// API extension: storage_volume_state
+type StorageVolumeStateUsage struct {
+	Used  uint64 `json:"used" yaml:"used"`
+	Total int64  `json:"total" yaml:"total"`
+
+	// Only set for filesystem volumes
+	InodesUsed  uint64 `json:"inodes_used" yaml:"inodes_used"`
+	InodesTotal uint64 `json:"inodes_total" yaml:"inodes_total"`
+}
+
+// StorageVolumeStateIO represents the I/O counters of a block backed LXD storage volume
+//
+// API extension: storage_volume_state
+type StorageVolumeStateIO struct {
+	BytesRead       uint64 `json:"bytes_read" yaml:"bytes_read"`
+	BytesWritten    uint64 `json:"bytes_written" yaml:"bytes_written"`
+	ReadsCompleted  uint64 `json:"reads_completed" yaml:"reads_completed"`
+	WritesCompleted uint64 `json:"writes_completed" yaml:"writes_completed"`
+}
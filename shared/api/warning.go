@@ -0,0 +1,57 @@
+package api
+
+import (
+	"time"
+)
+
+// WarningStatusNew indicates a warning which hasn't been acknowledged.
+//
+// API extension: warnings
+const WarningStatusNew = "new"
+
+// WarningStatusAcknowledged indicates a warning which has been acknowledged.
+//
+// API extension: warnings
+const WarningStatusAcknowledged = "acknowledged"
+
+// WarningStatusResolved indicates a warning whose cause has gone away.
+//
+// API extension: warnings
+const WarningStatusResolved = "resolved"
+
+// WarningPut represents the modifiable fields of a warning.
+//
+// API extension: warnings
+type WarningPut struct {
+	Status string `json:"status" yaml:"status"`
+}
+
+// Warning represents a warning entry.
+//
+// API extension: warnings
+type Warning struct {
+	WarningPut `yaml:",inline"`
+
+	UUID string `json:"uuid" yaml:"uuid"`
+
+	// The name of the cluster member the warning applies to, empty for cluster wide warnings.
+	Location string `json:"location" yaml:"location"`
+
+	// The project the warning applies to, empty for server wide warnings.
+	Project string `json:"project" yaml:"project"`
+
+	Type        string    `json:"type" yaml:"type"`
+	Count       int       `json:"count" yaml:"count"`
+	FirstSeenAt time.Time `json:"first_seen_at" yaml:"first_seen_at"`
+	LastSeenAt  time.Time `json:"last_seen_at" yaml:"last_seen_at"`
+	LastMessage string    `json:"last_message" yaml:"last_message"`
+	Severity    string    `json:"severity" yaml:"severity"`
+
+	// The URL of the affected entity, empty if the warning doesn't apply to a specific entity.
+	EntityURL string `json:"entity_url" yaml:"entity_url"`
+}
+
+// Writable converts a full Warning struct into a WarningPut struct (filters read-only fields).
+func (w *Warning) Writable() WarningPut {
+	return w.WarningPut
+}
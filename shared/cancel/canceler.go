@@ -10,6 +10,7 @@ import (
 type Canceler struct {
 	reqChCancel map[*http.Request]chan struct{}
 	lock        sync.Mutex
+	canceled    bool
 }
 
 // NewCanceler returns a new Canceler struct
@@ -43,11 +44,20 @@ func (c *Canceler) Cancel() error {
 		close(ch)
 		delete(c.reqChCancel, req)
 	}
+	c.canceled = true
 	c.lock.Unlock()
 
 	return nil
 }
 
+// Canceled indicates whether the operations were canceled
+func (c *Canceler) Canceled() bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.canceled
+}
+
 // CancelableDownload performs an http request and allows for it to be canceled at any time
 func CancelableDownload(c *Canceler, client *http.Client, req *http.Request) (*http.Response, chan bool, error) {
 	chDone := make(chan bool)
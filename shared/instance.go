@@ -71,12 +71,29 @@ var HugePageSizeSuffix = [...]string{"64KB", "1MB", "2MB", "1GB"}
 // to an appropriate checker function, which validates whether or not a
 // given value is syntactically legal.
 var KnownInstanceConfigKeys = map[string]func(value string) error{
+	"backups.schedule": validate.Optional(validate.IsCronSchedule),
+	"backups.schedule.expiry": func(value string) error {
+		// Validate expression
+		_, err := GetSnapshotExpiry(time.Time{}, value)
+		return err
+	},
+
+	// Caller is responsible for full validation of the backup target
+	"backups.target": validate.IsAny,
+
 	"boot.autostart":             validate.Optional(validate.IsBool),
 	"boot.autostart.delay":       validate.Optional(validate.IsInt64),
 	"boot.autostart.priority":    validate.Optional(validate.IsInt64),
 	"boot.stop.priority":         validate.Optional(validate.IsInt64),
 	"boot.host_shutdown_timeout": validate.Optional(validate.IsInt64),
 
+	// Caller uses it to spread the replicas of an application across failure domains
+	"cluster.application": validate.IsAny,
+
+	"cluster.evacuate": validate.Optional(func(value string) error {
+		return validate.IsOneOf(value, []string{"auto", "migrate", "stop"})
+	}),
+
 	"limits.cpu": func(value string) error {
 		if value == "" {
 			return nil
@@ -249,6 +266,7 @@ var KnownInstanceConfigKeys = map[string]func(value string) error{
 
 	"volatile.apply_template":   validate.IsAny,
 	"volatile.base_image":       validate.IsAny,
+	"volatile.evacuate.origin":  validate.IsAny,
 	"volatile.last_state.idmap": validate.IsAny,
 	"volatile.last_state.power": validate.IsAny,
 	"volatile.idmap.base":       validate.IsAny,
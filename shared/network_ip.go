@@ -1,6 +1,7 @@
 package shared
 
 import (
+	"bytes"
 	"net"
 )
 
@@ -10,3 +11,12 @@ type IPRange struct {
 	Start net.IP
 	End   net.IP
 }
+
+// ContainsIP tests whether a supplied IP falls within the IPRange.
+func (r *IPRange) ContainsIP(ip net.IP) bool {
+	if r.End == nil {
+		return r.Start.Equal(ip)
+	}
+
+	return bytes.Compare(ip.To16(), r.Start.To16()) >= 0 && bytes.Compare(ip.To16(), r.End.To16()) <= 0
+}
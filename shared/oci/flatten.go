@@ -0,0 +1,282 @@
+package oci
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+// Whiteout markers, as defined by the OCI image layer specification.
+const (
+	whiteoutPrefix    = ".wh."
+	whiteoutOpaqueDir = ".wh..wh..opq"
+)
+
+// Flatten writes a unified LXD image tarball to the target, made of the metadata and of the root filesystem
+// resulting from applying the layers on top of each other. Layers are paths to (optionally gzip compressed)
+// layer tarballs, from the lowest to the uppermost one. The whiteout entries of a layer remove the matching
+// content of the layers below it.
+func Flatten(target io.Writer, metadata api.ImageMetadata, layers []string) error {
+	kept, err := keptEntries(layers)
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(target)
+	tw := tar.NewWriter(gz)
+
+	metadataData, err := yaml.Marshal(&metadata)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+
+	err = tw.WriteHeader(&tar.Header{Name: "metadata.yaml", Mode: 0644, Size: int64(len(metadataData)), ModTime: now, Typeflag: tar.TypeReg})
+	if err != nil {
+		return err
+	}
+
+	_, err = tw.Write(metadataData)
+	if err != nil {
+		return err
+	}
+
+	err = tw.WriteHeader(&tar.Header{Name: "rootfs/", Mode: 0755, ModTime: now, Typeflag: tar.TypeDir})
+	if err != nil {
+		return err
+	}
+
+	// Hard links are written once their target is, which may be in an upper layer.
+	type link struct {
+		hdr  tar.Header
+		name string
+	}
+
+	written := map[string]bool{}
+	deferredLinks := []link{}
+
+	writeEntry := func(hdr *tar.Header, name string, r io.Reader) error {
+		// Let the writer pick a format able to represent the renamed entry.
+		hdr.Format = tar.FormatUnknown
+		hdr.Name = path.Join("rootfs", name)
+		if hdr.Typeflag == tar.TypeDir {
+			hdr.Name += "/"
+		}
+
+		if hdr.Typeflag == tar.TypeLink {
+			hdr.Linkname = path.Join("rootfs", hdr.Linkname)
+		}
+
+		err := tw.WriteHeader(hdr)
+		if err != nil {
+			return err
+		}
+
+		if r != nil {
+			_, err = io.Copy(tw, r)
+			if err != nil {
+				return err
+			}
+		}
+
+		written[name] = true
+		return nil
+	}
+
+	for i, layer := range layers {
+		err := walkLayer(layer, func(hdr *tar.Header, name string, r io.Reader) error {
+			if !kept[i][name] {
+				return nil
+			}
+
+			if hdr.Typeflag == tar.TypeLink && !written[hdr.Linkname] {
+				deferredLinks = append(deferredLinks, link{hdr: *hdr, name: name})
+				return nil
+			}
+
+			return writeEntry(hdr, name, r)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, link := range deferredLinks {
+		if !written[link.hdr.Linkname] {
+			return fmt.Errorf("Hard link %q points to missing %q", link.name, link.hdr.Linkname)
+		}
+
+		err := writeEntry(&link.hdr, link.name, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tw.Close()
+	if err != nil {
+		return err
+	}
+
+	return gz.Close()
+}
+
+// keptEntries returns for each layer the set of entries which remain visible once all the layers are applied.
+func keptEntries(layers []string) ([]map[string]bool, error) {
+	kept := make([]map[string]bool, len(layers))
+
+	seen := map[string]bool{}
+
+	// Paths removed, along with their content, by the upper layers.
+	hidden := map[string]bool{}
+
+	// Directories whose content from the lower layers is hidden by the upper layers.
+	opaque := map[string]bool{}
+
+	isHidden := func(name string) bool {
+		if hidden[name] {
+			return true
+		}
+
+		for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+			if hidden[dir] || opaque[dir] {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	for i := len(layers) - 1; i >= 0; i-- {
+		kept[i] = map[string]bool{}
+
+		// Whiteouts only apply to the layers below the one they're in.
+		layerHidden := []string{}
+		layerOpaque := []string{}
+
+		err := walkLayer(layers[i], func(hdr *tar.Header, name string, r io.Reader) error {
+			base := path.Base(name)
+			if base == whiteoutOpaqueDir {
+				layerOpaque = append(layerOpaque, path.Dir(name))
+				return nil
+			}
+
+			if strings.HasPrefix(base, whiteoutPrefix) {
+				layerHidden = append(layerHidden, path.Join(path.Dir(name), strings.TrimPrefix(base, whiteoutPrefix)))
+				return nil
+			}
+
+			if seen[name] || isHidden(name) {
+				return nil
+			}
+
+			seen[name] = true
+			kept[i][name] = true
+
+			// Anything but a directory replaces the lower content at that path.
+			if hdr.Typeflag != tar.TypeDir {
+				layerHidden = append(layerHidden, name)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, name := range layerHidden {
+			hidden[name] = true
+		}
+
+		for _, name := range layerOpaque {
+			opaque[name] = true
+		}
+	}
+
+	return kept, nil
+}
+
+// walkLayer calls fn for each entry of the layer tarball, with the entry path relative to the root filesystem.
+// The header and reader are only valid until fn returns.
+func walkLayer(layer string, fn func(hdr *tar.Header, name string, r io.Reader) error) error {
+	f, err := os.Open(layer)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	br := bufio.NewReader(f)
+
+	magic, err := br.Peek(4)
+	if err != nil && err != io.EOF {
+		return err
+	}
+
+	var r io.Reader = br
+	if bytes.HasPrefix(magic, []byte{0x1f, 0x8b}) {
+		gzr, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer gzr.Close()
+
+		r = gzr
+	} else if bytes.Equal(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}) {
+		return fmt.Errorf("Zstandard compressed layers aren't supported")
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		name, err := layerPath(hdr.Name)
+		if err != nil {
+			return err
+		}
+
+		// The root directory itself is provided by the image.
+		if name == "." {
+			continue
+		}
+
+		if hdr.Typeflag == tar.TypeLink {
+			hdr.Linkname, err = layerPath(hdr.Linkname)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = fn(hdr, name, tr)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// layerPath returns the cleaned path of a layer entry, relative to the root filesystem.
+func layerPath(name string) (string, error) {
+	cleaned := path.Clean(strings.TrimLeft(name, "/"))
+	if cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("Invalid path %q in layer", name)
+	}
+
+	return cleaned, nil
+}
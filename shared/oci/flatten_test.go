@@ -0,0 +1,170 @@
+package oci
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+type testEntry struct {
+	name     string
+	typeflag byte
+	content  string
+	linkname string
+}
+
+// writeTestLayer writes a layer tarball made of the entries and returns its path.
+func writeTestLayer(t *testing.T, dir string, name string, compress bool, entries []testEntry) string {
+	buf := bytes.Buffer{}
+
+	var w io.Writer = &buf
+	var gz *gzip.Writer
+	if compress {
+		gz = gzip.NewWriter(&buf)
+		w = gz
+	}
+
+	tw := tar.NewWriter(w)
+	for _, entry := range entries {
+		hdr := &tar.Header{Name: entry.name, Typeflag: entry.typeflag, Mode: 0644, Linkname: entry.linkname}
+		if entry.typeflag == tar.TypeDir {
+			hdr.Mode = 0755
+		}
+
+		if entry.typeflag == tar.TypeReg {
+			hdr.Size = int64(len(entry.content))
+		}
+
+		require.NoError(t, tw.WriteHeader(hdr))
+
+		if entry.typeflag == tar.TypeReg {
+			_, err := tw.Write([]byte(entry.content))
+			require.NoError(t, err)
+		}
+	}
+
+	require.NoError(t, tw.Close())
+	if gz != nil {
+		require.NoError(t, gz.Close())
+	}
+
+	path := filepath.Join(dir, name)
+	require.NoError(t, ioutil.WriteFile(path, buf.Bytes(), 0644))
+
+	return path
+}
+
+// readTestImage returns the entries of a unified image tarball, in order.
+func readTestImage(t *testing.T, r io.Reader) []testEntry {
+	gz, err := gzip.NewReader(r)
+	require.NoError(t, err)
+
+	entries := []testEntry{}
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+
+		require.NoError(t, err)
+
+		content, err := ioutil.ReadAll(tr)
+		require.NoError(t, err)
+
+		entries = append(entries, testEntry{name: hdr.Name, typeflag: hdr.Typeflag, content: string(content), linkname: hdr.Linkname})
+	}
+
+	return entries
+}
+
+func TestFlatten(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lxd_oci_test_")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	layers := []string{
+		writeTestLayer(t, dir, "base", true, []testEntry{
+			{name: "./", typeflag: tar.TypeDir},
+			{name: "etc/", typeflag: tar.TypeDir},
+			{name: "etc/removed", typeflag: tar.TypeReg, content: "removed"},
+			{name: "etc/changed", typeflag: tar.TypeReg, content: "old"},
+			{name: "opt/", typeflag: tar.TypeDir},
+			{name: "opt/old", typeflag: tar.TypeReg, content: "old"},
+			{name: "opt/sub/", typeflag: tar.TypeDir},
+			{name: "opt/sub/old", typeflag: tar.TypeReg, content: "old"},
+			{name: "var/", typeflag: tar.TypeDir},
+			{name: "var/lib/", typeflag: tar.TypeDir},
+			{name: "var/lib/data", typeflag: tar.TypeReg, content: "data"},
+		}),
+		writeTestLayer(t, dir, "middle", false, []testEntry{
+			{name: "etc/.wh.removed", typeflag: tar.TypeReg},
+			{name: "opt/", typeflag: tar.TypeDir},
+			{name: "opt/.wh..wh..opq", typeflag: tar.TypeReg},
+			{name: "opt/new", typeflag: tar.TypeReg, content: "new"},
+			{name: "var/lib", typeflag: tar.TypeSymlink, linkname: "/srv"},
+			{name: "usr/bin/link", typeflag: tar.TypeLink, linkname: "usr/bin/tool"},
+		}),
+		writeTestLayer(t, dir, "top", true, []testEntry{
+			{name: "etc/changed", typeflag: tar.TypeReg, content: "new"},
+			{name: "etc/.wh.removed", typeflag: tar.TypeReg},
+			{name: "usr/", typeflag: tar.TypeDir},
+			{name: "usr/bin/", typeflag: tar.TypeDir},
+			{name: "usr/bin/tool", typeflag: tar.TypeReg, content: "tool"},
+		}),
+	}
+
+	metadata := api.ImageMetadata{Architecture: "x86_64", Properties: map[string]string{"description": "app:latest"}}
+
+	buf := bytes.Buffer{}
+	err = Flatten(&buf, metadata, layers)
+	require.NoError(t, err)
+
+	entries := readTestImage(t, &buf)
+	require.NotEmpty(t, entries)
+
+	assert.Equal(t, "metadata.yaml", entries[0].name)
+	result := api.ImageMetadata{}
+	require.NoError(t, yaml.Unmarshal([]byte(entries[0].content), &result))
+	assert.Equal(t, metadata.Architecture, result.Architecture)
+	assert.Equal(t, metadata.Properties, result.Properties)
+
+	assert.Equal(t, []testEntry{
+		{name: "rootfs/", typeflag: tar.TypeDir},
+		{name: "rootfs/etc/", typeflag: tar.TypeDir},
+		{name: "rootfs/var/", typeflag: tar.TypeDir},
+		{name: "rootfs/opt/", typeflag: tar.TypeDir},
+		{name: "rootfs/opt/new", typeflag: tar.TypeReg, content: "new"},
+		{name: "rootfs/var/lib", typeflag: tar.TypeSymlink, linkname: "/srv"},
+		{name: "rootfs/etc/changed", typeflag: tar.TypeReg, content: "new"},
+		{name: "rootfs/usr/", typeflag: tar.TypeDir},
+		{name: "rootfs/usr/bin/", typeflag: tar.TypeDir},
+		{name: "rootfs/usr/bin/tool", typeflag: tar.TypeReg, content: "tool"},
+		{name: "rootfs/usr/bin/link", typeflag: tar.TypeLink, linkname: "rootfs/usr/bin/tool"},
+	}, entries[1:])
+}
+
+func TestFlatten_InvalidPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lxd_oci_test_")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	layer := writeTestLayer(t, dir, "layer", false, []testEntry{
+		{name: "../escape", typeflag: tar.TypeReg, content: "escape"},
+	})
+
+	err = Flatten(ioutil.Discard, api.ImageMetadata{}, []string{layer})
+	assert.Error(t, err)
+}
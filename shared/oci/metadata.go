@@ -0,0 +1,82 @@
+package oci
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+// Properties returns the LXD image properties of an image. Besides the description and the reference pinning the
+// image ("oci.reference"), they record how to run the image: the command line ("oci.entrypoint"), the working
+// directory ("oci.cwd") and the environment variables ("oci.env.<name>").
+func Properties(image Image, config *ImageConfig) map[string]string {
+	description := fmt.Sprintf("%s:%s", image.Repository, image.Tag)
+	if image.Tag == "" {
+		description = fmt.Sprintf("%s@%s", image.Repository, image.Digest)
+	}
+
+	properties := map[string]string{
+		"architecture": image.Architecture,
+		"description":  description,
+		"os":           config.OS,
+
+		"oci.reference": fmt.Sprintf("%s@%s", image.Repository, image.Digest),
+	}
+
+	command := append(append([]string{}, config.Config.Entrypoint...), config.Config.Cmd...)
+	if len(command) > 0 {
+		properties["oci.entrypoint"] = quoteCommand(command)
+	}
+
+	if config.Config.WorkingDir != "" {
+		properties["oci.cwd"] = config.Config.WorkingDir
+	}
+
+	for _, env := range config.Config.Env {
+		fields := strings.SplitN(env, "=", 2)
+		if len(fields) != 2 || fields[0] == "" {
+			continue
+		}
+
+		properties[fmt.Sprintf("oci.env.%s", fields[0])] = fields[1]
+	}
+
+	return properties
+}
+
+// Metadata returns the metadata of the LXD container image built from an image.
+func Metadata(image Image, config *ImageConfig) api.ImageMetadata {
+	created := time.Now().UTC()
+	if config.Created != nil {
+		created = *config.Created
+	}
+
+	return api.ImageMetadata{
+		Architecture: image.Architecture,
+		CreationDate: created.Unix(),
+		Properties:   Properties(image, config),
+	}
+}
+
+// quoteCommand joins the arguments into a command line, quoting the arguments containing whitespace or quotes
+// so that it can be split back by liblxc. Arguments containing both single and double quotes are double quoted,
+// with their double quotes and backslashes escaped.
+func quoteCommand(args []string) string {
+	quoted := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg != "" && !strings.ContainsAny(arg, " \t\n'\"") {
+			quoted = append(quoted, arg)
+		} else if strings.Contains(arg, "'") && strings.Contains(arg, `"`) {
+			escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(arg)
+			quoted = append(quoted, fmt.Sprintf(`"%s"`, escaped))
+		} else if strings.Contains(arg, "'") {
+			quoted = append(quoted, fmt.Sprintf(`"%s"`, arg))
+		} else {
+			quoted = append(quoted, fmt.Sprintf("'%s'", arg))
+		}
+	}
+
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,518 @@
+package oci
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"sync"
+
+	"github.com/lxc/lxd/shared"
+	"github.com/lxc/lxd/shared/cancel"
+	"github.com/lxc/lxd/shared/ioprogress"
+	"github.com/lxc/lxd/shared/osarch"
+)
+
+// Limit on the size of the manifests and configs read into memory.
+const maxMetadataSize = 4 * 1024 * 1024
+
+// Docker Hub keeps single component repository names in the "library" namespace.
+var dockerHubHosts = []string{"docker.io", "index.docker.io", "registry-1.docker.io", "registry.hub.docker.com"}
+
+// NewClient returns a client for the OCI distribution (Docker registry v2) API of the provided registry URL.
+// Credentials included in the URL are used to authenticate against the registry.
+func NewClient(url string, httpClient http.Client, useragent string) *Client {
+	c := &Client{
+		http:          &httpClient,
+		url:           strings.TrimSuffix(url, "/"),
+		useragent:     useragent,
+		authorization: map[string]string{},
+	}
+
+	u, err := neturl.Parse(url)
+	if err == nil {
+		if u.User != nil {
+			c.username = u.User.Username()
+			c.password, _ = u.User.Password()
+			u.User = nil
+			c.url = strings.TrimSuffix(u.String(), "/")
+		}
+
+		c.host = u.Hostname()
+	}
+
+	return c
+}
+
+// Client represents an OCI registry client.
+type Client struct {
+	http      *http.Client
+	url       string
+	host      string
+	useragent string
+
+	username string
+	password string
+
+	authorization     map[string]string
+	authorizationLock sync.Mutex
+}
+
+// Resolve resolves an image reference to the images available for each supported architecture.
+func (c *Client) Resolve(name string) ([]Image, error) {
+	ref, err := ParseReference(name)
+	if err != nil {
+		return nil, err
+	}
+
+	repository := c.repository(ref.Repository)
+
+	body, mediaType, digest, err := c.getManifest(repository, ref.Reference())
+	if err != nil {
+		return nil, err
+	}
+
+	images := []Image{}
+	switch mediaType {
+	case MediaTypeImageIndex, MediaTypeDockerManifestList:
+		index := Index{}
+		err = json.Unmarshal(body, &index)
+		if err != nil {
+			return nil, fmt.Errorf("Failed parsing index of %q: %v", name, err)
+		}
+
+		for _, entry := range index.Manifests {
+			if entry.Platform == nil || entry.Platform.OS != "linux" {
+				continue
+			}
+
+			architecture, err := architectureName(entry.Platform.Architecture, entry.Platform.Variant)
+			if err != nil {
+				continue
+			}
+
+			// Keep the first image of each architecture.
+			found := false
+			for _, image := range images {
+				if image.Architecture == architecture {
+					found = true
+					break
+				}
+			}
+
+			if found {
+				continue
+			}
+
+			images = append(images, Image{Repository: repository, Tag: ref.Tag, Digest: entry.Digest, Architecture: architecture})
+		}
+	case MediaTypeImageManifest, MediaTypeDockerManifest:
+		manifest := Manifest{}
+		err = json.Unmarshal(body, &manifest)
+		if err != nil {
+			return nil, fmt.Errorf("Failed parsing manifest of %q: %v", name, err)
+		}
+
+		config, err := c.GetConfig(repository, &manifest)
+		if err != nil {
+			return nil, err
+		}
+
+		architecture, err := architectureName(config.Architecture, config.Variant)
+		if err != nil {
+			return nil, err
+		}
+
+		images = append(images, Image{Repository: repository, Tag: ref.Tag, Digest: digest, Architecture: architecture})
+	default:
+		return nil, fmt.Errorf("Unsupported manifest type %q for %q", mediaType, name)
+	}
+
+	if len(images) == 0 {
+		return nil, fmt.Errorf("No Linux images found for %q", name)
+	}
+
+	return images, nil
+}
+
+// GetManifest returns the manifest of an image.
+func (c *Client) GetManifest(image Image) (*Manifest, error) {
+	body, mediaType, _, err := c.getManifest(image.Repository, image.Digest)
+	if err != nil {
+		return nil, err
+	}
+
+	if mediaType != MediaTypeImageManifest && mediaType != MediaTypeDockerManifest {
+		return nil, fmt.Errorf("Unsupported manifest type %q for %q", mediaType, image.Digest)
+	}
+
+	manifest := Manifest{}
+	err = json.Unmarshal(body, &manifest)
+	if err != nil {
+		return nil, fmt.Errorf("Failed parsing manifest %q: %v", image.Digest, err)
+	}
+
+	return &manifest, nil
+}
+
+// GetConfig returns the config of the image described by the manifest.
+func (c *Client) GetConfig(repository string, manifest *Manifest) (*ImageConfig, error) {
+	if manifest.Config.Size > maxMetadataSize {
+		return nil, fmt.Errorf("Image config %q is too large", manifest.Config.Digest)
+	}
+
+	buf := bytes.Buffer{}
+	_, err := c.GetBlob(repository, manifest.Config, &buf, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	config := ImageConfig{}
+	err = json.Unmarshal(buf.Bytes(), &config)
+	if err != nil {
+		return nil, fmt.Errorf("Failed parsing image config %q: %v", manifest.Config.Digest, err)
+	}
+
+	return &config, nil
+}
+
+// GetBlob downloads a blob to the target, verifying its digest.
+func (c *Client) GetBlob(repository string, blob Descriptor, target io.Writer, canceler *cancel.Canceler, progress func(percent int64, speed int64)) (int64, error) {
+	hashFunc, expected, err := digestHash(blob.Digest)
+	if err != nil {
+		return -1, err
+	}
+
+	resp, doneCh, err := c.get(repository, fmt.Sprintf("/v2/%s/blobs/%s", repository, blob.Digest), nil, canceler)
+	if err != nil {
+		return -1, err
+	}
+	defer close(doneCh)
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return -1, fmt.Errorf("Failed fetching blob %q: %s", blob.Digest, resp.Status)
+	}
+
+	length := resp.ContentLength
+	if length < 0 {
+		length = blob.Size
+	}
+
+	var body io.ReadCloser = resp.Body
+	if progress != nil {
+		body = &ioprogress.ProgressReader{
+			ReadCloser: resp.Body,
+			Tracker: &ioprogress.ProgressTracker{
+				Length:  length,
+				Handler: progress,
+			},
+		}
+	}
+
+	size, err := io.Copy(io.MultiWriter(target, hashFunc), body)
+	if err != nil {
+		return -1, err
+	}
+
+	result := fmt.Sprintf("%x", hashFunc.Sum(nil))
+	if result != expected {
+		return -1, fmt.Errorf("Hash mismatch for blob %q: %s", blob.Digest, result)
+	}
+
+	return size, nil
+}
+
+// repository returns the name of the repository in the registry.
+func (c *Client) repository(name string) string {
+	if shared.StringInSlice(c.host, dockerHubHosts) && !strings.Contains(name, "/") {
+		return fmt.Sprintf("library/%s", name)
+	}
+
+	return name
+}
+
+// getManifest fetches a manifest, returning its content, media type and digest.
+func (c *Client) getManifest(repository string, reference string) ([]byte, string, string, error) {
+	accept := []string{MediaTypeImageIndex, MediaTypeDockerManifestList, MediaTypeImageManifest, MediaTypeDockerManifest}
+
+	resp, doneCh, err := c.get(repository, fmt.Sprintf("/v2/%s/manifests/%s", repository, reference), accept, nil)
+	if err != nil {
+		return nil, "", "", err
+	}
+	defer close(doneCh)
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, "", "", fmt.Errorf("Image \"%s:%s\" not found", repository, reference)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", "", fmt.Errorf("Failed fetching manifest \"%s:%s\": %s", repository, reference, resp.Status)
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxMetadataSize+1))
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	if len(body) > maxMetadataSize {
+		return nil, "", "", fmt.Errorf("Manifest \"%s:%s\" is too large", repository, reference)
+	}
+
+	digest := fmt.Sprintf("sha256:%x", sha256.Sum256(body))
+	if strings.Contains(reference, ":") && reference != digest {
+		return nil, "", "", fmt.Errorf("Hash mismatch for manifest %q: %s", reference, digest)
+	}
+
+	// Fallback to the media type recorded in the manifest itself.
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || !shared.StringInSlice(mediaType, accept) {
+		fields := struct {
+			MediaType string            `json:"mediaType"`
+			Manifests []json.RawMessage `json:"manifests"`
+		}{}
+
+		err = json.Unmarshal(body, &fields)
+		if err != nil {
+			return nil, "", "", fmt.Errorf("Failed parsing manifest \"%s:%s\": %v", repository, reference, err)
+		}
+
+		mediaType = fields.MediaType
+		if mediaType == "" {
+			mediaType = MediaTypeImageManifest
+			if fields.Manifests != nil {
+				mediaType = MediaTypeImageIndex
+			}
+		}
+	}
+
+	return body, mediaType, digest, nil
+}
+
+// get performs a GET request against the registry, authenticating when requested to by the registry.
+// The returned channel must be closed once done with the response.
+func (c *Client) get(repository string, path string, accept []string, canceler *cancel.Canceler) (*http.Response, chan bool, error) {
+	authenticated := false
+	for {
+		req, err := http.NewRequest("GET", c.url+path, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if c.useragent != "" {
+			req.Header.Set("User-Agent", c.useragent)
+		}
+
+		for _, mediaType := range accept {
+			req.Header.Add("Accept", mediaType)
+		}
+
+		c.authorizationLock.Lock()
+		authorization, ok := c.authorization[repository]
+		c.authorizationLock.Unlock()
+		if ok {
+			req.Header.Set("Authorization", authorization)
+		}
+
+		resp, doneCh, err := cancel.CancelableDownload(canceler, c.http, req)
+		if err != nil {
+			close(doneCh)
+			return nil, nil, err
+		}
+
+		if resp.StatusCode != http.StatusUnauthorized || authenticated {
+			return resp, doneCh, nil
+		}
+
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		close(doneCh)
+
+		err = c.authenticate(repository, challenge)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		authenticated = true
+	}
+}
+
+// authenticate handles an authentication challenge of the registry for the repository.
+func (c *Client) authenticate(repository string, challenge string) error {
+	scheme, params := parseChallenge(challenge)
+
+	var authorization string
+	switch scheme {
+	case "basic":
+		if c.username == "" {
+			return fmt.Errorf("The registry requires authentication")
+		}
+
+		authorization = fmt.Sprintf("Basic %s", base64.StdEncoding.EncodeToString([]byte(c.username+":"+c.password)))
+	case "bearer":
+		token, err := c.getToken(repository, params)
+		if err != nil {
+			return err
+		}
+
+		authorization = fmt.Sprintf("Bearer %s", token)
+	default:
+		return fmt.Errorf("Unsupported registry authentication scheme %q", scheme)
+	}
+
+	c.authorizationLock.Lock()
+	c.authorization[repository] = authorization
+	c.authorizationLock.Unlock()
+
+	return nil
+}
+
+// getToken requests a pull token for the repository from the token service named in the challenge.
+func (c *Client) getToken(repository string, params map[string]string) (string, error) {
+	if params["realm"] == "" {
+		return "", fmt.Errorf("Missing realm in registry authentication challenge")
+	}
+
+	u, err := neturl.Parse(params["realm"])
+	if err != nil {
+		return "", fmt.Errorf("Invalid registry token realm %q: %v", params["realm"], err)
+	}
+
+	scope := params["scope"]
+	if scope == "" {
+		scope = fmt.Sprintf("repository:%s:pull", repository)
+	}
+
+	values := u.Query()
+	values.Set("scope", scope)
+	if params["service"] != "" {
+		values.Set("service", params["service"])
+	}
+
+	u.RawQuery = values.Encode()
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+
+	if c.useragent != "" {
+		req.Header.Set("User-Agent", c.useragent)
+	}
+
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Failed getting registry token: %s", resp.Status)
+	}
+
+	token := struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+
+	err = json.NewDecoder(io.LimitReader(resp.Body, maxMetadataSize)).Decode(&token)
+	if err != nil {
+		return "", fmt.Errorf("Failed parsing registry token: %v", err)
+	}
+
+	if token.Token != "" {
+		return token.Token, nil
+	}
+
+	if token.AccessToken != "" {
+		return token.AccessToken, nil
+	}
+
+	return "", fmt.Errorf("No token returned by the registry")
+}
+
+// parseChallenge parses a WWW-Authenticate header into its scheme and parameters.
+func parseChallenge(header string) (string, map[string]string) {
+	params := map[string]string{}
+
+	fields := strings.SplitN(strings.TrimSpace(header), " ", 2)
+	scheme := strings.ToLower(fields[0])
+	if len(fields) < 2 {
+		return scheme, params
+	}
+
+	// Parameters are comma separated key=value pairs, quoted values may contain commas.
+	rest := fields[1]
+	for {
+		rest = strings.TrimLeft(rest, ", ")
+		pos := strings.Index(rest, "=")
+		if pos < 0 {
+			break
+		}
+
+		key := strings.ToLower(strings.TrimSpace(rest[:pos]))
+		rest = rest[pos+1:]
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				value = rest[1:]
+				rest = ""
+			} else {
+				value = rest[1 : end+1]
+				rest = rest[end+2:]
+			}
+		} else {
+			end := strings.Index(rest, ",")
+			if end < 0 {
+				value = rest
+				rest = ""
+			} else {
+				value = rest[:end]
+				rest = rest[end+1:]
+			}
+		}
+
+		params[key] = value
+	}
+
+	return scheme, params
+}
+
+// digestHash returns the hash function and the expected hex encoded hash of a digest.
+func digestHash(digest string) (hash.Hash, string, error) {
+	if !digestPattern.MatchString(digest) {
+		return nil, "", fmt.Errorf("Unsupported digest %q", digest)
+	}
+
+	return sha256.New(), strings.TrimPrefix(digest, "sha256:"), nil
+}
+
+// architectureName converts an OCI platform architecture to its LXD name.
+func architectureName(architecture string, variant string) (string, error) {
+	// Only ARMv7 is supported out of the 32bit ARM variants.
+	if architecture == "arm" && variant != "" && variant != "v7" {
+		return "", fmt.Errorf("Architecture isn't supported: %s/%s", architecture, variant)
+	}
+
+	id, err := osarch.ArchitectureId(architecture)
+	if err != nil {
+		return "", err
+	}
+
+	return osarch.ArchitectureName(id)
+}
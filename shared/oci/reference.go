@@ -0,0 +1,74 @@
+package oci
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var repositoryPattern = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*$`)
+var tagPattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+var digestPattern = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+
+// Reference identifies an image within a registry, either by tag or by manifest digest.
+type Reference struct {
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// ParseReference parses a "repository[:tag]" or "repository@digest" image reference.
+// The tag defaults to "latest" when neither a tag nor a digest is specified.
+func ParseReference(name string) (*Reference, error) {
+	ref := Reference{}
+
+	repository := name
+	if strings.Contains(name, "@") {
+		fields := strings.SplitN(name, "@", 2)
+		repository = fields[0]
+		ref.Digest = fields[1]
+
+		if !digestPattern.MatchString(ref.Digest) {
+			return nil, fmt.Errorf("Invalid digest %q", ref.Digest)
+		}
+	} else {
+		// The tag separator is the last colon after the last slash.
+		pos := strings.LastIndex(name, ":")
+		if pos > strings.LastIndex(name, "/") {
+			repository = name[:pos]
+			ref.Tag = name[pos+1:]
+
+			if !tagPattern.MatchString(ref.Tag) {
+				return nil, fmt.Errorf("Invalid tag %q", ref.Tag)
+			}
+		} else {
+			ref.Tag = "latest"
+		}
+	}
+
+	if !repositoryPattern.MatchString(repository) {
+		return nil, fmt.Errorf("Invalid repository name %q", repository)
+	}
+
+	ref.Repository = repository
+
+	return &ref, nil
+}
+
+// Reference returns the tag or digest part used to fetch the manifest.
+func (r *Reference) Reference() string {
+	if r.Digest != "" {
+		return r.Digest
+	}
+
+	return r.Tag
+}
+
+// String returns the textual form of the reference.
+func (r *Reference) String() string {
+	if r.Digest != "" {
+		return fmt.Sprintf("%s@%s", r.Repository, r.Digest)
+	}
+
+	return fmt.Sprintf("%s:%s", r.Repository, r.Tag)
+}
@@ -2,6 +2,7 @@ package simplestreams
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
@@ -220,7 +221,8 @@ func (s *Products) ToLXD() ([]api.Image, map[string][][]string) {
 					// Locate source image fingerprint
 					var srcFingerprint string
 					for _, item := range srcImage.Items {
-						if item.FileType != "lxd.tar.xz" {
+						// Skip the metadata of images without a squashfs rootfs (e.g. virtual machines)
+						if item.FileType != "lxd.tar.xz" || item.LXDHashSha256SquashFs == "" {
 							continue
 						}
 
@@ -274,3 +276,205 @@ func (s *Products) ToLXD() ([]api.Image, map[string][][]string) {
 
 	return images, downloads
 }
+
+// ProductsFromLXD converts a list of LXD images into products data, it is the inverse of ToLXD.
+// The files map is indexed by image fingerprint and holds the "meta", "root" and "root.delta-<fingerprint>"
+// files of each image, as returned by GetFiles. Split images must have their "type" property set to the file
+// type of their root file (squashfs, disk-kvm.img, root.tar.xz, ...). Images which can't be represented are
+// skipped, as are all but the oldest of the images sharing a product version.
+func ProductsFromLXD(images []api.Image, files map[string]map[string]DownloadableFile) *Products {
+	nameLayout := "20060102"
+	eolLayout := "2006-01-02"
+
+	products := map[string]Product{}
+
+	// Record the product and version of every published image to resolve the delta bases.
+	imageProducts := map[string]string{}
+	imageVersions := map[string]string{}
+
+	// Process the oldest images first so the product metadata ends up matching the newest image.
+	sorted := make([]api.Image, len(images))
+	copy(sorted, images)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].CreatedAt.Before(sorted[j].CreatedAt)
+	})
+
+	for _, image := range sorted {
+		imgFiles := files[image.Fingerprint]
+
+		meta, ok := imgFiles["meta"]
+		if !ok {
+			continue
+		}
+
+		root, split := imgFiles["root"]
+		rootType := image.Properties["type"]
+		if split && !shared.StringInSlice(rootType, []string{"disk1.img", "disk-kvm.img", "uefi1.img", "root.tar.xz", "squashfs"}) {
+			continue
+		}
+
+		// Keep the architecture name used by the image if it has one.
+		architecture := image.Properties["architecture"]
+		_, err := osarch.ArchitectureId(architecture)
+		if err != nil {
+			architecture = image.Architecture
+		}
+
+		_, err = osarch.ArchitectureId(architecture)
+		if err != nil {
+			continue
+		}
+
+		// Each product only holds images of a single type so that a version has a single set of files.
+		osName := image.Properties["os"]
+		release := image.Properties["release"]
+		productName := image.Fingerprint
+		if osName != "" && release != "" {
+			fields := []string{osName, release, architecture}
+			if image.Properties["variant"] != "" {
+				fields = append(fields, image.Properties["variant"])
+			}
+
+			productName = strings.Join(append(fields, image.Type), ":")
+		}
+
+		// Use the serial as the version name if it's usable as such, the creation date otherwise.
+		versionName := image.Properties["serial"]
+		if len(versionName) < 8 {
+			versionName = image.CreatedAt.UTC().Format("20060102_150405")
+		} else {
+			_, err = time.Parse(nameLayout, versionName[0:8])
+			if err != nil {
+				versionName = image.CreatedAt.UTC().Format("20060102_150405")
+			}
+		}
+
+		product, ok := products[productName]
+		if !ok {
+			product.Versions = map[string]ProductVersion{}
+		}
+
+		_, ok = product.Versions[versionName]
+		if ok {
+			continue
+		}
+
+		product.Architecture = architecture
+		product.OperatingSystem = osName
+		product.Release = release
+		product.ReleaseTitle = image.Properties["release_title"]
+		if product.ReleaseTitle == "" {
+			product.ReleaseTitle = release
+		}
+
+		product.Version = image.Properties["version"]
+		product.SupportedEOL = ""
+		if image.ExpiresAt.Unix() > 0 {
+			product.SupportedEOL = image.ExpiresAt.UTC().Format(eolLayout)
+		}
+
+		// Merge the aliases of all the images of the product.
+		aliases := []string{}
+		if product.Aliases != "" {
+			aliases = strings.Split(product.Aliases, ",")
+		}
+
+		for _, alias := range image.Aliases {
+			if !shared.StringInSlice(alias.Name, aliases) {
+				aliases = append(aliases, alias.Name)
+			}
+		}
+
+		sort.Strings(aliases)
+		product.Aliases = strings.Join(aliases, ",")
+
+		version := ProductVersion{
+			Items:      map[string]ProductVersionItem{},
+			Label:      image.Properties["label"],
+			PublicName: image.Properties["description"],
+		}
+
+		if split {
+			metaItem := ProductVersionItem{
+				FileType:   "lxd.tar.xz",
+				Path:       meta.Path,
+				HashSha256: meta.Sha256,
+				Size:       meta.Size,
+			}
+
+			switch rootType {
+			case "root.tar.xz":
+				metaItem.LXDHashSha256RootXz = image.Fingerprint
+			case "squashfs":
+				metaItem.LXDHashSha256SquashFs = image.Fingerprint
+			case "disk-kvm.img":
+				metaItem.LXDHashSha256DiskKvmImg = image.Fingerprint
+			case "disk1.img":
+				metaItem.LXDHashSha256DiskImg = image.Fingerprint
+			case "uefi1.img":
+				metaItem.LXDHashSha256DiskUefiImg = image.Fingerprint
+			}
+
+			version.Items["lxd.tar.xz"] = metaItem
+			version.Items[rootType] = ProductVersionItem{
+				FileType:   rootType,
+				Path:       root.Path,
+				HashSha256: root.Sha256,
+				Size:       root.Size,
+			}
+		} else {
+			version.Items["lxd_combined.tar.gz"] = ProductVersionItem{
+				FileType:   "lxd_combined.tar.gz",
+				Path:       meta.Path,
+				HashSha256: meta.Sha256,
+				Size:       meta.Size,
+			}
+		}
+
+		product.Versions[versionName] = version
+		products[productName] = product
+
+		imageProducts[image.Fingerprint] = productName
+		imageVersions[image.Fingerprint] = versionName
+	}
+
+	// Add the deltas against other versions of the same product (only on squashfs).
+	for _, image := range sorted {
+		productName, ok := imageProducts[image.Fingerprint]
+		if !ok || image.Properties["type"] != "squashfs" {
+			continue
+		}
+
+		version := products[productName].Versions[imageVersions[image.Fingerprint]]
+		if version.Items["lxd.tar.xz"].LXDHashSha256SquashFs != image.Fingerprint {
+			continue
+		}
+
+		for fileType, file := range files[image.Fingerprint] {
+			if !strings.HasPrefix(fileType, "root.delta-") {
+				continue
+			}
+
+			srcFingerprint := strings.TrimPrefix(fileType, "root.delta-")
+			if imageProducts[srcFingerprint] != productName {
+				continue
+			}
+
+			srcVersionName := imageVersions[srcFingerprint]
+			version.Items[fmt.Sprintf("delta-%s.vcdiff", srcVersionName)] = ProductVersionItem{
+				FileType:   "squashfs.vcdiff",
+				Path:       file.Path,
+				HashSha256: file.Sha256,
+				Size:       file.Size,
+				DeltaBase:  srcVersionName,
+			}
+		}
+	}
+
+	return &Products{
+		ContentID: "images",
+		DataType:  "image-downloads",
+		Format:    "products:1.0",
+		Products:  products,
+	}
+}
@@ -0,0 +1,132 @@
+package simplestreams
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/lxc/lxd/shared/api"
+)
+
+type productsTestSuite struct {
+	suite.Suite
+}
+
+func TestProductsTestSuite(t *testing.T) {
+	suite.Run(t, new(productsTestSuite))
+}
+
+func (s *productsTestSuite) TestProductsFromLXDRoundTrip() {
+	created := time.Date(2021, time.March, 1, 12, 0, 0, 0, time.UTC)
+	properties := map[string]string{
+		"os":           "ubuntu",
+		"release":      "focal",
+		"architecture": "amd64",
+		"type":         "squashfs",
+	}
+
+	images := []api.Image{}
+	for i, fingerprint := range []string{"aaaa", "bbbb"} {
+		image := api.Image{
+			Architecture: "x86_64",
+			Type:         "container",
+			Fingerprint:  fingerprint,
+			CreatedAt:    created.AddDate(0, 0, i),
+			ImagePut: api.ImagePut{
+				Public:     true,
+				Properties: map[string]string{},
+			},
+		}
+
+		for k, v := range properties {
+			image.Properties[k] = v
+		}
+
+		image.Properties["serial"] = image.CreatedAt.Format("20060102_1504")
+		image.Aliases = []api.ImageAlias{{Name: "ubuntu/focal"}}
+		images = append(images, image)
+	}
+
+	images = append(images, api.Image{
+		Architecture: "x86_64",
+		Type:         "container",
+		Fingerprint:  "cccc",
+		CreatedAt:    created,
+	})
+
+	files := map[string]map[string]DownloadableFile{
+		"aaaa": {
+			"meta": {Path: "streams/images/aaaa/lxd.tar.xz", Sha256: "aaaa-meta", Size: 1},
+			"root": {Path: "streams/images/aaaa/rootfs.squashfs", Sha256: "aaaa-root", Size: 10},
+		},
+		"bbbb": {
+			"meta":             {Path: "streams/images/bbbb/lxd.tar.xz", Sha256: "bbbb-meta", Size: 1},
+			"root":             {Path: "streams/images/bbbb/rootfs.squashfs", Sha256: "bbbb-root", Size: 10},
+			"root.delta-aaaa":  {Path: "streams/images/bbbb/aaaa.vcdiff", Sha256: "bbbb-delta", Size: 2},
+			"root.delta-other": {Path: "streams/images/bbbb/other.vcdiff", Sha256: "other-delta", Size: 2},
+		},
+		"cccc": {
+			"meta": {Path: "streams/images/cccc/lxd.tar.gz", Sha256: "cccc", Size: 5},
+		},
+	}
+
+	products := ProductsFromLXD(images, files)
+	s.Len(products.Products, 2)
+	s.Equal("ubuntu/focal", products.Products["ubuntu:focal:amd64:container"].Aliases)
+
+	result, downloads := products.ToLXD()
+	s.Len(result, 3)
+
+	for _, image := range result {
+		s.Equal("x86_64", image.Architecture)
+	}
+
+	s.Equal([][]string{{"streams/images/cccc/lxd.tar.gz", "cccc", "meta", "5"}}, downloads["cccc"])
+	s.Equal([][]string{
+		{"streams/images/aaaa/lxd.tar.xz", "aaaa-meta", "meta", "1"},
+		{"streams/images/aaaa/rootfs.squashfs", "aaaa-root", "root", "10"},
+	}, downloads["aaaa"])
+	s.Equal([][]string{
+		{"streams/images/bbbb/lxd.tar.xz", "bbbb-meta", "meta", "1"},
+		{"streams/images/bbbb/rootfs.squashfs", "bbbb-root", "root", "10"},
+		{"streams/images/bbbb/aaaa.vcdiff", "bbbb-delta", "root.delta-aaaa", "2"},
+	}, downloads["bbbb"])
+}
+
+func (s *productsTestSuite) TestToLXDDeltaSourceWithSeveralMetadata() {
+	products := Products{
+		Products: map[string]Product{
+			"ubuntu:focal:amd64:default": {
+				Architecture:    "amd64",
+				OperatingSystem: "ubuntu",
+				Release:         "focal",
+				Versions: map[string]ProductVersion{
+					"20210301_12:00": {
+						Items: map[string]ProductVersionItem{
+							"lxd.tar.xz":    {FileType: "lxd.tar.xz", Path: "old/lxd.tar.xz", LXDHashSha256SquashFs: "old-container"},
+							"lxd-vm.tar.xz": {FileType: "lxd.tar.xz", Path: "old/lxd-vm.tar.xz", LXDHashSha256DiskKvmImg: "old-vm"},
+							"root.squashfs": {FileType: "squashfs", Path: "old/root.squashfs"},
+							"disk-kvm.img":  {FileType: "disk-kvm.img", Path: "old/disk-kvm.img"},
+							"delta.vcdiff":  {FileType: "squashfs.vcdiff", Path: "old/ignored.vcdiff", DeltaBase: "20210228_12:00"},
+						},
+					},
+					"20210302_12:00": {
+						Items: map[string]ProductVersionItem{
+							"lxd.tar.xz":    {FileType: "lxd.tar.xz", Path: "new/lxd.tar.xz", LXDHashSha256SquashFs: "new-container"},
+							"root.squashfs": {FileType: "squashfs", Path: "new/root.squashfs"},
+							"delta.vcdiff":  {FileType: "squashfs.vcdiff", Path: "new/delta.vcdiff", HashSha256: "delta", Size: 3, DeltaBase: "20210301_12:00"},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for i := 0; i < 10; i++ {
+		_, downloads := products.ToLXD()
+		s.Contains(downloads["new-container"], []string{"new/delta.vcdiff", "delta", "root.delta-old-container", "3"})
+		s.Len(downloads["old-container"], 2)
+		s.Len(downloads["old-vm"], 2)
+	}
+}
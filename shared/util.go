@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"crypto/rand"
+	"encoding/base64"
 	"encoding/gob"
 	"encoding/hex"
 	"encoding/json"
@@ -27,6 +28,7 @@ import (
 	"github.com/flosch/pongo2"
 	"github.com/pkg/errors"
 
+	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/cancel"
 	"github.com/lxc/lxd/shared/ioprogress"
 	"github.com/lxc/lxd/shared/units"
@@ -1227,3 +1229,49 @@ func InSnap() bool {
 
 	return false
 }
+
+// JoinTokenDecode decodes a base64 and JSON encoded cluster member join token.
+func JoinTokenDecode(input string) (*api.ClusterMemberJoinToken, error) {
+	joinToken := api.ClusterMemberJoinToken{}
+
+	err := tokenDecode(input, &joinToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if joinToken.ServerName == "" || joinToken.Secret == "" || len(joinToken.Addresses) < 1 {
+		return nil, fmt.Errorf("Invalid join token")
+	}
+
+	return &joinToken, nil
+}
+
+// CertificateTokenDecode decodes a base64 and JSON encoded certificate add token.
+func CertificateTokenDecode(input string) (*api.CertificateAddToken, error) {
+	addToken := api.CertificateAddToken{}
+
+	err := tokenDecode(input, &addToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if addToken.ClientName == "" || addToken.Secret == "" || len(addToken.Addresses) < 1 {
+		return nil, fmt.Errorf("Invalid certificate add token")
+	}
+
+	return &addToken, nil
+}
+
+func tokenDecode(input string, token interface{}) error {
+	data, err := base64.StdEncoding.DecodeString(input)
+	if err != nil {
+		return errors.Wrap(err, "Failed to decode token")
+	}
+
+	err = json.Unmarshal(data, token)
+	if err != nil {
+		return errors.Wrap(err, "Failed to parse token")
+	}
+
+	return nil
+}
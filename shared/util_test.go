@@ -12,6 +12,8 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/lxc/lxd/shared/api"
 )
 
 func TestURLEncode(t *testing.T) {
@@ -174,3 +176,33 @@ func TestGetSnapshotExpiry(t *testing.T) {
 	require.Error(t, err)
 	require.Equal(t, time.Time{}, expiryDate)
 }
+
+func TestJoinTokenDecode(t *testing.T) {
+	joinToken := api.ClusterMemberJoinToken{
+		ServerName:  "node2",
+		Fingerprint: "abcdef",
+		Addresses:   []string{"10.0.0.1:8443", "10.0.0.2:8443"},
+		Secret:      "secret",
+	}
+
+	decoded, err := JoinTokenDecode(joinToken.String())
+	require.NoError(t, err)
+	require.Equal(t, joinToken, *decoded)
+
+	_, err = JoinTokenDecode("not a token")
+	require.Error(t, err)
+
+	// A certificate add token isn't a valid join token.
+	addToken := api.CertificateAddToken{
+		ClientName: "client",
+		Addresses:  []string{"10.0.0.1:8443"},
+		Secret:     "secret",
+	}
+
+	_, err = JoinTokenDecode(addToken.String())
+	require.Error(t, err)
+
+	decodedAddToken, err := CertificateTokenDecode(addToken.String())
+	require.NoError(t, err)
+	require.Equal(t, addToken, *decodedAddToken)
+}
@@ -7,6 +7,8 @@ import (
 	"strconv"
 	"strings"
 
+	"gopkg.in/robfig/cron.v2"
+
 	"github.com/lxc/lxd/shared/units"
 )
 
@@ -411,3 +413,17 @@ func IsURLSegmentSafe(value string) error {
 
 	return nil
 }
+
+// IsCronSchedule validates a cron expression of the form <minute> <hour> <day-of-month> <month> <day-of-week>.
+func IsCronSchedule(value string) error {
+	if len(strings.Split(value, " ")) != 5 {
+		return fmt.Errorf("Schedule must be of the form: <minute> <hour> <day-of-month> <month> <day-of-week>")
+	}
+
+	_, err := cron.Parse(fmt.Sprintf("* %s", value))
+	if err != nil {
+		return fmt.Errorf("Error parsing schedule: %v", err)
+	}
+
+	return nil
+}
@@ -28,3 +28,24 @@ func ExampleIsNetworkMAC() {
 	// invalid, false
 	// , false
 }
+
+func ExampleIsCronSchedule() {
+	tests := []string{
+		"0 2 * * *",
+		"*/15 * * * 1-5",
+		"0 2 * *",    // too short
+		"0 25 * * *", // invalid hour
+		"",
+	}
+
+	for _, v := range tests {
+		err := validate.IsCronSchedule(v)
+		fmt.Printf("%s, %t\n", v, err == nil)
+	}
+
+	// Output: 0 2 * * *, true
+	// */15 * * * 1-5, true
+	// 0 2 * *, false
+	// 0 25 * * *, false
+	// , false
+}
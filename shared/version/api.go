@@ -224,6 +224,32 @@ var APIExtensions = []string{
 	"network_type_sriov",
 	"container_syscall_intercept_bpf_devices",
 	"network_type_ovn",
+	"metrics",
+	"network_acl",
+	"warnings",
+	"clustering_evacuation",
+	"custom_volume_backup",
+	"vm_live_migration",
+	"vm_stateful",
+	"vm_device_hotplug",
+	"network_forward",
+	"network_load_balancer",
+	"projects_networks",
+	"image_source_oci",
+	"storage_buckets",
+	"storage_driver_dmthin",
+	"backup_incremental",
+	"backup_schedule",
+	"storage_volume_snapshot_copy",
+	"storage_volume_state",
+	"storage_pool_migrate",
+	"images_simplestreams_server",
+	"certificate_project",
+	"clustering_join_token",
+	"certificate_token",
+	"clustering_groups",
+	"clustering_healing",
+	"clustering_upgrade",
 }
 
 // APIExtensionsCount returns the number of available API extensions.
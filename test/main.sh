@@ -183,6 +183,8 @@ run_test test_clustering_handover "clustering handover"
 run_test test_clustering_rebalance "clustering rebalance"
 run_test test_clustering_remove_raft_node "custering remove raft node"
 run_test test_clustering_failure_domains "clustering failure domains"
+run_test test_clustering_evacuation "clustering evacuation"
+run_test test_clustering_groups "clustering groups"
 # run_test test_clustering_upgrade "clustering upgrade"
 run_test test_projects_default "default project"
 run_test test_projects_crud "projects CRUD operations"
@@ -220,6 +222,7 @@ run_test test_image_list_all_aliases "image list all aliases"
 run_test test_image_auto_update "image auto-update"
 run_test test_image_prefer_cached "image prefer cached"
 run_test test_image_import_dir "import image from directory"
+run_test test_image_simplestreams_server "image simplestreams server"
 run_test test_concurrent_exec "concurrent exec"
 run_test test_concurrent "concurrent startup"
 run_test test_snapshots "container snapshots"
@@ -233,6 +236,7 @@ run_test test_container_snapshot_config "container snapshot configuration"
 run_test test_server_config "server configuration"
 run_test test_filemanip "file manipulations"
 run_test test_network "network management"
+run_test test_network_acl "network ACL management"
 run_test test_idmap "id mapping"
 run_test test_template "file templating"
 run_test test_pki "PKI mode"
@@ -252,6 +256,8 @@ run_test test_storage_driver_btrfs "btrfs storage driver"
 run_test test_storage_driver_ceph "ceph storage driver"
 run_test test_storage_driver_cephfs "cephfs storage driver"
 run_test test_resources "resources"
+run_test test_metrics "metrics"
+run_test test_warnings "warnings"
 run_test test_kernel_limits "kernel limits"
 run_test test_macaroon_auth "macaroon authentication"
 run_test test_console "console"
@@ -260,6 +266,7 @@ run_test test_storage_local_volume_handling "storage local volume handling"
 run_test test_backup_import "backup import"
 run_test test_backup_export "backup export"
 run_test test_backup_rename "backup rename"
+run_test test_backup_volume_export "backup volume export"
 run_test test_container_local_cross_pool_handling "container local cross pool handling"
 run_test test_incremental_copy "incremental container copy"
 run_test test_profiles_project_default "profiles in default project"
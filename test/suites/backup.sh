@@ -413,3 +413,75 @@ test_backup_rename() {
 
   lxc delete --force c2
 }
+
+test_backup_volume_export() {
+  ensure_has_localhost_remote "${LXD_ADDR}"
+
+  pool="lxdtest-$(basename "${LXD_DIR}")"
+  lxd_backend=$(storage_backend "$LXD_DIR")
+
+  mkdir "${LXD_DIR}/optimized" "${LXD_DIR}/non-optimized"
+
+  lxc storage volume create "${pool}" testvol
+  lxc storage volume snapshot "${pool}" testvol
+
+  # volume only
+
+  if [ "$lxd_backend" = "btrfs" ] || [ "$lxd_backend" = "zfs" ]; then
+    lxc storage volume export "${pool}" testvol "${LXD_DIR}/testvol-optimized.tar.gz" --optimized-storage --volume-only
+    tar -xzf "${LXD_DIR}/testvol-optimized.tar.gz" -C "${LXD_DIR}/optimized"
+
+    [ -f "${LXD_DIR}/optimized/backup/index.yaml" ]
+    [ -f "${LXD_DIR}/optimized/backup/volume.bin" ]
+    [ ! -d "${LXD_DIR}/optimized/backup/volume-snapshots" ]
+  fi
+
+  lxc storage volume export "${pool}" testvol "${LXD_DIR}/testvol.tar.gz" --volume-only
+  tar -xzf "${LXD_DIR}/testvol.tar.gz" -C "${LXD_DIR}/non-optimized"
+
+  # check tarball content
+  [ -f "${LXD_DIR}/non-optimized/backup/index.yaml" ]
+  [ -d "${LXD_DIR}/non-optimized/backup/volume" ]
+  [ ! -d "${LXD_DIR}/non-optimized/backup/volume-snapshots" ]
+  grep -q "type: custom" "${LXD_DIR}/non-optimized/backup/index.yaml"
+
+  rm -rf "${LXD_DIR}/non-optimized/"* "${LXD_DIR}/optimized/"*
+
+  # with snapshots
+
+  lxc storage volume export "${pool}" testvol "${LXD_DIR}/testvol.tar.gz"
+  tar -xzf "${LXD_DIR}/testvol.tar.gz" -C "${LXD_DIR}/non-optimized"
+
+  [ -f "${LXD_DIR}/non-optimized/backup/index.yaml" ]
+  [ -d "${LXD_DIR}/non-optimized/backup/volume" ]
+  [ -d "${LXD_DIR}/non-optimized/backup/volume-snapshots/snap0" ]
+
+  # The temporary backup should be gone
+  [ "$(lxc query /1.0/storage-pools/${pool}/volumes/custom/testvol/backups | jq length)" = "0" ]
+
+  # Import under the original and a new name
+  lxc storage volume delete "${pool}" testvol
+  lxc storage volume import "${pool}" "${LXD_DIR}/testvol.tar.gz"
+  lxc storage volume show "${pool}" testvol
+  lxc storage volume show "${pool}" testvol/snap0
+
+  lxc storage volume import "${pool}" "${LXD_DIR}/testvol.tar.gz" testvol2
+  lxc storage volume show "${pool}" testvol2/snap0
+
+  # Importing over an existing volume must fail
+  ! lxc storage volume import "${pool}" "${LXD_DIR}/testvol.tar.gz" || false
+
+  # Custom volume backups can't be imported as instances
+  ! lxc import "${LXD_DIR}/testvol.tar.gz" || false
+
+  # Backups follow the volume on rename and are removed with it
+  lxc query -X POST --wait -d '{\"name\":\"foo\"}' "/1.0/storage-pools/${pool}/volumes/custom/testvol2/backups"
+  lxc query "/1.0/storage-pools/${pool}/volumes/custom/testvol2/backups/foo"
+  lxc storage volume rename "${pool}" testvol2 testvol3
+  lxc query "/1.0/storage-pools/${pool}/volumes/custom/testvol3/backups/foo"
+  lxc storage volume delete "${pool}" testvol3
+  [ ! -d "${LXD_DIR}/backups/custom/${pool}/default_testvol3" ]
+
+  lxc storage volume delete "${pool}" testvol
+  rm -rf "${LXD_DIR}/optimized" "${LXD_DIR}/non-optimized" "${LXD_DIR}"/testvol*.tar.gz
+}
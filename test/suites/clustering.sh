@@ -2005,3 +2005,174 @@ test_clustering_failure_domains() {
   kill_lxd "${LXD_FIVE_DIR}"
   kill_lxd "${LXD_SIX_DIR}"
 }
+
+test_clustering_evacuation() {
+  # shellcheck disable=2039
+  local LXD_DIR
+
+  setup_clustering_bridge
+  prefix="lxd$$"
+  bridge="${prefix}"
+
+  setup_clustering_netns 1
+  LXD_ONE_DIR=$(mktemp -d -p "${TEST_DIR}" XXX)
+  chmod +x "${LXD_ONE_DIR}"
+  ns1="${prefix}1"
+  spawn_lxd_and_bootstrap_cluster "${ns1}" "${bridge}" "${LXD_ONE_DIR}"
+
+  # Add a newline at the end of each line. YAML as weird rules..
+  cert=$(sed ':a;N;$!ba;s/\n/\n\n/g' "${LXD_ONE_DIR}/server.crt")
+
+  # Spawn a second node
+  setup_clustering_netns 2
+  LXD_TWO_DIR=$(mktemp -d -p "${TEST_DIR}" XXX)
+  chmod +x "${LXD_TWO_DIR}"
+  ns2="${prefix}2"
+  spawn_lxd_and_join_cluster "${ns2}" "${bridge}" "${cert}" 2 1 "${LXD_TWO_DIR}"
+
+  # Spawn a third node
+  setup_clustering_netns 3
+  LXD_THREE_DIR=$(mktemp -d -p "${TEST_DIR}" XXX)
+  chmod +x "${LXD_THREE_DIR}"
+  ns3="${prefix}3"
+  spawn_lxd_and_join_cluster "${ns3}" "${bridge}" "${cert}" 3 1 "${LXD_THREE_DIR}"
+
+  # Create instances on node1 with the different evacuation policies
+  LXD_DIR="${LXD_ONE_DIR}" ensure_import_testimage
+  LXD_DIR="${LXD_ONE_DIR}" lxc launch --target node1 testimage c1
+  LXD_DIR="${LXD_ONE_DIR}" lxc launch --target node1 testimage c2 -c cluster.evacuate=stop
+  LXD_DIR="${LXD_ONE_DIR}" lxc init --target node1 testimage c3 -c cluster.evacuate=migrate
+
+  # Evacuate node1
+  LXD_DIR="${LXD_TWO_DIR}" lxc cluster evacuate node1 --force
+  LXD_DIR="${LXD_TWO_DIR}" lxc cluster list | grep node1 | grep -q EVACUATED
+
+  # c1 and c3 were moved, c2 was stopped in place
+  ! LXD_DIR="${LXD_TWO_DIR}" lxc info c1 | grep -q "Location: node1" || false
+  LXD_DIR="${LXD_TWO_DIR}" lxc list -c ns c1 | grep -q RUNNING
+  LXD_DIR="${LXD_TWO_DIR}" lxc info c2 | grep -q "Location: node1"
+  LXD_DIR="${LXD_TWO_DIR}" lxc list -c ns c2 | grep -q STOPPED
+  ! LXD_DIR="${LXD_TWO_DIR}" lxc info c3 | grep -q "Location: node1" || false
+  LXD_DIR="${LXD_TWO_DIR}" lxc list -c ns c3 | grep -q STOPPED
+
+  # Evacuated members don't receive new instances
+  ! LXD_DIR="${LXD_TWO_DIR}" lxc init --target node1 testimage c4 || false
+
+  # Evacuating twice fails
+  ! LXD_DIR="${LXD_TWO_DIR}" lxc cluster evacuate node1 --force || false
+
+  # Restore node1
+  LXD_DIR="${LXD_TWO_DIR}" lxc cluster restore node1 --force
+  LXD_DIR="${LXD_TWO_DIR}" lxc cluster list | grep node1 | grep -q ONLINE
+
+  # All instances are back and in their previous state
+  LXD_DIR="${LXD_TWO_DIR}" lxc info c1 | grep -q "Location: node1"
+  LXD_DIR="${LXD_TWO_DIR}" lxc list -c ns c1 | grep -q RUNNING
+  LXD_DIR="${LXD_TWO_DIR}" lxc info c2 | grep -q "Location: node1"
+  LXD_DIR="${LXD_TWO_DIR}" lxc list -c ns c2 | grep -q RUNNING
+  LXD_DIR="${LXD_TWO_DIR}" lxc info c3 | grep -q "Location: node1"
+  LXD_DIR="${LXD_TWO_DIR}" lxc list -c ns c3 | grep -q STOPPED
+  ! LXD_DIR="${LXD_TWO_DIR}" lxc config get c1 volatile.evacuate.origin | grep -q node1 || false
+
+  # Restoring a non-evacuated member fails
+  ! LXD_DIR="${LXD_TWO_DIR}" lxc cluster restore node1 --force || false
+
+  LXD_DIR="${LXD_ONE_DIR}" lxc delete -f c1 c2 c3
+  LXD_DIR="${LXD_ONE_DIR}" lxc image delete testimage
+
+  LXD_DIR="${LXD_THREE_DIR}" lxd shutdown
+  LXD_DIR="${LXD_TWO_DIR}" lxd shutdown
+  LXD_DIR="${LXD_ONE_DIR}" lxd shutdown
+  sleep 0.5
+  rm -f "${LXD_THREE_DIR}/unix.socket"
+  rm -f "${LXD_TWO_DIR}/unix.socket"
+  rm -f "${LXD_ONE_DIR}/unix.socket"
+
+  teardown_clustering_netns
+  teardown_clustering_bridge
+
+  kill_lxd "${LXD_ONE_DIR}"
+  kill_lxd "${LXD_TWO_DIR}"
+  kill_lxd "${LXD_THREE_DIR}"
+}
+
+test_clustering_groups() {
+  # shellcheck disable=2039
+  local LXD_DIR
+
+  setup_clustering_bridge
+  prefix="lxd$$"
+  bridge="${prefix}"
+
+  setup_clustering_netns 1
+  LXD_ONE_DIR=$(mktemp -d -p "${TEST_DIR}" XXX)
+  chmod +x "${LXD_ONE_DIR}"
+  ns1="${prefix}1"
+  spawn_lxd_and_bootstrap_cluster "${ns1}" "${bridge}" "${LXD_ONE_DIR}"
+
+  # Add a newline at the end of each line. YAML as weird rules..
+  cert=$(sed ':a;N;$!ba;s/\n/\n\n/g' "${LXD_ONE_DIR}/server.crt")
+
+  # Spawn a second node
+  setup_clustering_netns 2
+  LXD_TWO_DIR=$(mktemp -d -p "${TEST_DIR}" XXX)
+  chmod +x "${LXD_TWO_DIR}"
+  ns2="${prefix}2"
+  spawn_lxd_and_join_cluster "${ns2}" "${bridge}" "${cert}" 2 1 "${LXD_TWO_DIR}"
+
+  # All members start in the default group
+  LXD_DIR="${LXD_ONE_DIR}" lxc cluster group show default | grep -q node1
+  LXD_DIR="${LXD_ONE_DIR}" lxc cluster group show default | grep -q node2
+
+  # Create a group and move node2 to it
+  LXD_DIR="${LXD_ONE_DIR}" lxc cluster group create foo
+  LXD_DIR="${LXD_ONE_DIR}" lxc cluster group assign node2 foo
+  LXD_DIR="${LXD_ONE_DIR}" lxc cluster group show foo | grep -q node2
+  ! LXD_DIR="${LXD_ONE_DIR}" lxc cluster group show default | grep -q node2 || false
+
+  # Members can't be left without a group
+  ! LXD_DIR="${LXD_ONE_DIR}" lxc cluster group remove node2 foo || false
+
+  # The default group can't be renamed or deleted, non-empty groups can't be deleted
+  ! LXD_DIR="${LXD_ONE_DIR}" lxc cluster group rename default bar || false
+  ! LXD_DIR="${LXD_ONE_DIR}" lxc cluster group delete default || false
+  ! LXD_DIR="${LXD_ONE_DIR}" lxc cluster group delete foo || false
+
+  # Instances targeting a group land on its members
+  LXD_DIR="${LXD_ONE_DIR}" ensure_import_testimage
+  LXD_DIR="${LXD_ONE_DIR}" lxc init --target @foo testimage c1
+  LXD_DIR="${LXD_ONE_DIR}" lxc info c1 | grep -q "Location: node2"
+  ! LXD_DIR="${LXD_ONE_DIR}" lxc init --target @bar testimage c2 || false
+
+  # Restricted projects only use the members of their allowed groups
+  LXD_DIR="${LXD_ONE_DIR}" lxc project create p1 -c features.images=false -c features.profiles=false
+  LXD_DIR="${LXD_ONE_DIR}" lxc project set p1 restricted=true
+  LXD_DIR="${LXD_ONE_DIR}" lxc project set p1 restricted.cluster.groups=foo
+  LXD_DIR="${LXD_ONE_DIR}" lxc init testimage c3 --project p1
+  LXD_DIR="${LXD_ONE_DIR}" lxc info c3 --project p1 | grep -q "Location: node2"
+  ! LXD_DIR="${LXD_ONE_DIR}" lxc init --target node1 testimage c4 --project p1 || false
+  ! LXD_DIR="${LXD_ONE_DIR}" lxc init --target @default testimage c4 --project p1 || false
+  LXD_DIR="${LXD_ONE_DIR}" lxc delete c3 --project p1
+  LXD_DIR="${LXD_ONE_DIR}" lxc project delete p1
+
+  # Rename the group, then empty and delete it
+  LXD_DIR="${LXD_ONE_DIR}" lxc delete c1
+  LXD_DIR="${LXD_ONE_DIR}" lxc cluster group rename foo bar
+  LXD_DIR="${LXD_ONE_DIR}" lxc cluster group assign node2 default
+  LXD_DIR="${LXD_ONE_DIR}" lxc cluster group delete bar
+  ! LXD_DIR="${LXD_ONE_DIR}" lxc cluster group list | grep -q bar || false
+
+  LXD_DIR="${LXD_ONE_DIR}" lxc image delete testimage
+
+  LXD_DIR="${LXD_TWO_DIR}" lxd shutdown
+  LXD_DIR="${LXD_ONE_DIR}" lxd shutdown
+  sleep 0.5
+  rm -f "${LXD_TWO_DIR}/unix.socket"
+  rm -f "${LXD_ONE_DIR}/unix.socket"
+
+  teardown_clustering_netns
+  teardown_clustering_bridge
+
+  kill_lxd "${LXD_ONE_DIR}"
+  kill_lxd "${LXD_TWO_DIR}"
+}
@@ -90,3 +90,30 @@ test_image_import_existing_alias() {
     lxc image import testimage.file --alias newimage
     lxc image delete newimage image2
 }
+
+test_image_simplestreams_server() {
+    ensure_import_testimage
+    # shellcheck disable=2039,2034,2155
+    local sum=$(lxc image info testimage | grep ^Fingerprint | cut -d' ' -f2)
+
+    # The feed is disabled by default
+    curl -k -s -X GET "https://${LXD_ADDR}/streams/v1/index.json" | grep 404
+
+    lxc config set images.simplestreams_server true
+
+    # Private images aren't published
+    curl -k -s -X GET "https://${LXD_ADDR}/streams/v1/images.json" | grep -q "${sum}" && false
+    curl -k -s -X GET "https://${LXD_ADDR}/streams/images/${sum}/meta" | grep 404
+
+    lxc image show testimage | sed "s/public: false/public: true/" | lxc image edit testimage
+    [ "$(curl -k -s -X GET "https://${LXD_ADDR}/streams/v1/index.json" | jq -r .index.images.path)" = "streams/v1/images.json" ]
+    curl -k -s -X GET "https://${LXD_ADDR}/streams/v1/images.json" | grep -q "${sum}"
+
+    # The published files match the image
+    curl -k -s -o meta "https://${LXD_ADDR}/streams/images/${sum}/meta"
+    cmp meta "${LXD_DIR}/images/${sum}"
+    rm meta
+
+    lxc image show testimage | sed "s/public: true/public: false/" | lxc image edit testimage
+    lxc config unset images.simplestreams_server
+}
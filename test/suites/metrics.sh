@@ -0,0 +1,30 @@
+test_metrics() {
+  ensure_import_testimage
+
+  lxc launch testimage c1
+  lxc init testimage c2
+
+  # The metrics endpoint is accessible through the unix socket.
+  curl -s --unix-socket "${LXD_DIR}/unix.socket" "lxd/1.0/metrics" | grep "name=\"c1\""
+  ! curl -s --unix-socket "${LXD_DIR}/unix.socket" "lxd/1.0/metrics" | grep "name=\"c2\"" || false
+
+  # Host metrics are included unless filtering on a project.
+  curl -s --unix-socket "${LXD_DIR}/unix.socket" "lxd/1.0/metrics" | grep "lxd_host_memory_total_bytes"
+  ! curl -s --unix-socket "${LXD_DIR}/unix.socket" "lxd/1.0/metrics?project=default" | grep "lxd_host_memory_total_bytes" || false
+  curl -s --unix-socket "${LXD_DIR}/unix.socket" "lxd/1.0/metrics?project=default" | grep "name=\"c1\""
+
+  # Untrusted clients are rejected.
+  gen_cert metrics
+  ! curl -k -s --cert "${LXD_CONF}/metrics.crt" --key "${LXD_CONF}/metrics.key" "https://${LXD_ADDR}/1.0/metrics" | grep "name=\"c1\"" || false
+
+  # Metrics certificates are only trusted for the metrics endpoint.
+  lxc config trust add "${LXD_CONF}/metrics.crt" --type=metrics
+  lxc config trust list | grep metrics
+  curl -k -s --cert "${LXD_CONF}/metrics.crt" --key "${LXD_CONF}/metrics.key" "https://${LXD_ADDR}/1.0/metrics" | grep "name=\"c1\""
+  curl -k -s --cert "${LXD_CONF}/metrics.crt" --key "${LXD_CONF}/metrics.key" "https://${LXD_ADDR}/1.0/instances" | grep 403
+
+  # Unknown certificate types are rejected.
+  ! lxc config trust add "${LXD_CONF}/metrics.crt" --type=foo || false
+
+  lxc delete -f c1 c2
+}
@@ -0,0 +1,136 @@
+test_network_acl() {
+  ensure_import_testimage
+  ensure_has_localhost_remote "${LXD_ADDR}"
+
+  # Check basic ACL creation, listing, deletion and naming rules.
+  lxc network acl create testacl
+  lxc network acl list | grep testacl
+  ! lxc network acl create testacl || false # Duplicate.
+  ! lxc network acl create 1testacl || false # Invalid name.
+  ! lxc network acl create "test,acl" || false # Invalid name.
+  lxc network acl delete testacl
+
+  # Check creation from YAML and config validation.
+  cat <<EOF | lxc network acl create testacl
+description: Test ACL
+egress:
+  - action: allow
+    destination: 192.0.2.1
+    protocol: tcp
+    destination_port: "80,443"
+ingress:
+  - action: drop
+    source: 192.0.2.0/24
+config:
+  user.mykey: foo
+EOF
+  lxc network acl show testacl | grep "description: Test ACL"
+  lxc network acl show testacl | grep "destination_port: 80,443"
+  [ "$(lxc network acl get testacl user.mykey)" = "foo" ]
+  ! lxc network acl set testacl invalid.key=foo || false
+
+  # Check rule validation.
+  ! lxc network acl rule add testacl ingress action=accept || false
+  ! lxc network acl rule add testacl ingress action=allow source=192.0.2.1 destination=2001:db8::1 || false
+  ! lxc network acl rule add testacl ingress action=allow destination_port=22 || false
+  ! lxc network acl rule add testacl ingress action=allow protocol=icmp4 icmp_type=256 || false
+  ! lxc network acl rule add testacl invalid action=allow || false
+
+  # Check rule addition and removal.
+  lxc network acl rule add testacl ingress action=allow protocol=tcp destination_port=22
+  lxc network acl show testacl | grep "destination_port: \"22\""
+  lxc network acl rule remove testacl ingress protocol=tcp destination_port=22
+  ! lxc network acl show testacl | grep "destination_port: \"22\"" || false
+  ! lxc network acl rule remove testacl ingress protocol=tcp destination_port=22 || false
+
+  # Check PATCH keeps the existing config and rules.
+  lxc query -X PATCH -d "{\\\"config\\\": {\\\"user.otherkey\\\": \\\"bar\\\"}}" /1.0/network-acls/testacl
+  [ "$(lxc network acl get testacl user.mykey)" = "foo" ]
+  [ "$(lxc network acl get testacl user.otherkey)" = "bar" ]
+  lxc network acl show testacl | grep "source: 192.0.2.0/24"
+
+  # Check the ACL can be applied to a bridge and is then in use.
+  firewallDriver=$(lxc info | awk -F ":" '/firewall:/{gsub(/ /, "", $0); print $2}')
+  lxc network create lxdt$$ ipv4.address=192.0.2.1/24 ipv6.address=none
+  ! lxc network set lxdt$$ security.acls=missingacl || false
+  ! lxc network set lxdt$$ security.acls.default.ingress.action=accept || false
+  lxc network set lxdt$$ security.acls=testacl
+  lxc network set lxdt$$ security.acls.default.egress.action=drop
+  lxc network acl show testacl | grep "/1.0/networks/lxdt$$"
+
+  if [ "$firewallDriver" = "nftables" ]; then
+    nft -nn list chain inet lxd "aclfwd.lxdt$$" | grep "dport { 80, 443 }"
+  else
+    iptables -S FORWARD | grep "LXD network lxdt$$ acl"
+  fi
+
+  # Check in use ACLs can't be renamed or deleted.
+  ! lxc network acl rename testacl testacl2 || false
+  ! lxc network acl delete testacl || false
+
+  # Check the rules are removed with the ACL assignment.
+  lxc network unset lxdt$$ security.acls
+  if [ "$firewallDriver" = "nftables" ]; then
+    ! nft -nn list chain inet lxd "aclfwd.lxdt$$" || false
+  else
+    ! iptables -S FORWARD | grep "LXD network lxdt$$ acl" || false
+  fi
+
+  # Check that NIC ACLs filter the traffic between two instances on the same bridge.
+  # With xtables, bridged traffic only goes through iptables with br_netfilter.
+  if [ "$firewallDriver" = "xtables" ]; then
+    modprobe br_netfilter || true
+  fi
+
+  if [ "$firewallDriver" = "nftables" ] || grep -q 1 /proc/sys/net/bridge/bridge-nf-call-iptables; then
+    lxc network set lxdt$$ security.acls.default.egress.action=allow
+    lxc network acl create nicacl
+    lxc network acl rule add nicacl ingress action=allow protocol=icmp4 source=192.0.2.1
+    lxc init testimage nt$$A -n lxdt$$
+    lxc init testimage nt$$B -n lxdt$$
+    ! lxc config device set nt$$A eth0 security.acls=missingacl || false
+    lxc start nt$$A nt$$B
+    lxc exec nt$$A -- ip a add 192.0.2.2/24 dev eth0
+    lxc exec nt$$B -- ip a add 192.0.2.3/24 dev eth0
+    lxc exec nt$$B -- ping -c2 -W1 192.0.2.2
+
+    lxc config device set nt$$A eth0 security.acls=nicacl
+    lxc network acl show nicacl | grep "/1.0/instances/nt$$A"
+    ctAHostName=$(lxc config get nt$$A volatile.eth0.host_name)
+    if [ "$firewallDriver" = "nftables" ]; then
+      nft -nn list chain bridge lxd "aclout.nt$$A.eth0" | grep "oifname \"${ctAHostName}\" ip saddr .*192.0.2.1"
+    else
+      iptables -S "lxdacl-${ctAHostName}" | grep -- "--physdev-out ${ctAHostName}"
+    fi
+
+    lxc exec nt$$A -- ping -c2 -W1 192.0.2.1
+    lxc exec nt$$A -- ping -c2 -W1 192.0.2.3 # Replies are allowed.
+    ! lxc exec nt$$B -- ping -c2 -W1 192.0.2.2 || false
+
+    # Check ACL changes are applied to the running instances.
+    lxc network acl rule add nicacl ingress action=allow protocol=icmp4 source=192.0.2.3
+    lxc exec nt$$B -- ping -c2 -W1 192.0.2.2
+
+    # Check the rules are removed with the ACL assignment.
+    lxc config device unset nt$$A eth0 security.acls
+    if [ "$firewallDriver" = "nftables" ]; then
+      ! nft -nn list chain bridge lxd "aclout.nt$$A.eth0" || false
+    else
+      ! iptables -S "lxdacl-${ctAHostName}" || false
+    fi
+
+    lxc delete -f nt$$A nt$$B
+    lxc network acl delete nicacl
+  else
+    echo "br_netfilter didn't load, skipping NIC ACL checks"
+  fi
+
+  lxc network delete lxdt$$
+
+  # Check renaming an unused ACL.
+  lxc network acl create testacl2
+  ! lxc network acl rename testacl testacl2 || false # Conflict.
+  lxc network acl delete testacl2
+  lxc network acl rename testacl testacl2
+  lxc network acl delete testacl2
+}
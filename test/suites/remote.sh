@@ -63,6 +63,23 @@ test_remote_admin() {
     false
   fi
 
+  # Add a new client using a single use certificate add token.
+  token="$(lxc_remote config trust add --name foo | tail -n1)"
+  lxc_remote config trust list-tokens | grep -q foo
+  (
+    set -e
+    LXD_CONF=$(mktemp -d -p "${TEST_DIR}" XXX)
+    export LXD_CONF
+    lxc_remote remote add tokenremote "${token}"
+    lxc_remote info tokenremote:
+    lxc_remote remote remove tokenremote
+
+    # The token can only be used once.
+    LXD_CONF=$(mktemp -d -p "${TEST_DIR}" XXX)
+    ! lxc_remote remote add tokenremote "${token}" || false
+  )
+  ! lxc_remote config trust list-tokens | grep -q foo || false
+
   # Check that we can add domains with valid certs without confirmation:
   if [ -z "${LXD_OFFLINE:-}" ]; then
     lxc_remote remote add images1 images.linuxcontainers.org
@@ -31,6 +31,10 @@ test_storage() {
   lxc storage volume set "$storage_pool" "$storage_volume" user.abc def
   [ "$(lxc storage volume get "$storage_pool" "$storage_volume" user.abc)" = "def" ]
 
+  # Check the volume state
+  lxc query "/1.0/storage-pools/${storage_pool}/volumes/custom/${storage_volume}/state" | jq -e '.usage == null or .usage.total > 0'
+  ! lxc query "/1.0/storage-pools/${storage_pool}/volumes/image/${storage_volume}/state" || false
+
   lxc storage volume delete "$storage_pool" "$storage_volume"
   lxc storage delete "$storage_pool"
 
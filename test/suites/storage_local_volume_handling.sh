@@ -165,6 +165,40 @@ test_storage_local_volume_handling() {
         fi
       done
     done
+
+    # Migrate all volumes of a pool to a pool of a different driver
+    if [ -n "${pool_opts}" ]; then
+      # shellcheck disable=SC2086
+      lxc storage create "lxdtest-$(basename "${LXD_DIR}")-migrate" "${driver}" $pool_opts
+    else
+      lxc storage create "lxdtest-$(basename "${LXD_DIR}")-migrate" "${driver}"
+    fi
+
+    lxc storage create "lxdtest-$(basename "${LXD_DIR}")-migrate-dir" dir
+    lxc profile create migrate
+    lxc profile device add migrate root disk path=/ pool="lxdtest-$(basename "${LXD_DIR}")-migrate"
+    lxc init testimage c1 -p default -p migrate
+    lxc snapshot c1
+    lxc storage volume create "lxdtest-$(basename "${LXD_DIR}")-migrate" vol1
+    lxc storage volume snapshot "lxdtest-$(basename "${LXD_DIR}")-migrate" vol1
+    lxc storage volume attach "lxdtest-$(basename "${LXD_DIR}")-migrate" vol1 c1 /mnt
+
+    lxc storage migrate "lxdtest-$(basename "${LXD_DIR}")-migrate" "lxdtest-$(basename "${LXD_DIR}")-migrate-dir"
+    [ -z "$(lxc storage volume list "lxdtest-$(basename "${LXD_DIR}")-migrate" --format csv)" ]
+    lxc storage volume show "lxdtest-$(basename "${LXD_DIR}")-migrate-dir" container/c1
+    lxc storage volume show "lxdtest-$(basename "${LXD_DIR}")-migrate-dir" container/c1/snap0
+    lxc storage volume show "lxdtest-$(basename "${LXD_DIR}")-migrate-dir" vol1/snap0
+    [ "$(lxc profile device get migrate root pool)" = "lxdtest-$(basename "${LXD_DIR}")-migrate-dir" ]
+    [ "$(lxc config device get c1 vol1 pool)" = "lxdtest-$(basename "${LXD_DIR}")-migrate-dir" ]
+    ! lxc storage get "lxdtest-$(basename "${LXD_DIR}")-migrate" volatile.migration.target | grep -q . || false
+    lxc start c1
+    lxc exec c1 -- test -d /mnt
+
+    lxc delete -f c1
+    lxc profile delete migrate
+    lxc storage volume delete "lxdtest-$(basename "${LXD_DIR}")-migrate-dir" vol1
+    lxc storage delete "lxdtest-$(basename "${LXD_DIR}")-migrate"
+    lxc storage delete "lxdtest-$(basename "${LXD_DIR}")-migrate-dir"
   )
 
   # shellcheck disable=SC2031
@@ -75,6 +75,26 @@ test_storage_volume_snapshots() {
   # is attached to the container
   ! lxc storage volume restore "${storage_pool}" "${storage_volume}" snap0 || false
 
+  # Copy a snapshot into a new volume, leaving the source volume and its snapshots untouched
+  lxc storage volume copy "${storage_pool}/${storage_volume}/snap0" "${storage_pool}/${storage_volume}-copy"
+  ! lxc storage volume list "${storage_pool}" | grep -q "${storage_volume}-copy/" || false
+  lxc storage volume show "${storage_pool}" "${storage_volume}/foo" | grep 'name: foo'
+  ! lxc storage volume move "${storage_pool}/${storage_volume}/snap0" "${storage_pool}/${storage_volume}-moved" || false
+  lxc storage volume attach "${storage_pool}" "${storage_volume}-copy" c1 /mnt2
+  [ "$(lxc exec c1 -- cat /mnt2/testfile)" = 'foobar' ]
+  lxc storage volume detach "${storage_pool}" "${storage_volume}-copy" c1
+  lxc storage volume delete "${storage_pool}" "${storage_volume}-copy"
+
+  # Copy a snapshot into a new volume on another pool
+  lxc storage create "${storage_pool}2" "$lxd_backend"
+  lxc storage volume copy "${storage_pool}/${storage_volume}/snap0" "${storage_pool}2/${storage_volume}-copy"
+  ! lxc storage volume list "${storage_pool}2" | grep -q "${storage_volume}-copy/" || false
+  lxc storage volume attach "${storage_pool}2" "${storage_volume}-copy" c1 /mnt2
+  [ "$(lxc exec c1 -- cat /mnt2/testfile)" = 'foobar' ]
+  lxc storage volume detach "${storage_pool}2" "${storage_volume}-copy" c1
+  lxc storage volume delete "${storage_pool}2" "${storage_volume}-copy"
+  lxc storage delete "${storage_pool}2"
+
   lxc stop -f c1
   lxc storage volume restore "${storage_pool}" "${storage_volume}" foo
 
@@ -0,0 +1,32 @@
+test_warnings() {
+  # Delete any warning raised during startup.
+  lxc warning delete --all
+  [ "$(lxc warning list --format csv | wc -l)" = "0" ]
+
+  # Create a warning through the database.
+  uuid="39c61a48-cc5a-4a51-9a4d-7c4a1f4fbd1c"
+  lxd sql global "INSERT INTO warnings (node_id, uuid, type_code, status, first_seen_date, last_seen_date, last_message, count) VALUES (1, '${uuid}', 1, 1, '2020-12-01 10:00:00', '2020-12-01 10:00:00', 'Test warning', 1)"
+
+  # Check listing and showing.
+  lxc warning list | grep "${uuid}"
+  lxc warning list --format csv | grep "NEW"
+  lxc warning show "${uuid}" | grep "last_message: Test warning"
+  lxc warning show "${uuid}" | grep "severity: low"
+  lxc query /1.0/warnings | grep "/1.0/warnings/${uuid}"
+
+  # Acknowledged warnings are hidden by default.
+  lxc warning ack "${uuid}"
+  lxc warning show "${uuid}" | grep "status: acknowledged"
+  ! lxc warning list | grep "${uuid}" || false
+  lxc warning list --all | grep "${uuid}"
+
+  # Only the new and acknowledged statuses can be set by users.
+  ! lxc query -X PUT -d '{"status": "resolved"}' "/1.0/warnings/${uuid}" || false
+  lxc query -X PUT -d '{"status": "new"}' "/1.0/warnings/${uuid}"
+  lxc warning show "${uuid}" | grep "status: new"
+
+  # Check deletion.
+  lxc warning delete "${uuid}"
+  ! lxc warning show "${uuid}" || false
+  ! lxc warning delete "${uuid}" || false
+}